@@ -13,6 +13,10 @@ service:
 
 # ================================ Broker配置  ===================================
 broker:
+  # redis 或 bbolt，bbolt 仅适用于单机部署
+  type: redis
+  bbolt:
+    path: bmw_broker.db
   redis:
     mode: standalone
     db: 0
@@ -119,6 +123,16 @@ worker:
       tolerateCount: 60
       tolerateInterval: 10s
       intolerantFactor: 2
+  # 按任务类型限制执行，被限流的任务会延迟重新执行，不计入重试次数
+  taskLimits: {}
+  #  "async:your_taskName":
+  #    # 单个 worker 内同时执行的最大任务数
+  #    concurrency: 2
+  #    # 单个 worker 每秒最多开始执行的任务数及突发数
+  #    rate: 0.5
+  #    burst: 1
+  #    # 所有 worker 同时执行的最大任务数
+  #    globalConcurrency: 4
 
 # ================================ 任务配置  ===================================
 taskConfig:
@@ -184,6 +198,19 @@ taskConfig:
       flowClusterGroup: "default_inland"
       projectMaintainer: "admin"
       isAllowAllCmdbLevel: false
+    slo:
+      sloPushGatewayToken: ""
+      sloPushGatewayEndpoint: ""
+      unifyQueryUrl: ""
+      queryTimeout: 30s
+      # burnRateWindows: 多窗口燃烧率告警窗口，slo 目标本身从带有 slo 标签的策略中解析
+      burnRateWindows:
+        - long: 1h
+          short: 5m
+          factor: 14.4
+        - long: 6h
+          short: 30m
+          factor: 6
   # apmPreCalculate: apm预计算配置
   apmPreCalculate:
     notifier:
@@ -200,6 +227,8 @@ taskConfig:
         concurrentExpirationMaximum: 100000
     processor:
       enabledTraceInfoCache: 0
+      # trace info 中保存自身耗时的 span 数上限，按自身耗时倒序保留，0 表示全部保存
+      spanSelfTimeMaxCount: 0
     storage:
       saveRequestBufferSize: 100000
       workerCount: 10
@@ -227,6 +256,30 @@ taskConfig:
         enabled: false
         host: http://127.0.0.1:14040
         appIdx: appIdx-1
+  # recordRule: 预计算规则计算配置
+  recordRule:
+    queryMode: vm
+    unifyQuery:
+      url: ""
+    vm:
+      queryPath: /api/v1/query
+      writePath: /api/v1/write
+    checkInterval: 5s
+    refreshInterval: 1m
+    evaluationDelay: 30s
+    maxBackfill: 1h
+    queryTimeout: 30s
+    concurrency: 10
+  # cluster_metrics: 存储集群指标采集配置
+  cluster_metrics:
+    storage_key_prefix: bkmonitor
+    storage_ttl: 300
+    vm:
+      timeout: 10s
+      metricsPath: /metrics
+    kafka:
+      # partitionLagLimit: 分区维度消费组 lag 的最大序列数，为 0 时只上报 topic 维度的 lag_sum
+      partitionLagLimit: 0
 
 # ================================ 任务调度器配置  ===================================
 scheduler:
@@ -0,0 +1,1022 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/timex"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// set ttl
+const statsTTL = 90 * 24 * time.Hour
+
+const LeaseDuration = 30 * time.Minute
+
+const (
+	maxArchiveSize           = 10000 // maximum number of tasks in archive
+	archivedExpirationInDays = 90    // number of days before an archived task gets deleted permanently
+)
+
+// 数据布局:
+//
+//	queues/<qname>/tasks      task id -> taskRecord
+//	queues/<qname>/pending    序号 -> task id，按序号从小到大出队
+//	queues/<qname>/active     task id -> nil
+//	queues/<qname>/lease      task id -> 租约过期时间
+//	queues/<qname>/scheduled  时间戳+task id -> nil，下同，用于按时间范围查找
+//	queues/<qname>/retry
+//	queues/<qname>/archived
+//	queues/<qname>/completed
+//	queues/<qname>/stats      统计项 -> 计数
+//	unique                    unique key -> uniqueLock
+//	paused                    暂停的队列名 -> 暂停时间
+//	servers                   server key -> serverRecord
+//	semaphores/<name>         holder -> 过期时间
+//	workflows                 workflow id -> workflowRecord
+//	workflow_index            创建时间+workflow id -> nil
+//	workflow_advancing        workflow id -> 开始推进的时间，当前阶段的任务全部下发后删除
+//	schedulers                scheduler id -> schedulerRecord
+//	scheduler_history/<entry> 入队时间+task id -> 入队事件
+//	daemon_tasks              常驻任务的 sha256 -> 序列化的常驻任务
+var (
+	bucketQueues        = []byte("queues")
+	bucketUnique        = []byte("unique")
+	bucketPaused        = []byte("paused")
+	bucketServers       = []byte("servers")
+	bucketSemaphores    = []byte("semaphores")
+	bucketWorkflows     = []byte("workflows")
+	bucketWorkflowIndex = []byte("workflow_index")
+	bucketWorkflowAdv   = []byte("workflow_advancing")
+	bucketSchedulers    = []byte("schedulers")
+	bucketSchedHistory  = []byte("scheduler_history")
+	bucketDaemonTasks   = []byte("daemon_tasks")
+
+	bucketTasks     = []byte("tasks")
+	bucketPending   = []byte("pending")
+	bucketActive    = []byte("active")
+	bucketLease     = []byte("lease")
+	bucketScheduled = []byte("scheduled")
+	bucketRetry     = []byte("retry")
+	bucketArchived  = []byte("archived")
+	bucketCompleted = []byte("completed")
+	bucketStats     = []byte("stats")
+
+	rootBuckets = [][]byte{
+		bucketQueues, bucketUnique, bucketPaused, bucketServers, bucketSemaphores, bucketWorkflows, bucketWorkflowIndex,
+		bucketWorkflowAdv, bucketSchedulers, bucketSchedHistory, bucketDaemonTasks,
+	}
+	queueBuckets = [][]byte{
+		bucketTasks, bucketPending, bucketActive, bucketLease,
+		bucketScheduled, bucketRetry, bucketArchived, bucketCompleted, bucketStats,
+	}
+)
+
+const (
+	statsProcessed = "processed"
+	statsFailed    = "failed"
+)
+
+// taskRecord 任务数据，与 redis broker 中任务 hash 的字段一致
+type taskRecord struct {
+	Msg          []byte `json:"msg"`
+	State        string `json:"state"`
+	PendingSince int64  `json:"pending_since,omitempty"`
+	UniqueKey    string `json:"unique_key,omitempty"`
+	Result       []byte `json:"result,omitempty"`
+}
+
+// uniqueLock 任务唯一锁，过期后可被重新获取
+type uniqueLock struct {
+	TaskID   string `json:"task_id"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// serverRecord worker 运行状态
+type serverRecord struct {
+	Info     []byte   `json:"info"`
+	Workers  [][]byte `json:"workers"`
+	ExpireAt int64    `json:"expire_at"`
+}
+
+// Broker 基于 bbolt 的 broker，所有数据保存在本地文件中，仅适用于单机部署及测试
+type Broker struct {
+	mut   sync.Mutex
+	path  string
+	db    *bolt.DB
+	clock timex.Clock
+}
+
+var (
+	brokerInstance *Broker
+	brokerOnce     sync.Once
+)
+
+// GetBroker Get the bbolt broker
+func GetBroker() *Broker {
+	if brokerInstance != nil {
+		return brokerInstance
+	}
+
+	brokerOnce.Do(func() {
+		b := NewBroker(config.BrokerBboltPath)
+		// 因为是必要依赖，如果有错误，直接异常
+		if err := b.Open(); err != nil {
+			logger.Fatalf("failed to open bbolt broker, error: %s", err)
+		}
+		brokerInstance = b
+	})
+
+	return brokerInstance
+}
+
+// NewBroker new a bbolt broker with the db file path
+func NewBroker(path string) *Broker {
+	return &Broker{path: path, clock: timex.NewTimeClock()}
+}
+
+// Open opens the db file, creates it if not exists
+func (b *Broker) Open() error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.db != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o700); err != nil {
+		return fmt.Errorf("unable to create directory %s: %v", b.path, err)
+	}
+	db, err := bolt.Open(b.path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return fmt.Errorf("unable to open boltdb: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range rootBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("unable to init boltdb: %w", err)
+	}
+	b.db = db
+	return nil
+}
+
+// Close closes the db file
+func (b *Broker) Close() error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.db == nil {
+		return nil
+	}
+	err := b.db.Close()
+	b.db = nil
+	return err
+}
+
+// SetClock sets the clock used by broker to the given clock.
+func (b *Broker) SetClock(c timex.Clock) {
+	b.clock = c
+}
+
+// update 在写事务中执行 fn，非 errors.Error 类型的错误视为内部错误
+func (b *Broker) update(op errors.Op, fn func(tx *bolt.Tx) error) error {
+	return wrapError(op, b.db.Update(fn))
+}
+
+// view 在读事务中执行 fn
+func (b *Broker) view(op errors.Op, fn func(tx *bolt.Tx) error) error {
+	return wrapError(op, b.db.View(fn))
+}
+
+func wrapError(op errors.Op, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *errors.Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return errors.E(op, errors.Internal, fmt.Sprintf("bbolt error: %v", err))
+}
+
+// queueBucket 获取队列的 bucket，不存在时创建，仅可在写事务中使用
+func queueBucket(tx *bolt.Tx, qname string) (*bolt.Bucket, error) {
+	qb, err := tx.Bucket(bucketQueues).CreateBucketIfNotExists([]byte(qname))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range queueBuckets {
+		if _, err := qb.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return qb, nil
+}
+
+func getTask(qb *bolt.Bucket, id string) (*taskRecord, error) {
+	data := qb.Bucket(bucketTasks).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	rec := new(taskRecord)
+	if err := jsonx.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("cannot decode task %s: %v", id, err)
+	}
+	return rec, nil
+}
+
+func putTask(qb *bolt.Bucket, id string, rec *taskRecord) error {
+	data, err := jsonx.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("cannot encode task %s: %v", id, err)
+	}
+	return qb.Bucket(bucketTasks).Put([]byte(id), data)
+}
+
+func uint64Bytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+// pushPending 将任务加入待执行列表，head 为 true 时下一个出队
+func pushPending(qb *bolt.Bucket, id string, head bool) error {
+	pb := qb.Bucket(bucketPending)
+	// 从中间值开始，两端均可追加
+	seq := uint64(1) << 63
+	c := pb.Cursor()
+	if head {
+		if k, _ := c.First(); k != nil {
+			seq = binary.BigEndian.Uint64(k) - 1
+		}
+	} else {
+		if k, _ := c.Last(); k != nil {
+			seq = binary.BigEndian.Uint64(k) + 1
+		}
+	}
+	return pb.Put(uint64Bytes(seq), []byte(id))
+}
+
+// zsetKey 以分数作为前缀，使 key 按分数有序
+func zsetKey(score int64, id string) []byte {
+	key := make([]byte, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(score))
+	copy(key[8:], id)
+	return key
+}
+
+// zsetRangeByScore 按分数升序返回分数不大于 max 的 key，limit 不大于 0 时不限制数量
+func zsetRangeByScore(zb *bolt.Bucket, max int64, limit int) [][]byte {
+	var keys [][]byte
+	c := zb.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if int64(binary.BigEndian.Uint64(k[:8])) > max {
+			break
+		}
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+		keys = append(keys, bytes.Clone(k))
+	}
+	return keys
+}
+
+// countKeys 统计 bucket 中的 key 数量，Stats 在写事务中不包含未提交的修改
+func countKeys(b *bolt.Bucket) int {
+	var n int
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+	return n
+}
+
+func zsetMember(key []byte) string {
+	return string(key[8:])
+}
+
+// removeActive 将任务移出执行中列表及租约，任务不在执行中时返回 false
+func removeActive(qb *bolt.Bucket, id string) (bool, error) {
+	ab, lb := qb.Bucket(bucketActive), qb.Bucket(bucketLease)
+	if ab.Get([]byte(id)) == nil || lb.Get([]byte(id)) == nil {
+		return false, nil
+	}
+	if err := ab.Delete([]byte(id)); err != nil {
+		return false, err
+	}
+	return true, lb.Delete([]byte(id))
+}
+
+func incrCounter(b *bolt.Bucket, key string) (uint64, error) {
+	var n uint64
+	if v := b.Get([]byte(key)); v != nil {
+		n = binary.BigEndian.Uint64(v)
+	}
+	n++
+	return n, b.Put([]byte(key), uint64Bytes(n))
+}
+
+// incrStats 增加处理数，failed 为 true 时同时增加失败数，每天首次统计时清理过期的按天统计
+func incrStats(qb *bolt.Bucket, now time.Time, failed bool) error {
+	sb := qb.Bucket(bucketStats)
+	names := []string{statsProcessed}
+	if failed {
+		names = append(names, statsFailed)
+	}
+	day := now.UTC().Format("2006-01-02")
+	for _, name := range names {
+		if _, err := incrCounter(sb, name); err != nil {
+			return err
+		}
+		n, err := incrCounter(sb, name+":"+day)
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			if err := pruneStats(sb, name, now.Add(-statsTTL)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func pruneStats(sb *bolt.Bucket, name string, cutoff time.Time) error {
+	prefix := []byte(name + ":")
+	var expired [][]byte
+	c := sb.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		day, err := time.Parse("2006-01-02", string(k[len(prefix):]))
+		if err != nil || day.Before(cutoff) {
+			expired = append(expired, bytes.Clone(k))
+		}
+	}
+	for _, k := range expired {
+		if err := sb.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquireUnique 获取任务唯一锁，锁被其他任务持有且未过期时返回 false
+func acquireUnique(tx *bolt.Tx, key, id string, expireAt time.Time, now time.Time) (bool, error) {
+	ub := tx.Bucket(bucketUnique)
+	if data := ub.Get([]byte(key)); data != nil {
+		var lock uniqueLock
+		if err := jsonx.Unmarshal(data, &lock); err == nil && lock.ExpireAt > now.Unix() {
+			return false, nil
+		}
+	}
+	data, err := jsonx.Marshal(uniqueLock{TaskID: id, ExpireAt: expireAt.Unix()})
+	if err != nil {
+		return false, err
+	}
+	return true, ub.Put([]byte(key), data)
+}
+
+// releaseUnique 释放任务持有的唯一锁
+func releaseUnique(tx *bolt.Tx, key, id string) error {
+	if key == "" {
+		return nil
+	}
+	ub := tx.Bucket(bucketUnique)
+	data := ub.Get([]byte(key))
+	if data == nil {
+		return nil
+	}
+	var lock uniqueLock
+	if err := jsonx.Unmarshal(data, &lock); err == nil && lock.TaskID != id {
+		return nil
+	}
+	return ub.Delete([]byte(key))
+}
+
+// enqueue 保存任务并加入待执行列表
+func (b *Broker) enqueue(op errors.Op, msg *task.TaskMessage, uniqueTTL time.Duration) error {
+	encoded, err := task.EncodeMessage(msg)
+	if err != nil {
+		return errors.E(op, errors.Unknown, fmt.Sprintf("cannot encode message: %v", err))
+	}
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		if uniqueTTL > 0 {
+			ok, err := acquireUnique(tx, msg.UniqueKey, msg.ID, now.Add(uniqueTTL), now)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.E(op, errors.AlreadyExists, errors.ErrDuplicateTask, msg.UniqueKey)
+			}
+		}
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			return errors.E(op, errors.AlreadyExists, errors.ErrTaskIdConflict)
+		}
+		rec = &taskRecord{
+			Msg:          encoded,
+			State:        task.TaskStatePending.String(),
+			PendingSince: now.UnixNano(),
+		}
+		if uniqueTTL > 0 {
+			rec.UniqueKey = msg.UniqueKey
+		}
+		if err = putTask(qb, msg.ID, rec); err != nil {
+			return err
+		}
+		return pushPending(qb, msg.ID, false)
+	})
+}
+
+// Enqueue adds the given task to the pending list of the queue.
+func (b *Broker) Enqueue(_ context.Context, msg *task.TaskMessage) error {
+	return b.enqueue("bbolt.Enqueue", msg, 0)
+}
+
+// EnqueueUnique inserts the given task if the task's uniqueness lock can be acquired.
+// It returns ErrDuplicateTask if the lock cannot be acquired.
+func (b *Broker) EnqueueUnique(_ context.Context, msg *task.TaskMessage, ttl time.Duration) error {
+	return b.enqueue("bbolt.EnqueueUnique", msg, ttl)
+}
+
+// Dequeue queries given queues in order and pops a task message
+// off a queue if one exists and returns the message and its lease expiration time.
+// If all queues are empty, ErrNoProcessableTask error is returned.
+// Dequeue skips a queue if the queue is paused.
+func (b *Broker) Dequeue(qnames ...string) (msg *task.TaskMessage, leaseExpirationTime time.Time, err error) {
+	var op errors.Op = "bbolt.Dequeue"
+	leaseExpirationTime = b.clock.Now().Add(LeaseDuration)
+	err = b.update(op, func(tx *bolt.Tx) error {
+		for _, qname := range qnames {
+			qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+			if qb == nil || tx.Bucket(bucketPaused).Get([]byte(qname)) != nil {
+				continue
+			}
+			pb := qb.Bucket(bucketPending)
+			for k, v := pb.Cursor().First(); k != nil; k, v = pb.Cursor().First() {
+				id := string(v)
+				if err := pb.Delete(k); err != nil {
+					return err
+				}
+				rec, err := getTask(qb, id)
+				if err != nil {
+					return err
+				}
+				// 任务已被删除
+				if rec == nil {
+					continue
+				}
+				rec.State = task.TaskStateActive.String()
+				rec.PendingSince = 0
+				if err = putTask(qb, id, rec); err != nil {
+					return err
+				}
+				if err = qb.Bucket(bucketActive).Put([]byte(id), nil); err != nil {
+					return err
+				}
+				if err = qb.Bucket(bucketLease).Put([]byte(id), uint64Bytes(uint64(leaseExpirationTime.Unix()))); err != nil {
+					return err
+				}
+				if msg, err = task.DecodeMessage(rec.Msg); err != nil {
+					return errors.E(op, errors.Internal, fmt.Sprintf("cannot decode message: %v", err))
+				}
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if msg == nil {
+		return nil, time.Time{}, errors.E(op, errors.NotFound, errors.ErrNoProcessableTask)
+	}
+	return msg, leaseExpirationTime, nil
+}
+
+// Done removes the task from active queue and deletes the task.
+// It removes a uniqueness lock acquired by the task, if any.
+func (b *Broker) Done(_ context.Context, msg *task.TaskMessage) error {
+	var op errors.Op = "bbolt.Done"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		if _, err = removeActive(qb, msg.ID); err != nil {
+			return err
+		}
+		tb := qb.Bucket(bucketTasks)
+		if tb.Get([]byte(msg.ID)) == nil {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("task %s not found", msg.ID))
+		}
+		if err = tb.Delete([]byte(msg.ID)); err != nil {
+			return err
+		}
+		if err = incrStats(qb, now, false); err != nil {
+			return err
+		}
+		return releaseUnique(tx, msg.UniqueKey, msg.ID)
+	})
+}
+
+// MarkAsComplete removes the task from active queue to mark the task as completed.
+// It removes a uniqueness lock acquired by the task, if any.
+func (b *Broker) MarkAsComplete(_ context.Context, msg *task.TaskMessage) error {
+	var op errors.Op = "bbolt.MarkAsComplete"
+	now := b.clock.Now()
+	msg.CompletedAt = now.Unix()
+	encoded, err := task.EncodeMessage(msg)
+	if err != nil {
+		return errors.E(op, errors.Unknown, fmt.Sprintf("cannot encode message: %v", err))
+	}
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		ok, err := removeActive(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("task %s is not active", msg.ID))
+		}
+		if err = qb.Bucket(bucketCompleted).Put(zsetKey(now.Unix()+msg.Retention, msg.ID), nil); err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = new(taskRecord)
+		}
+		rec.Msg = encoded
+		rec.State = task.TaskStateCompleted.String()
+		if err = putTask(qb, msg.ID, rec); err != nil {
+			return err
+		}
+		if err = incrStats(qb, now, false); err != nil {
+			return err
+		}
+		return releaseUnique(tx, msg.UniqueKey, msg.ID)
+	})
+}
+
+// Requeue moves the task from active queue to the head of pending list.
+func (b *Broker) Requeue(_ context.Context, msg *task.TaskMessage) error {
+	var op errors.Op = "bbolt.Requeue"
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		ok, err := removeActive(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("task %s is not active", msg.ID))
+		}
+		if err = pushPending(qb, msg.ID, true); err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil || rec == nil {
+			return err
+		}
+		rec.State = task.TaskStatePending.String()
+		return putTask(qb, msg.ID, rec)
+	})
+}
+
+// schedule 保存任务并加入定时列表
+func (b *Broker) schedule(op errors.Op, msg *task.TaskMessage, processAt time.Time, uniqueTTL time.Duration) error {
+	encoded, err := task.EncodeMessage(msg)
+	if err != nil {
+		return errors.E(op, errors.Unknown, fmt.Sprintf("cannot encode message: %v", err))
+	}
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		if uniqueTTL > 0 {
+			ok, err := acquireUnique(tx, msg.UniqueKey, msg.ID, now.Add(uniqueTTL), now)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.E(op, errors.AlreadyExists, errors.ErrDuplicateTask)
+			}
+		}
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			return errors.E(op, errors.AlreadyExists, errors.ErrTaskIdConflict)
+		}
+		rec = &taskRecord{Msg: encoded, State: task.TaskStateScheduled.String()}
+		if uniqueTTL > 0 {
+			rec.UniqueKey = msg.UniqueKey
+		}
+		if err = putTask(qb, msg.ID, rec); err != nil {
+			return err
+		}
+		return qb.Bucket(bucketScheduled).Put(zsetKey(processAt.Unix(), msg.ID), nil)
+	})
+}
+
+// Schedule adds the task to the scheduled set to be processed in the future.
+func (b *Broker) Schedule(_ context.Context, msg *task.TaskMessage, processAt time.Time) error {
+	return b.schedule("bbolt.Schedule", msg, processAt, 0)
+}
+
+// ScheduleUnique adds the task to the backlog queue to be processed in the future,
+// if the uniqueness lock can be acquired.
+// It returns ErrDuplicateTask if the lock cannot be acquired.
+func (b *Broker) ScheduleUnique(_ context.Context, msg *task.TaskMessage, processAt time.Time, ttl time.Duration) error {
+	return b.schedule("bbolt.ScheduleUnique", msg, processAt, ttl)
+}
+
+// Retry moves the task from active to retry queue.
+// It also annotates the message with the given error message and
+// if isFailure is true increments the retried counter.
+func (b *Broker) Retry(_ context.Context, msg *task.TaskMessage, processAt time.Time, errMsg string, isFailure bool) error {
+	var op errors.Op = "bbolt.Retry"
+	now := b.clock.Now()
+	modified := *msg
+	if isFailure {
+		modified.Retried++
+	}
+	modified.ErrorMsg = errMsg
+	modified.LastFailedAt = now.Unix()
+	encoded, err := task.EncodeMessage(&modified)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode message: %v", err))
+	}
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		ok, err := removeActive(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("task %s is not active", msg.ID))
+		}
+		if err = qb.Bucket(bucketRetry).Put(zsetKey(processAt.Unix(), msg.ID), nil); err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = new(taskRecord)
+		}
+		rec.Msg = encoded
+		rec.State = task.TaskStateRetry.String()
+		if err = putTask(qb, msg.ID, rec); err != nil {
+			return err
+		}
+		if isFailure {
+			return incrStats(qb, now, true)
+		}
+		return nil
+	})
+}
+
+// Archive sends the given task to archive, attaching the error message to the task.
+// It also trims the archive by timestamp and set size.
+func (b *Broker) Archive(_ context.Context, msg *task.TaskMessage, errMsg string) error {
+	var op errors.Op = "bbolt.Archive"
+	now := b.clock.Now()
+	modified := *msg
+	modified.ErrorMsg = errMsg
+	modified.LastFailedAt = now.Unix()
+	encoded, err := task.EncodeMessage(&modified)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode message: %v", err))
+	}
+	cutoff := now.AddDate(0, 0, -archivedExpirationInDays)
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, msg.Queue)
+		if err != nil {
+			return err
+		}
+		if _, err = removeActive(qb, msg.ID); err != nil {
+			return err
+		}
+		arb := qb.Bucket(bucketArchived)
+		if err = arb.Put(zsetKey(now.Unix(), msg.ID), nil); err != nil {
+			return err
+		}
+		rec, err := getTask(qb, msg.ID)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = new(taskRecord)
+		}
+		rec.Msg = encoded
+		rec.State = task.TaskStateArchived.String()
+		if err = putTask(qb, msg.ID, rec); err != nil {
+			return err
+		}
+		if err = trimArchived(qb, cutoff); err != nil {
+			return err
+		}
+		return incrStats(qb, now, true)
+	})
+}
+
+// trimArchived 删除过期的归档任务，并保证归档任务数不超过 maxArchiveSize
+func trimArchived(qb *bolt.Bucket, cutoff time.Time) error {
+	arb := qb.Bucket(bucketArchived)
+	expired := zsetRangeByScore(arb, cutoff.Unix(), 0)
+	if overflow := countKeys(arb) - len(expired) - maxArchiveSize; overflow > 0 {
+		c := arb.Cursor()
+		k, _ := c.First()
+		for i := 0; i < len(expired) && k != nil; i++ {
+			k, _ = c.Next()
+		}
+		for ; overflow > 0 && k != nil; overflow-- {
+			expired = append(expired, bytes.Clone(k))
+			k, _ = c.Next()
+		}
+	}
+	tb := qb.Bucket(bucketTasks)
+	for _, k := range expired {
+		if err := arb.Delete(k); err != nil {
+			return err
+		}
+		if err := tb.Delete([]byte(zsetMember(k))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForwardIfReady checks scheduled and retry sets of the given queues
+// and move any tasks that are ready to be processed to the pending set.
+func (b *Broker) ForwardIfReady(qnames ...string) error {
+	var op errors.Op = "bbolt.ForwardIfReady"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		for _, qname := range qnames {
+			qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+			if qb == nil {
+				continue
+			}
+			for _, name := range [][]byte{bucketScheduled, bucketRetry} {
+				zb := qb.Bucket(name)
+				for _, k := range zsetRangeByScore(zb, now.Unix(), 0) {
+					id := zsetMember(k)
+					if err := zb.Delete(k); err != nil {
+						return err
+					}
+					rec, err := getTask(qb, id)
+					if err != nil {
+						return err
+					}
+					if rec == nil {
+						continue
+					}
+					rec.State = task.TaskStatePending.String()
+					rec.PendingSince = now.UnixNano()
+					if err = putTask(qb, id, rec); err != nil {
+						return err
+					}
+					if err = pushPending(qb, id, false); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteExpiredCompletedTasks checks for any expired tasks in the given queue's completed set,
+// and delete all expired tasks.
+func (b *Broker) DeleteExpiredCompletedTasks(qname string) error {
+	var op errors.Op = "bbolt.DeleteExpiredCompletedTasks"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+		if qb == nil {
+			return nil
+		}
+		cb, tb := qb.Bucket(bucketCompleted), qb.Bucket(bucketTasks)
+		for _, k := range zsetRangeByScore(cb, now.Unix(), 0) {
+			if err := cb.Delete(k); err != nil {
+				return err
+			}
+			if err := tb.Delete([]byte(zsetMember(k))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListLeaseExpired returns a list of task messages with an expired lease from the given queues.
+func (b *Broker) ListLeaseExpired(cutoff time.Time, qnames ...string) ([]*task.TaskMessage, error) {
+	var op errors.Op = "bbolt.ListLeaseExpired"
+	var msgs []*task.TaskMessage
+	err := b.view(op, func(tx *bolt.Tx) error {
+		for _, qname := range qnames {
+			qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+			if qb == nil {
+				continue
+			}
+			c := qb.Bucket(bucketLease).Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if int64(binary.BigEndian.Uint64(v)) > cutoff.Unix() {
+					continue
+				}
+				rec, err := getTask(qb, string(k))
+				if err != nil {
+					return err
+				}
+				if rec == nil {
+					continue
+				}
+				msg, err := task.DecodeMessage(rec.Msg)
+				if err != nil {
+					return errors.E(op, errors.Internal, fmt.Sprintf("cannot decode message: %v", err))
+				}
+				msgs = append(msgs, msg)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// ExtendLease extends the lease for the given tasks by LeaseDuration.
+// It returns a new expiration time if the operation was successful.
+func (b *Broker) ExtendLease(qname string, ids ...string) (time.Time, error) {
+	var op errors.Op = "bbolt.ExtendLease"
+	expireAt := b.clock.Now().Add(LeaseDuration)
+	err := b.update(op, func(tx *bolt.Tx) error {
+		qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+		if qb == nil {
+			return nil
+		}
+		lb := qb.Bucket(bucketLease)
+		for _, id := range ids {
+			// 仅更新已存在的租约
+			if lb.Get([]byte(id)) == nil {
+				continue
+			}
+			if err := lb.Put([]byte(id), uint64Bytes(uint64(expireAt.Unix()))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expireAt, nil
+}
+
+// WriteServerState writes server state data with expiration set to the value ttl.
+func (b *Broker) WriteServerState(info *common.ServerInfo, workers []*common.WorkerInfo, ttl time.Duration) error {
+	var op errors.Op = "bbolt.WriteServerState"
+	now := b.clock.Now()
+	data, err := common.EncodeServerInfo(info)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode server info: %v", err))
+	}
+	rec := serverRecord{Info: data, ExpireAt: now.Add(ttl).Unix()}
+	for _, w := range workers {
+		data, err := common.EncodeWorkerInfo(w)
+		if err != nil {
+			continue // skip bad data
+		}
+		rec.Workers = append(rec.Workers, data)
+	}
+	encoded, err := jsonx.Marshal(rec)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode server state: %v", err))
+	}
+	return b.update(op, func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketServers)
+		// 清理异常退出的 worker 遗留的状态
+		var expired [][]byte
+		c := sb.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var r serverRecord
+			if err := jsonx.Unmarshal(v, &r); err != nil || r.ExpireAt < now.Unix() {
+				expired = append(expired, bytes.Clone(k))
+			}
+		}
+		for _, k := range expired {
+			if err := sb.Delete(k); err != nil {
+				return err
+			}
+		}
+		return sb.Put([]byte(common.ServerInfoKey(info.Host, info.PID, info.ServerID)), encoded)
+	})
+}
+
+// ClearServerState deletes server state data.
+func (b *Broker) ClearServerState(host string, pid int, serverID string) error {
+	var op errors.Op = "bbolt.ClearServerState"
+	return b.update(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketServers).Delete([]byte(common.ServerInfoKey(host, pid, serverID)))
+	})
+}
+
+// WriteResult writes the given result data for the specified task.
+func (b *Broker) WriteResult(qname, taskID string, data []byte) (int, error) {
+	var op errors.Op = "bbolt.WriteResult"
+	err := b.update(op, func(tx *bolt.Tx) error {
+		qb, err := queueBucket(tx, qname)
+		if err != nil {
+			return err
+		}
+		rec, err := getTask(qb, taskID)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("task %s not found", taskID))
+		}
+		rec.Result = data
+		return putTask(qb, taskID, rec)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// ReadResult reads the result written by the task, returns nil if no result.
+func (b *Broker) ReadResult(qname, taskID string) ([]byte, error) {
+	var op errors.Op = "bbolt.ReadResult"
+	var result []byte
+	err := b.view(op, func(tx *bolt.Tx) error {
+		qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+		if qb == nil {
+			return nil
+		}
+		rec, err := getTask(qb, taskID)
+		if err != nil || rec == nil {
+			return err
+		}
+		result = rec.Result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,296 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBroker(t *testing.T) (*Broker, *testClock) {
+	b := NewBroker(filepath.Join(t.TempDir(), "broker.db"))
+	assert.NoError(t, b.Open())
+	t.Cleanup(func() { b.Close() })
+
+	clock := &testClock{now: time.Now()}
+	b.SetClock(clock)
+	return b, clock
+}
+
+func newTestMessage(id string) *task.TaskMessage {
+	return &task.TaskMessage{ID: id, Kind: "async:test", Payload: []byte(`{"a":1}`), Queue: "default", Retry: 3, Timeout: 60}
+}
+
+// taskState 读取任务状态，任务不存在时返回空
+func taskState(t *testing.T, b *Broker, id string) string {
+	var state string
+	assert.NoError(t, b.db.View(func(tx *bolt.Tx) error {
+		rec, err := getTask(tx.Bucket(bucketQueues).Bucket([]byte("default")), id)
+		if rec != nil {
+			state = rec.State
+		}
+		return err
+	}))
+	return state
+}
+
+func statsCount(t *testing.T, b *Broker, name string) int {
+	var n int
+	assert.NoError(t, b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucketQueues).Bucket([]byte("default")).Bucket(bucketStats).Get([]byte(name)); v != nil {
+			n = int(binary.BigEndian.Uint64(v))
+		}
+		return nil
+	}))
+	return n
+}
+
+func TestEnqueueDequeue(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t2")))
+	assert.Equal(t, errors.AlreadyExists, errors.CanonicalCode(b.Enqueue(ctx, newTestMessage("t1"))))
+
+	// 先进先出
+	msg, _, err := b.Dequeue("not-exists", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", msg.ID)
+	assert.Equal(t, []byte(`{"a":1}`), msg.Payload)
+	assert.Equal(t, task.TaskStateActive.String(), taskState(t, b, "t1"))
+
+	msg, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, "t2", msg.ID)
+
+	_, _, err = b.Dequeue("default")
+	assert.True(t, errors.Is(err, errors.ErrNoProcessableTask))
+
+	// 重新入队的任务优先执行
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t3")))
+	assert.NoError(t, b.Requeue(ctx, msg))
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(b.Requeue(ctx, msg)))
+	msg, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, "t2", msg.ID)
+
+	assert.NoError(t, b.Done(ctx, msg))
+	assert.Equal(t, "", taskState(t, b, "t2"))
+	assert.Equal(t, 1, statsCount(t, b, statsProcessed))
+}
+
+func TestEnqueueUnique(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	msg := newTestMessage("t1")
+	msg.UniqueKey = common.UniqueKey("default", msg.Kind, msg.Payload)
+	assert.NoError(t, b.EnqueueUnique(ctx, msg, time.Minute))
+
+	dup := newTestMessage("t2")
+	dup.UniqueKey = msg.UniqueKey
+	assert.True(t, errors.Is(b.EnqueueUnique(ctx, dup, time.Minute), errors.ErrDuplicateTask))
+	assert.True(t, errors.Is(b.ScheduleUnique(ctx, dup, clock.now, time.Minute), errors.ErrDuplicateTask))
+
+	// 任务完成后释放唯一锁
+	_, _, err := b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.NoError(t, b.Done(ctx, msg))
+	assert.NoError(t, b.EnqueueUnique(ctx, dup, time.Minute))
+
+	// 唯一锁过期后可重新获取
+	other := newTestMessage("t3")
+	other.UniqueKey = msg.UniqueKey
+	assert.Error(t, b.EnqueueUnique(ctx, other, time.Minute))
+	clock.Add(2 * time.Minute)
+	assert.NoError(t, b.EnqueueUnique(ctx, other, time.Minute))
+}
+
+func TestScheduleAndRetry(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Schedule(ctx, newTestMessage("t1"), clock.now.Add(time.Minute)))
+	assert.Equal(t, errors.AlreadyExists, errors.CanonicalCode(b.Schedule(ctx, newTestMessage("t1"), clock.now)))
+	assert.Equal(t, task.TaskStateScheduled.String(), taskState(t, b, "t1"))
+
+	assert.NoError(t, b.ForwardIfReady("default"))
+	_, _, err := b.Dequeue("default")
+	assert.True(t, errors.Is(err, errors.ErrNoProcessableTask))
+
+	clock.Add(time.Minute)
+	assert.NoError(t, b.ForwardIfReady("default"))
+	msg, _, err := b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", msg.ID)
+
+	assert.NoError(t, b.Retry(ctx, msg, clock.now.Add(time.Minute), "boom", true))
+	assert.Equal(t, task.TaskStateRetry.String(), taskState(t, b, "t1"))
+	assert.Equal(t, 1, statsCount(t, b, statsFailed))
+
+	clock.Add(time.Minute)
+	assert.NoError(t, b.ForwardIfReady("default"))
+	msg, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, msg.Retried)
+	assert.Equal(t, "boom", msg.ErrorMsg)
+}
+
+func TestArchiveAndComplete(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t2")))
+
+	msg, _, err := b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.NoError(t, b.Archive(ctx, msg, "boom"))
+	assert.Equal(t, task.TaskStateArchived.String(), taskState(t, b, "t1"))
+
+	msg, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+	msg.Retention = 60
+	assert.NoError(t, b.MarkAsComplete(ctx, msg))
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(b.MarkAsComplete(ctx, msg)))
+	assert.Equal(t, task.TaskStateCompleted.String(), taskState(t, b, "t2"))
+	assert.Equal(t, 2, statsCount(t, b, statsProcessed))
+	assert.Equal(t, 1, statsCount(t, b, statsFailed))
+
+	// 保留时间内不会删除
+	assert.NoError(t, b.DeleteExpiredCompletedTasks("default"))
+	assert.Equal(t, task.TaskStateCompleted.String(), taskState(t, b, "t2"))
+	clock.Add(2 * time.Minute)
+	assert.NoError(t, b.DeleteExpiredCompletedTasks("default"))
+	assert.Equal(t, "", taskState(t, b, "t2"))
+
+	// 归档任务过期后在下次归档时清理
+	clock.Add((archivedExpirationInDays + 1) * 24 * time.Hour)
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t3")))
+	msg, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.NoError(t, b.Archive(ctx, msg, "boom"))
+	assert.Equal(t, "", taskState(t, b, "t1"))
+	assert.Equal(t, task.TaskStateArchived.String(), taskState(t, b, "t3"))
+}
+
+func TestLease(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	_, leaseExpiration, err := b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, clock.now.Add(LeaseDuration), leaseExpiration)
+
+	msgs, err := b.ListLeaseExpired(clock.now, "default")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+	msgs, err = b.ListLeaseExpired(leaseExpiration, "default")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+
+	clock.Add(time.Minute)
+	expireAt, err := b.ExtendLease("default", "t1", "not-exists")
+	assert.NoError(t, err)
+	msgs, err = b.ListLeaseExpired(leaseExpiration, "default")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+	msgs, err = b.ListLeaseExpired(expireAt, "default")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+}
+
+func TestResult(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	_, err := b.WriteResult("default", "t1", []byte("ok"))
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	data, err := b.ReadResult("default", "t1")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	n, err := b.WriteResult("default", "t1", []byte("ok"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	data, err = b.ReadResult("default", "t1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), data)
+}
+
+func TestServerState(t *testing.T) {
+	b, clock := newTestBroker(t)
+
+	countServers := func() int {
+		var n int
+		assert.NoError(t, b.db.View(func(tx *bolt.Tx) error {
+			n = countKeys(tx.Bucket(bucketServers))
+			return nil
+		}))
+		return n
+	}
+
+	info := &common.ServerInfo{Host: "host", PID: 1, ServerID: "s1"}
+	workers := []*common.WorkerInfo{{ID: "t1", Queue: "default"}}
+	assert.NoError(t, b.WriteServerState(info, workers, time.Minute))
+	assert.Equal(t, 1, countServers())
+
+	// 过期的状态在其他 worker 上报时清理
+	clock.Add(2 * time.Minute)
+	assert.NoError(t, b.WriteServerState(&common.ServerInfo{Host: "host", PID: 2, ServerID: "s2"}, nil, time.Minute))
+	assert.Equal(t, 1, countServers())
+
+	assert.NoError(t, b.ClearServerState("host", 2, "s2"))
+	assert.Equal(t, 0, countServers())
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broker.db")
+	ctx := context.Background()
+
+	b := NewBroker(path)
+	assert.NoError(t, b.Open())
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, b.Close())
+
+	// 重新打开后任务仍然存在
+	b = NewBroker(path)
+	assert.NoError(t, b.Open())
+	defer b.Close()
+	msg, _, err := b.Dequeue("default")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", msg.ID)
+}
@@ -0,0 +1,444 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+// stateEntry 任务在状态 bucket 中的 key 及 task id
+type stateEntry struct {
+	key []byte
+	id  string
+}
+
+// stateBucket returns the bucket holding the task ids in the given state.
+func stateBucket(qb *bolt.Bucket, state task.TaskState) (*bolt.Bucket, error) {
+	switch state {
+	case task.TaskStatePending:
+		return qb.Bucket(bucketPending), nil
+	case task.TaskStateActive:
+		return qb.Bucket(bucketActive), nil
+	case task.TaskStateScheduled:
+		return qb.Bucket(bucketScheduled), nil
+	case task.TaskStateRetry:
+		return qb.Bucket(bucketRetry), nil
+	case task.TaskStateArchived:
+		return qb.Bucket(bucketArchived), nil
+	case task.TaskStateCompleted:
+		return qb.Bucket(bucketCompleted), nil
+	}
+	return nil, fmt.Errorf("unknown task state: %d", state)
+}
+
+// stateEntries 返回某个状态下的所有任务，pending 按入队时间倒序，active 按 task id，其他按分数升序，与 redis broker 一致
+func stateEntries(sb *bolt.Bucket, state task.TaskState) []stateEntry {
+	var entries []stateEntry
+	c := sb.Cursor()
+	switch state {
+	case task.TaskStatePending:
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			entries = append(entries, stateEntry{key: bytes.Clone(k), id: string(v)})
+		}
+	case task.TaskStateActive:
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			entries = append(entries, stateEntry{key: bytes.Clone(k), id: string(k)})
+		}
+	default:
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			entries = append(entries, stateEntry{key: bytes.Clone(k), id: zsetMember(k)})
+		}
+	}
+	return entries
+}
+
+// removeEntry 将任务移出其所在状态的 bucket，pending 及 zset 的 key 不包含 task id 本身，需要遍历查找
+func removeEntry(qb *bolt.Bucket, state task.TaskState, id string) error {
+	sb, err := stateBucket(qb, state)
+	if err != nil {
+		return err
+	}
+	for _, e := range stateEntries(sb, state) {
+		if e.id == id {
+			return sb.Delete(e.key)
+		}
+	}
+	return nil
+}
+
+// existingQueue 获取已存在的队列的 bucket，队列不存在时返回 NotFound
+func existingQueue(tx *bolt.Tx, op errors.Op, qname string) (*bolt.Bucket, error) {
+	qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+	if qb == nil {
+		return nil, errors.E(op, errors.NotFound, fmt.Sprintf("queue %s not found", qname))
+	}
+	return qb, nil
+}
+
+// findTask 获取任务及其状态，任务不存在时返回 NotFound
+func findTask(tx *bolt.Tx, op errors.Op, qname, id string) (*bolt.Bucket, *taskRecord, task.TaskState, error) {
+	notFound := errors.E(op, errors.NotFound, fmt.Sprintf("task %s not found in queue %s", id, qname))
+	qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+	if qb == nil {
+		return nil, nil, 0, notFound
+	}
+	rec, err := getTask(qb, id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if rec == nil {
+		return nil, nil, 0, notFound
+	}
+	state, err := task.ParseTaskState(rec.State)
+	if err != nil {
+		return nil, nil, 0, errors.E(op, errors.Internal, err)
+	}
+	return qb, rec, state, nil
+}
+
+// runTask 将任务加入待执行列表尾部
+func runTask(qb *bolt.Bucket, id string, rec *taskRecord, now time.Time) error {
+	rec.State = task.TaskStatePending.String()
+	rec.PendingSince = now.UnixNano()
+	if err := putTask(qb, id, rec); err != nil {
+		return err
+	}
+	return pushPending(qb, id, false)
+}
+
+// archiveTask 将任务加入归档列表
+func archiveTask(qb *bolt.Bucket, id string, rec *taskRecord, now time.Time) error {
+	rec.State = task.TaskStateArchived.String()
+	rec.PendingSince = 0
+	if err := putTask(qb, id, rec); err != nil {
+		return err
+	}
+	return qb.Bucket(bucketArchived).Put(zsetKey(now.Unix(), id), nil)
+}
+
+// deleteTask 删除任务数据并释放其持有的唯一锁
+func deleteTask(tx *bolt.Tx, qb *bolt.Bucket, id string, rec *taskRecord) error {
+	if err := releaseUnique(tx, rec.UniqueKey, id); err != nil {
+		return err
+	}
+	return qb.Bucket(bucketTasks).Delete([]byte(id))
+}
+
+func readCounter(sb *bolt.Bucket, key string) int {
+	if v := sb.Get([]byte(key)); v != nil {
+		return int(binary.BigEndian.Uint64(v))
+	}
+	return 0
+}
+
+// AllQueues returns all queue names.
+func (b *Broker) AllQueues() ([]string, error) {
+	var op errors.Op = "bbolt.AllQueues"
+	var qnames []string
+	err := b.view(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketQueues).ForEach(func(k, _ []byte) error {
+			qnames = append(qnames, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return qnames, nil
+}
+
+// CurrentStats returns the task counts of the queue.
+func (b *Broker) CurrentStats(qname string) (*common.QueueStats, error) {
+	var op errors.Op = "bbolt.CurrentStats"
+	now := b.clock.Now()
+	day := now.UTC().Format("2006-01-02")
+	var stats *common.QueueStats
+	err := b.view(op, func(tx *bolt.Tx) error {
+		qb, err := existingQueue(tx, op, qname)
+		if err != nil {
+			return err
+		}
+		sb := qb.Bucket(bucketStats)
+		stats = &common.QueueStats{
+			Queue:          qname,
+			Paused:         tx.Bucket(bucketPaused).Get([]byte(qname)) != nil,
+			Pending:        countKeys(qb.Bucket(bucketPending)),
+			Active:         countKeys(qb.Bucket(bucketActive)),
+			Scheduled:      countKeys(qb.Bucket(bucketScheduled)),
+			Retry:          countKeys(qb.Bucket(bucketRetry)),
+			Archived:       countKeys(qb.Bucket(bucketArchived)),
+			Completed:      countKeys(qb.Bucket(bucketCompleted)),
+			Processed:      readCounter(sb, statsProcessed+":"+day),
+			Failed:         readCounter(sb, statsFailed+":"+day),
+			ProcessedTotal: readCounter(sb, statsProcessed),
+			FailedTotal:    readCounter(sb, statsFailed),
+			Timestamp:      now,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListTasks returns the tasks in the given state, pending tasks are ordered by enqueue time desc,
+// active tasks by task id, others by score (process time, archived time or expire time).
+func (b *Broker) ListTasks(qname string, state task.TaskState, offset, limit int) ([]*task.TaskInfo, error) {
+	var op errors.Op = "bbolt.ListTasks"
+	now := b.clock.Now()
+	var res []*task.TaskInfo
+	err := b.view(op, func(tx *bolt.Tx) error {
+		qb, err := existingQueue(tx, op, qname)
+		if err != nil {
+			return err
+		}
+		sb, err := stateBucket(qb, state)
+		if err != nil {
+			return errors.E(op, errors.FailedPrecondition, err)
+		}
+		entries := stateEntries(sb, state)
+		if limit <= 0 || offset >= len(entries) {
+			return nil
+		}
+		entries = entries[offset:min(offset+limit, len(entries))]
+		for _, e := range entries {
+			rec, err := getTask(qb, e.id)
+			if err != nil {
+				return err
+			}
+			// 任务数据可能已被删除
+			if rec == nil {
+				continue
+			}
+			msg, err := task.DecodeMessage(rec.Msg)
+			if err != nil {
+				return errors.E(op, errors.Internal, fmt.Sprintf("cannot decode message: %v", err))
+			}
+			var nextProcessAt time.Time
+			switch state {
+			case task.TaskStatePending:
+				nextProcessAt = now
+			case task.TaskStateScheduled, task.TaskStateRetry:
+				nextProcessAt = time.Unix(int64(binary.BigEndian.Uint64(e.key[:8])), 0)
+			}
+			res = append(res, task.NewTaskInfo(msg, state, nextProcessAt, rec.Result))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RunTask moves a scheduled, retry or archived task to pending list to run it immediately.
+func (b *Broker) RunTask(qname, id string) error {
+	var op errors.Op = "bbolt.RunTask"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, rec, state, err := findTask(tx, op, qname, id)
+		if err != nil {
+			return err
+		}
+		if state != task.TaskStateScheduled && state != task.TaskStateRetry && state != task.TaskStateArchived {
+			return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("task %s in queue %s is not in expected state", id, qname))
+		}
+		if err = removeEntry(qb, state, id); err != nil {
+			return err
+		}
+		return runTask(qb, id, rec, now)
+	})
+}
+
+// ArchiveTask archives a pending, scheduled or retry task.
+func (b *Broker) ArchiveTask(qname, id string) error {
+	var op errors.Op = "bbolt.ArchiveTask"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, rec, state, err := findTask(tx, op, qname, id)
+		if err != nil {
+			return err
+		}
+		if state != task.TaskStatePending && state != task.TaskStateScheduled && state != task.TaskStateRetry {
+			return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("task %s in queue %s is not in expected state", id, qname))
+		}
+		if err = removeEntry(qb, state, id); err != nil {
+			return err
+		}
+		if err = archiveTask(qb, id, rec, now); err != nil {
+			return err
+		}
+		return trimArchived(qb, now.AddDate(0, 0, -archivedExpirationInDays))
+	})
+}
+
+// DeleteTask deletes a task which is not active.
+func (b *Broker) DeleteTask(qname, id string) error {
+	var op errors.Op = "bbolt.DeleteTask"
+	return b.update(op, func(tx *bolt.Tx) error {
+		qb, rec, state, err := findTask(tx, op, qname, id)
+		if err != nil {
+			return err
+		}
+		if state == task.TaskStateActive {
+			return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("task %s in queue %s is not in expected state", id, qname))
+		}
+		if err = removeEntry(qb, state, id); err != nil {
+			return err
+		}
+		return deleteTask(tx, qb, id, rec)
+	})
+}
+
+// moveAll 将某个状态下的所有任务移出，并逐个交由 fn 处理，返回处理的任务数量
+func (b *Broker) moveAll(op errors.Op, qname string, state task.TaskState, fn func(tx *bolt.Tx, qb *bolt.Bucket, id string, rec *taskRecord) error) (int, error) {
+	var n int
+	err := b.update(op, func(tx *bolt.Tx) error {
+		qb := tx.Bucket(bucketQueues).Bucket([]byte(qname))
+		if qb == nil {
+			return nil
+		}
+		sb, err := stateBucket(qb, state)
+		if err != nil {
+			return errors.E(op, errors.FailedPrecondition, err)
+		}
+		for _, e := range stateEntries(sb, state) {
+			if err = sb.Delete(e.key); err != nil {
+				return err
+			}
+			rec, err := getTask(qb, e.id)
+			if err != nil {
+				return err
+			}
+			if rec == nil {
+				continue
+			}
+			if err = fn(tx, qb, e.id, rec); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// RunAllTasks moves all scheduled, retry or archived tasks to pending list.
+func (b *Broker) RunAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "bbolt.RunAllTasks"
+	if state != task.TaskStateScheduled && state != task.TaskStateRetry && state != task.TaskStateArchived {
+		return 0, errors.E(op, errors.FailedPrecondition, fmt.Sprintf("cannot run %s tasks", state))
+	}
+	now := b.clock.Now()
+	return b.moveAll(op, qname, state, func(_ *bolt.Tx, qb *bolt.Bucket, id string, rec *taskRecord) error {
+		return runTask(qb, id, rec, now)
+	})
+}
+
+// ArchiveAllTasks archives all pending, scheduled or retry tasks.
+func (b *Broker) ArchiveAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "bbolt.ArchiveAllTasks"
+	if state != task.TaskStatePending && state != task.TaskStateScheduled && state != task.TaskStateRetry {
+		return 0, errors.E(op, errors.FailedPrecondition, fmt.Sprintf("cannot archive %s tasks", state))
+	}
+	now := b.clock.Now()
+	n, err := b.moveAll(op, qname, state, func(_ *bolt.Tx, qb *bolt.Bucket, id string, rec *taskRecord) error {
+		return archiveTask(qb, id, rec, now)
+	})
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, b.update(op, func(tx *bolt.Tx) error {
+		return trimArchived(tx.Bucket(bucketQueues).Bucket([]byte(qname)), now.AddDate(0, 0, -archivedExpirationInDays))
+	})
+}
+
+// DeleteAllTasks deletes all tasks in the given state except active.
+func (b *Broker) DeleteAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "bbolt.DeleteAllTasks"
+	if state == task.TaskStateActive {
+		return 0, errors.E(op, errors.FailedPrecondition, "cannot delete active tasks")
+	}
+	return b.moveAll(op, qname, state, deleteTask)
+}
+
+// Pause pauses the queue, paused queue will not be dequeued.
+func (b *Broker) Pause(qname string) error {
+	var op errors.Op = "bbolt.Pause"
+	now := b.clock.Now()
+	return b.update(op, func(tx *bolt.Tx) error {
+		if _, err := existingQueue(tx, op, qname); err != nil {
+			return err
+		}
+		pb := tx.Bucket(bucketPaused)
+		if pb.Get([]byte(qname)) != nil {
+			return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("queue %s is already paused", qname))
+		}
+		return pb.Put([]byte(qname), uint64Bytes(uint64(now.Unix())))
+	})
+}
+
+// Unpause resumes the paused queue.
+func (b *Broker) Unpause(qname string) error {
+	var op errors.Op = "bbolt.Unpause"
+	return b.update(op, func(tx *bolt.Tx) error {
+		pb := tx.Bucket(bucketPaused)
+		if pb.Get([]byte(qname)) == nil {
+			return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("queue %s is not paused", qname))
+		}
+		return pb.Delete([]byte(qname))
+	})
+}
+
+// ListServers returns the servers and their active workers written by WriteServerState.
+func (b *Broker) ListServers() ([]*common.ServerInfo, []*common.WorkerInfo, error) {
+	var op errors.Op = "bbolt.ListServers"
+	now := b.clock.Now().Unix()
+	var (
+		servers []*common.ServerInfo
+		workers []*common.WorkerInfo
+	)
+	err := b.view(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketServers).ForEach(func(_, v []byte) error {
+			var rec serverRecord
+			if err := jsonx.Unmarshal(v, &rec); err != nil || rec.ExpireAt < now {
+				return nil // skip bad or expired data
+			}
+			info, err := common.DecodeServerInfo(rec.Info)
+			if err != nil {
+				return nil // skip bad data
+			}
+			servers = append(servers, info)
+			for _, data := range rec.Workers {
+				w, err := common.DecodeWorkerInfo(data)
+				if err != nil {
+					continue // skip bad data
+				}
+				workers = append(workers, w)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return servers, workers, nil
+}
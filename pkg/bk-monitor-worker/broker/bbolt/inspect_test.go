@@ -0,0 +1,167 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+func TestInspectTasks(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t2")))
+	assert.NoError(t, b.Schedule(ctx, newTestMessage("t3"), clock.now.Add(time.Hour)))
+
+	qnames, err := b.AllQueues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default"}, qnames)
+
+	stats, err := b.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Pending)
+	assert.Equal(t, 1, stats.Scheduled)
+
+	_, err = b.CurrentStats("not-exists")
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+
+	// pending 按入队时间倒序
+	infos, err := b.ListTasks("default", task.TaskStatePending, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, "t2", infos[0].ID)
+	assert.Equal(t, []byte(`{"a":1}`), infos[0].Payload)
+
+	infos, err = b.ListTasks("default", task.TaskStatePending, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "t1", infos[0].ID)
+
+	infos, err = b.ListTasks("default", task.TaskStateScheduled, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, clock.now.Add(time.Hour).Unix(), infos[0].NextProcessAt.Unix())
+
+	// 归档后可以重新执行
+	assert.NoError(t, b.ArchiveTask("default", "t1"))
+	assert.Equal(t, errors.FailedPrecondition, errors.CanonicalCode(b.ArchiveTask("default", "t1")))
+	infos, err = b.ListTasks("default", task.TaskStateArchived, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.NoError(t, b.RunTask("default", "t1"))
+	assert.NoError(t, b.RunTask("default", "t3"))
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(b.RunTask("default", "t4")))
+
+	stats, err = b.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Pending)
+	assert.Equal(t, 0, stats.Scheduled)
+	assert.Equal(t, 0, stats.Archived)
+
+	assert.NoError(t, b.DeleteTask("default", "t2"))
+	assert.Equal(t, "", taskState(t, b, "t2"))
+	n, err := b.ArchiveAllTasks("default", task.TaskStatePending)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = b.RunAllTasks("default", task.TaskStateArchived)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, err = b.RunAllTasks("default", task.TaskStatePending)
+	assert.Error(t, err)
+	n, err = b.DeleteAllTasks("default", task.TaskStatePending)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	stats, err = b.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Pending)
+
+	_, _, err = b.Dequeue("default")
+	assert.True(t, errors.Is(err, errors.ErrNoProcessableTask))
+}
+
+func TestInspectActiveAndUnique(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	msg := newTestMessage("t1")
+	msg.UniqueKey = "unique:t1"
+	assert.NoError(t, b.EnqueueUnique(ctx, msg, time.Hour))
+	_, _, err := b.Dequeue("default")
+	assert.NoError(t, err)
+
+	infos, err := b.ListTasks("default", task.TaskStateActive, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, errors.FailedPrecondition, errors.CanonicalCode(b.DeleteTask("default", "t1")))
+
+	// 删除任务后释放唯一锁
+	assert.NoError(t, b.Archive(ctx, msg, "boom"))
+	infos, err = b.ListTasks("default", task.TaskStateArchived, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "boom", infos[0].LastErr)
+	assert.NoError(t, b.DeleteTask("default", "t1"))
+	msg = newTestMessage("t2")
+	msg.UniqueKey = "unique:t1"
+	assert.NoError(t, b.EnqueueUnique(ctx, msg, time.Hour))
+}
+
+func TestPauseQueue(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(b.Pause("default")))
+	assert.NoError(t, b.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, b.Pause("default"))
+	assert.Error(t, b.Pause("default"))
+
+	_, _, err := b.Dequeue("default")
+	assert.True(t, errors.Is(err, errors.ErrNoProcessableTask))
+
+	stats, err := b.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.True(t, stats.Paused)
+
+	assert.NoError(t, b.Unpause("default"))
+	assert.Error(t, b.Unpause("default"))
+	_, _, err = b.Dequeue("default")
+	assert.NoError(t, err)
+}
+
+func TestListServers(t *testing.T) {
+	b, clock := newTestBroker(t)
+
+	info := &common.ServerInfo{Host: "host", PID: 1, ServerID: "s1", Queues: map[string]int{"default": 1}, Started: clock.now}
+	workers := []*common.WorkerInfo{{Host: "host", PID: 1, ServerID: "s1", ID: "t1", Queue: "default", Started: clock.now, Deadline: clock.now}}
+	assert.NoError(t, b.WriteServerState(info, workers, time.Minute))
+
+	servers, ws, err := b.ListServers()
+	assert.NoError(t, err)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, "s1", servers[0].ServerID)
+	assert.Len(t, ws, 1)
+	assert.Equal(t, "t1", ws[0].ID)
+
+	// 过期的状态不再返回
+	clock.Add(2 * time.Minute)
+	servers, _, err = b.ListServers()
+	assert.NoError(t, err)
+	assert.Len(t, servers, 0)
+}
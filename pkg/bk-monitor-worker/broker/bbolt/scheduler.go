@@ -0,0 +1,166 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+// maxSchedulerEvents 每个调度项保留的入队事件数
+const maxSchedulerEvents = 1000
+
+// schedulerRecord 调度器的调度项快照
+type schedulerRecord struct {
+	Entries  [][]byte `json:"entries"`
+	ExpireAt int64    `json:"expire_at"`
+}
+
+// WriteSchedulerEntries writes the entries of the scheduler with expiration set to ttl.
+func (b *Broker) WriteSchedulerEntries(schedulerID string, entries []*common.SchedulerEntry, ttl time.Duration) error {
+	var op errors.Op = "bbolt.WriteSchedulerEntries"
+	now := b.clock.Now()
+	rec := schedulerRecord{ExpireAt: now.Add(ttl).Unix()}
+	for _, e := range entries {
+		data, err := common.EncodeSchedulerEntry(e)
+		if err != nil {
+			continue // skip bad data
+		}
+		rec.Entries = append(rec.Entries, data)
+	}
+	encoded, err := jsonx.Marshal(rec)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode scheduler entries: %v", err))
+	}
+	return b.update(op, func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketSchedulers)
+		// 清理异常退出的调度器遗留的调度项
+		var expired [][]byte
+		c := sb.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var r schedulerRecord
+			if err := jsonx.Unmarshal(v, &r); err != nil || r.ExpireAt < now.Unix() {
+				expired = append(expired, bytes.Clone(k))
+			}
+		}
+		for _, k := range expired {
+			if err := sb.Delete(k); err != nil {
+				return err
+			}
+		}
+		return sb.Put([]byte(schedulerID), encoded)
+	})
+}
+
+// ClearSchedulerEntries deletes the entries of the scheduler.
+func (b *Broker) ClearSchedulerEntries(schedulerID string) error {
+	var op errors.Op = "bbolt.ClearSchedulerEntries"
+	return b.update(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSchedulers).Delete([]byte(schedulerID))
+	})
+}
+
+// RecordSchedulerEnqueueEvent records the time when the task of the entry was enqueued,
+// only the latest maxSchedulerEvents events are kept.
+func (b *Broker) RecordSchedulerEnqueueEvent(entryID string, event *common.SchedulerEnqueueEvent) error {
+	var op errors.Op = "bbolt.RecordSchedulerEnqueueEvent"
+	data, err := common.EncodeSchedulerEnqueueEvent(event)
+	if err != nil {
+		return errors.E(op, errors.Internal, fmt.Sprintf("cannot encode scheduler enqueue event: %v", err))
+	}
+	return b.update(op, func(tx *bolt.Tx) error {
+		hb, err := tx.Bucket(bucketSchedHistory).CreateBucketIfNotExists([]byte(entryID))
+		if err != nil {
+			return err
+		}
+		if err = hb.Put(zsetKey(event.EnqueuedAt.Unix(), event.TaskID), data); err != nil {
+			return err
+		}
+		// 按入队时间删除最早的事件
+		c := hb.Cursor()
+		for k, _ := c.First(); k != nil && countKeys(hb) > maxSchedulerEvents; k, _ = c.First() {
+			if err = hb.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClearSchedulerHistory deletes the enqueue event history of the entry.
+func (b *Broker) ClearSchedulerHistory(entryID string) error {
+	var op errors.Op = "bbolt.ClearSchedulerHistory"
+	return b.update(op, func(tx *bolt.Tx) error {
+		err := tx.Bucket(bucketSchedHistory).DeleteBucket([]byte(entryID))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// daemonTaskKey 以序列化内容的 sha256 作为 key，与 redis set 一致，相同内容的常驻任务只保存一份
+func daemonTaskKey(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// AddDaemonTask saves the serialized daemon task, does nothing if it already exists.
+func (b *Broker) AddDaemonTask(data []byte) error {
+	var op errors.Op = "bbolt.AddDaemonTask"
+	return b.update(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDaemonTasks).Put(daemonTaskKey(data), data)
+	})
+}
+
+// RemoveDaemonTask deletes the serialized daemon task.
+func (b *Broker) RemoveDaemonTask(data []byte) error {
+	var op errors.Op = "bbolt.RemoveDaemonTask"
+	return b.update(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDaemonTasks).Delete(daemonTaskKey(data))
+	})
+}
+
+// RemoveAllDaemonTasks deletes all daemon tasks.
+func (b *Broker) RemoveAllDaemonTasks() error {
+	var op errors.Op = "bbolt.RemoveAllDaemonTasks"
+	return b.update(op, func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucketDaemonTasks); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(bucketDaemonTasks)
+		return err
+	})
+}
+
+// ListDaemonTasks returns all serialized daemon tasks.
+func (b *Broker) ListDaemonTasks() ([][]byte, error) {
+	var op errors.Op = "bbolt.ListDaemonTasks"
+	var res [][]byte
+	err := b.view(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDaemonTasks).ForEach(func(_, v []byte) error {
+			res = append(res, bytes.Clone(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
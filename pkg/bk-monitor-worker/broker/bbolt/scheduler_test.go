@@ -0,0 +1,83 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+)
+
+func TestSchedulerState(t *testing.T) {
+	b, clock := newTestBroker(t)
+
+	count := func(name []byte, entryID string) int {
+		var n int
+		assert.NoError(t, b.db.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(name)
+			if entryID != "" {
+				bucket = bucket.Bucket([]byte(entryID))
+			}
+			if bucket != nil {
+				n = countKeys(bucket)
+			}
+			return nil
+		}))
+		return n
+	}
+
+	entries := []*common.SchedulerEntry{{ID: "e1", Spec: "* * * * *", Kind: "periodic:test"}}
+	assert.NoError(t, b.WriteSchedulerEntries("s1", entries, 5*time.Second))
+	assert.Equal(t, 1, count(bucketSchedulers, ""))
+
+	// 过期的调度项在其他调度器上报时清理
+	clock.Add(time.Minute)
+	assert.NoError(t, b.WriteSchedulerEntries("s2", entries, 5*time.Second))
+	assert.Equal(t, 1, count(bucketSchedulers, ""))
+	assert.NoError(t, b.ClearSchedulerEntries("s2"))
+	assert.Equal(t, 0, count(bucketSchedulers, ""))
+
+	// 入队事件只保留最近的 maxSchedulerEvents 条
+	for i := 0; i <= maxSchedulerEvents; i++ {
+		event := &common.SchedulerEnqueueEvent{TaskID: fmt.Sprintf("t%d", i), EnqueuedAt: clock.Now()}
+		assert.NoError(t, b.RecordSchedulerEnqueueEvent("e1", event))
+		clock.Add(time.Second)
+	}
+	assert.Equal(t, maxSchedulerEvents, count(bucketSchedHistory, "e1"))
+	assert.NoError(t, b.ClearSchedulerHistory("e1"))
+	assert.Equal(t, 0, count(bucketSchedHistory, "e1"))
+	assert.NoError(t, b.ClearSchedulerHistory("e1"))
+}
+
+func TestDaemonTasks(t *testing.T) {
+	b, _ := newTestBroker(t)
+
+	assert.NoError(t, b.AddDaemonTask([]byte(`{"kind":"daemon:a"}`)))
+	assert.NoError(t, b.AddDaemonTask([]byte(`{"kind":"daemon:a"}`)))
+	assert.NoError(t, b.AddDaemonTask([]byte(`{"kind":"daemon:b"}`)))
+	tasks, err := b.ListDaemonTasks()
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	assert.NoError(t, b.RemoveDaemonTask([]byte(`{"kind":"daemon:a"}`)))
+	tasks, err = b.ListDaemonTasks()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"kind":"daemon:b"}`)}, tasks)
+
+	assert.NoError(t, b.RemoveAllDaemonTasks())
+	tasks, err = b.ListDaemonTasks()
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
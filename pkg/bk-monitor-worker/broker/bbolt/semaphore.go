@@ -0,0 +1,68 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+// AcquireSemaphore acquires a slot of the named semaphore for the holder until expireAt.
+func (b *Broker) AcquireSemaphore(_ context.Context, name, holder string, limit int, expireAt time.Time) (bool, error) {
+	var op errors.Op = "bbolt.AcquireSemaphore"
+	now := b.clock.Now().Unix()
+	var ok bool
+	err := b.update(op, func(tx *bolt.Tx) error {
+		sb, err := tx.Bucket(bucketSemaphores).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		// 释放过期的持有者
+		var expired [][]byte
+		c := sb.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, bytes.Clone(k))
+			}
+		}
+		for _, k := range expired {
+			if err = sb.Delete(k); err != nil {
+				return err
+			}
+		}
+		if sb.Get([]byte(holder)) == nil && countKeys(sb) >= limit {
+			return nil
+		}
+		ok = true
+		return sb.Put([]byte(holder), uint64Bytes(uint64(expireAt.Unix())))
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// ReleaseSemaphore releases the slot held by the holder.
+func (b *Broker) ReleaseSemaphore(_ context.Context, name, holder string) error {
+	var op errors.Op = "bbolt.ReleaseSemaphore"
+	return b.update(op, func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketSemaphores).Bucket([]byte(name))
+		if sb == nil {
+			return nil
+		}
+		return sb.Delete([]byte(holder))
+	})
+}
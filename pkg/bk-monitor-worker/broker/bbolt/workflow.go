@@ -0,0 +1,316 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+// workflowRecord 任务编排的定义及状态，与 redis broker 中编排 hash 的字段一致
+type workflowRecord struct {
+	ID         string                   `json:"id"`
+	Steps      [][]*task.SerializerTask `json:"steps"`
+	State      task.WorkflowState       `json:"state"`
+	Step       int                      `json:"step"`
+	Pending    int                      `json:"pending"`
+	TaskStates map[string]int           `json:"task_states"`
+	Results    map[string][]byte        `json:"results"`
+	ErrorMsg   string                   `json:"error,omitempty"`
+	FailedTask string                   `json:"failed_task,omitempty"`
+	CreatedAt  int64                    `json:"created_at"`
+	UpdatedAt  int64                    `json:"updated_at"`
+}
+
+func workflowTaskField(step, index int) string {
+	return fmt.Sprintf("%d:%d", step, index)
+}
+
+// getWorkflow 获取编排，不存在或已过期时返回 nil
+func getWorkflow(tx *bolt.Tx, id string, now int64) (*workflowRecord, error) {
+	data := tx.Bucket(bucketWorkflows).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	rec := new(workflowRecord)
+	if err := jsonx.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("cannot decode workflow %s: %v", id, err)
+	}
+	if rec.CreatedAt+int64(common.DefaultWorkflowRetention.Seconds()) < now {
+		return nil, nil
+	}
+	return rec, nil
+}
+
+func putWorkflow(tx *bolt.Tx, rec *workflowRecord) error {
+	data, err := jsonx.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("cannot encode workflow %s: %v", rec.ID, err)
+	}
+	return tx.Bucket(bucketWorkflows).Put([]byte(rec.ID), data)
+}
+
+// pruneWorkflows 删除超过保留时间的编排
+func pruneWorkflows(tx *bolt.Tx, now int64) error {
+	ib, wb := tx.Bucket(bucketWorkflowIndex), tx.Bucket(bucketWorkflows)
+	for _, k := range zsetRangeByScore(ib, now-int64(common.DefaultWorkflowRetention.Seconds()), 0) {
+		if err := ib.Delete(k); err != nil {
+			return err
+		}
+		if err := wb.Delete([]byte(zsetMember(k))); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketWorkflowAdv).Delete([]byte(zsetMember(k))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *workflowRecord) toMessage() *task.WorkflowMessage {
+	msg := &task.WorkflowMessage{
+		ID:         r.ID,
+		Steps:      r.Steps,
+		State:      r.State,
+		Step:       r.Step,
+		ErrorMsg:   r.ErrorMsg,
+		FailedTask: r.FailedTask,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		TaskStates: make([][]task.WorkflowState, len(r.Steps)),
+		Results:    make([][][]byte, len(r.Steps)),
+	}
+	for step, tasks := range r.Steps {
+		msg.TaskStates[step] = make([]task.WorkflowState, len(tasks))
+		msg.Results[step] = make([][]byte, len(tasks))
+		for index := range tasks {
+			// 仅记录已结束任务的状态，其余根据所在阶段推断
+			state := task.WorkflowStatePending
+			if v, ok := r.TaskStates[workflowTaskField(step, index)]; ok {
+				state = task.WorkflowState(v)
+			} else if step == r.Step {
+				state = task.WorkflowStateRunning
+			}
+			msg.TaskStates[step][index] = state
+			msg.Results[step][index] = r.Results[workflowTaskField(step, index)]
+		}
+	}
+	return msg
+}
+
+// CreateWorkflow saves a new workflow, the first step is running after created.
+func (b *Broker) CreateWorkflow(_ context.Context, msg *task.WorkflowMessage) error {
+	var op errors.Op = "bbolt.CreateWorkflow"
+	if len(msg.Steps) == 0 || len(msg.Steps[0]) == 0 {
+		return errors.E(op, errors.FailedPrecondition, "workflow has no task")
+	}
+	now := b.clock.Now().Unix()
+	return b.update(op, func(tx *bolt.Tx) error {
+		if err := pruneWorkflows(tx, now); err != nil {
+			return err
+		}
+		rec, err := getWorkflow(tx, msg.ID, now)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			return errors.E(op, errors.AlreadyExists, fmt.Sprintf("workflow %s already exists", msg.ID))
+		}
+		rec = &workflowRecord{
+			ID:         msg.ID,
+			Steps:      msg.Steps,
+			State:      task.WorkflowStateRunning,
+			Pending:    len(msg.Steps[0]),
+			TaskStates: make(map[string]int),
+			Results:    make(map[string][]byte),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err = putWorkflow(tx, rec); err != nil {
+			return err
+		}
+		if err = tx.Bucket(bucketWorkflowAdv).Put([]byte(msg.ID), uint64Bytes(uint64(now))); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketWorkflowIndex).Put(zsetKey(now, msg.ID), nil)
+	})
+}
+
+// GetWorkflow returns the definition and state of the workflow.
+func (b *Broker) GetWorkflow(_ context.Context, id string) (*task.WorkflowMessage, error) {
+	var op errors.Op = "bbolt.GetWorkflow"
+	now := b.clock.Now().Unix()
+	var msg *task.WorkflowMessage
+	err := b.view(op, func(tx *bolt.Tx) error {
+		rec, err := getWorkflow(tx, id, now)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			return errors.E(op, errors.NotFound, fmt.Sprintf("workflow %s not found", id))
+		}
+		msg = rec.toMessage()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ListWorkflows returns the workflows order by created time desc.
+func (b *Broker) ListWorkflows(_ context.Context, offset, limit int) ([]*task.WorkflowMessage, error) {
+	var op errors.Op = "bbolt.ListWorkflows"
+	now := b.clock.Now().Unix()
+	var res []*task.WorkflowMessage
+	err := b.view(op, func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketWorkflowIndex).Cursor()
+		i := 0
+		for k, _ := c.Last(); k != nil && len(res) < limit; k, _ = c.Prev() {
+			rec, err := getWorkflow(tx, zsetMember(k), now)
+			if err != nil {
+				return err
+			}
+			// 已过期
+			if rec == nil {
+				continue
+			}
+			if i++; i <= offset {
+				continue
+			}
+			res = append(res, rec.toMessage())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CompleteWorkflowTask records the result of a succeeded task, the workflow is recorded as
+// advancing until all tasks of the next step are enqueued.
+func (b *Broker) CompleteWorkflowTask(_ context.Context, id string, step, index int, result []byte, nextStepSize int) (bool, error) {
+	var op errors.Op = "bbolt.CompleteWorkflowTask"
+	now := b.clock.Now().Unix()
+	var stepDone bool
+	err := b.update(op, func(tx *bolt.Tx) error {
+		rec, err := getWorkflow(tx, id, now)
+		if err != nil {
+			return err
+		}
+		// 编排未在运行或任务已记录时忽略
+		if rec == nil || rec.State != task.WorkflowStateRunning || rec.Step != step {
+			return nil
+		}
+		field := workflowTaskField(step, index)
+		if _, ok := rec.TaskStates[field]; ok {
+			return nil
+		}
+		rec.TaskStates[field] = int(task.WorkflowStateSucceeded)
+		if len(result) > 0 {
+			rec.Results[field] = result
+		}
+		rec.UpdatedAt = now
+		rec.Pending--
+		if rec.Pending <= 0 {
+			stepDone = true
+			if nextStepSize == 0 {
+				rec.State = task.WorkflowStateSucceeded
+			} else {
+				rec.Step = step + 1
+				rec.Pending = nextStepSize
+				if err = tx.Bucket(bucketWorkflowAdv).Put([]byte(id), uint64Bytes(uint64(now))); err != nil {
+					return err
+				}
+			}
+		}
+		return putWorkflow(tx, rec)
+	})
+	if err != nil {
+		return false, err
+	}
+	return stepDone, nil
+}
+
+// FailWorkflow marks the workflow failed, the following steps will not be enqueued.
+func (b *Broker) FailWorkflow(_ context.Context, id string, step, index int, taskID, errMsg string) (bool, error) {
+	var op errors.Op = "bbolt.FailWorkflow"
+	now := b.clock.Now().Unix()
+	var failed bool
+	err := b.update(op, func(tx *bolt.Tx) error {
+		rec, err := getWorkflow(tx, id, now)
+		if err != nil {
+			return err
+		}
+		if rec == nil || rec.State != task.WorkflowStateRunning {
+			return nil
+		}
+		rec.TaskStates[workflowTaskField(step, index)] = int(task.WorkflowStateFailed)
+		rec.State = task.WorkflowStateFailed
+		rec.FailedTask = taskID
+		rec.ErrorMsg = errMsg
+		rec.UpdatedAt = now
+		failed = true
+		if err = tx.Bucket(bucketWorkflowAdv).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return putWorkflow(tx, rec)
+	})
+	if err != nil {
+		return false, err
+	}
+	return failed, nil
+}
+
+// MarkWorkflowStepEnqueued records that all tasks of the step are enqueued.
+func (b *Broker) MarkWorkflowStepEnqueued(_ context.Context, id string, step int) error {
+	var op errors.Op = "bbolt.MarkWorkflowStepEnqueued"
+	now := b.clock.Now().Unix()
+	return b.update(op, func(tx *bolt.Tx) error {
+		rec, err := getWorkflow(tx, id, now)
+		if err != nil {
+			return err
+		}
+		// 编排已推进到其他阶段，该阶段的记录不再属于本次下发
+		if rec != nil && rec.State == task.WorkflowStateRunning && rec.Step != step {
+			return nil
+		}
+		return tx.Bucket(bucketWorkflowAdv).Delete([]byte(id))
+	})
+}
+
+// ListAdvancingWorkflows returns the IDs of workflows whose current step has not been
+// enqueued completely since the cutoff.
+func (b *Broker) ListAdvancingWorkflows(_ context.Context, cutoff time.Time) ([]string, error) {
+	var op errors.Op = "bbolt.ListAdvancingWorkflows"
+	var ids []string
+	err := b.view(op, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWorkflowAdv).ForEach(func(k, v []byte) error {
+			if int64(binary.BigEndian.Uint64(v)) <= cutoff.Unix() {
+				ids = append(ids, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
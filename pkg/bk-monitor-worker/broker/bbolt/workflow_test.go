@@ -0,0 +1,178 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package bbolt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+func newTestWorkflow(id string, sizes ...int) *task.WorkflowMessage {
+	msg := &task.WorkflowMessage{ID: id}
+	for _, size := range sizes {
+		var tasks []*task.SerializerTask
+		for i := 0; i < size; i++ {
+			tasks = append(tasks, &task.SerializerTask{Kind: "async:test", Options: task.Options{Queue: "default"}})
+		}
+		msg.Steps = append(msg.Steps, tasks)
+	}
+	return msg
+}
+
+func TestWorkflowSucceeded(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	// chord: 两个任务并行，全部成功后执行回调
+	assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow("w1", 2, 1)))
+	assert.Error(t, b.CreateWorkflow(ctx, newTestWorkflow("w1", 1)))
+	assert.Equal(t, errors.FailedPrecondition, errors.CanonicalCode(b.CreateWorkflow(ctx, newTestWorkflow("w0"))))
+
+	msg, err := b.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateRunning, msg.State)
+	assert.Equal(t, [][]task.WorkflowState{
+		{task.WorkflowStateRunning, task.WorkflowStateRunning},
+		{task.WorkflowStatePending},
+	}, msg.TaskStates)
+
+	done, err := b.CompleteWorkflowTask(ctx, "w1", 0, 1, []byte("b"), 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	// 重复上报以及非当前阶段的任务会被忽略
+	done, err = b.CompleteWorkflowTask(ctx, "w1", 0, 1, []byte("b"), 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	done, err = b.CompleteWorkflowTask(ctx, "w1", 1, 0, nil, 0)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	done, err = b.CompleteWorkflowTask(ctx, "w1", 0, 0, []byte("a"), 1)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	msg, err = b.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, msg.Step)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, msg.Results[0])
+	assert.Equal(t, task.WorkflowStateRunning, msg.TaskStates[1][0])
+
+	done, err = b.CompleteWorkflowTask(ctx, "w1", 1, 0, nil, 0)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	msg, err = b.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateSucceeded, msg.State)
+	assert.Equal(t, task.WorkflowStateSucceeded, msg.TaskStates[1][0])
+	assert.Nil(t, msg.Results[1][0])
+}
+
+func TestWorkflowFailed(t *testing.T) {
+	b, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	// chain: 第一个任务失败后整个编排失败，后续结果不再记录
+	assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow("w2", 1, 1)))
+
+	failed, err := b.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	assert.True(t, failed)
+	failed, err = b.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	assert.False(t, failed)
+
+	done, err := b.CompleteWorkflowTask(ctx, "w2", 0, 0, nil, 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	msg, err := b.GetWorkflow(ctx, "w2")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateFailed, msg.State)
+	assert.Equal(t, "boom", msg.ErrorMsg)
+	assert.Equal(t, "wf:w2:0:0", msg.FailedTask)
+	assert.Equal(t, [][]task.WorkflowState{{task.WorkflowStateFailed}, {task.WorkflowStatePending}}, msg.TaskStates)
+
+	_, err = b.GetWorkflow(ctx, "not-exists")
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+}
+
+func TestListWorkflows(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"w1", "w2", "w3"} {
+		assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow(id, 1)))
+		clock.Add(time.Second)
+	}
+
+	// 按创建时间倒序
+	msgs, err := b.ListWorkflows(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, "w2", msgs[0].ID)
+	assert.Equal(t, "w1", msgs[1].ID)
+
+	// 超过保留时间的编排在创建新编排时清理
+	clock.Add(common.DefaultWorkflowRetention)
+	_, err = b.GetWorkflow(ctx, "w1")
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+	assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow("w1", 1)))
+	msgs, err = b.ListWorkflows(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+}
+
+func TestAdvancingWorkflows(t *testing.T) {
+	b, clock := newTestBroker(t)
+	ctx := context.Background()
+
+	// 创建后第一阶段未下发完成
+	assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow("w1", 1, 1)))
+	ids, err := b.ListAdvancingWorkflows(ctx, clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w1"}, ids)
+	ids, err = b.ListAdvancingWorkflows(ctx, clock.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.NoError(t, b.MarkWorkflowStepEnqueued(ctx, "w1", 0))
+	ids, err = b.ListAdvancingWorkflows(ctx, clock.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 进入下一阶段时与任务结果一起记录，过期的阶段不会清除记录
+	done, err := b.CompleteWorkflowTask(ctx, "w1", 0, 0, nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.NoError(t, b.MarkWorkflowStepEnqueued(ctx, "w1", 0))
+	ids, err = b.ListAdvancingWorkflows(ctx, clock.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w1"}, ids)
+	assert.NoError(t, b.MarkWorkflowStepEnqueued(ctx, "w1", 1))
+	ids, err = b.ListAdvancingWorkflows(ctx, clock.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 编排失败后不再推进
+	assert.NoError(t, b.CreateWorkflow(ctx, newTestWorkflow("w2", 1)))
+	_, err = b.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	ids, err = b.ListAdvancingWorkflows(ctx, clock.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
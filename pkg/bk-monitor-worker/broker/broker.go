@@ -17,6 +17,12 @@ import (
 	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
 )
 
+// broker types
+const (
+	TypeRedis = "redis"
+	TypeBbolt = "bbolt"
+)
+
 // Broker is a message broker interface
 type Broker interface {
 	// Open opens a broker connection
@@ -58,3 +64,87 @@ type Broker interface {
 	// WriteResult writes the given result data for the specified task.
 	WriteResult(qname, id string, data []byte) (n int, err error)
 }
+
+// WorkflowBroker 任务编排状态的存储接口
+type WorkflowBroker interface {
+	// CreateWorkflow saves a new workflow whose first step is about to be enqueued
+	CreateWorkflow(ctx context.Context, msg *task.WorkflowMessage) error
+	// GetWorkflow returns the definition and state of the workflow
+	GetWorkflow(ctx context.Context, id string) (*task.WorkflowMessage, error)
+	// ListWorkflows returns the workflows order by created time desc
+	ListWorkflows(ctx context.Context, offset, limit int) ([]*task.WorkflowMessage, error)
+	// CompleteWorkflowTask records the result of a succeeded task, returns true if it is the last
+	// unfinished task of the current step, then the workflow moves to the next step or succeeds
+	// when nextStepSize is 0
+	CompleteWorkflowTask(ctx context.Context, id string, step, index int, result []byte, nextStepSize int) (bool, error)
+	// FailWorkflow marks the workflow failed by the given task
+	FailWorkflow(ctx context.Context, id string, step, index int, taskID, errMsg string) (bool, error)
+	// MarkWorkflowStepEnqueued records that all tasks of the current step are enqueued, a workflow
+	// is advancing after it is created or moves to the next step until then
+	MarkWorkflowStepEnqueued(ctx context.Context, id string, step int) error
+	// ListAdvancingWorkflows returns the IDs of workflows which are advancing since the cutoff
+	ListAdvancingWorkflows(ctx context.Context, cutoff time.Time) ([]string, error)
+	// ReadResult reads the result written by the task
+	ReadResult(qname, id string) ([]byte, error)
+}
+
+// SemaphoreBroker 分布式信号量，用于限制任务类型在所有 worker 中的并发数
+type SemaphoreBroker interface {
+	// AcquireSemaphore acquires a slot of the named semaphore for the holder until expireAt,
+	// returns false if all the slots are held by others
+	AcquireSemaphore(ctx context.Context, name, holder string, limit int, expireAt time.Time) (bool, error)
+	// ReleaseSemaphore releases the slot held by the holder
+	ReleaseSemaphore(ctx context.Context, name, holder string) error
+}
+
+// InspectBroker 队列巡检接口，查看队列及任务状态，并对任务进行重新执行、归档及删除操作
+type InspectBroker interface {
+	// AllQueues returns all queue names
+	AllQueues() ([]string, error)
+	// CurrentStats returns the task counts of the queue
+	CurrentStats(qname string) (*common.QueueStats, error)
+	// ListTasks returns the tasks in the given state
+	ListTasks(qname string, state task.TaskState, offset, limit int) ([]*task.TaskInfo, error)
+	// RunTask moves a scheduled, retry or archived task to pending list to run it immediately
+	RunTask(qname, id string) error
+	// ArchiveTask archives a pending, scheduled or retry task
+	ArchiveTask(qname, id string) error
+	// DeleteTask deletes a task which is not active
+	DeleteTask(qname, id string) error
+	// RunAllTasks moves all scheduled, retry or archived tasks to pending list
+	RunAllTasks(qname string, state task.TaskState) (int, error)
+	// ArchiveAllTasks archives all pending, scheduled or retry tasks
+	ArchiveAllTasks(qname string, state task.TaskState) (int, error)
+	// DeleteAllTasks deletes all tasks in the given state except active
+	DeleteAllTasks(qname string, state task.TaskState) (int, error)
+	// Pause pauses the queue, Dequeue skips the paused queue
+	Pause(qname string) error
+	// Unpause resumes the paused queue
+	Unpause(qname string) error
+	// ListServers returns the alive servers and their active workers
+	ListServers() ([]*common.ServerInfo, []*common.WorkerInfo, error)
+}
+
+// SchedulerBroker 周期任务调度器的状态存储接口，记录调度项及入队历史
+type SchedulerBroker interface {
+	// WriteSchedulerEntries writes the entries of the scheduler with expiration set to ttl
+	WriteSchedulerEntries(schedulerID string, entries []*common.SchedulerEntry, ttl time.Duration) error
+	// ClearSchedulerEntries deletes the entries of the scheduler
+	ClearSchedulerEntries(schedulerID string) error
+	// RecordSchedulerEnqueueEvent records the time when the task of the entry was enqueued
+	RecordSchedulerEnqueueEvent(entryID string, event *common.SchedulerEnqueueEvent) error
+	// ClearSchedulerHistory deletes the enqueue event history of the entry
+	ClearSchedulerHistory(entryID string) error
+}
+
+// DaemonTaskBroker 常驻任务的存储接口，单机部署时常驻任务全部由 worker 进程执行，不需要分配
+type DaemonTaskBroker interface {
+	// AddDaemonTask saves the serialized daemon task, does nothing if it already exists
+	AddDaemonTask(data []byte) error
+	// RemoveDaemonTask deletes the serialized daemon task
+	RemoveDaemonTask(data []byte) error
+	// RemoveAllDaemonTasks deletes all daemon tasks
+	RemoveAllDaemonTasks() error
+	// ListDaemonTasks returns all serialized daemon tasks
+	ListDaemonTasks() ([][]byte, error)
+}
@@ -0,0 +1,502 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/spf13/cast"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+// stateKey returns the redis key holding the task ids in the given state,
+// pending and active tasks are stored in list, others in sorted set.
+func stateKey(qname string, state task.TaskState) (key string, isList bool, err error) {
+	switch state {
+	case task.TaskStatePending:
+		return common.PendingKey(qname), true, nil
+	case task.TaskStateActive:
+		return common.ActiveKey(qname), true, nil
+	case task.TaskStateScheduled:
+		return common.ScheduledKey(qname), false, nil
+	case task.TaskStateRetry:
+		return common.RetryKey(qname), false, nil
+	case task.TaskStateArchived:
+		return common.ArchivedKey(qname), false, nil
+	case task.TaskStateCompleted:
+		return common.CompletedKey(qname), false, nil
+	}
+	return "", false, fmt.Errorf("unknown task state: %d", state)
+}
+
+func (r *RDB) checkQueueExists(ctx context.Context, op errors.Op, qname string) error {
+	exists, err := r.client.SIsMember(ctx, common.AllQueues, qname).Result()
+	if err != nil {
+		return errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "sismember", Err: err})
+	}
+	if !exists {
+		return errors.E(op, errors.NotFound, fmt.Sprintf("queue %s not found", qname))
+	}
+	return nil
+}
+
+// AllQueues returns all queue names.
+func (r *RDB) AllQueues() ([]string, error) {
+	var op errors.Op = "rdb.AllQueues"
+	qnames, err := r.client.SMembers(context.Background(), common.AllQueues).Result()
+	if err != nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "smembers", Err: err})
+	}
+	return qnames, nil
+}
+
+// CurrentStats returns the task counts of the queue.
+func (r *RDB) CurrentStats(qname string) (*common.QueueStats, error) {
+	var op errors.Op = "rdb.CurrentStats"
+	ctx := context.Background()
+	if err := r.checkQueueExists(ctx, op, qname); err != nil {
+		return nil, err
+	}
+	now := r.clock.Now()
+	pipe := r.client.Pipeline()
+	pending := pipe.LLen(ctx, common.PendingKey(qname))
+	active := pipe.LLen(ctx, common.ActiveKey(qname))
+	scheduled := pipe.ZCard(ctx, common.ScheduledKey(qname))
+	retry := pipe.ZCard(ctx, common.RetryKey(qname))
+	archived := pipe.ZCard(ctx, common.ArchivedKey(qname))
+	completed := pipe.ZCard(ctx, common.CompletedKey(qname))
+	processed := pipe.Get(ctx, common.ProcessedKey(qname, now))
+	failed := pipe.Get(ctx, common.FailedKey(qname, now))
+	processedTotal := pipe.Get(ctx, common.ProcessedTotalKey(qname))
+	failedTotal := pipe.Get(ctx, common.FailedTotalKey(qname))
+	paused := pipe.Exists(ctx, common.PausedKey(qname))
+	// 统计 key 不存在时返回 redis.Nil，视为 0
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "pipeline", Err: err})
+	}
+	return &common.QueueStats{
+		Queue:          qname,
+		Paused:         paused.Val() == 1,
+		Pending:        int(pending.Val()),
+		Active:         int(active.Val()),
+		Scheduled:      int(scheduled.Val()),
+		Retry:          int(retry.Val()),
+		Archived:       int(archived.Val()),
+		Completed:      int(completed.Val()),
+		Processed:      cast.ToInt(processed.Val()),
+		Failed:         cast.ToInt(failed.Val()),
+		ProcessedTotal: cast.ToInt(processedTotal.Val()),
+		FailedTotal:    cast.ToInt(failedTotal.Val()),
+		Timestamp:      now,
+	}, nil
+}
+
+// ListTasks returns the tasks in the given state, pending and active tasks are
+// ordered by enqueue time desc, others by score (process time, archived time or expire time).
+func (r *RDB) ListTasks(qname string, state task.TaskState, offset, limit int) ([]*task.TaskInfo, error) {
+	var op errors.Op = "rdb.ListTasks"
+	ctx := context.Background()
+	if err := r.checkQueueExists(ctx, op, qname); err != nil {
+		return nil, err
+	}
+	key, isList, err := stateKey(qname, state)
+	if err != nil {
+		return nil, errors.E(op, errors.FailedPrecondition, err)
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var ids []string
+	scores := make(map[string]float64)
+	start, stop := int64(offset), int64(offset+limit-1)
+	if isList {
+		ids, err = r.client.LRange(ctx, key, start, stop).Result()
+		if err != nil {
+			return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "lrange", Err: err})
+		}
+	} else {
+		zs, err := r.client.ZRangeWithScores(ctx, key, start, stop).Result()
+		if err != nil {
+			return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrange", Err: err})
+		}
+		for _, z := range zs {
+			id := cast.ToString(z.Member)
+			ids = append(ids, id)
+			scores[id] = z.Score
+		}
+	}
+
+	now := r.clock.Now()
+	var res []*task.TaskInfo
+	for _, id := range ids {
+		vals, err := r.client.HMGet(ctx, common.TaskKey(qname, id), "msg", "result").Result()
+		if err != nil {
+			return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "hmget", Err: err})
+		}
+		// 任务数据可能已过期
+		if vals[0] == nil {
+			continue
+		}
+		msg, err := task.DecodeMessage([]byte(cast.ToString(vals[0])))
+		if err != nil {
+			return nil, errors.E(op, errors.Internal, fmt.Sprintf("cannot decode message: %v", err))
+		}
+		var result []byte
+		if vals[1] != nil {
+			result = []byte(cast.ToString(vals[1]))
+		}
+		var nextProcessAt time.Time
+		switch state {
+		case task.TaskStatePending:
+			nextProcessAt = now
+		case task.TaskStateScheduled, task.TaskStateRetry:
+			nextProcessAt = time.Unix(int64(scores[id]), 0)
+		}
+		res = append(res, task.NewTaskInfo(msg, state, nextProcessAt, result))
+	}
+	return res, nil
+}
+
+// runTaskCmd moves a scheduled, retry or archived task to pending list.
+//
+// KEYS[1] -> bmw:{<qname>}:t:<task_id>
+// KEYS[2] -> bmw:{<qname>}:pending
+// --
+// ARGV[1] -> task ID
+// ARGV[2] -> queue key prefix
+//
+// Output:
+// Returns 1 if successfully moved
+// Returns 0 if task not found
+// Returns -1 if task is not in scheduled, retry or archived state
+var runTaskCmd = redis.NewScript(`
+local state = redis.call("HGET", KEYS[1], "state")
+if not state then
+	return 0
+end
+if state ~= "scheduled" and state ~= "retry" and state ~= "archived" then
+	return -1
+end
+redis.call("ZREM", ARGV[2] .. state, ARGV[1])
+redis.call("LPUSH", KEYS[2], ARGV[1])
+redis.call("HSET", KEYS[1], "state", "pending")
+return 1
+`)
+
+// archiveTaskCmd moves a pending, scheduled or retry task to archived set.
+//
+// KEYS[1] -> bmw:{<qname>}:t:<task_id>
+// KEYS[2] -> bmw:{<qname>}:archived
+// --
+// ARGV[1] -> task ID
+// ARGV[2] -> queue key prefix
+// ARGV[3] -> current unix time
+// ARGV[4] -> archived task expiration in seconds
+//
+// Output:
+// Returns 1 if successfully archived
+// Returns 0 if task not found
+// Returns -1 if task is not in pending, scheduled or retry state
+var archiveTaskCmd = redis.NewScript(`
+local state = redis.call("HGET", KEYS[1], "state")
+if not state then
+	return 0
+end
+if state == "pending" then
+	redis.call("LREM", ARGV[2] .. state, 0, ARGV[1])
+elseif state == "scheduled" or state == "retry" then
+	redis.call("ZREM", ARGV[2] .. state, ARGV[1])
+else
+	return -1
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[1])
+redis.call("HSET", KEYS[1], "state", "archived")
+redis.call("EXPIRE", KEYS[1], ARGV[4])
+return 1
+`)
+
+// deleteTaskCmd deletes a task which is not active.
+//
+// KEYS[1] -> bmw:{<qname>}:t:<task_id>
+// --
+// ARGV[1] -> task ID
+// ARGV[2] -> queue key prefix
+//
+// Output:
+// Returns 1 if successfully deleted
+// Returns 0 if task not found
+// Returns -1 if task is active
+var deleteTaskCmd = redis.NewScript(`
+local state = redis.call("HGET", KEYS[1], "state")
+if not state then
+	return 0
+end
+if state == "active" then
+	return -1
+end
+if state == "pending" then
+	redis.call("LREM", ARGV[2] .. state, 0, ARGV[1])
+else
+	redis.call("ZREM", ARGV[2] .. state, ARGV[1])
+end
+local uniqueKey = redis.call("HGET", KEYS[1], "unique_key")
+if uniqueKey and redis.call("GET", uniqueKey) == ARGV[1] then
+	redis.call("DEL", uniqueKey)
+end
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
+func (r *RDB) runTaskScript(op errors.Op, script *redis.Script, qname, id string, keys []string, args ...interface{}) error {
+	n, err := r.runScriptWithErrorCode(context.Background(), op, script, keys, args...)
+	if err != nil {
+		return err
+	}
+	switch n {
+	case 0:
+		return errors.E(op, errors.NotFound, fmt.Sprintf("task %s not found in queue %s", id, qname))
+	case -1:
+		return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("task %s in queue %s is not in expected state", id, qname))
+	}
+	return nil
+}
+
+// RunTask moves a scheduled, retry or archived task to pending list to run it immediately.
+func (r *RDB) RunTask(qname, id string) error {
+	var op errors.Op = "rdb.RunTask"
+	keys := []string{common.TaskKey(qname, id), common.PendingKey(qname)}
+	return r.runTaskScript(op, runTaskCmd, qname, id, keys, id, common.QueueKeyPrefix(qname))
+}
+
+// ArchiveTask archives a pending, scheduled or retry task.
+func (r *RDB) ArchiveTask(qname, id string) error {
+	var op errors.Op = "rdb.ArchiveTask"
+	keys := []string{common.TaskKey(qname, id), common.ArchivedKey(qname)}
+	return r.runTaskScript(
+		op, archiveTaskCmd, qname, id, keys,
+		id, common.QueueKeyPrefix(qname), r.clock.Now().Unix(), archivedExpirationInDays*24*60*60,
+	)
+}
+
+// DeleteTask deletes a task which is not active.
+func (r *RDB) DeleteTask(qname, id string) error {
+	var op errors.Op = "rdb.DeleteTask"
+	keys := []string{common.TaskKey(qname, id)}
+	return r.runTaskScript(op, deleteTaskCmd, qname, id, keys, id, common.QueueKeyPrefix(qname))
+}
+
+// runAllCmd moves all tasks in the sorted set to pending list.
+//
+// KEYS[1] -> bmw:{<qname>}:<scheduled|retry|archived>
+// KEYS[2] -> bmw:{<qname>}:pending
+// --
+// ARGV[1] -> task key prefix
+var runAllCmd = redis.NewScript(`
+local ids = redis.call("ZRANGE", KEYS[1], 0, -1)
+for _, id in ipairs(ids) do
+	redis.call("LPUSH", KEYS[2], id)
+	redis.call("HSET", ARGV[1] .. id, "state", "pending")
+end
+redis.call("DEL", KEYS[1])
+return table.getn(ids)
+`)
+
+// archiveAllCmd moves all tasks in the list or sorted set to archived set.
+//
+// KEYS[1] -> bmw:{<qname>}:<pending|scheduled|retry>
+// KEYS[2] -> bmw:{<qname>}:archived
+// --
+// ARGV[1] -> task key prefix
+// ARGV[2] -> current unix time
+// ARGV[3] -> archived task expiration in seconds
+// ARGV[4] -> 1 if KEYS[1] is a list
+var archiveAllCmd = redis.NewScript(`
+local ids
+if ARGV[4] == "1" then
+	ids = redis.call("LRANGE", KEYS[1], 0, -1)
+else
+	ids = redis.call("ZRANGE", KEYS[1], 0, -1)
+end
+for _, id in ipairs(ids) do
+	local key = ARGV[1] .. id
+	redis.call("ZADD", KEYS[2], ARGV[2], id)
+	redis.call("HSET", key, "state", "archived")
+	redis.call("EXPIRE", key, ARGV[3])
+end
+redis.call("DEL", KEYS[1])
+return table.getn(ids)
+`)
+
+// deleteAllCmd deletes all tasks in the list or sorted set.
+//
+// KEYS[1] -> bmw:{<qname>}:<pending|scheduled|retry|archived|completed>
+// --
+// ARGV[1] -> task key prefix
+// ARGV[2] -> 1 if KEYS[1] is a list
+var deleteAllCmd = redis.NewScript(`
+local ids
+if ARGV[2] == "1" then
+	ids = redis.call("LRANGE", KEYS[1], 0, -1)
+else
+	ids = redis.call("ZRANGE", KEYS[1], 0, -1)
+end
+for _, id in ipairs(ids) do
+	local key = ARGV[1] .. id
+	local uniqueKey = redis.call("HGET", key, "unique_key")
+	if uniqueKey and redis.call("GET", uniqueKey) == id then
+		redis.call("DEL", uniqueKey)
+	end
+	redis.call("DEL", key)
+end
+redis.call("DEL", KEYS[1])
+return table.getn(ids)
+`)
+
+func boolArg(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// RunAllTasks moves all scheduled, retry or archived tasks to pending list.
+func (r *RDB) RunAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "rdb.RunAllTasks"
+	key, _, err := stateKey(qname, state)
+	if err != nil {
+		return 0, errors.E(op, errors.FailedPrecondition, err)
+	}
+	if state != task.TaskStateScheduled && state != task.TaskStateRetry && state != task.TaskStateArchived {
+		return 0, errors.E(op, errors.FailedPrecondition, fmt.Sprintf("cannot run %s tasks", state))
+	}
+	keys := []string{key, common.PendingKey(qname)}
+	n, err := r.runScriptWithErrorCode(context.Background(), op, runAllCmd, keys, common.TaskKeyPrefix(qname))
+	return int(n), err
+}
+
+// ArchiveAllTasks archives all pending, scheduled or retry tasks.
+func (r *RDB) ArchiveAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "rdb.ArchiveAllTasks"
+	key, isList, err := stateKey(qname, state)
+	if err != nil {
+		return 0, errors.E(op, errors.FailedPrecondition, err)
+	}
+	if state != task.TaskStatePending && state != task.TaskStateScheduled && state != task.TaskStateRetry {
+		return 0, errors.E(op, errors.FailedPrecondition, fmt.Sprintf("cannot archive %s tasks", state))
+	}
+	keys := []string{key, common.ArchivedKey(qname)}
+	argv := []interface{}{
+		common.TaskKeyPrefix(qname),
+		r.clock.Now().Unix(),
+		archivedExpirationInDays * 24 * 60 * 60,
+		boolArg(isList),
+	}
+	n, err := r.runScriptWithErrorCode(context.Background(), op, archiveAllCmd, keys, argv...)
+	return int(n), err
+}
+
+// DeleteAllTasks deletes all tasks in the given state except active.
+func (r *RDB) DeleteAllTasks(qname string, state task.TaskState) (int, error) {
+	var op errors.Op = "rdb.DeleteAllTasks"
+	key, isList, err := stateKey(qname, state)
+	if err != nil {
+		return 0, errors.E(op, errors.FailedPrecondition, err)
+	}
+	if state == task.TaskStateActive {
+		return 0, errors.E(op, errors.FailedPrecondition, "cannot delete active tasks")
+	}
+	keys := []string{key}
+	n, err := r.runScriptWithErrorCode(context.Background(), op, deleteAllCmd, keys, common.TaskKeyPrefix(qname), boolArg(isList))
+	return int(n), err
+}
+
+// Pause pauses the queue, paused queue will not be dequeued.
+func (r *RDB) Pause(qname string) error {
+	var op errors.Op = "rdb.Pause"
+	ctx := context.Background()
+	if err := r.checkQueueExists(ctx, op, qname); err != nil {
+		return err
+	}
+	ok, err := r.client.SetNX(ctx, common.PausedKey(qname), r.clock.Now().Unix(), 0).Result()
+	if err != nil {
+		return errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "setnx", Err: err})
+	}
+	if !ok {
+		return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("queue %s is already paused", qname))
+	}
+	return nil
+}
+
+// Unpause resumes the paused queue.
+func (r *RDB) Unpause(qname string) error {
+	var op errors.Op = "rdb.Unpause"
+	n, err := r.client.Del(context.Background(), common.PausedKey(qname)).Result()
+	if err != nil {
+		return errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "del", Err: err})
+	}
+	if n == 0 {
+		return errors.E(op, errors.FailedPrecondition, fmt.Sprintf("queue %s is not paused", qname))
+	}
+	return nil
+}
+
+// ListServers returns the servers and their active workers written by WriteServerState.
+func (r *RDB) ListServers() ([]*common.ServerInfo, []*common.WorkerInfo, error) {
+	var op errors.Op = "rdb.ListServers"
+	ctx := context.Background()
+	now := fmt.Sprintf("%d", r.clock.Now().Unix())
+
+	serverKeys, err := r.client.ZRangeByScore(ctx, common.AllServers, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrangebyscore", Err: err})
+	}
+	var servers []*common.ServerInfo
+	for _, key := range serverKeys {
+		data, err := r.client.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "get", Err: err})
+		}
+		info, err := common.DecodeServerInfo(data)
+		if err != nil {
+			continue // skip bad data
+		}
+		servers = append(servers, info)
+	}
+
+	workerKeys, err := r.client.ZRangeByScore(ctx, common.AllWorkers, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrangebyscore", Err: err})
+	}
+	var workers []*common.WorkerInfo
+	for _, key := range workerKeys {
+		values, err := r.client.HVals(ctx, key).Result()
+		if err != nil {
+			return nil, nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "hvals", Err: err})
+		}
+		for _, v := range values {
+			info, err := common.DecodeWorkerInfo([]byte(v))
+			if err != nil {
+				continue // skip bad data
+			}
+			workers = append(workers, info)
+		}
+	}
+	return servers, workers, nil
+}
@@ -0,0 +1,147 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+func newTestMessage(id string) *task.TaskMessage {
+	return &task.TaskMessage{ID: id, Kind: "async:test", Payload: []byte(`{"a":1}`), Queue: "default", Retry: 3, Timeout: 60}
+}
+
+func TestInspectTasks(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+
+	assert.NoError(t, r.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, r.Enqueue(ctx, newTestMessage("t2")))
+	assert.NoError(t, r.Schedule(ctx, newTestMessage("t3"), time.Now().Add(time.Hour)))
+
+	qnames, err := r.AllQueues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default"}, qnames)
+
+	stats, err := r.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Pending)
+	assert.Equal(t, 1, stats.Scheduled)
+
+	_, err = r.CurrentStats("not-exists")
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+
+	infos, err := r.ListTasks("default", task.TaskStatePending, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 2)
+	assert.Equal(t, []byte(`{"a":1}`), infos[0].Payload)
+
+	infos, err = r.ListTasks("default", task.TaskStateScheduled, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.False(t, infos[0].NextProcessAt.IsZero())
+
+	// 归档后可以重新执行
+	assert.NoError(t, r.ArchiveTask("default", "t1"))
+	assert.Equal(t, errors.FailedPrecondition, errors.CanonicalCode(r.ArchiveTask("default", "t1")))
+	infos, err = r.ListTasks("default", task.TaskStateArchived, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.NoError(t, r.RunTask("default", "t1"))
+	assert.NoError(t, r.RunTask("default", "t3"))
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(r.RunTask("default", "t4")))
+
+	stats, err = r.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Pending)
+	assert.Equal(t, 0, stats.Scheduled)
+	assert.Equal(t, 0, stats.Archived)
+
+	assert.NoError(t, r.DeleteTask("default", "t2"))
+	n, err := r.ArchiveAllTasks("default", task.TaskStatePending)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = r.RunAllTasks("default", task.TaskStateArchived)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, err = r.RunAllTasks("default", task.TaskStatePending)
+	assert.Error(t, err)
+	n, err = r.DeleteAllTasks("default", task.TaskStatePending)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	stats, err = r.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Pending)
+}
+
+func TestArchiveKeepsMessage(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+
+	msg := newTestMessage("t1")
+	assert.NoError(t, r.Enqueue(ctx, msg))
+	_, _, err := r.Dequeue("default")
+	assert.NoError(t, err)
+	assert.NoError(t, r.Archive(ctx, msg, "boom"))
+
+	infos, err := r.ListTasks("default", task.TaskStateArchived, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "boom", infos[0].LastErr)
+}
+
+func TestPauseQueue(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+
+	assert.NoError(t, r.Enqueue(ctx, newTestMessage("t1")))
+	assert.NoError(t, r.Pause("default"))
+	assert.Error(t, r.Pause("default"))
+
+	_, _, err := r.Dequeue("default")
+	assert.True(t, errors.Is(err, errors.ErrNoProcessableTask))
+
+	stats, err := r.CurrentStats("default")
+	assert.NoError(t, err)
+	assert.True(t, stats.Paused)
+
+	assert.NoError(t, r.Unpause("default"))
+	assert.Error(t, r.Unpause("default"))
+	_, _, err = r.Dequeue("default")
+	assert.NoError(t, err)
+}
+
+func TestListServers(t *testing.T) {
+	r := newTestRDB(t)
+
+	info := &common.ServerInfo{Host: "host", PID: 1, ServerID: "s1", Queues: map[string]int{"default": 1}, Started: time.Now()}
+	workers := []*common.WorkerInfo{{Host: "host", PID: 1, ServerID: "s1", ID: "t1", Queue: "default", Started: time.Now(), Deadline: time.Now()}}
+	assert.NoError(t, r.WriteServerState(info, workers, time.Minute))
+
+	servers, ws, err := r.ListServers()
+	assert.NoError(t, err)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, "s1", servers[0].ServerID)
+	assert.Len(t, ws, 1)
+	assert.Equal(t, "t1", ws[0].ID)
+
+	assert.NoError(t, r.ClearServerState("host", 1, "s1"))
+	servers, _, err = r.ListServers()
+	assert.NoError(t, err)
+	assert.Len(t, servers, 0)
+}
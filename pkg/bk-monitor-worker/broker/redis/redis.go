@@ -775,13 +775,15 @@ const (
 // ARGV[5] -> max number of tasks in archive (e.g., 100)
 // ARGV[6] -> stats expiration timestamp
 // ARGV[7] -> max int64 value
+// ARGV[8] -> archived task expiration in seconds
 var archiveCmd = redis.NewScript(`
 redis.call("LREM", KEYS[2], 0, ARGV[1])
 redis.call("ZREM", KEYS[3], ARGV[1])
 redis.call("ZADD", KEYS[4], ARGV[3], ARGV[1])
 redis.call("ZREMRANGEBYSCORE", KEYS[4], "-inf", ARGV[4])
 redis.call("ZREMRANGEBYRANK", KEYS[4], 0, -ARGV[5])
-redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], "msg", ARGV[2], "state", "archived")
+redis.call("EXPIRE", KEYS[1], ARGV[8])
 local n = redis.call("INCR", KEYS[5])
 if tonumber(n) == 1 then
 	redis.call("EXPIREAT", KEYS[5], ARGV[6])
@@ -832,6 +834,7 @@ func (r *RDB) Archive(ctx context.Context, msg *task.TaskMessage, errMsg string)
 		maxArchiveSize,
 		expireAt.Unix(),
 		int64(math.MaxInt64),
+		archivedExpirationInDays * 24 * 60 * 60,
 	}
 	return r.runScript(ctx, op, archiveCmd, keys, argv...)
 }
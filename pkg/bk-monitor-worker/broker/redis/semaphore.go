@@ -0,0 +1,68 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+)
+
+// acquireSemaphoreCmd acquires a slot of the semaphore, the expired slots are released first.
+//
+// KEYS[1] -> bmw:semaphores:{<name>}
+// --
+// ARGV[1] -> holder
+// ARGV[2] -> max number of holders
+// ARGV[3] -> expiration of the slot in unix time
+// ARGV[4] -> current unix time
+//
+// Output:
+// Returns 1 if the slot is acquired or already held by the holder
+// Returns 0 if the semaphore is full
+var acquireSemaphoreCmd = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[4])
+if not redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[2]) then
+		return 0
+	end
+end
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// AcquireSemaphore acquires a slot of the named semaphore for the holder until expireAt.
+func (r *RDB) AcquireSemaphore(ctx context.Context, name, holder string, limit int, expireAt time.Time) (bool, error) {
+	var op errors.Op = "rdb.AcquireSemaphore"
+	argv := []interface{}{
+		holder,
+		limit,
+		expireAt.Unix(),
+		r.clock.Now().Unix(),
+	}
+	n, err := r.runScriptWithErrorCode(ctx, op, acquireSemaphoreCmd, []string{common.SemaphoreKey(name)}, argv...)
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ReleaseSemaphore releases the slot held by the holder.
+func (r *RDB) ReleaseSemaphore(ctx context.Context, name, holder string) error {
+	var op errors.Op = "rdb.ReleaseSemaphore"
+	if err := r.client.ZRem(ctx, common.SemaphoreKey(name), holder).Err(); err != nil {
+		return errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrem", Err: err})
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphore(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+	expireAt := time.Now().Add(time.Minute)
+
+	ok, err := r.AcquireSemaphore(ctx, "async:test", "t1", 2, expireAt)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.AcquireSemaphore(ctx, "async:test", "t2", 2, expireAt)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 已满时只有持有者可以重复获取
+	ok, err = r.AcquireSemaphore(ctx, "async:test", "t3", 2, expireAt)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = r.AcquireSemaphore(ctx, "async:test", "t1", 2, expireAt)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, r.ReleaseSemaphore(ctx, "async:test", "t1"))
+	ok, err = r.AcquireSemaphore(ctx, "async:test", "t3", 2, expireAt)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 过期的持有者自动释放
+	ok, err = r.AcquireSemaphore(ctx, "async:other", "t1", 1, time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.AcquireSemaphore(ctx, "async:other", "t2", 1, expireAt)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,350 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/spf13/cast"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+// workflowDefinition 任务编排的定义，创建后不再变更
+type workflowDefinition struct {
+	ID        string                   `json:"id"`
+	Steps     [][]*task.SerializerTask `json:"steps"`
+	CreatedAt int64                    `json:"created_at"`
+}
+
+func workflowTaskField(step, index int) string {
+	return fmt.Sprintf("task:%d:%d", step, index)
+}
+
+func workflowResultField(step, index int) string {
+	return fmt.Sprintf("result:%d:%d", step, index)
+}
+
+// createWorkflowCmd saves the workflow definition and its initial state.
+//
+// KEYS[1] -> bmw:workflows:{<workflow_id>}
+// KEYS[2] -> bmw:workflows
+// KEYS[3] -> bmw:advancing_workflows
+// --
+// ARGV[1] -> workflow definition data
+// ARGV[2] -> workflow ID
+// ARGV[3] -> number of tasks in the first step
+// ARGV[4] -> running state
+// ARGV[5] -> current unix time
+// ARGV[6] -> retention in seconds
+//
+// Output:
+// Returns 1 if successfully created
+// Returns 0 if workflow ID already exists
+var createWorkflowCmd = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1],
+           "msg", ARGV[1],
+           "state", ARGV[4],
+           "step", 0,
+           "pending", ARGV[3],
+           "created_at", ARGV[5],
+           "updated_at", ARGV[5])
+redis.call("EXPIRE", KEYS[1], ARGV[6])
+redis.call("ZADD", KEYS[2], ARGV[5], ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[5] - ARGV[6])
+redis.call("ZADD", KEYS[3], ARGV[5], ARGV[2])
+return 1
+`)
+
+// CreateWorkflow saves a new workflow, the first step is running after created.
+func (r *RDB) CreateWorkflow(ctx context.Context, msg *task.WorkflowMessage) error {
+	var op errors.Op = "rdb.CreateWorkflow"
+	if len(msg.Steps) == 0 || len(msg.Steps[0]) == 0 {
+		return errors.E(op, errors.FailedPrecondition, "workflow has no task")
+	}
+	now := r.clock.Now().Unix()
+	encoded, err := jsonx.Marshal(workflowDefinition{ID: msg.ID, Steps: msg.Steps, CreatedAt: now})
+	if err != nil {
+		return errors.E(op, errors.Unknown, fmt.Sprintf("cannot encode workflow: %v", err))
+	}
+	keys := []string{common.WorkflowKey(msg.ID), common.AllWorkflows, common.AdvancingWorkflows}
+	argv := []interface{}{
+		encoded,
+		msg.ID,
+		len(msg.Steps[0]),
+		int(task.WorkflowStateRunning),
+		now,
+		int64(common.DefaultWorkflowRetention.Seconds()),
+	}
+	n, err := r.runScriptWithErrorCode(ctx, op, createWorkflowCmd, keys, argv...)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.E(op, errors.AlreadyExists, fmt.Sprintf("workflow %s already exists", msg.ID))
+	}
+	return nil
+}
+
+// GetWorkflow returns the definition and state of the workflow.
+func (r *RDB) GetWorkflow(ctx context.Context, id string) (*task.WorkflowMessage, error) {
+	var op errors.Op = "rdb.GetWorkflow"
+	values, err := r.client.HGetAll(ctx, common.WorkflowKey(id)).Result()
+	if err != nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "hgetall", Err: err})
+	}
+	if len(values) == 0 {
+		return nil, errors.E(op, errors.NotFound, fmt.Sprintf("workflow %s not found", id))
+	}
+	var def workflowDefinition
+	if err = jsonx.Unmarshal([]byte(values["msg"]), &def); err != nil {
+		return nil, errors.E(op, errors.Internal, fmt.Sprintf("cannot decode workflow: %v", err))
+	}
+
+	msg := &task.WorkflowMessage{
+		ID:         def.ID,
+		Steps:      def.Steps,
+		State:      task.WorkflowState(cast.ToInt(values["state"])),
+		Step:       cast.ToInt(values["step"]),
+		ErrorMsg:   values["error"],
+		FailedTask: values["failed_task"],
+		CreatedAt:  cast.ToInt64(values["created_at"]),
+		UpdatedAt:  cast.ToInt64(values["updated_at"]),
+		TaskStates: make([][]task.WorkflowState, len(def.Steps)),
+		Results:    make([][][]byte, len(def.Steps)),
+	}
+	for step, tasks := range def.Steps {
+		msg.TaskStates[step] = make([]task.WorkflowState, len(tasks))
+		msg.Results[step] = make([][]byte, len(tasks))
+		for index := range tasks {
+			// 仅记录已结束任务的状态，其余根据所在阶段推断
+			state := task.WorkflowStatePending
+			if v, ok := values[workflowTaskField(step, index)]; ok {
+				state = task.WorkflowState(cast.ToInt(v))
+			} else if step == msg.Step {
+				state = task.WorkflowStateRunning
+			}
+			msg.TaskStates[step][index] = state
+			if v, ok := values[workflowResultField(step, index)]; ok {
+				msg.Results[step][index] = []byte(v)
+			}
+		}
+	}
+	return msg, nil
+}
+
+// ListWorkflows returns the workflows order by created time desc.
+func (r *RDB) ListWorkflows(ctx context.Context, offset, limit int) ([]*task.WorkflowMessage, error) {
+	var op errors.Op = "rdb.ListWorkflows"
+	ids, err := r.client.ZRevRange(ctx, common.AllWorkflows, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrevrange", Err: err})
+	}
+	var res []*task.WorkflowMessage
+	for _, id := range ids {
+		msg, err := r.GetWorkflow(ctx, id)
+		if errors.CanonicalCode(err) == errors.NotFound {
+			// 已过期
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, msg)
+	}
+	return res, nil
+}
+
+// completeWorkflowTaskCmd records the result of the task and moves to the next
+// step if all tasks of the current step are succeeded, the workflow is recorded as
+// advancing until all tasks of the next step are enqueued.
+//
+// KEYS[1] -> bmw:workflows:{<workflow_id>}
+// KEYS[2] -> bmw:advancing_workflows
+// --
+// ARGV[1] -> step of the task
+// ARGV[2] -> task state field
+// ARGV[3] -> task result field
+// ARGV[4] -> task result data, empty if the task wrote no result
+// ARGV[5] -> number of tasks in the next step, 0 if it is the last step
+// ARGV[6] -> running state
+// ARGV[7] -> succeeded state
+// ARGV[8] -> current unix time
+// ARGV[9] -> workflow ID
+//
+// Output:
+// Returns 1 if the current step is finished
+// Returns 0 if there are unfinished tasks in the current step
+// Returns -1 if the task is ignored (workflow not running or task already recorded)
+var completeWorkflowTaskCmd = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "state") ~= ARGV[6] then
+	return -1
+end
+if tonumber(redis.call("HGET", KEYS[1], "step")) ~= tonumber(ARGV[1]) then
+	return -1
+end
+if redis.call("HEXISTS", KEYS[1], ARGV[2]) == 1 then
+	return -1
+end
+redis.call("HSET", KEYS[1], ARGV[2], ARGV[7], "updated_at", ARGV[8])
+if string.len(ARGV[4]) > 0 then
+	redis.call("HSET", KEYS[1], ARGV[3], ARGV[4])
+end
+if redis.call("HINCRBY", KEYS[1], "pending", -1) > 0 then
+	return 0
+end
+if tonumber(ARGV[5]) == 0 then
+	redis.call("HSET", KEYS[1], "state", ARGV[7])
+else
+	redis.call("HSET", KEYS[1], "step", tonumber(ARGV[1]) + 1, "pending", ARGV[5])
+	redis.call("ZADD", KEYS[2], ARGV[8], ARGV[9])
+end
+return 1
+`)
+
+// CompleteWorkflowTask records the result of a succeeded task.
+func (r *RDB) CompleteWorkflowTask(ctx context.Context, id string, step, index int, result []byte, nextStepSize int) (bool, error) {
+	var op errors.Op = "rdb.CompleteWorkflowTask"
+	keys := []string{common.WorkflowKey(id), common.AdvancingWorkflows}
+	argv := []interface{}{
+		step,
+		workflowTaskField(step, index),
+		workflowResultField(step, index),
+		result,
+		nextStepSize,
+		int(task.WorkflowStateRunning),
+		int(task.WorkflowStateSucceeded),
+		r.clock.Now().Unix(),
+		id,
+	}
+	n, err := r.runScriptWithErrorCode(ctx, op, completeWorkflowTaskCmd, keys, argv...)
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// failWorkflowCmd marks the workflow failed.
+//
+// KEYS[1] -> bmw:workflows:{<workflow_id>}
+// KEYS[2] -> bmw:advancing_workflows
+// --
+// ARGV[1] -> task state field
+// ARGV[2] -> task ID
+// ARGV[3] -> error message
+// ARGV[4] -> running state
+// ARGV[5] -> failed state
+// ARGV[6] -> current unix time
+// ARGV[7] -> workflow ID
+//
+// Output:
+// Returns 1 if the workflow is marked failed
+// Returns 0 if the workflow is not running
+var failWorkflowCmd = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "state") ~= ARGV[4] then
+	return 0
+end
+redis.call("HSET", KEYS[1],
+           ARGV[1], ARGV[5],
+           "state", ARGV[5],
+           "failed_task", ARGV[2],
+           "error", ARGV[3],
+           "updated_at", ARGV[6])
+redis.call("ZREM", KEYS[2], ARGV[7])
+return 1
+`)
+
+// FailWorkflow marks the workflow failed, the following steps will not be enqueued.
+func (r *RDB) FailWorkflow(ctx context.Context, id string, step, index int, taskID, errMsg string) (bool, error) {
+	var op errors.Op = "rdb.FailWorkflow"
+	keys := []string{common.WorkflowKey(id), common.AdvancingWorkflows}
+	argv := []interface{}{
+		workflowTaskField(step, index),
+		taskID,
+		errMsg,
+		int(task.WorkflowStateRunning),
+		int(task.WorkflowStateFailed),
+		r.clock.Now().Unix(),
+		id,
+	}
+	n, err := r.runScriptWithErrorCode(ctx, op, failWorkflowCmd, keys, argv...)
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// markWorkflowStepEnqueuedCmd removes the workflow from the advancing set after
+// all tasks of its current step are enqueued, workflows not running are removed too.
+//
+// KEYS[1] -> bmw:workflows:{<workflow_id>}
+// KEYS[2] -> bmw:advancing_workflows
+// --
+// ARGV[1] -> step
+// ARGV[2] -> running state
+// ARGV[3] -> workflow ID
+//
+// Output:
+// Returns 1 if removed
+// Returns 0 if the workflow has moved to another step
+var markWorkflowStepEnqueuedCmd = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "state") == ARGV[2] and
+   tonumber(redis.call("HGET", KEYS[1], "step")) ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[3])
+return 1
+`)
+
+// MarkWorkflowStepEnqueued records that all tasks of the step are enqueued.
+func (r *RDB) MarkWorkflowStepEnqueued(ctx context.Context, id string, step int) error {
+	var op errors.Op = "rdb.MarkWorkflowStepEnqueued"
+	keys := []string{common.WorkflowKey(id), common.AdvancingWorkflows}
+	argv := []interface{}{step, int(task.WorkflowStateRunning), id}
+	_, err := r.runScriptWithErrorCode(ctx, op, markWorkflowStepEnqueuedCmd, keys, argv...)
+	return err
+}
+
+// ListAdvancingWorkflows returns the IDs of workflows whose current step has not been
+// enqueued completely since the cutoff.
+func (r *RDB) ListAdvancingWorkflows(ctx context.Context, cutoff time.Time) ([]string, error) {
+	var op errors.Op = "rdb.ListAdvancingWorkflows"
+	ids, err := r.client.ZRangeByScore(ctx, common.AdvancingWorkflows, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(cutoff.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "zrangebyscore", Err: err})
+	}
+	return ids, nil
+}
+
+// ReadResult reads the result written by the task, returns nil if no result.
+func (r *RDB) ReadResult(qname, taskID string) ([]byte, error) {
+	var op errors.Op = "rdb.ReadResult"
+	data, err := r.client.HGet(context.Background(), common.TaskKey(qname, taskID), "result").Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.E(op, errors.Unknown, &errors.RedisCommandError{Command: "hget", Err: err})
+	}
+	return data, nil
+}
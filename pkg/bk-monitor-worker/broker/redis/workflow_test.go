@@ -0,0 +1,182 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	common "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	task "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/timex"
+)
+
+func newTestRDB(t *testing.T) *RDB {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	return &RDB{client: client, clock: timex.NewTimeClock()}
+}
+
+func newTestWorkflow(id string, sizes ...int) *task.WorkflowMessage {
+	msg := &task.WorkflowMessage{ID: id}
+	for _, size := range sizes {
+		var tasks []*task.SerializerTask
+		for i := 0; i < size; i++ {
+			tasks = append(tasks, &task.SerializerTask{Kind: "async:test", Options: task.Options{Queue: "default"}})
+		}
+		msg.Steps = append(msg.Steps, tasks)
+	}
+	return msg
+}
+
+func TestWorkflowSucceeded(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+
+	// chord: 两个任务并行，全部成功后执行回调
+	assert.NoError(t, r.CreateWorkflow(ctx, newTestWorkflow("w1", 2, 1)))
+	assert.Error(t, r.CreateWorkflow(ctx, newTestWorkflow("w1", 1)))
+
+	msg, err := r.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateRunning, msg.State)
+	assert.Equal(t, [][]task.WorkflowState{
+		{task.WorkflowStateRunning, task.WorkflowStateRunning},
+		{task.WorkflowStatePending},
+	}, msg.TaskStates)
+
+	done, err := r.CompleteWorkflowTask(ctx, "w1", 0, 1, []byte("b"), 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	// 重复上报以及非当前阶段的任务会被忽略
+	done, err = r.CompleteWorkflowTask(ctx, "w1", 0, 1, []byte("b"), 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	done, err = r.CompleteWorkflowTask(ctx, "w1", 1, 0, nil, 0)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	done, err = r.CompleteWorkflowTask(ctx, "w1", 0, 0, []byte("a"), 1)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	msg, err = r.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, msg.Step)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, msg.Results[0])
+	assert.Equal(t, task.WorkflowStateRunning, msg.TaskStates[1][0])
+
+	done, err = r.CompleteWorkflowTask(ctx, "w1", 1, 0, nil, 0)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	msg, err = r.GetWorkflow(ctx, "w1")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateSucceeded, msg.State)
+	assert.Equal(t, task.WorkflowStateSucceeded, msg.TaskStates[1][0])
+	assert.Nil(t, msg.Results[1][0])
+}
+
+func TestWorkflowFailed(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+
+	// chain: 第一个任务失败后整个编排失败，后续结果不再记录
+	assert.NoError(t, r.CreateWorkflow(ctx, newTestWorkflow("w2", 1, 1)))
+
+	failed, err := r.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	assert.True(t, failed)
+	failed, err = r.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	assert.False(t, failed)
+
+	done, err := r.CompleteWorkflowTask(ctx, "w2", 0, 0, nil, 1)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	msg, err := r.GetWorkflow(ctx, "w2")
+	assert.NoError(t, err)
+	assert.Equal(t, task.WorkflowStateFailed, msg.State)
+	assert.Equal(t, "boom", msg.ErrorMsg)
+	assert.Equal(t, "wf:w2:0:0", msg.FailedTask)
+	assert.Equal(t, [][]task.WorkflowState{{task.WorkflowStateFailed}, {task.WorkflowStatePending}}, msg.TaskStates)
+
+	_, err = r.GetWorkflow(ctx, "not-exists")
+	assert.Equal(t, errors.NotFound, errors.CanonicalCode(err))
+
+	msgs, err := r.ListWorkflows(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+}
+
+func TestAdvancingWorkflows(t *testing.T) {
+	r := newTestRDB(t)
+	ctx := context.Background()
+	cutoff := time.Now().Add(time.Minute)
+
+	// 创建后第一阶段未下发完成
+	assert.NoError(t, r.CreateWorkflow(ctx, newTestWorkflow("w1", 1, 1)))
+	ids, err := r.ListAdvancingWorkflows(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w1"}, ids)
+	ids, err = r.ListAdvancingWorkflows(ctx, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.NoError(t, r.MarkWorkflowStepEnqueued(ctx, "w1", 0))
+	ids, err = r.ListAdvancingWorkflows(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 进入下一阶段时与任务结果一起记录，过期的阶段不会清除记录
+	done, err := r.CompleteWorkflowTask(ctx, "w1", 0, 0, nil, 1)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.NoError(t, r.MarkWorkflowStepEnqueued(ctx, "w1", 0))
+	ids, err = r.ListAdvancingWorkflows(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w1"}, ids)
+	assert.NoError(t, r.MarkWorkflowStepEnqueued(ctx, "w1", 1))
+	ids, err = r.ListAdvancingWorkflows(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 编排失败或过期后不再推进
+	assert.NoError(t, r.CreateWorkflow(ctx, newTestWorkflow("w2", 1)))
+	_, err = r.FailWorkflow(ctx, "w2", 0, 0, "wf:w2:0:0", "boom")
+	assert.NoError(t, err)
+	assert.NoError(t, r.CreateWorkflow(ctx, newTestWorkflow("w3", 1)))
+	assert.NoError(t, r.client.Del(ctx, common.WorkflowKey("w3")).Err())
+	assert.NoError(t, r.MarkWorkflowStepEnqueued(ctx, "w3", 0))
+	ids, err = r.ListAdvancingWorkflows(ctx, cutoff)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestReadResult(t *testing.T) {
+	r := newTestRDB(t)
+
+	data, err := r.ReadResult("default", "t1")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	_, err = r.WriteResult("default", "t1", []byte("ok"))
+	assert.NoError(t, err)
+	data, err = r.ReadResult("default", "t1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), data)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
 	bmwHttp "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/http"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/log"
@@ -46,6 +47,10 @@ func startController(cmd *cobra.Command, args []string) {
 	config.InitConfig()
 	// 初始化日志
 	log.InitLogger()
+	// bbolt 数据文件只能被 worker 进程打开，由 worker 进程运行周期任务调度器并直接执行常驻任务
+	if config.BrokerType == broker.TypeBbolt {
+		logger.Fatalf("controller is not needed by broker: %s, periodic and daemon tasks are run by the worker", config.BrokerType)
+	}
 
 	r := bmwHttp.NewProfHttpService()
 
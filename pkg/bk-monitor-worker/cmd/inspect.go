@@ -0,0 +1,206 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/timex"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/worker"
+)
+
+var (
+	inspectQueue  string
+	inspectState  string
+	inspectTaskID string
+	inspectOffset int
+	inspectLimit  int
+)
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.AddCommand(inspectQueueCmd, inspectTaskCmd, inspectServerCmd)
+	inspectQueueCmd.AddCommand(inspectQueuePauseCmd, inspectQueueResumeCmd)
+	inspectTaskCmd.AddCommand(inspectTaskRunCmd, inspectTaskArchiveCmd, inspectTaskDeleteCmd)
+
+	inspectTaskCmd.PersistentFlags().StringVar(&inspectQueue, "queue", "default", "queue name")
+	inspectTaskCmd.PersistentFlags().StringVar(&inspectState, "state", "pending", "task state, one of pending|active|scheduled|retry|archived|completed")
+	inspectTaskCmd.Flags().IntVar(&inspectOffset, "offset", 0, "offset of tasks")
+	inspectTaskCmd.Flags().IntVar(&inspectLimit, "limit", 20, "max number of tasks")
+	for _, c := range []*cobra.Command{inspectTaskRunCmd, inspectTaskArchiveCmd, inspectTaskDeleteCmd} {
+		c.Flags().StringVar(&inspectTaskID, "id", "", "task id, operate all tasks in --state if empty")
+	}
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "inspect queues, tasks and workers",
+	Long:  "inspect queues, tasks and workers of bk monitor worker",
+}
+
+var inspectQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "list queues with task counts",
+	Run: func(cmd *cobra.Command, args []string) {
+		stats, err := newInspector().Queues()
+		exitOnErr(err)
+
+		w := newTabWriter()
+		fmt.Fprintln(w, "QUEUE\tPAUSED\tPENDING\tACTIVE\tSCHEDULED\tRETRY\tARCHIVED\tCOMPLETED\tPROCESSED\tFAILED")
+		for _, s := range stats {
+			fmt.Fprintf(w, "%s\t%v\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+				s.Queue, s.Paused, s.Pending, s.Active, s.Scheduled, s.Retry, s.Archived, s.Completed, s.Processed, s.Failed)
+		}
+		w.Flush()
+	},
+}
+
+var inspectQueuePauseCmd = &cobra.Command{
+	Use:   "pause <queue>",
+	Short: "pause the queue",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErr(newInspector().PauseQueue(args[0]))
+		fmt.Printf("queue %s paused\n", args[0])
+	},
+}
+
+var inspectQueueResumeCmd = &cobra.Command{
+	Use:   "resume <queue>",
+	Short: "resume the paused queue",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnErr(newInspector().ResumeQueue(args[0]))
+		fmt.Printf("queue %s resumed\n", args[0])
+	},
+}
+
+var inspectTaskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "list tasks in the queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		infos, err := newInspector().ListTasks(inspectQueue, inspectState, inspectOffset, inspectLimit)
+		exitOnErr(err)
+
+		w := newTabWriter()
+		fmt.Fprintln(w, "ID\tKIND\tRETRIED\tNEXT_PROCESS_AT\tPAYLOAD\tLAST_ERROR")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+				info.ID, info.Kind, info.Retried, info.MaxRetry, formatInspectTime(info.NextProcessAt),
+				truncate(string(info.Payload), 64), truncate(info.LastErr, 64))
+		}
+		w.Flush()
+	},
+}
+
+var inspectTaskRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "run the scheduled, retry or archived task immediately",
+	Run: func(cmd *cobra.Command, args []string) {
+		inspector := newInspector()
+		operateInspectTask("run", inspector.RunTask, inspector.RunAllTasks)
+	},
+}
+
+var inspectTaskArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "archive the pending, scheduled or retry task",
+	Run: func(cmd *cobra.Command, args []string) {
+		inspector := newInspector()
+		operateInspectTask("archive", inspector.ArchiveTask, inspector.ArchiveAllTasks)
+	},
+}
+
+var inspectTaskDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "delete the task which is not active",
+	Run: func(cmd *cobra.Command, args []string) {
+		inspector := newInspector()
+		operateInspectTask("delete", inspector.DeleteTask, inspector.DeleteAllTasks)
+	},
+}
+
+var inspectServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "list running workers and their active tasks",
+	Run: func(cmd *cobra.Command, args []string) {
+		servers, err := newInspector().Servers()
+		exitOnErr(err)
+
+		w := newTabWriter()
+		fmt.Fprintln(w, "HOST\tPID\tSERVER_ID\tQUEUES\tACTIVE\tSTARTED")
+		for _, s := range servers {
+			var queues []string
+			for q, p := range s.Queues {
+				queues = append(queues, fmt.Sprintf("%s:%d", q, p))
+			}
+			sort.Strings(queues)
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d/%d\t%s\n",
+				s.Host, s.PID, s.ServerID, strings.Join(queues, ","), s.ActiveWorkerCount, s.Concurrency, formatInspectTime(s.Started))
+			// 正在执行的任务缩进展示在所属进程下
+			for _, info := range s.Workers {
+				fmt.Fprintf(w, "\t\t%s\t%s\t%s\t%s\n", info.ID, info.Queue, info.Kind, formatInspectTime(info.Started))
+			}
+		}
+		w.Flush()
+	},
+}
+
+// operateInspectTask 指定 --id 时操作单个任务，否则操作 --state 状态下的所有任务
+func operateInspectTask(action string, one func(qname, id string) error, all func(qname, state string) (int, error)) {
+	if inspectTaskID != "" {
+		exitOnErr(one(inspectQueue, inspectTaskID))
+		fmt.Printf("%s task %s in queue %s success\n", action, inspectTaskID, inspectQueue)
+		return
+	}
+	n, err := all(inspectQueue, inspectState)
+	exitOnErr(err)
+	fmt.Printf("%s %d %s tasks in queue %s success\n", action, n, inspectState, inspectQueue)
+}
+
+func newInspector() *worker.Inspector {
+	config.InitConfig()
+	inspector, err := worker.NewInspector(worker.GetBroker())
+	exitOnErr(err)
+	return inspector
+}
+
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+func exitOnErr(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inspect error, %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func formatInspectTime(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return "-"
+	}
+	return t.Format(timex.TimeLayout)
+}
+
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
 	bmwHttp "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/http"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/log"
@@ -43,6 +44,10 @@ func startTask(cmd *cobra.Command, args []string) {
 	config.InitConfig()
 	// 初始化日志
 	log.InitLogger()
+	// bbolt 数据文件只能被 worker 进程打开，由 worker 进程提供任务 API
+	if config.BrokerType == broker.TypeBbolt {
+		logger.Fatalf("task api process is not needed by broker: %s, task api is served by the worker", config.BrokerType)
+	}
 
 	r := bmwHttp.NewHTTPService()
 
@@ -19,11 +19,13 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
 	bmwHttp "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/http"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/log"
 	service "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service/scheduler/daemon"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service/scheduler/periodic"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/runtimex"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
@@ -53,7 +55,11 @@ func startWorker(cmd *cobra.Command, args []string) {
 	// 初始化日志
 	log.InitLogger()
 
+	// 单机部署时 bbolt 数据文件只能被 worker 进程打开，由 worker 进程同时提供任务及队列巡检 API
 	r := bmwHttp.NewProfHttpService()
+	if config.BrokerType == broker.TypeBbolt {
+		r = bmwHttp.NewHTTPService()
+	}
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", config.WorkerListenHost, config.WorkerListenPort),
@@ -75,10 +81,19 @@ func startWorker(cmd *cobra.Command, args []string) {
 	}
 	go workerService.Run()
 
-	// 2. 启动常驻任务维护器
+	// 2. 启动常驻任务维护器，单机部署时直接执行 broker 中的全部常驻任务
 	daemonTaskMaintainer := daemon.NewDaemonTaskRunMaintainer(ctx, workerService.GetWorkerId())
 	go daemonTaskMaintainer.Run()
 
+	// 3. 单机部署时没有 controller，由 worker 进程运行周期任务调度器
+	if config.BrokerType == broker.TypeBbolt {
+		periodicTaskScheduler, err := periodic.NewPeriodicTaskScheduler(ctx)
+		if err != nil {
+			logger.Fatalf("failed to create periodic task scheduler: %s", err)
+		}
+		go periodicTaskScheduler.Run()
+	}
+
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
 	for {
@@ -137,6 +137,16 @@ func SchedulerHistoryKey(entryID string) string {
 	return fmt.Sprintf("bmw:scheduler_history:%s", entryID)
 }
 
+// WorkflowKey returns a redis key for the given workflow.
+func WorkflowKey(id string) string {
+	return fmt.Sprintf("%s:{%s}", AllWorkflows, id)
+}
+
+// SemaphoreKey returns a redis key for the distributed semaphore of the given name.
+func SemaphoreKey(name string) string {
+	return fmt.Sprintf("bmw:semaphores:{%s}", name)
+}
+
 // UniqueKey returns a redis key with the given type, payload, and queue name.
 func UniqueKey(qname, tasktype string, payload []byte) string {
 	if payload == nil {
@@ -199,6 +209,24 @@ type ServerInfo struct {
 	ActiveWorkerCount int
 }
 
+// QueueStats holds the task counts of a queue.
+type QueueStats struct {
+	Queue     string
+	Paused    bool
+	Pending   int
+	Active    int
+	Scheduled int
+	Retry     int
+	Archived  int
+	Completed int
+	// Processed/Failed 为当天的处理数量
+	Processed      int
+	Failed         int
+	ProcessedTotal int
+	FailedTotal    int
+	Timestamp      time.Time
+}
+
 // EncodeServerInfo marshals the given ServerInfo and returns the encoded bytes.
 func EncodeServerInfo(info *ServerInfo) ([]byte, error) {
 	if info == nil {
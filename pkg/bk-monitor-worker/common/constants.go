@@ -31,6 +31,10 @@ const (
 	AllSchedulers = "bmw:schedulers"
 	// AllQueues queues key
 	AllQueues = "bmw:queues"
+	// AllWorkflows workflow key
+	AllWorkflows = "bmw:workflows"
+	// AdvancingWorkflows 当前阶段的任务尚未全部下发的编排
+	AdvancingWorkflows = "bmw:advancing_workflows"
 )
 
 const (
@@ -46,6 +50,10 @@ const (
 	DefaultDelayedTaskCheckInterval = 5 * time.Second
 	// DefaultUniqueTTL 默认唯一任务的 TTL
 	DefaultUniqueTTL = 10 * time.Second
+	// DefaultWorkflowRetention 任务编排状态的保留时间
+	DefaultWorkflowRetention = 7 * 24 * time.Hour
+	// DefaultWorkflowRecoverInterval 重新下发编排阶段的检测间隔，超过该间隔仍未下发完成的阶段会被重新下发
+	DefaultWorkflowRecoverInterval = time.Minute
 )
 
 var (
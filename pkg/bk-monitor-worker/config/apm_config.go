@@ -59,6 +59,9 @@ var (
 	// TraceEsQueryRate To prevent too many es queries caused by bloom-filter,
 	// each dataId needs to set a threshold for the maximum number of requests in a minute. default is 20
 	TraceEsQueryRate int
+	// SpanSelfTimeMaxCount The maximum number of spans whose self-time is saved in trace info,
+	// spans with the longest self-time are kept. 0 means all spans are saved.
+	SpanSelfTimeMaxCount int
 	// StorageSaveRequestBufferSize Number of storage chan
 	StorageSaveRequestBufferSize int
 	// StorageWorkerCount The number of concurrent storage requests accepted simultaneously
@@ -150,6 +153,7 @@ func initApmVariables() {
 	EnabledTraceInfoReport = GetValue("taskConfig.apmPreCalculate.processor.enabledTraceInfoReport", true)
 
 	TraceEsQueryRate = GetValue("taskConfig.apmPreCalculate.processor.traceEsQueryRate", 20)
+	SpanSelfTimeMaxCount = GetValue("taskConfig.apmPreCalculate.processor.spanSelfTimeMaxCount", 0)
 	StorageSaveRequestBufferSize = GetValue("taskConfig.apmPreCalculate.storage.saveRequestBufferSize", 1000)
 	StorageWorkerCount = GetValue("taskConfig.apmPreCalculate.storage.workerCount", 10)
 	StorageSaveHoldMaxCount = GetValue("taskConfig.apmPreCalculate.storage.saveHoldMaxCount", 30)
@@ -11,6 +11,9 @@ package config
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 var (
@@ -24,6 +27,15 @@ var (
 	ClusterMetricHostFieldName    string
 	ESClusterMetricTarget         string
 	ESClusterMetricQueueName      string
+
+	// ClusterMetricComponentFieldName 集群组件字段名，如 VM 集群的 vmstorage/vminsert/vmselect
+	ClusterMetricComponentFieldName string
+	// VmClusterMetricTimeout 拉取单个 VM 组件 /metrics 的超时时间
+	VmClusterMetricTimeout time.Duration
+	// VmClusterMetricPath VM 组件指标接口路径
+	VmClusterMetricPath string
+	// KafkaClusterMetricPartitionLagLimit 单个 kafka 集群上报分区维度消费组 lag 的最大序列数，为 0 时只上报 topic 维度的 lag_sum
+	KafkaClusterMetricPartitionLagLimit int
 )
 
 func initClusterMetricVariables() {
@@ -36,7 +48,12 @@ func initClusterMetricVariables() {
 	ClusterMetricClusterFieldName = "bkm_cluster"
 	ClusterMetricFieldName = "bkm_metric_name"
 	ClusterMetricHostFieldName = "bkm_hostname"
+	ClusterMetricComponentFieldName = "bkm_component"
 
 	ESClusterMetricTarget = "bk_log_search"
 	ESClusterMetricQueueName = GetValue("taskConfig.logSearch.queueName", "log-search")
+
+	VmClusterMetricTimeout = GetValue("taskConfig.cluster_metrics.vm.timeout", 10*time.Second, viper.GetDuration)
+	VmClusterMetricPath = GetValue("taskConfig.cluster_metrics.vm.metricsPath", "/metrics")
+	KafkaClusterMetricPartitionLagLimit = GetValue("taskConfig.cluster_metrics.kafka.partitionLagLimit", 0)
 }
@@ -47,6 +47,10 @@ var (
 	// MaxBackups max backup of log file
 	MaxBackups int
 
+	// BrokerType type of broker, redis or bbolt
+	BrokerType string
+	// BrokerBboltPath db file path of bbolt broker
+	BrokerBboltPath string
 	// BrokerRedisMode redis mode
 	BrokerRedisMode string
 	// BrokerRedisSentinelMasterName broker redis mater name
@@ -164,6 +168,8 @@ var (
 	WorkerHealthCheckInterval time.Duration
 	// WorkerHealthCheckInfoDuration cache duration of worker info
 	WorkerHealthCheckInfoDuration time.Duration
+	// WorkerTaskLimits limits of task types
+	WorkerTaskLimits map[string]TaskLimit
 	// WorkerDaemonTaskMaintainerInterval check interval of task maintainer
 	WorkerDaemonTaskMaintainerInterval time.Duration
 	// WorkerDaemonTaskRetryTolerateCount max retry of task
@@ -268,6 +274,10 @@ func initVariables() {
 	// MaxBackups 日志文件保存最大数量
 	MaxBackups = GetValue("log.maxBackups", 5)
 
+	/* Broker 配置，单机部署时可使用 bbolt 替代 redis */
+	BrokerType = GetValue("broker.type", "redis")
+	BrokerBboltPath = GetValue("broker.bbolt.path", "bmw_broker.db")
+
 	/* Broker Redis 配置 */
 	BrokerRedisMode = GetValue("broker.redis.mode", "standalone")
 	BrokerRedisSentinelMasterName = GetValue("broker.redis.sentinel.masterName", "")
@@ -348,6 +358,8 @@ func initVariables() {
 	)
 	// WorkerDaemonTaskRetryTolerateCount worker常驻任务配置，当任务重试超过指定数量仍然失败时，下次重试间隔就不断动态增长
 	WorkerDaemonTaskRetryTolerateCount = GetValue("worker.daemonTask.maintainer.tolerateCount", 60)
+	// WorkerTaskLimits 按任务类型限制任务的执行，key 为任务类型
+	WorkerTaskLimits = GetValue("worker.taskLimits", map[string]TaskLimit{}, getTaskLimits)
 	/*
 		Worker配置 ----- END
 	*/
@@ -462,6 +474,26 @@ func GetValue[T any](key string, def T, getter ...func(string) T) T {
 	return value.(T)
 }
 
+// TaskLimit 任务类型的执行限制，为 0 的项不限制
+type TaskLimit struct {
+	// Concurrency 单个 worker 内同时执行的最大任务数
+	Concurrency int `mapstructure:"concurrency"`
+	// Rate 单个 worker 每秒最多开始执行的任务数
+	Rate float64 `mapstructure:"rate"`
+	// Burst 允许突发执行的任务数
+	Burst int `mapstructure:"burst"`
+	// GlobalConcurrency 所有 worker 同时执行的最大任务数
+	GlobalConcurrency int `mapstructure:"globalConcurrency"`
+}
+
+func getTaskLimits(key string) map[string]TaskLimit {
+	limits := make(map[string]TaskLimit)
+	if err := viper.UnmarshalKey(key, &limits); err != nil {
+		logger.Errorf("failed to parse config: %s, error: %s", key, err)
+	}
+	return limits
+}
+
 func GetFloatSlice(key string) []float64 {
 	items, err := cast.ToSliceE(viper.Get(key))
 	if err != nil {
@@ -503,4 +535,5 @@ func InitConfig() {
 	initClusterMetricVariables()
 	initApmVariables()
 	initAlarmConfig()
+	initRecordRuleVariables()
 }
@@ -11,8 +11,11 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
 
 var (
@@ -134,8 +137,35 @@ var (
 	SloPushGatewayToken string
 	// SloPushGatewayEndpoint slo数据上报端点
 	SloPushGatewayEndpoint string
+	// SloUnifyQueryUrl slo 目标执行策略中 good/total 查询使用的 unify-query 地址
+	SloUnifyQueryUrl string
+	// SloQueryTimeout slo 目标单次查询的超时时间
+	SloQueryTimeout time.Duration
+	// SloBurnRateWindows slo 多窗口燃烧率告警窗口
+	SloBurnRateWindows []SloBurnRateWindow
 )
 
+// SloBurnRateWindow 燃烧率告警窗口，长短窗口的燃烧率同时超过阈值时触发
+type SloBurnRateWindow struct {
+	Long   time.Duration `mapstructure:"long"`
+	Short  time.Duration `mapstructure:"short"`
+	Factor float64       `mapstructure:"factor"`
+}
+
+// defaultSloBurnRateWindows 默认的燃烧率告警窗口，分别对应 1h 内消耗 2% 及 6h 内消耗 5% 的错误预算
+var defaultSloBurnRateWindows = []SloBurnRateWindow{
+	{Long: time.Hour, Short: 5 * time.Minute, Factor: 14.4},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, Factor: 6},
+}
+
+func getSloBurnRateWindows(key string) []SloBurnRateWindow {
+	var windows []SloBurnRateWindow
+	if err := viper.UnmarshalKey(key, &windows); err != nil {
+		logger.Errorf("failed to parse config: %s, error: %s", key, err)
+	}
+	return windows
+}
+
 func initMetadataVariables() {
 	MetadataMetricDimensionMetricKeyPrefix = GetValue("taskConfig.metadata.metricDimension.metricKeyPrefix", "bkmonitor:metrics_")
 	MetadataMetricDimensionKeyPrefix = GetValue("taskConfig.metadata.metricDimension.metricDimensionKeyPrefix", "bkmonitor:metric_dimensions_")
@@ -198,4 +228,7 @@ func initMetadataVariables() {
 
 	SloPushGatewayToken = GetValue("taskConfig.metadata.slo.sloPushGatewayToken", "")
 	SloPushGatewayEndpoint = GetValue("taskConfig.metadata.slo.sloPushGatewayEndpoint", "")
+	SloUnifyQueryUrl = GetValue("taskConfig.metadata.slo.unifyQueryUrl", "")
+	SloQueryTimeout = GetValue("taskConfig.metadata.slo.queryTimeout", 30*time.Second, viper.GetDuration)
+	SloBurnRateWindows = GetValue("taskConfig.metadata.slo.burnRateWindows", defaultSloBurnRateWindows, getSloBurnRateWindows)
 }
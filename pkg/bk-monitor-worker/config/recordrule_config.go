@@ -0,0 +1,52 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// RecordRuleQueryMode 预计算查询方式，unify_query 或 vm
+	RecordRuleQueryMode string
+	// RecordRuleUnifyQueryUrl unify-query 服务地址
+	RecordRuleUnifyQueryUrl string
+	// RecordRuleVmQueryPath VM 集群查询接口路径
+	RecordRuleVmQueryPath string
+	// RecordRuleVmWritePath VM 集群 remote write 接口路径
+	RecordRuleVmWritePath string
+	// RecordRuleCheckInterval 检查规则是否需要计算的周期
+	RecordRuleCheckInterval time.Duration
+	// RecordRuleRefreshInterval 从 db 重新加载规则的周期
+	RecordRuleRefreshInterval time.Duration
+	// RecordRuleEvaluationDelay 计算时间点相对当前时间的延迟，等待源数据写入完成
+	RecordRuleEvaluationDelay time.Duration
+	// RecordRuleMaxBackfill 规则首次计算或者中断恢复后最多回填的时长
+	RecordRuleMaxBackfill time.Duration
+	// RecordRuleQueryTimeout 单次查询及写入的超时时间
+	RecordRuleQueryTimeout time.Duration
+	// RecordRuleConcurrency 同时计算的规则数
+	RecordRuleConcurrency int
+)
+
+func initRecordRuleVariables() {
+	RecordRuleQueryMode = GetValue("taskConfig.recordRule.queryMode", "vm")
+	RecordRuleUnifyQueryUrl = GetValue("taskConfig.recordRule.unifyQuery.url", "")
+	RecordRuleVmQueryPath = GetValue("taskConfig.recordRule.vm.queryPath", "/api/v1/query")
+	RecordRuleVmWritePath = GetValue("taskConfig.recordRule.vm.writePath", "/api/v1/write")
+	RecordRuleCheckInterval = GetValue("taskConfig.recordRule.checkInterval", 5*time.Second, viper.GetDuration)
+	RecordRuleRefreshInterval = GetValue("taskConfig.recordRule.refreshInterval", time.Minute, viper.GetDuration)
+	RecordRuleEvaluationDelay = GetValue("taskConfig.recordRule.evaluationDelay", 30*time.Second, viper.GetDuration)
+	RecordRuleMaxBackfill = GetValue("taskConfig.recordRule.maxBackfill", time.Hour, viper.GetDuration)
+	RecordRuleQueryTimeout = GetValue("taskConfig.recordRule.queryTimeout", 30*time.Second, viper.GetDuration)
+	RecordRuleConcurrency = GetValue("taskConfig.recordRule.concurrency", 10)
+}
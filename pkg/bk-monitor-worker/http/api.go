@@ -10,13 +10,11 @@
 package http
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	rdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service/scheduler/daemon"
 	storeRedis "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/redis"
@@ -140,12 +138,11 @@ func composeOption(opt taskOptions) []task.Option {
 
 // 写入任务队列
 func enqueueAsyncTask(t *task.Task) error {
-	// new client
+	// client 及其 broker 为进程内共享的单例，使用后不能关闭
 	client, err := worker.GetClient()
 	if err != nil {
 		return err
 	}
-	defer client.Close()
 
 	// 入队列
 	if _, err := client.Enqueue(t); err != nil {
@@ -174,8 +171,6 @@ func pushPeriodicTaskToRedis(t *task.Task) error {
 
 // 推送任务到 task队列中
 func enqueueDaemonTask(t *task.Task) error {
-	broker := rdb.GetRDB()
-
 	serializerTask, err := task.NewSerializerTask(*t)
 	if err != nil {
 		return err
@@ -185,7 +180,7 @@ func enqueueDaemonTask(t *task.Task) error {
 		return err
 	}
 
-	return broker.Client().SAdd(context.Background(), common.DaemonTaskKey(), data).Err()
+	return addDaemonTask(data)
 }
 
 // RemoveAllTask 删除所有任务
@@ -198,9 +193,7 @@ func RemoveAllTask(c *gin.Context) {
 
 	switch params.TaskType {
 	case DaemonTask:
-		broker := rdb.GetRDB()
-		_, err := broker.Client().Del(context.Background(), common.DaemonTaskKey()).Result()
-		if err != nil {
+		if err := removeAllDaemonTasks(); err != nil {
 			ServerErrResponse(c, fmt.Sprintf("failed to delete key: %s.", common.DaemonTaskKey()), err)
 			return
 		}
@@ -226,7 +219,10 @@ func RemoveTask(c *gin.Context) {
 			return
 		}
 		if daemonTaskBytes != nil {
-			rdb.GetRDB().Client().SRem(context.Background(), common.DaemonTaskKey(), daemonTaskBytes)
+			if err = removeDaemonTask(daemonTaskBytes); err != nil {
+				ServerErrResponse(c, "remove daemon task error, %v", err)
+				return
+			}
 			Response(c, &gin.H{"data": params.TaskUniId})
 			return
 		}
@@ -246,8 +242,7 @@ func ListTask(c *gin.Context) {
 
 	switch taskType {
 	case DaemonTask:
-		client := rdb.GetRDB()
-		tasks, err := client.Client().SMembers(context.Background(), common.DaemonTaskKey()).Result()
+		tasks, err := listDaemonTasks()
 		if err != nil {
 			ServerErrResponse(c, fmt.Sprintf("failed to list task by key: %s.", common.DaemonTaskKey()), err)
 			return
@@ -255,7 +250,7 @@ func ListTask(c *gin.Context) {
 		var res []daemonTaskItem
 		for _, i := range tasks {
 			var item task.SerializerTask
-			if err = jsonx.Unmarshal([]byte(i), &item); err != nil {
+			if err = jsonx.Unmarshal(i, &item); err != nil {
 				ServerErrResponse(c, fmt.Sprintf("failed to parse key: %v to Task on value: %s", common.DaemonTaskKey(), i), err)
 				return
 			}
@@ -267,18 +262,27 @@ func ListTask(c *gin.Context) {
 				return
 			}
 
-			// 查询绑定信息
-			workerId, err := daemon.GetBinding().GetBindingWorkerIdByTask(item)
-			if err != nil {
-				ServerErrResponse(c, fmt.Sprintf("failed to get worker for taskUnid: %s", taskUinId), err)
-				return
-			}
 			taskRes := daemonTaskItem{
 				UniId:   taskUinId,
 				Kind:    item.Kind,
 				Options: item.Options,
 				Payload: payload,
 			}
+			// 单机部署时常驻任务均由当前 worker 执行
+			if _, ok := daemon.LocalTaskBroker(); ok {
+				if workerId := daemon.LocalWorkerId(); workerId != "" {
+					taskRes.Binding = &daemonTaskBindingInfo{WorkerId: workerId, WorkerIsNormal: true}
+				}
+				res = append(res, taskRes)
+				continue
+			}
+
+			// 查询绑定信息
+			workerId, err := daemon.GetBinding().GetBindingWorkerIdByTask(item)
+			if err != nil {
+				ServerErrResponse(c, fmt.Sprintf("failed to get worker for taskUnid: %s", taskUinId), err)
+				return
+			}
 			var bindingInfo daemonTaskBindingInfo
 			var alive bool
 			if workerId != "" {
@@ -23,4 +23,20 @@ const (
 	DeleteAllTaskPath = "/all"
 	// DaemonTaskReloadPath 常驻任务重载(重新启动)
 	DaemonTaskReloadPath = "/daemon/reload"
+	// WorkflowPath 任务编排
+	WorkflowPath = "/workflow"
+	// QueueRouterPrefix 队列巡检
+	QueueRouterPrefix = "/queue"
+	// QueuePausePath 暂停队列
+	QueuePausePath = "/pause"
+	// QueueResumePath 恢复队列
+	QueueResumePath = "/resume"
+	// QueueTaskPath 队列中的任务
+	QueueTaskPath = "/task"
+	// QueueTaskRunPath 立即执行任务
+	QueueTaskRunPath = "/task/run"
+	// QueueTaskArchivePath 归档任务
+	QueueTaskArchivePath = "/task/archive"
+	// ServerPath worker 运行状态
+	ServerPath = "/server"
 )
@@ -45,9 +45,26 @@ func ReloadDaemonTask(c *gin.Context) {
 		return
 	}
 
-	broker := rdb.GetRDB()
+	// 单机部署时由 worker 进程直接更新并重载常驻任务
+	if _, ok := daemon.LocalTaskBroker(); ok {
+		var payloadData []byte
+		if len(params.Payload) > 0 {
+			if payloadData, err = jsonx.Marshal(params.Payload); err != nil {
+				BadReqResponse(c, "failed to parse payload to bytes, error: %s", err)
+				return
+			}
+		}
+		if err = daemon.ReloadLocalTask(params.UniId, payloadData); err != nil {
+			BadReqResponse(c, "reload daemon task failed, error: %v", err)
+			return
+		}
+		Response(c, &gin.H{"data": fmt.Sprintf("reload %s in worker: %s", params.UniId, daemon.LocalWorkerId())})
+		return
+	}
+
+	client := rdb.GetRDB().Client()
 	// 检查是否已经存在于重载队列中
-	exist, err := broker.Client().SIsMember(context.Background(), common.DaemonReloadReqChannel(), params.UniId).Result()
+	exist, err := client.SIsMember(context.Background(), common.DaemonReloadReqChannel(), params.UniId).Result()
 	if err != nil {
 		BadReqResponse(c, "found: %s if in queue failed, error: %s", params.UniId, err)
 		return
@@ -62,7 +79,7 @@ func ReloadDaemonTask(c *gin.Context) {
 	}
 
 	// 推送重载请求到调度队队列中、并将此次 payload 更新存储在 hash 结构中等待消费
-	pipe := broker.Client().Pipeline()
+	pipe := client.Pipeline()
 	pipe.Publish(context.Background(), common.DaemonReloadReqChannel(), params.UniId)
 	if len(params.Payload) > 0 {
 		payloadData, err := jsonx.Marshal(params.Payload)
@@ -80,20 +97,61 @@ func ReloadDaemonTask(c *gin.Context) {
 	Response(c, &gin.H{"data": fmt.Sprintf("send %s to channel: %s", params.UniId, common.DaemonReloadReqChannel())})
 }
 
+// listDaemonTasks 获取全部常驻任务，单机部署时常驻任务存储在 broker 中
+func listDaemonTasks() ([][]byte, error) {
+	if b, ok := daemon.LocalTaskBroker(); ok {
+		return b.ListDaemonTasks()
+	}
+	members, err := rdb.GetRDB().Client().SMembers(context.Background(), common.DaemonTaskKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([][]byte, 0, len(members))
+	for _, i := range members {
+		tasks = append(tasks, []byte(i))
+	}
+	return tasks, nil
+}
+
+// addDaemonTask 添加常驻任务
+func addDaemonTask(data []byte) error {
+	if b, ok := daemon.LocalTaskBroker(); ok {
+		return b.AddDaemonTask(data)
+	}
+	return rdb.GetRDB().Client().SAdd(context.Background(), common.DaemonTaskKey(), data).Err()
+}
+
+// removeDaemonTask 删除常驻任务
+func removeDaemonTask(data []byte) error {
+	if b, ok := daemon.LocalTaskBroker(); ok {
+		return b.RemoveDaemonTask(data)
+	}
+	return rdb.GetRDB().Client().SRem(context.Background(), common.DaemonTaskKey(), data).Err()
+}
+
+// removeAllDaemonTasks 删除全部常驻任务
+func removeAllDaemonTasks() error {
+	if b, ok := daemon.LocalTaskBroker(); ok {
+		return b.RemoveAllDaemonTasks()
+	}
+	return rdb.GetRDB().Client().Del(context.Background(), common.DaemonTaskKey()).Err()
+}
+
+// getDaemonTask 查找常驻任务
 func getDaemonTask(taskUniId string) ([]byte, error) {
-	tasks, err := rdb.GetRDB().Client().SMembers(context.Background(), common.DaemonTaskKey()).Result()
+	tasks, err := listDaemonTasks()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, i := range tasks {
 		var item task.SerializerTask
-		if err = jsonx.Unmarshal([]byte(i), &item); err != nil {
+		if err = jsonx.Unmarshal(i, &item); err != nil {
 			return nil, err
 		}
 		itemTaskUniId := daemon.ComputeTaskUniId(item)
 		if itemTaskUniId == taskUniId {
-			return []byte(i), nil
+			return i, nil
 		}
 	}
 	return nil, nil
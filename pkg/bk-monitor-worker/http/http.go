@@ -27,7 +27,20 @@ func NewHTTPService() *gin.Engine {
 		taskRouter.DELETE("", RemoveTask)
 		taskRouter.DELETE(DeleteAllTaskPath, RemoveAllTask)
 		taskRouter.POST(DaemonTaskReloadPath, ReloadDaemonTask)
+		taskRouter.GET(WorkflowPath, ListWorkflow)
+		taskRouter.POST(WorkflowPath, CreateWorkflow)
 	}
+	queueRouter := bmwRouter.Group(QueueRouterPrefix)
+	{
+		queueRouter.GET("", ListQueue)
+		queueRouter.POST(QueuePausePath, PauseQueue)
+		queueRouter.POST(QueueResumePath, ResumeQueue)
+		queueRouter.GET(QueueTaskPath, ListQueueTask)
+		queueRouter.DELETE(QueueTaskPath, DeleteQueueTask)
+		queueRouter.POST(QueueTaskRunPath, RunQueueTask)
+		queueRouter.POST(QueueTaskArchivePath, ArchiveQueueTask)
+	}
+	bmwRouter.GET(ServerPath, ListServer)
 
 	return svr
 }
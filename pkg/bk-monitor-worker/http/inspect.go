@@ -0,0 +1,326 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/timex"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/worker"
+)
+
+// 默认返回的任务数量
+const defaultQueueTaskLimit = 100
+
+type queueParams struct {
+	Queue string `binding:"required" json:"queue"`
+}
+
+// queueTaskParams 指定 task_id 时操作单个任务，否则操作 state 状态下的所有任务
+type queueTaskParams struct {
+	Queue  string `binding:"required" json:"queue"`
+	TaskID string `json:"task_id"`
+	State  string `json:"state"`
+}
+
+type queueItem struct {
+	Queue          string `json:"queue"`
+	Paused         bool   `json:"paused"`
+	Pending        int    `json:"pending"`
+	Active         int    `json:"active"`
+	Scheduled      int    `json:"scheduled"`
+	Retry          int    `json:"retry"`
+	Archived       int    `json:"archived"`
+	Completed      int    `json:"completed"`
+	Processed      int    `json:"processed"`
+	Failed         int    `json:"failed"`
+	ProcessedTotal int    `json:"processed_total"`
+	FailedTotal    int    `json:"failed_total"`
+}
+
+type queueTaskItem struct {
+	ID            string `json:"id"`
+	Queue         string `json:"queue"`
+	Kind          string `json:"kind"`
+	State         string `json:"state"`
+	Payload       any    `json:"payload"`
+	MaxRetry      int    `json:"max_retry"`
+	Retried       int    `json:"retried"`
+	LastErr       string `json:"last_err,omitempty"`
+	LastFailedAt  string `json:"last_failed_at,omitempty"`
+	NextProcessAt string `json:"next_process_at,omitempty"`
+	Result        string `json:"result,omitempty"`
+}
+
+type serverWorkerItem struct {
+	TaskID   string `json:"task_id"`
+	Kind     string `json:"kind"`
+	Queue    string `json:"queue"`
+	Started  string `json:"started"`
+	Deadline string `json:"deadline"`
+}
+
+type serverItem struct {
+	Host              string             `json:"host"`
+	PID               int                `json:"pid"`
+	ServerID          string             `json:"server_id"`
+	Concurrency       int                `json:"concurrency"`
+	Queues            map[string]int     `json:"queues"`
+	Status            string             `json:"status"`
+	Started           string             `json:"started"`
+	ActiveWorkerCount int                `json:"active_worker_count"`
+	Workers           []serverWorkerItem `json:"workers"`
+}
+
+// inspectErrResponse 队列或任务不存在、任务状态不符合时返回参数错误
+func inspectErrResponse(c *gin.Context, err error) {
+	switch errors.CanonicalCode(err) {
+	case errors.NotFound, errors.FailedPrecondition:
+		BadReqResponse(c, "%v", err)
+	default:
+		ServerErrResponse(c, "%v", err)
+	}
+}
+
+// formatTime 零值时间返回空字符串
+func formatTime(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return ""
+	}
+	return t.Format(timex.TimeLayout)
+}
+
+// newInspector 获取当前 broker 的巡检器，broker 不支持时直接返回错误
+func newInspector(c *gin.Context) (*worker.Inspector, bool) {
+	inspector, err := worker.NewInspector(worker.GetBroker())
+	if err != nil {
+		ServerErrResponse(c, "new inspector error, %v", err)
+		return nil, false
+	}
+	return inspector, true
+}
+
+// ListQueue 获取所有队列的任务统计
+func ListQueue(c *gin.Context) {
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	stats, err := inspector.Queues()
+	if err != nil {
+		ServerErrResponse(c, "list queue error, %v", err)
+		return
+	}
+	res := make([]queueItem, 0, len(stats))
+	for _, s := range stats {
+		res = append(res, queueItem{
+			Queue:          s.Queue,
+			Paused:         s.Paused,
+			Pending:        s.Pending,
+			Active:         s.Active,
+			Scheduled:      s.Scheduled,
+			Retry:          s.Retry,
+			Archived:       s.Archived,
+			Completed:      s.Completed,
+			Processed:      s.Processed,
+			Failed:         s.Failed,
+			ProcessedTotal: s.ProcessedTotal,
+			FailedTotal:    s.FailedTotal,
+		})
+	}
+	Response(c, &gin.H{"data": res})
+}
+
+// PauseQueue 暂停队列
+func PauseQueue(c *gin.Context) {
+	params := new(queueParams)
+	if err := BindJSON(c, params); err != nil {
+		BadReqResponse(c, "parse params error: %v", err)
+		return
+	}
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	if err := inspector.PauseQueue(params.Queue); err != nil {
+		inspectErrResponse(c, err)
+		return
+	}
+	Response(c, &gin.H{})
+}
+
+// ResumeQueue 恢复暂停的队列
+func ResumeQueue(c *gin.Context) {
+	params := new(queueParams)
+	if err := BindJSON(c, params); err != nil {
+		BadReqResponse(c, "parse params error: %v", err)
+		return
+	}
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	if err := inspector.ResumeQueue(params.Queue); err != nil {
+		inspectErrResponse(c, err)
+		return
+	}
+	Response(c, &gin.H{})
+}
+
+// ListQueueTask 分页获取队列中某个状态的任务
+func ListQueueTask(c *gin.Context) {
+	qname := c.Query("queue")
+	state := c.DefaultQuery("state", "pending")
+	offset := cast.ToInt(c.DefaultQuery("offset", "0"))
+	limit := cast.ToInt(c.DefaultQuery("limit", cast.ToString(defaultQueueTaskLimit)))
+	if qname == "" {
+		BadReqResponse(c, "params:[queue] is null")
+		return
+	}
+	if offset < 0 || limit <= 0 {
+		BadReqResponse(c, "invalid offset: %d or limit: %d", offset, limit)
+		return
+	}
+	if _, err := task.ParseTaskState(state); err != nil {
+		BadReqResponse(c, "%v", err)
+		return
+	}
+
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	infos, err := inspector.ListTasks(qname, state, offset, limit)
+	if err != nil {
+		inspectErrResponse(c, err)
+		return
+	}
+	res := make([]queueTaskItem, 0, len(infos))
+	for _, info := range infos {
+		item := queueTaskItem{
+			ID:            info.ID,
+			Queue:         info.Queue,
+			Kind:          info.Kind,
+			State:         info.State.String(),
+			MaxRetry:      info.MaxRetry,
+			Retried:       info.Retried,
+			LastErr:       info.LastErr,
+			LastFailedAt:  formatTime(info.LastFailedAt),
+			NextProcessAt: formatTime(info.NextProcessAt),
+			Result:        string(info.Result),
+		}
+		var payload any
+		if err = jsonx.Unmarshal(info.Payload, &payload); err == nil {
+			item.Payload = payload
+		} else {
+			item.Payload = string(info.Payload)
+		}
+		res = append(res, item)
+	}
+	Response(c, &gin.H{"data": res})
+}
+
+// operateQueueTask 操作单个任务或某个状态下的所有任务
+func operateQueueTask(c *gin.Context, one func(qname, id string) error, all func(qname, state string) (int, error)) {
+	params := new(queueTaskParams)
+	if err := BindJSON(c, params); err != nil {
+		BadReqResponse(c, "parse params error: %v", err)
+		return
+	}
+	if params.TaskID != "" {
+		if err := one(params.Queue, params.TaskID); err != nil {
+			inspectErrResponse(c, err)
+			return
+		}
+		Response(c, &gin.H{"data": 1})
+		return
+	}
+	if _, err := task.ParseTaskState(params.State); err != nil {
+		BadReqResponse(c, "params:[task_id] or valid [state] is required, %v", err)
+		return
+	}
+	n, err := all(params.Queue, params.State)
+	if err != nil {
+		inspectErrResponse(c, err)
+		return
+	}
+	Response(c, &gin.H{"data": n})
+}
+
+// RunQueueTask 立即执行任务
+func RunQueueTask(c *gin.Context) {
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	operateQueueTask(c, inspector.RunTask, inspector.RunAllTasks)
+}
+
+// ArchiveQueueTask 归档任务
+func ArchiveQueueTask(c *gin.Context) {
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	operateQueueTask(c, inspector.ArchiveTask, inspector.ArchiveAllTasks)
+}
+
+// DeleteQueueTask 删除任务
+func DeleteQueueTask(c *gin.Context) {
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	operateQueueTask(c, inspector.DeleteTask, inspector.DeleteAllTasks)
+}
+
+// ListServer 获取存活的 worker 进程及其正在执行的任务
+func ListServer(c *gin.Context) {
+	inspector, ok := newInspector(c)
+	if !ok {
+		return
+	}
+	servers, err := inspector.Servers()
+	if err != nil {
+		ServerErrResponse(c, "list server error, %v", err)
+		return
+	}
+	res := make([]serverItem, 0, len(servers))
+	for _, s := range servers {
+		item := serverItem{
+			Host:              s.Host,
+			PID:               s.PID,
+			ServerID:          s.ServerID,
+			Concurrency:       s.Concurrency,
+			Queues:            s.Queues,
+			Status:            s.Status,
+			Started:           formatTime(s.Started),
+			ActiveWorkerCount: s.ActiveWorkerCount,
+			Workers:           make([]serverWorkerItem, 0, len(s.Workers)),
+		}
+		for _, w := range s.Workers {
+			item.Workers = append(item.Workers, serverWorkerItem{
+				TaskID:   w.ID,
+				Kind:     w.Kind,
+				Queue:    w.Queue,
+				Started:  formatTime(w.Started),
+				Deadline: formatTime(w.Deadline),
+			})
+		}
+		res = append(res, item)
+	}
+	Response(c, &gin.H{"data": res})
+}
@@ -0,0 +1,170 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/timex"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/worker"
+)
+
+// 默认返回的任务编排数量
+const defaultWorkflowLimit = 100
+
+// workflowParams 阶段之间串行执行，阶段内的任务并行执行
+// chain: [[a], [b]]; group: [[a, b]]; chord: [[a, b], [callback]]
+type workflowParams struct {
+	Steps [][]taskParams `binding:"required" json:"steps"`
+}
+
+type workflowTaskItem struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Queue   string `json:"queue"`
+	State   string `json:"state"`
+	Payload any    `json:"payload"`
+	Result  string `json:"result,omitempty"`
+}
+
+type workflowItem struct {
+	ID         string               `json:"id"`
+	State      string               `json:"state"`
+	Step       int                  `json:"step"`
+	Error      string               `json:"error,omitempty"`
+	FailedTask string               `json:"failed_task,omitempty"`
+	CreatedAt  string               `json:"created_at"`
+	UpdatedAt  string               `json:"updated_at"`
+	Steps      [][]workflowTaskItem `json:"steps"`
+}
+
+// CreateWorkflow 创建任务编排，仅支持异步任务
+func CreateWorkflow(c *gin.Context) {
+	params := new(workflowParams)
+	if err := BindJSON(c, params); err != nil {
+		BadReqResponse(c, "parse params error: %v", err)
+		return
+	}
+
+	workflow := &task.Workflow{}
+	for _, step := range params.Steps {
+		var tasks []*task.Task
+		for _, p := range step {
+			if !strings.HasPrefix(p.Kind, AsyncTask) {
+				BadReqResponse(c, "task kind: %s not support in workflow", p.Kind)
+				return
+			}
+			payload, err := jsonx.Marshal(p.Payload)
+			if err != nil {
+				ServerErrResponse(c, "json marshal error: %v", err)
+				return
+			}
+			tasks = append(tasks, task.NewTask(p.Kind, payload, composeOption(p.Options)...))
+		}
+		if len(tasks) == 0 {
+			BadReqResponse(c, "workflow step cannot be empty")
+			return
+		}
+		workflow.Then(tasks...)
+	}
+
+	client, err := worker.GetClient()
+	if err != nil {
+		ServerErrResponse(c, "get client error, %v", err)
+		return
+	}
+	msg, err := client.EnqueueWorkflow(context.Background(), workflow)
+	if err != nil {
+		ServerErrResponse(c, "enqueue workflow error, %v", err)
+		return
+	}
+	Response(c, &gin.H{"data": msg.ID})
+}
+
+// ListWorkflow 获取任务编排，指定 workflow_id 时返回单个编排的详情
+func ListWorkflow(c *gin.Context) {
+	client, err := worker.GetClient()
+	if err != nil {
+		ServerErrResponse(c, "get client error, %v", err)
+		return
+	}
+
+	if id := c.Query("workflow_id"); id != "" {
+		msg, err := client.GetWorkflow(context.Background(), id)
+		if errors.CanonicalCode(err) == errors.NotFound {
+			BadReqResponse(c, "workflow: %s not found", id)
+			return
+		}
+		if err != nil {
+			ServerErrResponse(c, "get workflow error, %v", err)
+			return
+		}
+		Response(c, &gin.H{"data": toWorkflowItem(msg)})
+		return
+	}
+
+	offset := cast.ToInt(c.DefaultQuery("offset", "0"))
+	limit := cast.ToInt(c.DefaultQuery("limit", cast.ToString(defaultWorkflowLimit)))
+	if offset < 0 || limit <= 0 {
+		BadReqResponse(c, "invalid offset: %d or limit: %d", offset, limit)
+		return
+	}
+	msgs, err := client.ListWorkflows(context.Background(), offset, limit)
+	if err != nil {
+		ServerErrResponse(c, "list workflow error, %v", err)
+		return
+	}
+	res := make([]workflowItem, 0, len(msgs))
+	for _, msg := range msgs {
+		res = append(res, toWorkflowItem(msg))
+	}
+	Response(c, &gin.H{"data": res})
+}
+
+// toWorkflowItem 转换为接口返回的结构，payload 优先按照 json 解析
+func toWorkflowItem(msg *task.WorkflowMessage) workflowItem {
+	item := workflowItem{
+		ID:         msg.ID,
+		State:      msg.State.String(),
+		Step:       msg.Step,
+		Error:      msg.ErrorMsg,
+		FailedTask: msg.FailedTask,
+		CreatedAt:  timex.UnixTime2Time(msg.CreatedAt).Format(timex.TimeLayout),
+		UpdatedAt:  timex.UnixTime2Time(msg.UpdatedAt).Format(timex.TimeLayout),
+	}
+	for step, tasks := range msg.Steps {
+		items := make([]workflowTaskItem, 0, len(tasks))
+		for index, t := range tasks {
+			taskItem := workflowTaskItem{
+				ID:    task.WorkflowTaskID(msg.ID, step, index),
+				Kind:  t.Kind,
+				Queue: t.Options.Queue,
+				State: msg.TaskStates[step][index].String(),
+			}
+			var payload any
+			if err := jsonx.Unmarshal(t.Payload, &payload); err == nil {
+				taskItem.Payload = payload
+			} else {
+				taskItem.Payload = string(t.Payload)
+			}
+			taskItem.Result = string(msg.Results[step][index])
+			items = append(items, taskItem)
+		}
+		item.Steps = append(item.Steps, items)
+	}
+	return item
+}
@@ -49,6 +49,7 @@ func Initial(parentCtx context.Context) (PreCalculateProcessor, error) {
 			window.TraceMetricsReportEnabled(config.EnabledTraceMetricsReport),
 			window.TraceInfoReportEnabled(config.EnabledTraceInfoReport),
 			window.TraceMetricsLayer4ReportEnabled(config.MetricsProcessLayer4ExportEnabled),
+			window.SpanSelfTimeMaxCount(config.SpanSelfTimeMaxCount),
 		).
 		WithStorageConfig(
 			storage.WorkerCount(config.StorageWorkerCount),
@@ -32,6 +32,8 @@ const (
 	SystemApmServiceFlow = "system_to_apm_service_flow"
 	ApmServiceSystemFlow = "apm_service_to_system_flow"
 	SystemFlow           = "system_to_system_flow"
+
+	ApmServiceCriticalPath = "apm_service_critical_path_duration"
 )
 
 // Flow metrics category and kind
@@ -10,6 +10,8 @@
 package window
 
 import (
+	"sort"
+
 	"golang.org/x/exp/slices"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/apm/pre_calculate/core"
@@ -129,6 +131,156 @@ func (g *DiGraph) NodeDepths() []NodeDegree {
 	return res
 }
 
+// CriticalPathNode Span on the critical path and the time (unit: μs) it contributes to the path
+type CriticalPathNode struct {
+	Node     Node
+	Duration int
+}
+
+// SelfTimes Get the self-time of each span (unit: μs).
+// Self-time is the span duration minus the union of its children intervals,
+// so concurrent children will not be subtracted repeatedly.
+func (g *DiGraph) SelfTimes() map[string]int {
+	res := make(map[string]int, len(g.Nodes))
+
+	for _, node := range g.Nodes {
+		var intervals [][2]int
+		for _, child := range g.Edges[node.SpanId] {
+			start := max(child.StartTime, node.StartTime)
+			end := min(child.EndTime, node.EndTime)
+			if end > start {
+				intervals = append(intervals, [2]int{start, end})
+			}
+		}
+		sort.Slice(intervals, func(i, j int) bool {
+			return intervals[i][0] < intervals[j][0]
+		})
+
+		childrenTime := 0
+		cursor := node.StartTime
+		for _, interval := range intervals {
+			start := max(interval[0], cursor)
+			if interval[1] > start {
+				childrenTime += interval[1] - start
+				cursor = interval[1]
+			}
+		}
+
+		res[node.SpanId] = max(node.Elapsed()-childrenTime, 0)
+	}
+
+	return res
+}
+
+// CriticalPath Get the critical path of the trace, which is ordered by start time.
+// Walk backwards from the end of the trace, every time choose the last-finishing child
+// which starts before the cursor, so concurrent children that do not block their parent
+// will not be on the path. The end of a span is extended by its descendants,
+// so asynchronous children that finish after their parent are also taken into account.
+func (g *DiGraph) CriticalPath() []CriticalPathNode {
+	nodeMapping := make(map[string]Node, len(g.Nodes))
+	for _, node := range g.Nodes {
+		if _, exist := nodeMapping[node.SpanId]; !exist {
+			nodeMapping[node.SpanId] = node
+		}
+	}
+
+	// subtreeEnds: the latest end time of span and its descendants
+	subtreeEnds := make(map[string]int, len(nodeMapping))
+	var subtreeEnd func(Node) int
+	subtreeEnd = func(node Node) int {
+		if end, exist := subtreeEnds[node.SpanId]; exist {
+			return end
+		}
+		// mark first to avoid endless loop when spans reference each other
+		subtreeEnds[node.SpanId] = node.EndTime
+		end := node.EndTime
+		for _, child := range g.Edges[node.SpanId] {
+			end = max(end, subtreeEnd(child))
+		}
+		subtreeEnds[node.SpanId] = end
+		return end
+	}
+
+	// sortByEnd sort spans by subtree end desc, so the first span which starts before cursor is the last-finishing one
+	sortByEnd := func(nodes []Node) []Node {
+		res := make([]Node, len(nodes))
+		copy(res, nodes)
+		sort.SliceStable(res, func(i, j int) bool {
+			return subtreeEnd(res[i]) > subtreeEnd(res[j])
+		})
+		return res
+	}
+
+	durations := make(map[string]int, len(nodeMapping))
+	attribute := func(node Node, from, to int) {
+		// time out of span (such as waiting for asynchronous consumer) does not belong to any span
+		from = max(from, node.StartTime)
+		to = min(to, node.EndTime)
+		if to > from {
+			durations[node.SpanId] += to - from
+		}
+	}
+
+	walked := make(map[string]bool, len(nodeMapping))
+	var walk func(Node, int)
+	walk = func(node Node, end int) {
+		if walked[node.SpanId] {
+			return
+		}
+		walked[node.SpanId] = true
+
+		cursor := end
+		for _, child := range sortByEnd(g.Edges[node.SpanId]) {
+			if child.StartTime >= cursor {
+				continue
+			}
+			childEnd := min(subtreeEnd(child), cursor)
+			attribute(node, childEnd, cursor)
+			walk(child, childEnd)
+			cursor = child.StartTime
+		}
+		attribute(node, node.StartTime, cursor)
+	}
+
+	// span whose parent is missing is regarded as root, multiple roots are children of a virtual root
+	var roots []Node
+	traceEnd := 0
+	for _, node := range nodeMapping {
+		if _, exist := nodeMapping[node.ParentSpanId]; node.ParentSpanId != "" && exist {
+			continue
+		}
+		roots = append(roots, node)
+		traceEnd = max(traceEnd, subtreeEnd(node))
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].SpanId < roots[j].SpanId
+	})
+
+	cursor := traceEnd
+	for _, root := range sortByEnd(roots) {
+		if root.StartTime >= cursor {
+			continue
+		}
+		end := min(subtreeEnd(root), cursor)
+		walk(root, end)
+		cursor = root.StartTime
+	}
+
+	res := make([]CriticalPathNode, 0, len(durations))
+	for spanId, duration := range durations {
+		res = append(res, CriticalPathNode{Node: nodeMapping[spanId], Duration: duration})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Node.StartTime != res[j].Node.StartTime {
+			return res[i].Node.StartTime < res[j].Node.StartTime
+		}
+		return res[i].Node.SpanId < res[j].Node.SpanId
+	})
+
+	return res
+}
+
 func (g *DiGraph) Length() int {
 	return len(g.Nodes)
 }
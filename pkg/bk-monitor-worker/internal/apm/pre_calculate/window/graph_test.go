@@ -14,6 +14,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/apm/pre_calculate/core"
 )
 
 func TestSpanGraph(t *testing.T) {
@@ -81,3 +83,124 @@ func TestSpanGraph(t *testing.T) {
 	assert.Equal(t, "2ef9eb548c622d19", nodeDegrees[2].Node.SpanId)
 	assert.Equal(t, "4b477f46b2298b0b", nodeDegrees[3].Node.SpanId)
 }
+
+func newTestGraph(spans ...StandardSpan) DiGraph {
+	graph := NewDiGraph()
+	for _, s := range spans {
+		graph.AddNode(Node{StandardSpan: s})
+	}
+	graph.RefreshEdges()
+	return graph
+}
+
+func TestCriticalPath(t *testing.T) {
+	testCases := map[string]struct {
+		spans    []StandardSpan
+		expected map[string]int
+		order    []string
+	}{
+		"串行子节点": {
+			spans: []StandardSpan{
+				{SpanId: "root", StartTime: 0, EndTime: 100},
+				{SpanId: "a", ParentSpanId: "root", StartTime: 10, EndTime: 40},
+				{SpanId: "b", ParentSpanId: "root", StartTime: 50, EndTime: 90},
+			},
+			expected: map[string]int{"root": 30, "a": 30, "b": 40},
+			order:    []string{"root", "a", "b"},
+		},
+		"并发子节点只取最晚结束的": {
+			spans: []StandardSpan{
+				{SpanId: "root", StartTime: 0, EndTime: 100},
+				{SpanId: "a", ParentSpanId: "root", StartTime: 10, EndTime: 80},
+				{SpanId: "b", ParentSpanId: "root", StartTime: 20, EndTime: 60},
+			},
+			expected: map[string]int{"root": 30, "a": 70},
+			order:    []string{"root", "a"},
+		},
+		"并发子节点部分重叠": {
+			spans: []StandardSpan{
+				{SpanId: "root", StartTime: 0, EndTime: 100},
+				{SpanId: "a", ParentSpanId: "root", StartTime: 10, EndTime: 50},
+				{SpanId: "b", ParentSpanId: "root", StartTime: 40, EndTime: 90},
+			},
+			expected: map[string]int{"root": 20, "a": 30, "b": 50},
+			order:    []string{"root", "a", "b"},
+		},
+		"异步子节点晚于父节点结束": {
+			spans: []StandardSpan{
+				{SpanId: "producer", StartTime: 0, EndTime: 20},
+				{SpanId: "consumer", ParentSpanId: "producer", StartTime: 30, EndTime: 100},
+				{SpanId: "db", ParentSpanId: "consumer", StartTime: 40, EndTime: 60},
+			},
+			expected: map[string]int{"producer": 20, "consumer": 50, "db": 20},
+			order:    []string{"producer", "consumer", "db"},
+		},
+		"多个根节点": {
+			spans: []StandardSpan{
+				{SpanId: "a", ParentSpanId: "missing", StartTime: 0, EndTime: 50},
+				{SpanId: "b", StartTime: 10, EndTime: 30},
+				{SpanId: "c", ParentSpanId: "missing", StartTime: 60, EndTime: 80},
+			},
+			expected: map[string]int{"a": 50, "c": 20},
+			order:    []string{"a", "c"},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			graph := newTestGraph(c.spans...)
+			path := graph.CriticalPath()
+
+			durations := make(map[string]int)
+			var order []string
+			for _, item := range path {
+				durations[item.Node.SpanId] = item.Duration
+				order = append(order, item.Node.SpanId)
+			}
+			assert.Equal(t, c.expected, durations)
+			assert.Equal(t, c.order, order)
+		})
+	}
+}
+
+func TestSelfTimes(t *testing.T) {
+	graph := newTestGraph(
+		StandardSpan{SpanId: "root", StartTime: 0, EndTime: 100},
+		StandardSpan{SpanId: "a", ParentSpanId: "root", StartTime: 10, EndTime: 50},
+		StandardSpan{SpanId: "b", ParentSpanId: "root", StartTime: 40, EndTime: 70},
+		StandardSpan{SpanId: "c", ParentSpanId: "root", StartTime: 90, EndTime: 120},
+		StandardSpan{SpanId: "d", ParentSpanId: "a", StartTime: 20, EndTime: 30},
+	)
+
+	assert.Equal(t, map[string]int{"root": 30, "a": 30, "b": 30, "c": 30, "d": 10}, graph.SelfTimes())
+}
+
+func TestToCriticalPathSpans(t *testing.T) {
+	service := func(name string) map[string]string {
+		return map[string]string{core.ServiceNameField.DisplayKey(): name}
+	}
+	graph := newTestGraph(
+		StandardSpan{SpanId: "root", StartTime: 0, EndTime: 100, Collections: service("api")},
+		StandardSpan{SpanId: "a", ParentSpanId: "root", StartTime: 10, EndTime: 50, Collections: service("db")},
+		StandardSpan{SpanId: "b", ParentSpanId: "root", StartTime: 60, EndTime: 90, Collections: service("cache")},
+	)
+
+	selfTimes := graph.SelfTimes()
+	spans, total, services := toCriticalPathSpans(graph, selfTimes)
+	assert.Len(t, spans, 3)
+	assert.Equal(t, 100, total)
+	// 按耗时倒序，服务名不作为字段名
+	assert.Equal(t, []ServiceDuration{
+		{ServiceName: "db", Duration: 40},
+		{ServiceName: "api", Duration: 30},
+		{ServiceName: "cache", Duration: 30},
+	}, services)
+
+	// 全部 span 均保存自身耗时，配置上限时按自身耗时倒序保留
+	assert.Equal(t, []SpanSelfTime{
+		{SpanId: "a", SelfTime: 40},
+		{SpanId: "b", SelfTime: 30},
+		{SpanId: "root", SelfTime: 30},
+	}, toSpanSelfTimes(selfTimes, 0))
+	assert.Equal(t, []SpanSelfTime{{SpanId: "a", SelfTime: 40}}, toSpanSelfTimes(selfTimes, 1))
+}
@@ -58,7 +58,7 @@ type MetricProcessor struct {
 func (m *MetricProcessor) ToMetrics(receiver chan<- storage.SaveRequest, fullTreeGraph DiGraph) {
 	flowIgnoreSpanIds := m.findSpanMetric(receiver, fullTreeGraph)
 	m.findParentChildAndAloneFlowMetric(receiver, fullTreeGraph, flowIgnoreSpanIds)
-
+	m.findCriticalPathMetric(receiver, fullTreeGraph)
 }
 
 func (m *MetricProcessor) findSpanMetric(
@@ -347,6 +347,47 @@ func (m *MetricProcessor) findParentChildAndAloneFlowMetric(
 	}
 }
 
+// findCriticalPathMetric find the time spent on the critical path of each service and operation.
+// Durations of the same operation in one trace are summed up, spans from history are ignored to avoid repeated report.
+func (m *MetricProcessor) findCriticalPathMetric(receiver chan<- storage.SaveRequest, fullTreeGraph DiGraph) {
+	durations := make(map[string]int)
+
+	for _, item := range fullTreeGraph.CriticalPath() {
+		if item.Node.IsFromHistory() {
+			continue
+		}
+		serviceName := item.Node.GetFieldValue(core.ServiceNameField)
+		if serviceName == "" {
+			continue
+		}
+
+		labelKey := strings.Join(
+			[]string{
+				pair("__name__", storage.ApmServiceCriticalPath),
+				pair("apm_application_name", m.appName),
+				pair("apm_service_name", serviceName),
+				pair("span_name", item.Node.SpanName),
+			},
+			",",
+		)
+		durations[labelKey] += item.Duration
+	}
+
+	if len(durations) == 0 {
+		return
+	}
+
+	metricRecordMapping := make(map[string]*storage.FlowMetricRecordStats, len(durations))
+	for labelKey, duration := range durations {
+		m.addToStats(labelKey, duration, metricRecordMapping)
+	}
+	m.sendToSave(
+		storage.PrometheusStorageData{Kind: storage.PromFlowMetric, Value: metricRecordMapping},
+		map[string]int{storage.ApmServiceCriticalPath: len(durations)},
+		receiver,
+	)
+}
+
 func (m *MetricProcessor) getOppositeSpanKind(kind int) int {
 	if slices.Contains(CallerKinds, kind) {
 		if kind == int(core.KindClient) {
@@ -61,6 +61,38 @@ type ProcessResult struct {
 	CategoryStatistics    map[core.SpanCategory]int     `json:"category_statistics"`
 	KindStatistics        map[core.SpanKindCategory]int `json:"kind_statistics"`
 	Collections           map[string][]string           `json:"collections"`
+	// CriticalPath spans on the critical path, ordered by start time
+	CriticalPath         []CriticalPathSpan `json:"critical_path"`
+	CriticalPathDuration int                `json:"critical_path_duration"`
+	// ServiceCriticalPathDuration time spent on the critical path by each service, ordered by duration desc.
+	// Saved as a list rather than a map so that service names do not become dynamic field names in ES.
+	ServiceCriticalPathDuration []ServiceDuration `json:"service_critical_path_duration"`
+	// SpanSelfTimes self-time of every span, ordered by self-time desc.
+	// If spanSelfTimeMaxCount is configured, only the top spans are saved.
+	SpanSelfTimes []SpanSelfTime `json:"span_self_times"`
+}
+
+// ServiceDuration time (unit: μs) spent by a service
+type ServiceDuration struct {
+	ServiceName string `json:"service_name"`
+	Duration    int    `json:"duration"`
+}
+
+// SpanSelfTime self-time (unit: μs) of a span, saved as a list rather than a map so that span ids
+// do not become dynamic field names in ES.
+type SpanSelfTime struct {
+	SpanId   string `json:"span_id"`
+	SelfTime int    `json:"self_time"`
+}
+
+// CriticalPathSpan span on the critical path, duration is the time it contributes to the path (unit: μs).
+type CriticalPathSpan struct {
+	SpanId      string `json:"span_id"`
+	SpanName    string `json:"span_name"`
+	ServiceName string `json:"service_name"`
+	Kind        int    `json:"kind"`
+	Duration    int    `json:"duration"`
+	SelfTime    int    `json:"self_time"`
 }
 
 type Processor struct {
@@ -399,6 +431,9 @@ func (p *Processor) ToTraceInfo(receiver chan<- storage.SaveRequest, event Event
 		// determine statusCode additionally so that this field can support <null> value in json
 		res.RootServiceStatusCode = &statusCodeOptional
 	}
+	selfTimes := event.Graph.SelfTimes()
+	res.CriticalPath, res.CriticalPathDuration, res.ServiceCriticalPathDuration = toCriticalPathSpans(event.Graph, selfTimes)
+	res.SpanSelfTimes = toSpanSelfTimes(selfTimes, p.config.spanSelfTimeMaxCount)
 
 	p.sendStorageRequests(receiver, res, event)
 }
@@ -436,6 +471,62 @@ func (p *Processor) sendStorageRequests(receiver chan<- storage.SaveRequest, res
 	}
 }
 
+// toCriticalPathSpans Convert critical path of graph to the format saved in trace info
+func toCriticalPathSpans(graph DiGraph, selfTimes map[string]int) ([]CriticalPathSpan, int, []ServiceDuration) {
+	criticalPath := graph.CriticalPath()
+
+	spans := make([]CriticalPathSpan, 0, len(criticalPath))
+	serviceDuration := make(map[string]int)
+	totalDuration := 0
+	for _, item := range criticalPath {
+		serviceName := item.Node.GetFieldValue(core.ServiceNameField)
+		spans = append(spans, CriticalPathSpan{
+			SpanId:      item.Node.SpanId,
+			SpanName:    item.Node.SpanName,
+			ServiceName: serviceName,
+			Kind:        item.Node.Kind,
+			Duration:    item.Duration,
+			SelfTime:    selfTimes[item.Node.SpanId],
+		})
+		if serviceName != "" {
+			serviceDuration[serviceName] += item.Duration
+		}
+		totalDuration += item.Duration
+	}
+
+	serviceDurations := make([]ServiceDuration, 0, len(serviceDuration))
+	for serviceName, d := range serviceDuration {
+		serviceDurations = append(serviceDurations, ServiceDuration{ServiceName: serviceName, Duration: d})
+	}
+	sort.Slice(serviceDurations, func(i, j int) bool {
+		if serviceDurations[i].Duration != serviceDurations[j].Duration {
+			return serviceDurations[i].Duration > serviceDurations[j].Duration
+		}
+		return serviceDurations[i].ServiceName < serviceDurations[j].ServiceName
+	})
+
+	return spans, totalDuration, serviceDurations
+}
+
+// toSpanSelfTimes Convert self-time of spans to the format saved in trace info, ordered by self-time desc.
+// maxCount <= 0 means all spans are saved.
+func toSpanSelfTimes(selfTimes map[string]int, maxCount int) []SpanSelfTime {
+	res := make([]SpanSelfTime, 0, len(selfTimes))
+	for spanId, selfTime := range selfTimes {
+		res = append(res, SpanSelfTime{SpanId: spanId, SelfTime: selfTime})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].SelfTime != res[j].SelfTime {
+			return res[i].SelfTime > res[j].SelfTime
+		}
+		return res[i].SpanId < res[j].SpanId
+	})
+	if maxCount > 0 && len(res) > maxCount {
+		res = res[:maxCount]
+	}
+	return res
+}
+
 func sortNode(nodeDegrees []NodeDegree) func(a, b int) bool {
 	return func(a, b int) bool {
 		aItem := nodeDegrees[a]
@@ -538,6 +629,7 @@ type ProcessorOptions struct {
 	metricReportEnabled       bool
 	infoReportEnabled         bool
 	metricLayer4ReportEnabled bool
+	spanSelfTimeMaxCount      int
 }
 
 type ProcessorOption func(*ProcessorOptions)
@@ -578,6 +670,14 @@ func TraceMetricsLayer4ReportEnabled(e bool) ProcessorOption {
 	}
 }
 
+// SpanSelfTimeMaxCount The maximum number of spans whose self-time is saved in trace info,
+// spans with the longest self-time are kept. default is 0, which means all spans are saved.
+func SpanSelfTimeMaxCount(c int) ProcessorOption {
+	return func(options *ProcessorOptions) {
+		options.spanSelfTimeMaxCount = c
+	}
+}
+
 func NewProcessor(ctx context.Context, dataId string, storageProxy *storage.Proxy, options ...ProcessorOption) Processor {
 	opts := ProcessorOptions{}
 	for _, setter := range options {
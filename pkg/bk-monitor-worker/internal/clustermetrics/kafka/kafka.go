@@ -0,0 +1,383 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/storage"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/service"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/mysql"
+	redisStore "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/redis"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	MetricBrokers              = "kafka_brokers"
+	MetricBrokerInfo           = "kafka_broker_info"
+	MetricTopicPartitions      = "kafka_topic_partitions"
+	MetricTopicUnderReplicated = "kafka_topic_under_replicated_partitions"
+	MetricConsumerGroupLag     = "kafka_consumergroup_lag"
+	MetricConsumerGroupLagSum  = "kafka_consumergroup_lag_sum"
+
+	// internalTopicPrefix kafka 内部 topic 前缀，如 __consumer_offsets，不做采集
+	internalTopicPrefix = "__"
+)
+
+type Instance struct {
+	ClusterName string
+	HostName    string
+	Cluster     storage.ClusterInfo
+}
+
+func (inst *Instance) GetContext() map[string]string {
+	return map[string]string{
+		config.ClusterMetricClusterFieldName: inst.ClusterName,
+		config.ClusterMetricHostFieldName:    inst.HostName,
+	}
+}
+
+// ReportKafkaClusterMetric 采集 metadata 中注册的 kafka 集群指标并写入 KV 存储
+func ReportKafkaClusterMetric(ctx context.Context, t *t.Task) error {
+	var clusters []storage.ClusterInfo
+	dbSession := mysql.GetDBSession()
+	err := storage.NewClusterInfoQuerySet(dbSession.DB).ClusterTypeEq(models.StorageTypeKafka).All(&clusters)
+	if err != nil {
+		logger.Errorf("Fail to query kafka ClusterInfo records, %v", err)
+		return err
+	}
+	metrics, err := clustermetrics.QueryClusterMetrics(ctx, models.StorageTypeKafka)
+	if err != nil {
+		logger.Errorf("Fail to query ClusterMetric, %v", err)
+		return err
+	}
+
+	instances := make([]*Instance, 0, len(clusters))
+	for _, cluster := range clusters {
+		instances = append(instances, &Instance{
+			ClusterName: cluster.ClusterName,
+			HostName:    fmt.Sprintf("%s:%v", cluster.DomainName, cluster.Port),
+			Cluster:     cluster,
+		})
+	}
+
+	redisClient := redisStore.GetStorageRedisInstance()
+	ks := clustermetrics.KvShipper{RedisClient: redisClient}
+	recordQueue := make(chan *clustermetrics.Record)
+	bl := BatchLoader{
+		wg:          &sync.WaitGroup{},
+		semaphore:   make(chan struct{}, clustermetrics.GetGoroutineLimit("report_kafka")),
+		recordQueue: recordQueue,
+		instances:   instances,
+		metrics:     metrics,
+		newClient: func(c storage.ClusterInfo) (sarama.Client, error) {
+			return service.NewClusterInfoSvc(&c).GetKafkaClient()
+		},
+	}
+	go bl.Load(ctx)
+	for {
+		select {
+		case record, ok := <-recordQueue:
+			if !ok {
+				return nil
+			}
+			logger.Infof("Load record(%v), start to write to kv store", record.Print())
+			ks.Write(ctx, record)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type BatchLoader struct {
+	wg          *sync.WaitGroup
+	semaphore   chan struct{}
+	recordQueue chan *clustermetrics.Record
+	instances   []*Instance
+	metrics     []clustermetrics.ClusterMetric
+	newClient   func(storage.ClusterInfo) (sarama.Client, error)
+}
+
+func (bl *BatchLoader) Load(ctx context.Context) {
+loop:
+	for _, instance := range bl.instances {
+		select {
+		case bl.semaphore <- struct{}{}:
+			bl.wg.Add(1)
+			go func(inst *Instance) {
+				defer func() {
+					bl.wg.Done()
+					<-bl.semaphore
+				}()
+				bl.loadClusterMetrics(ctx, inst)
+			}(instance)
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	bl.wg.Wait()
+	close(bl.recordQueue)
+}
+
+func (bl *BatchLoader) loadClusterMetrics(ctx context.Context, instance *Instance) {
+	client, err := bl.newClient(instance.Cluster)
+	if err != nil {
+		logger.Errorf("loadClusterMetrics:Fail to create kafka client of cluster(%s), %v", instance.ClusterName, err)
+		return
+	}
+	// admin 关闭时会同时关闭 client
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		logger.Errorf("loadClusterMetrics:Fail to create kafka admin of cluster(%s), %v", instance.ClusterName, err)
+		return
+	}
+	defer admin.Close()
+
+	values, err := collectKafkaMetrics(client, admin, config.KafkaClusterMetricPartitionLagLimit)
+	if err != nil {
+		logger.Errorf("loadClusterMetrics:Fail to collect kafka cluster(%s) metrics, %v", instance.ClusterName, err)
+		return
+	}
+
+	for _, record := range toRecords(instance, bl.metrics, values, time.Now()) {
+		select {
+		case bl.recordQueue <- record:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// toRecords 将采集结果按照指标配置组装成 Record，并补充时间及 bkm_% 内置标签字段
+func toRecords(instance clustermetrics.ClusterInstance, metrics []clustermetrics.ClusterMetric,
+	values map[string][]map[string]interface{}, now time.Time,
+) []*clustermetrics.Record {
+	var records []*clustermetrics.Record
+	for _, m := range metrics {
+		data := values[m.MetricName]
+		if len(data) == 0 {
+			continue
+		}
+		for _, d := range data {
+			d["time"] = float64(now.Unix())
+			for k, v := range instance.GetContext() {
+				if m.IsInTags(k) {
+					d[k] = v
+				}
+			}
+		}
+		recordMetric := m
+		records = append(records, &clustermetrics.Record{Instance: instance, Metric: &recordMetric, Data: data})
+	}
+	return records
+}
+
+// collectKafkaMetrics 采集 broker、topic 分区副本及消费组 lag 指标，返回 指标名 -> 数据点 列表
+// 分区维度的 lag 序列数随消费组及分区数增长，最多上报 partitionLagLimit 条，为 0 时只上报 topic 维度的 lag_sum
+func collectKafkaMetrics(client sarama.Client, admin sarama.ClusterAdmin, partitionLagLimit int) (map[string][]map[string]interface{}, error) {
+	res := make(map[string][]map[string]interface{})
+
+	brokers := client.Brokers()
+	res[MetricBrokers] = append(res[MetricBrokers], map[string]interface{}{"value": float64(len(brokers))})
+	for _, broker := range brokers {
+		res[MetricBrokerInfo] = append(res[MetricBrokerInfo], map[string]interface{}{
+			"value":     float64(1),
+			"broker_id": strconv.Itoa(int(broker.ID())),
+			"address":   broker.Addr(),
+		})
+	}
+
+	topics, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(topics)
+
+	topicPartitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		if strings.HasPrefix(topic, internalTopicPrefix) {
+			continue
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			logger.Warnf("collectKafkaMetrics:Fail to get partitions of topic(%s), %v", topic, err)
+			continue
+		}
+		topicPartitions[topic] = partitions
+
+		underReplicated := 0
+		for _, partition := range partitions {
+			replicas, err := client.Replicas(topic, partition)
+			if err != nil {
+				logger.Warnf("collectKafkaMetrics:Fail to get replicas of topic(%s) partition(%d), %v", topic, partition, err)
+			}
+			isr, err := client.InSyncReplicas(topic, partition)
+			if err != nil {
+				logger.Warnf("collectKafkaMetrics:Fail to get isr of topic(%s) partition(%d), %v", topic, partition, err)
+			}
+			if len(isr) < len(replicas) {
+				underReplicated++
+			}
+		}
+
+		res[MetricTopicPartitions] = append(res[MetricTopicPartitions], map[string]interface{}{
+			"value": float64(len(partitions)),
+			"topic": topic,
+		})
+		res[MetricTopicUnderReplicated] = append(res[MetricTopicUnderReplicated], map[string]interface{}{
+			"value": float64(underReplicated),
+			"topic": topic,
+		})
+	}
+
+	groups, err := admin.ListConsumerGroups()
+	if err != nil {
+		// 消费组获取失败时，仍然上报 broker 及 topic 指标
+		logger.Warnf("collectKafkaMetrics:Fail to list consumer groups, %v", err)
+		return res, nil
+	}
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+
+	// 记录各分区最新 offset，用于计算消费组 lag
+	newestOffsets := fetchNewestOffsets(client, topicPartitions)
+	var partitionLags, droppedLags int
+	for _, group := range groupNames {
+		// 不指定分区时获取消费组所有已提交的 offset
+		resp, err := admin.ListConsumerGroupOffsets(group, nil)
+		if err != nil {
+			logger.Warnf("collectKafkaMetrics:Fail to list offsets of consumer group(%s), %v", group, err)
+			continue
+		}
+		groupTopics := make([]string, 0, len(resp.Blocks))
+		for topic := range resp.Blocks {
+			groupTopics = append(groupTopics, topic)
+		}
+		sort.Strings(groupTopics)
+
+		for _, topic := range groupTopics {
+			offsets, ok := newestOffsets[topic]
+			if !ok {
+				continue
+			}
+			blocks := resp.Blocks[topic]
+			partitions := make([]int32, 0, len(blocks))
+			for partition := range blocks {
+				partitions = append(partitions, partition)
+			}
+			sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+			var (
+				lagSum   int64
+				hasBlock bool
+			)
+			for _, partition := range partitions {
+				block := blocks[partition]
+				newest, ok := offsets[partition]
+				if !ok || block == nil || block.Err != sarama.ErrNoError || block.Offset < 0 {
+					continue
+				}
+				lag := newest - block.Offset
+				if lag < 0 {
+					lag = 0
+				}
+				lagSum += lag
+				hasBlock = true
+
+				if partitionLags >= partitionLagLimit {
+					droppedLags++
+					continue
+				}
+				partitionLags++
+				res[MetricConsumerGroupLag] = append(res[MetricConsumerGroupLag], map[string]interface{}{
+					"value":         float64(lag),
+					"consumergroup": group,
+					"topic":         topic,
+					"partition":     strconv.Itoa(int(partition)),
+				})
+			}
+			if hasBlock {
+				res[MetricConsumerGroupLagSum] = append(res[MetricConsumerGroupLagSum], map[string]interface{}{
+					"value":         float64(lagSum),
+					"consumergroup": group,
+					"topic":         topic,
+				})
+			}
+		}
+	}
+	if partitionLagLimit > 0 && droppedLags > 0 {
+		logger.Warnf("collectKafkaMetrics:Partition lag exceeds limit(%d), %d series are dropped", partitionLagLimit, droppedLags)
+	}
+
+	return res, nil
+}
+
+// fetchNewestOffsets 按分区的 leader broker 批量获取最新 offset，每个 broker 只发送一次 OffsetRequest
+func fetchNewestOffsets(client sarama.Client, topicPartitions map[string][]int32) map[string]map[int32]int64 {
+	var version int16
+	if client.Config().Version.IsAtLeast(sarama.V0_10_1_0) {
+		version = 1
+	}
+
+	requests := make(map[*sarama.Broker]*sarama.OffsetRequest)
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			leader, err := client.Leader(topic, partition)
+			if err != nil {
+				logger.Warnf("collectKafkaMetrics:Fail to get leader of topic(%s) partition(%d), %v", topic, partition, err)
+				continue
+			}
+			request, ok := requests[leader]
+			if !ok {
+				request = &sarama.OffsetRequest{Version: version}
+				requests[leader] = request
+			}
+			request.AddBlock(topic, partition, sarama.OffsetNewest, 1)
+		}
+	}
+
+	newestOffsets := make(map[string]map[int32]int64, len(topicPartitions))
+	for leader, request := range requests {
+		resp, err := leader.GetAvailableOffsets(request)
+		if err != nil {
+			logger.Warnf("collectKafkaMetrics:Fail to get newest offsets from broker(%s), %v", leader.Addr(), err)
+			continue
+		}
+		for topic, blocks := range resp.Blocks {
+			for partition, block := range blocks {
+				if block.Err != sarama.ErrNoError || len(block.Offsets) == 0 {
+					logger.Warnf("collectKafkaMetrics:Fail to get newest offset of topic(%s) partition(%d), %v", topic, partition, block.Err)
+					continue
+				}
+				if newestOffsets[topic] == nil {
+					newestOffsets[topic] = make(map[int32]int64)
+				}
+				newestOffsets[topic][partition] = block.Offsets[0]
+			}
+		}
+	}
+	return newestOffsets
+}
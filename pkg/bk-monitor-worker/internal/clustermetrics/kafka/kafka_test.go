@@ -0,0 +1,184 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics"
+)
+
+type fakeClient struct {
+	sarama.Client
+	brokers    []*sarama.Broker
+	partitions map[string][]int32
+	replicas   map[string][]int32
+	isr        map[string][]int32
+	// leaders 分区 -> leader broker，按分区号取模
+	leaders []*sarama.Broker
+}
+
+func (c *fakeClient) Brokers() []*sarama.Broker { return c.brokers }
+
+func (c *fakeClient) Topics() ([]string, error) {
+	var topics []string
+	for topic := range c.partitions {
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
+
+func (c *fakeClient) Partitions(topic string) ([]int32, error) { return c.partitions[topic], nil }
+
+func (c *fakeClient) Replicas(topic string, partition int32) ([]int32, error) {
+	return c.replicas[topic], nil
+}
+
+func (c *fakeClient) InSyncReplicas(topic string, partition int32) ([]int32, error) {
+	if partition == 0 {
+		return c.isr[topic], nil
+	}
+	return c.replicas[topic], nil
+}
+
+func (c *fakeClient) Config() *sarama.Config {
+	conf := sarama.NewConfig()
+	conf.Version = sarama.V0_10_2_0
+	return conf
+}
+
+func (c *fakeClient) Leader(topic string, partition int32) (*sarama.Broker, error) {
+	return c.leaders[int(partition)%len(c.leaders)], nil
+}
+
+// newLeader 启动 mock broker，分区最新 offset 由 offsets 决定
+func newLeader(t *testing.T, id int32, offsets map[string]map[int32]int64) (*sarama.MockBroker, *sarama.Broker) {
+	mockBroker := sarama.NewMockBroker(t, id)
+	// 与 fakeClient 的 kafka 版本对应，使用 v1 的 OffsetRequest
+	resp := sarama.NewMockOffsetResponse(t).SetVersion(1)
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			resp.SetOffset(topic, partition, sarama.OffsetNewest, offset)
+		}
+	}
+	mockBroker.SetHandlerByMap(map[string]sarama.MockResponse{"OffsetRequest": resp})
+
+	broker := sarama.NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(sarama.NewConfig()))
+	t.Cleanup(func() {
+		_ = broker.Close()
+		mockBroker.Close()
+	})
+	return mockBroker, broker
+}
+
+type fakeAdmin struct {
+	sarama.ClusterAdmin
+	groupOffsets map[string]map[string]map[int32]int64
+}
+
+func (a *fakeAdmin) ListConsumerGroups() (map[string]string, error) {
+	groups := make(map[string]string)
+	for group := range a.groupOffsets {
+		groups[group] = "consumer"
+	}
+	return groups, nil
+}
+
+func (a *fakeAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	resp := &sarama.OffsetFetchResponse{}
+	for topic, offsets := range a.groupOffsets[group] {
+		for partition, offset := range offsets {
+			resp.AddBlock(topic, partition, &sarama.OffsetFetchResponseBlock{Offset: offset, Err: sarama.ErrNoError})
+		}
+	}
+	return resp, nil
+}
+
+func TestCollectKafkaMetrics(t *testing.T) {
+	// 分区 0 的 leader 为 broker 1，分区 1 的 leader 为 broker 2
+	mockBroker1, leader1 := newLeader(t, 1, map[string]map[int32]int64{"topic_a": {0: 100}, "topic_b": {0: 50}})
+	mockBroker2, leader2 := newLeader(t, 2, map[string]map[int32]int64{"topic_a": {1: 200}})
+	client := &fakeClient{
+		brokers: []*sarama.Broker{sarama.NewBroker("127.0.0.1:9092"), sarama.NewBroker("127.0.0.2:9092")},
+		partitions: map[string][]int32{
+			"topic_a":            {0, 1},
+			"topic_b":            {0},
+			"__consumer_offsets": {0},
+		},
+		replicas: map[string][]int32{"topic_a": {1, 2}, "topic_b": {1, 2}},
+		isr:      map[string][]int32{"topic_a": {1}, "topic_b": {1, 2}},
+		leaders:  []*sarama.Broker{leader1, leader2},
+	}
+	admin := &fakeAdmin{groupOffsets: map[string]map[string]map[int32]int64{
+		"group_1": {"topic_a": {0: 90, 1: 150}},
+		// 已提交 offset 大于最新 offset 时 lag 为 0，未知 topic 忽略
+		"group_2": {"topic_b": {0: 60}, "unknown": {0: 1}},
+	}}
+
+	values, err := collectKafkaMetrics(client, admin, 0)
+	assert.NoError(t, err)
+
+	// 每个 leader broker 只发送一次 OffsetRequest
+	assert.Len(t, mockBroker1.History(), 1)
+	assert.Len(t, mockBroker2.History(), 1)
+
+	assert.Equal(t, []map[string]interface{}{{"value": float64(2)}}, values[MetricBrokers])
+	assert.Len(t, values[MetricBrokerInfo], 2)
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(2), "topic": "topic_a"},
+		{"value": float64(1), "topic": "topic_b"},
+	}, values[MetricTopicPartitions])
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(1), "topic": "topic_a"},
+		{"value": float64(0), "topic": "topic_b"},
+	}, values[MetricTopicUnderReplicated])
+	// 默认只上报 topic 维度的 lag_sum
+	assert.Empty(t, values[MetricConsumerGroupLag])
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(60), "consumergroup": "group_1", "topic": "topic_a"},
+		{"value": float64(0), "consumergroup": "group_2", "topic": "topic_b"},
+	}, values[MetricConsumerGroupLagSum])
+
+	// 分区维度的 lag 超过上限的部分不上报，lag_sum 不受影响
+	values, err = collectKafkaMetrics(client, admin, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(10), "consumergroup": "group_1", "topic": "topic_a", "partition": "0"},
+		{"value": float64(50), "consumergroup": "group_1", "topic": "topic_a", "partition": "1"},
+	}, values[MetricConsumerGroupLag])
+	assert.Len(t, values[MetricConsumerGroupLagSum], 2)
+}
+
+func TestToRecords(t *testing.T) {
+	instance := &Instance{ClusterName: "kafka_cluster", HostName: "127.0.0.1:9092"}
+	config.ClusterMetricClusterFieldName = "bkm_cluster"
+	config.ClusterMetricHostFieldName = "bkm_hostname"
+	metrics := []clustermetrics.ClusterMetric{
+		{MetricName: MetricBrokers, Tags: []string{"bkm_cluster"}, ClusterType: "kafka"},
+		{MetricName: MetricTopicPartitions, Tags: []string{"bkm_cluster", "bkm_hostname", "topic"}, ClusterType: "kafka"},
+	}
+	values := map[string][]map[string]interface{}{
+		MetricBrokers: {{"value": float64(3)}},
+	}
+	now := time.Unix(1700000000, 0)
+
+	records := toRecords(instance, metrics, values, now)
+	assert.Len(t, records, 1)
+	assert.Equal(t, MetricBrokers, records[0].Metric.MetricName)
+	assert.Equal(t, []map[string]interface{}{
+		{"value": float64(3), "time": float64(1700000000), "bkm_cluster": "kafka_cluster"},
+	}, records[0].Data)
+}
@@ -80,6 +80,11 @@ func (cm *ClusterMetric) GetNonBkmTags() []string {
 var configFS embed.FS
 
 func QueryInfluxdbMetrics(ctx context.Context) ([]ClusterMetric, error) {
+	return QueryClusterMetrics(ctx, "influxdb")
+}
+
+// QueryClusterMetrics 从 meta.yaml 中获取指定集群类型的指标配置
+func QueryClusterMetrics(ctx context.Context, clusterType string) ([]ClusterMetric, error) {
 	data, err := fs2.ReadFile(configFS, "meta.yaml")
 	if err != nil {
 		return nil, errors.Errorf("Fail to load cluster metrics from meta.yaml, %+v", err)
@@ -89,5 +94,11 @@ func QueryInfluxdbMetrics(ctx context.Context) ([]ClusterMetric, error) {
 	if err != nil {
 		return nil, errors.Errorf("meta.confg is not yaml format, %+v", err)
 	}
-	return metaCfg.Metrics, nil
+	var metrics []ClusterMetric
+	for _, m := range metaCfg.Metrics {
+		if m.ClusterType == clusterType {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
 }
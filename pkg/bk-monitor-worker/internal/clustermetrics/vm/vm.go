@@ -0,0 +1,284 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/storage"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/mysql"
+	redisStore "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/redis"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/cipher"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/http"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	// MetricComponentUp 组件 /metrics 是否可以正常拉取，1 为正常，0 为异常
+	MetricComponentUp = "vm_component_up"
+	// DefaultComponent 集群未配置组件地址时，直接拉取集群地址的指标
+	DefaultComponent = "vm"
+)
+
+type Instance struct {
+	ClusterName string
+	HostName    string
+	Component   string
+	Url         string
+	Username    string
+	Password    string
+}
+
+func (inst *Instance) GetContext() map[string]string {
+	return map[string]string{
+		config.ClusterMetricClusterFieldName:   inst.ClusterName,
+		config.ClusterMetricHostFieldName:      inst.HostName,
+		config.ClusterMetricComponentFieldName: inst.Component,
+	}
+}
+
+// customOption VM 集群 custom_option 中的组件地址配置
+// 如: {"components": {"vmstorage": ["127.0.0.1:8482"], "vminsert": ["127.0.0.1:8480"], "vmselect": ["127.0.0.1:8481"]}}
+type customOption struct {
+	Components map[string][]string `json:"components"`
+}
+
+// ReportVmClusterMetric 拉取 metadata 中注册的 VM 集群各组件的指标并写入 KV 存储
+func ReportVmClusterMetric(ctx context.Context, t *t.Task) error {
+	var clusters []storage.ClusterInfo
+	dbSession := mysql.GetDBSession()
+	err := storage.NewClusterInfoQuerySet(dbSession.DB).ClusterTypeEq(models.StorageTypeVM).All(&clusters)
+	if err != nil {
+		logger.Errorf("Fail to query victoria metrics ClusterInfo records, %v", err)
+		return err
+	}
+	metrics, err := clustermetrics.QueryClusterMetrics(ctx, models.StorageTypeVM)
+	if err != nil {
+		logger.Errorf("Fail to query ClusterMetric, %v", err)
+		return err
+	}
+
+	instances := make([]*Instance, 0)
+	for _, cluster := range clusters {
+		clusterInstances, err := composeInstances(cluster)
+		if err != nil {
+			logger.Errorf("Fail to compose instances of vm cluster(%s), %v", cluster.ClusterName, err)
+			continue
+		}
+		instances = append(instances, clusterInstances...)
+	}
+
+	redisClient := redisStore.GetStorageRedisInstance()
+	ks := clustermetrics.KvShipper{RedisClient: redisClient}
+	recordQueue := make(chan *clustermetrics.Record)
+	bl := BatchLoader{
+		wg:          &sync.WaitGroup{},
+		semaphore:   make(chan struct{}, clustermetrics.GetGoroutineLimit("report_vm")),
+		recordQueue: recordQueue,
+		instances:   instances,
+		client:      http.NewClient(),
+		metrics:     metrics,
+	}
+	go bl.Load(ctx)
+	for {
+		select {
+		case record, ok := <-recordQueue:
+			if !ok {
+				return nil
+			}
+			logger.Infof("Load record(%v), start to write to kv store", record.Print())
+			ks.Write(ctx, record)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// composeInstances 根据集群 custom_option 中的组件配置组装实例，未配置时使用集群地址
+func composeInstances(cluster storage.ClusterInfo) ([]*Instance, error) {
+	var option customOption
+	if cluster.CustomOption != "" {
+		if err := jsonx.UnmarshalString(cluster.CustomOption, &option); err != nil {
+			return nil, errors.WithMessage(err, "failed to unmarshal custom option")
+		}
+	}
+	password, err := cipher.GetDBAESCipher().AESDecrypt(cluster.Password)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt password")
+	}
+	schema := "http"
+	if cluster.Schema != nil && *cluster.Schema != "" {
+		schema = *cluster.Schema
+	}
+
+	components := option.Components
+	if len(components) == 0 {
+		components = map[string][]string{
+			DefaultComponent: {fmt.Sprintf("%s:%v", cluster.DomainName, cluster.Port)},
+		}
+	}
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var instances []*Instance
+	for _, name := range names {
+		for _, address := range components[name] {
+			host := address
+			url := address
+			if idx := strings.Index(address, "://"); idx >= 0 {
+				host = address[idx+3:]
+			} else {
+				url = fmt.Sprintf("%s://%s", schema, address)
+			}
+			instances = append(instances, &Instance{
+				ClusterName: cluster.ClusterName,
+				HostName:    strings.TrimSuffix(host, "/"),
+				Component:   name,
+				Url:         strings.TrimSuffix(url, "/") + config.VmClusterMetricPath,
+				Username:    cluster.Username,
+				Password:    password,
+			})
+		}
+	}
+	return instances, nil
+}
+
+type BatchLoader struct {
+	wg          *sync.WaitGroup
+	semaphore   chan struct{}
+	recordQueue chan *clustermetrics.Record
+	instances   []*Instance
+	client      http.Client
+	metrics     []clustermetrics.ClusterMetric
+}
+
+func (bl *BatchLoader) Load(ctx context.Context) {
+loop:
+	for _, instance := range bl.instances {
+		select {
+		case bl.semaphore <- struct{}{}:
+			bl.wg.Add(1)
+			go func(inst *Instance) {
+				defer func() {
+					bl.wg.Done()
+					<-bl.semaphore
+				}()
+				bl.loadInstanceMetrics(ctx, inst)
+			}(instance)
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	bl.wg.Wait()
+	close(bl.recordQueue)
+}
+
+func (bl *BatchLoader) loadInstanceMetrics(ctx context.Context, instance *Instance) {
+	up := float64(1)
+	families, err := bl.scrape(ctx, instance)
+	if err != nil {
+		logger.Errorf("loadInstanceMetrics:Fail to load vm component(%s) metrics of cluster(%s), %v",
+			instance.Url, instance.ClusterName, err)
+		up = 0
+	}
+
+	now := float64(time.Now().Unix())
+	for _, m := range bl.metrics {
+		var recordData []map[string]interface{}
+		if m.MetricName == MetricComponentUp {
+			recordData = append(recordData, map[string]interface{}{"value": up})
+		} else if family, ok := families[m.MetricName]; ok {
+			recordData = familyToData(family)
+		}
+		if len(recordData) == 0 {
+			continue
+		}
+
+		for _, d := range recordData {
+			d["time"] = now
+			// 补充 bkm_% 内置标签字段
+			for k, v := range instance.GetContext() {
+				if m.IsInTags(k) {
+					d[k] = v
+				}
+			}
+		}
+		recordMetric := m
+		select {
+		case bl.recordQueue <- &clustermetrics.Record{Instance: instance, Metric: &recordMetric, Data: recordData}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scrape 拉取组件 /metrics 并解析为 指标名 -> 指标族
+func (bl *BatchLoader) scrape(ctx context.Context, instance *Instance) (map[string]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.VmClusterMetricTimeout)
+	defer cancel()
+
+	resp, err := bl.client.Request(ctx, http.MethodGet, http.Options{
+		BaseUrl:  instance.Url,
+		UserName: instance.Username,
+		Password: instance.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("unexpected status code %d, %s", resp.StatusCode, body)
+	}
+
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+// familyToData 将指标族转换为数据点，标签作为维度字段，仅支持 counter/gauge/untyped 类型
+func familyToData(family *dto.MetricFamily) []map[string]interface{} {
+	var data []map[string]interface{}
+	for _, metric := range family.GetMetric() {
+		d := make(map[string]interface{})
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			d["value"] = metric.GetCounter().GetValue()
+		case dto.MetricType_GAUGE:
+			d["value"] = metric.GetGauge().GetValue()
+		case dto.MetricType_UNTYPED:
+			d["value"] = metric.GetUntyped().GetValue()
+		default:
+			continue
+		}
+		for _, label := range metric.GetLabel() {
+			d[label.GetName()] = label.GetValue()
+		}
+		data = append(data, d)
+	}
+	return data
+}
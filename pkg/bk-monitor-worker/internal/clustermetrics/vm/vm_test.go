@@ -0,0 +1,145 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/storage"
+	httpUtils "github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/http"
+)
+
+const testMetrics = `# HELP vm_rows_inserted_total
+# TYPE vm_rows_inserted_total counter
+vm_rows_inserted_total{type="promremotewrite"} 100
+vm_rows_inserted_total{type="influx"} 20
+# TYPE vm_concurrent_select_current gauge
+vm_concurrent_select_current 3
+# TYPE vm_request_duration_seconds summary
+vm_request_duration_seconds_sum 1
+vm_request_duration_seconds_count 2
+`
+
+func TestComposeInstances(t *testing.T) {
+	schema := "https"
+	testCases := map[string]struct {
+		cluster  storage.ClusterInfo
+		expected []*Instance
+	}{
+		"未配置组件": {
+			cluster: storage.ClusterInfo{ClusterName: "vm", DomainName: "vm.svc", Port: 8481},
+			expected: []*Instance{
+				{ClusterName: "vm", HostName: "vm.svc:8481", Component: DefaultComponent, Url: "http://vm.svc:8481/metrics"},
+			},
+		},
+		"配置组件地址": {
+			cluster: storage.ClusterInfo{
+				ClusterName:  "vm",
+				DomainName:   "vm.svc",
+				Port:         8481,
+				Schema:       &schema,
+				Username:     "admin",
+				Password:     "pwd",
+				CustomOption: `{"components": {"vmstorage": ["10.0.0.1:8482"], "vminsert": ["http://10.0.0.2:8480/"]}}`,
+			},
+			expected: []*Instance{
+				{ClusterName: "vm", HostName: "10.0.0.2:8480", Component: "vminsert", Url: "http://10.0.0.2:8480/metrics", Username: "admin", Password: "pwd"},
+				{ClusterName: "vm", HostName: "10.0.0.1:8482", Component: "vmstorage", Url: "https://10.0.0.1:8482/metrics", Username: "admin", Password: "pwd"},
+			},
+		},
+	}
+
+	config.VmClusterMetricPath = "/metrics"
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			instances, err := composeInstances(c.cluster)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, instances)
+		})
+	}
+}
+
+func TestLoadInstanceMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(testMetrics))
+	}))
+	defer server.Close()
+
+	config.VmClusterMetricTimeout = 5 * time.Second
+	config.ClusterMetricClusterFieldName = "bkm_cluster"
+	config.ClusterMetricHostFieldName = "bkm_hostname"
+	config.ClusterMetricComponentFieldName = "bkm_component"
+	tags := []string{"bkm_cluster", "bkm_hostname", "bkm_component"}
+	metrics := []clustermetrics.ClusterMetric{
+		{MetricName: MetricComponentUp, Tags: tags},
+		{MetricName: "vm_rows_inserted_total", Tags: append(tags, "type")},
+		{MetricName: "vm_concurrent_select_current", Tags: tags},
+		{MetricName: "vm_request_duration_seconds", Tags: tags},
+	}
+
+	load := func(url string) map[string][]map[string]interface{} {
+		recordQueue := make(chan *clustermetrics.Record, 10)
+		bl := BatchLoader{
+			wg:          &sync.WaitGroup{},
+			semaphore:   make(chan struct{}, 1),
+			recordQueue: recordQueue,
+			instances:   []*Instance{{ClusterName: "vm", HostName: "vm.svc", Component: "vmselect", Url: url}},
+			client:      httpUtils.NewClient(),
+			metrics:     metrics,
+		}
+		bl.Load(context.Background())
+
+		res := make(map[string][]map[string]interface{})
+		for record := range recordQueue {
+			for _, d := range record.Data {
+				assert.Equal(t, "vm", d["bkm_cluster"])
+				assert.Equal(t, "vmselect", d["bkm_component"])
+				delete(d, "time")
+				delete(d, "bkm_cluster")
+				delete(d, "bkm_hostname")
+				delete(d, "bkm_component")
+			}
+			res[record.Metric.MetricName] = record.Data
+		}
+		return res
+	}
+
+	t.Run("正常拉取", func(t *testing.T) {
+		res := load(server.URL + "/metrics")
+		assert.Equal(t, map[string][]map[string]interface{}{
+			MetricComponentUp: {{"value": float64(1)}},
+			"vm_rows_inserted_total": {
+				{"value": float64(100), "type": "promremotewrite"},
+				{"value": float64(20), "type": "influx"},
+			},
+			"vm_concurrent_select_current": {{"value": float64(3)}},
+		}, res)
+	})
+
+	t.Run("拉取失败", func(t *testing.T) {
+		res := load(server.URL + "/not_found")
+		assert.Equal(t, map[string][]map[string]interface{}{
+			MetricComponentUp: {{"value": float64(0)}},
+		}, res)
+	})
+}
@@ -236,6 +236,13 @@ const (
 	DatabusStatusStarting = "starting"
 )
 
+const (
+	RecordRuleStatusCreated = "created" // 预计算规则已创建，尚未计算
+	RecordRuleStatusRunning = "running" // 预计算规则计算中
+	RecordRuleStatusFailed  = "failed"  // 预计算规则最近一次计算失败
+	RecordRuleStatusDeleted = "deleted" // 预计算规则已删除
+)
+
 const SystemUser = "system"
 
 const LogReportMaxQPS = 50000 //Log Report Default QPS
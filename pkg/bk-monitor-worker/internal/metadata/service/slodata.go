@@ -12,10 +12,12 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/slo"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
 
 // 导入内置 fmt
@@ -132,3 +134,86 @@ func QueryBizV2(db *gorm.DB, prefix string, suffixes []string) (map[int32][]stri
 
 	return finalResult, nil
 }
+
+// QuerySloObjectives 查询带有 slo 标签及 slo_target 标签的策略，并解析为 slo 目标
+func QuerySloObjectives(db *gorm.DB, prefix string) ([]SloObjective, error) {
+	var results []struct {
+		LabelName  string
+		BkBizID    int32
+		StrategyID int32
+		Name       string
+	}
+
+	res := db.Table("alarm_strategy_label AS label").
+		Select("label.label_name, label.bk_biz_id, label.strategy_id, strategy.name").
+		Joins("INNER JOIN alarm_strategy_v2 AS strategy ON label.strategy_id = strategy.id").
+		Where("label.bk_biz_id != ? AND label.strategy_id != ? AND label.label_name LIKE ?", 0, 0, prefix+"%").
+		Find(&results)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to query data: %v", res.Error)
+	}
+
+	strategies := make(map[int32]SloStrategy)
+	for _, result := range results {
+		// 标签格式为 /slo/场景名称/方法论/
+		parts := strings.Split(strings.Trim(result.LabelName, "/"), "/")
+		if len(parts) != 3 {
+			continue
+		}
+		strategies[result.StrategyID] = SloStrategy{
+			BkBizStrategy: BkBizStrategy{
+				Middle:     parts[1],
+				BkBizID:    result.BkBizID,
+				StrategyID: result.StrategyID,
+				Name:       result.Name,
+			},
+			Velat: parts[2],
+		}
+	}
+	if len(strategies) == 0 {
+		return nil, nil
+	}
+	strategyIds := make([]int32, 0, len(strategies))
+	for strategyId := range strategies {
+		strategyIds = append(strategyIds, strategyId)
+	}
+
+	var labels []struct {
+		LabelName  string
+		StrategyID int32
+	}
+	res = db.Table("alarm_strategy_label").
+		Select("label_name, strategy_id").
+		Where("strategy_id IN (?) AND (label_name LIKE ? OR label_name LIKE ?)", strategyIds, sloTargetLabelPrefix+"%", sloWindowLabelPrefix+"%").
+		Find(&labels)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to query data: %v", res.Error)
+	}
+	strategyLabels := make(map[int32][]string)
+	for _, label := range labels {
+		strategyLabels[label.StrategyID] = append(strategyLabels[label.StrategyID], label.LabelName)
+	}
+
+	var configs []slo.AlarmQueryConfigV2
+	res = db.Where("strategy_id IN (?) AND alias IN (?)", strategyIds, []string{sloGoodQueryAlias, sloTotalQueryAlias}).Find(&configs)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to query data: %v", res.Error)
+	}
+	strategyConfigs := make(map[int32][]slo.AlarmQueryConfigV2)
+	for _, config := range configs {
+		strategyConfigs[config.StrategyID] = append(strategyConfigs[config.StrategyID], config)
+	}
+
+	var objectives []SloObjective
+	for strategyId, strategy := range strategies {
+		objective, err := parseSloObjective(strategy, strategyLabels[strategyId], strategyConfigs[strategyId])
+		if err != nil {
+			logger.Warnf("slo objective strategy [%d] parse failed, %v", strategyId, err)
+			continue
+		}
+		if objective != nil {
+			objectives = append(objectives, *objective)
+		}
+	}
+	return objectives, nil
+}
@@ -0,0 +1,340 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+
+	cfg "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/slo"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/space"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/mysql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/remote"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	// sloTargetLabelPrefix slo 目标标签，格式为 /slo_target/99.9/，值为百分比
+	sloTargetLabelPrefix = "/slo_target/"
+	// sloWindowLabelPrefix slo 滚动窗口标签，格式为 /slo_window/30d/，未设置时使用 defaultSloWindow
+	sloWindowLabelPrefix = "/slo_window/"
+	// sloGoodQueryAlias 策略中 good 事件数查询的别名
+	sloGoodQueryAlias = "good"
+	// sloTotalQueryAlias 策略中 total 事件数查询的别名
+	sloTotalQueryAlias = "total"
+)
+
+// defaultSloWindow 默认的 slo 滚动窗口
+const defaultSloWindow = 30 * 24 * time.Hour
+
+// SloStrategy 带有 slo 标签的策略
+type SloStrategy struct {
+	BkBizStrategy
+	// Velat 方法论，例如 availability
+	Velat string
+}
+
+// SloObjective 从策略中解析的 slo 目标，good/total 查询中的时间范围会被替换为各窗口的时长
+type SloObjective struct {
+	Strategy   SloStrategy
+	Target     float64
+	Window     time.Duration
+	GoodQuery  string
+	TotalQuery string
+}
+
+// SloQuerier slo 目标 good/total 查询
+type SloQuerier interface {
+	QueryInstant(ctx context.Context, spaceUid, promql string, ts time.Time, step time.Duration) ([]remote.PromSample, error)
+}
+
+// BurnRateAlertState 燃烧率告警窗口的计算结果
+type BurnRateAlertState struct {
+	Window cfg.SloBurnRateWindow
+	Firing bool
+}
+
+// SloObjectiveState slo 目标的计算结果
+type SloObjectiveState struct {
+	// SLI 滚动窗口内 good/total 的比例
+	SLI float64
+	// ErrorBudgetRemaining 滚动窗口内剩余的错误预算比例，预算超支时为负数
+	ErrorBudgetRemaining float64
+	// BurnRates 各窗口的错误预算燃烧率，1 表示按窗口内恰好耗尽预算的速度消耗
+	BurnRates map[time.Duration]float64
+	Alerts    []BurnRateAlertState
+}
+
+// CalculateSloObjectives 计算所有 slo 目标的 SLI、错误预算及多窗口燃烧率并记录指标
+func CalculateSloObjectives(ctx context.Context, now time.Time) error {
+	metrics.ResetObjectiveGauge()
+	objectives, err := QuerySloObjectives(mysql.GetDBSession().DB, "/slo/")
+	if err != nil {
+		return errors.Wrap(err, "failed to query slo objectives")
+	}
+	if len(objectives) == 0 {
+		return nil
+	}
+	if cfg.SloUnifyQueryUrl == "" {
+		return errors.New("slo unify-query url is empty")
+	}
+
+	querier := remote.NewUnifyQueryClient(cfg.SloUnifyQueryUrl, cfg.SloQueryTimeout)
+	for _, objective := range objectives {
+		strategyId := objective.Strategy.StrategyID
+		spaceUid, err := getSloSpaceUid(objective.Strategy.BkBizID)
+		if err != nil {
+			logger.Errorf("slo objective strategy [%d] get space failed, %v", strategyId, err)
+			continue
+		}
+
+		state, err := ComputeSloObjective(ctx, querier, spaceUid, objective, cfg.SloBurnRateWindows, now)
+		if err != nil {
+			logger.Errorf("slo objective strategy [%d] compute failed, %v", strategyId, err)
+			continue
+		}
+		recordSloObjective(objective, state)
+	}
+	return nil
+}
+
+// parseSloObjective 从策略的标签及查询配置中解析 slo 目标，策略没有 slo_target 标签时返回 nil
+func parseSloObjective(strategy SloStrategy, labels []string, configs []slo.AlarmQueryConfigV2) (*SloObjective, error) {
+	objective := &SloObjective{Strategy: strategy, Window: defaultSloWindow}
+	var hasTarget bool
+	for _, label := range labels {
+		switch {
+		case strings.HasPrefix(label, sloTargetLabelPrefix):
+			value := strings.Trim(strings.TrimPrefix(label, sloTargetLabelPrefix), "/")
+			percent, err := strconv.ParseFloat(value, 64)
+			if err != nil || percent <= 0 || percent >= 100 {
+				return nil, errors.Errorf("invalid slo target label: %s", label)
+			}
+			objective.Target = percent / 100
+			hasTarget = true
+		case strings.HasPrefix(label, sloWindowLabelPrefix):
+			value := strings.Trim(strings.TrimPrefix(label, sloWindowLabelPrefix), "/")
+			window, err := model.ParseDuration(value)
+			if err != nil || window <= 0 {
+				return nil, errors.Errorf("invalid slo window label: %s", label)
+			}
+			objective.Window = time.Duration(window)
+		}
+	}
+	if !hasTarget {
+		return nil, nil
+	}
+
+	for _, config := range configs {
+		var queryConfig struct {
+			Promql string `json:"promql"`
+		}
+		if err := json.Unmarshal([]byte(config.Config), &queryConfig); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal query config of %s", config.Alias)
+		}
+		switch config.Alias {
+		case sloGoodQueryAlias:
+			objective.GoodQuery = queryConfig.Promql
+		case sloTotalQueryAlias:
+			objective.TotalQuery = queryConfig.Promql
+		}
+	}
+	if objective.GoodQuery == "" || objective.TotalQuery == "" {
+		return nil, errors.Errorf("promql of query %s and %s are required", sloGoodQueryAlias, sloTotalQueryAlias)
+	}
+	// 提前校验查询中包含时间范围，避免每个窗口都查询失败
+	for _, query := range []string{objective.GoodQuery, objective.TotalQuery} {
+		if _, err := replaceRangeDuration(query, objective.Window); err != nil {
+			return nil, err
+		}
+	}
+	return objective, nil
+}
+
+// ComputeSloObjective 计算单个 slo 目标，各窗口的 good/total 只查询一次
+func ComputeSloObjective(ctx context.Context, querier SloQuerier, spaceUid string, objective SloObjective, windows []cfg.SloBurnRateWindow, now time.Time) (*SloObjectiveState, error) {
+	if objective.Target <= 0 || objective.Target >= 1 {
+		return nil, errors.Errorf("invalid target: %v", objective.Target)
+	}
+	if objective.Window <= 0 || objective.GoodQuery == "" || objective.TotalQuery == "" {
+		return nil, errors.New("window, good query and total query are required")
+	}
+
+	errorRatios := make(map[time.Duration]float64)
+	errorRatio := func(window time.Duration) (float64, error) {
+		if ratio, ok := errorRatios[window]; ok {
+			return ratio, nil
+		}
+		good, err := querySloValue(ctx, querier, spaceUid, objective.GoodQuery, window, now)
+		if err != nil {
+			return 0, errors.Wrapf(err, "query good in %s", formatPromDuration(window))
+		}
+		total, err := querySloValue(ctx, querier, spaceUid, objective.TotalQuery, window, now)
+		if err != nil {
+			return 0, errors.Wrapf(err, "query total in %s", formatPromDuration(window))
+		}
+		// 窗口内没有请求时认为没有消耗错误预算
+		var ratio float64
+		if total > 0 {
+			ratio = math.Min(math.Max(1-good/total, 0), 1)
+		}
+		errorRatios[window] = ratio
+		return ratio, nil
+	}
+
+	budget := 1 - objective.Target
+	ratio, err := errorRatio(objective.Window)
+	if err != nil {
+		return nil, err
+	}
+	state := &SloObjectiveState{
+		SLI:                  1 - ratio,
+		ErrorBudgetRemaining: 1 - ratio/budget,
+		BurnRates:            map[time.Duration]float64{objective.Window: ratio / budget},
+	}
+	for _, w := range windows {
+		longRatio, err := errorRatio(w.Long)
+		if err != nil {
+			return nil, err
+		}
+		shortRatio, err := errorRatio(w.Short)
+		if err != nil {
+			return nil, err
+		}
+		longBurn, shortBurn := longRatio/budget, shortRatio/budget
+		state.BurnRates[w.Long] = longBurn
+		state.BurnRates[w.Short] = shortBurn
+		state.Alerts = append(state.Alerts, BurnRateAlertState{
+			Window: w,
+			Firing: longBurn >= w.Factor && shortBurn >= w.Factor,
+		})
+	}
+	return state, nil
+}
+
+// querySloValue 查询窗口内的事件数，多条序列时求和
+func querySloValue(ctx context.Context, querier SloQuerier, spaceUid, query string, window time.Duration, now time.Time) (float64, error) {
+	promql, err := replaceRangeDuration(query, window)
+	if err != nil {
+		return 0, err
+	}
+	samples, err := querier.QueryInstant(ctx, spaceUid, promql, now, window)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	for _, sample := range samples {
+		if !math.IsNaN(sample.Value) {
+			sum += sample.Value
+		}
+	}
+	return sum, nil
+}
+
+// replaceRangeDuration 将查询中区间向量及子查询的时间范围替换为窗口时长，例如 [5m] 及 [5m:1m]，引号内的内容保持不变
+func replaceRangeDuration(query string, window time.Duration) (string, error) {
+	var (
+		sb       strings.Builder
+		quote    rune
+		replaced bool
+	)
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && quote != '`' && i+1 < len(runes) {
+				sb.WriteRune(r)
+				i++
+				r = runes[i]
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return "", errors.Errorf("unclosed range selector in query: %s", query)
+			}
+			content := string(runes[i+1 : end])
+			rangePart, stepPart, isSubquery := strings.Cut(content, ":")
+			if _, err := model.ParseDuration(strings.TrimSpace(rangePart)); err != nil {
+				return "", errors.Errorf("invalid range selector [%s] in query: %s", content, query)
+			}
+			sb.WriteString("[" + formatPromDuration(window))
+			if isSubquery {
+				sb.WriteString(":" + stepPart)
+			}
+			sb.WriteString("]")
+			i = end
+			replaced = true
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	if !replaced {
+		return "", errors.Errorf("range selector is required in query: %s", query)
+	}
+	return sb.String(), nil
+}
+
+func recordSloObjective(objective SloObjective, state *SloObjectiveState) {
+	strategy := objective.Strategy
+	bkBizId := fmt.Sprintf("%d", strategy.BkBizID)
+	strategyId := fmt.Sprintf("%d", strategy.StrategyID)
+	metrics.RecordSloObjective(objective.Target, state.SLI, state.ErrorBudgetRemaining, bkBizId, strategy.Middle, strategy.Velat, strategyId, strategy.Name, formatPromDuration(objective.Window))
+	for window, burnRate := range state.BurnRates {
+		metrics.RecordSloBurnRate(burnRate, bkBizId, strategy.Middle, strategy.Velat, strategyId, strategy.Name, formatPromDuration(window))
+	}
+	for _, alert := range state.Alerts {
+		metrics.RecordSloBurnRateAlert(alert.Firing, bkBizId, strategy.Middle, strategy.Velat, strategyId, strategy.Name, formatPromDuration(alert.Window.Long), formatPromDuration(alert.Window.Short))
+	}
+}
+
+// getSloSpaceUid 业务 ID 转换为空间 uid，负数业务 ID 对应非 bkcc 类型的空间
+func getSloSpaceUid(bkBizId int32) (string, error) {
+	if bkBizId > 0 {
+		return fmt.Sprintf("%s__%d", models.SpaceTypeBKCC, bkBizId), nil
+	}
+	var sp space.Space
+	if err := space.NewSpaceQuerySet(mysql.GetDBSession().DB).IdEq(-int(bkBizId)).One(&sp); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s__%s", sp.SpaceTypeId, sp.SpaceId), nil
+}
+
+// formatPromDuration 转换为 PromQL 的时长格式，例如 30d、1h、5m
+func formatPromDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return strconv.FormatInt(int64(d/(24*time.Hour)), 10) + "d"
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+}
@@ -0,0 +1,188 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	cfg "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/slo"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/remote"
+)
+
+// testSloQuerier 按 查询语句 返回预设的值
+type testSloQuerier struct {
+	values  map[string]float64
+	queries []string
+}
+
+func (q *testSloQuerier) QueryInstant(_ context.Context, spaceUid, promql string, _ time.Time, _ time.Duration) ([]remote.PromSample, error) {
+	q.queries = append(q.queries, promql)
+	if spaceUid != "bkcc__2" {
+		return nil, errors.New("space not found")
+	}
+	v, ok := q.values[promql]
+	if !ok {
+		return nil, nil
+	}
+	// 拆分为两条序列，验证多序列求和
+	return []remote.PromSample{{Value: v / 2}, {Value: v / 2}}, nil
+}
+
+func TestComputeSloObjective(t *testing.T) {
+	objective := SloObjective{
+		Strategy:   SloStrategy{BkBizStrategy: BkBizStrategy{BkBizID: 2, StrategyID: 1}},
+		Target:     0.99,
+		Window:     30 * 24 * time.Hour,
+		GoodQuery:  `sum(increase(requests_total{code!~"5.."}[1m]))`,
+		TotalQuery: `sum(increase(requests_total[1m]))`,
+	}
+	windows := []cfg.SloBurnRateWindow{
+		{Long: time.Hour, Short: 5 * time.Minute, Factor: 14.4},
+		{Long: 6 * time.Hour, Short: 30 * time.Minute, Factor: 6},
+	}
+	good := func(w string) string { return strings.ReplaceAll(objective.GoodQuery, "1m", w) }
+	total := func(w string) string { return strings.ReplaceAll(objective.TotalQuery, "1m", w) }
+
+	querier := &testSloQuerier{values: map[string]float64{
+		// 30d 错误率 0.5%，消耗一半预算
+		good("30d"): 99500, total("30d"): 100000,
+		// 1h 及 5m 错误率 20%，燃烧率 20
+		good("1h"): 800, total("1h"): 1000,
+		good("5m"): 80, total("5m"): 100,
+		// 6h 错误率 10%，30m 没有请求
+		good("6h"): 5400, total("6h"): 6000,
+	}}
+
+	state, err := ComputeSloObjective(context.Background(), querier, "bkcc__2", objective, windows, time.Now())
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.995, state.SLI, 1e-9)
+	assert.InDelta(t, 0.5, state.ErrorBudgetRemaining, 1e-9)
+	assert.InDelta(t, 20, state.BurnRates[time.Hour], 1e-9)
+	assert.InDelta(t, 20, state.BurnRates[5*time.Minute], 1e-9)
+	assert.InDelta(t, 10, state.BurnRates[6*time.Hour], 1e-9)
+	assert.InDelta(t, 0, state.BurnRates[30*time.Minute], 1e-9)
+	assert.Equal(t, []BurnRateAlertState{
+		{Window: windows[0], Firing: true},
+		{Window: windows[1], Firing: false},
+	}, state.Alerts)
+	// 每个窗口只查询一次 good 及 total
+	assert.Len(t, querier.queries, 10)
+
+	_, err = ComputeSloObjective(context.Background(), querier, "bkcc__3", objective, windows, time.Now())
+	assert.Error(t, err)
+
+	objective.Target = 1
+	_, err = ComputeSloObjective(context.Background(), querier, "bkcc__2", objective, windows, time.Now())
+	assert.Error(t, err)
+}
+
+func TestParseSloObjective(t *testing.T) {
+	strategy := SloStrategy{BkBizStrategy: BkBizStrategy{BkBizID: 2, StrategyID: 1}, Velat: "availability"}
+	configs := []slo.AlarmQueryConfigV2{
+		{StrategyID: 1, Alias: "good", Config: `{"promql": "sum(increase(requests_total{code!~\"5..\"}[1m]))", "agg_interval": 60}`},
+		{StrategyID: 1, Alias: "total", Config: `{"promql": "sum(increase(requests_total[1m]))", "agg_interval": 60}`},
+	}
+
+	objective, err := parseSloObjective(strategy, []string{"/slo_target/99.9/", "/slo_window/7d/"}, configs)
+	assert.NoError(t, err)
+	assert.Equal(t, strategy, objective.Strategy)
+	assert.InDelta(t, 0.999, objective.Target, 1e-9)
+	assert.Equal(t, 7*24*time.Hour, objective.Window)
+	assert.Equal(t, `sum(increase(requests_total{code!~"5.."}[1m]))`, objective.GoodQuery)
+	assert.Equal(t, `sum(increase(requests_total[1m]))`, objective.TotalQuery)
+
+	// 未设置窗口时使用默认窗口
+	objective, err = parseSloObjective(strategy, []string{"/slo_target/99/"}, configs)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultSloWindow, objective.Window)
+
+	// 没有目标标签的策略不是 slo 目标
+	objective, err = parseSloObjective(strategy, []string{"/slo_window/7d/"}, configs)
+	assert.NoError(t, err)
+	assert.Nil(t, objective)
+
+	_, err = parseSloObjective(strategy, []string{"/slo_target/100/"}, configs)
+	assert.Error(t, err)
+	_, err = parseSloObjective(strategy, []string{"/slo_target/99/", "/slo_window/abc/"}, configs)
+	assert.Error(t, err)
+	_, err = parseSloObjective(strategy, []string{"/slo_target/99/"}, configs[:1])
+	assert.Error(t, err)
+
+	// 查询中没有时间范围
+	_, err = parseSloObjective(strategy, []string{"/slo_target/99/"}, []slo.AlarmQueryConfigV2{
+		configs[0],
+		{StrategyID: 1, Alias: "total", Config: `{"promql": "sum(requests_total)"}`},
+	})
+	assert.Error(t, err)
+}
+
+func TestReplaceRangeDuration(t *testing.T) {
+	testCases := map[string]struct {
+		query    string
+		expected string
+		err      bool
+	}{
+		"区间向量": {
+			query:    `sum(increase(requests_total{code!~"5.."}[5m]))`,
+			expected: `sum(increase(requests_total{code!~"5.."}[1h]))`,
+		},
+		"子查询": {
+			query:    `sum_over_time(sum(rate(requests_total[1m]))[5m:1m])`,
+			expected: `sum_over_time(sum(rate(requests_total[1h]))[1h:1m])`,
+		},
+		"引号内的中括号": {
+			query:    `sum(increase(requests_total{path=~"/api/[a-z]+"}[5m]))`,
+			expected: `sum(increase(requests_total{path=~"/api/[a-z]+"}[1h]))`,
+		},
+		"没有时间范围": {
+			query: `sum(requests_total)`,
+			err:   true,
+		},
+		"时间范围不合法": {
+			query: `sum(increase(requests_total[abc]))`,
+			err:   true,
+		},
+	}
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := replaceRangeDuration(c.query, time.Hour)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestFormatPromDuration(t *testing.T) {
+	testCases := map[string]struct {
+		d        time.Duration
+		expected string
+	}{
+		"天":  {d: 30 * 24 * time.Hour, expected: "30d"},
+		"小时": {d: 6 * time.Hour, expected: "6h"},
+		"分钟": {d: 90 * time.Minute, expected: "90m"},
+		"秒":  {d: 45 * time.Second, expected: "45s"},
+	}
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, formatPromDuration(c.d))
+		})
+	}
+}
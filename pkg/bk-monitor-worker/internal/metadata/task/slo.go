@@ -38,6 +38,15 @@ func SloPush(ctx context.Context, t *t.Task) error {
 
 	logger.Info("start auto SloPush task")
 
+	// 计算 slo 目标的 SLI、错误预算及燃烧率，与业务批次无关，单独上报一次
+	objectiveRegistry := prometheus.NewRegistry()
+	metrics.InitObjectiveGauge(objectiveRegistry)
+	if err := service.CalculateSloObjectives(ctx, time.Now()); err != nil {
+		logger.Errorf("calculate slo objectives failed, %v", err)
+	} else {
+		metrics.PushObjectiveRes(objectiveRegistry)
+	}
+
 	//检索所有满足标签的业务
 	bizID, err := service.FindAllBiz()
 	if err != nil {
@@ -0,0 +1,43 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"context"
+
+	cfg "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// EvaluateDaemon 预计算规则计算常驻任务，用于没有计算平台的环境
+type EvaluateDaemon struct{}
+
+func (d *EvaluateDaemon) Start(runInstanceCtx context.Context, errorReceiveChan chan<- error, _ []byte) {
+	querier, err := NewQuerier(cfg.RecordRuleQueryMode, cfg.RecordRuleUnifyQueryUrl, cfg.RecordRuleQueryTimeout)
+	if err != nil {
+		errorReceiveChan <- err
+		return
+	}
+
+	engine := NewEngine(dbStore{}, querier, NewWriter(cfg.RecordRuleQueryTimeout), Options{
+		CheckInterval:   cfg.RecordRuleCheckInterval,
+		RefreshInterval: cfg.RecordRuleRefreshInterval,
+		EvaluationDelay: cfg.RecordRuleEvaluationDelay,
+		MaxBackfill:     cfg.RecordRuleMaxBackfill,
+		Timeout:         cfg.RecordRuleQueryTimeout,
+		Concurrency:     cfg.RecordRuleConcurrency,
+	})
+	logger.Infof("[record_rule] engine started, query mode: %s", cfg.RecordRuleQueryMode)
+	engine.Run(runInstanceCtx)
+}
+
+func (d *EvaluateDaemon) GetTaskDimension(_ []byte) string {
+	return ""
+}
@@ -0,0 +1,362 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// Options 计算引擎配置
+type Options struct {
+	CheckInterval   time.Duration
+	RefreshInterval time.Duration
+	EvaluationDelay time.Duration
+	MaxBackfill     time.Duration
+	Timeout         time.Duration
+	Concurrency     int
+}
+
+// ruleState 规则的计算状态
+type ruleState struct {
+	rule    *evalRule
+	status  string
+	running bool
+	// lastEval 最后一次成功计算的时间点
+	lastEval time.Time
+	// lastSeries 最后一次计算产出的序列，序列消失时需要写入 staleness 标记
+	lastSeries map[string][]prompb.Label
+}
+
+// Engine 预计算规则计算引擎，按规则周期查询并将结果写回目标 VM 结果表
+type Engine struct {
+	store   Store
+	querier Querier
+	writer  Writer
+	opts    Options
+	now     func() time.Time
+
+	mut      sync.Mutex
+	states   map[int]*ruleState
+	clusters map[int]*Cluster
+	sem      chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewEngine(store Store, querier Querier, writer Writer, opts Options) *Engine {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	return &Engine{
+		store:    store,
+		querier:  querier,
+		writer:   writer,
+		opts:     opts,
+		now:      time.Now,
+		states:   make(map[int]*ruleState),
+		clusters: make(map[int]*Cluster),
+		sem:      make(chan struct{}, opts.Concurrency),
+	}
+}
+
+// Run 启动计算，直到 ctx 取消
+func (e *Engine) Run(ctx context.Context) {
+	checkTicker := time.NewTicker(e.opts.CheckInterval)
+	defer checkTicker.Stop()
+	refreshTicker := time.NewTicker(e.opts.RefreshInterval)
+	defer refreshTicker.Stop()
+
+	e.reload(ctx)
+	e.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			e.wg.Wait()
+			logger.Info("[record_rule] engine stopped")
+			return
+		case <-refreshTicker.C:
+			e.reload(ctx)
+		case <-checkTicker.C:
+			e.check(ctx)
+		}
+	}
+}
+
+// reload 从存储中重新加载规则，已删除的规则会写入 staleness 标记后移除
+func (e *Engine) reload(ctx context.Context) {
+	records, err := e.store.ListRules()
+	if err != nil {
+		logger.Errorf("[record_rule] list rules failed, %v", err)
+		return
+	}
+
+	rules := make(map[int]*evalRule, len(records))
+	statuses := make(map[int]string, len(records))
+	clusters := make(map[int]*Cluster)
+	for i := range records {
+		record := &records[i]
+		rule, err := parseRecordRule(record)
+		if err != nil {
+			logger.Errorf("[record_rule] parse rule failed, %v", err)
+			e.updateStatus(record.Id, record.Status, models.RecordRuleStatusFailed)
+			continue
+		}
+		if _, ok := clusters[rule.vmClusterId]; !ok {
+			cluster, err := e.store.GetCluster(rule.vmClusterId)
+			if err != nil {
+				logger.Errorf("[record_rule] get cluster of rule [%d] failed, %v", rule.id, err)
+				continue
+			}
+			clusters[rule.vmClusterId] = cluster
+		}
+		rules[rule.id] = rule
+		statuses[rule.id] = record.Status
+	}
+
+	e.mut.Lock()
+	removed := make(map[*ruleState]*Cluster)
+	for id, state := range e.states {
+		if _, ok := rules[id]; ok || state.running {
+			continue
+		}
+		delete(e.states, id)
+		removed[state] = e.clusters[state.rule.vmClusterId]
+	}
+	e.clusters = clusters
+	for id, rule := range rules {
+		state, ok := e.states[id]
+		if !ok {
+			e.states[id] = &ruleState{rule: rule, status: statuses[id]}
+			continue
+		}
+		if state.rule.version != rule.version {
+			logger.Infof("[record_rule] rule [%d] config changed", id)
+		}
+		state.rule = rule
+	}
+	e.mut.Unlock()
+
+	for state, cluster := range removed {
+		e.markRemoved(ctx, state, cluster)
+	}
+}
+
+// markRemoved 规则删除后为其产出的序列写入 staleness 标记，避免查询时继续回溯到旧数据
+func (e *Engine) markRemoved(ctx context.Context, state *ruleState, cluster *Cluster) {
+	defer metrics.DeleteRecordRuleMetrics(strconv.Itoa(state.rule.id))
+	if len(state.lastSeries) == 0 || cluster == nil {
+		return
+	}
+
+	ts := state.lastEval.Add(state.rule.interval)
+	series := staleSeries(state.lastSeries, nil, ts)
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+	if err := e.writer.Write(ctx, cluster, series); err != nil {
+		logger.Errorf("[record_rule] write stale markers of removed rule [%d] failed, %v", state.rule.id, err)
+		return
+	}
+	logger.Infof("[record_rule] rule [%d] removed, %d series marked as stale", state.rule.id, len(series))
+}
+
+// check 调度到达计算时间点的规则
+func (e *Engine) check(ctx context.Context) {
+	now := e.now()
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	for _, state := range e.states {
+		if state.running {
+			continue
+		}
+		rule := state.rule
+		cluster, ok := e.clusters[rule.vmClusterId]
+		if !ok {
+			continue
+		}
+		evalTime := now.Add(-e.opts.EvaluationDelay).Truncate(rule.interval)
+		if !evalTime.After(state.lastEval) {
+			continue
+		}
+
+		state.running = true
+		e.wg.Add(1)
+		go func(state *ruleState) {
+			defer e.wg.Done()
+			select {
+			case e.sem <- struct{}{}:
+				defer func() { <-e.sem }()
+				e.evaluate(ctx, state, rule, cluster, evalTime)
+			case <-ctx.Done():
+			}
+
+			e.mut.Lock()
+			state.running = false
+			e.mut.Unlock()
+		}(state)
+	}
+}
+
+// evaluate 计算规则从上次计算之后到 evalTime 的所有时间点，首次计算或者中断恢复时按 MaxBackfill 回填
+func (e *Engine) evaluate(ctx context.Context, state *ruleState, rule *evalRule, cluster *Cluster, evalTime time.Time) {
+	e.mut.Lock()
+	lastEval, lastSeries, status := state.lastEval, state.lastSeries, state.status
+	e.mut.Unlock()
+
+	earliest := evalTime.Add(-e.opts.MaxBackfill).Truncate(rule.interval)
+	start := lastEval.Add(rule.interval)
+	if lastEval.IsZero() {
+		start = rule.createAt.Truncate(rule.interval)
+	}
+	if start.Before(earliest) {
+		start = earliest
+	}
+
+	ruleId := strconv.Itoa(rule.id)
+	var evaluated int
+	var err error
+	for ts := start; !ts.After(evalTime); ts = ts.Add(rule.interval) {
+		if ctx.Err() != nil {
+			return
+		}
+		var current map[string][]prompb.Label
+		current, err = e.evalAt(ctx, rule, cluster, ts, lastSeries)
+		if err != nil {
+			err = errors.Wrapf(err, "evaluate at %s", ts.Format(time.RFC3339))
+			break
+		}
+		lastEval, lastSeries = ts, current
+		evaluated++
+		metrics.SetRecordRuleLastEvaluation(ruleId, ts)
+	}
+
+	if err != nil {
+		metrics.RecordRuleEvaluationTotal(ruleId, "failed")
+		logger.Errorf("[record_rule] rule [%d] evaluate failed, %v", rule.id, err)
+		status = e.updateStatus(rule.id, status, models.RecordRuleStatusFailed)
+	} else if evaluated > 0 {
+		metrics.RecordRuleEvaluationTotal(ruleId, "success")
+		if evaluated > 1 {
+			logger.Infof("[record_rule] rule [%d] backfilled %d points until %s", rule.id, evaluated, evalTime.Format(time.RFC3339))
+		}
+		status = e.updateStatus(rule.id, status, models.RecordRuleStatusRunning)
+	}
+
+	e.mut.Lock()
+	state.lastEval, state.lastSeries, state.status = lastEval, lastSeries, status
+	e.mut.Unlock()
+}
+
+// evalAt 计算单个时间点并写入结果，返回本次产出的序列
+func (e *Engine) evalAt(ctx context.Context, rule *evalRule, cluster *Cluster, ts time.Time, lastSeries map[string][]prompb.Label) (map[string][]prompb.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+
+	current := make(map[string][]prompb.Label)
+	var series []prompb.TimeSeries
+	for _, r := range rule.rules {
+		samples, err := e.querier.Query(ctx, rule, cluster, r.Expr, ts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "query record [%s]", r.Record)
+		}
+		for _, sample := range samples {
+			labels := buildLabels(rule, r, sample.Labels)
+			key := labelsKey(labels)
+			if _, ok := current[key]; ok {
+				return nil, errors.Errorf("record [%s] contains duplicate series after applying labels: %s", r.Record, key)
+			}
+			current[key] = labels
+			series = append(series, prompb.TimeSeries{
+				Labels:  labels,
+				Samples: []prompb.Sample{{Value: sample.Value, Timestamp: ts.UnixMilli()}},
+			})
+		}
+	}
+	series = append(series, staleSeries(lastSeries, current, ts)...)
+
+	if err := e.writer.Write(ctx, cluster, series); err != nil {
+		return nil, errors.Wrap(err, "remote write")
+	}
+	return current, nil
+}
+
+// updateStatus 状态发生变化时更新到 db，返回最新的状态
+func (e *Engine) updateStatus(ruleId int, old, status string) string {
+	if old == status {
+		return old
+	}
+	if err := e.store.UpdateStatus(ruleId, status); err != nil {
+		logger.Errorf("[record_rule] update status of rule [%d] to [%s] failed, %v", ruleId, status, err)
+		return old
+	}
+	logger.Infof("[record_rule] rule [%d] status changed from [%s] to [%s]", ruleId, old, status)
+	return status
+}
+
+// buildLabels 组装写入的维度，指标名及目标结果表会覆盖查询结果中的同名维度
+func buildLabels(rule *evalRule, r Rule, sampleLabels map[string]string) []prompb.Label {
+	m := make(map[string]string, len(sampleLabels)+len(r.Labels)+2)
+	for k, v := range sampleLabels {
+		m[k] = v
+	}
+	for k, v := range r.Labels {
+		m[k] = v
+	}
+	m["__name__"] = r.Record
+	m[vmTableLabel] = rule.dstVmTableId
+
+	labels := make([]prompb.Label, 0, len(m))
+	for k, v := range m {
+		labels = append(labels, prompb.Label{Name: k, Value: v})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func labelsKey(labels []prompb.Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l.Value))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// staleSeries 为上次存在而本次消失的序列生成 staleness 标记
+func staleSeries(last, current map[string][]prompb.Label, ts time.Time) []prompb.TimeSeries {
+	var series []prompb.TimeSeries
+	for key, labels := range last {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		series = append(series, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: math.Float64frombits(value.StaleNaN), Timestamp: ts.UnixMilli()}},
+		})
+	}
+	return series
+}
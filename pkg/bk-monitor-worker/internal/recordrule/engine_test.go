@@ -0,0 +1,204 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	ruleModel "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/recordrule"
+)
+
+type testStore struct {
+	mut      sync.Mutex
+	rules    []ruleModel.RecordRule
+	statuses map[int]string
+}
+
+func (s *testStore) ListRules() ([]ruleModel.RecordRule, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]ruleModel.RecordRule(nil), s.rules...), nil
+}
+
+func (s *testStore) GetCluster(_ int) (*Cluster, error) {
+	return &Cluster{}, nil
+}
+
+func (s *testStore) UpdateStatus(ruleId int, status string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.statuses[ruleId] = status
+	return nil
+}
+
+func (s *testStore) status(ruleId int) string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.statuses[ruleId]
+}
+
+type testQuerier struct {
+	query func(ts time.Time) ([]Sample, error)
+}
+
+func (q *testQuerier) Query(_ context.Context, _ *evalRule, _ *Cluster, _ string, ts time.Time) ([]Sample, error) {
+	return q.query(ts)
+}
+
+type testWriter struct {
+	mut    sync.Mutex
+	series []prompb.TimeSeries
+}
+
+func (w *testWriter) Write(_ context.Context, _ *Cluster, series []prompb.TimeSeries) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	w.series = append(w.series, series...)
+	return nil
+}
+
+func (w *testWriter) reset() []prompb.TimeSeries {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	series := w.series
+	w.series = nil
+	return series
+}
+
+func newTestEngine(querier Querier) (*Engine, *testStore, *testWriter) {
+	store := &testStore{
+		rules: []ruleModel.RecordRule{{
+			Id:           1,
+			RuleConfig:   `{"interval":"1m","rules":[{"record":"pod_up","expr":"sum(up) by (pod)","labels":{"source":"rr"}}]}`,
+			VmClusterId:  1,
+			DstVmTableId: "2_vm_dst",
+			Status:       models.RecordRuleStatusCreated,
+		}},
+		statuses: make(map[int]string),
+	}
+	writer := &testWriter{}
+	engine := NewEngine(store, querier, writer, Options{
+		MaxBackfill: 5 * time.Minute,
+		Timeout:     time.Second,
+		Concurrency: 2,
+	})
+	return engine, store, writer
+}
+
+func labelValue(labels []prompb.Label, name string) string {
+	for _, l := range labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+func TestEngineBackfillAndStaleness(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+
+	pods := []string{"p1", "p2"}
+	querier := &testQuerier{query: func(ts time.Time) ([]Sample, error) {
+		var samples []Sample
+		for _, pod := range pods {
+			samples = append(samples, Sample{Labels: map[string]string{"pod": pod, "__name__": "up"}, Value: float64(ts.Unix())})
+		}
+		return samples, nil
+	}}
+	engine, store, writer := newTestEngine(querier)
+	engine.now = func() time.Time { return now }
+
+	// 首次计算回填 MaxBackfill 范围内的所有时间点
+	engine.reload(ctx)
+	engine.check(ctx)
+	engine.wg.Wait()
+	series := writer.reset()
+	assert.Len(t, series, 12)
+	first := series[0]
+	assert.Equal(t, "pod_up", labelValue(first.Labels, "__name__"))
+	assert.Equal(t, "2_vm_dst", labelValue(first.Labels, vmTableLabel))
+	assert.Equal(t, "rr", labelValue(first.Labels, "source"))
+	assert.Equal(t, time.Date(2024, 1, 1, 9, 55, 0, 0, time.UTC).UnixMilli(), first.Samples[0].Timestamp)
+	assert.Equal(t, models.RecordRuleStatusRunning, store.status(1))
+
+	// 同一周期内不会重复计算
+	engine.check(ctx)
+	engine.wg.Wait()
+	assert.Len(t, writer.reset(), 0)
+
+	// 序列消失后写入 staleness 标记
+	pods = []string{"p1"}
+	now = now.Add(time.Minute)
+	engine.check(ctx)
+	engine.wg.Wait()
+	series = writer.reset()
+	assert.Len(t, series, 2)
+	assert.Equal(t, "p1", labelValue(series[0].Labels, "pod"))
+	assert.Equal(t, "p2", labelValue(series[1].Labels, "pod"))
+	assert.True(t, value.IsStaleNaN(series[1].Samples[0].Value))
+
+	// 规则删除后为剩余序列写入 staleness 标记
+	store.rules = nil
+	engine.reload(ctx)
+	series = writer.reset()
+	assert.Len(t, series, 1)
+	assert.Equal(t, "p1", labelValue(series[0].Labels, "pod"))
+	assert.True(t, value.IsStaleNaN(series[0].Samples[0].Value))
+	assert.Len(t, engine.states, 0)
+}
+
+func TestEngineEvaluateFailed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+
+	failedAt := time.Date(2024, 1, 1, 9, 58, 0, 0, time.UTC)
+	querier := &testQuerier{query: func(ts time.Time) ([]Sample, error) {
+		if !ts.Before(failedAt) {
+			return nil, errors.New("query timeout")
+		}
+		return []Sample{{Labels: map[string]string{}, Value: math.Pi}}, nil
+	}}
+	engine, store, writer := newTestEngine(querier)
+	engine.now = func() time.Time { return now }
+
+	engine.reload(ctx)
+	engine.check(ctx)
+	engine.wg.Wait()
+
+	// 失败前的时间点已经写入，下次从失败的时间点继续计算
+	assert.Len(t, writer.reset(), 3)
+	assert.Equal(t, models.RecordRuleStatusFailed, store.status(1))
+	assert.Equal(t, failedAt.Add(-time.Minute), engine.states[1].lastEval)
+
+	failedAt = now
+	engine.check(ctx)
+	engine.wg.Wait()
+	assert.Len(t, writer.reset(), 3)
+	assert.Equal(t, models.RecordRuleStatusRunning, store.status(1))
+}
+
+func TestEngineInvalidRule(t *testing.T) {
+	engine, store, _ := newTestEngine(&testQuerier{})
+	store.rules[0].RuleConfig = "{"
+	engine.reload(context.Background())
+	assert.Len(t, engine.states, 0)
+	assert.Equal(t, models.RecordRuleStatusFailed, store.status(1))
+}
@@ -0,0 +1,192 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/remote"
+)
+
+const (
+	QueryModeUnifyQuery = "unify_query"
+	QueryModeVM         = "vm"
+
+	// vmTableLabel VM 中区分结果表的维度
+	vmTableLabel = "result_table_id"
+)
+
+// Sample 查询得到的单条序列在计算时间点的值
+type Sample = remote.PromSample
+
+// Cluster 规则计算使用的 VM 集群地址
+type Cluster struct {
+	QueryUrl string
+	WriteUrl string
+	Username string
+	Password string
+}
+
+// Querier 执行 PromQL 瞬时查询
+type Querier interface {
+	Query(ctx context.Context, rule *evalRule, cluster *Cluster, expr string, ts time.Time) ([]Sample, error)
+}
+
+// NewQuerier 根据查询方式创建 Querier
+func NewQuerier(mode, unifyQueryUrl string, timeout time.Duration) (Querier, error) {
+	client := &http.Client{Timeout: timeout}
+	switch mode {
+	case QueryModeVM:
+		return &vmQuerier{client: client}, nil
+	case QueryModeUnifyQuery:
+		if unifyQueryUrl == "" {
+			return nil, errors.New("unify-query url is empty")
+		}
+		return &unifyQueryQuerier{client: remote.NewUnifyQueryClient(unifyQueryUrl, timeout)}, nil
+	default:
+		return nil, errors.Errorf("unsupported query mode: %s", mode)
+	}
+}
+
+// vmQuerier 直接查询 VM 集群，通过 extra_filters 将查询限制在规则的源结果表内
+type vmQuerier struct {
+	client *http.Client
+}
+
+type vmQueryResponse struct {
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	ErrorType string `json:"errorType"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type vmVectorItem struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+func (q *vmQuerier) Query(ctx context.Context, rule *evalRule, cluster *Cluster, expr string, ts time.Time) ([]Sample, error) {
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("time", strconv.FormatInt(ts.Unix(), 10))
+	if len(rule.srcTableIds) > 0 {
+		tableIds := make([]string, 0, len(rule.srcTableIds))
+		for _, tid := range rule.srcTableIds {
+			tableIds = append(tableIds, regexp.QuoteMeta(tid))
+		}
+		params.Add("extra_filters[]", fmt.Sprintf(`{%s=~"%s"}`, vmTableLabel, strings.Join(tableIds, "|")))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cluster.QueryUrl, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cluster.Username != "" {
+		req.SetBasicAuth(cluster.Username, cluster.Password)
+	}
+
+	body, err := doRequest(q.client, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp vmQueryResponse
+	if err := jsonx.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "unmarshal vm response failed")
+	}
+	if resp.Status != "success" {
+		return nil, errors.Errorf("vm query failed, %s: %s", resp.ErrorType, resp.Error)
+	}
+
+	switch resp.Data.ResultType {
+	case "vector":
+		var items []vmVectorItem
+		if err := jsonx.Unmarshal(resp.Data.Result, &items); err != nil {
+			return nil, errors.Wrap(err, "unmarshal vm vector failed")
+		}
+		samples := make([]Sample, 0, len(items))
+		for _, item := range items {
+			v, err := parsePointValue(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, Sample{Labels: item.Metric, Value: v})
+		}
+		return samples, nil
+	case "scalar":
+		var point []interface{}
+		if err := jsonx.Unmarshal(resp.Data.Result, &point); err != nil {
+			return nil, errors.Wrap(err, "unmarshal vm scalar failed")
+		}
+		v, err := parsePointValue(point)
+		if err != nil {
+			return nil, err
+		}
+		return []Sample{{Labels: map[string]string{}, Value: v}}, nil
+	default:
+		return nil, errors.Errorf("unsupported result type: %s", resp.Data.ResultType)
+	}
+}
+
+// parsePointValue 解析 prometheus 接口返回的 [timestamp, "value"]
+func parsePointValue(point []interface{}) (float64, error) {
+	if len(point) != 2 {
+		return 0, errors.Errorf("invalid point: %v", point)
+	}
+	s, ok := point[1].(string)
+	if !ok {
+		return 0, errors.Errorf("invalid point value: %v", point[1])
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// unifyQueryQuerier 通过 unify-query 查询，由 unify-query 根据空间路由到源结果表
+type unifyQueryQuerier struct {
+	client *remote.UnifyQueryClient
+}
+
+func (q *unifyQueryQuerier) Query(ctx context.Context, rule *evalRule, _ *Cluster, expr string, ts time.Time) ([]Sample, error) {
+	return q.client.QueryInstant(ctx, rule.spaceUid, expr, ts, rule.interval)
+}
+
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		return nil, errors.Errorf("request %s returned HTTP status %s: %s", req.URL.Path, resp.Status, body)
+	}
+	return body, nil
+}
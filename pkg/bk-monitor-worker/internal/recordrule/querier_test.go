@@ -0,0 +1,60 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVmQuerier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "sum(up) by (pod)", r.Form.Get("query"))
+		assert.Equal(t, "1700000000", r.Form.Get("time"))
+		assert.Equal(t, `{result_table_id=~"2_vm_1|2_vm_2"}`, r.Form.Get("extra_filters[]"))
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"p1"},"value":[1700000000,"1.5"]},{"metric":{"pod":"p2"},"value":[1700000000,"2"]}]}}`))
+	}))
+	defer server.Close()
+
+	q, err := NewQuerier(QueryModeVM, "", time.Second)
+	assert.NoError(t, err)
+	rule := &evalRule{srcTableIds: []string{"2_vm_1", "2_vm_2"}, interval: time.Minute}
+	samples, err := q.Query(context.Background(), rule, &Cluster{QueryUrl: server.URL}, "sum(up) by (pod)", time.Unix(1700000000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []Sample{
+		{Labels: map[string]string{"pod": "p1"}, Value: 1.5},
+		{Labels: map[string]string{"pod": "p2"}, Value: 2},
+	}, samples)
+}
+
+func TestUnifyQueryQuerier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/query/ts/promql", r.URL.Path)
+		assert.Equal(t, "bkcc__2", r.Header.Get("X-Bk-Scope-Space-Uid"))
+		_, _ = w.Write([]byte(`{"series":[{"group_keys":["pod"],"group_values":["p1"],"values":[[1700000000000,3]]}]}`))
+	}))
+	defer server.Close()
+
+	q, err := NewQuerier(QueryModeUnifyQuery, server.URL, time.Second)
+	assert.NoError(t, err)
+	rule := &evalRule{spaceUid: "bkcc__2", interval: time.Minute}
+	samples, err := q.Query(context.Background(), rule, nil, "sum(up) by (pod)", time.Unix(1700000000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []Sample{{Labels: map[string]string{"pod": "p1"}, Value: 3}}, samples)
+
+	_, err = NewQuerier("unknown", "", time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	ruleModel "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/recordrule"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+const (
+	defaultInterval = time.Minute
+	minInterval     = 10 * time.Second
+)
+
+// RuleGroup 预计算规则配置，与 prometheus 规则组格式保持一致，支持 json 及 yaml
+type RuleGroup struct {
+	Name     string `json:"name" yaml:"name"`
+	Interval string `json:"interval" yaml:"interval"`
+	Rules    []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule 单条预计算规则
+type Rule struct {
+	Record string            `json:"record" yaml:"record"`
+	Expr   string            `json:"expr" yaml:"expr"`
+	Labels map[string]string `json:"labels" yaml:"labels"`
+}
+
+// evalRule 解析后的可计算规则
+type evalRule struct {
+	id           int
+	name         string
+	spaceUid     string
+	vmClusterId  int
+	srcTableIds  []string
+	dstVmTableId string
+	interval     time.Duration
+	rules        []Rule
+	createAt     time.Time
+	// version 规则配置的摘要，配置变更后需要重新计算状态
+	version string
+}
+
+// parseRecordRule 将 db 中的规则记录转换为可计算规则
+func parseRecordRule(r *ruleModel.RecordRule) (*evalRule, error) {
+	if r.DstVmTableId == "" {
+		return nil, errors.Errorf("record rule [%d] dst_vm_table_id is empty", r.Id)
+	}
+	if r.VmClusterId == 0 {
+		return nil, errors.Errorf("record rule [%d] vm_cluster_id is empty", r.Id)
+	}
+
+	var group RuleGroup
+	if err := yaml.Unmarshal([]byte(r.RuleConfig), &group); err != nil {
+		return nil, errors.Wrapf(err, "record rule [%d] parse rule_config failed", r.Id)
+	}
+	if len(group.Rules) == 0 {
+		return nil, errors.Errorf("record rule [%d] has no rules", r.Id)
+	}
+
+	interval := defaultInterval
+	if group.Interval != "" {
+		d, err := time.ParseDuration(group.Interval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "record rule [%d] parse interval failed", r.Id)
+		}
+		interval = d
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+
+	// rule_metrics 记录了 record 到实际写入指标名的映射，不存在时直接使用 record 作为指标名
+	metricNames := make(map[string]string)
+	if r.RuleMetrics != "" {
+		_ = jsonx.UnmarshalString(r.RuleMetrics, &metricNames)
+	}
+	rules := make([]Rule, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		if rule.Record == "" || rule.Expr == "" {
+			return nil, errors.Errorf("record rule [%d] record or expr is empty", r.Id)
+		}
+		if name, ok := metricNames[rule.Record]; ok && name != "" {
+			rule.Record = name
+		}
+		rules = append(rules, rule)
+	}
+
+	var srcTableIds []string
+	for _, tid := range strings.Split(r.SrcVmTableIds, ",") {
+		if tid = strings.TrimSpace(tid); tid != "" {
+			srcTableIds = append(srcTableIds, tid)
+		}
+	}
+
+	sum := md5.Sum([]byte(fmt.Sprintf("%s|%s|%s|%d|%s", r.RuleConfig, r.RuleMetrics, r.SrcVmTableIds, r.VmClusterId, r.DstVmTableId)))
+	return &evalRule{
+		id:           r.Id,
+		name:         r.RecordName,
+		spaceUid:     fmt.Sprintf("%s__%s", r.SpaceType, r.SpaceId),
+		vmClusterId:  r.VmClusterId,
+		srcTableIds:  srcTableIds,
+		dstVmTableId: r.DstVmTableId,
+		interval:     interval,
+		rules:        rules,
+		createAt:     r.CreateAt,
+		version:      hex.EncodeToString(sum[:]),
+	}, nil
+}
@@ -0,0 +1,98 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ruleModel "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/recordrule"
+)
+
+func TestParseRecordRule(t *testing.T) {
+	testCases := map[string]struct {
+		record   ruleModel.RecordRule
+		interval time.Duration
+		records  []string
+		srcIds   []string
+		hasErr   bool
+	}{
+		"json 配置": {
+			record: ruleModel.RecordRule{
+				Id:            1,
+				RuleConfig:    `{"name":"g1","interval":"2m","rules":[{"record":"a:b","expr":"sum(up)"}]}`,
+				RuleMetrics:   `{"a:b":"a_b"}`,
+				SrcVmTableIds: "2_vm_1, 2_vm_2",
+				VmClusterId:   1,
+				DstVmTableId:  "2_vm_dst",
+			},
+			interval: 2 * time.Minute,
+			records:  []string{"a_b"},
+			srcIds:   []string{"2_vm_1", "2_vm_2"},
+		},
+		"yaml 配置使用默认周期": {
+			record: ruleModel.RecordRule{
+				Id:           2,
+				RuleConfig:   "name: g2\nrules:\n- record: r1\n  expr: sum(up)\n- record: r2\n  expr: count(up)\n",
+				VmClusterId:  1,
+				DstVmTableId: "2_vm_dst",
+			},
+			interval: time.Minute,
+			records:  []string{"r1", "r2"},
+		},
+		"周期过小": {
+			record: ruleModel.RecordRule{
+				Id:           3,
+				RuleConfig:   `{"interval":"1s","rules":[{"record":"r1","expr":"up"}]}`,
+				VmClusterId:  1,
+				DstVmTableId: "2_vm_dst",
+			},
+			interval: minInterval,
+			records:  []string{"r1"},
+		},
+		"缺少目标结果表": {
+			record: ruleModel.RecordRule{
+				Id:          4,
+				RuleConfig:  `{"rules":[{"record":"r1","expr":"up"}]}`,
+				VmClusterId: 1,
+			},
+			hasErr: true,
+		},
+		"缺少表达式": {
+			record: ruleModel.RecordRule{
+				Id:           5,
+				RuleConfig:   `{"rules":[{"record":"r1"}]}`,
+				VmClusterId:  1,
+				DstVmTableId: "2_vm_dst",
+			},
+			hasErr: true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rule, err := parseRecordRule(&c.record)
+			if c.hasErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.interval, rule.interval)
+			var records []string
+			for _, r := range rule.rules {
+				records = append(records, r.Record)
+			}
+			assert.Equal(t, c.records, records)
+			assert.Equal(t, c.srcIds, rule.srcTableIds)
+		})
+	}
+}
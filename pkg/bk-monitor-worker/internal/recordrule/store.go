@@ -0,0 +1,77 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	cfg "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models"
+	ruleModel "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/recordrule"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/models/storage"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/store/mysql"
+)
+
+// Store 规则及集群信息的存储
+type Store interface {
+	// ListRules 获取所有未删除的规则
+	ListRules() ([]ruleModel.RecordRule, error)
+	// GetCluster 获取 VM 集群的查询及写入地址
+	GetCluster(clusterId int) (*Cluster, error)
+	// UpdateStatus 更新规则状态
+	UpdateStatus(ruleId int, status string) error
+}
+
+// dbStore 基于 metadata db 的存储
+type dbStore struct{}
+
+func (dbStore) ListRules() ([]ruleModel.RecordRule, error) {
+	var rules []ruleModel.RecordRule
+	db := mysql.GetDBSession().DB
+	if err := ruleModel.NewRecordRuleQuerySet(db).StatusNe(models.RecordRuleStatusDeleted).All(&rules); err != nil {
+		return nil, errors.Wrap(err, "query record rules failed")
+	}
+	return rules, nil
+}
+
+func (dbStore) GetCluster(clusterId int) (*Cluster, error) {
+	var cluster storage.ClusterInfo
+	db := mysql.GetDBSession().DB
+	if err := storage.NewClusterInfoQuerySet(db).ClusterIDEq(uint(clusterId)).ClusterTypeEq(models.StorageTypeVM).One(&cluster); err != nil {
+		return nil, errors.Wrapf(err, "query vm cluster [%d] failed", clusterId)
+	}
+
+	schema := "http"
+	if cluster.Schema != nil && *cluster.Schema != "" {
+		schema = *cluster.Schema
+	}
+	address := fmt.Sprintf("%s://%s:%d", schema, cluster.DomainName, cluster.Port)
+	return &Cluster{
+		QueryUrl: address + ensureSlash(cfg.RecordRuleVmQueryPath),
+		WriteUrl: address + ensureSlash(cfg.RecordRuleVmWritePath),
+		Username: cluster.Username,
+		Password: cluster.Password,
+	}, nil
+}
+
+func (dbStore) UpdateStatus(ruleId int, status string) error {
+	db := mysql.GetDBSession().DB
+	return ruleModel.NewRecordRuleQuerySet(db).IdEq(ruleId).GetUpdater().SetStatus(status).Update()
+}
+
+func ensureSlash(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	return "/" + path
+}
@@ -0,0 +1,59 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package recordrule
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Writer 将计算结果写入目标 VM 集群
+type Writer interface {
+	Write(ctx context.Context, cluster *Cluster, series []prompb.TimeSeries) error
+}
+
+// NewWriter 创建 prometheus remote write 协议的 Writer
+func NewWriter(timeout time.Duration) Writer {
+	return &remoteWriter{client: &http.Client{Timeout: timeout}}
+}
+
+type remoteWriter struct {
+	client *http.Client
+}
+
+func (w *remoteWriter) Write(ctx context.Context, cluster *Cluster, series []prompb.TimeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: series})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cluster.WriteUrl, bytes.NewReader(snappy.Encode(nil, data)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if cluster.Username != "" {
+		req.SetBasicAuth(cluster.Username, cluster.Password)
+	}
+
+	_, err = doRequest(w.client, req)
+	return err
+}
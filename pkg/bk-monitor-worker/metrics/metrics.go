@@ -60,6 +60,51 @@ var (
 		[]string{"name"},
 	)
 
+	// 任务类型的执行限制
+	taskLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: bmwMetricNamespace,
+			Name:      "task_limit",
+			Help:      "task limit of the task type",
+		},
+		[]string{"name", "type"},
+	)
+	// 受限任务类型正在执行的任务数
+	taskLimitRunning = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: bmwMetricNamespace,
+			Name:      "task_limit_running",
+			Help:      "running task count of the limited task type",
+		},
+		[]string{"name"},
+	)
+	// 任务被限流而重新调度的次数
+	taskThrottledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: bmwMetricNamespace,
+			Name:      "task_throttled_total",
+			Help:      "task throttled total",
+		},
+		[]string{"name", "reason"},
+	)
+	// 预计算规则的计算次数
+	recordRuleEvaluationTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: bmwMetricNamespace,
+			Name:      "record_rule_evaluation_total",
+			Help:      "record rule evaluation total",
+		},
+		[]string{"rule_id", "status"},
+	)
+	// 预计算规则最近一次成功计算的时间点
+	recordRuleLastEvaluationTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: bmwMetricNamespace,
+			Name:      "record_rule_last_evaluation_timestamp_seconds",
+			Help:      "timestamp of the last successful record rule evaluation",
+		},
+		[]string{"rule_id"},
+	)
 	// 常驻任务正在运行的任务统计
 	daemonRunningTaskCount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -160,6 +205,62 @@ func RunTaskDurationSeconds(taskName string, startTime time.Time) {
 	metric.Observe(time.Since(startTime).Seconds())
 }
 
+// SetTaskLimit set the limit of the task type
+func SetTaskLimit(taskName, limitType string, value float64) {
+	metric, err := taskLimit.GetMetricWithLabelValues(taskName, limitType)
+	if err != nil {
+		logger.Errorf("prom get task limit metric failed: %s", err)
+		return
+	}
+	metric.Set(value)
+}
+
+// SetTaskLimitRunning set the running task count of the limited task type
+func SetTaskLimitRunning(taskName string, n int) {
+	metric, err := taskLimitRunning.GetMetricWithLabelValues(taskName)
+	if err != nil {
+		logger.Errorf("prom get task limit running metric failed: %s", err)
+		return
+	}
+	metric.Set(float64(n))
+}
+
+// TaskThrottledTotal task throttled total
+func TaskThrottledTotal(taskName, reason string) {
+	metric, err := taskThrottledTotal.GetMetricWithLabelValues(taskName, reason)
+	if err != nil {
+		logger.Errorf("prom get task throttled total metric failed: %s", err)
+		return
+	}
+	metric.Inc()
+}
+
+// RecordRuleEvaluationTotal record rule evaluation total
+func RecordRuleEvaluationTotal(ruleId, status string) {
+	metric, err := recordRuleEvaluationTotal.GetMetricWithLabelValues(ruleId, status)
+	if err != nil {
+		logger.Errorf("prom get record rule evaluation total metric failed: %s", err)
+		return
+	}
+	metric.Inc()
+}
+
+// SetRecordRuleLastEvaluation set the last successful evaluation time of the record rule
+func SetRecordRuleLastEvaluation(ruleId string, t time.Time) {
+	metric, err := recordRuleLastEvaluationTimestamp.GetMetricWithLabelValues(ruleId)
+	if err != nil {
+		logger.Errorf("prom get record rule last evaluation metric failed: %s", err)
+		return
+	}
+	metric.Set(float64(t.Unix()))
+}
+
+// DeleteRecordRuleMetrics delete the metrics of the removed record rule
+func DeleteRecordRuleMetrics(ruleId string) {
+	recordRuleEvaluationTotal.DeletePartialMatch(prometheus.Labels{"rule_id": ruleId})
+	recordRuleLastEvaluationTimestamp.DeleteLabelValues(ruleId)
+}
+
 // 设置 api 请求的耗时
 func SetApiRequestCostTime(method, apiPath string) func() {
 	start := time.Now()
@@ -195,6 +296,11 @@ func init() {
 		apiRequestCost,
 		taskTotal,
 		taskDurationSeconds,
+		taskLimit,
+		taskLimitRunning,
+		taskThrottledTotal,
+		recordRuleEvaluationTotal,
+		recordRuleLastEvaluationTimestamp,
 		daemonRunningTaskCount,
 		daemonTaskRetryCount,
 	)
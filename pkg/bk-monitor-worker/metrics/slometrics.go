@@ -91,6 +91,46 @@ var (
 		[]string{"bk_biz_id", "range_time", "strategy_id", "scene", "event_id", "event_status"},
 	)
 
+	sloObjectiveTarget = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_objective_target",
+			Help: "SLO objective target",
+		},
+		[]string{"bk_biz_id", "scene", "velat", "strategy_id", "strategy_name"},
+	)
+
+	sloObjectiveSli = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_objective_sli",
+			Help: "SLI of the SLO objective in the rolling window",
+		},
+		[]string{"bk_biz_id", "scene", "velat", "strategy_id", "strategy_name", "window"},
+	)
+
+	sloErrorBudgetRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_error_budget_remaining",
+			Help: "Remaining error budget ratio of the SLO objective in the rolling window",
+		},
+		[]string{"bk_biz_id", "scene", "velat", "strategy_id", "strategy_name", "window"},
+	)
+
+	sloBurnRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_burn_rate",
+			Help: "Error budget burn rate of the SLO objective",
+		},
+		[]string{"bk_biz_id", "scene", "velat", "strategy_id", "strategy_name", "window"},
+	)
+
+	sloBurnRateAlert = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_burn_rate_alert",
+			Help: "Whether both the long and short window burn rates exceed the factor",
+		},
+		[]string{"bk_biz_id", "scene", "velat", "strategy_id", "strategy_name", "long_window", "short_window"},
+	)
+
 	sloMonitor = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "slo_monitor",
@@ -114,6 +154,26 @@ func InitGauge(Registry *prometheus.Registry) {
 	)
 }
 
+// InitObjectiveGauge 注册 slo 目标相关指标，slo 目标与业务批次无关，使用单独的 Registry 上报
+func InitObjectiveGauge(Registry *prometheus.Registry) {
+	Registry.MustRegister(
+		sloObjectiveTarget,
+		sloObjectiveSli,
+		sloErrorBudgetRemaining,
+		sloBurnRate,
+		sloBurnRateAlert,
+	)
+}
+
+// ResetObjectiveGauge 清理 slo 目标相关指标，避免已删除的目标继续上报
+func ResetObjectiveGauge() {
+	sloObjectiveTarget.Reset()
+	sloObjectiveSli.Reset()
+	sloErrorBudgetRemaining.Reset()
+	sloBurnRate.Reset()
+	sloBurnRateAlert.Reset()
+}
+
 // RecordSloMonitor updates the RecordSloMonitor metric with the provided values
 func RecordSloMonitor(bk_biz_id string, scene string, name string, flag string) {
 	metric, err := sloMonitor.GetMetricWithLabelValues(bk_biz_id, scene, name, flag)
@@ -209,9 +269,72 @@ func RecordSloErrorEventTimeInfo(value float64, bk_biz_id string, range_time str
 	metric.Set(value)
 }
 
+// RecordSloObjective updates the slo objective metrics with the provided values
+func RecordSloObjective(target, sli, budgetRemaining float64, bk_biz_id string, scene string, velat string, strategy_id string, strategy_name string, window string) {
+	targetMetric, err := sloObjectiveTarget.GetMetricWithLabelValues(bk_biz_id, scene, velat, strategy_id, strategy_name)
+	if err != nil {
+		logger.Errorf("prom get [sloObjectiveTarget] metric failed: %s", err)
+		RecordSloMonitor(bk_biz_id, scene, "SloObjective", "0")
+		return
+	}
+	sliMetric, err := sloObjectiveSli.GetMetricWithLabelValues(bk_biz_id, scene, velat, strategy_id, strategy_name, window)
+	if err != nil {
+		logger.Errorf("prom get [sloObjectiveSli] metric failed: %s", err)
+		RecordSloMonitor(bk_biz_id, scene, "SloObjective", "0")
+		return
+	}
+	budgetMetric, err := sloErrorBudgetRemaining.GetMetricWithLabelValues(bk_biz_id, scene, velat, strategy_id, strategy_name, window)
+	if err != nil {
+		logger.Errorf("prom get [sloErrorBudgetRemaining] metric failed: %s", err)
+		RecordSloMonitor(bk_biz_id, scene, "SloObjective", "0")
+		return
+	}
+	RecordSloMonitor(bk_biz_id, scene, "SloObjective", "1")
+	targetMetric.Set(target)
+	sliMetric.Set(sli)
+	budgetMetric.Set(budgetRemaining)
+}
+
+// RecordSloBurnRate updates the sloBurnRate metric with the provided values
+func RecordSloBurnRate(value float64, bk_biz_id string, scene string, velat string, strategy_id string, strategy_name string, window string) {
+	metric, err := sloBurnRate.GetMetricWithLabelValues(bk_biz_id, scene, velat, strategy_id, strategy_name, window)
+	if err != nil {
+		logger.Errorf("prom get [sloBurnRate] metric failed: %s", err)
+		RecordSloMonitor(bk_biz_id, scene, "SloBurnRate", "0")
+		return
+	}
+	RecordSloMonitor(bk_biz_id, scene, "SloBurnRate", "1")
+	metric.Set(value)
+}
+
+// RecordSloBurnRateAlert updates the sloBurnRateAlert metric with the provided values
+func RecordSloBurnRateAlert(firing bool, bk_biz_id string, scene string, velat string, strategy_id string, strategy_name string, long_window string, short_window string) {
+	metric, err := sloBurnRateAlert.GetMetricWithLabelValues(bk_biz_id, scene, velat, strategy_id, strategy_name, long_window, short_window)
+	if err != nil {
+		logger.Errorf("prom get [sloBurnRateAlert] metric failed: %s", err)
+		RecordSloMonitor(bk_biz_id, scene, "SloBurnRateAlert", "0")
+		return
+	}
+	RecordSloMonitor(bk_biz_id, scene, "SloBurnRateAlert", "1")
+	if firing {
+		metric.Set(1)
+	} else {
+		metric.Set(0)
+	}
+}
+
 func PushRes(Registry *prometheus.Registry) {
+	push2Gateway(Registry, "slo")
+}
+
+// PushObjectiveRes 上报 slo 目标相关指标，使用单独的 job 避免与业务批次的指标互相覆盖
+func PushObjectiveRes(Registry *prometheus.Registry) {
+	push2Gateway(Registry, "slo_objective")
+}
+
+func push2Gateway(Registry *prometheus.Registry, job string) {
 	// 创建一个新的 Pusher
-	pusher := push.New(config.SloPushGatewayEndpoint, "slo").Gatherer(Registry)
+	pusher := push.New(config.SloPushGatewayEndpoint, job).Gatherer(Registry)
 
 	// 设置自定义客户端
 	pusher.Client(&bkClient{})
@@ -222,5 +345,5 @@ func PushRes(Registry *prometheus.Registry) {
 		return
 	}
 
-	logger.Info("Pushed all metrics successfully")
+	logger.Infof("Pushed all metrics of job [%s] successfully", job)
 }
@@ -38,3 +38,13 @@ type ErrorHandlerFunc func(ctx context.Context, task *t.Task, err error)
 func (fn ErrorHandlerFunc) HandleError(ctx context.Context, task *t.Task, err error) {
 	fn(ctx, task, err)
 }
+
+// WorkflowHandler 任务编排的推进接口，任务执行前后由 processor 回调
+type WorkflowHandler interface {
+	// PrepareContext 任务执行前注入编排上下文，如上一阶段的执行结果
+	PrepareContext(ctx context.Context, msg *t.TaskMessage) context.Context
+	// HandleSucceeded 任务执行成功后推进编排，返回错误时任务结果未被记录，需要重新执行任务
+	HandleSucceeded(ctx context.Context, msg *t.TaskMessage) error
+	// HandleFailed 任务重试耗尽后终止编排
+	HandleFailed(ctx context.Context, msg *t.TaskMessage, err error)
+}
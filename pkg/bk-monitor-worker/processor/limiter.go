@@ -0,0 +1,157 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// 任务被限流的原因
+const (
+	ThrottleReasonConcurrency       = "concurrency"
+	ThrottleReasonRate              = "rate"
+	ThrottleReasonGlobalConcurrency = "global_concurrency"
+)
+
+var errTaskThrottled = errors.New("task throttled")
+
+// minThrottleDelay 任务被限流后重新调度的最小延迟
+const minThrottleDelay = time.Second
+
+// TaskLimit 任务类型的执行限制，为 0 的项不限制
+type TaskLimit struct {
+	// Concurrency 单个 worker 内同时执行的最大任务数
+	Concurrency int
+	// Rate 单个 worker 每秒最多开始执行的任务数
+	Rate float64
+	// Burst 允许突发执行的任务数，默认为 1
+	Burst int
+	// GlobalConcurrency 所有 worker 同时执行的最大任务数，通过 broker 实现的分布式信号量控制
+	GlobalConcurrency int
+}
+
+// kindLimiter 单个任务类型的限制器
+type kindLimiter struct {
+	kind  string
+	limit TaskLimit
+	rate  *rate.Limiter
+
+	mut     sync.Mutex
+	running int
+}
+
+// TaskLimiter 按任务类型限制任务的执行，被限流的任务重新调度而不是失败
+type TaskLimiter struct {
+	semaphore broker.SemaphoreBroker
+	limiters  map[string]*kindLimiter
+}
+
+// NewTaskLimiter new a task limiter, the global concurrency is ignored if the broker does not support semaphore
+func NewTaskLimiter(b broker.Broker, limits map[string]TaskLimit) *TaskLimiter {
+	l := &TaskLimiter{limiters: make(map[string]*kindLimiter)}
+	l.semaphore, _ = b.(broker.SemaphoreBroker)
+
+	for kind, limit := range limits {
+		kl := &kindLimiter{kind: kind, limit: limit}
+		if limit.Rate > 0 {
+			burst := limit.Burst
+			if burst <= 0 {
+				burst = 1
+			}
+			kl.rate = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+		}
+		if limit.GlobalConcurrency > 0 && l.semaphore == nil {
+			logger.Warnf("broker does not support semaphore, global concurrency of task: %s is ignored", kind)
+		}
+		l.limiters[kind] = kl
+
+		metrics.SetTaskLimit(kind, ThrottleReasonConcurrency, float64(limit.Concurrency))
+		metrics.SetTaskLimit(kind, ThrottleReasonRate, limit.Rate)
+		metrics.SetTaskLimit(kind, ThrottleReasonGlobalConcurrency, float64(limit.GlobalConcurrency))
+	}
+	return l
+}
+
+// Acquire 获取任务的执行许可，成功时返回释放许可的函数，任务执行结束后需要调用，被限流时返回重新调度的延迟
+// 全局许可在 expireAt 后自动失效，避免 worker 异常退出后无法释放
+func (l *TaskLimiter) Acquire(ctx context.Context, msg *t.TaskMessage, expireAt time.Time) (release func(), delay time.Duration, ok bool) {
+	kl, exists := l.limiters[msg.Kind]
+	if !exists {
+		return func() {}, 0, true
+	}
+
+	// 单个 worker 内的并发
+	kl.mut.Lock()
+	if kl.limit.Concurrency > 0 && kl.running >= kl.limit.Concurrency {
+		kl.mut.Unlock()
+		return l.throttle(kl, ThrottleReasonConcurrency, minThrottleDelay)
+	}
+	kl.running++
+	metrics.SetTaskLimitRunning(kl.kind, kl.running)
+	kl.mut.Unlock()
+
+	releaseLocal := func() {
+		kl.mut.Lock()
+		kl.running--
+		metrics.SetTaskLimitRunning(kl.kind, kl.running)
+		kl.mut.Unlock()
+	}
+
+	// 执行频率，被限流时归还令牌
+	var reservation *rate.Reservation
+	if kl.rate != nil {
+		reservation = kl.rate.Reserve()
+		if d := reservation.Delay(); d > 0 {
+			reservation.Cancel()
+			releaseLocal()
+			return l.throttle(kl, ThrottleReasonRate, d)
+		}
+	}
+
+	// 所有 worker 的并发
+	if kl.limit.GlobalConcurrency > 0 && l.semaphore != nil {
+		acquired, err := l.semaphore.AcquireSemaphore(ctx, kl.kind, msg.ID, kl.limit.GlobalConcurrency, expireAt)
+		if err != nil {
+			logger.Errorf("acquire semaphore of task: %s error, %v", kl.kind, err)
+		}
+		if err != nil || !acquired {
+			if reservation != nil {
+				reservation.Cancel()
+			}
+			releaseLocal()
+			return l.throttle(kl, ThrottleReasonGlobalConcurrency, minThrottleDelay)
+		}
+		return func() {
+			releaseLocal()
+			if err := l.semaphore.ReleaseSemaphore(context.Background(), kl.kind, msg.ID); err != nil {
+				logger.Errorf("release semaphore of task: %s error, %v", kl.kind, err)
+			}
+		}, 0, true
+	}
+	return releaseLocal, 0, true
+}
+
+func (l *TaskLimiter) throttle(kl *kindLimiter, reason string, delay time.Duration) (func(), time.Duration, bool) {
+	metrics.TaskThrottledTotal(kl.kind, reason)
+	if delay < minThrottleDelay {
+		delay = minThrottleDelay
+	}
+	return nil, delay, false
+}
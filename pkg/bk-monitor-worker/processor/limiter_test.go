@@ -0,0 +1,107 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package processor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/bbolt"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+)
+
+func newTestBroker(test *testing.T) *bbolt.Broker {
+	b := bbolt.NewBroker(filepath.Join(test.TempDir(), "broker.db"))
+	assert.NoError(test, b.Open())
+	test.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestTaskLimiter(test *testing.T) {
+	ctx := context.Background()
+	expireAt := time.Now().Add(time.Minute)
+	msg := func(id, kind string) *t.TaskMessage {
+		return &t.TaskMessage{ID: id, Kind: kind, Queue: "default"}
+	}
+
+	testCases := map[string]struct {
+		limit TaskLimit
+		// 依次获取许可，空字符串表示释放最近获取成功的许可
+		ids      []string
+		expected []bool
+	}{
+		"单个 worker 并发": {
+			limit:    TaskLimit{Concurrency: 2},
+			ids:      []string{"t1", "t2", "t3", "", "t3"},
+			expected: []bool{true, true, false, true},
+		},
+		"执行频率": {
+			limit:    TaskLimit{Rate: 0.1, Burst: 2},
+			ids:      []string{"t1", "t2", "", "t3"},
+			expected: []bool{true, true, false},
+		},
+		"全局并发": {
+			limit:    TaskLimit{GlobalConcurrency: 1},
+			ids:      []string{"t1", "t2", "", "t2"},
+			expected: []bool{true, false, true},
+		},
+	}
+
+	for name, c := range testCases {
+		test.Run(name, func(test *testing.T) {
+			l := NewTaskLimiter(newTestBroker(test), map[string]TaskLimit{"async:heavy": c.limit})
+
+			var (
+				releases []func()
+				results  []bool
+			)
+			for _, id := range c.ids {
+				if id == "" {
+					releases[len(releases)-1]()
+					releases = releases[:len(releases)-1]
+					continue
+				}
+				release, delay, ok := l.Acquire(ctx, msg(id, "async:heavy"), expireAt)
+				results = append(results, ok)
+				if ok {
+					releases = append(releases, release)
+				} else {
+					assert.GreaterOrEqual(test, delay, minThrottleDelay)
+				}
+			}
+			assert.Equal(test, c.expected, results)
+
+			// 未配置限制的任务类型不受影响
+			_, _, ok := l.Acquire(ctx, msg("t9", "async:light"), expireAt)
+			assert.True(test, ok)
+		})
+	}
+}
+
+func TestTaskLimiterShareGlobalConcurrency(test *testing.T) {
+	ctx := context.Background()
+	b := newTestBroker(test)
+	limits := map[string]TaskLimit{"async:heavy": {GlobalConcurrency: 1}}
+
+	// 两个 worker 共享同一个 broker
+	l1, l2 := NewTaskLimiter(b, limits), NewTaskLimiter(b, limits)
+	release, _, ok := l1.Acquire(ctx, &t.TaskMessage{ID: "t1", Kind: "async:heavy"}, time.Now().Add(time.Minute))
+	assert.True(test, ok)
+	_, _, ok = l2.Acquire(ctx, &t.TaskMessage{ID: "t2", Kind: "async:heavy"}, time.Now().Add(time.Minute))
+	assert.False(test, ok)
+
+	release()
+	_, _, ok = l2.Acquire(ctx, &t.TaskMessage{ID: "t2", Kind: "async:heavy"}, time.Now().Add(time.Minute))
+	assert.True(test, ok)
+}
@@ -47,6 +47,10 @@ type Processor struct {
 	IsFailureFunc  func(error) bool
 
 	ErrHandler ErrorHandler
+	// Workflow 推进任务编排，为空时不处理编排
+	Workflow WorkflowHandler
+	// Limiter 按任务类型限制任务的执行
+	Limiter *TaskLimiter
 	// sema is a counting semaphore to ensure the number of active workers
 	// does not exceed the limit.
 	Sema chan struct{}
@@ -62,6 +66,9 @@ type Processor struct {
 
 	// abort operate
 	Abort chan struct{}
+
+	// Active 正在执行的任务，用于上报运行状态
+	Active sync.Map
 }
 
 type ProcessorParams struct {
@@ -73,6 +80,8 @@ type ProcessorParams struct {
 	Queues          map[string]int
 	StrictPriority  bool
 	ErrHandler      ErrorHandler
+	Workflow        WorkflowHandler
+	TaskLimits      map[string]TaskLimit
 	ShutdownTimeout time.Duration
 }
 
@@ -96,6 +105,8 @@ func NewProcessor(params ProcessorParams) *Processor {
 		Quit:            make(chan struct{}),
 		Abort:           make(chan struct{}),
 		ErrHandler:      params.ErrHandler,
+		Workflow:        params.Workflow,
+		Limiter:         NewTaskLimiter(params.Broker, params.TaskLimits),
 		Handler:         HandlerFunc(func(ctx context.Context, t *t.Task) error { return fmt.Errorf("handler not set") }),
 		ShutdownTimeout: params.ShutdownTimeout,
 	}
@@ -167,9 +178,25 @@ func (p *Processor) Exec() {
 		}
 
 		lease := common.NewLease(leaseExpirationTime)
+		release, delay, ok := p.Limiter.Acquire(context.Background(), msg, leaseExpirationTime)
+		if !ok {
+			p.Throttle(lease, msg, delay)
+			<-p.Sema // release token
+			return
+		}
 		deadline := p.ComputeDeadline(msg)
+		p.Active.Store(msg.ID, &common.WorkerInfo{
+			ID:       msg.ID,
+			Kind:     msg.Kind,
+			Payload:  msg.Payload,
+			Queue:    msg.Queue,
+			Started:  time.Now(),
+			Deadline: deadline,
+		})
 		go func() {
 			defer func() {
+				release()
+				p.Active.Delete(msg.ID)
 				<-p.Sema // release token
 			}()
 
@@ -177,6 +204,10 @@ func (p *Processor) Exec() {
 			defer func() {
 				cancel()
 			}()
+			ctx = t.AddResultWriter2Context(ctx, p.Broker)
+			if p.Workflow != nil {
+				ctx = p.Workflow.PrepareContext(ctx, msg)
+			}
 
 			// check context before starting a worker goroutine.
 			select {
@@ -232,6 +263,17 @@ func (p *Processor) Exec() {
 	}
 }
 
+// ActiveWorkers returns the tasks being processed
+func (p *Processor) ActiveWorkers() []*common.WorkerInfo {
+	var workers []*common.WorkerInfo
+	p.Active.Range(func(_, value any) bool {
+		w := *value.(*common.WorkerInfo)
+		workers = append(workers, &w)
+		return true
+	})
+	return workers
+}
+
 // Requeue enqueue for retry task
 func (p *Processor) Requeue(l *common.Lease, msg *t.TaskMessage) {
 	if !l.IsValid() {
@@ -247,8 +289,32 @@ func (p *Processor) Requeue(l *common.Lease, msg *t.TaskMessage) {
 	}
 }
 
+// Throttle 任务被限流，延迟后重新执行，不计入重试次数
+func (p *Processor) Throttle(l *common.Lease, msg *t.TaskMessage, delay time.Duration) {
+	ctx, cancel := context.WithDeadline(context.Background(), l.Deadline())
+	defer cancel()
+	err := p.Broker.Retry(ctx, msg, time.Now().Add(delay), errTaskThrottled.Error(), false)
+	if err != nil {
+		logger.Warnf("throttle task id=%s type=%q error, %v", msg.ID, msg.Kind, err)
+		return
+	}
+	logger.Debugf("Task id=%s type=%q is throttled, retry after %s", msg.ID, msg.Kind, delay)
+}
+
 // HandleSucceededMessage succeeded task handler
 func (p *Processor) HandleSucceededMessage(l *common.Lease, msg *t.TaskMessage) {
+	// 需要在任务删除前推进编排，以读取任务写入的结果
+	if p.Workflow != nil && l.IsValid() {
+		ctx, cancel := context.WithDeadline(context.Background(), l.Deadline())
+		err := p.Workflow.HandleSucceeded(ctx, msg)
+		cancel()
+		if err != nil {
+			// 结果未记录时编排无法推进，重新执行任务，不计入重试次数
+			logger.Warnf("workflow of task id=%s is not advanced, retry the task, error: %v", msg.ID, err)
+			p.Retry(l, msg, err, false)
+			return
+		}
+	}
 	if msg.Retention > 0 {
 		p.MarkAsComplete(l, msg)
 	} else {
@@ -302,6 +368,11 @@ func (p *Processor) HandleFailedMessage(ctx context.Context, l *common.Lease, ms
 	if msg.Retried >= msg.Retry || errors.Is(err, skipRetryErr) {
 		logger.Warnf("Retry exhausted for task id=%s", msg.ID)
 		p.Archive(l, msg, err)
+		if p.Workflow != nil {
+			wctx, cancel := context.WithDeadline(context.Background(), l.Deadline())
+			p.Workflow.HandleFailed(wctx, msg, err)
+			cancel()
+		}
 	} else {
 		logger.Warnf("Task failed and retry for task id=%s, error: %v", msg.ID, err)
 		p.Retry(l, msg, err, true)
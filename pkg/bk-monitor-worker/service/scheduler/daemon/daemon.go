@@ -20,6 +20,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/alarm/cmdbcache"
 	apmTasks "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/apm/pre_calculate"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/recordrule"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
@@ -79,6 +80,10 @@ var taskDefine = map[string]OperatorDefine{
 		logger.Info("cmdb_cache_refresh daemon task is initialized")
 		return &cmdbcache.CacheRefreshDaemon{}, nil
 	}},
+	"daemon:metadata:record_rule_evaluate": {initialFunc: func(ctx context.Context) (Operator, error) {
+		logger.Info("record_rule_evaluate daemon task is initialized")
+		return &recordrule.EvaluateDaemon{}, nil
+	}},
 }
 
 var daemonTaskDimensionOperatorMapping map[string]func(payload []byte) string
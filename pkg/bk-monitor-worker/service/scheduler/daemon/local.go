@@ -0,0 +1,119 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package daemon
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/worker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// 单机部署(bbolt broker)时只有一个 worker 进程，常驻任务不需要分配，
+// 由该进程的 RunMaintainer 直接执行 broker 中的全部常驻任务，重载请求通过进程内的 channel 传递
+
+var (
+	localReloadChan = make(chan TaskBinding, 16)
+
+	localWorkerMu sync.RWMutex
+	localWorkerId string
+)
+
+// LocalTaskBroker 返回单机部署时的常驻任务存储，redis broker 下返回 false
+func LocalTaskBroker() (broker.DaemonTaskBroker, bool) {
+	if config.BrokerType != broker.TypeBbolt {
+		return nil, false
+	}
+	b, ok := worker.GetBroker().(broker.DaemonTaskBroker)
+	return b, ok
+}
+
+// LocalWorkerId 返回单机部署时执行常驻任务的 worker，RunMaintainer 未启动时为空
+func LocalWorkerId() string {
+	localWorkerMu.RLock()
+	defer localWorkerMu.RUnlock()
+	return localWorkerId
+}
+
+func setLocalWorkerId(workerId string) {
+	localWorkerMu.Lock()
+	defer localWorkerMu.Unlock()
+	localWorkerId = workerId
+}
+
+// listLocalTaskBindings 将 broker 中的全部常驻任务绑定到当前 worker
+func listLocalTaskBindings(b broker.DaemonTaskBroker) ([]TaskBinding, error) {
+	items, err := b.ListDaemonTasks()
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]TaskBinding, 0, len(items))
+	for _, data := range items {
+		var t task.SerializerTask
+		if err = jsonx.Unmarshal(data, &t); err != nil {
+			logger.Errorf("failed to parse daemon task: %s, error: %s", data, err)
+			continue
+		}
+		bindings = append(bindings, TaskBinding{UniId: ComputeTaskUniId(t), SerializerTask: t})
+	}
+	return bindings, nil
+}
+
+// ReloadLocalTask 合并新的 payload 并更新常驻任务，然后通知 RunMaintainer 重启该任务
+func ReloadLocalTask(taskUniId string, newPayload []byte) error {
+	b, ok := LocalTaskBroker()
+	if !ok {
+		return fmt.Errorf("daemon task is not stored locally by broker: %s", config.BrokerType)
+	}
+	items, err := b.ListDaemonTasks()
+	if err != nil {
+		return err
+	}
+
+	for _, originData := range items {
+		var t task.SerializerTask
+		if err = jsonx.Unmarshal(originData, &t); err != nil || ComputeTaskUniId(t) != taskUniId {
+			continue
+		}
+
+		if len(newPayload) != 0 {
+			if t.Payload, err = mergeMapping(t.Payload, newPayload); err != nil {
+				return err
+			}
+			if ComputeTaskUniId(t) != taskUniId {
+				return fmt.Errorf("taskUniId: %s is inconsistent after update, "+
+					"the dimension field of this task.payload cannot be modified", taskUniId)
+			}
+		}
+		newData, err := jsonx.Marshal(t)
+		if err != nil {
+			return err
+		}
+		if err = b.RemoveDaemonTask(originData); err != nil {
+			return err
+		}
+		if err = b.AddDaemonTask(newData); err != nil {
+			return err
+		}
+
+		select {
+		case localReloadChan <- TaskBinding{UniId: taskUniId, SerializerTask: t}:
+			return nil
+		default:
+			return fmt.Errorf("too many pending reload requests, taskUniId: %s", taskUniId)
+		}
+	}
+	return fmt.Errorf("taskUniId: %s not found", taskUniId)
+}
@@ -0,0 +1,61 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+// TestLocalDaemonTask 单机部署时 worker 直接执行 broker 中的全部常驻任务，并在进程内重载
+func TestLocalDaemonTask(t *testing.T) {
+	config.BrokerType = broker.TypeBbolt
+	config.BrokerBboltPath = filepath.Join(t.TempDir(), "broker.db")
+
+	b, ok := LocalTaskBroker()
+	assert.True(t, ok)
+
+	serializerTask, err := task.NewSerializerTask(task.Task{
+		Kind:    "daemon:apm:pre_calculate",
+		Payload: []byte(`{"data_id":"543713"}`),
+	})
+	assert.NoError(t, err)
+	data, err := jsonx.Marshal(serializerTask)
+	assert.NoError(t, err)
+	assert.NoError(t, b.AddDaemonTask(data))
+
+	maintainer := NewDaemonTaskRunMaintainer(context.Background(), "worker-1")
+	assert.Equal(t, "worker-1", LocalWorkerId())
+	bindings, err := maintainer.listTaskBindings()
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 1)
+	uniId := ComputeTaskUniId(*serializerTask)
+	assert.Equal(t, uniId, bindings[0].UniId)
+
+	// 重载时合并 payload，唯一维度不能修改
+	assert.Error(t, ReloadLocalTask(uniId, []byte(`{"data_id":"1"}`)))
+	assert.NoError(t, ReloadLocalTask(uniId, []byte(`{"qps":10}`)))
+	reload := <-localReloadChan
+	assert.Equal(t, uniId, reload.UniId)
+	assert.JSONEq(t, `{"data_id":"543713","qps":10}`, string(reload.Payload))
+
+	bindings, err = maintainer.listTaskBindings()
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 1)
+	assert.JSONEq(t, `{"data_id":"543713","qps":10}`, string(bindings[0].Payload))
+}
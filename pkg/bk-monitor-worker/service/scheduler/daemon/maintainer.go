@@ -17,6 +17,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	rdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
@@ -56,6 +57,7 @@ type RunMaintainer struct {
 	listenReloadKey string
 
 	redisClient           redis.UniversalClient
+	localTasks            broker.DaemonTaskBroker
 	methodOperatorMapping map[string]Operator
 	runningInstance       *sync.Map
 }
@@ -86,22 +88,13 @@ func (r *RunMaintainer) Run() {
 			return
 		case <-ticker.C:
 			currentTask := make(map[string]bool)
-			taskHash, err := r.redisClient.HGetAll(r.ctx, r.listenTaskKey).Result()
+			bindings, err := r.listTaskBindings()
 			if err != nil {
 				logger.Errorf("DaemonTask maintainer(%s) check %s change failed. error: %s", r.listenWorkerId, r.listenTaskKey, err)
 				continue
 			}
 
-			for taskUniId, taskStr := range taskHash {
-				var taskBinding TaskBinding
-				if err = jsonx.Unmarshal([]byte(taskStr), &taskBinding); err != nil {
-					logger.Errorf(
-						"failed to parse value to TaskBinding on key: %s field: %s. "+
-							"error: %s", r.listenTaskKey, taskUniId, err,
-					)
-					continue
-				}
-
+			for _, taskBinding := range bindings {
 				currentTask[taskBinding.UniId] = true
 				if !taskMarkMapping[taskBinding.UniId] {
 					r.handleAddTaskBinding(taskBinding)
@@ -119,6 +112,31 @@ func (r *RunMaintainer) Run() {
 	}
 }
 
+// listTaskBindings 获取分配给当前 worker 的常驻任务，单机部署时为 broker 中的全部常驻任务
+func (r *RunMaintainer) listTaskBindings() ([]TaskBinding, error) {
+	if r.localTasks != nil {
+		return listLocalTaskBindings(r.localTasks)
+	}
+
+	taskHash, err := r.redisClient.HGetAll(r.ctx, r.listenTaskKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]TaskBinding, 0, len(taskHash))
+	for taskUniId, taskStr := range taskHash {
+		var taskBinding TaskBinding
+		if err = jsonx.Unmarshal([]byte(taskStr), &taskBinding); err != nil {
+			logger.Errorf(
+				"failed to parse value to TaskBinding on key: %s field: %s. "+
+					"error: %s", r.listenTaskKey, taskUniId, err,
+			)
+			continue
+		}
+		bindings = append(bindings, taskBinding)
+	}
+	return bindings, nil
+}
+
 func (r *RunMaintainer) handleAddTaskBinding(taskBinding TaskBinding) {
 	define, exist := r.methodOperatorMapping[taskBinding.Kind]
 	if !exist {
@@ -272,6 +290,18 @@ func (r *RunMaintainer) listenReloadSignal() {
 		r.listenWorkerId, r.listenWorkerId, r.listenReloadKey, r.config.checkInterval,
 	)
 
+	if r.localTasks != nil {
+		for {
+			select {
+			case <-r.ctx.Done():
+				logger.Infof("[ReloadSignalListener] receive lifeline context done singal, stopped and return")
+				return
+			case binding := <-localReloadChan:
+				r.handleReloadBinding(binding)
+			}
+		}
+	}
+
 	sub := rdb.GetRDB().Client().Subscribe(r.ctx, r.listenReloadKey)
 	ch := sub.Channel()
 	for {
@@ -314,8 +344,8 @@ func (r *RunMaintainer) handleReloadBinding(taskBinding TaskBinding) {
 	)
 }
 
+// NewDaemonTaskRunMaintainer 单机部署时直接执行 broker 中的全部常驻任务，否则执行分配给当前 worker 的常驻任务
 func NewDaemonTaskRunMaintainer(ctx context.Context, workerId string) *RunMaintainer {
-
 	operatorMapping := make(map[string]Operator, len(taskDefine))
 
 	for taskKind, define := range taskDefine {
@@ -336,14 +366,23 @@ func NewDaemonTaskRunMaintainer(ctx context.Context, workerId string) *RunMainta
 		RetryTolerateCount: config.WorkerDaemonTaskRetryTolerateCount,
 	}
 
-	return &RunMaintainer{
+	maintainer := &RunMaintainer{
 		ctx:                   ctx,
 		config:                options,
 		listenWorkerId:        workerId,
-		listenTaskKey:         common.DaemonBindingWorker(workerId),
-		listenReloadKey:       common.DaemonReloadExecQueue(workerId),
-		redisClient:           rdb.GetRDB().Client(),
 		methodOperatorMapping: operatorMapping,
 		runningInstance:       &sync.Map{},
 	}
+	if localTasks, ok := LocalTaskBroker(); ok {
+		maintainer.localTasks = localTasks
+		maintainer.listenTaskKey = config.BrokerType
+		maintainer.listenReloadKey = config.BrokerType
+		setLocalWorkerId(workerId)
+		return maintainer
+	}
+
+	maintainer.listenTaskKey = common.DaemonBindingWorker(workerId)
+	maintainer.listenReloadKey = common.DaemonReloadExecQueue(workerId)
+	maintainer.redisClient = rdb.GetRDB().Client()
+	return maintainer
 }
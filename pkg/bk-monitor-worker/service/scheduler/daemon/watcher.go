@@ -309,7 +309,7 @@ func (d *DefaultWatcher) overrideAndUpdateTaskPayload(mark *watchTaskMark) error
 			"[OverridePayload] find new payload, override.\nNEW: %s\nOLD: %s\n",
 			mark.newPayload, mark.task.Payload,
 		)
-		mergePayload, err := d.mergeMapping(mark.task.Payload, mark.newPayload)
+		mergePayload, err := mergeMapping(mark.task.Payload, mark.newPayload)
 		if err != nil {
 			return err
 		}
@@ -335,7 +335,7 @@ func (d *DefaultWatcher) overrideAndUpdateTaskPayload(mark *watchTaskMark) error
 	return err
 }
 
-func (d *DefaultWatcher) mergeMapping(origin []byte, target []byte) ([]byte, error) {
+func mergeMapping(origin []byte, target []byte) ([]byte, error) {
 	// Merge two map
 	var originMapping map[string]any
 	var targetMapping map[string]any
@@ -18,6 +18,8 @@ import (
 	cfg "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
 	cmESTask "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics/es"
 	cmInfluxdbTask "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics/influxdb"
+	cmKafkaTask "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics/kafka"
+	cmVmTask "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/clustermetrics/vm"
 	metadataTask "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/internal/metadata/task"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/processor"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
@@ -61,6 +63,8 @@ func getPeriodicTasks() map[string]PeriodicTask {
 	ReportInfluxdbClusterMetrics := "periodic:cluster_metrics:report_influxdb"
 	PushAndPublishSpaceRouterInfo := "periodic:cluster_metrics:push_and_publish_space_router_info"
 	ReportESClusterMetrics := "periodic:cluster_metrics:report_es"
+	ReportKafkaClusterMetrics := "periodic:cluster_metrics:report_kafka"
+	ReportVmClusterMetrics := "periodic:cluster_metrics:report_vm"
 	ClearDeprecatedRedisKey := "periodic:metadata:clear_deprecated_redis_key"
 	CleanDataIdConsulPath := "periodic:metadata:clean_data_id_consul_path"
 
@@ -170,6 +174,15 @@ func getPeriodicTasks() map[string]PeriodicTask {
 			Handler: cmESTask.ReportESClusterMetrics,
 			Option:  []task.Option{task.Queue(cfg.ESClusterMetricQueueName), task.Timeout(300 * time.Second)},
 		},
+		ReportKafkaClusterMetrics: {
+			Cron:    "*/1 * * * *",
+			Handler: cmKafkaTask.ReportKafkaClusterMetric,
+			Option:  []task.Option{task.Timeout(50 * time.Second)},
+		},
+		ReportVmClusterMetrics: {
+			Cron:    "*/1 * * * *",
+			Handler: cmVmTask.ReportVmClusterMetric,
+		},
 		ClearDeprecatedRedisKey: {
 			Cron:    "0 0 */14 * *",
 			Handler: metadataTask.ClearDeprecatedRedisKey,
@@ -15,9 +15,11 @@ import (
 
 	"github.com/go-redis/redis/v8"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	rdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/processor"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service/scheduler"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/service/scheduler/periodic"
 	commonUtils "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/common"
@@ -67,10 +69,21 @@ func NewWorkerService(ctx context.Context, queues []string) (*WorkerService, err
 		}
 	}
 
+	limits := make(map[string]processor.TaskLimit)
+	for kind, limit := range config.WorkerTaskLimits {
+		limits[kind] = processor.TaskLimit{
+			Concurrency:       limit.Concurrency,
+			Rate:              limit.Rate,
+			Burst:             limit.Burst,
+			GlobalConcurrency: limit.GlobalConcurrency,
+		}
+	}
+
 	w, err := worker.NewWorker(worker.WorkerConfig{
 		Concurrency: config.WorkerConcurrency,
 		BaseContext: func() context.Context { return ctx },
 		Queues:      qs,
+		TaskLimits:  limits,
 	})
 
 	if err != nil {
@@ -105,6 +118,11 @@ type WorkerInfo struct {
 }
 
 func (w *WorkerHealthMaintainer) Start() {
+	// 单机部署时没有 redis，worker 信息仅用于常驻任务分配，无需上报
+	if w.redisClient == nil {
+		logger.Infof("Worker starts with the Id: %s, heartbeat reporting is disabled by broker: %s", w.id, config.BrokerType)
+		return
+	}
 	ticker := time.NewTicker(w.config.checkInternal)
 
 	logger.Infof("Worker starts with the Id: %s to enable periodic heartbeat reporting.", w.id)
@@ -133,13 +151,16 @@ func NewWorkerHealthMaintainer(ctx context.Context, queues []string) (*WorkerHea
 		infoTtl:       config.WorkerHealthCheckInfoDuration,
 	}
 
-	broker := rdb.GetRDB()
+	var redisClient redis.UniversalClient
+	if config.BrokerType != broker.TypeBbolt {
+		redisClient = rdb.GetRDB().Client()
+	}
 
 	return &WorkerHealthMaintainer{
 		id:          commonUtils.GenerateProcessorId(),
 		ctx:         ctx,
 		config:      options,
-		redisClient: broker.Client(),
+		redisClient: redisClient,
 		queues:      queues,
 	}, nil
 }
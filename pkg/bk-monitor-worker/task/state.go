@@ -9,6 +9,8 @@
 
 package task
 
+import "fmt"
+
 type TaskState int
 
 // inspire by asynq and machinery
@@ -39,3 +41,22 @@ func (s TaskState) String() string {
 	}
 	panic("unknown task state")
 }
+
+// ParseTaskState parse the task state from string
+func ParseTaskState(s string) (TaskState, error) {
+	switch s {
+	case "active":
+		return TaskStateActive, nil
+	case "pending":
+		return TaskStatePending, nil
+	case "scheduled":
+		return TaskStateScheduled, nil
+	case "retry":
+		return TaskStateRetry, nil
+	case "archived":
+		return TaskStateArchived, nil
+	case "completed":
+		return TaskStateCompleted, nil
+	}
+	return 0, fmt.Errorf("unknown task state: %s", s)
+}
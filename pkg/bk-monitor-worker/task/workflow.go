@@ -0,0 +1,163 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WorkflowState 任务编排以及编排内任务的状态
+type WorkflowState int
+
+const (
+	// WorkflowStatePending 所在阶段尚未开始
+	WorkflowStatePending WorkflowState = iota + 1
+	// WorkflowStateRunning 执行中
+	WorkflowStateRunning
+	// WorkflowStateSucceeded 执行成功
+	WorkflowStateSucceeded
+	// WorkflowStateFailed 执行失败
+	WorkflowStateFailed
+)
+
+// String return the workflow string state
+func (s WorkflowState) String() string {
+	switch s {
+	case WorkflowStatePending:
+		return "pending"
+	case WorkflowStateRunning:
+		return "running"
+	case WorkflowStateSucceeded:
+		return "succeeded"
+	case WorkflowStateFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Workflow 任务编排，由若干阶段组成
+// 阶段之间串行执行，阶段内的任务并行执行；上一阶段的任务全部成功后才会下发下一阶段，
+// 下一阶段的任务可以通过 GetParentResults 获取上一阶段各任务通过 WriteResult 写入的结果
+type Workflow struct {
+	Steps [][]*Task
+}
+
+// NewChain 串行执行，前一个任务成功后才执行后一个任务
+func NewChain(tasks ...*Task) *Workflow {
+	w := &Workflow{}
+	for _, t := range tasks {
+		w.Then(t)
+	}
+	return w
+}
+
+// NewGroup 并行执行一组任务
+func NewGroup(tasks ...*Task) *Workflow {
+	return (&Workflow{}).Then(tasks...)
+}
+
+// NewChord 并行执行一组任务，全部成功后执行回调任务汇总结果
+func NewChord(header []*Task, callback *Task) *Workflow {
+	return NewGroup(header...).Then(callback)
+}
+
+// Then 追加一个阶段，阶段内的任务并行执行
+func (w *Workflow) Then(tasks ...*Task) *Workflow {
+	if len(tasks) > 0 {
+		w.Steps = append(w.Steps, tasks)
+	}
+	return w
+}
+
+// WorkflowMessage 任务编排在 broker 中的存储结构
+type WorkflowMessage struct {
+	ID    string
+	Steps [][]*SerializerTask
+
+	// 以下为运行时状态
+	State      WorkflowState
+	Step       int
+	ErrorMsg   string
+	FailedTask string
+	CreatedAt  int64
+	UpdatedAt  int64
+	// TaskStates/Results 与 Steps 一一对应
+	TaskStates [][]WorkflowState
+	Results    [][][]byte
+}
+
+const workflowTaskIDPrefix = "wf:"
+
+// WorkflowTaskID 编排内任务的 ID，通过 ID 即可定位任务在编排中的位置
+func WorkflowTaskID(workflowID string, step, index int) string {
+	return fmt.Sprintf("%s%s:%d:%d", workflowTaskIDPrefix, workflowID, step, index)
+}
+
+// ParseWorkflowTaskID 解析编排内任务的 ID，非编排任务返回 false
+func ParseWorkflowTaskID(id string) (workflowID string, step, index int, ok bool) {
+	if !strings.HasPrefix(id, workflowTaskIDPrefix) {
+		return "", 0, 0, false
+	}
+	parts := strings.Split(strings.TrimPrefix(id, workflowTaskIDPrefix), ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", 0, 0, false
+	}
+	step, err := strconv.Atoi(parts[1])
+	if err != nil || step < 0 {
+		return "", 0, 0, false
+	}
+	index, err = strconv.Atoi(parts[2])
+	if err != nil || index < 0 {
+		return "", 0, 0, false
+	}
+	return parts[0], step, index, true
+}
+
+// ResultWriter 任务结果的写入接口，由 broker 实现
+type ResultWriter interface {
+	WriteResult(qname, id string, data []byte) (n int, err error)
+}
+
+const (
+	resultWriterCtxKey  = "resultWriterKey"
+	parentResultsCtxKey = "parentResultsKey"
+)
+
+// AddResultWriter2Context add result writer to context
+func AddResultWriter2Context(ctx context.Context, w ResultWriter) context.Context {
+	return context.WithValue(ctx, resultWriterCtxKey, w)
+}
+
+// WriteResult 写入当前任务的执行结果，编排中的下游任务可以通过 GetParentResults 获取
+func WriteResult(ctx context.Context, data []byte) (int, error) {
+	metadata, ok := ctx.Value(taskMetadataCtxKey).(TaskMetadata)
+	if !ok {
+		return 0, fmt.Errorf("task metadata not found in context")
+	}
+	w, ok := ctx.Value(resultWriterCtxKey).(ResultWriter)
+	if !ok {
+		return 0, fmt.Errorf("result writer not found in context")
+	}
+	return w.WriteResult(metadata.qname, metadata.id, data)
+}
+
+// AddParentResults2Context add results of the previous workflow step to context
+func AddParentResults2Context(ctx context.Context, results [][]byte) context.Context {
+	return context.WithValue(ctx, parentResultsCtxKey, results)
+}
+
+// GetParentResults 获取编排中上一阶段各任务的执行结果，顺序与任务定义顺序一致，未写入结果的任务为 nil
+func GetParentResults(ctx context.Context) [][]byte {
+	results, _ := ctx.Value(parentResultsCtxKey).([][]byte)
+	return results
+}
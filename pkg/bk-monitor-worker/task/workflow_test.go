@@ -0,0 +1,62 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowBuilder(t *testing.T) {
+	a, b, c := NewTask("a", nil), NewTask("b", nil), NewTask("c", nil)
+
+	assert.Equal(t, [][]*Task{{a}, {b}, {c}}, NewChain(a, b, c).Steps)
+	assert.Equal(t, [][]*Task{{a, b}}, NewGroup(a, b).Steps)
+	assert.Equal(t, [][]*Task{{a, b}, {c}}, NewChord([]*Task{a, b}, c).Steps)
+	assert.Equal(t, [][]*Task{{a}, {b, c}}, NewChain(a).Then().Then(b, c).Steps)
+}
+
+func TestParseWorkflowTaskID(t *testing.T) {
+	testCases := map[string]struct {
+		id         string
+		workflowID string
+		step       int
+		index      int
+		ok         bool
+	}{
+		"编排任务": {
+			id:         WorkflowTaskID("9b2f", 1, 3),
+			workflowID: "9b2f",
+			step:       1,
+			index:      3,
+			ok:         true,
+		},
+		"普通任务": {
+			id: "9b2f-0c1d",
+		},
+		"缺少序号": {
+			id: "wf:9b2f:1",
+		},
+		"序号非法": {
+			id: "wf:9b2f:1:-1",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			workflowID, step, index, ok := ParseWorkflowTaskID(c.id)
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.workflowID, workflowID)
+			assert.Equal(t, c.step, step)
+			assert.Equal(t, c.index, index)
+		})
+	}
+}
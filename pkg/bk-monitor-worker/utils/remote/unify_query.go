@@ -0,0 +1,131 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/jsonx"
+)
+
+const (
+	unifyQueryPromQLPath = "/query/ts/promql"
+	spaceUidHeader       = "X-Bk-Scope-Space-Uid"
+)
+
+// PromSample 瞬时查询得到的单条序列的值
+type PromSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// UnifyQueryClient unify-query PromQL 查询客户端，由 unify-query 按空间路由到对应的结果表
+type UnifyQueryClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewUnifyQueryClient(url string, timeout time.Duration) *UnifyQueryClient {
+	return &UnifyQueryClient{
+		url:    strings.TrimRight(url, "/") + unifyQueryPromQLPath,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type unifyQueryPromQLRequest struct {
+	PromQL  string `json:"promql"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Step    string `json:"step"`
+	Instant bool   `json:"instant"`
+}
+
+type unifyQueryPromQLResponse struct {
+	Series []struct {
+		GroupKeys   []string        `json:"group_keys"`
+		GroupValues []string        `json:"group_values"`
+		Values      [][]interface{} `json:"values"`
+	} `json:"series"`
+	Error string `json:"error"`
+}
+
+// QueryInstant 执行瞬时查询，每条序列只取最后一个点
+func (c *UnifyQueryClient) QueryInstant(ctx context.Context, spaceUid, promql string, ts time.Time, step time.Duration) ([]PromSample, error) {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	data, err := jsonx.Marshal(unifyQueryPromQLRequest{
+		PromQL:  promql,
+		Start:   unix,
+		End:     unix,
+		Step:    step.String(),
+		Instant: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(spaceUidHeader, spaceUid)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "[UnifyQuery] request failed")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		return nil, errors.Errorf("[UnifyQuery] returned HTTP status %s: %s", resp.Status, body)
+	}
+
+	var result unifyQueryPromQLResponse
+	if err := jsonx.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "[UnifyQuery] unmarshal response failed")
+	}
+	if result.Error != "" {
+		return nil, errors.Errorf("[UnifyQuery] query failed: %s", result.Error)
+	}
+
+	samples := make([]PromSample, 0, len(result.Series))
+	for _, series := range result.Series {
+		if len(series.Values) == 0 || len(series.GroupKeys) != len(series.GroupValues) {
+			continue
+		}
+		point := series.Values[len(series.Values)-1]
+		if len(point) != 2 {
+			return nil, errors.Errorf("[UnifyQuery] invalid point: %v", point)
+		}
+		v, ok := point[1].(float64)
+		if !ok {
+			return nil, errors.Errorf("[UnifyQuery] invalid point value: %v", point[1])
+		}
+		labels := make(map[string]string, len(series.GroupKeys))
+		for i, k := range series.GroupKeys {
+			labels[k] = series.GroupValues[i]
+		}
+		samples = append(samples, PromSample{Labels: labels, Value: v})
+	}
+	return samples, nil
+}
@@ -0,0 +1,34 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifyQueryClient_QueryInstant(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, unifyQueryPromQLPath, r.URL.Path)
+		assert.Equal(t, "bkcc__2", r.Header.Get(spaceUidHeader))
+		_, _ = w.Write([]byte(`{"series":[{"group_keys":["pod"],"group_values":["p1"],"values":[[1700000000000,1],[1700000060000,3]]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewUnifyQueryClient(server.URL+"/", time.Second)
+	samples, err := client.QueryInstant(context.Background(), "bkcc__2", "sum(up) by (pod)", time.Unix(1700000060, 0), time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []PromSample{{Labels: map[string]string{"pod": "p1"}, Value: 3}}, samples)
+}
@@ -15,7 +15,6 @@ import (
 	"time"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
-	rdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
 	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
@@ -38,7 +37,7 @@ func GetClient() (*Client, error) {
 		return clientInstance, nil
 	}
 
-	brokerInstance := rdb.GetRDB()
+	brokerInstance := GetBroker()
 	clientInstance = &Client{broker: brokerInstance}
 	return clientInstance, nil
 }
@@ -113,7 +112,7 @@ func (c *Client) EnqueueWithContext(ctx context.Context, task *t.Task, opts ...t
 		return nil, fmt.Errorf("task already exists")
 	case errors.Is(err, errors.ErrTaskIdConflict):
 		logger.Warnf("task: %s conflict with exist task, not schedule a task again, error: %+v", task.Kind, err)
-		return nil, fmt.Errorf("task conflict with exist task")
+		return nil, fmt.Errorf("task conflict with exist task, %w", errors.ErrTaskIdConflict)
 	case err != nil:
 		logger.Errorf("task: %s is error, not schedule a task again, error: %+v", task.Kind, err)
 		return nil, err
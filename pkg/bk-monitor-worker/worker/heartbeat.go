@@ -0,0 +1,96 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package worker
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/processor"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// heartbeater 周期性上报 worker 及正在执行的任务，状态过期时间为两个上报周期，进程异常退出后自动清理
+type heartbeater struct {
+	broker    broker.Broker
+	processor *processor.Processor
+	info      *common.ServerInfo
+	interval  time.Duration
+	done      chan struct{}
+}
+
+func newHeartbeater(b broker.Broker, p *processor.Processor, concurrency int, queues map[string]int, strictPriority bool, interval time.Duration) *heartbeater {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown-host"
+	}
+	return &heartbeater{
+		broker:    b,
+		processor: p,
+		info: &common.ServerInfo{
+			Host:           host,
+			PID:            os.Getpid(),
+			ServerID:       uuid.NewString(),
+			Concurrency:    concurrency,
+			Queues:         queues,
+			StrictPriority: strictPriority,
+			Status:         "active",
+			Started:        time.Now(),
+		},
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 启动上报
+func (h *heartbeater) Start(wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		h.beat()
+		for {
+			select {
+			case <-h.done:
+				if err := h.broker.ClearServerState(h.info.Host, h.info.PID, h.info.ServerID); err != nil {
+					logger.Errorf("Could not clear server state: %v", err)
+				}
+				return
+			case <-ticker.C:
+				h.beat()
+			}
+		}
+	}()
+}
+
+// Shutdown 停止上报并清理状态
+func (h *heartbeater) Shutdown() {
+	close(h.done)
+}
+
+func (h *heartbeater) beat() {
+	workers := h.processor.ActiveWorkers()
+	for _, w := range workers {
+		w.Host = h.info.Host
+		w.PID = h.info.PID
+		w.ServerID = h.info.ServerID
+	}
+	h.info.ActiveWorkerCount = len(workers)
+	if err := h.broker.WriteServerState(h.info, workers, h.interval*2); err != nil {
+		logger.Warnf("Could not write server state: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package worker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+)
+
+// Inspector 队列巡检，查看队列及任务状态，并对任务进行重新执行、归档及删除操作
+type Inspector struct {
+	broker broker.InspectBroker
+}
+
+// ServerState worker 进程状态及其正在执行的任务
+type ServerState struct {
+	*common.ServerInfo
+	Workers []*common.WorkerInfo
+}
+
+// NewInspector new a inspector, the broker must support inspection
+func NewInspector(b broker.Broker) (*Inspector, error) {
+	ib, ok := b.(broker.InspectBroker)
+	if !ok {
+		return nil, fmt.Errorf("broker does not support inspection")
+	}
+	return &Inspector{broker: ib}, nil
+}
+
+// Queues 获取所有队列的任务统计，按队列名排序
+func (i *Inspector) Queues() ([]*common.QueueStats, error) {
+	qnames, err := i.broker.AllQueues()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(qnames)
+	res := make([]*common.QueueStats, 0, len(qnames))
+	for _, qname := range qnames {
+		stats, err := i.broker.CurrentStats(qname)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, stats)
+	}
+	return res, nil
+}
+
+// ListTasks 分页获取队列中某个状态的任务
+func (i *Inspector) ListTasks(qname, state string, offset, limit int) ([]*t.TaskInfo, error) {
+	s, err := t.ParseTaskState(state)
+	if err != nil {
+		return nil, err
+	}
+	return i.broker.ListTasks(qname, s, offset, limit)
+}
+
+// RunTask 立即执行 scheduled、retry 或 archived 状态的任务
+func (i *Inspector) RunTask(qname, id string) error {
+	return i.broker.RunTask(qname, id)
+}
+
+// ArchiveTask 归档 pending、scheduled 或 retry 状态的任务
+func (i *Inspector) ArchiveTask(qname, id string) error {
+	return i.broker.ArchiveTask(qname, id)
+}
+
+// DeleteTask 删除非 active 状态的任务
+func (i *Inspector) DeleteTask(qname, id string) error {
+	return i.broker.DeleteTask(qname, id)
+}
+
+// RunAllTasks 立即执行某个状态下的所有任务，返回处理的任务数量
+func (i *Inspector) RunAllTasks(qname, state string) (int, error) {
+	s, err := t.ParseTaskState(state)
+	if err != nil {
+		return 0, err
+	}
+	return i.broker.RunAllTasks(qname, s)
+}
+
+// ArchiveAllTasks 归档某个状态下的所有任务，返回处理的任务数量
+func (i *Inspector) ArchiveAllTasks(qname, state string) (int, error) {
+	s, err := t.ParseTaskState(state)
+	if err != nil {
+		return 0, err
+	}
+	return i.broker.ArchiveAllTasks(qname, s)
+}
+
+// DeleteAllTasks 删除某个状态下的所有任务，返回处理的任务数量
+func (i *Inspector) DeleteAllTasks(qname, state string) (int, error) {
+	s, err := t.ParseTaskState(state)
+	if err != nil {
+		return 0, err
+	}
+	return i.broker.DeleteAllTasks(qname, s)
+}
+
+// PauseQueue 暂停队列，暂停后队列中的任务不会被消费
+func (i *Inspector) PauseQueue(qname string) error {
+	return i.broker.Pause(qname)
+}
+
+// ResumeQueue 恢复暂停的队列
+func (i *Inspector) ResumeQueue(qname string) error {
+	return i.broker.Unpause(qname)
+}
+
+// Servers 获取存活的 worker 进程及其正在执行的任务
+func (i *Inspector) Servers() ([]*ServerState, error) {
+	servers, workers, err := i.broker.ListServers()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*ServerState, 0, len(servers))
+	index := make(map[string]*ServerState)
+	for _, s := range servers {
+		state := &ServerState{ServerInfo: s}
+		index[s.ServerID] = state
+		res = append(res, state)
+	}
+	for _, w := range workers {
+		if state, ok := index[w.ServerID]; ok {
+			state.Workers = append(state.Workers, w)
+		}
+	}
+	sort.Slice(res, func(a, b int) bool {
+		if res[a].Host != res[b].Host {
+			return res[a].Host < res[b].Host
+		}
+		return res[a].PID < res[b].PID
+	})
+	return res, nil
+}
@@ -21,8 +21,9 @@ import (
 	"github.com/google/uuid"
 	cron "github.com/robfig/cron/v3"
 
-	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
 	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
 	commonUtils "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/common"
@@ -35,7 +36,7 @@ type Scheduler struct {
 	id string
 
 	client     *Client
-	rdb        *redis.RDB
+	broker     broker.SchedulerBroker
 	cron       *cron.Cron
 	location   *time.Location
 	done       chan struct{}
@@ -66,7 +67,11 @@ func NewScheduler(ctx context.Context, opts SchedulerOpts) (*Scheduler, error) {
 	if loc == nil {
 		loc = time.UTC
 	}
-	broker := redis.GetRDB()
+	// 调度器需要记录调度项及入队事件，broker 需要支持存储调度状态
+	schedulerBroker, ok := GetBroker().(broker.SchedulerBroker)
+	if !ok {
+		return nil, fmt.Errorf("scheduler is not supported by broker: %s", config.BrokerType)
+	}
 	client, err := GetClient()
 	if err != nil {
 		return nil, err
@@ -75,7 +80,7 @@ func NewScheduler(ctx context.Context, opts SchedulerOpts) (*Scheduler, error) {
 	return &Scheduler{
 		id:         commonUtils.GenerateProcessorId(),
 		client:     client,
-		rdb:        broker,
+		broker:     schedulerBroker,
 		cron:       cron.New(cron.WithLocation(loc)),
 		location:   loc,
 		done:       make(chan struct{}),
@@ -93,7 +98,7 @@ type enqueueJob struct {
 	opts       []t.Option
 	location   *time.Location
 	client     *Client
-	rdb        *redis.RDB
+	broker     broker.SchedulerBroker
 	errHandler func(task *t.Task, opts []t.Option, err error)
 }
 
@@ -110,7 +115,7 @@ func (j *enqueueJob) Run() {
 		TaskID:     info.ID,
 		EnqueuedAt: time.Now().In(j.location),
 	}
-	err = j.rdb.RecordSchedulerEnqueueEvent(j.id.String(), event)
+	err = j.broker.RecordSchedulerEnqueueEvent(j.id.String(), event)
 	if err != nil {
 		logger.Warnf("scheduler could not record enqueue event of enqueued task %s: %v", info.ID, err)
 	}
@@ -126,7 +131,7 @@ func (s *Scheduler) Register(cronspec string, task *t.Task, opts ...t.Option) (e
 		opts:       opts,
 		location:   s.location,
 		client:     s.client,
-		rdb:        s.rdb,
+		broker:     s.broker,
 		errHandler: s.errHandler,
 	}
 	cronID, err := s.cron.AddJob(cronspec, job)
@@ -179,6 +184,7 @@ func (s *Scheduler) Run() error {
 		case <-s.ctx.Done():
 			ticker.Stop()
 			s.Shutdown()
+			return nil
 		}
 	}
 }
@@ -190,12 +196,13 @@ func (s *Scheduler) Shutdown() {
 	<-ctx.Done()
 
 	s.clearHistory()
+	// bbolt 下调度器运行在 worker 进程中，与 worker 共用 broker，由 worker 负责关闭
+	if config.BrokerType == broker.TypeBbolt {
+		return
+	}
 	if err := s.client.Close(); err != nil {
 		logger.Warnf("Failed to close client, error: %s", err)
 	}
-	if err := s.rdb.Close(); err != nil {
-		logger.Warnf("Failed to close RDB, error: %s", err)
-	}
 }
 
 func (s *Scheduler) runHeartbeater() {
@@ -205,7 +212,7 @@ func (s *Scheduler) runHeartbeater() {
 		select {
 		case <-s.done:
 			logger.Debugf("Scheduler heatbeater shutting down")
-			s.rdb.ClearSchedulerEntries(s.id)
+			s.broker.ClearSchedulerEntries(s.id)
 			ticker.Stop()
 			return
 		case <-ticker.C:
@@ -214,7 +221,7 @@ func (s *Scheduler) runHeartbeater() {
 	}
 }
 
-// beat writes a snapshot of entries to redis.
+// beat writes a snapshot of entries to the broker.
 func (s *Scheduler) beat() {
 	var entries []*common.SchedulerEntry
 	for _, entry := range s.cron.Entries() {
@@ -231,7 +238,7 @@ func (s *Scheduler) beat() {
 		entries = append(entries, e)
 	}
 	logger.Debugf("Writing entries %v", entries)
-	if err := s.rdb.WriteSchedulerEntries(s.id, entries, 5*time.Second); err != nil {
+	if err := s.broker.WriteSchedulerEntries(s.id, entries, 5*time.Second); err != nil {
 		logger.Warnf("Scheduler could not write heartbeat data: %v", err)
 	}
 }
@@ -247,7 +254,7 @@ func stringifyOptions(opts []t.Option) []string {
 func (s *Scheduler) clearHistory() {
 	for _, entry := range s.cron.Entries() {
 		job := entry.Job.(*enqueueJob)
-		if err := s.rdb.ClearSchedulerHistory(job.id.String()); err != nil {
+		if err := s.broker.ClearSchedulerHistory(job.id.String()); err != nil {
 			logger.Warnf("Could not clear scheduler history for entry %q: %v", job.id.String(), err)
 		}
 	}
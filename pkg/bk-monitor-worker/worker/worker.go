@@ -23,7 +23,6 @@ import (
 	"time"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
-	rdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/redis"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/common"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/processor"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
@@ -38,8 +37,10 @@ type Worker struct {
 	// waitgroup
 	wg sync.WaitGroup
 	// goroutines
-	forwarder *processor.Forwarder
-	processor *processor.Processor
+	forwarder   *processor.Forwarder
+	processor   *processor.Processor
+	heartbeater *heartbeater
+	recoverer   *workflowRecoverer
 }
 
 // WorkerConfig config info
@@ -55,6 +56,8 @@ type WorkerConfig struct {
 	HealthCheckFunc          func(error)
 	HealthCheckInterval      time.Duration
 	DelayedTaskCheckInterval time.Duration
+	// TaskLimits 按任务类型限制任务的执行
+	TaskLimits map[string]processor.TaskLimit
 }
 
 // DefaultRetryDelayFunc default retry time
@@ -108,13 +111,13 @@ func NewWorker(cfg WorkerConfig) (*Worker, error) {
 	if shutdownTimeout == 0 {
 		shutdownTimeout = common.DefaultShutdownTimeout
 	}
-	// TODO: health check
+	// 运行状态上报周期 TODO: health check
 	healthcheckInterval := cfg.HealthCheckInterval
 	if healthcheckInterval == 0 {
 		healthcheckInterval = common.DefaultHealthCheckInterval
 	}
 
-	rdb := rdb.GetRDB()
+	b := GetBroker()
 
 	delayedTaskCheckInterval := cfg.DelayedTaskCheckInterval
 	if delayedTaskCheckInterval == 0 {
@@ -122,12 +125,16 @@ func NewWorker(cfg WorkerConfig) (*Worker, error) {
 	}
 
 	forwarder := processor.NewForwarder(processor.ForwarderParams{
-		Broker:   rdb,
+		Broker:   b,
 		Queues:   qnames,
 		Interval: delayedTaskCheckInterval,
 	})
+	workflow, err := NewWorkflowEngine(b)
+	if err != nil {
+		return nil, err
+	}
 	processor := processor.NewProcessor(processor.ProcessorParams{
-		Broker:          rdb,
+		Broker:          b,
 		RetryDelayFunc:  delayFunc,
 		BaseCtxFn:       baseCtxFn,
 		IsFailureFunc:   isFailureFunc,
@@ -135,12 +142,16 @@ func NewWorker(cfg WorkerConfig) (*Worker, error) {
 		Queues:          queues,
 		StrictPriority:  cfg.StrictPriority,
 		ErrHandler:      cfg.ErrorHandler,
+		Workflow:        workflow,
+		TaskLimits:      cfg.TaskLimits,
 		ShutdownTimeout: shutdownTimeout,
 	})
 	return &Worker{
-		broker:    rdb,
-		forwarder: forwarder,
-		processor: processor,
+		broker:      b,
+		forwarder:   forwarder,
+		processor:   processor,
+		heartbeater: newHeartbeater(b, processor, n, queues, cfg.StrictPriority, healthcheckInterval),
+		recoverer:   newWorkflowRecoverer(workflow, common.DefaultWorkflowRecoverInterval),
 	}, nil
 }
 
@@ -170,6 +181,8 @@ func (w *Worker) Run(handler processor.Handler) error {
 	logger.Info("Starting processing")
 	w.forwarder.Start(&w.wg)
 	w.processor.Start(&w.wg)
+	w.heartbeater.Start(&w.wg)
+	w.recoverer.Start(&w.wg)
 
 	return nil
 }
@@ -179,6 +192,8 @@ func (w *Worker) Shutdown() {
 	logger.Info("Starting graceful shutdown")
 	w.forwarder.Shutdown()
 	w.processor.Shutdown()
+	w.heartbeater.Shutdown()
+	w.recoverer.Shutdown()
 	w.wg.Wait()
 
 	w.broker.Close()
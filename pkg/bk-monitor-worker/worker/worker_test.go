@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/config"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+)
+
+// TestWorkerWithBboltBroker 使用 bbolt broker 在单机上完整执行任务，无需 redis
+func TestWorkerWithBboltBroker(test *testing.T) {
+	config.BrokerType = broker.TypeBbolt
+	config.BrokerBboltPath = filepath.Join(test.TempDir(), "broker.db")
+
+	w, err := NewWorker(WorkerConfig{Concurrency: 1, Queues: map[string]int{"default": 1}})
+	assert.NoError(test, err)
+
+	payloads := make(chan string, 16)
+	mux := NewServeMux()
+	mux.HandleFunc("async:test", func(ctx context.Context, task *t.Task) error {
+		payloads <- string(task.Payload)
+		return nil
+	})
+	assert.NoError(test, w.Run(mux))
+	defer w.Shutdown()
+
+	client, err := GetClient()
+	assert.NoError(test, err)
+	_, err = client.Enqueue(t.NewTask("async:test", []byte("now")))
+	assert.NoError(test, err)
+	_, err = client.Enqueue(t.NewTask("async:test", []byte("later")), t.ProcessInterval(time.Second))
+	assert.NoError(test, err)
+
+	for _, expected := range []string{"now", "later"} {
+		select {
+		case payload := <-payloads:
+			assert.Equal(test, expected, payload)
+		case <-time.After(10 * time.Second):
+			test.Fatalf("task %s is not processed", expected)
+		}
+	}
+
+	// 暂停期间任务不会被消费
+	inspector, err := NewInspector(GetBroker())
+	assert.NoError(test, err)
+	assert.NoError(test, inspector.PauseQueue("default"))
+	_, err = client.Enqueue(t.NewTask("async:test", []byte("paused")))
+	assert.NoError(test, err)
+	select {
+	case payload := <-payloads:
+		test.Fatalf("task %s is processed in paused queue", payload)
+	case <-time.After(2 * time.Second):
+	}
+	stats, err := inspector.Queues()
+	assert.NoError(test, err)
+	assert.Len(test, stats, 1)
+	assert.True(test, stats[0].Paused)
+	assert.Equal(test, 1, stats[0].Pending)
+
+	assert.NoError(test, inspector.ResumeQueue("default"))
+	select {
+	case payload := <-payloads:
+		assert.Equal(test, "paused", payload)
+	case <-time.After(10 * time.Second):
+		test.Fatal("task is not processed after resume")
+	}
+
+	// 周期任务调度器与 worker 运行在同一进程中，共用 bbolt broker
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler, err := NewScheduler(ctx, SchedulerOpts{})
+	assert.NoError(test, err)
+	_, err = scheduler.Register("@every 1s", t.NewTask("async:test", []byte("periodic")))
+	assert.NoError(test, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(test, scheduler.Run())
+	}()
+	select {
+	case payload := <-payloads:
+		assert.Equal(test, "periodic", payload)
+	case <-time.After(10 * time.Second):
+		test.Fatal("periodic task is not processed")
+	}
+	cancel()
+	<-done
+}
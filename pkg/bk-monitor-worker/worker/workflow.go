@@ -0,0 +1,312 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/metrics"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/errors"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/utils/stringx"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// WorkflowEngine 推进任务编排
+// 任务成功后记录其结果，阶段内任务全部成功后下发下一阶段；任务重试耗尽后终止编排，后续阶段不再下发
+// 进入新阶段时 broker 同时将编排记录为推进中，阶段内任务全部下发后才清除，
+// 进程在两者之间退出或下发失败时由 recoverer 按确定的任务 ID 重新下发，已下发的任务不会重复
+type WorkflowEngine struct {
+	broker broker.WorkflowBroker
+	client *Client
+}
+
+// NewWorkflowEngine new a workflow engine, the broker must support workflow
+func NewWorkflowEngine(b broker.Broker) (*WorkflowEngine, error) {
+	wb, ok := b.(broker.WorkflowBroker)
+	if !ok {
+		return nil, fmt.Errorf("broker does not support workflow")
+	}
+	return &WorkflowEngine{broker: wb, client: &Client{broker: b}}, nil
+}
+
+// PrepareContext 将上一阶段的执行结果注入到任务上下文中
+func (e *WorkflowEngine) PrepareContext(ctx context.Context, msg *t.TaskMessage) context.Context {
+	id, step, _, ok := t.ParseWorkflowTaskID(msg.ID)
+	if !ok || step == 0 {
+		return ctx
+	}
+	wf, err := e.broker.GetWorkflow(ctx, id)
+	if err != nil {
+		logger.Errorf("get workflow: %s for task id=%s error, %v", id, msg.ID, err)
+		return ctx
+	}
+	if step > len(wf.Results) {
+		return ctx
+	}
+	return t.AddParentResults2Context(ctx, wf.Results[step-1])
+}
+
+// HandleSucceeded 记录任务结果，当前阶段完成后下发下一阶段
+func (e *WorkflowEngine) HandleSucceeded(ctx context.Context, msg *t.TaskMessage) error {
+	id, step, index, ok := t.ParseWorkflowTaskID(msg.ID)
+	if !ok {
+		return nil
+	}
+	wf, err := e.broker.GetWorkflow(ctx, id)
+	if errors.CanonicalCode(err) == errors.NotFound {
+		logger.Warnf("workflow: %s of task id=%s not found", id, msg.ID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get workflow: %s error, %w", id, err)
+	}
+	if step >= len(wf.Steps) {
+		logger.Errorf("task id=%s out of workflow: %s steps", msg.ID, id)
+		return nil
+	}
+	result, err := e.broker.ReadResult(msg.Queue, msg.ID)
+	if err != nil {
+		logger.Warnf("read result of task id=%s error, %v", msg.ID, err)
+	}
+	nextStepSize := 0
+	if step+1 < len(wf.Steps) {
+		nextStepSize = len(wf.Steps[step+1])
+	}
+	stepDone, err := e.broker.CompleteWorkflowTask(ctx, id, step, index, result, nextStepSize)
+	if err != nil {
+		return fmt.Errorf("complete task of workflow: %s error, %w", id, err)
+	}
+	if !stepDone {
+		return nil
+	}
+	if nextStepSize == 0 {
+		logger.Infof("workflow: %s succeeded", id)
+		return nil
+	}
+	// 下一阶段已记录为推进中，下发失败时由 recoverer 重试
+	if err = e.advance(ctx, wf, step+1); err != nil {
+		logger.Warnf("enqueue step: %d of workflow: %s error, it will be retried later, %v", step+1, id, err)
+	}
+	return nil
+}
+
+// HandleFailed 任务最终失败时终止编排
+func (e *WorkflowEngine) HandleFailed(ctx context.Context, msg *t.TaskMessage, err error) {
+	id, step, index, ok := t.ParseWorkflowTaskID(msg.ID)
+	if !ok {
+		return
+	}
+	failed, ferr := e.broker.FailWorkflow(ctx, id, step, index, msg.ID, err.Error())
+	if ferr != nil {
+		logger.Errorf("fail workflow: %s by task id=%s error, %v", id, msg.ID, ferr)
+		return
+	}
+	if failed {
+		logger.Warnf("workflow: %s failed by task id=%s, error: %v", id, msg.ID, err)
+	}
+}
+
+// advance 下发某个阶段的全部任务，全部下发后清除编排的推进中记录
+func (e *WorkflowEngine) advance(ctx context.Context, wf *t.WorkflowMessage, step int) error {
+	if _, err := e.enqueueStep(ctx, wf, step); err != nil {
+		return err
+	}
+	return e.broker.MarkWorkflowStepEnqueued(ctx, wf.ID, step)
+}
+
+// enqueueStep 下发某个阶段的全部任务，可以重复调用
+// 任务 ID 由编排 ID 及任务位置确定，已存在或已结束的任务跳过；任意任务下发失败时删除本次已下发的任务，
+// 保证阶段内的任务要么全部下发，要么全部未下发；失败时返回下发失败的任务位置
+func (e *WorkflowEngine) enqueueStep(ctx context.Context, wf *t.WorkflowMessage, step int) (int, error) {
+	var enqueued []*t.TaskInfo
+	for index, st := range wf.Steps[step] {
+		if step < len(wf.TaskStates) && index < len(wf.TaskStates[step]) {
+			if state := wf.TaskStates[step][index]; state == t.WorkflowStateSucceeded || state == t.WorkflowStateFailed {
+				continue
+			}
+		}
+		taskID := t.WorkflowTaskID(wf.ID, step, index)
+		task := t.NewTask(st.Kind, st.Payload, workflowTaskOptions(st.Options, taskID)...)
+		metrics.EnqueueTaskTotal(task.Kind)
+		info, err := e.client.EnqueueWithContext(ctx, task)
+		if errors.Is(err, errors.ErrTaskIdConflict) {
+			continue
+		}
+		if err != nil {
+			e.deleteTasks(enqueued)
+			return index, fmt.Errorf("enqueue task id=%s error, %w", taskID, err)
+		}
+		enqueued = append(enqueued, info)
+	}
+	return 0, nil
+}
+
+// deleteTasks 删除已下发的任务，已开始执行的任务无法删除，其结果在阶段重新下发后照常记录
+func (e *WorkflowEngine) deleteTasks(tasks []*t.TaskInfo) {
+	ib, ok := e.client.broker.(broker.InspectBroker)
+	if !ok {
+		return
+	}
+	for _, info := range tasks {
+		if err := ib.DeleteTask(info.Queue, info.ID); err != nil {
+			logger.Warnf("delete task id=%s of unfinished workflow step error, %v", info.ID, err)
+		}
+	}
+}
+
+// Recover 重新下发推进中超过 cutoff 的编排的当前阶段
+func (e *WorkflowEngine) Recover(ctx context.Context, cutoff time.Time) {
+	ids, err := e.broker.ListAdvancingWorkflows(ctx, cutoff)
+	if err != nil {
+		logger.Errorf("list advancing workflows error, %v", err)
+		return
+	}
+	for _, id := range ids {
+		wf, err := e.broker.GetWorkflow(ctx, id)
+		if err != nil && errors.CanonicalCode(err) != errors.NotFound {
+			logger.Errorf("get workflow: %s error, %v", id, err)
+			continue
+		}
+		// 已过期或已结束的编排不再下发，仅清除推进中记录
+		if wf == nil || wf.State != t.WorkflowStateRunning {
+			if err = e.broker.MarkWorkflowStepEnqueued(ctx, id, 0); err != nil {
+				logger.Errorf("clear advancing workflow: %s error, %v", id, err)
+			}
+			continue
+		}
+		if err = e.advance(ctx, wf, wf.Step); err != nil {
+			logger.Errorf("recover step: %d of workflow: %s error, %v", wf.Step, id, err)
+			continue
+		}
+		logger.Infof("step: %d of workflow: %s is recovered", wf.Step, id)
+	}
+}
+
+// workflowRecoverer 周期性重新下发推进中的编排
+type workflowRecoverer struct {
+	engine   *WorkflowEngine
+	interval time.Duration
+	done     chan struct{}
+}
+
+func newWorkflowRecoverer(engine *WorkflowEngine, interval time.Duration) *workflowRecoverer {
+	return &workflowRecoverer{engine: engine, interval: interval, done: make(chan struct{})}
+}
+
+// Start 启动检测，推进中超过一个检测周期的编排视为下发中断
+func (r *workflowRecoverer) Start(wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case now := <-ticker.C:
+				r.engine.Recover(context.Background(), now.Add(-r.interval))
+			}
+		}
+	}()
+}
+
+// Shutdown 停止检测
+func (r *workflowRecoverer) Shutdown() {
+	close(r.done)
+}
+
+// workflowTaskOptions 还原任务的 option，编排内的任务在上一阶段完成后立即执行，因此忽略 ProcessAt
+func workflowTaskOptions(opt t.Options, taskID string) []t.Option {
+	opts := []t.Option{t.TaskID(taskID), t.MaxRetry(opt.Retry), t.Queue(opt.Queue)}
+	if opt.Timeout > 0 {
+		opts = append(opts, t.Timeout(opt.Timeout))
+	}
+	if !opt.Deadline.IsZero() {
+		opts = append(opts, t.Deadline(opt.Deadline))
+	}
+	if opt.UniqueTTL > 0 {
+		opts = append(opts, t.Unique(opt.UniqueTTL))
+	}
+	if opt.Retention > 0 {
+		opts = append(opts, t.Retention(opt.Retention))
+	}
+	return opts
+}
+
+// EnqueueWorkflow 创建任务编排并下发第一阶段的任务
+func (c *Client) EnqueueWorkflow(ctx context.Context, w *t.Workflow) (*t.WorkflowMessage, error) {
+	engine, err := NewWorkflowEngine(c.broker)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil || len(w.Steps) == 0 {
+		return nil, fmt.Errorf("workflow cannot be empty")
+	}
+	msg := &t.WorkflowMessage{ID: uuid.NewString(), State: t.WorkflowStateRunning}
+	for step, tasks := range w.Steps {
+		if len(tasks) == 0 {
+			return nil, fmt.Errorf("step %d of workflow cannot be empty", step)
+		}
+		serializerTasks := make([]*t.SerializerTask, 0, len(tasks))
+		for _, task := range tasks {
+			if task == nil || stringx.IsEmpty(task.Kind) {
+				return nil, fmt.Errorf("task typename in step %d of workflow cannot be empty", step)
+			}
+			serializerTask, err := t.NewSerializerTask(*task)
+			if err != nil {
+				return nil, err
+			}
+			serializerTasks = append(serializerTasks, serializerTask)
+		}
+		msg.Steps = append(msg.Steps, serializerTasks)
+	}
+
+	if err = engine.broker.CreateWorkflow(ctx, msg); err != nil {
+		return nil, err
+	}
+	if index, err := engine.enqueueStep(ctx, msg, 0); err != nil {
+		// 第一阶段下发失败时直接终止编排，由调用方决定是否重新创建
+		taskID := t.WorkflowTaskID(msg.ID, 0, index)
+		if _, ferr := engine.broker.FailWorkflow(ctx, msg.ID, 0, index, taskID, err.Error()); ferr != nil {
+			logger.Errorf("fail workflow: %s error, %v", msg.ID, ferr)
+		}
+		return nil, err
+	}
+	if err = engine.broker.MarkWorkflowStepEnqueued(ctx, msg.ID, 0); err != nil {
+		logger.Warnf("mark step: 0 of workflow: %s enqueued error, %v", msg.ID, err)
+	}
+	return msg, nil
+}
+
+// GetWorkflow 获取任务编排的定义及状态
+func (c *Client) GetWorkflow(ctx context.Context, id string) (*t.WorkflowMessage, error) {
+	engine, err := NewWorkflowEngine(c.broker)
+	if err != nil {
+		return nil, err
+	}
+	return engine.broker.GetWorkflow(ctx, id)
+}
+
+// ListWorkflows 按创建时间倒序获取任务编排
+func (c *Client) ListWorkflows(ctx context.Context, offset, limit int) ([]*t.WorkflowMessage, error) {
+	engine, err := NewWorkflowEngine(c.broker)
+	if err != nil {
+		return nil, err
+	}
+	return engine.broker.ListWorkflows(ctx, offset, limit)
+}
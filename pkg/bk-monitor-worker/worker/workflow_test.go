@@ -0,0 +1,97 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/broker/bbolt"
+	t "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bk-monitor-worker/task"
+)
+
+// failingBroker 下发指定任务时返回错误
+type failingBroker struct {
+	*bbolt.Broker
+	failID string
+}
+
+func (b *failingBroker) Enqueue(ctx context.Context, msg *t.TaskMessage) error {
+	if msg.ID == b.failID {
+		return fmt.Errorf("enqueue task id=%s failed", msg.ID)
+	}
+	return b.Broker.Enqueue(ctx, msg)
+}
+
+func pendingTaskIDs(test *testing.T, b *bbolt.Broker) []string {
+	tasks, err := b.ListTasks("default", t.TaskStatePending, 0, 100)
+	assert.NoError(test, err)
+	var ids []string
+	for _, i := range tasks {
+		ids = append(ids, i.ID)
+	}
+	return ids
+}
+
+// TestWorkflowRecover 模拟记录任务结果后、下发下一阶段前进程退出，由 recoverer 重新下发
+func TestWorkflowRecover(test *testing.T) {
+	b := bbolt.NewBroker(filepath.Join(test.TempDir(), "broker.db"))
+	assert.NoError(test, b.Open())
+	defer b.Close()
+	ctx := context.Background()
+
+	engine, err := NewWorkflowEngine(b)
+	assert.NoError(test, err)
+	client := &Client{broker: b}
+	wf, err := client.EnqueueWorkflow(ctx, t.NewChain(t.NewTask("wf:first", nil)).Then(
+		t.NewTask("wf:second", nil), t.NewTask("wf:third", nil),
+	))
+	assert.NoError(test, err)
+	first := t.WorkflowTaskID(wf.ID, 0, 0)
+	assert.Equal(test, []string{first}, pendingTaskIDs(test, b))
+	assert.NoError(test, b.DeleteTask("default", first))
+
+	// 第一阶段完成，进程在下发第二阶段前退出
+	done, err := b.CompleteWorkflowTask(ctx, wf.ID, 0, 0, nil, 1)
+	assert.NoError(test, err)
+	assert.True(test, done)
+	assert.Empty(test, pendingTaskIDs(test, b))
+
+	// 尚未超过 cutoff 的编排不处理
+	engine.Recover(ctx, time.Now().Add(-time.Minute))
+	assert.Empty(test, pendingTaskIDs(test, b))
+
+	// 部分任务下发失败时删除已下发的任务，编排仍为推进中
+	second, third := t.WorkflowTaskID(wf.ID, 1, 0), t.WorkflowTaskID(wf.ID, 1, 1)
+	failing, err := NewWorkflowEngine(&failingBroker{Broker: b, failID: third})
+	assert.NoError(test, err)
+	failing.Recover(ctx, time.Now().Add(time.Minute))
+	assert.Empty(test, pendingTaskIDs(test, b))
+	ids, err := b.ListAdvancingWorkflows(ctx, time.Now().Add(time.Minute))
+	assert.NoError(test, err)
+	assert.Equal(test, []string{wf.ID}, ids)
+
+	engine.Recover(ctx, time.Now().Add(time.Minute))
+	assert.ElementsMatch(test, []string{second, third}, pendingTaskIDs(test, b))
+	ids, err = b.ListAdvancingWorkflows(ctx, time.Now().Add(time.Minute))
+	assert.NoError(test, err)
+	assert.Empty(test, ids)
+
+	// 重复下发同一阶段不会产生重复任务
+	wf, err = b.GetWorkflow(ctx, wf.ID)
+	assert.NoError(test, err)
+	assert.NoError(test, engine.advance(ctx, wf, 1))
+	assert.ElementsMatch(test, []string{second, third}, pendingTaskIDs(test, b))
+}
@@ -16,6 +16,11 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
 )
 
+const (
+	DefaultCgroupRoot     = "/sys/fs/cgroup"
+	DefaultCgroupMaxCount = 500
+)
+
 type CpuConfig struct {
 	// collector times in one period
 	StatTimes   int           `config:"stat_times"`
@@ -74,6 +79,17 @@ type NetConfig struct {
 	InterfaceBlackList        []*regexp.Regexp `config:",ignore"`
 }
 
+// CgroupConfig cgroup v2 资源采集配置
+type CgroupConfig struct {
+	Enabled bool `config:"enabled"`
+	// Root cgroup v2 挂载路径
+	Root string `config:"root"`
+	// ReportAll 上报所有 cgroup，默认仅上报能够识别出容器 id 的 cgroup
+	ReportAll bool `config:"report_all"`
+	// MaxCount 单次最多上报的 cgroup 数量
+	MaxCount int `config:"max_count"`
+}
+
 // BasereportConfig
 type BasereportConfig struct {
 	BaseTaskParam `config:"_,inline"`
@@ -85,6 +101,8 @@ type BasereportConfig struct {
 	Mem  MemConfig  `config:"mem"`
 	Net  NetConfig  `config:"net"`
 
+	Cgroup CgroupConfig `config:"cgroup"`
+
 	// 环境信息的上报开关
 	ReportCrontab bool `config:"report_crontab"`
 	ReportHosts   bool `config:"report_hosts"`
@@ -138,6 +156,10 @@ var DefaultBasereportConfig = BasereportConfig{
 		InterfaceBlackList:  []*regexp.Regexp{},
 		RevertProtectNumber: 100,
 	},
+	Cgroup: CgroupConfig{
+		Root:     DefaultCgroupRoot,
+		MaxCount: DefaultCgroupMaxCount,
+	},
 	ReportCrontab: false,
 	ReportHosts:   false,
 	ReportRoute:   false,
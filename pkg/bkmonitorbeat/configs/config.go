@@ -101,12 +101,15 @@ type Config struct {
 	HeartBeat          *HeartBeatConfig       `config:"heart_beat"`
 	GatherUpBeat       *GatherUpBeatConfig    `config:"gather_up_beat"`
 	UDPTask            *UDPTaskMetaConfig     `config:"udp_task"`
+	DNSTask            *DNSTaskMetaConfig     `config:"dns_task"`
+	GRPCTask           *GRPCTaskMetaConfig    `config:"grpc_task"`
 	HTTPTask           *HTTPTaskMetaConfig    `config:"http_task"`
 	ScriptTask         *ScriptTaskMetaConfig  `config:"script_task"`
 	PingTask           *PingTaskMetaConfig    `config:"ping_task"`
 	MetricTask         *MetricBeatMetaConfig  `config:"metricbeat_task"`
 	KeywordTask        *KeywordTaskMetaConfig `config:"keyword_task"`
 	TrapTask           *TrapMetaConfig        `config:"trap_task"`
+	SNMPTask           *SNMPTaskMetaConfig    `config:"snmp_task"`
 	StaticTask         *StaticTaskMetaConfig  `config:"static_task"`
 	BaseReportTask     *BasereportConfig      `config:"basereport_task"`
 	ExceptionBeatTask  *ExceptionBeatConfig   `config:"exceptionbeat_task"`
@@ -140,12 +143,15 @@ func NewConfig() *Config {
 	}
 	config.TCPTask = NewTCPTaskMetaConfig(config)
 	config.UDPTask = NewUDPTaskMetaConfig(config)
+	config.DNSTask = NewDNSTaskMetaConfig(config)
+	config.GRPCTask = NewGRPCTaskMetaConfig(config)
 	config.HTTPTask = NewHTTPTaskMetaConfig(config)
 	config.ScriptTask = NewScriptTaskMetaConfig(config)
 	config.PingTask = NewPingTaskMetaConfig(config)
 	config.MetricTask = NewMetricBeatMetaConfig(config)
 	config.KeywordTask = NewKeywordTaskMetaConfig(config)
 	config.TrapTask = NewTrapMetaConfig(config)
+	config.SNMPTask = NewSNMPTaskMetaConfig(config)
 	config.StaticTask = NewStaticTaskMetaConfig(config)
 	config.BaseReportTask = NewBasereportConfig(config)
 	config.ExceptionBeatTask = NewExceptionBeatConfig(config)
@@ -0,0 +1,206 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/utils"
+)
+
+const (
+	ConfigTypeDNS = define.ModuleDNS
+)
+
+// DNS 查询协议
+const (
+	DNSProtocolUDP = "udp"
+	DNSProtocolTCP = "tcp"
+	DNSProtocolDoT = "dot"
+)
+
+// DNS 查询记录类型
+const (
+	DNSQueryTypeA     = "A"
+	DNSQueryTypeAAAA  = "AAAA"
+	DNSQueryTypeCNAME = "CNAME"
+	DNSQueryTypeMX    = "MX"
+	DNSQueryTypeTXT   = "TXT"
+	DNSQueryTypeSRV   = "SRV"
+)
+
+// 期望结果的匹配模式
+const (
+	// DNSMatchAll 所有解析结果都必须在期望列表中，用于发现劫持
+	DNSMatchAll = "all"
+	// DNSMatchAny 至少一个解析结果在期望列表中
+	DNSMatchAny = "any"
+	// DNSMatchExact 解析结果与期望列表完全一致
+	DNSMatchExact = "exact"
+)
+
+const (
+	DefaultDNSPort = 53
+	DefaultDoTPort = 853
+)
+
+// DNSTaskConfig :
+type DNSTaskConfig struct {
+	NetTaskParam `config:"_,inline"`
+	CustomReport bool `config:"custom_report"`
+
+	// TargetHost DNS 服务器地址，未配置时使用系统解析
+	TargetHost string `config:"target_host"`
+	// 支持多个 DNS 服务器，当配置多个服务器时忽略单个服务器配置
+	TargetHostList []string `config:"target_host_list"`
+	// TargetPort 未配置时 udp/tcp 使用 53，dot 使用 853
+	TargetPort int `config:"target_port"`
+
+	// Domain 查询的域名
+	Domain    string `config:"domain" validate:"required"`
+	QueryType string `config:"query_type"`
+	Protocol  string `config:"protocol"`
+	// ServerName dot 协议用于 SNI 以及证书校验，为空时使用服务器地址
+	ServerName         string `config:"server_name"`
+	InsecureSkipVerify bool   `config:"insecure_skip_verify"`
+
+	// ExpectedRcode 期望的响应码，如 NOERROR、NXDOMAIN，默认为 NOERROR
+	ExpectedRcode string `config:"expected_rcode"`
+	// ExpectedAnswers 期望的解析结果，为空时不检查
+	ExpectedAnswers []string `config:"expected_answers"`
+	MatchMode       string   `config:"match_mode"`
+}
+
+// InitIdent :
+func (c *DNSTaskConfig) InitIdent() error {
+	return c.initIdent(c)
+}
+
+// Hosts DNS 服务器列表，为空时使用系统解析
+func (c *DNSTaskConfig) Hosts() []string {
+	if len(c.TargetHostList) > 0 {
+		return c.TargetHostList
+	}
+	if c.TargetHost == "" {
+		return nil
+	}
+	return []string{c.TargetHost}
+}
+
+// CleanParams :
+func (c *DNSTaskConfig) CleanParams() error {
+	if len(c.TargetHostList) > 0 {
+		// 当配置多个服务器时忽略单个服务器配置
+		c.TargetHost = ""
+	}
+
+	c.Protocol = strings.ToLower(c.Protocol)
+	switch c.Protocol {
+	case "":
+		c.Protocol = DNSProtocolUDP
+	case DNSProtocolUDP, DNSProtocolTCP, DNSProtocolDoT:
+	default:
+		return fmt.Errorf("unknown dns protocol: %s", c.Protocol)
+	}
+
+	if c.TargetPort == 0 {
+		c.TargetPort = DefaultDNSPort
+		if c.Protocol == DNSProtocolDoT {
+			c.TargetPort = DefaultDoTPort
+		}
+	}
+
+	c.QueryType = strings.ToUpper(c.QueryType)
+	switch c.QueryType {
+	case "":
+		c.QueryType = DNSQueryTypeA
+	case DNSQueryTypeA, DNSQueryTypeAAAA, DNSQueryTypeCNAME, DNSQueryTypeMX, DNSQueryTypeTXT, DNSQueryTypeSRV:
+	default:
+		return fmt.Errorf("unknown dns query type: %s", c.QueryType)
+	}
+
+	c.MatchMode = strings.ToLower(c.MatchMode)
+	switch c.MatchMode {
+	case "":
+		c.MatchMode = DNSMatchAll
+	case DNSMatchAll, DNSMatchAny, DNSMatchExact:
+	default:
+		return fmt.Errorf("unknown dns match mode: %s", c.MatchMode)
+	}
+
+	c.ExpectedRcode = strings.ToUpper(c.ExpectedRcode)
+	if c.ExpectedRcode == "" {
+		c.ExpectedRcode = "NOERROR"
+	}
+	return nil
+}
+
+// Clean :
+func (c *DNSTaskConfig) Clean() error {
+	return utils.CleanCompositeParamList(&c.NetTaskParam, c)
+}
+
+// GetType :
+func (c *DNSTaskConfig) GetType() string {
+	return ConfigTypeDNS
+}
+
+// NewDNSTaskConfig :
+func NewDNSTaskConfig() *DNSTaskConfig {
+	var conf DNSTaskConfig
+	conf.Timeout = define.DefaultTimeout
+	conf.BufferSize = DefaultBufferSize
+	return &conf
+}
+
+// DNSTaskMetaConfig : dns task config
+type DNSTaskMetaConfig struct {
+	NetTaskMetaParam `config:"_,inline"`
+
+	Tasks []*DNSTaskConfig `config:"tasks"`
+}
+
+// Clean :
+func (c *DNSTaskMetaConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.NetTaskMetaParam)
+	if err != nil {
+		return err
+	}
+	for _, task := range c.Tasks {
+		err = c.CleanTask(task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskConfigList :
+func (c *DNSTaskMetaConfig) GetTaskConfigList() []define.TaskConfig {
+	tasks := make([]define.TaskConfig, len(c.Tasks))
+	for index, task := range c.Tasks {
+		tasks[index] = task
+	}
+	return tasks
+}
+
+// NewDNSTaskMetaConfig :
+func NewDNSTaskMetaConfig(root *Config) *DNSTaskMetaConfig {
+	config := &DNSTaskMetaConfig{
+		NetTaskMetaParam: NewNetTaskMetaParam(),
+	}
+	config.Tasks = make([]*DNSTaskConfig, 0)
+
+	root.TaskTypeMapping[ConfigTypeDNS] = config
+
+	return config
+}
@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+func TestDNSTaskConfigClean(t *testing.T) {
+	testCases := map[string]struct {
+		conf      configs.DNSTaskConfig
+		port      int
+		queryType string
+		rcode     string
+		hosts     []string
+		err       bool
+	}{
+		"默认配置使用系统解析": {
+			conf:      configs.DNSTaskConfig{Domain: "bk.tencent.com"},
+			port:      configs.DefaultDNSPort,
+			queryType: configs.DNSQueryTypeA,
+			rcode:     "NOERROR",
+		},
+		"dot 默认端口": {
+			conf:      configs.DNSTaskConfig{Domain: "bk.tencent.com", TargetHost: "1.1.1.1", Protocol: "DoT", QueryType: "aaaa"},
+			port:      configs.DefaultDoTPort,
+			queryType: configs.DNSQueryTypeAAAA,
+			rcode:     "NOERROR",
+			hosts:     []string{"1.1.1.1"},
+		},
+		"多个服务器时忽略单个服务器": {
+			conf: configs.DNSTaskConfig{
+				Domain:         "bk.tencent.com",
+				TargetHost:     "1.1.1.1",
+				TargetHostList: []string{"8.8.8.8", "8.8.4.4"},
+				TargetPort:     5353,
+				ExpectedRcode:  "nxdomain",
+			},
+			port:      5353,
+			queryType: configs.DNSQueryTypeA,
+			rcode:     "NXDOMAIN",
+			hosts:     []string{"8.8.8.8", "8.8.4.4"},
+		},
+		"未知的协议": {
+			conf: configs.DNSTaskConfig{Domain: "bk.tencent.com", Protocol: "doh"},
+			err:  true,
+		},
+		"未知的记录类型": {
+			conf: configs.DNSTaskConfig{Domain: "bk.tencent.com", QueryType: "PTR"},
+			err:  true,
+		},
+		"未知的匹配方式": {
+			conf: configs.DNSTaskConfig{Domain: "bk.tencent.com", MatchMode: "prefix"},
+			err:  true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := c.conf
+			var taskConf define.TaskConfig = &conf
+			err := taskConf.Clean()
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.port, conf.TargetPort)
+			assert.Equal(t, c.queryType, conf.QueryType)
+			assert.Equal(t, c.rcode, conf.ExpectedRcode)
+			assert.Equal(t, configs.DNSMatchAll, conf.MatchMode)
+			assert.Equal(t, c.hosts, conf.Hosts())
+			assert.Equal(t, configs.ConfigTypeDNS, taskConf.GetType())
+		})
+	}
+}
+
+func TestDNSMetaTaskConfig(t *testing.T) {
+	root := configs.NewConfig()
+	conf := root.DNSTask
+	conf.DataID = 1011
+	taskConf := configs.NewDNSTaskConfig()
+	taskConf.Domain = "bk.tencent.com"
+	conf.Tasks = append(conf.Tasks, taskConf)
+
+	assert.NoError(t, conf.Clean())
+	assert.Equal(t, conf.DataID, taskConf.DataID)
+	assert.Len(t, conf.GetTaskConfigList(), 1)
+	assert.Equal(t, define.TaskMetaConfig(conf), root.TaskTypeMapping[configs.ConfigTypeDNS])
+}
@@ -21,6 +21,12 @@ const (
 	DiskSpace
 	Core
 	OOM
+	InodeFull
+	FdFull
+	ConntrackFull
+	ZombieProcess
+	ClockJump
+	HungTask
 )
 
 type ExceptionBeatConfig struct {
@@ -39,6 +45,24 @@ type ExceptionBeatConfig struct {
 	CoreFileReportGap      time.Duration `config:"corefile_report_gap"`
 	CoreFilePattern        string        `config:"corefile_pattern"`
 	CoreFileMatchRegex     string        `config:"corefile_match_regex"`
+
+	CheckInodeInterval     time.Duration `config:"check_inode_interval"`
+	InodeUsagePercent      int           `config:"used_max_inode_percent"`
+	InodeReportGap         time.Duration `config:"inode_report_gap"`
+	CheckFdInterval        time.Duration `config:"check_fd_interval"`
+	FdUsagePercent         int           `config:"used_max_fd_percent"`
+	FdReportGap            time.Duration `config:"fd_report_gap"`
+	CheckConntrackInterval time.Duration `config:"check_conntrack_interval"`
+	ConntrackUsagePercent  int           `config:"used_max_conntrack_percent"`
+	ConntrackReportGap     time.Duration `config:"conntrack_report_gap"`
+	CheckZombieInterval    time.Duration `config:"check_zombie_interval"`
+	ZombieMaxCount         int           `config:"max_zombie_count"`
+	ZombieReportGap        time.Duration `config:"zombie_report_gap"`
+	CheckClockInterval     time.Duration `config:"check_clock_interval"`
+	ClockJumpThreshold     time.Duration `config:"clock_jump_threshold"`
+	ClockJumpReportGap     time.Duration `config:"clock_jump_report_gap"`
+	CheckHungTaskInterval  time.Duration `config:"check_hung_task_interval"`
+	HungTaskReportGap      time.Duration `config:"hung_task_report_gap"`
 }
 
 var DefaultExceptionBeatConfig = ExceptionBeatConfig{
@@ -52,6 +76,25 @@ var DefaultExceptionBeatConfig = ExceptionBeatConfig{
 	DiskMinFreeSpace:       10,
 	CoreFileReportGap:      time.Minute, // 默认同一个维度的corefile信息，需要相隔1分钟后才会上报
 	CoreFilePattern:        "",
+
+	// 持续性的异常默认相隔10分钟后才会重复上报
+	CheckInodeInterval:     time.Minute,
+	InodeUsagePercent:      95,
+	InodeReportGap:         10 * time.Minute,
+	CheckFdInterval:        time.Minute,
+	FdUsagePercent:         90,
+	FdReportGap:            10 * time.Minute,
+	CheckConntrackInterval: time.Minute,
+	ConntrackUsagePercent:  90,
+	ConntrackReportGap:     10 * time.Minute,
+	CheckZombieInterval:    time.Minute,
+	ZombieMaxCount:         100,
+	ZombieReportGap:        10 * time.Minute,
+	CheckClockInterval:     10 * time.Second,
+	ClockJumpThreshold:     5 * time.Second,
+	ClockJumpReportGap:     time.Minute,
+	CheckHungTaskInterval:  10 * time.Second,
+	HungTaskReportGap:      time.Minute,
 }
 
 func (c *ExceptionBeatConfig) GetTaskConfigList() []define.TaskConfig {
@@ -0,0 +1,185 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/utils"
+)
+
+const (
+	ConfigTypeGRPC = define.ModuleGRPC
+)
+
+// GRPCHealthCheckMethod 未配置调用方法时使用健康检查
+const GRPCHealthCheckMethod = "grpc.health.v1.Health/Check"
+
+// GRPCTaskConfig :
+type GRPCTaskConfig struct {
+	NetTaskParam    `config:"_,inline"`
+	SimpleTaskParam `config:"_,inline"`
+	CustomReport    bool `config:"custom_report"`
+
+	// Method 调用的方法，格式为 package.Service/Method，为空时调用健康检查
+	Method string `config:"method"`
+	// Service 健康检查的服务名，为空时检查服务整体状态
+	Service string `config:"service"`
+	// Request JSON 格式的请求内容，通过服务端反射获取请求结构
+	Request string `config:"request"`
+	// Metadata 请求携带的 metadata
+	Metadata map[string]string `config:"metadata"`
+
+	// TLS 使用 TLS 连接，配置了客户端证书时进行双向认证
+	TLS                bool   `config:"tls"`
+	ServerName         string `config:"server_name"`
+	InsecureSkipVerify bool   `config:"insecure_skip_verify"`
+	CAFile             string `config:"ca_file"`
+	CertFile           string `config:"cert_file"`
+	KeyFile            string `config:"key_file"`
+
+	// ExpectedCode 期望的状态码，如 OK、NOT_FOUND，默认为 OK
+	ExpectedCode string `config:"expected_code"`
+	// Assertions 响应断言，与 http 断言语法一致，header 对应响应的 metadata，status_code 对应状态码数值
+	Assertions []*HTTPAssertionConfig `config:"assertions"`
+}
+
+// InitIdent :
+func (c *GRPCTaskConfig) InitIdent() error {
+	return c.initIdent(c)
+}
+
+// GetMethod 返回完整的方法名 /package.Service/Method
+func (c *GRPCTaskConfig) GetMethod() string {
+	if c.Method == "" {
+		return "/" + GRPCHealthCheckMethod
+	}
+	return "/" + strings.TrimPrefix(c.Method, "/")
+}
+
+// IsHealthCheck 是否调用健康检查
+func (c *GRPCTaskConfig) IsHealthCheck() bool {
+	return c.GetMethod() == "/"+GRPCHealthCheckMethod
+}
+
+// parseGRPCCode 解析状态码名称，如 OK、NOT_FOUND
+func parseGRPCCode(s string) (codes.Code, error) {
+	var code codes.Code
+	err := code.UnmarshalJSON([]byte(fmt.Sprintf("%q", s)))
+	return code, err
+}
+
+// GetExpectedCode :
+func (c *GRPCTaskConfig) GetExpectedCode() codes.Code {
+	code, _ := parseGRPCCode(c.ExpectedCode)
+	return code
+}
+
+// CleanParams :
+func (c *GRPCTaskConfig) CleanParams() error {
+	method := strings.TrimPrefix(c.Method, "/")
+	if method != "" {
+		index := strings.LastIndex(method, "/")
+		if index <= 0 || index == len(method)-1 {
+			return fmt.Errorf("grpc method %s is invalid, should be package.Service/Method", c.Method)
+		}
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file should be configured together")
+	}
+	if c.CAFile != "" || c.CertFile != "" {
+		c.TLS = true
+	}
+
+	c.ExpectedCode = strings.ToUpper(c.ExpectedCode)
+	if c.ExpectedCode == "" {
+		c.ExpectedCode = "OK"
+	}
+	if _, err := parseGRPCCode(c.ExpectedCode); err != nil {
+		return fmt.Errorf("unknown grpc code %s", c.ExpectedCode)
+	}
+
+	for _, a := range c.Assertions {
+		if err := a.Clean(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clean :
+func (c *GRPCTaskConfig) Clean() error {
+	return utils.CleanCompositeParamList(
+		&c.NetTaskParam,
+		&c.SimpleTaskParam,
+		c,
+	)
+}
+
+// GetType :
+func (c *GRPCTaskConfig) GetType() string {
+	return ConfigTypeGRPC
+}
+
+// NewGRPCTaskConfig :
+func NewGRPCTaskConfig() *GRPCTaskConfig {
+	var conf GRPCTaskConfig
+	conf.Timeout = define.DefaultTimeout
+	conf.BufferSize = DefaultBufferSize
+	return &conf
+}
+
+// GRPCTaskMetaConfig : grpc task config
+type GRPCTaskMetaConfig struct {
+	NetTaskMetaParam `config:"_,inline"`
+
+	Tasks []*GRPCTaskConfig `config:"tasks"`
+}
+
+// Clean :
+func (c *GRPCTaskMetaConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.NetTaskMetaParam)
+	if err != nil {
+		return err
+	}
+	for _, task := range c.Tasks {
+		err = c.CleanTask(task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskConfigList :
+func (c *GRPCTaskMetaConfig) GetTaskConfigList() []define.TaskConfig {
+	tasks := make([]define.TaskConfig, len(c.Tasks))
+	for index, task := range c.Tasks {
+		tasks[index] = task
+	}
+	return tasks
+}
+
+// NewGRPCTaskMetaConfig :
+func NewGRPCTaskMetaConfig(root *Config) *GRPCTaskMetaConfig {
+	config := &GRPCTaskMetaConfig{
+		NetTaskMetaParam: NewNetTaskMetaParam(),
+	}
+	config.Tasks = make([]*GRPCTaskConfig, 0)
+
+	root.TaskTypeMapping[ConfigTypeGRPC] = config
+
+	return config
+}
@@ -0,0 +1,79 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestGRPCTaskConfigClean(t *testing.T) {
+	testCases := map[string]struct {
+		conf        configs.GRPCTaskConfig
+		method      string
+		healthCheck bool
+		code        codes.Code
+		tls         bool
+		err         bool
+	}{
+		"默认调用健康检查": {
+			method:      "/grpc.health.v1.Health/Check",
+			healthCheck: true,
+			code:        codes.OK,
+		},
+		"指定方法以及状态码": {
+			conf:   configs.GRPCTaskConfig{Method: "bk.monitor.Query/Search", ExpectedCode: "not_found"},
+			method: "/bk.monitor.Query/Search",
+			code:   codes.NotFound,
+		},
+		"配置证书时开启 TLS": {
+			conf:   configs.GRPCTaskConfig{Method: "/bk.monitor.Query/Search", CertFile: "client.crt", KeyFile: "client.key"},
+			method: "/bk.monitor.Query/Search",
+			code:   codes.OK,
+			tls:    true,
+		},
+		"方法格式不合法": {
+			conf: configs.GRPCTaskConfig{Method: "bk.monitor.Query"},
+			err:  true,
+		},
+		"证书以及私钥需要同时配置": {
+			conf: configs.GRPCTaskConfig{CertFile: "client.crt"},
+			err:  true,
+		},
+		"未知的状态码": {
+			conf: configs.GRPCTaskConfig{ExpectedCode: "BROKEN"},
+			err:  true,
+		},
+		"断言类型不合法": {
+			conf: configs.GRPCTaskConfig{Assertions: []*configs.HTTPAssertionConfig{{Type: "cookie"}}},
+			err:  true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := c.conf
+			err := conf.Clean()
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.method, conf.GetMethod())
+			assert.Equal(t, c.healthCheck, conf.IsHealthCheck())
+			assert.Equal(t, c.code, conf.GetExpectedCode())
+			assert.Equal(t, c.tls, conf.TLS)
+		})
+	}
+}
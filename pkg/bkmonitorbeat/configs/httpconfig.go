@@ -10,6 +10,8 @@
 package configs
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -22,6 +24,115 @@ const (
 	ConfigTypeHTTP = define.ModuleHTTP
 )
 
+// 变量提取来源
+const (
+	HTTPExtractorJSON   = "json"
+	HTTPExtractorRegex  = "regex"
+	HTTPExtractorHeader = "header"
+	HTTPExtractorCookie = "cookie"
+)
+
+// 断言对象
+const (
+	HTTPAssertionJSON         = "json"
+	HTTPAssertionHeader       = "header"
+	HTTPAssertionBody         = "body"
+	HTTPAssertionStatusCode   = "status_code"
+	HTTPAssertionResponseTime = "response_time"
+)
+
+// 断言操作符
+const (
+	HTTPOperatorEq        = "eq"
+	HTTPOperatorNq        = "nq"
+	HTTPOperatorGt        = "gt"
+	HTTPOperatorGe        = "ge"
+	HTTPOperatorLt        = "lt"
+	HTTPOperatorLe        = "le"
+	HTTPOperatorContains  = "contains"
+	HTTPOperatorNContains = "ncontains"
+	HTTPOperatorReg       = "reg"
+	HTTPOperatorExists    = "exists"
+	HTTPOperatorNExists   = "nexists"
+)
+
+// HTTPExtractorConfig 从响应中提取变量，提取的变量可以通过 ${name} 的方式在后续步骤的 url、headers、request 中引用
+type HTTPExtractorConfig struct {
+	Name string `config:"name"`
+	// Type 提取来源：json, regex, header, cookie
+	Type string `config:"type"`
+	// Expr json 路径（gjson 语法）/ 正则（存在捕获组时取第一个捕获组）/ header 名称 / cookie 名称
+	Expr string `config:"expr"`
+	// Default 提取失败时的默认值，为空时提取失败会导致步骤失败
+	Default string `config:"default"`
+	// regex regex 类型在 Clean 时编译的正则，不导出以避免参与任务配置的 gob 哈希
+	regex *regexp.Regexp
+}
+
+// Regex 返回 regex 类型在 Clean 时编译的正则
+func (c *HTTPExtractorConfig) Regex() *regexp.Regexp {
+	return c.regex
+}
+
+// Clean :
+func (c *HTTPExtractorConfig) Clean() error {
+	if c.Name == "" || c.Expr == "" {
+		return fmt.Errorf("extractor name and expr are required")
+	}
+	switch c.Type {
+	case HTTPExtractorJSON, HTTPExtractorHeader, HTTPExtractorCookie:
+	case HTTPExtractorRegex:
+		re, err := regexp.Compile(c.Expr)
+		if err != nil {
+			return fmt.Errorf("extractor %s regex is invalid: %v", c.Name, err)
+		}
+		c.regex = re
+	default:
+		return fmt.Errorf("extractor %s type %s is not supported", c.Name, c.Type)
+	}
+	return nil
+}
+
+// HTTPAssertionConfig 响应断言
+type HTTPAssertionConfig struct {
+	// Type 断言对象：json, header, body, status_code, response_time
+	Type string `config:"type"`
+	// Expr json 路径或者 header 名称
+	Expr string `config:"expr"`
+	// Operator 支持：eq, nq, gt, ge, lt, le, contains, ncontains, reg, exists, nexists
+	Operator string `config:"operator"`
+	// Value 期望值，response_time 支持 500ms 这样的时间格式，纯数字时单位为毫秒
+	Value string `config:"value"`
+}
+
+// Clean :
+func (c *HTTPAssertionConfig) Clean() error {
+	switch c.Type {
+	case HTTPAssertionJSON, HTTPAssertionHeader:
+		if c.Expr == "" {
+			return fmt.Errorf("assertion %s expr is required", c.Type)
+		}
+	case HTTPAssertionBody, HTTPAssertionStatusCode, HTTPAssertionResponseTime:
+	default:
+		return fmt.Errorf("assertion type %s is not supported", c.Type)
+	}
+
+	if c.Operator == "" {
+		c.Operator = HTTPOperatorEq
+	}
+	switch c.Operator {
+	case HTTPOperatorEq, HTTPOperatorNq, HTTPOperatorGt, HTTPOperatorGe, HTTPOperatorLt, HTTPOperatorLe,
+		HTTPOperatorContains, HTTPOperatorNContains, HTTPOperatorExists, HTTPOperatorNExists:
+	case HTTPOperatorReg:
+		if _, err := regexp.Compile(c.Value); err != nil {
+			return fmt.Errorf("assertion regex is invalid: %v", err)
+		}
+	default:
+		return fmt.Errorf("assertion operator %s is not supported", c.Operator)
+	}
+	return nil
+}
+
 // HTTPTaskStepConfig :
 type HTTPTaskStepConfig struct {
 	SimpleMatchParam `config:"_,inline"`
@@ -32,6 +143,27 @@ type HTTPTaskStepConfig struct {
 	Headers          map[string]string `config:"headers"`
 	ResponseCode     string            `config:"response_code"`
 	ResponseCodeList []int             `config:"response_code_list"`
+
+	Extractors []*HTTPExtractorConfig `config:"extractors"`
+	Assertions []*HTTPAssertionConfig `config:"assertions"`
+}
+
+// NeedBody 是否需要读取响应内容
+func (c *HTTPTaskStepConfig) NeedBody() bool {
+	if c.Response != "" {
+		return true
+	}
+	for _, e := range c.Extractors {
+		if e.Type == HTTPExtractorJSON || e.Type == HTTPExtractorRegex {
+			return true
+		}
+	}
+	for _, a := range c.Assertions {
+		if a.Type == HTTPAssertionJSON || a.Type == HTTPAssertionBody {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *HTTPTaskStepConfig) URLs() []string {
@@ -78,6 +210,16 @@ func (c *HTTPTaskStepConfig) Clean() error {
 		}
 		c.ResponseCodeList = append(c.ResponseCodeList, code)
 	}
+	for _, e := range c.Extractors {
+		if err = e.Clean(); err != nil {
+			return err
+		}
+	}
+	for _, a := range c.Assertions {
+		if err = a.Clean(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -88,6 +230,11 @@ type HTTPTaskConfig struct {
 	InsecureSkipVerify bool                  `config:"insecure_skip_verify"`
 	Steps              []*HTTPTaskStepConfig `config:"steps"`
 	CustomReport       bool                  `config:"custom_report"`
+
+	// Transaction 事务模式，按顺序执行所有步骤并共享 cookie 和变量，任一步骤失败则中止，整个事务上报一条事件
+	Transaction bool `config:"transaction"`
+	// Variables 事务的初始变量
+	Variables map[string]string `config:"variables"`
 }
 
 // InitIdent :
@@ -61,3 +61,52 @@ func (s *HTTPConfiSuite) TestConfigClean() {
 	s.Equal("", stepConf.Response)
 	s.Equal("startswith", stepConf.ResponseFormat)
 }
+
+// TestConfigCleanWithRegexExtractor 编译后的正则不能影响任务配置的哈希
+func (s *HTTPConfiSuite) TestConfigCleanWithRegexExtractor() {
+	metaConf := configs.NewHTTPTaskMetaConfig(configs.NewConfig())
+	taskConf := configs.NewHTTPTaskConfig()
+	stepConf := &configs.HTTPTaskStepConfig{
+		URL: "bk.tencent.com",
+		Extractors: []*configs.HTTPExtractorConfig{
+			{Name: "token", Type: configs.HTTPExtractorRegex, Expr: "token=(\\w+)"},
+		},
+	}
+	taskConf.Steps = append(taskConf.Steps, stepConf)
+	metaConf.Tasks = append(metaConf.Tasks, taskConf)
+
+	s.NoError(metaConf.Clean(), "clean error")
+	s.NotEmpty(taskConf.GetIdent())
+	s.Equal([]string{"token=abc", "abc"}, stepConf.Extractors[0].Regex().FindStringSubmatch("token=abc"))
+}
+
+// TestStepExtractorAndAssertionClean :
+func (s *HTTPConfiSuite) TestStepExtractorAndAssertionClean() {
+	stepConf := &configs.HTTPTaskStepConfig{
+		URL: "bk.tencent.com",
+		Extractors: []*configs.HTTPExtractorConfig{
+			{Name: "token", Type: configs.HTTPExtractorJSON, Expr: "data.token"},
+		},
+		Assertions: []*configs.HTTPAssertionConfig{
+			{Type: configs.HTTPAssertionJSON, Expr: "code", Value: "0"},
+		},
+	}
+	s.NoError(stepConf.Clean())
+	s.Equal(configs.HTTPOperatorEq, stepConf.Assertions[0].Operator)
+	s.True(stepConf.NeedBody())
+
+	stepConf.Extractors[0].Type = "xpath"
+	s.Error(stepConf.Clean())
+
+	stepConf.Extractors[0].Type = configs.HTTPExtractorRegex
+	stepConf.Extractors[0].Expr = "token=("
+	s.Error(stepConf.Clean())
+
+	stepConf.Extractors = nil
+	stepConf.Assertions = []*configs.HTTPAssertionConfig{{Type: configs.HTTPAssertionHeader, Operator: configs.HTTPOperatorExists}}
+	s.Error(stepConf.Clean())
+
+	stepConf.Assertions[0].Expr = "X-Request-Id"
+	s.NoError(stepConf.Clean())
+	s.False(stepConf.NeedBody())
+}
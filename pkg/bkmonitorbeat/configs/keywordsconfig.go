@@ -32,6 +32,11 @@ const (
 	// 结果聚合发送方式
 	OutputFormatEvent      = "event"
 	DefaultRetainFileBytes = 1024 * 1024 // 1MB
+
+	DefaultMultilineTimeout   = 3 * time.Second
+	DefaultMultilineMaxLines  = 500
+	DefaultMaxDimensionValues = 100
+	DefaultSampleSize         = 3
 )
 
 // 日志关键字匹配规则配置
@@ -40,6 +45,20 @@ type KeywordConfig struct {
 	Pattern string `config:"pattern"` // 正则匹配规则
 }
 
+// MultilineConfig 多行日志聚合配置，命中 StartPattern 的行作为新日志的开始，后续的行追加到该日志中
+// 只配置 ContinuePattern 时，命中的行追加到上一条日志中
+type MultilineConfig struct {
+	StartPattern    string        `config:"start_pattern"`    // 多行日志起始行的正则
+	ContinuePattern string        `config:"continue_pattern"` // 多行日志后续行的正则，不配置时不匹配起始行正则的行均视为后续行
+	Timeout         time.Duration `config:"timeout"`          // 超过该时间没有新的行写入时结束当前日志
+	MaxLines        int           `config:"max_lines"`        // 单条日志的最大行数，超过的行会被丢弃
+}
+
+// Enabled 是否开启多行聚合
+func (c *MultilineConfig) Enabled() bool {
+	return c.StartPattern != "" || c.ContinuePattern != ""
+}
+
 // 采集下发来源配置说明
 type Label struct {
 	BkCollectConfigID         string `config:"bk_collect_config_id"`
@@ -109,6 +128,10 @@ type KeywordTaskConfig struct {
 	TimeUnit        string          `config:"time_unit"`       // 上报时间单位，默认是ms
 	Label           []Label         `config:"labels"`
 	RetainFileBytes int64           `config:"retain_file_bytes"` // 保留前置文件的尾部数据
+	Multiline       MultilineConfig `config:"multiline"`         // 多行日志聚合
+	// 单个文件单条规则在一个上报周期内的维度组合上限，超出的组合维度值统一为 __other__，小于 0 时不限制
+	MaxDimensionValues int `config:"max_dimension_values"`
+	SampleSize         int `config:"sample_size"` // 事件中附带的日志样例数量，小于 0 时不附带
 }
 
 func (c *KeywordTaskConfig) InitIdent() error {
@@ -151,6 +174,18 @@ func (c *KeywordTaskConfig) Clean() error {
 	if c.RetainFileBytes < 0 {
 		c.RetainFileBytes = 0
 	}
+	if c.Multiline.Timeout <= 0 {
+		c.Multiline.Timeout = DefaultMultilineTimeout
+	}
+	if c.Multiline.MaxLines <= 0 {
+		c.Multiline.MaxLines = DefaultMultilineMaxLines
+	}
+	if c.MaxDimensionValues == 0 {
+		c.MaxDimensionValues = DefaultMaxDimensionValues
+	}
+	if c.SampleSize == 0 {
+		c.SampleSize = DefaultSampleSize
+	}
 	return nil
 }
 
@@ -0,0 +1,208 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/utils"
+)
+
+const (
+	ConfigTypeSNMP = define.ModuleSNMP
+)
+
+const (
+	SNMPMetricGauge   = "gauge"
+	SNMPMetricCounter = "counter"
+
+	SNMPWalkModeWalk     = "walk"
+	SNMPWalkModeBulkWalk = "bulkwalk"
+
+	DefaultSNMPPort           = 161
+	DefaultSNMPIndexName      = "index"
+	DefaultSNMPMaxRepetitions = 10
+)
+
+// SNMPMetricConfig oid 与指标的映射
+type SNMPMetricConfig struct {
+	Name string `config:"name"` // 指标名
+	OID  string `config:"oid"`  // 标量 oid 或者表格中列的 oid，配置了 MIB 时可以使用符号名
+	// Type gauge 直接上报数值；counter 同时上报 <name>_rate 每秒增长速率
+	Type string `config:"type"`
+}
+
+// SNMPDimensionConfig 表格中作为维度上报的列
+type SNMPDimensionConfig struct {
+	Name string `config:"name"`
+	OID  string `config:"oid"`
+}
+
+// SNMPTableConfig 表格采集配置，相同索引的列组成一行，索引以及维度列作为该行指标的维度
+type SNMPTableConfig struct {
+	IndexName  string                `config:"index_name"` // 索引的维度名，默认为 index
+	Metrics    []SNMPMetricConfig    `config:"metrics"`
+	Dimensions []SNMPDimensionConfig `config:"dimensions"`
+}
+
+// SNMPTaskConfig 主动轮询设备的 SNMP 采集任务
+type SNMPTaskConfig struct {
+	BaseTaskParam `config:"_,inline"`
+
+	// Targets 设备地址，未指定端口时使用 Port
+	Targets   []string `config:"targets"`
+	Port      int      `config:"port"`
+	Version   string   `config:"snmp_version"`
+	Community string   `config:"community"`
+	Retries   int      `config:"retries"`
+	// WalkMode 表格的遍历方式，walk 或者 bulkwalk，v1 只支持 walk
+	WalkMode       string `config:"walk_mode"`
+	MaxRepetitions uint8  `config:"max_repetitions"`
+	// UsmInfo v3 认证参数
+	UsmInfo UsmInfo `config:"usm_info"`
+
+	// MibDirs MIB 文件目录，配置后 oid 支持使用符号名，维度值按照 MIB 中的枚举以及 DISPLAY-HINT 格式化
+	MibDirs []string `config:"mib_dirs"`
+
+	// Metrics 通过 GET 采集的标量
+	Metrics []SNMPMetricConfig `config:"metrics"`
+	// Tables 通过 WALK/BULKWALK 采集的表格
+	Tables []SNMPTableConfig `config:"tables"`
+}
+
+// InitIdent :
+func (c *SNMPTaskConfig) InitIdent() error {
+	return c.initIdent(c)
+}
+
+func cleanSNMPMetrics(metrics []SNMPMetricConfig) error {
+	for i := range metrics {
+		m := &metrics[i]
+		if m.Name == "" || m.OID == "" {
+			return fmt.Errorf("snmp metric name and oid should not be empty")
+		}
+		m.OID = strings.TrimPrefix(m.OID, ".")
+		m.Type = strings.ToLower(m.Type)
+		switch m.Type {
+		case "":
+			m.Type = SNMPMetricGauge
+		case SNMPMetricGauge, SNMPMetricCounter:
+		default:
+			return fmt.Errorf("unknown snmp metric type %s of %s", m.Type, m.Name)
+		}
+	}
+	return nil
+}
+
+// Clean :
+func (c *SNMPTaskConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.BaseTaskParam)
+	if err != nil {
+		return err
+	}
+	if c.Port == 0 {
+		c.Port = DefaultSNMPPort
+	}
+	if c.Version == "" {
+		c.Version = "2c"
+	}
+	if c.MaxRepetitions == 0 {
+		c.MaxRepetitions = DefaultSNMPMaxRepetitions
+	}
+
+	c.WalkMode = strings.ToLower(c.WalkMode)
+	switch c.WalkMode {
+	case "":
+		c.WalkMode = SNMPWalkModeBulkWalk
+	case SNMPWalkModeWalk, SNMPWalkModeBulkWalk:
+	default:
+		return fmt.Errorf("unknown snmp walk mode %s", c.WalkMode)
+	}
+	// v1 不支持 GETBULK
+	if strings.Trim(strings.ToLower(c.Version), "v") == "1" {
+		c.WalkMode = SNMPWalkModeWalk
+	}
+
+	if err = cleanSNMPMetrics(c.Metrics); err != nil {
+		return err
+	}
+	for i := range c.Tables {
+		table := &c.Tables[i]
+		if table.IndexName == "" {
+			table.IndexName = DefaultSNMPIndexName
+		}
+		if len(table.Metrics) == 0 {
+			return fmt.Errorf("snmp table should have at least one metric")
+		}
+		if err = cleanSNMPMetrics(table.Metrics); err != nil {
+			return err
+		}
+		for j := range table.Dimensions {
+			table.Dimensions[j].OID = strings.TrimPrefix(table.Dimensions[j].OID, ".")
+		}
+	}
+	return nil
+}
+
+// GetType :
+func (c *SNMPTaskConfig) GetType() string {
+	return ConfigTypeSNMP
+}
+
+// NewSNMPTaskConfig :
+func NewSNMPTaskConfig() *SNMPTaskConfig {
+	var conf SNMPTaskConfig
+	conf.Timeout = define.DefaultTimeout
+	return &conf
+}
+
+// SNMPTaskMetaConfig : snmp task config
+type SNMPTaskMetaConfig struct {
+	BaseTaskMetaParam `config:"_,inline"`
+
+	Tasks []*SNMPTaskConfig `config:"tasks"`
+}
+
+// Clean :
+func (c *SNMPTaskMetaConfig) Clean() error {
+	err := utils.CleanCompositeParamList(&c.BaseTaskMetaParam)
+	if err != nil {
+		return err
+	}
+	for _, task := range c.Tasks {
+		err = c.CleanTask(task)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskConfigList :
+func (c *SNMPTaskMetaConfig) GetTaskConfigList() []define.TaskConfig {
+	tasks := make([]define.TaskConfig, len(c.Tasks))
+	for index, task := range c.Tasks {
+		tasks[index] = task
+	}
+	return tasks
+}
+
+// NewSNMPTaskMetaConfig :
+func NewSNMPTaskMetaConfig(root *Config) *SNMPTaskMetaConfig {
+	config := &SNMPTaskMetaConfig{
+		BaseTaskMetaParam: NewBaseTaskMetaParam(),
+	}
+	config.Tasks = make([]*SNMPTaskConfig, 0)
+	root.TaskTypeMapping[ConfigTypeSNMP] = config
+
+	return config
+}
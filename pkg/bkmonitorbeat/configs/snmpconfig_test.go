@@ -0,0 +1,75 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package configs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestSNMPTaskConfigClean(t *testing.T) {
+	testCases := map[string]struct {
+		conf     configs.SNMPTaskConfig
+		walkMode string
+		err      bool
+	}{
+		"默认配置": {
+			conf: configs.SNMPTaskConfig{
+				Metrics: []configs.SNMPMetricConfig{{Name: "sys_uptime", OID: ".1.3.6.1.2.1.1.3.0"}},
+			},
+			walkMode: configs.SNMPWalkModeBulkWalk,
+		},
+		"v1 只支持 walk": {
+			conf:     configs.SNMPTaskConfig{Version: "v1", WalkMode: "BulkWalk"},
+			walkMode: configs.SNMPWalkModeWalk,
+		},
+		"未知的遍历方式": {
+			conf: configs.SNMPTaskConfig{WalkMode: "getnext"},
+			err:  true,
+		},
+		"未知的指标类型": {
+			conf: configs.SNMPTaskConfig{
+				Metrics: []configs.SNMPMetricConfig{{Name: "sys_uptime", OID: "1.3.6.1.2.1.1.3.0", Type: "histogram"}},
+			},
+			err: true,
+		},
+		"指标缺少 oid": {
+			conf: configs.SNMPTaskConfig{
+				Metrics: []configs.SNMPMetricConfig{{Name: "sys_uptime"}},
+			},
+			err: true,
+		},
+		"表格没有指标": {
+			conf: configs.SNMPTaskConfig{Tables: []configs.SNMPTableConfig{{}}},
+			err:  true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := c.conf
+			err := conf.Clean()
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.walkMode, conf.WalkMode)
+			assert.Equal(t, configs.DefaultSNMPPort, conf.Port)
+			for _, m := range conf.Metrics {
+				assert.Equal(t, "1.3.6.1.2.1.1.3.0", m.OID)
+				assert.Equal(t, configs.SNMPMetricGauge, m.Type)
+			}
+		})
+	}
+}
@@ -11,9 +11,20 @@ package configs
 
 import "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
 
+const (
+	DefaultTCPInfoTopN = 100
+)
+
+// TCPInfoConfig 连接质量指标采集配置，按进程以及对端地址聚合后以自定义指标上报
+type TCPInfoConfig struct {
+	DataID int32 `config:"dataid"` // 自定义指标 dataid，未配置时不采集
+	TopN   int   `config:"top_n"`  // 按重传数排序后上报的聚合数量
+}
+
 type SocketSnapshotConfig struct {
 	BaseTaskParam `config:"_,inline"`
-	Detector      string `config:"detector"`
+	Detector      string        `config:"detector"`
+	TCPInfo       TCPInfoConfig `config:"tcp_info"`
 }
 
 func (c *SocketSnapshotConfig) GetTaskConfigList() []define.TaskConfig {
@@ -36,6 +47,9 @@ func (c *SocketSnapshotConfig) GetType() string {
 }
 
 func (c *SocketSnapshotConfig) Clean() error {
+	if c.TCPInfo.TopN <= 0 {
+		c.TCPInfo.TopN = DefaultTCPInfoTopN
+	}
 	return nil
 }
 
@@ -24,6 +24,13 @@ type TCPTaskConfig struct {
 	SimpleMatchParam `config:"_,inline"`
 	SimpleTaskParam  `config:"_,inline"`
 	CustomReport     bool `config:"custom_report"`
+
+	// TLS 建立连接之后进行 TLS 握手，并上报证书信息，请求以及响应内容通过 TLS 连接收发
+	TLS bool `config:"tls"`
+	// ServerName 用于 SNI 以及证书域名校验，为空时使用目标地址
+	ServerName string `config:"server_name"`
+	// InsecureSkipVerify 证书校验失败时不判定为失败，证书信息依然会上报
+	InsecureSkipVerify bool `config:"insecure_skip_verify"`
 }
 
 // InitIdent :
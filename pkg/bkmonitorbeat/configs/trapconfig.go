@@ -33,8 +33,10 @@ type TrapConfig struct {
 	Concurrency     int           `config:"concurrency"`
 	AggregatePeriod time.Duration `config:"aggregate_period"`
 
-	// oid翻译字典
+	// oid翻译字典，优先级高于 MIB 的翻译结果
 	OIDS map[string]string `config:"oids"`
+	// MIB 文件目录，用于将 oid 以及枚举值翻译为符号名
+	MibDirs []string `config:"mib_dirs"`
 
 	// 用户指定的需要作为维度上报的oid
 	ReportOIDDimensions []string `config:"report_oid_dimensions"`
@@ -64,9 +64,14 @@ var (
 	CodeRequestTimeout      = newNamedCode(1101, "RequestTimeout")
 	CodeResponseFailed      = newNamedCode(1200, "ResponseFailed")
 	CodeResponseNotMatch    = newNamedCode(1202, "ResponseNotMatch")
+	CodeAssertionFailed     = newNamedCode(1203, "AssertionFailed")
+	CodeExtractFailed       = newNamedCode(1204, "ExtractFailed")
 	CodeIPNotFound          = newNamedCode(1211, "IPNotFound")
 	CodeInvalidURL          = newNamedCode(1213, "InvalidURL")
 	CodeDNSResolveFailed    = newNamedCode(1004, "DNSResolveFailed")
+	CodeTLSHandshakeFailed  = newNamedCode(1005, "TLSHandshakeFailed")
+	CodeCertInvalid         = newNamedCode(1006, "CertInvalid")
 	CodeInvalidIP           = newNamedCode(2102, "InvalidIP")
 	CodeBadRequestParams    = newNamedCode(1103, "BadRequestParams")
+	CodeReflectionFailed    = newNamedCode(1104, "ReflectionFailed")
 )
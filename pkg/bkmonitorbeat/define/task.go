@@ -25,8 +25,11 @@ const (
 	ModuleScript          = "script"
 	ModuleTCP             = "tcp"
 	ModuleUDP             = "udp"
+	ModuleDNS             = "dns"
+	ModuleGRPC            = "grpc"
 	ModuleKeyword         = "keyword"
 	ModuleTrap            = "snmptrap"
+	ModuleSNMP            = "snmp"
 	ModuleBasereport      = "basereport"
 	ModuleExceptionbeat   = "exceptionbeat"
 	ModuleKubeevent       = "kubeevent"
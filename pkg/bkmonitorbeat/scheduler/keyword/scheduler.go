@@ -261,6 +261,7 @@ func (s *Scheduler) taskToKeywordTask(t define.Task) *keyword.TaskConfig {
 		ScanSleep:      c.ScanSleep,
 		FilterPatterns: c.FilterPatterns,
 		KeywordConfigs: c.KeywordConfigs,
+		Multiline:      c.Multiline,
 	}
 
 	taskConfig.Sender = keyword.SendConfig{
@@ -270,6 +271,9 @@ func (s *Scheduler) taskToKeywordTask(t define.Task) *keyword.TaskConfig {
 		OutputFormat: c.OutputFormat,
 		TimeUnit:     c.TimeUnit,
 		Label:        uniqLabel,
+
+		MaxDimensionValues: c.MaxDimensionValues,
+		SampleSize:         c.SampleSize,
 	}
 
 	taskConfig.TaskID = t.GetConfig().GetIdent()
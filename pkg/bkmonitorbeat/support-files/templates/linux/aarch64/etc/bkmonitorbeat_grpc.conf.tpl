@@ -0,0 +1,66 @@
+# 子配置信息
+type: grpc
+name: {{ config_name | default("grpc_task", true) }}
+version: {{ config_version| default("1.1.1", true) }}
+
+dataid: {{ data_id }}
+# 缓冲区最大空间
+max_buffer_size: {{ max_buffer_size | default(10240, true) }}
+# 最大超时时间
+max_timeout: {{ max_timeout | default("30s", true) }}
+# 最小检测间隔
+min_period: {{ min_period | default("3s", true) }}
+# 任务列表
+tasks: {% for task in tasks %}
+  - task_id: {{ task.task_id or task_id }}
+    bk_biz_id: {{ task.bk_biz_id or bk_biz_id }}
+    target_ip_type: {{ task.target_ip_type | default(0, true) }}
+    dns_check_mode: {{ task.dns_check_mode | default("single", true) }}
+    period: {{ task.period or period }}
+    # 检测超时（connect+call总共时间）
+    timeout: {{ (task.timeout or timeout) | default("3s", true) }}
+    {%- if custom_report == "true" %}
+    # 是否自定义上报
+    custom_report: {{ custom_report | default("false", true) }}{% endif %}
+    target_host: {{ task.target_host or task.ip }}
+    # 当配置的target_host_list不为空时，使用target_host_list，忽略target_host
+    target_host_list: {% if task.target_host_list %}{% for target_host in task.target_host_list %}
+    - {{ target_host }}{% endfor %}{% endif %}
+    target_port: {{ task.target_port or target_port }}
+    available_duration: {{ task.available_duration or available_duration }}
+    # 调用的方法（package.Service/Method），为空时调用 grpc.health.v1.Health/Check
+    method: {{ task.method or '' }}
+    # 健康检查的服务名
+    service: {{ task.service or '' }}
+    # JSON 格式的请求内容
+    request: '{{ task.request or '' }}'
+    {%- if task.metadata %}
+    metadata:
+    {%- for key, value in task.metadata.items() %}
+      {{ key }}: "{{ value }}"
+    {%- endfor %}{% endif %}
+    {%- if task.tls %}
+    # TLS 连接，配置客户端证书时进行双向认证
+    tls: true
+    server_name: {{ task.server_name or '' }}
+    insecure_skip_verify: {{ task.insecure_skip_verify | default(false, true) | lower }}
+    ca_file: {{ task.ca_file or '' }}
+    cert_file: {{ task.cert_file or '' }}
+    key_file: {{ task.key_file or '' }}{% endif %}
+    # 期望的状态码
+    expected_code: {{ task.expected_code | default("OK", true) }}
+    {%- if task.assertions %}
+    # 断言（json/header/body/status_code/response_time）
+    assertions: {% for assertion in task.assertions %}
+      - type: {{ assertion.type }}
+        expr: '{{ assertion.expr or '' }}'
+        operator: {{ assertion.operator | default("eq", true) }}
+        value: '{{ assertion.value or '' }}'{% endfor %}
+    {%- endif %}
+    {%- if task.labels and custom_report == "true" %}
+    labels:
+    {%- for key, value in task.labels.items() %}
+    {{"-" if loop.first else " "}} {{ key }}: "{{ value }}"
+    {%- endfor %}
+    {% endif %}
+{%- endfor %}
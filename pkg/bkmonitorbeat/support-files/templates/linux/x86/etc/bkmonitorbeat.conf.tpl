@@ -73,7 +73,7 @@ bkmonitorbeat:
   metrics_batch_size: 1024
   # 管理服务，包含指标和调试接口, 可动态reload开关或变更监听地址（unix使用SIGUSR2,windows发送bkreload2）
   # admin_addr: localhost:56060
-  # 并发限制，按照任务类型区分(http, tcp, udp, ping)，分为per_instance单实例限制和per_task单任务限制
+  # 并发限制，按照任务类型区分(http, tcp, udp, dns, grpc, ping)，分为per_instance单实例限制和per_task单任务限制
   concurrency_limit:
     task:
       http:
@@ -85,6 +85,12 @@ bkmonitorbeat:
       udp:
         per_instance: 100000
         per_task: 1000
+      dns:
+        per_instance: 100000
+        per_task: 1000
+      grpc:
+        per_instance: 100000
+        per_task: 1000
       ping:
         per_instance: 100000
         per_task: 1000
@@ -136,6 +142,12 @@ bkmonitorbeat:
       skip_virtual_interface: false
       interface_black_list: ["veth", "cni", "docker", "flannel", "tunnat", "cbr", "kube-ipvs", "dummy"]
       force_report_list: ["bond"]
+    # cgroup v2 容器资源及 PSI 采集
+    cgroup:
+      enabled: false
+      root: /sys/fs/cgroup
+      report_all: false
+      max_count: 500
 
   # 主机异常事件采集（磁盘满、磁盘只读、Corefile 事件以及 OOM 事件）
   exceptionbeat_task:
@@ -155,6 +167,12 @@ bkmonitorbeat:
     corefile_match_regex: {{ extra_vars.corefile_match_regex or '' }}
 {%- endif %}
     disk_ro_black_list: ["docker","container","k8s","kubelet","blueking"]
+    # 以下探测器需要在 check_bit 中添加 C_INODE|C_FD|C_CONNTRACK|C_ZOMBIE|C_CLOCK_JUMP|C_HUNG_TASK 开启
+    used_max_inode_percent: 95
+    used_max_fd_percent: 90
+    used_max_conntrack_percent: 90
+    max_zombie_count: 100
+    clock_jump_threshold: 5s
   # 进程采集：同步 CMDB 进程配置文件到 bkmonitorbeat 子任务文件夹下
   procconf_task:
     task_id: 103
@@ -228,6 +246,10 @@ bkmonitorbeat:
     dataid: 1100019
     period: 1m
     detector: netlink
+    # 连接质量指标，按进程及对端地址聚合后上报，配置 dataid 后开启
+    tcp_info:
+      dataid: 0
+      top_n: 100
 
   # rpmpackge 数据采集
   rpmpackage_task:
@@ -34,4 +34,14 @@ tasks: {% for task in tasks %}
           {% endfor %}{% endfor %}
      # 运行时加入新文件往前读取字节（默认 1M）
      retain_file_bytes: 1048576
+     # 单个文件单条规则在一个上报周期内的维度组合上限，超出的组合维度值为 __other__
+     max_dimension_values: {{ task.max_dimension_values | default(100, true) | int }}
+     # 事件附带的日志样例数量
+     sample_size: {{ task.sample_size | default(3, true) | int }}{% if task.multiline %}
+     # 多行日志聚合
+     multiline:
+       start_pattern: '{{ task.multiline.start_pattern | default("", true) | replace("'", "''") }}'
+       continue_pattern: '{{ task.multiline.continue_pattern | default("", true) | replace("'", "''") }}'
+       timeout: '{{ task.multiline.timeout | default("3s", true) }}'
+       max_lines: {{ task.multiline.max_lines | default(500, true) | int }}{% endif %}
 {% endfor %}
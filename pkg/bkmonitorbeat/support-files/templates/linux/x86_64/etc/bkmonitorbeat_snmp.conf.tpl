@@ -0,0 +1,67 @@
+# SNMP 轮询采集配置模板
+type: snmp
+name: {{ config_name | default("snmp_task", true) }}
+version: {{ config_version| default("1.1.1", true) }}
+
+# 配置框架需要，这里补充0，实际dataid在tasks下
+dataid: 0
+
+tasks: {% for task in tasks %}
+   - task_id: {{ task.task_id }}
+     bk_biz_id: {{ task.bk_biz_id }}
+     dataid: {{ task.dataid | int }}
+     period: {{ task.period | default('1m', true) }}
+     timeout: {{ task.timeout | default('10s', true) }}
+     # 设备地址，未指定端口时使用 port
+     targets: {% for target in task.targets %}
+        - {{ target }}{% endfor %}
+     port: {{ task.port | default(161, true) }}
+     # 版本，v1 v2c v3
+     snmp_version: {{ task.snmp_version | default('v2c', true) }}
+     # 团体名
+     community: {{ task.community }}
+     retries: {{ task.retries | default(1, true) }}
+     # 表格遍历方式，walk 或者 bulkwalk，v1 只支持 walk
+     walk_mode: {{ task.walk_mode | default('bulkwalk', true) }}
+     max_repetitions: {{ task.max_repetitions | default(10, true) }}
+     # MIB 文件目录，配置后 oid 可以使用符号名（如 IF-MIB::ifInOctets），维度值按照 MIB 格式化
+     mib_dirs: {% for dir in task.mib_dirs %}
+        - {{ dir }}{% endfor %}
+     # 标量指标，通过 GET 采集
+     # type 为 gauge 或者 counter，counter 会额外上报 <name>_rate 每秒增长速率
+     metrics: {% for metric in task.metrics %}
+        - name: {{ metric.name }}
+          oid: "{{ metric.oid }}"
+          type: {{ metric.type | default('gauge', true) }}{% endfor %}
+     # 表格指标，通过 WALK/BULKWALK 采集，相同索引的列组成一行，索引以及维度列作为维度上报
+     tables: {% for table in task.tables %}
+        - index_name: {{ table.index_name | default('index', true) }}
+          metrics: {% for metric in table.metrics %}
+            - name: {{ metric.name }}
+              oid: "{{ metric.oid }}"
+              type: {{ metric.type | default('gauge', true) }}{% endfor %}
+          dimensions: {% for dimension in table.dimensions %}
+            - name: {{ dimension.name }}
+              oid: "{{ dimension.oid }}"{% endfor %}{% endfor %}
+     # ==============  下面字段为 v3 专享  ==========
+     {%- if task.usm_info %}
+     usm_info:
+        # 上下文信息
+        context_name: {{ task.usm_info.context_name }}
+        # 消息标识位，authpriv authnopriv noauthnopriv三种
+        msg_flags: {{ task.usm_info.msg_flags }}
+        # USM配置信息
+        usm_config:
+           username: {{ task.usm_info.usm_config.username }}
+           # noauth, md5, sha, sha224, sha256, sha384, sha512  可选
+           authentication_protocol: {{ task.usm_info.usm_config.authentication_protocol }}
+           authentication_passphrase: {{ task.usm_info.usm_config.authentication_passphrase }}
+           # nopriv, des, aes, aes192, aes256, aes192c, aes256c 可选
+           privacy_protocol: {{ task.usm_info.usm_config.privacy_protocol }}
+           privacy_passphrase: {{ task.usm_info.usm_config.privacy_passphrase }}
+           authoritative_engineID: {{ task.usm_info.usm_config.authoritative_engineID }}{% endif %}
+     # 注入的labels
+     labels: {% for label in task.labels %}
+        {% for key, value in label.items() %}{{ "-" if loop.first else " "  }} {{ key }}: "{{ value }}"
+        {% endfor %}{% endfor %}
+{% endfor %}
@@ -42,6 +42,11 @@ tasks: {% for task in tasks or get_hosts_by_node(config_hosts) %}
     response: {{ task.response or response or '' }}
     # 内容匹配方式
     response_format: {{ (task.response_format or response_format) | default("eq", true) }}
+    {%- if task.tls %}
+    # TLS 握手并上报证书信息
+    tls: true
+    server_name: {{ task.server_name or '' }}
+    insecure_skip_verify: {{ task.insecure_skip_verify | default(false, true) | lower }}{% endif %}
     {%- if task.labels and custom_report == "true" %}
     labels:
     {%- for key, value in task.labels.items() %}
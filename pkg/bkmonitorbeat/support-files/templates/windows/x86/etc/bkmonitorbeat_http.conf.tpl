@@ -25,6 +25,13 @@ tasks: {% for task in tasks %}
     {%- if custom_report == "true" %}
     # 是否自定义上报
     custom_report: {{ custom_report | default("false", true) }}{% endif %}
+    {%- if task.transaction %}
+    # 事务模式，按顺序执行步骤并共享 cookie 以及变量
+    transaction: true
+    {%- if task.variables %}
+    variables: {% for key,value in task.variables.items() %}
+      {{ key }}: "{{ value }}"{% endfor %}
+    {%- endif %}{% endif %}
     # 采集步骤
     steps: {% for step in task.steps %}
       - method: {{ step.method }}
@@ -44,7 +51,23 @@ tasks: {% for task in tasks %}
         response: {{ step.response or '' }}
         # 内容匹配方式
         response_format: {{ step.response_format | default("eq", true) }}
-        response_code: {{ step.response_code }}{% endfor %}
+        response_code: {{ step.response_code }}
+        {%- if step.extractors %}
+        # 变量提取（json/regex/header/cookie），后续步骤通过 ${name} 引用
+        extractors: {% for extractor in step.extractors %}
+          - name: {{ extractor.name }}
+            type: {{ extractor.type }}
+            expr: '{{ extractor.expr }}'
+            default: '{{ extractor.default or '' }}'{% endfor %}
+        {%- endif %}
+        {%- if step.assertions %}
+        # 断言（json/header/body/status_code/response_time）
+        assertions: {% for assertion in step.assertions %}
+          - type: {{ assertion.type }}
+            expr: '{{ assertion.expr or '' }}'
+            operator: {{ assertion.operator | default("eq", true) }}
+            value: '{{ assertion.value or '' }}'{% endfor %}
+        {%- endif %}{% endfor %}
     {%- if task.labels and custom_report == "true" %}
     labels:
     {%- for key, value in task.labels.items() %}
@@ -73,7 +73,7 @@ bkmonitorbeat:
   metrics_batch_size: 1024
   # 管理服务，包含指标和调试接口, 可动态reload开关或变更监听地址（unix使用SIGUSR2,windows发送bkreload2）
   # admin_addr: localhost:56060
-  # 并发限制，按照任务类型区分(http, tcp, udp, ping)，分为per_instance单实例限制和per_task单任务限制
+  # 并发限制，按照任务类型区分(http, tcp, udp, dns, grpc, ping)，分为per_instance单实例限制和per_task单任务限制
   concurrency_limit:
     task:
       http:
@@ -85,6 +85,12 @@ bkmonitorbeat:
       udp:
         per_instance: 100000
         per_task: 1000
+      dns:
+        per_instance: 100000
+        per_task: 1000
+      grpc:
+        per_instance: 100000
+        per_task: 1000
       ping:
         per_instance: 100000
         per_task: 1000
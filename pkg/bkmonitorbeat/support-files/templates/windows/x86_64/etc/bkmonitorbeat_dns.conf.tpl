@@ -0,0 +1,55 @@
+# 子配置信息
+type: dns
+name: {{ config_name | default("dns_task", true) }}
+version: {{ config_version| default("1.1.1", true) }}
+
+dataid: {{ data_id }}
+# 缓冲区最大空间
+max_buffer_size: {{ max_buffer_size | default(10240, true) }}
+# 最大超时时间
+max_timeout: {{ max_timeout | default("30s", true) }}
+# 最小检测间隔
+min_period: {{ min_period | default("3s", true) }}
+# 任务列表
+tasks: {% for task in tasks %}
+  - task_id: {{ task.task_id or task_id }}
+    bk_biz_id: {{ task.bk_biz_id or bk_biz_id }}
+    target_ip_type: {{ task.target_ip_type | default(0, true) }}
+    dns_check_mode: {{ task.dns_check_mode | default("single", true) }}
+    period: {{ task.period or period }}
+    # 检测超时（connect+query总共时间）
+    timeout: {{ (task.timeout or timeout) | default("3s", true) }}
+    {%- if custom_report == "true" %}
+    # 是否自定义上报
+    custom_report: {{ custom_report | default("false", true) }}{% endif %}
+    # DNS 服务器，为空时使用系统解析
+    target_host: {{ task.target_host or '' }}
+    # 当配置的target_host_list不为空时，使用target_host_list，忽略target_host
+    target_host_list: {% if task.target_host_list %}{% for target_host in task.target_host_list %}
+    - {{ target_host }}{% endfor %}{% endif %}
+    # DNS 服务器端口，为空时 udp/tcp 使用 53，dot 使用 853
+    target_port: {{ task.target_port | default(0, true) }}
+    available_duration: {{ task.available_duration or available_duration }}
+    # 查询的域名
+    domain: {{ task.domain }}
+    # 记录类型（A/AAAA/CNAME/MX/TXT/SRV）
+    query_type: {{ task.query_type | default("A", true) }}
+    # 查询协议（udp/tcp/dot）
+    protocol: {{ task.protocol | default("udp", true) }}
+    {%- if task.protocol == "dot" %}
+    server_name: {{ task.server_name or '' }}
+    insecure_skip_verify: {{ task.insecure_skip_verify | default(false, true) | lower }}{% endif %}
+    # 期望的响应码
+    expected_rcode: {{ task.expected_rcode | default("NOERROR", true) }}
+    # 期望的解析结果，为空时不检查
+    expected_answers: {% if task.expected_answers %}{% for answer in task.expected_answers %}
+    - "{{ answer }}"{% endfor %}{% endif %}
+    # 解析结果匹配方式（all/any/exact）
+    match_mode: {{ task.match_mode | default("all", true) }}
+    {%- if task.labels and custom_report == "true" %}
+    labels:
+    {%- for key, value in task.labels.items() %}
+    {{"-" if loop.first else " "}} {{ key }}: "{{ value }}"
+    {%- endfor %}
+    {% endif %}
+{%- endfor %}
@@ -26,6 +26,9 @@ tasks: {% for task in tasks %}
      # oid事件指标map
      oids: {% for key, value in task.oids.items() %}
         "{{ key }}": "{{ value }}"{% endfor %}
+     # MIB 文件目录，oid 以及枚举值会按照 MIB 自动翻译，oids 中的配置优先
+     mib_dirs: {% for dir in task.mib_dirs %}
+        - {{ dir }}{% endfor %}
      # ==============  下面字段为 v3 专享  ==========
      # 多用户配置
      usm_info: {% for usm in task.usm_info %}
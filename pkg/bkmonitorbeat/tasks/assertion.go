@@ -0,0 +1,85 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tasks
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+// ParseMilliseconds 解析时间为毫秒，支持 500ms 这样的时间格式，纯数字时单位为毫秒
+func ParseMilliseconds(s string) (float64, bool) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return float64(d) / float64(time.Millisecond), true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// MatchValue 按照操作符比较实际值与期望值，exists 表示实际值是否存在
+func MatchValue(operator, actual string, exists bool, expected string) bool {
+	switch operator {
+	case configs.HTTPOperatorExists:
+		return exists
+	case configs.HTTPOperatorNExists:
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+
+	switch operator {
+	case configs.HTTPOperatorEq:
+		return actual == expected
+	case configs.HTTPOperatorNq:
+		return actual != expected
+	case configs.HTTPOperatorContains:
+		return strings.Contains(actual, expected)
+	case configs.HTTPOperatorNContains:
+		return !strings.Contains(actual, expected)
+	case configs.HTTPOperatorReg:
+		re, err := regexp.Compile(expected)
+		return err == nil && re.MatchString(actual)
+	case configs.HTTPOperatorGt, configs.HTTPOperatorGe, configs.HTTPOperatorLt, configs.HTTPOperatorLe:
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false
+		}
+		e, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false
+		}
+		return CompareNumber(operator, a, e)
+	}
+	return false
+}
+
+// CompareNumber 数值比较
+func CompareNumber(operator string, actual, expected float64) bool {
+	switch operator {
+	case configs.HTTPOperatorEq:
+		return actual == expected
+	case configs.HTTPOperatorNq:
+		return actual != expected
+	case configs.HTTPOperatorGt:
+		return actual > expected
+	case configs.HTTPOperatorGe:
+		return actual >= expected
+	case configs.HTTPOperatorLt:
+		return actual < expected
+	case configs.HTTPOperatorLe:
+		return actual <= expected
+	}
+	return false
+}
@@ -56,6 +56,13 @@ func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
 		cfg.Net.StatTimes = configs.DefaultBasereportConfig.Net.StatTimes
 	}
 
+	if cfg.Cgroup.Root == "" {
+		cfg.Cgroup.Root = configs.DefaultBasereportConfig.Cgroup.Root
+	}
+	if cfg.Cgroup.MaxCount <= 0 {
+		cfg.Cgroup.MaxCount = configs.DefaultBasereportConfig.Cgroup.MaxCount
+	}
+
 	// 计算出每次调用的时间间隔
 	cfg.Cpu.StatPeriod = cfg.Period / time.Duration(cfg.Cpu.StatTimes)
 	cfg.Disk.StatPeriod = cfg.Period / time.Duration(cfg.Disk.StatTimes)
@@ -178,6 +185,9 @@ func (g *Gather) fastRunOnce() {
 	cfg.Net.RevertProtectNumber = g.config.Net.RevertProtectNumber
 	cfg.Mem.SpecialSource = g.config.Mem.SpecialSource
 
+	// 同步 cgroup 配置，首次采集作为 cpu 使用率的基准
+	cfg.Cgroup = g.config.Cgroup
+
 	logger.Infof("basereport.fastRunOnce.config: %+v", cfg)
 	// 计算出每次调用的时间间隔
 	collector.Collect(cfg, true)
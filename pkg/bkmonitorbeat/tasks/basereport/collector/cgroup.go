@@ -0,0 +1,423 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+// hostPressureDir 主机级别的 PSI 信息
+const hostPressureDir = "/proc/pressure"
+
+var (
+	// 容器 cgroup 的目录名，如 docker-<id>.scope、cri-containerd-<id>.scope、crio-<id>.scope 或者直接为 <id>
+	containerIDRegex = regexp.MustCompile(`^(?:(docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+	// pod 的 cgroup 目录名，systemd 驱动下 uid 中的 - 会被替换为 _
+	podUIDRegex = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+	containerRuntimes = map[string]string{
+		"docker":         "docker",
+		"cri-containerd": "containerd",
+		"crio":           "crio",
+		"libpod":         "podman",
+	}
+)
+
+// PressureStat PSI 单行数据，avg 为百分比，total 为累计阻塞时间（微秒）
+type PressureStat struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// Pressure some 表示至少有一个任务阻塞，full 表示所有任务均阻塞
+type Pressure struct {
+	Some PressureStat `json:"some"`
+	Full PressureStat `json:"full"`
+}
+
+type PressureReport struct {
+	Cpu    *Pressure `json:"cpu"`
+	Memory *Pressure `json:"memory"`
+	IO     *Pressure `json:"io"`
+}
+
+type CgroupCpuStat struct {
+	UsageUsec     uint64 `json:"usage_usec"`
+	UserUsec      uint64 `json:"user_usec"`
+	SystemUsec    uint64 `json:"system_usec"`
+	NrPeriods     uint64 `json:"nr_periods"`
+	NrThrottled   uint64 `json:"nr_throttled"`
+	ThrottledUsec uint64 `json:"throttled_usec"`
+	// Usage 两次采集之间的 cpu 使用率，100 表示使用了一个核
+	Usage float64 `json:"usage"`
+	// Limit cpu.max 限制的核数，0 表示不限制
+	Limit float64 `json:"limit"`
+}
+
+type CgroupMemStat struct {
+	Current uint64 `json:"current"`
+	// Limit memory.max 限制的字节数，0 表示不限制
+	Limit   uint64 `json:"limit"`
+	OOM     uint64 `json:"oom"`
+	OOMKill uint64 `json:"oom_kill"`
+}
+
+type CgroupIOStat struct {
+	ReadBytes  uint64 `json:"rbytes"`
+	WriteBytes uint64 `json:"wbytes"`
+	ReadIOs    uint64 `json:"rios"`
+	WriteIOs   uint64 `json:"wios"`
+}
+
+type CgroupStat struct {
+	Path        string          `json:"path"`
+	ContainerID string          `json:"container_id"`
+	Runtime     string          `json:"runtime"`
+	PodUID      string          `json:"pod_uid"`
+	Cpu         CgroupCpuStat   `json:"cpu"`
+	Mem         CgroupMemStat   `json:"mem"`
+	IO          CgroupIOStat    `json:"io"`
+	Pressure    *PressureReport `json:"pressure"`
+}
+
+type CgroupReport struct {
+	Stats []*CgroupStat `json:"stats"`
+}
+
+type cpuSample struct {
+	usage uint64
+	ts    time.Time
+}
+
+var (
+	lastCgroupCpuMut sync.Mutex
+	lastCgroupCpu    = make(map[string]cpuSample)
+)
+
+// readKeyValues 读取 key value 格式的文件，如 cpu.stat、memory.events
+func readKeyValues(path string) (map[string]uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values, nil
+}
+
+// readSingleValue 读取单个数值的文件，max 表示不限制返回 0
+func readSingleValue(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parsePressure 解析 PSI 文件
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+// full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(b []byte) (*Pressure, error) {
+	var p Pressure
+	var found bool
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var stat *PressureStat
+		switch fields[0] {
+		case "some":
+			stat = &p.Some
+		case "full":
+			stat = &p.Full
+		default:
+			continue
+		}
+		found = true
+
+		for _, field := range fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			var err error
+			switch k {
+			case "avg10":
+				stat.Avg10, err = strconv.ParseFloat(v, 64)
+			case "avg60":
+				stat.Avg60, err = strconv.ParseFloat(v, 64)
+			case "avg300":
+				stat.Avg300, err = strconv.ParseFloat(v, 64)
+			case "total":
+				stat.Total, err = strconv.ParseUint(v, 10, 64)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse pressure field %s", field)
+			}
+		}
+	}
+
+	if !found {
+		return nil, errors.New("no pressure data found")
+	}
+	return &p, nil
+}
+
+// readPressureReport 读取目录下的 cpu.pressure、memory.pressure、io.pressure
+// 内核未开启 PSI 时文件不存在，返回空
+func readPressureReport(dir, suffix string) *PressureReport {
+	var report PressureReport
+	var found bool
+	for name, p := range map[string]**Pressure{
+		"cpu":    &report.Cpu,
+		"memory": &report.Memory,
+		"io":     &report.IO,
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name+suffix))
+		if err != nil {
+			continue
+		}
+		pressure, err := parsePressure(b)
+		if err != nil {
+			continue
+		}
+		*p = pressure
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return &report
+}
+
+// GetHostPressure 获取主机级别的 PSI 信息，不支持时返回空
+func GetHostPressure() *PressureReport {
+	return readPressureReport(hostPressureDir, "")
+}
+
+// parseCpuMax 解析 cpu.max，格式为 `$MAX $PERIOD`，返回限制的核数
+func parseCpuMax(b []byte) float64 {
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || period == 0 {
+		return 0
+	}
+	return quota / period
+}
+
+// parseIOStat 解析 io.stat 并累加所有设备的数据
+// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func parseIOStat(b []byte) CgroupIOStat {
+	var stat CgroupIOStat
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				stat.ReadBytes += n
+			case "wbytes":
+				stat.WriteBytes += n
+			case "rios":
+				stat.ReadIOs += n
+			case "wios":
+				stat.WriteIOs += n
+			}
+		}
+	}
+	return stat
+}
+
+// parseContainer 根据 cgroup 路径解析容器 id、容器运行时以及 pod uid
+func parseContainer(path string) (string, string, string) {
+	var podUID string
+	if match := podUIDRegex.FindStringSubmatch(path); len(match) == 2 {
+		podUID = strings.ReplaceAll(match[1], "_", "-")
+	}
+
+	match := containerIDRegex.FindStringSubmatch(filepath.Base(path))
+	if len(match) != 3 {
+		return "", "", podUID
+	}
+
+	runtime := containerRuntimes[match[1]]
+	if runtime == "" {
+		// cgroupfs 驱动下目录名即容器 id，通过上级目录判断运行时
+		if strings.Contains(path, "docker") {
+			runtime = "docker"
+		}
+	}
+	return match[2], runtime, podUID
+}
+
+// readCgroupStat 读取单个 cgroup 的资源使用情况
+func readCgroupStat(dir string) (*CgroupStat, error) {
+	var stat CgroupStat
+
+	cpuStat, err := readKeyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	stat.Cpu = CgroupCpuStat{
+		UsageUsec:     cpuStat["usage_usec"],
+		UserUsec:      cpuStat["user_usec"],
+		SystemUsec:    cpuStat["system_usec"],
+		NrPeriods:     cpuStat["nr_periods"],
+		NrThrottled:   cpuStat["nr_throttled"],
+		ThrottledUsec: cpuStat["throttled_usec"],
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		stat.Cpu.Limit = parseCpuMax(b)
+	}
+
+	// memory/io 控制器可能未开启，忽略读取失败
+	stat.Mem.Current, _ = readSingleValue(filepath.Join(dir, "memory.current"))
+	stat.Mem.Limit, _ = readSingleValue(filepath.Join(dir, "memory.max"))
+	if events, err := readKeyValues(filepath.Join(dir, "memory.events")); err == nil {
+		stat.Mem.OOM = events["oom"]
+		stat.Mem.OOMKill = events["oom_kill"]
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		stat.IO = parseIOStat(b)
+	}
+
+	stat.Pressure = readPressureReport(dir, ".pressure")
+	return &stat, nil
+}
+
+// walkCgroups 遍历 cgroup v2 层级，识别出容器后不再遍历容器内部的子 cgroup
+func walkCgroups(config configs.CgroupConfig) ([]*CgroupStat, error) {
+	root := config.Root
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, errors.Wrapf(err, "cgroup v2 is not mounted at %s", root)
+	}
+
+	var stats []*CgroupStat
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// cgroup 在遍历过程中可能被删除
+			if path != root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if config.MaxCount > 0 && len(stats) >= config.MaxCount {
+			return fs.SkipAll
+		}
+
+		rel := "/" + strings.TrimPrefix(path, root+string(filepath.Separator))
+		containerID, runtime, podUID := parseContainer(rel)
+		if containerID == "" && !config.ReportAll {
+			return nil
+		}
+
+		stat, err := readCgroupStat(path)
+		if err != nil {
+			return nil
+		}
+		stat.Path = rel
+		stat.ContainerID = containerID
+		stat.Runtime = runtime
+		stat.PodUID = podUID
+		stats = append(stats, stat)
+
+		if containerID != "" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetCgroupInfo 获取 cgroup v2 各层级的资源使用情况，cpu 使用率由两次采集的差值计算
+func GetCgroupInfo(config configs.CgroupConfig) (*CgroupReport, error) {
+	stats, err := walkCgroups(config)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lastCgroupCpuMut.Lock()
+	defer lastCgroupCpuMut.Unlock()
+
+	samples := make(map[string]cpuSample, len(stats))
+	for _, stat := range stats {
+		if last, ok := lastCgroupCpu[stat.Path]; ok {
+			elapsed := now.Sub(last.ts).Microseconds()
+			if elapsed > 0 {
+				stat.Cpu.Usage = float64(CounterDiff(stat.Cpu.UsageUsec, last.usage)) / float64(elapsed) * 100
+			}
+		}
+		samples[stat.Path] = cpuSample{usage: stat.Cpu.UsageUsec, ts: now}
+	}
+	// 只保留本次存在的 cgroup，避免已销毁的容器一直占用内存
+	lastCgroupCpu = samples
+
+	return &CgroupReport{Stats: stats}, nil
+}
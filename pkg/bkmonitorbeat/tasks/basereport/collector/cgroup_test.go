@@ -0,0 +1,164 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+const testContainerID = "3f2a9c1d5e7b4a6c8d0e2f4a6b8c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8f0a2b4c"
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure([]byte("some avg10=1.50 avg60=0.80 avg300=0.20 total=123456\nfull avg10=0.50 avg60=0.10 avg300=0.00 total=789\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, PressureStat{Avg10: 1.5, Avg60: 0.8, Avg300: 0.2, Total: 123456}, p.Some)
+	assert.Equal(t, PressureStat{Avg10: 0.5, Avg60: 0.1, Total: 789}, p.Full)
+
+	_, err = parsePressure([]byte("some avg10=x"))
+	assert.Error(t, err)
+
+	_, err = parsePressure([]byte(""))
+	assert.Error(t, err)
+}
+
+func TestParseContainer(t *testing.T) {
+	testCases := map[string]struct {
+		path        string
+		containerID string
+		runtime     string
+		podUID      string
+	}{
+		"docker systemd 驱动": {
+			path:        "/system.slice/docker-" + testContainerID + ".scope",
+			containerID: testContainerID,
+			runtime:     "docker",
+		},
+		"docker cgroupfs 驱动": {
+			path:        "/docker/" + testContainerID,
+			containerID: testContainerID,
+			runtime:     "docker",
+		},
+		"kubernetes containerd": {
+			path:        "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0f3e1a2b_4c5d_6e7f_8a9b_0c1d2e3f4a5b.slice/cri-containerd-" + testContainerID + ".scope",
+			containerID: testContainerID,
+			runtime:     "containerd",
+			podUID:      "0f3e1a2b-4c5d-6e7f-8a9b-0c1d2e3f4a5b",
+		},
+		"pod 层级": {
+			path:   "/kubepods/besteffort/pod0f3e1a2b-4c5d-6e7f-8a9b-0c1d2e3f4a5b",
+			podUID: "0f3e1a2b-4c5d-6e7f-8a9b-0c1d2e3f4a5b",
+		},
+		"普通服务": {
+			path: "/system.slice/sshd.service",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			containerID, runtime, podUID := parseContainer(c.path)
+			assert.Equal(t, c.containerID, containerID)
+			assert.Equal(t, c.runtime, runtime)
+			assert.Equal(t, c.podUID, podUID)
+		})
+	}
+}
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestGetCgroupInfo(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "cpu memory io",
+		"cpu.stat":           "usage_usec 999999",
+	})
+	writeCgroupFiles(t, filepath.Join(root, "system.slice", "sshd.service"), map[string]string{
+		"cpu.stat": "usage_usec 100",
+	})
+
+	containerDir := filepath.Join(root, "system.slice", "docker-"+testContainerID+".scope")
+	writeCgroupFiles(t, containerDir, map[string]string{
+		"cpu.stat":        "usage_usec 5000\nuser_usec 3000\nsystem_usec 2000\nnr_periods 10\nnr_throttled 2\nthrottled_usec 400",
+		"cpu.max":         "150000 100000",
+		"memory.current":  "1048576",
+		"memory.max":      "max",
+		"memory.events":   "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1",
+		"io.stat":         "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=3 wios=4 dbytes=0 dios=0",
+		"cpu.pressure":    "some avg10=2.00 avg60=1.00 avg300=0.50 total=100\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0",
+		"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0",
+	})
+	// 容器内部的子 cgroup 不再单独上报
+	writeCgroupFiles(t, filepath.Join(containerDir, "init.scope"), map[string]string{
+		"cpu.stat": "usage_usec 1",
+	})
+
+	conf := configs.CgroupConfig{Root: root}
+	report, err := GetCgroupInfo(conf)
+	assert.NoError(t, err)
+	assert.Len(t, report.Stats, 1)
+
+	stat := report.Stats[0]
+	assert.Equal(t, "/system.slice/docker-"+testContainerID+".scope", stat.Path)
+	assert.Equal(t, testContainerID, stat.ContainerID)
+	assert.Equal(t, "docker", stat.Runtime)
+	assert.Equal(t, CgroupCpuStat{
+		UsageUsec:     5000,
+		UserUsec:      3000,
+		SystemUsec:    2000,
+		NrPeriods:     10,
+		NrThrottled:   2,
+		ThrottledUsec: 400,
+		Limit:         1.5,
+	}, stat.Cpu)
+	assert.Equal(t, CgroupMemStat{Current: 1048576, OOM: 2, OOMKill: 1}, stat.Mem)
+	assert.Equal(t, CgroupIOStat{ReadBytes: 110, WriteBytes: 220, ReadIOs: 4, WriteIOs: 6}, stat.IO)
+	assert.Equal(t, 2.0, stat.Pressure.Cpu.Some.Avg10)
+	assert.NotNil(t, stat.Pressure.Memory)
+	assert.Nil(t, stat.Pressure.IO)
+
+	// 第二次采集根据差值计算 cpu 使用率
+	writeCgroupFiles(t, containerDir, map[string]string{
+		"cpu.stat": "usage_usec 1000000000",
+	})
+	report, err = GetCgroupInfo(conf)
+	assert.NoError(t, err)
+	assert.Greater(t, report.Stats[0].Cpu.Usage, 0.0)
+
+	// 上报所有 cgroup，没有 cpu.stat 的层级会被忽略
+	conf.ReportAll = true
+	report, err = GetCgroupInfo(conf)
+	assert.NoError(t, err)
+	var paths []string
+	for _, s := range report.Stats {
+		paths = append(paths, s.Path)
+	}
+	assert.Equal(t, "/system.slice/docker-"+testContainerID+".scope,/system.slice/sshd.service", strings.Join(paths, ","))
+
+	conf.MaxCount = 1
+	report, err = GetCgroupInfo(conf)
+	assert.NoError(t, err)
+	assert.Len(t, report.Stats, 1)
+}
+
+func TestGetCgroupInfoNotV2(t *testing.T) {
+	_, err := GetCgroupInfo(configs.CgroupConfig{Root: t.TempDir()})
+	assert.Error(t, err)
+}
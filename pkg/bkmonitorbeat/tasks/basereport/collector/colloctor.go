@@ -226,6 +226,14 @@ func Collect(config configs.BasereportConfig, firstRun bool) (ReportData, error)
 		data.Load = nil
 	}
 
+	if config.Cgroup.Enabled {
+		data.Cgroup, err = GetCgroupInfo(config.Cgroup)
+		if err != nil {
+			logger.Errorf("collector cgroup info failed: %v", err)
+			data.Cgroup = nil
+		}
+	}
+
 	// 默认赋值一个env的内容，防止数据依赖方使用了jsonschema等检查工具引发异常报错
 	logger.Debug("env report is enable at least one config, will report it.")
 	if !envJob.Running() {
@@ -261,6 +269,7 @@ type ReportData struct {
 	Mem    *MemReport    `json:"mem"`
 	Net    *NetReport    `json:"net"`
 	System *SystemReport `json:"system"`
+	Cgroup *CgroupReport `json:"cgroup,omitempty"`
 }
 
 func CounterDiff(now, before uint64) uint64 {
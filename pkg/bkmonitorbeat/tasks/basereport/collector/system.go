@@ -25,6 +25,8 @@ type BKInfoStat struct {
 
 type SystemReport struct {
 	Info BKInfoStat `json:"info"`
+	// Pressure 主机级别的 PSI 信息，内核不支持时为空
+	Pressure *PressureReport `json:"pressure"`
 }
 
 // osSystemType 运行时不会发生变更 可以缓存
@@ -50,6 +52,7 @@ func GetSystemInfo() (*SystemReport, error) {
 
 	// get system type, 32-bit or 64-bit or unknown
 	report.Info.SystemType = osSystemType
+	report.Pressure = GetHostPressure()
 	return &report, nil
 }
 
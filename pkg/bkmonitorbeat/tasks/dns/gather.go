@@ -0,0 +1,365 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/output/gse"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// DNS 拨测状态码详情
+//
+// error_code:
+// CodeOK               -> 响应码以及解析结果均符合预期
+// CodeConnFailed       -> 连接 DNS 服务器失败
+// CodeConnTimeout      -> 连接 DNS 服务器超时
+// CodeRequestTimeout   -> 查询超时
+// CodeResponseFailed   -> 响应读取或者解析失败
+// CodeDNSResolveFailed -> 响应码与期望不一致
+// CodeResponseNotMatch -> 解析结果与期望不一致，可能存在劫持
+
+// SystemResolver 未配置 DNS 服务器时使用的系统解析
+var SystemResolver = net.DefaultResolver
+
+// Gather :
+type Gather struct {
+	tasks.BaseTask
+}
+
+// Event DNS 拨测事件，target_host 为 DNS 服务器，使用系统解析时为空
+type Event struct {
+	*tasks.SimpleEvent
+	Domain      string
+	QueryType   string
+	Protocol    string
+	Rcode       string
+	Answers     []string
+	ResolveTime time.Duration
+}
+
+// AsMapStr :
+func (e *Event) AsMapStr() common.MapStr {
+	mapStr := e.SimpleEvent.AsMapStr()
+	mapStr["domain"] = e.Domain
+	mapStr["query_type"] = e.QueryType
+	mapStr["protocol"] = e.Protocol
+	mapStr["rcode"] = e.Rcode
+	mapStr["answers"] = strings.Join(e.Answers, ",")
+	mapStr["answer_count"] = len(e.Answers)
+	mapStr["resolve_time"] = int(e.ResolveTime.Milliseconds())
+	return mapStr
+}
+
+// GetType :
+func (e *Event) GetType() string {
+	return define.ModuleDNS
+}
+
+// NewCustomEventByDNSEvent 通过 DNS 事件创建自定义事件
+func NewCustomEventByDNSEvent(e *Event) *tasks.CustomEvent {
+	ts := e.StartAt.Unix()
+	info, _ := gse.GetAgentInfo()
+
+	dimensions := map[string]string{
+		"bk_biz_id":   strconv.Itoa(int(e.BizID)),
+		"target_host": e.TargetHost,
+		"target_port": strconv.Itoa(e.TargetPort),
+		"task_id":     strconv.Itoa(int(e.TaskID)),
+		"task_type":   e.TaskType,
+		"status":      strconv.Itoa(int(e.Status)),
+		"resolved_ip": e.ResolvedIP,
+		"error_code":  strconv.Itoa(e.ErrorCode.Code()),
+		"domain":      e.Domain,
+		"query_type":  e.QueryType,
+		"protocol":    e.Protocol,
+		"rcode":       e.Rcode,
+		"node_id":     fmt.Sprintf("%d:%s", info.Cloudid, info.IP),
+		"ip":          info.IP,
+		"bk_cloud_id": strconv.Itoa(int(info.Cloudid)),
+		"bk_agent_id": info.BKAgentID,
+	}
+	metrics := map[string]interface{}{
+		"available":     e.Available,
+		"task_duration": int(e.TaskDuration().Milliseconds()),
+		"resolve_time":  int(e.ResolveTime.Milliseconds()),
+		"answer_count":  len(e.Answers),
+	}
+
+	if e.TLS != nil {
+		for k, v := range e.TLS.Dimensions() {
+			dimensions[k] = v
+		}
+		for k, v := range e.TLS.Metrics() {
+			metrics[k] = v
+		}
+	}
+
+	data := common.MapStr{
+		"dataid": e.DataID,
+		"data": []map[string]interface{}{
+			{
+				"target":    e.Domain,
+				"dimension": dimensions,
+				"metrics":   metrics,
+				"timestamp": ts * 1000,
+			},
+		},
+		"time":      ts,
+		"timestamp": ts,
+	}
+	return tasks.NewCustomEvent(e.GetType(), data, e.IgnoreCMDBLevel(), e.Labels)
+}
+
+func (g *Gather) newEvent(taskConf *configs.DNSTaskConfig, taskHost string) *Event {
+	event := tasks.NewSimpleEvent(g)
+	event.StartAt = time.Now()
+	event.TargetHost = taskHost
+	if taskHost != "" {
+		event.TargetPort = taskConf.TargetPort
+	}
+	return &Event{
+		SimpleEvent: event,
+		Domain:      taskConf.Domain,
+		QueryType:   taskConf.QueryType,
+		Protocol:    taskConf.Protocol,
+	}
+}
+
+func isTimeout(err error) bool {
+	e, ok := err.(net.Error)
+	return ok && e.Timeout()
+}
+
+// dial 连接 DNS 服务器，dot 协议在连接之后进行 TLS 握手并记录证书信息
+func dial(ctx context.Context, taskConf *configs.DNSTaskConfig, network, host, address string, event *Event) (net.Conn, define.NamedCode) {
+	dialer := net.Dialer{Timeout: taskConf.Timeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		logger.Debugf("%v: connect %v fail: %v", taskConf.TaskID, address, err)
+		if isTimeout(err) {
+			return nil, define.CodeConnTimeout
+		}
+		return nil, define.CodeConnFailed
+	}
+	if taskConf.Protocol != configs.DNSProtocolDoT {
+		return conn, define.CodeOK
+	}
+
+	serverName := taskConf.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	// 握手时不校验证书，保证证书异常时依然可以获取到证书信息
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		logger.Debugf("%v: tls handshake with %v fail: %v", taskConf.TaskID, serverName, err)
+		_ = conn.Close()
+		if isTimeout(err) {
+			return nil, define.CodeConnTimeout
+		}
+		return nil, define.CodeTLSHandshakeFailed
+	}
+
+	state := tlsConn.ConnectionState()
+	event.TLS = tasks.NewTLSInfo(&state, serverName, nil)
+	if !taskConf.InsecureSkipVerify && !event.TLS.ChainValid {
+		logger.Debugf("%v: certificate of %v is invalid: %v", taskConf.TaskID, serverName, event.TLS.ChainError)
+		_ = tlsConn.Close()
+		return nil, define.CodeCertInvalid
+	}
+	return tlsConn, define.CodeOK
+}
+
+// query 向指定的 DNS 服务器发送查询，udp 响应被截断时使用 tcp 重试
+func query(ctx context.Context, taskConf *configs.DNSTaskConfig, host, ip string, event *Event) (*Result, define.NamedCode) {
+	qtype := queryTypes[taskConf.QueryType]
+	id := newQueryID()
+	msg, err := buildQuery(id, taskConf.Domain, qtype)
+	if err != nil {
+		logger.Warnf("%v: build dns query for %v failed: %v", taskConf.TaskID, taskConf.Domain, err)
+		return nil, define.CodeBadRequestParams
+	}
+
+	network := "tcp"
+	if taskConf.Protocol == configs.DNSProtocolUDP {
+		network = "udp"
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(taskConf.TargetPort))
+
+	for {
+		conn, code := dial(ctx, taskConf, network, host, address, event)
+		if code != define.CodeOK {
+			return nil, code
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		result, err := exchange(conn, network != "udp", id, qtype, msg)
+		_ = conn.Close()
+		if err != nil {
+			logger.Debugf("%v: query %v from %v fail: %v", taskConf.TaskID, taskConf.Domain, address, err)
+			if isTimeout(err) {
+				return nil, define.CodeRequestTimeout
+			}
+			return nil, define.CodeResponseFailed
+		}
+		if result.Truncated && network == "udp" {
+			network = "tcp"
+			continue
+		}
+		return result, define.CodeOK
+	}
+}
+
+// checkResult 检查响应码以及解析结果
+func checkResult(taskConf *configs.DNSTaskConfig, result *Result) define.NamedCode {
+	if RcodeName(result.Rcode) != taskConf.ExpectedRcode {
+		return define.CodeDNSResolveFailed
+	}
+
+	qtype := queryTypes[taskConf.QueryType]
+	expected := make([]string, 0, len(taskConf.ExpectedAnswers))
+	for _, e := range taskConf.ExpectedAnswers {
+		expected = append(expected, NormalizeAnswer(qtype, e))
+	}
+	if !MatchAnswers(taskConf.MatchMode, result.Answers, expected) {
+		return define.CodeResponseNotMatch
+	}
+	return define.CodeOK
+}
+
+// checkTarget 查询单个 DNS 服务器，ip 为空时使用系统解析
+func (g *Gather) checkTarget(ctx context.Context, taskConf *configs.DNSTaskConfig, host, ip string, event *Event) define.NamedCode {
+	ctx, cancel := context.WithTimeout(ctx, taskConf.Timeout)
+	defer cancel()
+
+	var (
+		result *Result
+		code   = define.CodeOK
+	)
+	start := time.Now()
+	if ip == "" {
+		var err error
+		result, err = lookup(ctx, SystemResolver, taskConf.Domain, queryTypes[taskConf.QueryType])
+		if err != nil {
+			logger.Debugf("%v: lookup %v fail: %v", taskConf.TaskID, taskConf.Domain, err)
+			code = define.CodeDNSResolveFailed
+			if isTimeout(err) {
+				code = define.CodeRequestTimeout
+			}
+		}
+	} else {
+		result, code = query(ctx, taskConf, host, ip, event)
+	}
+	event.ResolveTime = time.Since(start)
+	event.EndAt = time.Now()
+	if code != define.CodeOK {
+		return code
+	}
+
+	event.Rcode = RcodeName(result.Rcode)
+	event.Answers = result.Answers
+	logger.Debugf("%v: %v %v answers: %v, rcode: %v", taskConf.TaskID, taskConf.Domain, taskConf.QueryType, result.Answers, event.Rcode)
+	return checkResult(taskConf, result)
+}
+
+func (g *Gather) send(taskConf *configs.DNSTaskConfig, event *Event, e chan<- define.Event) {
+	// 如果需要使用自定义上报，则将事件转换为自定义事件
+	if taskConf.CustomReport {
+		e <- NewCustomEventByDNSEvent(event)
+	} else {
+		e <- event
+	}
+}
+
+// Run :
+func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
+	taskConf := g.TaskConfig.(*configs.DNSTaskConfig)
+	g.PreRun(ctx)
+	defer g.PostRun(ctx)
+
+	// 解析 DNS 服务器地址，未配置时使用系统解析
+	resultMap := make(map[string][]string)
+	hosts := taskConf.Hosts()
+	if len(hosts) == 0 {
+		resultMap[""] = []string{""}
+	} else {
+		hostsInfo := tasks.GetHostsInfo(ctx, hosts, taskConf.DNSCheckMode, taskConf.TargetIPType, configs.Tcp)
+		for _, h := range hostsInfo {
+			if h.Errno != define.CodeOK {
+				event := g.newEvent(taskConf, h.Host)
+				event.Fail(h.Errno)
+				g.send(taskConf, event, e)
+			} else {
+				resultMap[h.Host] = h.Ips
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for taskHost, result := range resultMap {
+		for _, targetHost := range result {
+			// 获取并发限制信号量
+			err := g.GetSemaphore().Acquire(ctx, 1)
+			if err != nil {
+				logger.Errorf("task(%d) semaphore acquire failed", g.TaskConfig.GetTaskID())
+				return
+			}
+
+			wg.Add(1)
+			go func(tHost, host string) {
+				event := g.newEvent(taskConf, tHost)
+				event.ResolvedIP = host
+
+				defer func() {
+					wg.Done()
+					g.GetSemaphore().Release(1)
+					g.send(taskConf, event, e)
+				}()
+
+				code := g.checkTarget(ctx, taskConf, tHost, host, event)
+				if code == define.CodeOK {
+					event.SuccessOrTimeout()
+				} else {
+					event.Fail(code)
+				}
+			}(taskHost, targetHost)
+		}
+	}
+	wg.Wait()
+}
+
+// New :
+func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
+	gather := &Gather{}
+	gather.GlobalConfig = globalConfig
+	gather.TaskConfig = taskConfig
+	gather.Init()
+
+	return gather
+}
@@ -0,0 +1,245 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+// answer 模拟 DNS 服务器，bk.example.com 返回 CNAME 以及 A 记录，其他域名返回 NXDOMAIN
+func answer(t *testing.T, req []byte) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(req)
+	assert.Nil(t, err)
+	q, err := p.Question()
+	assert.Nil(t, err)
+
+	h.Response = true
+	b := dnsmessage.NewBuilder(nil, h)
+	assert.Nil(t, b.StartQuestions())
+	assert.Nil(t, b.Question(q))
+	assert.Nil(t, b.StartAnswers())
+
+	switch strings.ToLower(q.Name.String()) {
+	case "bk.example.com.":
+		cname := dnsmessage.MustNewName("lb.example.com.")
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		assert.Nil(t, b.CNAMEResource(rh, dnsmessage.CNAMEResource{CNAME: cname}))
+		rh.Name = cname
+		assert.Nil(t, b.AResource(rh, dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}))
+		assert.Nil(t, b.AResource(rh, dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}))
+	default:
+		h.RCode = dnsmessage.RCodeNameError
+		b = dnsmessage.NewBuilder(nil, h)
+		assert.Nil(t, b.StartQuestions())
+		assert.Nil(t, b.Question(q))
+	}
+
+	resp, err := b.Finish()
+	assert.Nil(t, err)
+	return resp
+}
+
+func serveUDP(t *testing.T) (int, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		buf := make([]byte, maxUDPSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(answer(t, buf[:n]), addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port, func() { _ = conn.Close() }
+}
+
+func serveTCP(t *testing.T) (int, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err = io.ReadFull(conn, length[:]); err == nil {
+				req := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err = io.ReadFull(conn, req); err == nil {
+					resp := answer(t, req)
+					msg := make([]byte, 2+len(resp))
+					binary.BigEndian.PutUint16(msg, uint16(len(resp)))
+					copy(msg[2:], resp)
+					_, _ = conn.Write(msg)
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, func() { _ = ln.Close() }
+}
+
+func TestMatchAnswers(t *testing.T) {
+	testCases := map[string]struct {
+		mode     string
+		answers  []string
+		expected []string
+		ok       bool
+	}{
+		"未配置期望结果": {
+			mode:    configs.DNSMatchAll,
+			answers: []string{"10.0.0.1"},
+			ok:      true,
+		},
+		"全部在期望列表中": {
+			mode:     configs.DNSMatchAll,
+			answers:  []string{"10.0.0.1"},
+			expected: []string{"10.0.0.1", "10.0.0.2"},
+			ok:       true,
+		},
+		"存在非预期的解析结果": {
+			mode:     configs.DNSMatchAll,
+			answers:  []string{"10.0.0.1", "1.1.1.1"},
+			expected: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		"没有解析结果": {
+			mode:     configs.DNSMatchAll,
+			expected: []string{"10.0.0.1"},
+		},
+		"任意一个匹配": {
+			mode:     configs.DNSMatchAny,
+			answers:  []string{"10.0.0.1", "1.1.1.1"},
+			expected: []string{"10.0.0.1"},
+			ok:       true,
+		},
+		"完全一致": {
+			mode:     configs.DNSMatchExact,
+			answers:  []string{"10.0.0.2", "10.0.0.1"},
+			expected: []string{"10.0.0.1", "10.0.0.2"},
+			ok:       true,
+		},
+		"缺少期望的解析结果": {
+			mode:     configs.DNSMatchExact,
+			answers:  []string{"10.0.0.1"},
+			expected: []string{"10.0.0.1", "10.0.0.2"},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.ok, MatchAnswers(c.mode, c.answers, c.expected))
+		})
+	}
+}
+
+func TestNormalizeAnswer(t *testing.T) {
+	assert.Equal(t, "2001:db8::1", NormalizeAnswer(dnsmessage.TypeAAAA, "2001:0db8:0000::0001"))
+	assert.Equal(t, "lb.example.com", NormalizeAnswer(dnsmessage.TypeCNAME, "LB.Example.com."))
+	assert.Equal(t, "sip.example.com:5060", NormalizeAnswer(dnsmessage.TypeSRV, "sip.example.com.:5060"))
+	assert.Equal(t, "v=spf1 -all", NormalizeAnswer(dnsmessage.TypeTXT, "v=spf1 -all"))
+}
+
+func newGather(t *testing.T, taskConf *configs.DNSTaskConfig) *Gather {
+	globalConf := configs.NewConfig()
+	// 提供一个心跳的data_id，防止命中data_id防御机制
+	globalConf.HeartBeat.GlobalDataID = 1000
+
+	assert.Nil(t, globalConf.Clean())
+	assert.Nil(t, taskConf.Clean())
+	return New(globalConf, taskConf).(*Gather)
+}
+
+func TestGather(t *testing.T) {
+	udpPort, closeUDP := serveUDP(t)
+	defer closeUDP()
+	tcpPort, closeTCP := serveTCP(t)
+	defer closeTCP()
+
+	testCases := map[string]struct {
+		protocol  string
+		port      int
+		domain    string
+		expected  []string
+		rcode     string
+		errorCode define.NamedCode
+		answers   string
+	}{
+		"udp 查询": {
+			protocol:  configs.DNSProtocolUDP,
+			port:      udpPort,
+			domain:    "bk.example.com",
+			expected:  []string{"10.0.0.1", "10.0.0.2"},
+			rcode:     "NOERROR",
+			errorCode: define.CodeOK,
+			answers:   "10.0.0.1,10.0.0.2",
+		},
+		"tcp 查询": {
+			protocol:  configs.DNSProtocolTCP,
+			port:      tcpPort,
+			domain:    "BK.example.com.",
+			rcode:     "NOERROR",
+			errorCode: define.CodeOK,
+			answers:   "10.0.0.1,10.0.0.2",
+		},
+		"解析结果被劫持": {
+			protocol:  configs.DNSProtocolUDP,
+			port:      udpPort,
+			domain:    "bk.example.com",
+			expected:  []string{"10.0.0.1"},
+			rcode:     "NOERROR",
+			errorCode: define.CodeResponseNotMatch,
+			answers:   "10.0.0.1,10.0.0.2",
+		},
+		"域名不存在": {
+			protocol:  configs.DNSProtocolUDP,
+			port:      udpPort,
+			domain:    "not-exists.example.com",
+			rcode:     "NXDOMAIN",
+			errorCode: define.CodeDNSResolveFailed,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			taskConf := configs.NewDNSTaskConfig()
+			taskConf.TargetHost = "127.0.0.1"
+			taskConf.TargetPort = c.port
+			taskConf.Protocol = c.protocol
+			taskConf.Domain = c.domain
+			taskConf.ExpectedAnswers = c.expected
+			g := newGather(t, taskConf)
+
+			e := make(chan define.Event, 1)
+			g.Run(context.Background(), e)
+			g.Wait()
+
+			event := (<-e).AsMapStr()
+			assert.Equal(t, c.errorCode.Code(), event["error_code"])
+			assert.Equal(t, c.rcode, event["rcode"])
+			assert.Equal(t, c.answers, event["answers"])
+			assert.Equal(t, "127.0.0.1", event["target_host"])
+			assert.Equal(t, "A", event["query_type"])
+		})
+	}
+}
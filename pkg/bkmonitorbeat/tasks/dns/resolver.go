@@ -0,0 +1,322 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+// maxUDPSize udp 响应的最大长度
+const maxUDPSize = 65535
+
+var queryTypes = map[string]dnsmessage.Type{
+	configs.DNSQueryTypeA:     dnsmessage.TypeA,
+	configs.DNSQueryTypeAAAA:  dnsmessage.TypeAAAA,
+	configs.DNSQueryTypeCNAME: dnsmessage.TypeCNAME,
+	configs.DNSQueryTypeMX:    dnsmessage.TypeMX,
+	configs.DNSQueryTypeTXT:   dnsmessage.TypeTXT,
+	configs.DNSQueryTypeSRV:   dnsmessage.TypeSRV,
+}
+
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+// RcodeName 响应码的标准名称，未知的响应码返回数字
+func RcodeName(rcode dnsmessage.RCode) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return strconv.Itoa(int(rcode))
+}
+
+// Result 查询结果，Answers 只包含与查询类型一致的记录
+type Result struct {
+	Rcode     dnsmessage.RCode
+	Answers   []string
+	Truncated bool
+}
+
+func buildQuery(id uint16, domain string, qtype dnsmessage.Type) ([]byte, error) {
+	if !strings.HasSuffix(domain, ".") {
+		domain += "."
+	}
+	name, err := dnsmessage.NewName(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err = b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err = b.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+func parseResponse(id uint16, qtype dnsmessage.Type, msg []byte) (*Result, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		return nil, err
+	}
+	if h.ID != id || !h.Response {
+		return nil, errors.Errorf("unexpected response id %d", h.ID)
+	}
+
+	result := &Result{Rcode: h.RCode, Truncated: h.Truncated}
+	if err = p.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+	for {
+		ah, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 查询 A 记录时可能会先返回 CNAME 记录，只保留查询类型的记录
+		if ah.Type != qtype {
+			if err = p.SkipAnswer(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var answer string
+		switch ah.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = net.IP(r.A[:]).String()
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = net.IP(r.AAAA[:]).String()
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = r.CNAME.String()
+		case dnsmessage.TypeMX:
+			r, err := p.MXResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = r.MX.String()
+		case dnsmessage.TypeTXT:
+			r, err := p.TXTResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = strings.Join(r.TXT, "")
+		case dnsmessage.TypeSRV:
+			r, err := p.SRVResource()
+			if err != nil {
+				return nil, err
+			}
+			answer = net.JoinHostPort(r.Target.String(), strconv.Itoa(int(r.Port)))
+		default:
+			if err = p.SkipAnswer(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result.Answers = append(result.Answers, NormalizeAnswer(ah.Type, answer))
+	}
+	return result, nil
+}
+
+// NormalizeAnswer 统一解析结果的格式，用于与期望结果比较
+func NormalizeAnswer(qtype dnsmessage.Type, answer string) string {
+	switch qtype {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		if ip := net.ParseIP(answer); ip != nil {
+			return ip.String()
+		}
+	case dnsmessage.TypeTXT:
+		return answer
+	case dnsmessage.TypeSRV:
+		if host, port, err := net.SplitHostPort(answer); err == nil {
+			return net.JoinHostPort(strings.ToLower(strings.TrimSuffix(host, ".")), port)
+		}
+	}
+	return strings.ToLower(strings.TrimSuffix(answer, "."))
+}
+
+// exchange 通过已经建立的连接发送查询，tcp 以及 dot 协议的消息带有两个字节的长度前缀
+func exchange(conn net.Conn, stream bool, id uint16, qtype dnsmessage.Type, query []byte) (*Result, error) {
+	if !stream {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, maxUDPSize)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			result, err := parseResponse(id, qtype, buf[:n])
+			// 忽略 id 不匹配的过期响应，直到超时
+			if err != nil {
+				continue
+			}
+			return result, nil
+		}
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return parseResponse(id, qtype, resp)
+}
+
+func newQueryID() uint16 {
+	return uint16(rand.Intn(1 << 16))
+}
+
+// lookup 使用系统解析，根据错误类型模拟响应码
+func lookup(ctx context.Context, resolver *net.Resolver, domain string, qtype dnsmessage.Type) (*Result, error) {
+	var (
+		answers []string
+		err     error
+	)
+
+	switch qtype {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		network := "ip4"
+		if qtype == dnsmessage.TypeAAAA {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, network, domain)
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case dnsmessage.TypeCNAME:
+		var cname string
+		cname, err = resolver.LookupCNAME(ctx, domain)
+		if err == nil {
+			answers = append(answers, cname)
+		}
+	case dnsmessage.TypeMX:
+		var mxs []*net.MX
+		mxs, err = resolver.LookupMX(ctx, domain)
+		for _, mx := range mxs {
+			answers = append(answers, mx.Host)
+		}
+	case dnsmessage.TypeTXT:
+		answers, err = resolver.LookupTXT(ctx, domain)
+	case dnsmessage.TypeSRV:
+		var srvs []*net.SRV
+		_, srvs, err = resolver.LookupSRV(ctx, "", "", domain)
+		for _, srv := range srvs {
+			answers = append(answers, net.JoinHostPort(srv.Target, strconv.Itoa(int(srv.Port))))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported query type %v", qtype)
+	}
+
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || dnsErr.IsTimeout {
+			return nil, err
+		}
+		if dnsErr.IsNotFound {
+			return &Result{Rcode: dnsmessage.RCodeNameError}, nil
+		}
+		return &Result{Rcode: dnsmessage.RCodeServerFailure}, nil
+	}
+
+	result := &Result{Rcode: dnsmessage.RCodeSuccess}
+	for _, answer := range answers {
+		result.Answers = append(result.Answers, NormalizeAnswer(qtype, answer))
+	}
+	return result, nil
+}
+
+// MatchAnswers 按照匹配模式检查解析结果，期望结果为空时不检查
+func MatchAnswers(mode string, answers, expected []string) bool {
+	if len(expected) == 0 {
+		return true
+	}
+
+	expectedSet := make(map[string]struct{}, len(expected))
+	for _, e := range expected {
+		expectedSet[e] = struct{}{}
+	}
+	answerSet := make(map[string]struct{}, len(answers))
+	for _, a := range answers {
+		answerSet[a] = struct{}{}
+	}
+
+	switch mode {
+	case configs.DNSMatchAny:
+		for a := range answerSet {
+			if _, ok := expectedSet[a]; ok {
+				return true
+			}
+		}
+		return false
+	case configs.DNSMatchExact:
+		if len(answerSet) != len(expectedSet) {
+			return false
+		}
+		fallthrough
+	default:
+		if len(answerSet) == 0 {
+			return false
+		}
+		for a := range answerSet {
+			if _, ok := expectedSet[a]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -136,7 +136,8 @@ type SimpleEvent struct {
 	*Event
 	TargetHost string
 	TargetPort int
-	ResolvedIP string // DNS解析模式为全部时对应的实际请求IP，其他情况为空
+	ResolvedIP string   // DNS解析模式为全部时对应的实际请求IP，其他情况为空
+	TLS        *TLSInfo // 开启 TLS 检查时的握手以及证书信息
 }
 
 // AsMapStr :
@@ -145,6 +146,9 @@ func (e *SimpleEvent) AsMapStr() common.MapStr {
 	mapStr["target_host"] = e.TargetHost
 	mapStr["target_port"] = e.TargetPort
 	mapStr["resolved_ip"] = e.ResolvedIP // 增加实际请求IP
+	if e.TLS != nil {
+		mapStr.Update(e.TLS.AsMapStr())
+	}
 	return mapStr
 }
 
@@ -451,16 +455,28 @@ func NewCustomEventBySimpleEvent(e *SimpleEvent) *CustomEvent {
 		"bk_agent_id": info.BKAgentID,
 	}
 
+	metrics := map[string]interface{}{
+		"available":     e.Available,
+		"task_duration": int(e.TaskDuration().Milliseconds()),
+	}
+
+	// 证书信息
+	if e.TLS != nil {
+		for k, v := range e.TLS.Dimensions() {
+			dimensions[k] = v
+		}
+		for k, v := range e.TLS.Metrics() {
+			metrics[k] = v
+		}
+	}
+
 	data := common.MapStr{
 		"dataid": e.DataID,
 		"data": []map[string]interface{}{
 			{
 				"target":    fmt.Sprintf("%s:%d", e.TargetHost, e.TargetPort),
 				"dimension": dimensions,
-				"metrics": map[string]interface{}{
-					"available":     e.Available,
-					"task_duration": int(e.TaskDuration().Milliseconds()),
-				},
+				"metrics":   metrics,
 				"timestamp": ts * 1000,
 			},
 		},
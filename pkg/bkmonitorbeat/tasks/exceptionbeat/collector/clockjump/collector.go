@@ -0,0 +1,96 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package clockjump
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// sample 同时记录墙上时钟以及单调时钟，NTP 校时只会影响墙上时钟
+type sample struct {
+	wall time.Time
+	mono time.Duration
+}
+
+var (
+	start = time.Now()
+
+	takeSample = func() sample {
+		now := time.Now()
+		return sample{wall: now.Round(0), mono: now.Sub(start)}
+	}
+)
+
+// jump 两次采样之间墙上时钟与单调时钟流逝时间的差值，正数表示时钟向前跳变
+func jump(last, now sample) time.Duration {
+	return now.wall.Sub(last.wall) - (now.mono - last.mono)
+}
+
+// ClockJumpDetector 检测系统时钟跳变
+type ClockJumpDetector struct {
+	mut       sync.Mutex
+	threshold time.Duration
+	last      *sample
+}
+
+func init() {
+	collector.RegisterDetector(configs.ClockJump, new(ClockJumpDetector))
+}
+
+func (d *ClockJumpDetector) Name() string { return "ClockJumpDetector" }
+
+func (d *ClockJumpDetector) Setup(_ context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	def := configs.DefaultExceptionBeatConfig
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	d.threshold = collector.IntervalOrDefault(conf.ClockJumpThreshold, def.ClockJumpThreshold)
+	s := takeSample()
+	d.last = &s
+	return collector.IntervalOrDefault(conf.CheckClockInterval, def.CheckClockInterval),
+		collector.IntervalOrDefault(conf.ClockJumpReportGap, def.ClockJumpReportGap), nil
+}
+
+func (d *ClockJumpDetector) Detect() []collector.Anomaly {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	now := takeSample()
+	last := d.last
+	d.last = &now
+	if last == nil {
+		return nil
+	}
+
+	offset := jump(*last, now)
+	logger.Debugf("clock offset since last check: %v", offset)
+	if offset < d.threshold && offset > -d.threshold {
+		return nil
+	}
+
+	return []collector.Anomaly{{
+		Key: "clock",
+		Extra: beat.MapStr{
+			"type":          collector.ClockJumpEventType,
+			"offset":        offset.Seconds(),
+			"expected_time": last.wall.Add(now.mono - last.mono).Unix(),
+			"actual_time":   now.wall.Unix(),
+		},
+	}}
+}
@@ -0,0 +1,82 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package clockjump
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestClockJumpDetector(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		wallDelta time.Duration
+		monoDelta time.Duration
+		offset    float64
+		anomaly   bool
+	}{
+		"时钟正常": {
+			wallDelta: 10 * time.Second,
+			monoDelta: 10 * time.Second,
+		},
+		"时钟向前跳变": {
+			wallDelta: 70 * time.Second,
+			monoDelta: 10 * time.Second,
+			offset:    60,
+			anomaly:   true,
+		},
+		"时钟向后跳变": {
+			wallDelta: -50 * time.Second,
+			monoDelta: 10 * time.Second,
+			offset:    -60,
+			anomaly:   true,
+		},
+		"小于阈值": {
+			wallDelta: 12 * time.Second,
+			monoDelta: 10 * time.Second,
+		},
+	}
+
+	defer func(f func() sample) { takeSample = f }(takeSample)
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			samples := []sample{
+				{wall: base, mono: time.Minute},
+				{wall: base.Add(c.wallDelta), mono: time.Minute + c.monoDelta},
+			}
+			takeSample = func() sample {
+				s := samples[0]
+				samples = samples[1:]
+				return s
+			}
+
+			d := new(ClockJumpDetector)
+			_, _, err := d.Setup(context.Background(), &configs.ExceptionBeatConfig{})
+			assert.NoError(t, err)
+
+			anomalies := d.Detect()
+			if !c.anomaly {
+				assert.Len(t, anomalies, 0)
+				return
+			}
+			assert.Len(t, anomalies, 1)
+			assert.Equal(t, c.offset, anomalies[0].Extra["offset"])
+			assert.Equal(t, base.Add(c.wallDelta).Unix(), anomalies[0].Extra["actual_time"])
+			assert.Equal(t, base.Add(c.monoDelta).Unix(), anomalies[0].Extra["expected_time"])
+		})
+	}
+}
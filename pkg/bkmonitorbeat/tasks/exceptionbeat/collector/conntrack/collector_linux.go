@@ -0,0 +1,95 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build linux
+
+package conntrack
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+var (
+	countPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	maxPath   = "/proc/sys/net/netfilter/nf_conntrack_max"
+)
+
+// ConntrackDetector 检测 conntrack 表使用率，表满之后新连接会被内核丢弃
+type ConntrackDetector struct {
+	usageLimit int
+}
+
+func init() {
+	collector.RegisterDetector(configs.ConntrackFull, new(ConntrackDetector))
+}
+
+func (d *ConntrackDetector) Name() string { return "ConntrackDetector" }
+
+func (d *ConntrackDetector) Setup(_ context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	def := configs.DefaultExceptionBeatConfig
+	d.usageLimit = conf.ConntrackUsagePercent
+	if d.usageLimit <= 0 {
+		d.usageLimit = def.ConntrackUsagePercent
+	}
+	return collector.IntervalOrDefault(conf.CheckConntrackInterval, def.CheckConntrackInterval),
+		collector.IntervalOrDefault(conf.ConntrackReportGap, def.ConntrackReportGap), nil
+}
+
+func readUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func (d *ConntrackDetector) Detect() []collector.Anomaly {
+	count, err := readUint(countPath)
+	if err != nil {
+		// 未加载 nf_conntrack 模块时文件不存在
+		if os.IsNotExist(err) {
+			logger.Debugf("conntrack is not enabled: %v", err)
+		} else {
+			logger.Errorf("read %s failed: %v", countPath, err)
+		}
+		return nil
+	}
+	max, err := readUint(maxPath)
+	if err != nil {
+		logger.Errorf("read %s failed: %v", maxPath, err)
+		return nil
+	}
+	if max == 0 {
+		return nil
+	}
+
+	usedPercent := int(float64(count) / float64(max) * 100)
+	logger.Debugf("conntrack count: %d, max: %d, used percent: %d", count, max, usedPercent)
+	if usedPercent < d.usageLimit {
+		return nil
+	}
+	return []collector.Anomaly{{
+		Key: "conntrack",
+		Extra: beat.MapStr{
+			"type":         collector.ConntrackFullEventType,
+			"count":        count,
+			"max":          max,
+			"used_percent": usedPercent,
+		},
+	}}
+}
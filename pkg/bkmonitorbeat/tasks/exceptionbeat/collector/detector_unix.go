@@ -0,0 +1,165 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// Anomaly 探测到的异常，相同 Key 的异常在上报间隔内只会上报一次
+type Anomaly struct {
+	Key   string
+	Extra beat.MapStr
+}
+
+// Detector 周期性执行的异常探测器，通过 RegisterDetector 注册后由对应的 check_bit 控制开启
+type Detector interface {
+	// Name 探测器名称，用于日志
+	Name() string
+	// Setup 根据配置初始化探测器，返回检测周期以及上报间隔
+	Setup(ctx context.Context, conf *configs.ExceptionBeatConfig) (interval, reportGap time.Duration, err error)
+	// Detect 执行一次检测，返回本次探测到的异常
+	Detect() []Anomaly
+}
+
+// DetectorCollector 将 Detector 适配为 Collector，负责定时检测以及按上报间隔去重
+type DetectorCollector struct {
+	bit      int
+	detector Detector
+
+	mut        sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	reportGap  time.Duration
+	lastReport map[string]time.Time
+}
+
+func RegisterDetector(bit int, detector Detector) {
+	RegisterCollector(NewDetectorCollector(bit, detector))
+}
+
+func NewDetectorCollector(bit int, detector Detector) *DetectorCollector {
+	return &DetectorCollector{
+		bit:        bit,
+		detector:   detector,
+		lastReport: make(map[string]time.Time),
+	}
+}
+
+func (c *DetectorCollector) Start(ctx context.Context, e chan<- define.Event, conf *configs.ExceptionBeatConfig) {
+	name := c.detector.Name()
+	if (conf.CheckBit & c.bit) == 0 {
+		logger.Infof("%s detector closed by config: %s", name, conf.CheckMethod)
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.running() {
+		logger.Infof("%s detector has been already started", name)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	interval, reportGap, err := c.detector.Setup(ctx, conf)
+	if err != nil {
+		cancel()
+		logger.Errorf("%s detector setup failed: %v", name, err)
+		return
+	}
+	c.ctx, c.cancel = ctx, cancel
+	c.reportGap = reportGap
+
+	logger.Infof("%s detector start success, interval: %v, report gap: %v", name, interval, reportGap)
+	go c.run(ctx, e, int(conf.DataID), interval)
+}
+
+func (c *DetectorCollector) Reload(_ *configs.ExceptionBeatConfig) {}
+
+func (c *DetectorCollector) Stop() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if !c.running() {
+		logger.Errorf("%s detector stop failed: detector not open", c.detector.Name())
+		return
+	}
+	c.cancel()
+	logger.Infof("%s detector stopped", c.detector.Name())
+}
+
+// running 任务退出时 ctx 会被取消，无需额外维护状态
+func (c *DetectorCollector) running() bool {
+	return c.ctx != nil && c.ctx.Err() == nil
+}
+
+func (c *DetectorCollector) run(ctx context.Context, e chan<- define.Event, dataid int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("%s detector exit", c.detector.Name())
+			return
+
+		case <-ticker.C:
+			extraList := c.filter(time.Now(), c.detector.Detect())
+			if len(extraList) == 0 {
+				break
+			}
+			SendBulk(dataid, extraList, e)
+		}
+	}
+}
+
+// filter 过滤上报间隔内已经上报过的异常，并补充主机信息
+func (c *DetectorCollector) filter(now time.Time, anomalies []Anomaly) []beat.MapStr {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for key, t := range c.lastReport {
+		if now.Sub(t) >= c.reportGap {
+			delete(c.lastReport, key)
+		}
+	}
+
+	var extraList []beat.MapStr
+	for _, anomaly := range anomalies {
+		if _, ok := c.lastReport[anomaly.Key]; ok {
+			logger.Debugf("%s detector skip anomaly %s in report gap", c.detector.Name(), anomaly.Key)
+			continue
+		}
+		c.lastReport[anomaly.Key] = now
+
+		extra := anomaly.Extra
+		extra["bizid"] = BizID
+		extra["cloudid"] = CloudID
+		extra["host"] = NodeIP
+		extraList = append(extraList, extra)
+	}
+	return extraList
+}
+
+// IntervalOrDefault 配置项未设置时使用默认值
+func IntervalOrDefault(v, def time.Duration) time.Duration {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,101 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+)
+
+type testDetector struct {
+	anomalies []Anomaly
+}
+
+func (d *testDetector) Name() string { return "TestDetector" }
+
+func (d *testDetector) Setup(_ context.Context, _ *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	return 10 * time.Millisecond, time.Minute, nil
+}
+
+func (d *testDetector) Detect() []Anomaly {
+	var anomalies []Anomaly
+	for _, a := range d.anomalies {
+		extra := beat.MapStr{}
+		for k, v := range a.Extra {
+			extra[k] = v
+		}
+		anomalies = append(anomalies, Anomaly{Key: a.Key, Extra: extra})
+	}
+	return anomalies
+}
+
+func TestDetectorCollectorFilter(t *testing.T) {
+	c := NewDetectorCollector(configs.InodeFull, &testDetector{})
+	c.reportGap = time.Minute
+
+	now := time.Now()
+	anomalies := []Anomaly{
+		{Key: "/data", Extra: beat.MapStr{"disk": "/data"}},
+		{Key: "/home", Extra: beat.MapStr{"disk": "/home"}},
+	}
+	extraList := c.filter(now, anomalies)
+	assert.Len(t, extraList, 2)
+	assert.Equal(t, "/data", extraList[0]["disk"])
+	assert.Contains(t, extraList[0], "host")
+
+	// 上报间隔内不重复上报
+	extraList = c.filter(now.Add(30*time.Second), []Anomaly{{Key: "/data", Extra: beat.MapStr{}}})
+	assert.Len(t, extraList, 0)
+
+	// 超过上报间隔后重新上报
+	extraList = c.filter(now.Add(time.Minute), []Anomaly{{Key: "/data", Extra: beat.MapStr{}}})
+	assert.Len(t, extraList, 1)
+}
+
+func TestDetectorCollectorStart(t *testing.T) {
+	d := &testDetector{anomalies: []Anomaly{{Key: "k", Extra: beat.MapStr{"type": 1}}}}
+	c := NewDetectorCollector(configs.InodeFull, d)
+	conf := &configs.ExceptionBeatConfig{CheckBit: configs.InodeFull}
+	conf.DataID = 1000
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := make(chan define.Event, 10)
+	c.Start(ctx, e, conf)
+	assert.True(t, c.running())
+	// 重复启动不生效
+	c.Start(ctx, e, conf)
+
+	select {
+	case event := <-e:
+		m := event.AsMapStr()
+		assert.Equal(t, 1000, m["dataid"])
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	c.Stop()
+	assert.False(t, c.running())
+
+	// 未开启对应的 check_bit 时不启动
+	c = NewDetectorCollector(configs.HungTask, d)
+	c.Start(ctx, e, conf)
+	assert.False(t, c.running())
+}
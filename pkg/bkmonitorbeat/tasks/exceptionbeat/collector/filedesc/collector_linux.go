@@ -0,0 +1,107 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build linux
+
+package filedesc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+var fileNrPath = "/proc/sys/fs/file-nr"
+
+// FdDetector 检测系统文件句柄表使用率
+type FdDetector struct {
+	usageLimit int
+}
+
+func init() {
+	collector.RegisterDetector(configs.FdFull, new(FdDetector))
+}
+
+func (d *FdDetector) Name() string { return "FdDetector" }
+
+func (d *FdDetector) Setup(_ context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	def := configs.DefaultExceptionBeatConfig
+	d.usageLimit = conf.FdUsagePercent
+	if d.usageLimit <= 0 {
+		d.usageLimit = def.FdUsagePercent
+	}
+	return collector.IntervalOrDefault(conf.CheckFdInterval, def.CheckFdInterval),
+		collector.IntervalOrDefault(conf.FdReportGap, def.FdReportGap), nil
+}
+
+// readFileNr 读取已分配的句柄数以及句柄上限
+// $ cat /proc/sys/fs/file-nr
+// 3264	0	9223372036854775807
+func readFileNr() (uint64, uint64, error) {
+	b, err := os.ReadFile(fileNrPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) != 3 {
+		return 0, 0, fmt.Errorf("unexpected file-nr content: %s", b)
+	}
+	allocated, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	unused, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	// 2.6 之后的内核 unused 恒为 0，这里兼容老版本内核
+	if unused > allocated {
+		unused = allocated
+	}
+	return allocated - unused, max, nil
+}
+
+func (d *FdDetector) Detect() []collector.Anomaly {
+	used, max, err := readFileNr()
+	if err != nil {
+		logger.Errorf("read %s failed: %v", fileNrPath, err)
+		return nil
+	}
+	if max == 0 {
+		return nil
+	}
+
+	usedPercent := int(float64(used) / float64(max) * 100)
+	logger.Debugf("fd used: %d, max: %d, used percent: %d", used, max, usedPercent)
+	if usedPercent < d.usageLimit {
+		return nil
+	}
+	return []collector.Anomaly{{
+		Key: "file-nr",
+		Extra: beat.MapStr{
+			"type":         collector.FdFullEventType,
+			"used":         used,
+			"max":          max,
+			"used_percent": usedPercent,
+		},
+	}}
+}
@@ -0,0 +1,66 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build linux
+
+package filedesc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestFdDetector(t *testing.T) {
+	defer func(p string) { fileNrPath = p }(fileNrPath)
+	fileNrPath = filepath.Join(t.TempDir(), "file-nr")
+
+	testCases := map[string]struct {
+		content     string
+		anomaly     bool
+		usedPercent int
+	}{
+		"使用率正常": {
+			content: "3264\t0\t100000\n",
+		},
+		"超过阈值": {
+			content:     "95000\t0\t100000\n",
+			anomaly:     true,
+			usedPercent: 95,
+		},
+		"老版本内核扣除未使用的句柄": {
+			content: "95000\t20000\t100000\n",
+		},
+		"内容不合法": {
+			content: "invalid",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, os.WriteFile(fileNrPath, []byte(c.content), 0o644))
+			d := new(FdDetector)
+			_, _, err := d.Setup(context.Background(), &configs.ExceptionBeatConfig{})
+			assert.NoError(t, err)
+
+			anomalies := d.Detect()
+			if !c.anomaly {
+				assert.Len(t, anomalies, 0)
+				return
+			}
+			assert.Len(t, anomalies, 1)
+			assert.Equal(t, c.usedPercent, anomalies[0].Extra["used_percent"])
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build linux
+
+package hungtask
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/euank/go-kmsg-parser/kmsgparser"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	KindHungTask   = "hung_task"
+	KindSoftLockup = "soft_lockup"
+	KindHardLockup = "hard_lockup"
+)
+
+var (
+	// INFO: task jbd2/sda1-8:312 blocked for more than 120 seconds.
+	hungTaskRegexp = regexp.MustCompile(`task (.+):(\d+) blocked for more than (\d+) seconds`)
+	// watchdog: BUG: soft lockup - CPU#3 stuck for 23s! [kworker/3:1:1234]
+	softLockupRegexp = regexp.MustCompile(`soft lockup - CPU#(\d+) stuck for (\d+)s! \[(.+):(\d+)\]`)
+	// NMI watchdog: Watchdog detected hard LOCKUP on cpu 3
+	hardLockupRegexp = regexp.MustCompile(`(?i)detected hard LOCKUP on cpu (\d+)`)
+)
+
+// kernelEvent 从内核日志中解析出的任务阻塞事件
+type kernelEvent struct {
+	Kind     string
+	Process  string
+	Pid      int
+	Cpu      int
+	Duration int // 阻塞时长（秒）
+	Message  string
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// parseMessage 解析单行内核日志，非阻塞相关的日志返回空
+func parseMessage(msg string) *kernelEvent {
+	if m := hungTaskRegexp.FindStringSubmatch(msg); m != nil {
+		return &kernelEvent{Kind: KindHungTask, Process: m[1], Pid: atoi(m[2]), Cpu: -1, Duration: atoi(m[3]), Message: msg}
+	}
+	if m := softLockupRegexp.FindStringSubmatch(msg); m != nil {
+		return &kernelEvent{Kind: KindSoftLockup, Cpu: atoi(m[1]), Duration: atoi(m[2]), Process: m[3], Pid: atoi(m[4]), Message: msg}
+	}
+	if m := hardLockupRegexp.FindStringSubmatch(msg); m != nil {
+		return &kernelEvent{Kind: KindHardLockup, Cpu: atoi(m[1]), Message: msg}
+	}
+	return nil
+}
+
+// HungTaskDetector 通过内核日志检测 hung task 以及 soft/hard lockup
+type HungTaskDetector struct {
+	mut    sync.Mutex
+	events []*kernelEvent
+}
+
+func init() {
+	collector.RegisterDetector(configs.HungTask, new(HungTaskDetector))
+}
+
+func (d *HungTaskDetector) Name() string { return "HungTaskDetector" }
+
+func (d *HungTaskDetector) Setup(ctx context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	parser, err := kmsgparser.NewParser()
+	if err != nil {
+		return 0, 0, err
+	}
+	// 只关注启动之后的内核日志
+	if err = parser.SeekEnd(); err != nil {
+		logger.Errorf("kmsg parser SeekEnd error: %v", err)
+	}
+	go d.watch(ctx, parser)
+
+	def := configs.DefaultExceptionBeatConfig
+	return collector.IntervalOrDefault(conf.CheckHungTaskInterval, def.CheckHungTaskInterval),
+		collector.IntervalOrDefault(conf.HungTaskReportGap, def.HungTaskReportGap), nil
+}
+
+func (d *HungTaskDetector) watch(ctx context.Context, parser kmsgparser.Parser) {
+	defer parser.Close()
+	messages := parser.Parse()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			d.add(parseMessage(msg.Message))
+		}
+	}
+}
+
+func (d *HungTaskDetector) add(event *kernelEvent) {
+	if event == nil {
+		return
+	}
+	logger.Infof("kernel event detected: %s", event.Message)
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.events = append(d.events, event)
+}
+
+// Detect 汇总两次检测之间的内核事件，相同类型以及进程的事件合并计数
+func (d *HungTaskDetector) Detect() []collector.Anomaly {
+	d.mut.Lock()
+	events := d.events
+	d.events = nil
+	d.mut.Unlock()
+
+	var anomalies []collector.Anomaly
+	index := make(map[string]beat.MapStr)
+	for _, event := range events {
+		key := event.Kind + ":" + event.Process
+		if extra, ok := index[key]; ok {
+			extra["total"] = extra["total"].(int) + 1
+			continue
+		}
+
+		extra := beat.MapStr{
+			"type":     collector.HungTaskEventType,
+			"kind":     event.Kind,
+			"process":  event.Process,
+			"pid":      event.Pid,
+			"cpu":      event.Cpu,
+			"duration": event.Duration,
+			"message":  event.Message,
+			"total":    1,
+		}
+		index[key] = extra
+		anomalies = append(anomalies, collector.Anomaly{Key: key, Extra: extra})
+	}
+	return anomalies
+}
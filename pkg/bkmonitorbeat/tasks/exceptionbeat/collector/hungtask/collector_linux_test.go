@@ -0,0 +1,70 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build linux
+
+package hungtask
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessage(t *testing.T) {
+	testCases := map[string]struct {
+		msg   string
+		event *kernelEvent
+	}{
+		"hung task": {
+			msg:   "INFO: task jbd2/sda1-8:312 blocked for more than 120 seconds.",
+			event: &kernelEvent{Kind: KindHungTask, Process: "jbd2/sda1-8", Pid: 312, Cpu: -1, Duration: 120},
+		},
+		"soft lockup": {
+			msg:   "watchdog: BUG: soft lockup - CPU#3 stuck for 23s! [kworker/3:1:1234]",
+			event: &kernelEvent{Kind: KindSoftLockup, Process: "kworker/3:1", Pid: 1234, Cpu: 3, Duration: 23},
+		},
+		"hard lockup": {
+			msg:   "NMI watchdog: Watchdog detected hard LOCKUP on cpu 5",
+			event: &kernelEvent{Kind: KindHardLockup, Cpu: 5},
+		},
+		"无关日志": {
+			msg: "EXT4-fs (sda1): mounted filesystem with ordered data mode",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			event := parseMessage(c.msg)
+			if c.event == nil {
+				assert.Nil(t, event)
+				return
+			}
+			c.event.Message = c.msg
+			assert.Equal(t, c.event, event)
+		})
+	}
+}
+
+func TestHungTaskDetectorDetect(t *testing.T) {
+	d := new(HungTaskDetector)
+	d.add(parseMessage("INFO: task mysqld:100 blocked for more than 120 seconds."))
+	d.add(parseMessage("INFO: task mysqld:101 blocked for more than 240 seconds."))
+	d.add(parseMessage("watchdog: BUG: soft lockup - CPU#1 stuck for 22s! [java:200]"))
+	d.add(parseMessage("unrelated"))
+
+	anomalies := d.Detect()
+	assert.Len(t, anomalies, 2)
+	assert.Equal(t, "hung_task:mysqld", anomalies[0].Key)
+	assert.Equal(t, 2, anomalies[0].Extra["total"])
+	assert.Equal(t, "soft_lockup:java", anomalies[1].Key)
+
+	// 事件上报后清空
+	assert.Len(t, d.Detect(), 0)
+}
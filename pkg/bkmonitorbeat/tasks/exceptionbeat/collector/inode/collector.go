@@ -0,0 +1,93 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package inode
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+var (
+	partitionFunc = disk.Partitions
+	usageFunc     = disk.Usage
+)
+
+// InodeDetector 检测 inode 使用率超过阈值的分区
+type InodeDetector struct {
+	usageLimit int
+}
+
+func init() {
+	collector.RegisterDetector(configs.InodeFull, new(InodeDetector))
+}
+
+func (d *InodeDetector) Name() string { return "InodeDetector" }
+
+func (d *InodeDetector) Setup(_ context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	def := configs.DefaultExceptionBeatConfig
+	d.usageLimit = conf.InodeUsagePercent
+	if d.usageLimit <= 0 {
+		d.usageLimit = def.InodeUsagePercent
+	}
+	return collector.IntervalOrDefault(conf.CheckInodeInterval, def.CheckInodeInterval),
+		collector.IntervalOrDefault(conf.InodeReportGap, def.InodeReportGap), nil
+}
+
+func (d *InodeDetector) Detect() []collector.Anomaly {
+	// 此处只关心物理设备的分区，其他系统生成的分区不必关注
+	partitions, err := partitionFunc(false)
+	if err != nil {
+		logger.Errorf("get disk partitions failed: %v", err)
+		return nil
+	}
+
+	var anomalies []collector.Anomaly
+	for _, partition := range partitions {
+		usage, err := usageFunc(partition.Mountpoint)
+		if err != nil {
+			logger.Errorf("get usage of %s failed: %v", partition.Mountpoint, err)
+			continue
+		}
+		// 部分文件系统（如 btrfs）不提供 inode 信息
+		if usage.InodesTotal == 0 {
+			continue
+		}
+
+		usedPercent := int(math.Round(usage.InodesUsedPercent))
+		logger.Debugf("disk: %s, inode used percent: %d", usage.Path, usedPercent)
+		if usedPercent < d.usageLimit {
+			continue
+		}
+		anomalies = append(anomalies, collector.Anomaly{
+			Key: partition.Mountpoint,
+			Extra: beat.MapStr{
+				"type":         collector.InodeFullEventType,
+				"disk":         usage.Path,
+				"file_system":  partition.Device,
+				"fstype":       partition.Fstype,
+				"inodes_total": usage.InodesTotal,
+				"inodes_used":  usage.InodesUsed,
+				"inodes_free":  usage.InodesFree,
+				"used_percent": usedPercent,
+			},
+		})
+	}
+	return anomalies
+}
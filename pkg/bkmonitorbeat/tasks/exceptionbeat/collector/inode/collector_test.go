@@ -0,0 +1,53 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package inode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+func TestInodeDetector(t *testing.T) {
+	defer func(f func(bool) ([]disk.PartitionStat, error)) { partitionFunc = f }(partitionFunc)
+	defer func(f func(string) (*disk.UsageStat, error)) { usageFunc = f }(usageFunc)
+
+	partitionFunc = func(bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "ext4"},
+			{Device: "/dev/sdc1", Mountpoint: "/btrfs", Fstype: "btrfs"},
+		}, nil
+	}
+	usages := map[string]*disk.UsageStat{
+		"/":      {Path: "/", InodesTotal: 100, InodesUsed: 50, InodesFree: 50, InodesUsedPercent: 50},
+		"/data":  {Path: "/data", InodesTotal: 100, InodesUsed: 98, InodesFree: 2, InodesUsedPercent: 98},
+		"/btrfs": {Path: "/btrfs"},
+	}
+	usageFunc = func(path string) (*disk.UsageStat, error) {
+		return usages[path], nil
+	}
+
+	d := new(InodeDetector)
+	_, _, err := d.Setup(context.Background(), &configs.ExceptionBeatConfig{})
+	assert.NoError(t, err)
+
+	anomalies := d.Detect()
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, "/data", anomalies[0].Key)
+	assert.Equal(t, 98, anomalies[0].Extra["used_percent"])
+	assert.Equal(t, "/dev/sdb1", anomalies[0].Extra["file_system"])
+}
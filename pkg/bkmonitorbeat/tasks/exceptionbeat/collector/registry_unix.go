@@ -23,6 +23,13 @@ const (
 	DiskSpaceEventType = 6
 	CoreEventType      = 7
 	OutOfMemEventType  = 9
+
+	InodeFullEventType     = 10
+	FdFullEventType        = 11
+	ConntrackFullEventType = 12
+	ZombieProcessEventType = 13
+	ClockJumpEventType     = 14
+	HungTaskEventType      = 15
 )
 
 // Collector interface define the basic function interface that will be used by beater.go.
@@ -0,0 +1,134 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package zombie
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// maxParents 最多上报的父进程数量
+const maxParents = 5
+
+// parent 僵尸进程的父进程，僵尸进程需要由父进程回收
+type parent struct {
+	Pid   int32
+	Count int
+}
+
+var listZombieParents = func() (map[int32]int, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[int32]int)
+	for _, proc := range procs {
+		status, err := proc.Status()
+		if err != nil {
+			continue
+		}
+		if len(status) == 0 || status[0] != process.Zombie {
+			continue
+		}
+		ppid, err := proc.Ppid()
+		if err != nil {
+			continue
+		}
+		parents[ppid]++
+	}
+	return parents, nil
+}
+
+var processName = func(pid int32) string {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return ""
+	}
+	name, _ := proc.Name()
+	return name
+}
+
+// ZombieDetector 检测僵尸进程堆积，并给出未回收子进程最多的父进程
+type ZombieDetector struct {
+	maxCount int
+}
+
+func init() {
+	collector.RegisterDetector(configs.ZombieProcess, new(ZombieDetector))
+}
+
+func (d *ZombieDetector) Name() string { return "ZombieDetector" }
+
+func (d *ZombieDetector) Setup(_ context.Context, conf *configs.ExceptionBeatConfig) (time.Duration, time.Duration, error) {
+	def := configs.DefaultExceptionBeatConfig
+	d.maxCount = conf.ZombieMaxCount
+	if d.maxCount <= 0 {
+		d.maxCount = def.ZombieMaxCount
+	}
+	return collector.IntervalOrDefault(conf.CheckZombieInterval, def.CheckZombieInterval),
+		collector.IntervalOrDefault(conf.ZombieReportGap, def.ZombieReportGap), nil
+}
+
+func (d *ZombieDetector) Detect() []collector.Anomaly {
+	counts, err := listZombieParents()
+	if err != nil {
+		logger.Errorf("list processes failed: %v", err)
+		return nil
+	}
+
+	var total int
+	parents := make([]parent, 0, len(counts))
+	for pid, count := range counts {
+		total += count
+		parents = append(parents, parent{Pid: pid, Count: count})
+	}
+	logger.Debugf("zombie processes: %d", total)
+	if total < d.maxCount {
+		return nil
+	}
+
+	sort.Slice(parents, func(i, j int) bool {
+		if parents[i].Count != parents[j].Count {
+			return parents[i].Count > parents[j].Count
+		}
+		return parents[i].Pid < parents[j].Pid
+	})
+	if len(parents) > maxParents {
+		parents = parents[:maxParents]
+	}
+
+	top := make([]beat.MapStr, 0, len(parents))
+	for _, p := range parents {
+		top = append(top, beat.MapStr{
+			"ppid":  p.Pid,
+			"name":  processName(p.Pid),
+			"count": p.Count,
+		})
+	}
+	return []collector.Anomaly{{
+		Key: "zombie",
+		Extra: beat.MapStr{
+			"type":    collector.ZombieProcessEventType,
+			"total":   total,
+			"parents": top,
+		},
+	}}
+}
@@ -0,0 +1,68 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || zos
+
+package zombie
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/beat"
+)
+
+func TestZombieDetector(t *testing.T) {
+	defer func(f func() (map[int32]int, error)) { listZombieParents = f }(listZombieParents)
+	defer func(f func(int32) string) { processName = f }(processName)
+	processName = func(pid int32) string { return "proc" }
+
+	testCases := map[string]struct {
+		parents  map[int32]int
+		anomaly  bool
+		total    int
+		topPpids []int32
+	}{
+		"未超过阈值": {
+			parents: map[int32]int{1: 3},
+		},
+		"超过阈值": {
+			parents:  map[int32]int{1: 1, 2: 3, 3: 3, 4: 1, 5: 1, 6: 1},
+			anomaly:  true,
+			total:    10,
+			topPpids: []int32{2, 3, 1, 4, 5},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			listZombieParents = func() (map[int32]int, error) { return c.parents, nil }
+			d := new(ZombieDetector)
+			_, _, err := d.Setup(context.Background(), &configs.ExceptionBeatConfig{ZombieMaxCount: 10})
+			assert.NoError(t, err)
+
+			anomalies := d.Detect()
+			if !c.anomaly {
+				assert.Len(t, anomalies, 0)
+				return
+			}
+			assert.Len(t, anomalies, 1)
+			assert.Equal(t, c.total, anomalies[0].Extra["total"])
+
+			var ppids []int32
+			for _, p := range anomalies[0].Extra["parents"].([]beat.MapStr) {
+				ppids = append(ppids, p["ppid"].(int32))
+			}
+			assert.Equal(t, c.topPpids, ppids)
+		})
+	}
+}
@@ -21,10 +21,13 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/clockjump"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/corefile"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/diskro"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/diskspace"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/inode"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/outofmem"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/exceptionbeat/collector/zombie"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
 
@@ -33,6 +36,12 @@ const (
 	DiskSpaceCollection      = "C_DISK_SPACE"
 	CoreFileDetectCollection = "C_CORE"
 	OutOfMemCollection       = "C_OOM"
+	InodeFullCollection      = "C_INODE"
+	FdFullCollection         = "C_FD"
+	ConntrackFullCollection  = "C_CONNTRACK"
+	ZombieProcessCollection  = "C_ZOMBIE"
+	ClockJumpCollection      = "C_CLOCK_JUMP"
+	HungTaskCollection       = "C_HUNG_TASK"
 )
 
 var methods []collector.Collector
@@ -114,6 +123,18 @@ func parseCheckBit(conf configs.ExceptionBeatConfig) (int, error) {
 			bits |= configs.Core
 		case OutOfMemCollection:
 			bits |= configs.OOM
+		case InodeFullCollection:
+			bits |= configs.InodeFull
+		case FdFullCollection:
+			bits |= configs.FdFull
+		case ConntrackFullCollection:
+			bits |= configs.ConntrackFull
+		case ZombieProcessCollection:
+			bits |= configs.ZombieProcess
+		case ClockJumpCollection:
+			bits |= configs.ClockJump
+		case HungTaskCollection:
+			bits |= configs.HungTask
 		}
 	}
 	if bits == 0 {
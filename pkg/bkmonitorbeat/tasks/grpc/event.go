@@ -0,0 +1,104 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"google.golang.org/grpc/codes"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/output/gse"
+)
+
+// Event gRPC 拨测事件，response_code 为 gRPC 状态码
+type Event struct {
+	*tasks.SimpleEvent
+	Method       string
+	ResponseCode int
+	Message      string
+	// Duration 方法调用耗时，不包含建立连接以及反射的耗时
+	Duration time.Duration
+}
+
+// AsMapStr :
+func (e *Event) AsMapStr() common.MapStr {
+	mapStr := e.SimpleEvent.AsMapStr()
+	mapStr["method"] = e.Method
+	mapStr["response_code"] = e.ResponseCode
+	mapStr["response_code_name"] = codes.Code(e.ResponseCode).String()
+	mapStr["message"] = e.Message
+	mapStr["response_time"] = int(e.Duration.Milliseconds())
+	return mapStr
+}
+
+// GetType :
+func (e *Event) GetType() string {
+	return define.ModuleGRPC
+}
+
+// NewCustomEventByGRPCEvent 通过 gRPC 事件创建自定义事件
+func NewCustomEventByGRPCEvent(e *Event) *tasks.CustomEvent {
+	ts := e.StartAt.Unix()
+	info, _ := gse.GetAgentInfo()
+
+	dimensions := map[string]string{
+		"bk_biz_id":          strconv.Itoa(int(e.BizID)),
+		"target_host":        e.TargetHost,
+		"target_port":        strconv.Itoa(e.TargetPort),
+		"method":             e.Method,
+		"response_code":      strconv.Itoa(e.ResponseCode),
+		"response_code_name": codes.Code(e.ResponseCode).String(),
+		"message":            e.Message,
+		"error_code":         strconv.Itoa(e.ErrorCode.Code()),
+		"resolved_ip":        e.ResolvedIP,
+		"status":             strconv.Itoa(int(e.Status)),
+		"task_id":            strconv.Itoa(int(e.TaskID)),
+		"task_type":          e.TaskType,
+		"node_id":            fmt.Sprintf("%d:%s", info.Cloudid, info.IP),
+		"ip":                 info.IP,
+		"bk_cloud_id":        strconv.Itoa(int(info.Cloudid)),
+		"bk_agent_id":        info.BKAgentID,
+	}
+	metrics := map[string]interface{}{
+		"available":     e.Available,
+		"task_duration": int(e.TaskDuration().Milliseconds()),
+		"response_time": int(e.Duration.Milliseconds()),
+	}
+
+	// 证书信息
+	if e.TLS != nil {
+		for k, v := range e.TLS.Dimensions() {
+			dimensions[k] = v
+		}
+		for k, v := range e.TLS.Metrics() {
+			metrics[k] = v
+		}
+	}
+
+	data := common.MapStr{
+		"dataid": e.DataID,
+		"data": []map[string]interface{}{
+			{
+				"target":    fmt.Sprintf("%s:%d%s", e.TargetHost, e.TargetPort, e.Method),
+				"dimension": dimensions,
+				"metrics":   metrics,
+				"timestamp": ts * 1000,
+			},
+		},
+		"time":      ts,
+		"timestamp": ts,
+	}
+	return tasks.NewCustomEvent(e.GetType(), data, e.IgnoreCMDBLevel(), e.Labels)
+}
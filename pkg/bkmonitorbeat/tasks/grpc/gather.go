@@ -0,0 +1,325 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// gRPC 拨测状态码详情
+//
+// error_code:
+// CodeOK               -> 状态码以及断言均符合预期
+// CodeConnFailed       -> 连接失败
+// CodeConnTimeout      -> 连接超时
+// CodeCertInvalid      -> 证书校验失败
+// CodeReflectionFailed -> 通过服务端反射获取方法失败
+// CodeBadRequestParams -> 请求内容与方法的请求结构不一致
+// CodeRequestTimeout   -> 调用超时
+// CodeResponseNotMatch -> 状态码与期望不一致，或者健康检查状态不为 SERVING
+// CodeAssertionFailed  -> 响应断言失败
+
+// Response 调用结果，用于断言
+type Response struct {
+	Code     codes.Code
+	Message  string
+	Header   metadata.MD
+	Body     []byte
+	Duration time.Duration
+}
+
+// Gather :
+type Gather struct {
+	tasks.BaseTask
+}
+
+func (g *Gather) newEvent(taskConf *configs.GRPCTaskConfig, taskHost string) *Event {
+	event := tasks.NewSimpleEvent(g)
+	event.StartAt = time.Now()
+	event.TargetHost = taskHost
+	event.TargetPort = taskConf.TargetPort
+	return &Event{
+		SimpleEvent: event,
+		Method:      taskConf.GetMethod(),
+	}
+}
+
+// checkAssertion 与 http 断言语法一致，header 对应响应的 metadata，status_code 对应状态码数值
+func checkAssertion(a *configs.HTTPAssertionConfig, r *Response) bool {
+	var (
+		actual string
+		exists bool
+	)
+
+	switch a.Type {
+	case configs.HTTPAssertionJSON:
+		res := gjson.GetBytes(r.Body, a.Expr)
+		actual, exists = res.String(), res.Exists()
+	case configs.HTTPAssertionHeader:
+		values := r.Header.Get(a.Expr)
+		if len(values) > 0 {
+			actual, exists = values[0], true
+		}
+	case configs.HTTPAssertionBody:
+		actual, exists = string(r.Body), true
+	case configs.HTTPAssertionStatusCode:
+		actual, exists = strconv.Itoa(int(r.Code)), true
+	case configs.HTTPAssertionResponseTime:
+		expected, ok := tasks.ParseMilliseconds(a.Value)
+		if !ok {
+			return false
+		}
+		return tasks.CompareNumber(a.Operator, float64(r.Duration)/float64(time.Millisecond), expected)
+	}
+	return tasks.MatchValue(a.Operator, actual, exists, a.Value)
+}
+
+// dial 建立连接，握手时记录证书信息并校验证书，证书校验失败时不会建立连接
+func dial(ctx context.Context, taskConf *configs.GRPCTaskConfig, address, serverName string, roots *x509.CertPool, event *Event) (*grpc.ClientConn, define.NamedCode) {
+	// 握手在连接的协程中进行，连接失败后依然可能有重连，需要保证并发安全
+	var info atomic.Pointer[tasks.TLSInfo]
+	creds, err := newTransportCredentials(taskConf, serverName, roots, info.Store)
+	if err != nil {
+		logger.Warnf("%v: load client certificate failed: %v", taskConf.TaskID, err)
+		return nil, define.CodeBadRequestParams
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, taskConf.Timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.WithReturnConnectionError(),
+	)
+	event.TLS = info.Load()
+	if err != nil {
+		logger.Debugf("%v: connect %v fail: %v", taskConf.TaskID, address, err)
+		if event.TLS != nil && !taskConf.InsecureSkipVerify && !event.TLS.ChainValid {
+			logger.Debugf("%v: certificate of %v is invalid: %v", taskConf.TaskID, address, event.TLS.ChainError)
+			return nil, define.CodeCertInvalid
+		}
+		// 超时之前的最后一次连接错误会附带在错误信息中，没有连接错误时说明连接超时
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "authentication handshake failed"):
+			return nil, define.CodeTLSHandshakeFailed
+		case strings.Contains(msg, "connection error"):
+			return nil, define.CodeConnFailed
+		}
+		return nil, define.CodeConnTimeout
+	}
+	return conn, define.CodeOK
+}
+
+// call 调用健康检查或者通过服务端反射调用指定的方法
+func call(ctx context.Context, taskConf *configs.GRPCTaskConfig, conn *grpc.ClientConn) (*Response, define.NamedCode) {
+	if len(taskConf.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(taskConf.Metadata))
+	}
+
+	var (
+		req, resp proto.Message
+		header    metadata.MD
+	)
+	if taskConf.IsHealthCheck() {
+		req = &grpc_health_v1.HealthCheckRequest{Service: taskConf.Service}
+		resp = new(grpc_health_v1.HealthCheckResponse)
+	} else {
+		md, err := resolveMethod(ctx, conn, taskConf.GetMethod())
+		if err != nil {
+			logger.Debugf("%v: resolve method %v fail: %v", taskConf.TaskID, taskConf.GetMethod(), err)
+			if status.Code(err) == codes.DeadlineExceeded {
+				return nil, define.CodeRequestTimeout
+			}
+			return nil, define.CodeReflectionFailed
+		}
+		req, err = newRequest(md, taskConf.Request)
+		if err != nil {
+			logger.Warnf("%v: make request for %v failed: %v", taskConf.TaskID, taskConf.GetMethod(), err)
+			return nil, define.CodeBadRequestParams
+		}
+		resp = dynamicpb.NewMessage(md.Output())
+	}
+
+	start := time.Now()
+	err := conn.Invoke(ctx, taskConf.GetMethod(), req, resp, grpc.Header(&header))
+	r := &Response{
+		Code:     status.Code(err),
+		Message:  status.Convert(err).Message(),
+		Header:   header,
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		r.Body, _ = protojson.Marshal(resp)
+	}
+	return r, define.CodeOK
+}
+
+// checkResponse 检查状态码、健康状态以及断言
+func checkResponse(taskConf *configs.GRPCTaskConfig, r *Response) define.NamedCode {
+	if r.Code != taskConf.GetExpectedCode() {
+		switch r.Code {
+		case codes.DeadlineExceeded:
+			return define.CodeRequestTimeout
+		case codes.Unavailable:
+			return define.CodeRequestFailed
+		}
+		return define.CodeResponseNotMatch
+	}
+
+	if taskConf.IsHealthCheck() && r.Code == codes.OK {
+		servingStatus := gjson.GetBytes(r.Body, "status").String()
+		if servingStatus != grpc_health_v1.HealthCheckResponse_SERVING.String() {
+			return define.CodeResponseNotMatch
+		}
+	}
+
+	for _, a := range taskConf.Assertions {
+		if !checkAssertion(a, r) {
+			logger.Debugf("%v: assertion %s(%s) %s %s failed", taskConf.TaskID, a.Type, a.Expr, a.Operator, a.Value)
+			return define.CodeAssertionFailed
+		}
+	}
+	return define.CodeOK
+}
+
+func (g *Gather) checkTarget(ctx context.Context, taskConf *configs.GRPCTaskConfig, ip string, event *Event) define.NamedCode {
+	ctx, cancel := context.WithTimeout(ctx, taskConf.Timeout)
+	defer cancel()
+
+	roots, err := loadRootCAs(taskConf)
+	if err != nil {
+		logger.Warnf("%v: load ca failed: %v", taskConf.TaskID, err)
+		return define.CodeBadRequestParams
+	}
+	serverName := taskConf.ServerName
+	if serverName == "" {
+		serverName = event.TargetHost
+	}
+
+	address := net.JoinHostPort(ip, strconv.Itoa(taskConf.TargetPort))
+	conn, code := dial(ctx, taskConf, address, serverName, roots, event)
+	if code != define.CodeOK {
+		return code
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Warnf("%v: close conn error: %v", taskConf.TaskID, err)
+		}
+	}()
+
+	r, code := call(ctx, taskConf, conn)
+	event.EndAt = time.Now()
+	if code != define.CodeOK {
+		return code
+	}
+
+	event.ResponseCode = int(r.Code)
+	event.Message = r.Message
+	event.Duration = r.Duration
+	return checkResponse(taskConf, r)
+}
+
+func (g *Gather) send(taskConf *configs.GRPCTaskConfig, event *Event, e chan<- define.Event) {
+	// 如果需要使用自定义上报，则将事件转换为自定义事件
+	if taskConf.CustomReport {
+		e <- NewCustomEventByGRPCEvent(event)
+	} else {
+		e <- event
+	}
+}
+
+// Run :
+func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
+	resultMap := make(map[string][]string)
+	taskConf := g.TaskConfig.(*configs.GRPCTaskConfig)
+	g.PreRun(ctx)
+	defer g.PostRun(ctx)
+
+	hosts := taskConf.Hosts()
+	if len(hosts) == 0 {
+		return
+	}
+
+	hostsInfo := tasks.GetHostsInfo(ctx, hosts, taskConf.DNSCheckMode, taskConf.TargetIPType, configs.Tcp)
+	for _, h := range hostsInfo {
+		if h.Errno != define.CodeOK {
+			event := g.newEvent(taskConf, h.Host)
+			event.Fail(h.Errno)
+			g.send(taskConf, event, e)
+		} else {
+			resultMap[h.Host] = h.Ips
+		}
+	}
+
+	var wg sync.WaitGroup
+	for taskHost, result := range resultMap {
+		for _, targetHost := range result {
+			// 获取并发限制信号量
+			err := g.GetSemaphore().Acquire(ctx, 1)
+			if err != nil {
+				logger.Errorf("task(%d) semaphore acquire failed", g.TaskConfig.GetTaskID())
+				return
+			}
+
+			wg.Add(1)
+			go func(tHost, host string) {
+				event := g.newEvent(taskConf, tHost)
+				event.ResolvedIP = host
+
+				defer func() {
+					wg.Done()
+					g.GetSemaphore().Release(1)
+					g.send(taskConf, event, e)
+				}()
+
+				code := g.checkTarget(ctx, taskConf, host, event)
+				if code == define.CodeOK {
+					event.SuccessOrTimeout()
+				} else {
+					event.Fail(code)
+				}
+			}(taskHost, targetHost)
+		}
+	}
+	wg.Wait()
+}
+
+// New :
+func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
+	gather := &Gather{}
+	gather.GlobalConfig = globalConfig
+	gather.TaskConfig = taskConfig
+	gather.Init()
+
+	return gather
+}
@@ -0,0 +1,236 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/reflection/grpc_testing"
+	"google.golang.org/grpc/status"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+type searchServer struct {
+	grpc_testing.UnimplementedSearchServiceServer
+}
+
+// Search 返回请求的 query 以及 metadata 中的 token
+func (s *searchServer) Search(ctx context.Context, req *grpc_testing.SearchRequest) (*grpc_testing.SearchResponse, error) {
+	if req.GetQuery() == "missing" {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("token")) > 0 {
+		token = md.Get("token")[0]
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "r-1"))
+	return &grpc_testing.SearchResponse{
+		Results: []*grpc_testing.SearchResponse_Result{
+			{Url: "http://bk.tencent.com", Title: req.GetQuery(), Snippets: []string{token}},
+		},
+	}, nil
+}
+
+func serve(t *testing.T, opts ...grpc.ServerOption) (int, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	s := grpc.NewServer(opts...)
+	hs := health.NewServer()
+	hs.SetServingStatus("bk.stopped", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, hs)
+	grpc_testing.RegisterSearchServiceServer(s, &searchServer{})
+	reflection.Register(s)
+	go func() {
+		_ = s.Serve(ln)
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, s.Stop
+}
+
+func newGather(t *testing.T, taskConf *configs.GRPCTaskConfig) *Gather {
+	globalConf := configs.NewConfig()
+	// 提供一个心跳的data_id，防止命中data_id防御机制
+	globalConf.HeartBeat.GlobalDataID = 1000
+
+	assert.Nil(t, globalConf.Clean())
+	assert.Nil(t, taskConf.Clean())
+	return New(globalConf, taskConf).(*Gather)
+}
+
+func runGather(t *testing.T, taskConf *configs.GRPCTaskConfig) map[string]interface{} {
+	g := newGather(t, taskConf)
+	e := make(chan define.Event, 1)
+	g.Run(context.Background(), e)
+	g.Wait()
+	return (<-e).AsMapStr()
+}
+
+func TestGather(t *testing.T) {
+	port, stop := serve(t)
+	defer stop()
+
+	testCases := map[string]struct {
+		method       string
+		service      string
+		request      string
+		expectedCode string
+		assertions   []*configs.HTTPAssertionConfig
+		errorCode    define.NamedCode
+		responseCode codes.Code
+	}{
+		"健康检查": {
+			errorCode: define.CodeOK,
+		},
+		"服务不健康": {
+			service:   "bk.stopped",
+			errorCode: define.CodeResponseNotMatch,
+		},
+		"服务不存在": {
+			service:      "bk.unknown",
+			errorCode:    define.CodeResponseNotMatch,
+			responseCode: codes.NotFound,
+		},
+		"反射调用方法": {
+			method:  "grpc.testing.SearchService/Search",
+			request: `{"query": "bk"}`,
+			assertions: []*configs.HTTPAssertionConfig{
+				{Type: configs.HTTPAssertionJSON, Expr: "results.0.title", Value: "bk"},
+				{Type: configs.HTTPAssertionJSON, Expr: "results.0.snippets.0", Value: "t-1"},
+				{Type: configs.HTTPAssertionHeader, Expr: "X-Request-Id", Value: "r-1"},
+				{Type: configs.HTTPAssertionResponseTime, Operator: configs.HTTPOperatorLt, Value: "3s"},
+			},
+			errorCode: define.CodeOK,
+		},
+		"断言失败": {
+			method:  "/grpc.testing.SearchService/Search",
+			request: `{"query": "bk"}`,
+			assertions: []*configs.HTTPAssertionConfig{
+				{Type: configs.HTTPAssertionJSON, Expr: "results.0.title", Value: "monitor"},
+			},
+			errorCode: define.CodeAssertionFailed,
+		},
+		"期望的错误状态码": {
+			method:       "grpc.testing.SearchService/Search",
+			request:      `{"query": "missing"}`,
+			expectedCode: "not_found",
+			assertions: []*configs.HTTPAssertionConfig{
+				{Type: configs.HTTPAssertionStatusCode, Value: "5"},
+			},
+			errorCode:    define.CodeOK,
+			responseCode: codes.NotFound,
+		},
+		"请求内容不合法": {
+			method:    "grpc.testing.SearchService/Search",
+			request:   `{"unknown": "bk"}`,
+			errorCode: define.CodeBadRequestParams,
+		},
+		"方法不存在": {
+			method:    "grpc.testing.SearchService/Unknown",
+			errorCode: define.CodeReflectionFailed,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			taskConf := configs.NewGRPCTaskConfig()
+			taskConf.TargetHost = "127.0.0.1"
+			taskConf.TargetPort = port
+			taskConf.Method = c.method
+			taskConf.Service = c.service
+			taskConf.Request = c.request
+			taskConf.Metadata = map[string]string{"token": "t-1"}
+			taskConf.ExpectedCode = c.expectedCode
+			taskConf.Assertions = c.assertions
+
+			event := runGather(t, taskConf)
+			assert.Equal(t, c.errorCode.Code(), event["error_code"])
+			assert.Equal(t, taskConf.GetMethod(), event["method"])
+			if c.errorCode == define.CodeOK || c.responseCode != codes.OK {
+				assert.Equal(t, int(c.responseCode), event["response_code"])
+				assert.Equal(t, c.responseCode.String(), event["response_code_name"])
+			}
+		})
+	}
+}
+
+func TestGatherTLS(t *testing.T) {
+	// 使用 httptest 的自签名证书
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cert := server.TLS.Certificates[0]
+	server.Close()
+
+	// 记录服务端收到的请求数，证书校验失败时不能发送任何请求
+	var calls atomic.Int32
+	port, stop := serve(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls.Add(1)
+			return handler(ctx, req)
+		}),
+	)
+	defer stop()
+
+	testCases := map[string]struct {
+		tls        bool
+		skipVerify bool
+		errorCode  define.NamedCode
+	}{
+		"忽略证书校验": {
+			tls:        true,
+			skipVerify: true,
+			errorCode:  define.CodeOK,
+		},
+		"自签名证书校验失败": {
+			tls:       true,
+			errorCode: define.CodeCertInvalid,
+		},
+		"未开启 TLS": {
+			errorCode: define.CodeConnFailed,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			taskConf := configs.NewGRPCTaskConfig()
+			taskConf.TargetHost = "127.0.0.1"
+			taskConf.TargetPort = port
+			taskConf.TLS = c.tls
+			taskConf.ServerName = "example.com"
+			taskConf.InsecureSkipVerify = c.skipVerify
+			taskConf.Timeout = time.Second
+			calls.Store(0)
+
+			event := runGather(t, taskConf)
+			assert.Equal(t, c.errorCode.Code(), event["error_code"])
+			if c.tls {
+				assert.Equal(t, "TLS 1.3", event["tls_version"])
+				assert.Contains(t, event["cert_sans"], "example.com")
+			}
+			if c.errorCode == define.CodeCertInvalid {
+				assert.Equal(t, int32(0), calls.Load())
+			}
+		})
+	}
+}
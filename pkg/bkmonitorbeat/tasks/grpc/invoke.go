@@ -0,0 +1,179 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+)
+
+// loadRootCAs 加载自定义 CA，未配置时返回空，使用系统根证书
+func loadRootCAs(taskConf *configs.GRPCTaskConfig) (*x509.CertPool, error) {
+	if taskConf.CAFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(taskConf.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificate found in %s", taskConf.CAFile)
+	}
+	return pool, nil
+}
+
+// newTransportCredentials 跳过默认的证书检查，在握手时通过 VerifyConnection 记录证书信息并校验证书，校验失败时握手失败，不会发送任何请求
+func newTransportCredentials(taskConf *configs.GRPCTaskConfig, serverName string, roots *x509.CertPool, record func(*tasks.TLSInfo)) (credentials.TransportCredentials, error) {
+	if !taskConf.TLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	conf := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		VerifyConnection:   tasks.VerifyConnection(serverName, roots, taskConf.InsecureSkipVerify, record),
+	}
+	if taskConf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(taskConf.CertFile, taskConf.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+	index := strings.LastIndex(method, "/")
+	return method[:index], method[index+1:]
+}
+
+// resolveMethod 通过服务端反射获取方法的描述信息
+func resolveMethod(ctx context.Context, conn *grpc.ClientConn, method string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName := splitMethod(method)
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.CloseSend()
+	}()
+
+	request := func(req *rpb.ServerReflectionRequest) ([][]byte, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, errors.Errorf("reflection error: %s", e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}
+
+	// 获取服务所在的文件，再逐个补齐依赖的文件
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	pending, err := request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	})
+	for err == nil && len(pending) > 0 {
+		missing := make(map[string]struct{})
+		for _, raw := range pending {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err = proto.Unmarshal(raw, fd); err != nil {
+				return nil, err
+			}
+			files[fd.GetName()] = fd
+		}
+		for _, fd := range files {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := files[dep]; !ok {
+					missing[dep] = struct{}{}
+				}
+			}
+		}
+
+		pending = nil
+		for name := range missing {
+			var raws [][]byte
+			raws, err = request(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+			})
+			if err != nil {
+				break
+			}
+			pending = append(pending, raws...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range files {
+		set.File = append(set.File, fd)
+	}
+	registry, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := registry.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a service", serviceName)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, errors.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, errors.Errorf("streaming method %s is not supported", method)
+	}
+	return md, nil
+}
+
+// newRequest 根据 JSON 请求内容生成请求消息
+func newRequest(md protoreflect.MethodDescriptor, request string) (*dynamicpb.Message, error) {
+	req := dynamicpb.NewMessage(md.Input())
+	if strings.TrimSpace(request) == "" {
+		return req, nil
+	}
+	if err := protojson.Unmarshal([]byte(request), req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
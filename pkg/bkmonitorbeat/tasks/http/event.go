@@ -10,6 +10,7 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -35,6 +36,10 @@ type Event struct {
 	ContentLength int
 	MediaType     string
 	ResolvedIP    string
+	TLS           *tasks.TLSInfo
+
+	// StepDetails 事务模式下每个步骤的结果以及耗时
+	StepDetails []*StepDetail
 }
 
 func NewEvent(g *Gather) *Event {
@@ -61,6 +66,16 @@ func (e *Event) AsMapStr() common.MapStr {
 	mapStr["content_length"] = e.ContentLength
 	mapStr["media_type"] = e.MediaType
 	mapStr["resolved_ip"] = e.ResolvedIP
+	if e.TLS != nil {
+		mapStr.Update(e.TLS.AsMapStr())
+	}
+	if e.StepDetails != nil {
+		details := make([]common.MapStr, 0, len(e.StepDetails))
+		for _, d := range e.StepDetails {
+			details = append(details, d.AsMapStr())
+		}
+		mapStr["step_details"] = details
+	}
 	return mapStr
 }
 
@@ -81,6 +96,13 @@ func (e *Event) Fail(code define.NamedCode) {
 
 func (e *Event) FailFromError(err error) {
 	e.Message = err.Error()
+	// 证书校验失败时记录证书信息
+	var certErr *tasks.CertInvalidError
+	if errors.As(err, &certErr) {
+		e.TLS = certErr.Info
+		e.Fail(define.CodeCertInvalid)
+		return
+	}
 	switch typ := err.(type) {
 	case *url.Error:
 		if typ.Timeout() {
@@ -96,32 +118,45 @@ func NewCustomEventByHttpEvent(e *Event) *tasks.CustomEvent {
 
 	hostInfo, _ := gse.GetAgentInfo()
 
+	dimensions := map[string]string{
+		"bk_biz_id":     strconv.Itoa(int(e.BizID)),
+		"url":           e.URL,
+		"method":        e.Method,
+		"response_code": strconv.Itoa(e.ResponseCode),
+		"message":       e.Message,
+		"error_code":    strconv.Itoa(e.ErrorCode.Code()),
+		"media_type":    e.MediaType,
+		"resolved_ip":   e.ResolvedIP,
+		"status":        strconv.Itoa(int(e.Status)),
+		"task_id":       strconv.Itoa(int(e.TaskID)),
+		"task_type":     e.TaskType,
+		"node_id":       fmt.Sprintf("%d:%s", hostInfo.Cloudid, hostInfo.IP),
+		"ip":            hostInfo.IP,
+		"bk_cloud_id":   strconv.Itoa(int(hostInfo.Cloudid)),
+		"bk_agent_id":   hostInfo.BKAgentID,
+	}
+	metrics := map[string]interface{}{
+		"available":     e.Available,
+		"task_duration": int(e.TaskDuration().Milliseconds()),
+	}
+
+	// 证书信息
+	if e.TLS != nil {
+		for k, v := range e.TLS.Dimensions() {
+			dimensions[k] = v
+		}
+		for k, v := range e.TLS.Metrics() {
+			metrics[k] = v
+		}
+	}
+
 	data := common.MapStr{
 		"dataid": e.DataID,
 		"data": []map[string]interface{}{
 			{
-				"target": e.URL,
-				"dimension": map[string]string{
-					"bk_biz_id":     strconv.Itoa(int(e.BizID)),
-					"url":           e.URL,
-					"method":        e.Method,
-					"response_code": strconv.Itoa(e.ResponseCode),
-					"message":       e.Message,
-					"error_code":    strconv.Itoa(e.ErrorCode.Code()),
-					"media_type":    e.MediaType,
-					"resolved_ip":   e.ResolvedIP,
-					"status":        strconv.Itoa(int(e.Status)),
-					"task_id":       strconv.Itoa(int(e.TaskID)),
-					"task_type":     e.TaskType,
-					"node_id":       fmt.Sprintf("%d:%s", hostInfo.Cloudid, hostInfo.IP),
-					"ip":            hostInfo.IP,
-					"bk_cloud_id":   strconv.Itoa(int(hostInfo.Cloudid)),
-					"bk_agent_id":   hostInfo.BKAgentID,
-				},
-				"metrics": map[string]interface{}{
-					"available":     e.Available,
-					"task_duration": int(e.TaskDuration().Milliseconds()),
-				},
+				"target":    e.URL,
+				"dimension": dimensions,
+				"metrics":   metrics,
 				"timestamp": ts * 1000,
 			},
 		},
@@ -15,6 +15,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -100,7 +101,6 @@ func checkResponseCode(step *configs.HTTPTaskStepConfig, response *http.Response
 type Gather struct {
 	tasks.BaseTask
 	contentTypeRegexp *regexp.Regexp
-	bufferBuilder     tasks.BufferBuilder
 }
 
 // UpdateEventByResponse 根据返回写入结果数据
@@ -109,6 +109,15 @@ func (g *Gather) UpdateEventByResponse(event *Event, response *http.Response) {
 	event.ResponseCode = response.StatusCode
 	event.ContentLength, _ = strconv.Atoi(response.Header.Get("Content-Length"))
 
+	// https 请求记录握手以及证书信息
+	if response.TLS != nil {
+		var serverName string
+		if response.Request != nil {
+			serverName = response.Request.URL.Hostname()
+		}
+		event.TLS = tasks.NewTLSInfo(response.TLS, serverName, nil)
+	}
+
 	matches := g.contentTypeRegexp.FindStringSubmatch(response.Header.Get("Content-Type"))
 	if len(matches) > 0 {
 		for index, name := range g.contentTypeRegexp.SubexpNames() {
@@ -152,31 +161,37 @@ type Client interface {
 
 // GatherURL 测试链接并设置结果事件，url为请求的链接，proxyHost和proxyIP为需要代理的host和ip
 func (g *Gather) GatherURL(ctx context.Context, event *Event, step *configs.HTTPTaskStepConfig, url, host string) bool {
-	var (
-		ok    bool
-		count int
-		err   error
-	)
-
 	conf := g.GetConfig().(*configs.HTTPTaskConfig)
 	client := NewClient(conf, map[string]string{host: host})
 	utils.RecoverFor(func(err error) {
 		logger.Errorf("panic: %v", err)
 	})
 
+	_, ok := g.gatherStep(ctx, client, event, step, url)
+	if ok {
+		event.SuccessOrTimeout()
+	}
+	return ok
+}
+
+// gatherStep 执行单个步骤并检查响应，失败时设置事件状态，成功时返回响应结果用于变量提取
+func (g *Gather) gatherStep(ctx context.Context, client Client, event *Event, step *configs.HTTPTaskStepConfig, url string) (*StepResponse, bool) {
+	conf := g.GetConfig().(*configs.HTTPTaskConfig)
+	start := time.Now()
+
 	// 初始化请求
 	request, err := g.makeRequest(ctx, step, url)
 	if err != nil {
 		logger.Error(err)
 		event.Fail(define.CodeBadRequestParams)
-		return false
+		return nil, false
 	}
 	// 获取结果
 	response, err := client.Do(request)
 	if err != nil {
 		logger.Errorf("task(%d) request failed, url=%v, err: %v", conf.TaskID, url, err)
 		event.FailFromError(err)
-		return false
+		return nil, false
 	}
 	defer response.Body.Close()
 
@@ -186,32 +201,27 @@ func (g *Gather) GatherURL(ctx context.Context, event *Event, step *configs.HTTP
 	// 检查响应状态码是否符合预期
 	if !checkResponseCode(step, response) {
 		event.Fail(define.CodeResponseNotMatch)
-		return false
-	}
-	// 未配置响应内容无需检查
-	if step.Response == "" {
-		event.SuccessOrTimeout()
-		return true
+		return nil, false
 	}
 
-	// 读取响应内容明文reader
-	responseRd := makeResponseReader(response)
-	if responseRd == nil {
-		event.Fail(define.CodeResponseFailed)
-		return false
-	}
-	defer responseRd.Close()
+	result := &StepResponse{Response: response}
+	// 未配置响应内容、body 相关的断言以及变量提取时无需读取响应内容
+	if step.NeedBody() {
+		// 读取响应内容明文reader
+		responseRd := makeResponseReader(response)
+		if responseRd == nil {
+			event.Fail(define.CodeResponseFailed)
+			return nil, false
+		}
+		defer responseRd.Close()
 
-	if step.Response != "" {
 		// 读取响应内容字符串
-		body := g.bufferBuilder.GetBuffer(conf.BufferSize)
-		count, err = responseRd.Read(body)
-		if err != nil && err != io.EOF {
+		body, err := io.ReadAll(io.LimitReader(responseRd, int64(conf.BufferSize)))
+		if err != nil {
 			logger.Debugf("task(%d): %v read response error: %v", conf.TaskID, url, err)
 			event.FailFromError(err)
-			return false
+			return nil, false
 		}
-		body = body[:count]
 		// 根据返回编码转码为utf8
 		decoder := utils.NewDecoder(event.Charset)
 		if decoder != nil {
@@ -221,16 +231,26 @@ func (g *Gather) GatherURL(ctx context.Context, event *Event, step *configs.HTTP
 				body = decoded
 			}
 		}
-		// 对比响应内容是否符合配置
-		logger.Debugf("task(%d): %v response: %s", conf.TaskID, url, body)
-		ok = utils.IsMatch(step.ResponseFormat, body, []byte(step.Response))
-		if !ok {
+		result.Body = body
+	}
+	result.Duration = time.Since(start)
+
+	// 对比响应内容是否符合配置
+	if step.Response != "" {
+		logger.Debugf("task(%d): %v response: %s", conf.TaskID, url, result.Body)
+		if !utils.IsMatch(step.ResponseFormat, result.Body, []byte(step.Response)) {
 			event.Fail(define.CodeResponseNotMatch)
-			return false
+			return nil, false
 		}
 	}
-	event.SuccessOrTimeout()
-	return true
+
+	if a, ok := CheckAssertions(step.Assertions, result); !ok {
+		logger.Debugf("task(%d): %v assertion failed: %s %s %s %s", conf.TaskID, url, a.Type, a.Expr, a.Operator, a.Value)
+		event.Message = fmt.Sprintf("assertion failed: %s %s %s %s", a.Type, a.Expr, a.Operator, a.Value)
+		event.Fail(define.CodeAssertionFailed)
+		return nil, false
+	}
+	return result, true
 }
 
 // NewClient proxyMap代理配置 key: host value: proxy ip, 如{"example.com": "127.0.0.1"}
@@ -246,8 +266,9 @@ var NewClient = func(conf *configs.HTTPTaskConfig, proxyMap map[string]string) C
 		MaxResponseHeaderBytes: int64(conf.BufferSize),
 		DisableKeepAlives:      true,
 		TLSClientConfig: &tls.Config{
-			// 跳过https证书检查
-			InsecureSkipVerify: conf.InsecureSkipVerify,
+			// 跳过默认的证书检查，在握手时校验证书，证书校验失败时返回带有证书信息的错误
+			InsecureSkipVerify: true,
+			VerifyConnection:   tasks.VerifyConnection("", nil, conf.InsecureSkipVerify, nil),
 			Renegotiation:      tls.RenegotiateFreelyAsClient,
 		},
 		Proxy: func(_ *http.Request) (*url.URL, error) {
@@ -298,7 +319,13 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 	g.PreRun(ctx)
 	defer g.PostRun(ctx)
 
+	if conf.Transaction {
+		g.runTransaction(ctx, e)
+		return
+	}
+
 	for index, step := range conf.Steps {
+		step = Variables(conf.Variables).RenderStep(step)
 		urls := step.URLs()
 		if len(urls) == 0 {
 			continue
@@ -314,11 +341,7 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 				event := NewEvent(g)
 				event.ToStep(index, step.Method, h.Host)
 				event.Fail(h.Errno)
-				if conf.CustomReport {
-					e <- NewCustomEventByHttpEvent(event)
-				} else {
-					e <- event
-				}
+				g.sendEvent(e, event)
 			} else {
 				resolvedIPs[h.Host] = h.Ips
 			}
@@ -340,11 +363,7 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 				cancelFunc()
 				event.EndAt = time.Now()
 				g.GetSemaphore().Release(1)
-				if conf.CustomReport {
-					e <- NewCustomEventByHttpEvent(event)
-				} else {
-					e <- event
-				}
+				g.sendEvent(e, event)
 			}()
 			g.GatherURL(subCtx, event, arg.stepConfig, arg.url, arg.resolvedIP)
 		}
@@ -370,6 +389,136 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 	}
 }
 
+// sendEvent 根据配置上报自定义事件或者拨测事件
+func (g *Gather) sendEvent(e chan<- define.Event, event *Event) {
+	conf := g.GetConfig().(*configs.HTTPTaskConfig)
+	if conf.CustomReport {
+		e <- NewCustomEventByHttpEvent(event)
+	} else {
+		e <- event
+	}
+}
+
+// runTransaction 事务模式：按顺序执行所有步骤，步骤之间共享 cookie 以及变量，任一步骤失败时中止，整个事务上报一条事件
+func (g *Gather) runTransaction(ctx context.Context, e chan<- define.Event) {
+	conf := g.GetConfig().(*configs.HTTPTaskConfig)
+
+	err := g.GetSemaphore().Acquire(ctx, 1)
+	if err != nil {
+		logger.Errorf("task(%d) semaphore acquire failed", g.TaskConfig.GetTaskID())
+		return
+	}
+	defer g.GetSemaphore().Release(1)
+
+	// 超时时间作用于整个事务
+	subCtx, cancelFunc := context.WithTimeout(ctx, conf.GetTimeout())
+	defer cancelFunc()
+
+	vars := make(Variables, len(conf.Variables))
+	for k, v := range conf.Variables {
+		vars[k] = v
+	}
+
+	// 所有步骤使用同一个客户端，保证 cookie 在步骤之间传递
+	// 每个步骤请求前预先解析域名，并通过 proxyMap 固定域名对应的 ip
+	proxyMap := make(map[string]string)
+	client := NewClient(conf, proxyMap)
+	utils.RecoverFor(func(err error) {
+		logger.Errorf("panic: %v", err)
+	})
+
+	event := NewEvent(g)
+	event.StepDetails = make([]*StepDetail, 0, len(conf.Steps))
+	defer func() {
+		event.EndAt = time.Now()
+		g.sendEvent(e, event)
+	}()
+
+	for index, s := range conf.Steps {
+		step := vars.RenderStep(s)
+		// 事务模式下每个步骤只请求第一个 url
+		url := step.URLs()[0]
+
+		event.ToStep(index+1, step.Method, url)
+		event.ResponseCode = 0
+		event.Message = ""
+
+		host, ip, code := g.resolveURL(subCtx, url)
+		if code != define.CodeOK {
+			event.Fail(code)
+			event.StepDetails = append(event.StepDetails, &StepDetail{
+				Index:     index + 1,
+				URL:       url,
+				Method:    step.Method,
+				ErrorCode: code,
+			})
+			return
+		}
+		proxyMap[host] = ip
+
+		timer := newStepTimer()
+		result, ok := g.gatherStep(timer.WithContext(subCtx), client, event, step, url)
+		if ok {
+			if err = vars.Extract(step, result); err != nil {
+				logger.Debugf("task(%d): %v %v", conf.TaskID, url, err)
+				event.Message = err.Error()
+				event.Fail(define.CodeExtractFailed)
+				ok = false
+			}
+		}
+		if !ok && event.ErrorCode == define.CodeUnknown {
+			event.Fail(define.CodeRequestFailed)
+		}
+
+		detail := &StepDetail{
+			Index:        index + 1,
+			URL:          url,
+			Method:       step.Method,
+			ResponseCode: event.ResponseCode,
+			Message:      event.Message,
+			ErrorCode:    define.CodeOK,
+		}
+		if !ok {
+			detail.ErrorCode = event.ErrorCode
+		}
+		timer.Fill(detail)
+		if detail.ResolvedIP == "" {
+			detail.ResolvedIP = ip
+		}
+		event.ResolvedIP = detail.ResolvedIP
+		event.StepDetails = append(event.StepDetails, detail)
+
+		if !ok {
+			return
+		}
+	}
+	event.SuccessOrTimeout()
+}
+
+// resolveURL 按照 ip 类型配置解析 url 中的域名，事务模式下只请求解析结果中的第一个 ip
+func (g *Gather) resolveURL(ctx context.Context, rawURL string) (string, string, define.NamedCode) {
+	conf := g.GetConfig().(*configs.HTTPTaskConfig)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", define.CodeInvalidURL
+	}
+
+	var ip string
+	for _, h := range tasks.GetHostsInfo(ctx, []string{rawURL}, configs.CheckModeSingle, conf.TargetIPType, configs.Http) {
+		if h.Errno != define.CodeOK {
+			return "", "", h.Errno
+		}
+		if ip == "" && len(h.Ips) > 0 {
+			ip = h.Ips[0]
+		}
+	}
+	if ip == "" {
+		return "", "", define.CodeIPNotFound
+	}
+	return u.Hostname(), ip, define.CodeOK
+}
+
 func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
 	gather := &Gather{
 		contentTypeRegexp: regexp.MustCompile(`(?P<mediatype>[^;\s]*)\s*;?\s*(?:charset\s*=\s*(?P<charset>[^;\s]*)|)\s*;?\s*`),
@@ -0,0 +1,260 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/utils"
+)
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.\-]*)\}`)
+
+// Variables 事务中的变量，key 为变量名
+type Variables map[string]string
+
+// Render 替换字符串中的 ${name}，未定义的变量保持原样
+func (v Variables) Render(s string) string {
+	if len(v) == 0 || !strings.Contains(s, "${") {
+		return s
+	}
+	return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		if val, ok := v[m[2:len(m)-1]]; ok {
+			return val
+		}
+		return m
+	})
+}
+
+// RenderStep 使用变量渲染 url、headers 以及请求内容，返回新的步骤配置
+func (v Variables) RenderStep(step *configs.HTTPTaskStepConfig) *configs.HTTPTaskStepConfig {
+	if len(v) == 0 {
+		return step
+	}
+
+	s := *step
+	s.URL = v.Render(step.URL)
+	if len(step.URLList) > 0 {
+		s.URLList = make([]string, 0, len(step.URLList))
+		for _, u := range step.URLList {
+			s.URLList = append(s.URLList, v.Render(u))
+		}
+	}
+	// hex 格式的请求内容不支持变量
+	if step.RequestFormat != utils.ConvTypeHex {
+		s.Request = v.Render(step.Request)
+	}
+	s.Headers = make(map[string]string, len(step.Headers))
+	for key, value := range step.Headers {
+		s.Headers[key] = v.Render(value)
+	}
+	return &s
+}
+
+// Extract 按照配置从响应中提取变量，提取失败并且没有默认值时返回错误
+func (v Variables) Extract(step *configs.HTTPTaskStepConfig, r *StepResponse) error {
+	for _, e := range step.Extractors {
+		value, ok := extract(e, r)
+		if !ok {
+			if e.Default == "" {
+				return errors.Errorf("extract variable %s by %s(%s) failed", e.Name, e.Type, e.Expr)
+			}
+			value = e.Default
+		}
+		v[e.Name] = value
+	}
+	return nil
+}
+
+// StepResponse 步骤的响应结果，用于变量提取以及断言
+type StepResponse struct {
+	Response *http.Response
+	Body     []byte
+	Duration time.Duration
+}
+
+func extract(e *configs.HTTPExtractorConfig, r *StepResponse) (string, bool) {
+	switch e.Type {
+	case configs.HTTPExtractorJSON:
+		res := gjson.GetBytes(r.Body, e.Expr)
+		return res.String(), res.Exists()
+	case configs.HTTPExtractorRegex:
+		// 正则在配置初始化时编译
+		re := e.Regex()
+		if re == nil {
+			return "", false
+		}
+		m := re.FindSubmatch(r.Body)
+		if m == nil {
+			return "", false
+		}
+		// 存在捕获组时取第一个捕获组
+		if len(m) > 1 {
+			return string(m[1]), true
+		}
+		return string(m[0]), true
+	case configs.HTTPExtractorHeader:
+		values := r.Response.Header.Values(e.Expr)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	case configs.HTTPExtractorCookie:
+		for _, c := range r.Response.Cookies() {
+			if c.Name == e.Expr {
+				return c.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// CheckAssertions 检查所有断言，返回第一个不满足的断言
+func CheckAssertions(assertions []*configs.HTTPAssertionConfig, r *StepResponse) (*configs.HTTPAssertionConfig, bool) {
+	for _, a := range assertions {
+		if !checkAssertion(a, r) {
+			return a, false
+		}
+	}
+	return nil, true
+}
+
+func checkAssertion(a *configs.HTTPAssertionConfig, r *StepResponse) bool {
+	var (
+		actual string
+		exists bool
+	)
+
+	switch a.Type {
+	case configs.HTTPAssertionJSON:
+		res := gjson.GetBytes(r.Body, a.Expr)
+		actual, exists = res.String(), res.Exists()
+	case configs.HTTPAssertionHeader:
+		values := r.Response.Header.Values(a.Expr)
+		if len(values) > 0 {
+			actual, exists = values[0], true
+		}
+	case configs.HTTPAssertionBody:
+		actual, exists = string(r.Body), true
+	case configs.HTTPAssertionStatusCode:
+		actual, exists = strconv.Itoa(r.Response.StatusCode), true
+	case configs.HTTPAssertionResponseTime:
+		expected, ok := tasks.ParseMilliseconds(a.Value)
+		if !ok {
+			return false
+		}
+		return tasks.CompareNumber(a.Operator, float64(r.Duration)/float64(time.Millisecond), expected)
+	}
+
+	return tasks.MatchValue(a.Operator, actual, exists, a.Value)
+}
+
+// StepDetail 事务模式下每个步骤的结果以及耗时
+type StepDetail struct {
+	Index        int
+	URL          string
+	Method       string
+	ResponseCode int
+	ErrorCode    define.NamedCode
+	Message      string
+	ResolvedIP   string
+
+	DNSTime       time.Duration
+	ConnectTime   time.Duration
+	TLSTime       time.Duration
+	FirstByteTime time.Duration
+	Duration      time.Duration
+}
+
+// AsMapStr 耗时单位为毫秒
+func (d *StepDetail) AsMapStr() common.MapStr {
+	return common.MapStr{
+		"index":           d.Index,
+		"url":             d.URL,
+		"method":          d.Method,
+		"response_code":   d.ResponseCode,
+		"error_code":      d.ErrorCode.Code(),
+		"message":         d.Message,
+		"resolved_ip":     d.ResolvedIP,
+		"dns_time":        int(d.DNSTime.Milliseconds()),
+		"connect_time":    int(d.ConnectTime.Milliseconds()),
+		"tls_time":        int(d.TLSTime.Milliseconds()),
+		"first_byte_time": int(d.FirstByteTime.Milliseconds()),
+		"duration":        int(d.Duration.Milliseconds()),
+	}
+}
+
+// stepTimer 通过 httptrace 记录请求各阶段的耗时
+type stepTimer struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	remoteAddr   string
+}
+
+func newStepTimer() *stepTimer {
+	return &stepTimer{start: time.Now()}
+}
+
+func (t *stepTimer) WithContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tlsDone = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+	})
+}
+
+func since(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// Fill 将耗时写入步骤结果
+func (t *stepTimer) Fill(d *StepDetail) {
+	d.DNSTime = since(t.dnsStart, t.dnsDone)
+	d.ConnectTime = since(t.connectStart, t.connectDone)
+	d.TLSTime = since(t.tlsStart, t.tlsDone)
+	d.FirstByteTime = since(t.start, t.firstByte)
+	d.Duration = time.Since(t.start)
+	if host, _, err := net.SplitHostPort(t.remoteAddr); err == nil {
+		d.ResolvedIP = host
+	}
+}
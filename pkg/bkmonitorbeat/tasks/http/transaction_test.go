@@ -0,0 +1,260 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+func TestVariablesRender(t *testing.T) {
+	vars := Variables{"token": "abc", "user.id": "1"}
+	assert.Equal(t, "Bearer abc", vars.Render("Bearer ${token}"))
+	assert.Equal(t, "/users/1?t=abc", vars.Render("/users/${user.id}?t=${token}"))
+	assert.Equal(t, "${unknown}", vars.Render("${unknown}"))
+
+	step := &configs.HTTPTaskStepConfig{
+		URL:     "http://127.0.0.1/${user.id}",
+		Headers: map[string]string{"Authorization": "Bearer ${token}"},
+		SimpleMatchParam: configs.SimpleMatchParam{
+			Request: `{"token": "${token}"}`,
+		},
+	}
+	rendered := vars.RenderStep(step)
+	assert.Equal(t, "http://127.0.0.1/1", rendered.URL)
+	assert.Equal(t, "Bearer abc", rendered.Headers["Authorization"])
+	assert.Equal(t, `{"token": "abc"}`, rendered.Request)
+	// 原始配置不会被修改
+	assert.Equal(t, "Bearer ${token}", step.Headers["Authorization"])
+}
+
+func TestCheckAssertion(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Request-Id", "r-1")
+	r := &StepResponse{
+		Response: &http.Response{StatusCode: 201, Header: header},
+		Body:     []byte(`{"data": {"name": "admin", "count": 3, "tags": ["a", "b"]}}`),
+		Duration: 120 * time.Millisecond,
+	}
+
+	testCases := map[string]struct {
+		assertion configs.HTTPAssertionConfig
+		ok        bool
+	}{
+		"json 等于": {
+			assertion: configs.HTTPAssertionConfig{Type: "json", Expr: "data.name", Operator: "eq", Value: "admin"},
+			ok:        true,
+		},
+		"json 数值比较": {
+			assertion: configs.HTTPAssertionConfig{Type: "json", Expr: "data.count", Operator: "ge", Value: "3"},
+			ok:        true,
+		},
+		"json 数组长度": {
+			assertion: configs.HTTPAssertionConfig{Type: "json", Expr: "data.tags.#", Operator: "lt", Value: "2"},
+		},
+		"json 字段不存在": {
+			assertion: configs.HTTPAssertionConfig{Type: "json", Expr: "data.age", Operator: "nexists"},
+			ok:        true,
+		},
+		"header 正则": {
+			assertion: configs.HTTPAssertionConfig{Type: "header", Expr: "X-Request-Id", Operator: "reg", Value: `^r-\d+$`},
+			ok:        true,
+		},
+		"header 不存在": {
+			assertion: configs.HTTPAssertionConfig{Type: "header", Expr: "X-Trace-Id", Operator: "eq", Value: ""},
+		},
+		"body 包含": {
+			assertion: configs.HTTPAssertionConfig{Type: "body", Operator: "contains", Value: "admin"},
+			ok:        true,
+		},
+		"状态码": {
+			assertion: configs.HTTPAssertionConfig{Type: "status_code", Operator: "eq", Value: "200"},
+		},
+		"响应时间": {
+			assertion: configs.HTTPAssertionConfig{Type: "response_time", Operator: "lt", Value: "500ms"},
+			ok:        true,
+		},
+		"响应时间毫秒": {
+			assertion: configs.HTTPAssertionConfig{Type: "response_time", Operator: "le", Value: "100"},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, c.assertion.Clean())
+			assert.Equal(t, c.ok, checkAssertion(&c.assertion, r))
+		})
+	}
+}
+
+func newTransactionGather(t *testing.T, steps []*configs.HTTPTaskStepConfig, vars map[string]string) *Gather {
+	globalConf := configs.NewConfig()
+	// 提供一个心跳的data_id，防止命中data_id防御机制
+	globalConf.HeartBeat.GlobalDataID = 1000
+
+	taskConf := configs.NewHTTPTaskConfig()
+	taskConf.Transaction = true
+	taskConf.Variables = vars
+	taskConf.Steps = steps
+
+	assert.Nil(t, globalConf.Clean())
+	assert.Nil(t, taskConf.Clean())
+	return New(globalConf, taskConf).(*Gather)
+}
+
+func TestGatherTransaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"user": "admin"`) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1"})
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data": {"token": "t-1"}}`))
+		case "/profile":
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != "s-1" || r.Header.Get("Authorization") != "Bearer t-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Request-Id", "r-1")
+			_, _ = w.Write([]byte(`{"name": "admin", "id": 10}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	newSteps := func(name string, extractType string, extractExpr string) []*configs.HTTPTaskStepConfig {
+		return []*configs.HTTPTaskStepConfig{
+			{
+				URL:    server.URL + "/login",
+				Method: http.MethodPost,
+				SimpleMatchParam: configs.SimpleMatchParam{
+					Request: `{"user": "${user}"}`,
+				},
+				Extractors: []*configs.HTTPExtractorConfig{
+					{Name: "token", Type: extractType, Expr: extractExpr},
+				},
+			},
+			{
+				URL:     server.URL + "/profile",
+				Headers: map[string]string{"Authorization": "Bearer ${token}"},
+				Assertions: []*configs.HTTPAssertionConfig{
+					{Type: "json", Expr: "name", Value: name},
+					{Type: "header", Expr: "X-Request-Id", Operator: "exists"},
+					{Type: "response_time", Operator: "lt", Value: "5s"},
+				},
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		steps     []*configs.HTTPTaskStepConfig
+		errorCode define.NamedCode
+		status    int32
+		details   int
+		ip        string
+	}{
+		"登录之后调用接口": {
+			steps:     newSteps("admin", "json", "data.token"),
+			errorCode: define.CodeOK,
+			status:    define.GatherStatusOK,
+			details:   2,
+			ip:        "127.0.0.1",
+		},
+		"正则提取变量": {
+			steps:     newSteps("admin", "regex", `"token": "([^"]+)"`),
+			errorCode: define.CodeOK,
+			status:    define.GatherStatusOK,
+			details:   2,
+			ip:        "127.0.0.1",
+		},
+		"断言失败": {
+			steps:     newSteps("root", "json", "data.token"),
+			errorCode: define.CodeAssertionFailed,
+			status:    2,
+			details:   2,
+			ip:        "127.0.0.1",
+		},
+		"变量提取失败": {
+			steps:     newSteps("admin", "json", "data.not_exists"),
+			errorCode: define.CodeExtractFailed,
+			status:    1,
+			details:   1,
+			ip:        "127.0.0.1",
+		},
+		"域名解析失败": {
+			steps:     []*configs.HTTPTaskStepConfig{{URL: "http://not-exists.invalid/login"}},
+			errorCode: define.CodeDNSResolveFailed,
+			status:    1,
+			details:   1,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			g := newTransactionGather(t, c.steps, map[string]string{"user": "admin"})
+
+			e := make(chan define.Event, 1)
+			g.Run(context.Background(), e)
+			g.Wait()
+
+			event := (<-e).AsMapStr()
+			assert.Equal(t, c.errorCode.Code(), event["error_code"])
+			assert.Equal(t, c.status, event["status"])
+
+			details := event["step_details"].([]common.MapStr)
+			assert.Len(t, details, c.details)
+			last := details[len(details)-1]
+			assert.Equal(t, c.details, last["index"])
+			assert.Equal(t, c.errorCode.Code(), last["error_code"])
+			assert.Equal(t, c.ip, last["resolved_ip"])
+		})
+	}
+}
+
+func TestGatherCertInvalid(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	for _, skipVerify := range []bool{false, true} {
+		g := newTransactionGather(t, []*configs.HTTPTaskStepConfig{{URL: server.URL}}, nil)
+		g.GetConfig().(*configs.HTTPTaskConfig).InsecureSkipVerify = skipVerify
+
+		e := make(chan define.Event, 1)
+		g.Run(context.Background(), e)
+		g.Wait()
+
+		// 测试服务的证书不被系统根证书信任，跳过校验时依然上报证书信息
+		event := (<-e).AsMapStr()
+		if skipVerify {
+			assert.Equal(t, define.CodeOK.Code(), event["error_code"])
+		} else {
+			assert.Equal(t, define.CodeCertInvalid.Code(), event["error_code"])
+		}
+		assert.Equal(t, 0, event["cert_chain_valid"])
+		assert.Contains(t, event["cert_sans"], "example.com")
+	}
+}
@@ -127,6 +127,9 @@ type SendConfig struct {
 	OutputFormat string          // 上报方式
 	TimeUnit     string          // 上报时间单位
 	Label        []configs.Label // 配置下发模块信息
+
+	MaxDimensionValues int // 单个文件单条规则的维度组合上限
+	SampleSize         int // 事件附带的日志样例数量
 }
 
 type ProcessConfig struct {
@@ -141,4 +144,7 @@ type ProcessConfig struct {
 
 	// 日志关键字配置
 	KeywordConfigs []configs.KeywordConfig // 日志关键字配置信息
+
+	// 多行日志聚合配置
+	Multiline configs.MultilineConfig
 }
@@ -11,6 +11,7 @@ package processor
 
 import (
 	"regexp"
+	"time"
 
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/simplifiedchinese"
@@ -27,6 +28,7 @@ type EventProcessor struct {
 
 	filterRegs []*regexp.Regexp
 	rules      map[string]*regexp.Regexp
+	multiline  *MultilineAggregator // 未开启多行聚合时为 nil
 }
 
 func NewEventProcessor(cfg keyword.ProcessConfig) (*EventProcessor, error) {
@@ -68,6 +70,14 @@ func NewEventProcessor(cfg keyword.ProcessConfig) (*EventProcessor, error) {
 		p.rules[kfc.Name] = regex
 	}
 
+	if cfg.Multiline.Enabled() {
+		multiline, err := NewMultilineAggregator(cfg.Multiline)
+		if err != nil {
+			return nil, err
+		}
+		p.multiline = multiline
+	}
+
 	return p, nil
 }
 
@@ -98,6 +108,36 @@ func (client *EventProcessor) Handle(event *module.LogEvent) (interface{}, error
 		return results, nil
 	}
 
+	if client.multiline == nil {
+		results = client.match(results, event)
+	} else {
+		for _, e := range client.multiline.Add(event, time.Now()) {
+			results = client.match(results, e)
+		}
+	}
+
+	logger.Debugf("return event, count(%d), %v", len(results), results)
+	return results, nil
+}
+
+// Flush 处理聚合超时的多行日志，没有需要处理的日志时返回 nil
+func (client *EventProcessor) Flush(now time.Time) interface{} {
+	if client.multiline == nil {
+		return nil
+	}
+
+	var results []keyword.KeywordTaskResult
+	for _, e := range client.multiline.Flush(now) {
+		results = client.match(results, e)
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	return results
+}
+
+// match 使用所有规则匹配日志，正则中的命名分组作为维度
+func (client *EventProcessor) match(results []keyword.KeywordTaskResult, event *module.LogEvent) []keyword.KeywordTaskResult {
 	for ruleName, ruleRegex := range client.rules {
 		fields := ruleRegex.SubexpNames()
 		count := len(fields)
@@ -124,9 +164,7 @@ func (client *EventProcessor) Handle(event *module.LogEvent) (interface{}, error
 			results = append(results, res)
 		}
 	}
-
-	logger.Debugf("return event, count(%d), %v", len(results), results)
-	return results, nil
+	return results
 }
 
 func (client *EventProcessor) Send(event interface{}, outputs []chan<- interface{}) {
@@ -0,0 +1,126 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/keyword/module"
+)
+
+// multilineBlock 正在聚合中的多行日志
+type multilineBlock struct {
+	event *module.LogEvent
+	lines []string
+	last  time.Time
+}
+
+func (b *multilineBlock) toEvent() *module.LogEvent {
+	return &module.LogEvent{
+		Text: strings.Join(b.lines, "\n"),
+		Data: b.event.Data,
+		File: b.event.File,
+	}
+}
+
+// MultilineAggregator 按文件将多行日志聚合为一条日志
+type MultilineAggregator struct {
+	start    *regexp.Regexp
+	cont     *regexp.Regexp
+	timeout  time.Duration
+	maxLines int
+	blocks   map[string]*multilineBlock
+}
+
+func NewMultilineAggregator(cfg configs.MultilineConfig) (*MultilineAggregator, error) {
+	a := &MultilineAggregator{
+		timeout:  cfg.Timeout,
+		maxLines: cfg.MaxLines,
+		blocks:   make(map[string]*multilineBlock),
+	}
+
+	var err error
+	if cfg.StartPattern != "" {
+		if a.start, err = regexp.Compile(cfg.StartPattern); err != nil {
+			return nil, errors.Wrap(err, "compile multiline start pattern failed")
+		}
+	}
+	if cfg.ContinuePattern != "" {
+		if a.cont, err = regexp.Compile(cfg.ContinuePattern); err != nil {
+			return nil, errors.Wrap(err, "compile multiline continue pattern failed")
+		}
+	}
+	return a, nil
+}
+
+// isContinue 未配置后续行正则时，不匹配起始行正则的行均视为后续行
+func (a *MultilineAggregator) isContinue(text string) bool {
+	if a.cont != nil {
+		return a.cont.MatchString(text)
+	}
+	return !a.start.MatchString(text)
+}
+
+// isStart 未配置起始行正则时，所有非后续行均可作为起始行
+func (a *MultilineAggregator) isStart(text string) bool {
+	if a.start != nil {
+		return a.start.MatchString(text)
+	}
+	return true
+}
+
+// Add 写入一行日志，返回已经聚合完成的日志
+func (a *MultilineAggregator) Add(event *module.LogEvent, now time.Time) []*module.LogEvent {
+	source := event.File.State.Source
+	block, ok := a.blocks[source]
+	if ok && a.isContinue(event.Text) {
+		// 超过最大行数时丢弃后续行，但仍然属于当前日志
+		if a.maxLines <= 0 || len(block.lines) < a.maxLines {
+			block.lines = append(block.lines, event.Text)
+		}
+		block.last = now
+		return nil
+	}
+
+	var events []*module.LogEvent
+	if ok {
+		events = append(events, block.toEvent())
+		delete(a.blocks, source)
+	}
+
+	if !a.isStart(event.Text) {
+		// 不属于任何多行日志的行直接作为单行日志处理
+		return append(events, event)
+	}
+	a.blocks[source] = &multilineBlock{
+		event: event,
+		lines: []string{event.Text},
+		last:  now,
+	}
+	return events
+}
+
+// Flush 返回超时未更新的日志
+func (a *MultilineAggregator) Flush(now time.Time) []*module.LogEvent {
+	var events []*module.LogEvent
+	for source, block := range a.blocks {
+		if now.Sub(block.last) < a.timeout {
+			continue
+		}
+		events = append(events, block.toEvent())
+		delete(a.blocks, source)
+	}
+	return events
+}
@@ -0,0 +1,166 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/keyword"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/keyword/input/file"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/keyword/module"
+)
+
+func newLogEvent(source, text string) *module.LogEvent {
+	return &module.LogEvent{
+		Text: text,
+		File: &file.File{State: file.NewState(nil, source, "f")},
+	}
+}
+
+func collectTexts(events []*module.LogEvent) []string {
+	var texts []string
+	for _, e := range events {
+		texts = append(texts, e.Text)
+	}
+	return texts
+}
+
+func TestMultilineAggregator(t *testing.T) {
+	testCases := map[string]struct {
+		cfg      configs.MultilineConfig
+		lines    []string
+		expected []string
+		pending  []string
+	}{
+		"起始行正则": {
+			cfg: configs.MultilineConfig{StartPattern: `^\d{4}-\d{2}-\d{2}`},
+			lines: []string{
+				"orphan line",
+				"2023-01-01 ERROR java.lang.NullPointerException",
+				"\tat com.example.Foo.bar(Foo.java:10)",
+				"\tat com.example.Foo.main(Foo.java:5)",
+				"2023-01-01 INFO done",
+			},
+			expected: []string{
+				"orphan line",
+				"2023-01-01 ERROR java.lang.NullPointerException\n\tat com.example.Foo.bar(Foo.java:10)\n\tat com.example.Foo.main(Foo.java:5)",
+			},
+			pending: []string{"2023-01-01 INFO done"},
+		},
+		"后续行正则": {
+			cfg: configs.MultilineConfig{ContinuePattern: `^\s+`},
+			lines: []string{
+				"Traceback (most recent call last):",
+				"  File \"main.py\", line 1",
+				"ValueError: bad value",
+			},
+			expected: []string{
+				"Traceback (most recent call last):\n  File \"main.py\", line 1",
+			},
+			pending: []string{"ValueError: bad value"},
+		},
+		"起始行与后续行正则": {
+			cfg: configs.MultilineConfig{StartPattern: `^ERROR`, ContinuePattern: `^\s+at `},
+			lines: []string{
+				"ERROR failed",
+				"  at a",
+				"INFO ok",
+				"  at b",
+			},
+			expected: []string{"ERROR failed\n  at a", "INFO ok", "  at b"},
+		},
+		"超过最大行数": {
+			cfg: configs.MultilineConfig{StartPattern: `^ERROR`, MaxLines: 2},
+			lines: []string{
+				"ERROR failed",
+				"  at a",
+				"  at b",
+				"ERROR again",
+			},
+			expected: []string{"ERROR failed\n  at a"},
+			pending:  []string{"ERROR again"},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c.cfg.Timeout = time.Second
+			a, err := NewMultilineAggregator(c.cfg)
+			assert.NoError(t, err)
+
+			now := time.Now()
+			var events []*module.LogEvent
+			for _, line := range c.lines {
+				events = append(events, a.Add(newLogEvent("/var/log/app.log", line), now)...)
+			}
+			assert.Equal(t, c.expected, collectTexts(events))
+
+			// 未超时的日志不会输出
+			assert.Empty(t, a.Flush(now))
+			assert.Equal(t, c.pending, collectTexts(a.Flush(now.Add(time.Second))))
+		})
+	}
+}
+
+func TestMultilineAggregatorFiles(t *testing.T) {
+	a, err := NewMultilineAggregator(configs.MultilineConfig{StartPattern: `^ERROR`, Timeout: time.Second})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.Empty(t, a.Add(newLogEvent("a.log", "ERROR a"), now))
+	assert.Empty(t, a.Add(newLogEvent("b.log", "ERROR b"), now))
+	assert.Empty(t, a.Add(newLogEvent("a.log", "  at a"), now.Add(time.Second)))
+
+	// 不同文件的日志分别聚合，只有 b.log 超时
+	assert.Equal(t, []string{"ERROR b"}, collectTexts(a.Flush(now.Add(time.Second))))
+	assert.Equal(t, []string{"ERROR a\n  at a"}, collectTexts(a.Flush(now.Add(2*time.Second))))
+
+	_, err = NewMultilineAggregator(configs.MultilineConfig{StartPattern: `(`})
+	assert.Error(t, err)
+}
+
+func TestEventProcessor_HandleMultiline(t *testing.T) {
+	evp, err := NewEventProcessor(keyword.ProcessConfig{
+		DataID:   2,
+		Encoding: configs.EncodingUTF8,
+		KeywordConfigs: []configs.KeywordConfig{
+			{Name: "Exception", Pattern: `(?P<exception>\w+Exception)(?s:.*)at (?P<location>[\w.]+)\(`},
+		},
+		Multiline: configs.MultilineConfig{StartPattern: `^\d{4}-`, Timeout: time.Second},
+	})
+	assert.NoError(t, err)
+
+	lines := []string{
+		"2023-01-01 ERROR java.lang.NullPointerException: boom",
+		"\tat com.example.Foo.bar(Foo.java:10)",
+		"\tat com.example.Foo.main(Foo.java:5)",
+	}
+	for _, line := range lines {
+		results, err := evp.Handle(newLogEvent("/var/log/app.log", line))
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	}
+
+	// 整个异常堆栈只计数一次
+	results, ok := evp.Flush(time.Now().Add(time.Second)).([]keyword.KeywordTaskResult)
+	assert.True(t, ok)
+	assert.Len(t, results, 1)
+	assert.Equal(t, map[string]string{
+		"exception": "NullPointerException",
+		"location":  "com.example.Foo.main",
+	}, results[0].Dimensions)
+	assert.Equal(t, []string{"exception", "location"}, results[0].SortedFields)
+
+	assert.Nil(t, evp.Flush(time.Now().Add(time.Second)))
+}
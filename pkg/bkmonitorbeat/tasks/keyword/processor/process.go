@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -31,6 +32,12 @@ type IProcessor interface {
 	Send(event interface{}, outputs []chan<- interface{})
 }
 
+// IFlusher 需要定期输出缓存内容的处理器，例如多行日志聚合
+type IFlusher interface {
+	// return nil: nothing to send
+	Flush(now time.Time) interface{}
+}
+
 type Processor struct {
 	cfg     keyword.ProcessConfig
 	ctx     context.Context
@@ -86,11 +93,24 @@ func (client *Processor) run() {
 	client.wg.Add(1)
 	defer client.wg.Done()
 
+	// 开启多行聚合时，定期检查超时的多行日志
+	var flushC <-chan time.Time
+	flusher, ok := client.process.(IFlusher)
+	if ok && client.cfg.Multiline.Enabled() {
+		ticker := time.NewTicker(client.cfg.Multiline.Timeout)
+		defer ticker.Stop()
+		flushC = ticker.C
+	}
+
 	for {
 		select {
 		case <-client.ctx.Done():
 			logger.Infof("processor quit, id: %s", client.ID())
 			return
+		case now := <-flushC:
+			if event := flusher.Flush(now); event != nil {
+				client.send(event)
+			}
 		case event := <-client.input:
 			event, err := client.handle(event)
 			if err != nil {
@@ -137,6 +157,9 @@ func New(ctx context.Context, cfg keyword.ProcessConfig, taskType string) (modul
 		p   IProcessor
 		err error
 	)
+	if cfg.Multiline.Timeout <= 0 {
+		cfg.Multiline.Timeout = configs.DefaultMultilineTimeout
+	}
 	if taskType == configs.TaskTypeKeyword {
 		p, err = NewEventProcessor(cfg)
 	} else {
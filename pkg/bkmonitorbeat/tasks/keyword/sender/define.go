@@ -24,6 +24,8 @@ const (
 	EventTimeStampKey = "timestamp"  // 事件事件键值
 	EventEventNameKey = "event_name" // 事件名键值
 	EventTargetKey    = "target"     // 监控目标键值
+
+	OtherDimensionValue = "__other__" // 维度组合超过上限后统一使用的维度值
 )
 
 func New(ctx context.Context, cfg keyword.SendConfig, eChan chan<- define.Event) (module.Module, error) {
@@ -28,17 +28,22 @@ type EventCounter struct {
 	EventName  string                 // 日志采集事件名
 	Count      int                    // 事件产生计数器
 	LastLog    string                 // 最后日志
+	Samples    []string               // 日志样例，保留最早命中的几条日志
 	Dimensions map[string]interface{} // 事件相关维度
 }
 
 // toMapStr: 将记录直接转换为对应的记录
 func (e *EventCounter) toMapStr() common.MapStr {
+	event := map[string]interface{}{
+		"count":   e.Count,
+		"content": e.LastLog,
+	}
+	if len(e.Samples) > 0 {
+		event["samples"] = e.Samples
+	}
 	return map[string]interface{}{
 		EventEventNameKey: e.EventName,
-		EventEventKey: map[string]interface{}{
-			"count":   e.Count,
-			"content": e.LastLog,
-		},
+		EventEventKey:     event,
 		EventDimensionKey: e.Dimensions,
 	}
 }
@@ -47,12 +52,16 @@ func (e *EventCounter) toMapStr() common.MapStr {
 func (e *EventCounter) reset() {
 	e.Count = 0
 	e.LastLog = ""
+	e.Samples = nil
 }
 
-// addCount: 增加一条新的日志记录，并将旧的日志信息替换
-func (e *EventCounter) addCount(log string) {
+// addCount: 增加一条新的日志记录，并将旧的日志信息替换，样例数量未达到上限时记录为样例
+func (e *EventCounter) addCount(log string, sampleSize int) {
 	e.Count++
 	e.LastLog = log
+	if len(e.Samples) < sampleSize {
+		e.Samples = append(e.Samples, log)
+	}
 }
 
 // 时间单位转换，默认转为了毫秒级别
@@ -83,6 +92,8 @@ type EventSender struct {
 	cache     map[string]*EventCounter // 缓存计数器
 	ticker    *time.Ticker             // 计时器，用于计时周期发送汇聚结果
 
+	dimensionKeys map[string]map[string]struct{} // 每个文件每条规则在当前周期内出现的维度组合，用于限制维度组合数量
+
 	lock         sync.Mutex // cache锁，用于供汇聚发送时和数据写入时的协调，由于发送时需要清理cache，存在写行为；接受数据时，需要修改cache，存在写行为；所以此处只有一个写锁
 	isRunning    bool       // 任务是否已经启动，防止任务重入导致有多次发送
 	timeUnitBase int64      // 时间单位调整基数
@@ -96,6 +107,8 @@ func NewEventSender(ctx context.Context, config keyword.SendConfig, eChan chan<-
 		eventChan: eChan,
 		cache:     make(map[string]*EventCounter),
 
+		dimensionKeys: make(map[string]map[string]struct{}),
+
 		ctx: ctx,
 	}
 }
@@ -207,9 +220,12 @@ func (s *EventSender) cacheResult(rawResult interface{}) {
 	}()
 
 	// 3. 更新数据写入到cache中
+	if _, ok = s.cache[hashKey]; !ok {
+		hashKey = s.limitDimensions(&keywordResult, hashKey)
+	}
 	if counter, ok = s.cache[hashKey]; ok {
 		// 3.1 判断是否已经存在，如果存在则修改count和log即可
-		counter.addCount(keywordResult.Log)
+		counter.addCount(keywordResult.Log, s.cfg.SampleSize)
 		logger.Debugf("task->[%s] hashKey->[%s] update log info success.", s.ID(), hashKey)
 		return
 	}
@@ -226,15 +242,49 @@ func (s *EventSender) cacheResult(rawResult interface{}) {
 
 	counter = &EventCounter{
 		EventName:  keywordResult.RuleName,
-		Count:      1,
-		LastLog:    keywordResult.Log,
 		Dimensions: tempDimension,
 	}
+	counter.addCount(keywordResult.Log, s.cfg.SampleSize)
 
 	s.cache[hashKey] = counter
 	logger.Infof("task->[%s] hashKey->[%s] get new counter", s.ID(), hashKey)
 }
 
+// limitDimensions: 限制单个文件单条规则的维度组合数量，超过上限的结果将正则提取的维度值替换为 __other__，返回替换后的key
+func (s *EventSender) limitDimensions(result *keyword.KeywordTaskResult, hashKey string) string {
+	if s.cfg.MaxDimensionValues <= 0 || len(result.SortedFields) == 0 {
+		return hashKey
+	}
+
+	groupKey := result.FilePath + keyword.KeySeparator + result.RuleName
+	keys, ok := s.dimensionKeys[groupKey]
+	if !ok {
+		keys = make(map[string]struct{})
+		s.dimensionKeys[groupKey] = keys
+	}
+	if len(keys) < s.cfg.MaxDimensionValues {
+		keys[hashKey] = struct{}{}
+		return hashKey
+	}
+
+	dimensions := make(map[string]string, len(result.Dimensions))
+	for k, v := range result.Dimensions {
+		dimensions[k] = v
+	}
+	for _, field := range result.SortedFields {
+		dimensions[field] = OtherDimensionValue
+	}
+	result.Dimensions = dimensions
+
+	otherKey, err := result.MakeKey()
+	if err != nil {
+		logger.Warnf("task->[%s] make key for other dimensions failed->[%s], use origin key", s.ID(), err)
+		return hashKey
+	}
+	logger.Debugf("task->[%s] group->[%s] dimensions more than->[%d], use other dimensions", s.ID(), groupKey, s.cfg.MaxDimensionValues)
+	return otherKey
+}
+
 // flushCache: 清理发送缓存内容
 func (s *EventSender) flushCache() {
 	var (
@@ -294,6 +344,7 @@ func (s *EventSender) flushCache() {
 
 	// 此时已经发送成功了，所以再次遍历所有的cache将cache中的数据清空
 	s.cache = make(map[string]*EventCounter)
+	s.dimensionKeys = make(map[string]map[string]struct{})
 	logger.Debugf("task->[%s] cache reset success.", s.ID())
 }
 
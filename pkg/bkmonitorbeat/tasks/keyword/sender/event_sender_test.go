@@ -155,3 +155,53 @@ Loop:
 	assert.Equal(t, sendContent["target"].(string), target)
 	assert.Equal(t, sendContent["event_name"].(string), "rule_one")
 }
+
+// TestEventSenderLimitDimensions: 维度组合超过上限时合并为 __other__，并附带日志样例
+func TestEventSenderLimitDimensions(t *testing.T) {
+	test.MakeWatcher()
+	defer func() { test.CleanWatcher() }()
+
+	config := makeConfig()
+	config.MaxDimensionValues = 2
+	config.SampleSize = 2
+	eventChan := make(chan define.Event, 10)
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	s, _ := sender.New(ctx, config, eventChan)
+	linker := make(chan interface{})
+	s.AddInput(linker)
+	_ = s.Start()
+
+	for _, code := range []string{"E1", "E2", "E3", "E1", "E4", "E5"} {
+		linker <- keyword.KeywordTaskResult{
+			FilePath:     "test_path",
+			RuleName:     "rule_one",
+			SortedFields: []string{"code"},
+			Dimensions:   map[string]string{"code": code},
+			Log:          "error " + code,
+		}
+	}
+
+	// 退出时会上报缓存中的内容
+	ctxCancel()
+	s.Wait()
+
+	var sendContent []common.MapStr
+	select {
+	case res := <-eventChan:
+		sendContent = res.AsMapStr()["data"].([]common.MapStr)
+	case <-time.After(time.Second):
+		t.Fatal("no event sent")
+	}
+
+	counts := make(map[string]int)
+	samples := make(map[string]interface{})
+	for _, content := range sendContent {
+		code := content[sender.EventDimensionKey].(common.MapStr)["code"].(string)
+		event := content[sender.EventEventKey].(common.MapStr)
+		counts[code] += event["count"].(int)
+		samples[code] = event["samples"]
+	}
+	assert.Equal(t, map[string]int{"E1": 2, "E2": 1, sender.OtherDimensionValue: 3}, counts)
+	assert.Equal(t, []string{"error E1", "error E1"}, samples["E1"])
+	assert.Equal(t, []string{"error E3", "error E4"}, samples[sender.OtherDimensionValue])
+}
@@ -72,6 +72,7 @@ var udpStatesMap = map[uint8]string{
 
 const (
 	sizeOfInetDiagRequest = 72
+	sizeOfInetDiagMsg     = 72
 	sockDiagByFamily      = 20 // sock_diag.h
 )
 
@@ -299,30 +300,19 @@ func sockdiagSend(proto, seq, family uint8, exts uint8, states uint32) (skfd int
 	return skfd, nil
 }
 
-// sockdiagRecv inode -> FileSocket
-func sockdiagRecv(skfd int, proto uint8) (map[uint32]FileSocket, error) {
+// sockdiagDump 读取内核返回的所有消息，attrs 为 inet_diag_msg 之后的扩展属性
+func sockdiagDump(skfd int, proto uint8, fn func(m *inetDiagMsg, attrs []byte)) error {
 	var (
-		err         error
-		stateMap    = make(map[uint8]string)
-		filesockets = make(map[uint32]FileSocket)
-		buf         = make([]byte, os.Getpagesize())
+		n   int
+		err error
+		buf = make([]byte, os.Getpagesize())
 	)
 
-	switch proto {
-	case syscall.IPPROTO_UDP:
-		stateMap = udpStatesMap
-	case syscall.IPPROTO_TCP:
-		stateMap = tcpStatesMap
-	}
-
-	var n int
-
 	// loop here, it will ensure that all messages have been read from the kernel
-loop:
 	for {
 		for {
 			if n, _, _, _, err = unix.Recvmsg(skfd, buf, nil, unix.MSG_PEEK); err != nil {
-				return nil, errors.Wrap(err, "unix.Recvmsg")
+				return errors.Wrap(err, "unix.Recvmsg")
 			}
 			if n < len(buf) {
 				break
@@ -333,54 +323,80 @@ loop:
 		}
 
 		if n, _, _, _, err = unix.Recvmsg(skfd, buf, nil, 0); err != nil {
-			return nil, errors.Wrap(err, "unix.Recvmsg")
+			return errors.Wrap(err, "unix.Recvmsg")
 		}
 
 		// no messages anymore
 		if n == 0 {
 			logger.Debugf("recvmsg done, fd=%v, proto=%x", skfd, proto)
-			break loop
+			return nil
 		}
 
 		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
 		if err != nil {
-			return nil, errors.Wrap(err, "syscall.ParseNetlinkMessage")
+			return errors.Wrap(err, "syscall.ParseNetlinkMessage")
 		}
 
 		for idx, netlinkMessage := range msgs {
 			if netlinkMessage.Header.Type == syscall.NLMSG_DONE {
 				logger.Debugf("got done message from header, msg index=%d", idx)
-				break loop
+				return nil
 			}
 
 			data := netlinkMessage.Data
-			m := (*inetDiagMsg)(unsafe.Pointer(&data[0]))
-			srcIPString, _ := ipHex2String(m.IDiagFamily, m.ID.IdiagSrc)
-			dstIPString, _ := ipHex2String(m.IDiagFamily, m.ID.IdiagDst)
-			filesocket := FileSocket{
-				Status: stateMap[m.IDiagState],
-				Inode:  m.IDiagInode,
-				Family: uint32(m.IDiagFamily),
-				Saddr:  srcIPString,
-				Sport:  uint32(m.ID.IdiagSport.Int()),
-				Daddr:  dstIPString,
-				Dport:  uint32(m.ID.IdiagDport.Int()),
+			if len(data) < sizeOfInetDiagMsg {
+				continue
 			}
+			fn((*inetDiagMsg)(unsafe.Pointer(&data[0])), data[sizeOfInetDiagMsg:])
+		}
+	}
+}
 
-			switch proto {
-			case syscall.IPPROTO_UDP:
-				filesocket.Type = syscall.SOCK_DGRAM
-			case syscall.IPPROTO_TCP:
-				filesocket.Type = syscall.SOCK_STREAM
-			}
+// sockdiagRecv inode -> FileSocket
+func sockdiagRecv(skfd int, proto uint8) (map[uint32]FileSocket, error) {
+	var (
+		stateMap    = make(map[uint8]string)
+		filesockets = make(map[uint32]FileSocket)
+	)
 
-			filesockets[filesocket.Inode] = filesocket
-		}
+	switch proto {
+	case syscall.IPPROTO_UDP:
+		stateMap = udpStatesMap
+	case syscall.IPPROTO_TCP:
+		stateMap = tcpStatesMap
 	}
 
+	err := sockdiagDump(skfd, proto, func(m *inetDiagMsg, _ []byte) {
+		filesocket := newFileSocket(m, stateMap)
+		switch proto {
+		case syscall.IPPROTO_UDP:
+			filesocket.Type = syscall.SOCK_DGRAM
+		case syscall.IPPROTO_TCP:
+			filesocket.Type = syscall.SOCK_STREAM
+		}
+
+		filesockets[filesocket.Inode] = filesocket
+	})
+	if err != nil {
+		return nil, err
+	}
 	return filesockets, nil
 }
 
+func newFileSocket(m *inetDiagMsg, stateMap map[uint8]string) FileSocket {
+	srcIPString, _ := ipHex2String(m.IDiagFamily, m.ID.IdiagSrc)
+	dstIPString, _ := ipHex2String(m.IDiagFamily, m.ID.IdiagDst)
+	return FileSocket{
+		Status: stateMap[m.IDiagState],
+		Inode:  m.IDiagInode,
+		Family: uint32(m.IDiagFamily),
+		Saddr:  srcIPString,
+		Sport:  uint32(m.ID.IdiagSport.Int()),
+		Daddr:  dstIPString,
+		Dport:  uint32(m.ID.IdiagDport.Int()),
+	}
+}
+
 // getProcInodes returns inodes of the specified pid
 func getProcInodes(root string, pid int32) ([]uint64, error) {
 	var inodefds []uint64
@@ -0,0 +1,28 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package process
+
+// TCPInfo 连接质量指标，来自内核 tcp_info 以及 inet_diag_msg
+type TCPInfo struct {
+	Rtt          uint32 // 平滑 RTT，单位 us
+	RttVar       uint32 // RTT 抖动，单位 us
+	Retransmits  uint8  // 当前未确认的重传次数
+	TotalRetrans uint32 // 连接建立以来的重传报文数
+	Lost         uint32 // 被判定为丢失的报文数
+	SndCwnd      uint32 // 拥塞窗口，单位为报文数
+	RecvQueue    uint32 // 接收队列中未被读取的字节数
+	SendQueue    uint32 // 发送队列中未被确认的字节数
+}
+
+// TCPConn 进程已建立的 TCP 连接以及其质量指标
+type TCPConn struct {
+	FileSocket
+	Info TCPInfo
+}
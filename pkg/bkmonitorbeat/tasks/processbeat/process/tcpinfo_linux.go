@@ -0,0 +1,126 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package process
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inetDiagInfo = 2 // inet_diag.h INET_DIAG_INFO
+	sizeOfRtAttr = 4
+)
+
+// parseTCPInfo 从 inet_diag_msg 之后的扩展属性中解析 INET_DIAG_INFO
+/* rtnetlink.h
+struct rtattr {
+	unsigned short	rta_len;
+	unsigned short	rta_type;
+};
+*/
+func parseTCPInfo(attrs []byte) (*unix.TCPInfo, bool) {
+	order := getNativeEndian()
+	for len(attrs) >= sizeOfRtAttr {
+		l := int(order.Uint16(attrs[0:2]))
+		typ := order.Uint16(attrs[2:4])
+		if l < sizeOfRtAttr || l > len(attrs) {
+			return nil, false
+		}
+
+		if typ == inetDiagInfo {
+			// 低版本内核返回的结构体较短，缺失的字段保持为 0
+			var info unix.TCPInfo
+			copy((*[unix.SizeofTCPInfo]byte)(unsafe.Pointer(&info))[:], attrs[sizeOfRtAttr:l])
+			return &info, true
+		}
+
+		aligned := (l + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+		if aligned > len(attrs) {
+			break
+		}
+		attrs = attrs[aligned:]
+	}
+	return nil, false
+}
+
+func newTCPInfo(m *inetDiagMsg, info *unix.TCPInfo) TCPInfo {
+	return TCPInfo{
+		Rtt:          info.Rtt,
+		RttVar:       info.Rttvar,
+		Retransmits:  info.Retransmits,
+		TotalRetrans: info.Total_retrans,
+		Lost:         info.Lost,
+		SndCwnd:      info.Snd_cwnd,
+		RecvQueue:    m.IDiagRqueue,
+		SendQueue:    m.IDiagWqueue,
+	}
+}
+
+// sockdiagRecvTCPInfo inode -> TCPConn
+func sockdiagRecvTCPInfo(skfd int) (map[uint32]TCPConn, error) {
+	conns := make(map[uint32]TCPConn)
+	err := sockdiagDump(skfd, syscall.IPPROTO_TCP, func(m *inetDiagMsg, attrs []byte) {
+		info, ok := parseTCPInfo(attrs)
+		if !ok {
+			return
+		}
+		fs := newFileSocket(m, tcpStatesMap)
+		fs.Type = syscall.SOCK_STREAM
+		conns[fs.Inode] = TCPConn{
+			FileSocket: fs,
+			Info:       newTCPInfo(m, info),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+// GetTCPConns 通过 netlink 获取指定进程已建立的 TCP 连接以及 tcp_info
+func GetTCPConns(pids []int32) ([]TCPConn, error) {
+	conns := make(map[uint32]TCPConn)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		fd, err := sockdiagSend(syscall.IPPROTO_TCP, 0, family, 1<<(inetDiagInfo-1), 1<<tcpEstablished)
+		if err != nil {
+			return nil, errors.Wrap(err, "sockdiag send failed")
+		}
+
+		items, err := sockdiagRecvTCPInfo(fd)
+		_ = syscall.Close(fd)
+		if err != nil {
+			return nil, errors.Wrap(err, "sockdiag recv failed")
+		}
+		for inode, conn := range items {
+			conns[inode] = conn
+		}
+	}
+
+	var ret []TCPConn
+	for pid, inodes := range getConcernPidInodes(pids) {
+		for _, inode := range inodes {
+			conn, ok := conns[uint32(inode)]
+			if !ok {
+				continue
+			}
+			conn.Pid = pid
+			conn.Protocol = ProtocolTCP
+			if conn.Family == syscall.AF_INET6 {
+				conn.Protocol = ProtocolTCP6
+			}
+			ret = append(ret, conn)
+		}
+	}
+	return ret, nil
+}
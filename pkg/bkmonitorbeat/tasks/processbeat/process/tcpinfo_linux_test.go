@@ -0,0 +1,72 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package process
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func makeRtAttr(typ uint16, value []byte) []byte {
+	l := sizeOfRtAttr + len(value)
+	b := make([]byte, (l+unix.NLA_ALIGNTO-1)&^(unix.NLA_ALIGNTO-1))
+	getNativeEndian().PutUint16(b[0:2], uint16(l))
+	getNativeEndian().PutUint16(b[2:4], typ)
+	copy(b[sizeOfRtAttr:], value)
+	return b
+}
+
+func TestParseTCPInfo(t *testing.T) {
+	info := unix.TCPInfo{
+		Retransmits:   1,
+		Lost:          2,
+		Rtt:           1500,
+		Rttvar:        300,
+		Snd_cwnd:      10,
+		Total_retrans: 5,
+	}
+	raw := (*[unix.SizeofTCPInfo]byte)(unsafe.Pointer(&info))[:]
+
+	// 其他属性在前，INET_DIAG_INFO 在后
+	attrs := append(makeRtAttr(1, []byte{1, 2, 3}), makeRtAttr(inetDiagInfo, raw)...)
+	parsed, ok := parseTCPInfo(attrs)
+	assert.True(t, ok)
+	assert.Equal(t, info, *parsed)
+
+	m := &inetDiagMsg{IDiagRqueue: 100, IDiagWqueue: 200}
+	assert.Equal(t, TCPInfo{
+		Rtt:          1500,
+		RttVar:       300,
+		Retransmits:  1,
+		TotalRetrans: 5,
+		Lost:         2,
+		SndCwnd:      10,
+		RecvQueue:    100,
+		SendQueue:    200,
+	}, newTCPInfo(m, parsed))
+
+	// 低版本内核返回的结构体较短
+	parsed, ok = parseTCPInfo(makeRtAttr(inetDiagInfo, raw[:96]))
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1500), parsed.Rtt)
+	assert.Equal(t, uint32(0), parsed.Total_retrans)
+
+	_, ok = parseTCPInfo(makeRtAttr(1, []byte{1}))
+	assert.False(t, ok)
+
+	// 长度不合法
+	bad := make([]byte, 8)
+	getNativeEndian().PutUint16(bad, 64)
+	_, ok = parseTCPInfo(bad)
+	assert.False(t, ok)
+}
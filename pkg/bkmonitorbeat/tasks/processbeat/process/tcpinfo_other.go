@@ -0,0 +1,21 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+//go:build !linux
+
+package process
+
+import (
+	"github.com/pkg/errors"
+)
+
+// GetTCPConns 仅 linux 支持通过 netlink 获取连接质量指标
+func GetTCPConns(_ []int32) ([]TCPConn, error) {
+	return nil, errors.New("tcp info is only supported on linux")
+}
@@ -0,0 +1,81 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+)
+
+// Event 单个设备一次采集的指标，按照自定义时序格式上报
+type Event struct {
+	DataID    int32
+	Target    string
+	Labels    []map[string]string
+	Samples   []Sample
+	Timestamp int64
+}
+
+func NewEvent(task define.Task, target string, samples []Sample, now time.Time) *Event {
+	return &Event{
+		DataID:    task.GetConfig().GetDataID(),
+		Target:    target,
+		Labels:    task.GetConfig().GetLabels(),
+		Samples:   samples,
+		Timestamp: now.UnixMilli(),
+	}
+}
+
+func (e *Event) GetType() string {
+	return define.ModuleSNMP
+}
+
+func (e *Event) IgnoreCMDBLevel() bool {
+	return true
+}
+
+// AsMapStr 每一行生成一条数据，设备地址作为 target 维度，与采集维度冲突的 label 添加 exported_ 前缀
+func (e *Event) AsMapStr() common.MapStr {
+	data := make([]map[string]interface{}, 0, len(e.Samples))
+	for _, sample := range e.Samples {
+		dims := make(map[string]string, len(sample.Dimensions)+1)
+		for k, v := range sample.Dimensions {
+			dims[k] = v
+		}
+		dims["target"] = e.Target
+		if len(e.Labels) > 0 {
+			for k, v := range e.Labels[0] {
+				if _, ok := dims[k]; ok {
+					dims["exported_"+k] = v
+					continue
+				}
+				dims[k] = v
+			}
+		}
+
+		data = append(data, common.MapStr{
+			"metrics":   sample.Metrics,
+			"target":    e.Target,
+			"timestamp": e.Timestamp,
+			"dimension": dims,
+		})
+	}
+
+	ts := time.Now().Unix()
+	return common.MapStr{
+		"dataid":    e.DataID,
+		"data":      data,
+		"time":      ts,
+		"timestamp": ts,
+	}
+}
@@ -0,0 +1,165 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/gosnmp/gosnmp"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// SNMP 采集状态码详情
+//
+// CodeOK               -> 采集成功
+// CodeBadRequestParams -> 版本或者 v3 认证参数不合法
+// CodeConnFailed       -> 连接设备失败
+// CodeRequestTimeout   -> 请求超时
+// CodeRequestFailed    -> 请求失败
+
+// Gather :
+type Gather struct {
+	tasks.BaseTask
+	poller *Poller
+}
+
+// splitTarget 拆分设备地址，未指定端口时使用默认端口
+func splitTarget(target string, defaultPort int) (string, uint16) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return target, uint16(defaultPort)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return host, uint16(defaultPort)
+	}
+	return host, uint16(p)
+}
+
+// newClient 根据任务配置生成 snmp 客户端
+func newClient(ctx context.Context, taskConf *configs.SNMPTaskConfig, target string) (*gosnmp.GoSNMP, error) {
+	host, port := splitTarget(target, taskConf.Port)
+	cli := &gosnmp.GoSNMP{
+		Context:        ctx,
+		Target:         host,
+		Port:           port,
+		Transport:      "udp",
+		Community:      taskConf.Community,
+		Version:        ParseVersion(taskConf.Version),
+		Timeout:        taskConf.Timeout,
+		Retries:        taskConf.Retries,
+		MaxOids:        gosnmp.MaxOids,
+		MaxRepetitions: taskConf.MaxRepetitions,
+	}
+	if cli.Version == UnknownVersion {
+		return nil, errors.Errorf("unknown snmp version %s", taskConf.Version)
+	}
+
+	if cli.Version == gosnmp.Version3 {
+		sp, err := NewUsmSecurityParameters(taskConf.UsmInfo.USMConfig)
+		if err != nil {
+			return nil, err
+		}
+		cli.SecurityModel = gosnmp.UserSecurityModel
+		cli.MsgFlags = ParseMsgFlags(taskConf.UsmInfo.MsgFlags)
+		cli.SecurityParameters = sp
+		cli.ContextName = taskConf.UsmInfo.ContextName
+	}
+	return cli, nil
+}
+
+// errorCode 将请求错误转换为状态码
+func errorCode(err error) define.NamedCode {
+	if strings.Contains(err.Error(), "timeout") {
+		return define.CodeRequestTimeout
+	}
+	return define.CodeRequestFailed
+}
+
+// poll 采集单个设备，返回采集状态
+func (g *Gather) poll(ctx context.Context, taskConf *configs.SNMPTaskConfig, target string, e chan<- define.Event) define.NamedCode {
+	cli, err := newClient(ctx, taskConf, target)
+	if err != nil {
+		logger.Errorf("task(%d) make snmp client for %s failed: %v", taskConf.GetTaskID(), target, err)
+		return define.CodeBadRequestParams
+	}
+	if err = cli.Connect(); err != nil {
+		logger.Warnf("task(%d) connect %s failed: %v", taskConf.GetTaskID(), target, err)
+		return define.CodeConnFailed
+	}
+	defer func() {
+		if err := cli.Conn.Close(); err != nil {
+			logger.Warnf("task(%d) close conn of %s error: %v", taskConf.GetTaskID(), target, err)
+		}
+	}()
+
+	now := time.Now()
+	samples, err := g.poller.Poll(cli, target, now)
+	if err != nil {
+		logger.Warnf("task(%d) poll %s failed: %v", taskConf.GetTaskID(), target, err)
+		return errorCode(err)
+	}
+	if len(samples) > 0 {
+		e <- NewEvent(g, target, samples, now)
+	}
+	return define.CodeOK
+}
+
+// Run :
+func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
+	taskConf := g.TaskConfig.(*configs.SNMPTaskConfig)
+	g.PreRun(ctx)
+	defer g.PostRun(ctx)
+
+	var wg sync.WaitGroup
+	for _, target := range taskConf.Targets {
+		// 获取并发限制信号量
+		err := g.GetSemaphore().Acquire(ctx, 1)
+		if err != nil {
+			logger.Errorf("task(%d) semaphore acquire failed", g.TaskConfig.GetTaskID())
+			break
+		}
+
+		wg.Add(1)
+		go func(target string) {
+			defer func() {
+				wg.Done()
+				g.GetSemaphore().Release(1)
+			}()
+
+			code := g.poll(ctx, taskConf, target, e)
+			e <- tasks.NewGatherUpEventWithDims(g, code, common.MapStr{"target": target})
+		}(target)
+	}
+	wg.Wait()
+}
+
+// New :
+func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
+	gather := &Gather{}
+	gather.GlobalConfig = globalConfig
+	gather.TaskConfig = taskConfig
+	gather.Init()
+
+	taskConf := taskConfig.(*configs.SNMPTaskConfig)
+	gather.poller = NewPoller(taskConf, GetMIBStore(taskConf.MibDirs))
+	return gather
+}
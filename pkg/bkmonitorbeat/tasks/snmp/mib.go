@@ -0,0 +1,339 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// builtinOIDs SNMPv2-SMI 等基础模块中定义的节点，未加载这些模块时也可以被引用
+var builtinOIDs = map[string]string{
+	"ccitt":           "0",
+	"iso":             "1",
+	"joint-iso-ccitt": "2",
+	"org":             "1.3",
+	"dod":             "1.3.6",
+	"internet":        "1.3.6.1",
+	"directory":       "1.3.6.1.1",
+	"mgmt":            "1.3.6.1.2",
+	"mib-2":           "1.3.6.1.2.1",
+	"transmission":    "1.3.6.1.2.1.10",
+	"experimental":    "1.3.6.1.3",
+	"private":         "1.3.6.1.4",
+	"enterprises":     "1.3.6.1.4.1",
+	"security":        "1.3.6.1.5",
+	"snmpV2":          "1.3.6.1.6",
+	"snmpDomains":     "1.3.6.1.6.1",
+	"snmpProxys":      "1.3.6.1.6.2",
+	"snmpModules":     "1.3.6.1.6.3",
+	"zeroDotZero":     "0.0",
+}
+
+// builtinTypes SNMPv2-TC 中常用的 TEXTUAL-CONVENTION，未加载 SNMPv2-TC 时使用
+var builtinTypes = map[string]*mibType{
+	"DisplayString":   {Base: "OCTET STRING", Hint: "255a"},
+	"SnmpAdminString": {Base: "OCTET STRING", Hint: "255t"},
+	"PhysAddress":     {Base: "OCTET STRING", Hint: "1x:"},
+	"MacAddress":      {Base: "OCTET STRING", Hint: "1x:"},
+	"DateAndTime":     {Base: "OCTET STRING", Hint: "2d-1d-1d,1d:1d:1d.1d,1a1d:1d"},
+	"TruthValue":      {Base: "INTEGER", Enums: map[int64]string{1: "true", 2: "false"}},
+	"RowStatus": {Base: "INTEGER", Enums: map[int64]string{
+		1: "active", 2: "notInService", 3: "notReady", 4: "createAndGo", 5: "createAndWait", 6: "destroy",
+	}},
+}
+
+// Node MIB 中的节点
+type Node struct {
+	Name   string
+	Module string
+	OID    string
+	// Type 节点 SYNTAX 的基础类型，如 INTEGER、OCTET STRING、IpAddress、BITS
+	Type  string
+	Hint  string
+	Enums map[int64]string
+}
+
+// MIB 解析后的节点，按照 oid 以及名称索引
+type MIB struct {
+	nodes map[string]*Node
+	names map[string]*Node // name 以及 MODULE::name -> node
+}
+
+func newMIB() *MIB {
+	return &MIB{
+		nodes: make(map[string]*Node),
+		names: make(map[string]*Node),
+	}
+}
+
+// Len 节点数量
+func (m *MIB) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.nodes)
+}
+
+// Lookup 按照最长前缀匹配节点，返回节点以及剩余的索引部分，未匹配时返回 nil
+func (m *MIB) Lookup(oid string) (*Node, string) {
+	if m == nil {
+		return nil, ""
+	}
+	oid = normalizeOID(oid)
+	for prefix := oid; prefix != ""; {
+		if node, ok := m.nodes[prefix]; ok {
+			return node, strings.TrimPrefix(strings.TrimPrefix(oid, prefix), ".")
+		}
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	return nil, ""
+}
+
+// Translate 将 oid 翻译为符号名，如 1.3.6.1.2.1.2.2.1.8.3 -> ifOperStatus.3，同时返回匹配的 oid 层级数
+func (m *MIB) Translate(oid string) (string, int) {
+	node, suffix := m.Lookup(oid)
+	if node == nil {
+		return oid, 0
+	}
+	depth := strings.Count(node.OID, ".") + 1
+	if suffix == "" {
+		return node.Name, depth
+	}
+	return node.Name + "." + suffix, depth
+}
+
+// Resolve 将符号名翻译为 oid，支持 name、MODULE::name 以及 name.1 的形式，数字格式的 oid 原样返回
+func (m *MIB) Resolve(name string) (string, bool) {
+	name = normalizeOID(name)
+	if isNumericOID(name) {
+		return name, true
+	}
+
+	var suffix string
+	if i := strings.Index(name, "."); i > 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	if m != nil {
+		if node, ok := m.names[name]; ok {
+			return node.OID + suffix, true
+		}
+	}
+	if oid, ok := builtinOIDs[name]; ok {
+		return oid + suffix, true
+	}
+	return "", false
+}
+
+func isNumericOID(oid string) bool {
+	if oid == "" {
+		return false
+	}
+	for _, r := range oid {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatValue 根据节点的枚举以及 DISPLAY-HINT 格式化值，没有可用的格式时返回 false 由调用方按原始类型处理
+func (n *Node) FormatValue(pdu gosnmp.SnmpPDU) (string, bool) {
+	if n == nil {
+		return "", false
+	}
+
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Gauge32, gosnmp.Uinteger32, gosnmp.Counter32, gosnmp.Counter64:
+		v := gosnmp.ToBigInt(pdu.Value)
+		if label, ok := n.Enums[v.Int64()]; ok && v.IsInt64() {
+			return label, true
+		}
+		if n.Hint != "" {
+			return formatIntHint(n.Hint, v)
+		}
+
+	case gosnmp.OctetString:
+		b, ok := pdu.Value.([]byte)
+		if !ok {
+			return "", false
+		}
+		switch {
+		case n.Type == "BITS" && len(n.Enums) > 0:
+			return formatBits(n.Enums, b), true
+		case n.Type == "IpAddress" && len(b) == net.IPv4len:
+			return net.IP(b).String(), true
+		case n.Hint != "" && !isStringHint(n.Hint):
+			return formatOctetHint(n.Hint, b)
+		}
+	}
+	return "", false
+}
+
+// isStringHint 纯文本的 DISPLAY-HINT（如 DisplayString 的 255a），交由调用方按照编码处理
+func isStringHint(hint string) bool {
+	hint = strings.TrimRight(hint, "0123456789")
+	return strings.HasSuffix(hint, "a") || strings.HasSuffix(hint, "t")
+}
+
+// formatBits 将 BITS 格式化为置位的名称列表，名称不存在时使用位序号
+func formatBits(enums map[int64]string, b []byte) string {
+	var names []string
+	for i, octet := range b {
+		for bit := 0; bit < 8; bit++ {
+			if octet&(0x80>>bit) == 0 {
+				continue
+			}
+			pos := int64(i*8 + bit)
+			if name, ok := enums[pos]; ok {
+				names = append(names, name)
+			} else {
+				names = append(names, strconv.FormatInt(pos, 10))
+			}
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// formatIntHint 整数的 DISPLAY-HINT：d-N 表示 N 位小数，以及 x、o、b 进制
+func formatIntHint(hint string, v *big.Int) (string, bool) {
+	switch hint[0] {
+	case 'x':
+		return v.Text(16), true
+	case 'o':
+		return v.Text(8), true
+	case 'b':
+		return v.Text(2), true
+	case 'd':
+		if len(hint) == 1 {
+			return v.String(), true
+		}
+		decimals, err := strconv.Atoi(strings.TrimPrefix(hint[1:], "-"))
+		if err != nil || decimals <= 0 {
+			return "", false
+		}
+		s := new(big.Int).Abs(v).String()
+		if len(s) <= decimals {
+			s = strings.Repeat("0", decimals-len(s)+1) + s
+		}
+		s = s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+		if v.Sign() < 0 {
+			s = "-" + s
+		}
+		return s, true
+	}
+	return "", false
+}
+
+// octetHintSpec DISPLAY-HINT 中的单个格式，参考 RFC 2579
+type octetHintSpec struct {
+	repeat bool
+	length int
+	format byte
+	sep    byte
+	term   byte
+}
+
+func parseOctetHint(hint string) ([]octetHintSpec, bool) {
+	var specs []octetHintSpec
+	for i := 0; i < len(hint); {
+		var spec octetHintSpec
+		if hint[i] == '*' {
+			spec.repeat = true
+			i++
+		}
+		start := i
+		for i < len(hint) && hint[i] >= '0' && hint[i] <= '9' {
+			i++
+		}
+		if start == i || i >= len(hint) {
+			return nil, false
+		}
+		spec.length, _ = strconv.Atoi(hint[start:i])
+		spec.format = hint[i]
+		if !strings.ContainsRune("dxoat", rune(spec.format)) {
+			return nil, false
+		}
+		i++
+		if i < len(hint) && !isHintSpecStart(hint[i]) {
+			spec.sep = hint[i]
+			i++
+		}
+		if spec.repeat && i < len(hint) && !isHintSpecStart(hint[i]) {
+			spec.term = hint[i]
+			i++
+		}
+		specs = append(specs, spec)
+	}
+	return specs, len(specs) > 0
+}
+
+func isHintSpecStart(c byte) bool {
+	return c == '*' || (c >= '0' && c <= '9')
+}
+
+func formatOctets(format byte, b []byte) string {
+	switch format {
+	case 'a', 't':
+		return string(b)
+	}
+	v := new(big.Int).SetBytes(b)
+	switch format {
+	case 'x':
+		s := v.Text(16)
+		if pad := len(b)*2 - len(s); pad > 0 {
+			s = strings.Repeat("0", pad) + s
+		}
+		return s
+	case 'o':
+		return v.Text(8)
+	}
+	return v.String()
+}
+
+// formatOctetHint 按照 DISPLAY-HINT 格式化字节，最后一个格式会被重复使用直到数据结束
+func formatOctetHint(hint string, b []byte) (string, bool) {
+	specs, ok := parseOctetHint(hint)
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for i, k := 0, 0; i < len(b); k++ {
+		spec := specs[min(k, len(specs)-1)]
+		count := 1
+		if spec.repeat {
+			count = int(b[i])
+			i++
+		}
+		for r := 0; r < count && i < len(b); r++ {
+			n := min(spec.length, len(b)-i)
+			sb.WriteString(formatOctets(spec.format, b[i:i+n]))
+			i += n
+			if i >= len(b) {
+				break
+			}
+			if r == count-1 && spec.term != 0 {
+				sb.WriteByte(spec.term)
+			} else if spec.sep != 0 {
+				sb.WriteByte(spec.sep)
+			}
+		}
+	}
+	return sb.String(), true
+}
@@ -0,0 +1,343 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// maxTypeDepth TEXTUAL-CONVENTION 引用链的最大深度，防止循环引用
+const maxTypeDepth = 8
+
+// applicationTypes SMI 中的基础类型，类型解析到这里为止
+var applicationTypes = map[string]bool{
+	"INTEGER":           true,
+	"OCTET STRING":      true,
+	"OBJECT IDENTIFIER": true,
+	"BITS":              true,
+	"Integer32":         true,
+	"Unsigned32":        true,
+	"Counter32":         true,
+	"Counter64":         true,
+	"Gauge32":           true,
+	"TimeTicks":         true,
+	"IpAddress":         true,
+	"Opaque":            true,
+}
+
+// mibResolver 跨模块解析节点的 oid 以及类型，引用优先在本模块中查找，其次是 IMPORTS 的模块
+type mibResolver struct {
+	modules map[string]*mibModule
+	objects map[string]map[string]*mibObject
+	global  map[string]*mibObject
+	oids    map[*mibObject]string
+	pending map[*mibObject]bool
+}
+
+func newMIBResolver(modules []*mibModule) *mibResolver {
+	r := &mibResolver{
+		modules: make(map[string]*mibModule),
+		objects: make(map[string]map[string]*mibObject),
+		global:  make(map[string]*mibObject),
+		oids:    make(map[*mibObject]string),
+		pending: make(map[*mibObject]bool),
+	}
+	for _, module := range modules {
+		r.modules[module.Name] = module
+		objects := make(map[string]*mibObject, len(module.Objects))
+		for _, obj := range module.Objects {
+			objects[obj.Name] = obj
+			if _, ok := r.global[obj.Name]; !ok {
+				r.global[obj.Name] = obj
+			}
+		}
+		r.objects[module.Name] = objects
+	}
+	return r
+}
+
+func (r *mibResolver) lookupObject(moduleName, name string) *mibObject {
+	if obj, ok := r.objects[moduleName][name]; ok {
+		return obj
+	}
+	if module, ok := r.modules[moduleName]; ok {
+		if from, ok := module.Imports[name]; ok {
+			if obj, ok := r.objects[from][name]; ok {
+				return obj
+			}
+		}
+	}
+	return r.global[name]
+}
+
+func (r *mibResolver) resolveOID(obj *mibObject) (string, bool) {
+	if oid, ok := r.oids[obj]; ok {
+		return oid, true
+	}
+	if r.pending[obj] {
+		return "", false
+	}
+	r.pending[obj] = true
+	defer delete(r.pending, obj)
+
+	var parts []string
+	if parent := obj.Value.Parent; parent != "" {
+		var parentOID string
+		if parentObj := r.lookupObject(obj.Module, parent); parentObj != nil {
+			oid, ok := r.resolveOID(parentObj)
+			if !ok {
+				return "", false
+			}
+			parentOID = oid
+		} else if oid, ok := builtinOIDs[parent]; ok {
+			parentOID = oid
+		} else {
+			return "", false
+		}
+		parts = append(parts, parentOID)
+	}
+	for _, sub := range obj.Value.Subs {
+		parts = append(parts, strconv.FormatUint(uint64(sub), 10))
+	}
+
+	oid := strings.Join(parts, ".")
+	r.oids[obj] = oid
+	return oid, true
+}
+
+func (r *mibResolver) lookupType(moduleName, name string) *mibType {
+	if module, ok := r.modules[moduleName]; ok {
+		if t, ok := module.Types[name]; ok {
+			return t
+		}
+		if from, ok := module.Imports[name]; ok {
+			if t, ok := r.modules[from].typeOf(name); ok {
+				return t
+			}
+		}
+	}
+	for _, module := range r.modules {
+		if t, ok := module.Types[name]; ok {
+			return t
+		}
+	}
+	return builtinTypes[name]
+}
+
+func (m *mibModule) typeOf(name string) (*mibType, bool) {
+	if m == nil {
+		return nil, false
+	}
+	t, ok := m.Types[name]
+	return t, ok
+}
+
+// fillType 沿着 TEXTUAL-CONVENTION 的引用链补充节点的基础类型、DISPLAY-HINT 以及枚举
+func (r *mibResolver) fillType(node *Node, syntax *mibType) {
+	t := syntax
+	for depth := 0; t != nil && depth < maxTypeDepth; depth++ {
+		if node.Hint == "" {
+			node.Hint = t.Hint
+		}
+		if len(node.Enums) == 0 {
+			node.Enums = t.Enums
+		}
+		if applicationTypes[t.Base] {
+			node.Type = t.Base
+			return
+		}
+		node.Type = t.Base
+		t = r.lookupType(node.Module, t.Base)
+		// RFC1213-MIB 等 SMIv1 模块中的 DisplayString、PhysAddress 没有 DISPLAY-HINT
+		if builtin, ok := builtinTypes[node.Type]; ok && node.Hint == "" {
+			node.Hint = builtin.Hint
+		}
+	}
+}
+
+// buildMIB 解析所有节点，无法解析 oid 的节点会被忽略
+func buildMIB(modules []*mibModule) *MIB {
+	r := newMIBResolver(modules)
+	mib := newMIB()
+
+	// 按模块名排序，保证同名节点的选择是确定的
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	for _, module := range modules {
+		for _, obj := range module.Objects {
+			oid, ok := r.resolveOID(obj)
+			if !ok {
+				logger.Debugf("mib: unresolved oid of %s::%s", obj.Module, obj.Name)
+				continue
+			}
+			node := &Node{Name: obj.Name, Module: obj.Module, OID: oid}
+			if obj.Syntax != nil {
+				r.fillType(node, obj.Syntax)
+			}
+			if _, ok := mib.nodes[oid]; !ok {
+				mib.nodes[oid] = node
+			}
+			if _, ok := mib.names[obj.Name]; !ok {
+				mib.names[obj.Name] = node
+			}
+			mib.names[obj.Module+"::"+obj.Name] = node
+		}
+	}
+	return mib
+}
+
+// ParseMIB 解析 MIB 文本
+func ParseMIB(texts ...string) (*MIB, error) {
+	var modules []*mibModule
+	for _, text := range texts {
+		parsed, err := parseModules(text)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, parsed...)
+	}
+	return buildMIB(modules), nil
+}
+
+// mibFiles 列出目录下的所有文件，按路径排序
+func mibFiles(dirs []string) ([]string, error) {
+	var files []string
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() && !strings.HasPrefix(d.Name(), ".") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fingerprint 根据文件路径、大小以及修改时间判断目录是否发生变化
+func fingerprint(files []string) string {
+	h := md5.New()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(h, "%s|%d|%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LoadMIB 加载目录下所有的 MIB 文件，解析失败的文件会被跳过
+func LoadMIB(dirs []string) (*MIB, error) {
+	files, err := mibFiles(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []*mibModule
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseModules(string(b))
+		if err != nil {
+			logger.Warnf("mib: skip file %s: %v", file, err)
+			continue
+		}
+		modules = append(modules, parsed...)
+	}
+	return buildMIB(modules), nil
+}
+
+// MIBStore 一组目录对应的 MIB，重新加载后正在运行的任务通过同一个 store 获取到最新的结果
+type MIBStore struct {
+	dirs []string
+
+	mut         sync.RWMutex
+	fingerprint string
+	mib         *MIB
+}
+
+var (
+	mibStoresMut sync.Mutex
+	mibStores    = make(map[string]*MIBStore)
+)
+
+// GetMIBStore 获取目录对应的 store，目录内容发生变化时重新加载，没有配置目录时返回 nil
+func GetMIBStore(dirs []string) *MIBStore {
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	key := strings.Join(dirs, "|")
+	mibStoresMut.Lock()
+	store, ok := mibStores[key]
+	if !ok {
+		store = &MIBStore{dirs: dirs}
+		mibStores[key] = store
+	}
+	mibStoresMut.Unlock()
+
+	if err := store.Reload(); err != nil {
+		logger.Errorf("mib: load %v failed: %v", dirs, err)
+	}
+	return store
+}
+
+// Reload 目录内容发生变化时重新解析
+func (s *MIBStore) Reload() error {
+	files, err := mibFiles(s.dirs)
+	if err != nil {
+		return err
+	}
+	fp := fingerprint(files)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.mib != nil && fp == s.fingerprint {
+		return nil
+	}
+	mib, err := LoadMIB(s.dirs)
+	if err != nil {
+		return errors.Wrapf(err, "load mib from %v", s.dirs)
+	}
+	s.mib, s.fingerprint = mib, fp
+	logger.Infof("mib: loaded %d nodes from %v", mib.Len(), s.dirs)
+	return nil
+}
+
+// MIB 当前加载的 MIB，store 为 nil 时返回 nil
+func (s *MIBStore) MIB() *MIB {
+	if s == nil {
+		return nil
+	}
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.mib
+}
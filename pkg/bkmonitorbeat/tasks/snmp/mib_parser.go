@@ -0,0 +1,466 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// mibLexer 将 MIB 文本切分为 token，注释以及空白会被忽略，字符串作为单个 token 并保留引号
+type mibLexer struct {
+	src []rune
+	pos int
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'
+}
+
+// skipComment 跳过注释，注释以 -- 开始，到行尾或者下一个 -- 结束
+func (l *mibLexer) skipComment() {
+	l.pos += 2
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\n':
+			return
+		case l.src[l.pos] == '-' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '-':
+			l.pos += 2
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *mibLexer) next() (string, bool) {
+	for l.pos < len(l.src) {
+		r := l.src[l.pos]
+		switch {
+		case unicode.IsSpace(r):
+			l.pos++
+			continue
+		case r == '-' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '-':
+			l.skipComment()
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.src) {
+		return "", false
+	}
+
+	start := l.pos
+	r := l.src[l.pos]
+	switch {
+	case r == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			l.pos++
+		}
+		l.pos++
+		if l.pos > len(l.src) {
+			l.pos = len(l.src)
+		}
+	case r == ':' && strings.HasPrefix(string(l.src[l.pos:min(l.pos+3, len(l.src))]), "::="):
+		l.pos += 3
+	case r == '.' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '.':
+		l.pos += 2
+	case isIdentRune(r):
+		for l.pos < len(l.src) && isIdentRune(l.src[l.pos]) {
+			l.pos++
+		}
+	default:
+		l.pos++
+	}
+	return string(l.src[start:l.pos]), true
+}
+
+// tokenize 切分 MIB 文本
+func tokenize(text string) []string {
+	l := &mibLexer{src: []rune(text)}
+	var tokens []string
+	for {
+		token, ok := l.next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// mibType 类型定义，TEXTUAL-CONVENTION 或者类型赋值
+type mibType struct {
+	Base  string // 引用的类型名，如 INTEGER、OCTET STRING 或者其他 TEXTUAL-CONVENTION
+	Hint  string
+	Enums map[int64]string
+}
+
+// oidValue 未解析的 oid，由父节点以及后续的子 id 组成
+type oidValue struct {
+	Parent string
+	Subs   []uint32
+}
+
+// mibObject 模块中定义的 oid 节点
+type mibObject struct {
+	Name   string
+	Module string
+	Value  oidValue
+	Syntax *mibType
+}
+
+// mibModule 单个 MIB 模块的解析结果
+type mibModule struct {
+	Name    string
+	Imports map[string]string // 名称 -> 模块
+	Objects []*mibObject
+	Types   map[string]*mibType
+}
+
+// mibParser 只解析翻译需要的部分：oid 赋值、各类宏定义的 oid 以及 SYNTAX、类型定义
+type mibParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *mibParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *mibParser) peekAt(offset int) string {
+	if p.pos+offset >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *mibParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *mibParser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *mibParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return errors.Errorf("expect %q but got %q", token, got)
+	}
+	return nil
+}
+
+// skipBalanced 跳过成对的括号，当前 token 必须是左括号
+func (p *mibParser) skipBalanced(open, close string) {
+	depth := 0
+	for !p.eof() {
+		switch p.next() {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// parseModules 解析文件中的所有模块
+func parseModules(text string) ([]*mibModule, error) {
+	p := &mibParser{tokens: tokenize(text)}
+	var modules []*mibModule
+	for !p.eof() {
+		module, err := p.parseModule()
+		if err != nil {
+			return modules, err
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+func (p *mibParser) parseModule() (*mibModule, error) {
+	module := &mibModule{
+		Name:    p.next(),
+		Imports: make(map[string]string),
+		Types:   make(map[string]*mibType),
+	}
+	// 模块名后面可能跟着模块的 oid
+	if p.peek() == "{" {
+		p.skipBalanced("{", "}")
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, errors.Wrapf(err, "module %s", module.Name)
+	}
+	for !p.eof() && p.peek() != "::=" {
+		p.next()
+	}
+	if err := p.expect("::="); err != nil {
+		return nil, errors.Wrapf(err, "module %s", module.Name)
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, errors.Wrapf(err, "module %s", module.Name)
+	}
+
+	for !p.eof() {
+		token := p.peek()
+		switch token {
+		case "END":
+			p.next()
+			return module, nil
+		case "IMPORTS":
+			p.next()
+			p.parseImports(module)
+			continue
+		case "EXPORTS":
+			for !p.eof() && p.next() != ";" {
+			}
+			continue
+		}
+		if err := p.parseAssignment(module); err != nil {
+			return nil, errors.Wrapf(err, "module %s", module.Name)
+		}
+	}
+	return nil, errors.Errorf("module %s: missing END", module.Name)
+}
+
+// parseImports 解析 IMPORTS a, b FROM MODULE-A c FROM MODULE-B ;
+func (p *mibParser) parseImports(module *mibModule) {
+	var names []string
+	for !p.eof() {
+		token := p.next()
+		switch token {
+		case ";":
+			return
+		case ",":
+		case "FROM":
+			from := p.next()
+			for _, name := range names {
+				module.Imports[name] = from
+			}
+			names = names[:0]
+		default:
+			names = append(names, token)
+		}
+	}
+}
+
+// parseAssignment 解析模块中的单个定义
+func (p *mibParser) parseAssignment(module *mibModule) error {
+	name := p.next()
+
+	switch p.peek() {
+	case "MACRO":
+		// 宏定义只存在于 SNMPv2-SMI 等基础模块中，直接跳过
+		for !p.eof() && p.next() != "END" {
+		}
+		return nil
+
+	case "::=":
+		p.next()
+		// 类型赋值以大写字母开头，值赋值以小写字母开头
+		if name != "" && unicode.IsUpper([]rune(name)[0]) {
+			module.Types[name] = p.parseTypeAssignment()
+			return nil
+		}
+		return errors.Errorf("unexpected assignment of %s", name)
+
+	case "OBJECT":
+		if p.peekAt(1) == "IDENTIFIER" {
+			p.pos += 2
+			if err := p.expect("::="); err != nil {
+				return err
+			}
+			value, err := p.parseOIDValue()
+			if err != nil {
+				return errors.Wrapf(err, "object %s", name)
+			}
+			module.Objects = append(module.Objects, &mibObject{Name: name, Module: module.Name, Value: value})
+			return nil
+		}
+
+	case "TRAP-TYPE":
+		return p.parseTrapType(module, name)
+	}
+
+	// 其他宏：OBJECT-TYPE、MODULE-IDENTITY、NOTIFICATION-TYPE 等，读取到 ::= 为止
+	macro := p.next()
+	obj := &mibObject{Name: name, Module: module.Name}
+	for !p.eof() && p.peek() != "::=" {
+		token := p.next()
+		if token == "SYNTAX" && macro == "OBJECT-TYPE" {
+			obj.Syntax = p.parseType()
+		}
+	}
+	if err := p.expect("::="); err != nil {
+		return errors.Wrapf(err, "%s %s", macro, name)
+	}
+	value, err := p.parseOIDValue()
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", macro, name)
+	}
+	obj.Value = value
+	module.Objects = append(module.Objects, obj)
+	return nil
+}
+
+// parseTrapType 解析 v1 的 TRAP-TYPE，对应的 oid 为 enterprise.0.specific
+func (p *mibParser) parseTrapType(module *mibModule, name string) error {
+	p.next()
+	var enterprise string
+	for !p.eof() && p.peek() != "::=" {
+		if p.next() == "ENTERPRISE" {
+			enterprise = p.next()
+		}
+	}
+	if err := p.expect("::="); err != nil {
+		return errors.Wrapf(err, "TRAP-TYPE %s", name)
+	}
+	specific, err := strconv.ParseUint(p.next(), 10, 32)
+	if err != nil || enterprise == "" {
+		return errors.Errorf("TRAP-TYPE %s: invalid enterprise or specific number", name)
+	}
+	module.Objects = append(module.Objects, &mibObject{
+		Name:   name,
+		Module: module.Name,
+		Value:  oidValue{Parent: enterprise, Subs: []uint32{0, uint32(specific)}},
+	})
+	return nil
+}
+
+// parseTypeAssignment 解析 Name ::= TEXTUAL-CONVENTION ... 或者 Name ::= <type>
+func (p *mibParser) parseTypeAssignment() *mibType {
+	if p.peek() != "TEXTUAL-CONVENTION" {
+		return p.parseType()
+	}
+
+	p.next()
+	var hint string
+	for !p.eof() {
+		switch p.next() {
+		case "DISPLAY-HINT":
+			hint = strings.Trim(p.next(), `"`)
+		case "SYNTAX":
+			t := p.parseType()
+			t.Hint = hint
+			return t
+		}
+	}
+	return &mibType{Hint: hint}
+}
+
+// parseType 解析类型，记录引用的类型名以及枚举值，约束会被忽略
+func (p *mibParser) parseType() *mibType {
+	t := &mibType{}
+	// [APPLICATION 0] IMPLICIT
+	if p.peek() == "[" {
+		p.skipBalanced("[", "]")
+	}
+	if p.peek() == "IMPLICIT" || p.peek() == "EXPLICIT" {
+		p.next()
+	}
+
+	switch token := p.next(); token {
+	case "OCTET", "OBJECT":
+		t.Base = token + " " + p.next()
+	case "SEQUENCE":
+		if p.peek() == "OF" {
+			p.next()
+			t.Base = "SEQUENCE OF " + p.next()
+			return t
+		}
+		t.Base = token
+		p.skipBalanced("{", "}")
+		return t
+	case "CHOICE":
+		t.Base = token
+		p.skipBalanced("{", "}")
+		return t
+	default:
+		t.Base = token
+	}
+
+	switch p.peek() {
+	case "{":
+		t.Enums = p.parseEnums()
+	case "(":
+		p.skipBalanced("(", ")")
+	}
+	return t
+}
+
+// parseEnums 解析 { up(1), down(2) }
+func (p *mibParser) parseEnums() map[int64]string {
+	enums := make(map[int64]string)
+	p.next()
+	for !p.eof() {
+		token := p.next()
+		if token == "}" {
+			break
+		}
+		if p.peek() != "(" {
+			continue
+		}
+		p.next()
+		value, err := strconv.ParseInt(p.next(), 10, 64)
+		if err == nil {
+			enums[value] = token
+		}
+		for !p.eof() && p.next() != ")" {
+		}
+	}
+	return enums
+}
+
+// parseOIDValue 解析 { parent 1 2 } 或者 { iso org(3) dod(6) }
+func (p *mibParser) parseOIDValue() (oidValue, error) {
+	var value oidValue
+	if err := p.expect("{"); err != nil {
+		return value, err
+	}
+	first := true
+	for !p.eof() {
+		token := p.next()
+		if token == "}" {
+			if value.Parent == "" && len(value.Subs) == 0 {
+				return value, errors.New("empty oid value")
+			}
+			return value, nil
+		}
+
+		// name(1) 形式的组件使用括号内的数值
+		if p.peek() == "(" {
+			p.next()
+			token = p.next()
+			if err := p.expect(")"); err != nil {
+				return value, err
+			}
+		}
+		n, err := strconv.ParseUint(token, 10, 32)
+		if err != nil {
+			if !first {
+				return value, errors.Errorf("invalid oid component %q", token)
+			}
+			value.Parent = token
+		} else {
+			value.Subs = append(value.Subs, uint32(n))
+		}
+		first = false
+	}
+	return value, errors.New("unterminated oid value")
+}
@@ -0,0 +1,401 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMIB = `
+BK-TEST-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    MODULE-IDENTITY, OBJECT-TYPE, NOTIFICATION-TYPE,
+    Integer32, Counter32, IpAddress, enterprises
+        FROM SNMPv2-SMI
+    TEXTUAL-CONVENTION, DisplayString, DateAndTime
+        FROM SNMPv2-TC
+    TRAP-TYPE
+        FROM RFC-1215;
+
+bkTestMIB MODULE-IDENTITY
+    LAST-UPDATED "202410180000Z"
+    ORGANIZATION "BlueKing"
+    CONTACT-INFO "-- not a comment"
+    DESCRIPTION
+        "Test MIB with ::= inside the description."
+    REVISION "202410180000Z"
+    DESCRIPTION "Initial version."
+    ::= { enterprises 99999 }
+
+bkObjects OBJECT IDENTIFIER ::= { bkTestMIB 1 }
+bkNotifications OBJECT IDENTIFIER ::= { bkTestMIB 2 }
+
+BkStatus ::= TEXTUAL-CONVENTION
+    STATUS current
+    DESCRIPTION "Port status."
+    SYNTAX INTEGER { up(1), down(2), testing(3) }
+
+BkTemperature ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d-1"
+    STATUS current
+    DESCRIPTION "Temperature in 0.1 degree."
+    SYNTAX Integer32 (-1000..1000)
+
+BkStatusAlias ::= BkStatus
+
+bkSysName OBJECT-TYPE
+    SYNTAX DisplayString (SIZE (0..255))
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "System name."
+    ::= { bkObjects 1 }
+
+bkSysTime OBJECT-TYPE
+    SYNTAX DateAndTime
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "System time."
+    ::= { bkObjects 2 }
+
+bkTemperature OBJECT-TYPE
+    SYNTAX BkTemperature
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Temperature."
+    DEFVAL { 0 }
+    ::= { bkObjects 3 }
+
+bkPortTable OBJECT-TYPE
+    SYNTAX SEQUENCE OF BkPortEntry
+    MAX-ACCESS not-accessible
+    STATUS current
+    DESCRIPTION "Port table."
+    ::= { bkObjects 10 }
+
+bkPortEntry OBJECT-TYPE
+    SYNTAX BkPortEntry
+    MAX-ACCESS not-accessible
+    STATUS current
+    DESCRIPTION "Port entry."
+    INDEX { bkPortIndex }
+    ::= { bkPortTable 1 }
+
+BkPortEntry ::= SEQUENCE {
+    bkPortIndex   Integer32,
+    bkPortStatus  BkStatusAlias,
+    bkPortAddress IpAddress,
+    bkPortInPkts  Counter32,
+    bkPortFlags   BITS
+}
+
+bkPortIndex OBJECT-TYPE
+    SYNTAX Integer32 (1..65535)
+    MAX-ACCESS not-accessible
+    STATUS current
+    DESCRIPTION "Port index."
+    ::= { bkPortEntry 1 }
+
+bkPortStatus OBJECT-TYPE
+    SYNTAX BkStatusAlias
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Port status."
+    ::= { bkPortEntry 2 }
+
+bkPortAddress OBJECT-TYPE
+    SYNTAX IpAddress
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Port address."
+    ::= { bkPortEntry 3 }
+
+bkPortInPkts OBJECT-TYPE
+    SYNTAX Counter32
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Inbound packets."
+    ::= { bkPortEntry 4 }
+
+bkPortFlags OBJECT-TYPE
+    SYNTAX BITS { enabled(0), mirrored(1), trunk(2) }
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Port flags."
+    ::= { bkPortEntry 5 }
+
+bkPortDown NOTIFICATION-TYPE
+    OBJECTS { bkPortStatus, bkPortAddress }
+    STATUS current
+    DESCRIPTION "Port down."
+    ::= { bkNotifications 1 }
+
+bkLegacyTrap TRAP-TYPE
+    ENTERPRISE bkTestMIB
+    VARIABLES { bkPortStatus }
+    DESCRIPTION "SMIv1 trap."
+    ::= 7
+
+END
+`
+
+func TestParseMIB(t *testing.T) {
+	mib, err := ParseMIB(testMIB)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		oid      string
+		expected string
+	}{
+		"模块节点": {
+			oid:      "1.3.6.1.4.1.99999",
+			expected: "bkTestMIB",
+		},
+		"标量": {
+			oid:      ".1.3.6.1.4.1.99999.1.1.0",
+			expected: "bkSysName.0",
+		},
+		"表格列以及索引": {
+			oid:      "1.3.6.1.4.1.99999.1.10.1.2.3",
+			expected: "bkPortStatus.3",
+		},
+		"NOTIFICATION-TYPE": {
+			oid:      "1.3.6.1.4.1.99999.2.1",
+			expected: "bkPortDown",
+		},
+		"v1 TRAP-TYPE": {
+			oid:      "1.3.6.1.4.1.99999.0.7",
+			expected: "bkLegacyTrap",
+		},
+		"未定义的节点": {
+			oid:      "1.3.6.1.4.1.88888.1",
+			expected: "1.3.6.1.4.1.88888.1",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			translated, _ := mib.Translate(c.oid)
+			assert.Equal(t, c.expected, translated)
+		})
+	}
+
+	oid, ok := mib.Resolve("BK-TEST-MIB::bkPortInPkts")
+	assert.True(t, ok)
+	assert.Equal(t, "1.3.6.1.4.1.99999.1.10.1.4", oid)
+	oid, ok = mib.Resolve("bkSysName.0")
+	assert.True(t, ok)
+	assert.Equal(t, "1.3.6.1.4.1.99999.1.1.0", oid)
+	oid, ok = mib.Resolve("ifMtu")
+	assert.False(t, ok)
+}
+
+func TestNodeFormatValue(t *testing.T) {
+	mib, err := ParseMIB(testMIB)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		oid      string
+		pdu      gosnmp.SnmpPDU
+		expected string
+		ok       bool
+	}{
+		"TEXTUAL-CONVENTION 枚举": {
+			oid:      "1.3.6.1.4.1.99999.1.10.1.2.1",
+			pdu:      gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 2},
+			expected: "down",
+			ok:       true,
+		},
+		"未定义的枚举值": {
+			oid: "1.3.6.1.4.1.99999.1.10.1.2.1",
+			pdu: gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: 9},
+		},
+		"整数 DISPLAY-HINT": {
+			oid:      "1.3.6.1.4.1.99999.1.3.0",
+			pdu:      gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -5},
+			expected: "-0.5",
+			ok:       true,
+		},
+		"DateAndTime": {
+			oid:      "1.3.6.1.4.1.99999.1.2.0",
+			pdu:      gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0x07, 0xe8, 10, 18, 14, 30, 15, 0, '+', 8, 0}},
+			expected: "2024-10-18,14:30:15.0,+8:0",
+			ok:       true,
+		},
+		"DisplayString 交由调用方处理": {
+			oid: "1.3.6.1.4.1.99999.1.1.0",
+			pdu: gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("bk")},
+		},
+		"IpAddress": {
+			oid:      "1.3.6.1.4.1.99999.1.10.1.3.1",
+			pdu:      gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{10, 0, 0, 1}},
+			expected: "10.0.0.1",
+			ok:       true,
+		},
+		"BITS": {
+			oid:      "1.3.6.1.4.1.99999.1.10.1.5.1",
+			pdu:      gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0xa0, 0x01}},
+			expected: "enabled,trunk,15",
+			ok:       true,
+		},
+		"计数器没有格式": {
+			oid: "1.3.6.1.4.1.99999.1.10.1.4.1",
+			pdu: gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(10)},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			node, _ := mib.Lookup(c.oid)
+			assert.NotNil(t, node)
+			s, ok := node.FormatValue(c.pdu)
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.expected, s)
+		})
+	}
+}
+
+func TestFormatOctetHint(t *testing.T) {
+	testCases := map[string]struct {
+		hint     string
+		data     []byte
+		expected string
+	}{
+		"mac 地址": {
+			hint:     "1x:",
+			data:     []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+			expected: "00:1a:2b:3c:4d:5e",
+		},
+		"ipv4 地址": {
+			hint:     "1d.1d.1d.1d",
+			data:     []byte{192, 168, 1, 1},
+			expected: "192.168.1.1",
+		},
+		"重复次数以及结束符": {
+			hint:     "*1d./1d",
+			data:     []byte{2, 1, 2, 3},
+			expected: "1.2/3",
+		},
+		"多字节整数": {
+			hint:     "2x",
+			data:     []byte{0x00, 0x0f},
+			expected: "000f",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, ok := formatOctetHint(c.hint, c.data)
+			assert.True(t, ok)
+			assert.Equal(t, c.expected, s)
+		})
+	}
+
+	_, ok := formatOctetHint("1q", []byte{1})
+	assert.False(t, ok)
+}
+
+func TestParseMIBError(t *testing.T) {
+	_, err := ParseMIB("BROKEN-MIB DEFINITIONS ::= BEGIN\nbkObjects OBJECT IDENTIFIER ::= { enterprises 1 }\n")
+	assert.Error(t, err)
+}
+
+func TestMIBStore(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "BK-TEST-MIB.txt"), []byte(testMIB), 0o644))
+	// 无法解析的文件会被跳过
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("vendor mibs"), 0o644))
+
+	store := GetMIBStore([]string{dir})
+	assert.Same(t, store, GetMIBStore([]string{dir}))
+	mib := store.MIB()
+	name, _ := mib.Translate("1.3.6.1.4.1.99999.1.1.0")
+	assert.Equal(t, "bkSysName.0", name)
+
+	// 文件没有变化时不重新解析
+	assert.NoError(t, store.Reload())
+	assert.Same(t, mib, store.MIB())
+
+	extra := "BK-EXTRA-MIB DEFINITIONS ::= BEGIN\nbkExtra OBJECT IDENTIFIER ::= { bkObjects 9 }\nEND\n"
+	path := filepath.Join(dir, "BK-EXTRA-MIB.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(extra), 0o644))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	assert.NoError(t, store.Reload())
+	name, _ = store.MIB().Translate("1.3.6.1.4.1.99999.1.9")
+	assert.Equal(t, "bkExtra", name)
+
+	var nilStore *MIBStore
+	assert.Nil(t, nilStore.MIB())
+	assert.Nil(t, GetMIBStore(nil))
+}
+
+func TestParseSMIv1MIB(t *testing.T) {
+	smi := `
+RFC1155-SMI DEFINITIONS ::= BEGIN
+EXPORTS internet, IpAddress;
+internet OBJECT IDENTIFIER ::= { iso org(3) dod(6) 1 }
+mgmt OBJECT IDENTIFIER ::= { internet 2 }
+OBJECT-TYPE MACRO ::=
+BEGIN
+    TYPE NOTATION ::= "SYNTAX" type (TYPE ObjectSyntax)
+    VALUE NOTATION ::= value (VALUE ObjectName)
+END
+IpAddress ::= [APPLICATION 0] IMPLICIT OCTET STRING (SIZE (4))
+END
+`
+	rfc1213 := `
+RFC1213-MIB DEFINITIONS ::= BEGIN
+IMPORTS mgmt, IpAddress FROM RFC1155-SMI OBJECT-TYPE FROM RFC-1212;
+mib-2 OBJECT IDENTIFIER ::= { mgmt 1 }
+interfaces OBJECT IDENTIFIER ::= { mib-2 2 }
+PhysAddress ::= OCTET STRING
+ifTable OBJECT-TYPE
+    SYNTAX SEQUENCE OF IfEntry
+    ACCESS not-accessible
+    STATUS mandatory
+    ::= { interfaces 2 }
+ifEntry OBJECT-TYPE
+    SYNTAX IfEntry
+    ACCESS not-accessible
+    STATUS mandatory
+    INDEX { ifIndex }
+    ::= { ifTable 1 }
+ifPhysAddress OBJECT-TYPE
+    SYNTAX PhysAddress
+    ACCESS read-only
+    STATUS mandatory
+    ::= { ifEntry 6 }
+ifOperStatus OBJECT-TYPE
+    SYNTAX INTEGER { up(1), down(2), testing(3) }
+    ACCESS read-only
+    STATUS mandatory
+    DEFVAL { 'ff'H }
+    ::= { ifEntry 8 }
+END
+`
+	mib, err := ParseMIB(rfc1213, smi)
+	assert.NoError(t, err)
+
+	oid, ok := mib.Resolve("RFC1213-MIB::ifOperStatus")
+	assert.True(t, ok)
+	assert.Equal(t, "1.3.6.1.2.1.2.2.1.8", oid)
+
+	node, suffix := mib.Lookup("1.3.6.1.2.1.2.2.1.6.2")
+	assert.Equal(t, "2", suffix)
+	s, ok := node.FormatValue(gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0, 0x0c, 0x29, 1, 2, 3}})
+	assert.True(t, ok)
+	assert.Equal(t, "00:0c:29:01:02:03", s)
+}
@@ -0,0 +1,307 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// Client snmp 请求，由 gosnmp.GoSNMP 实现
+type Client interface {
+	Get(oids []string) (*gosnmp.SnmpPacket, error)
+	WalkAll(rootOid string) ([]gosnmp.SnmpPDU, error)
+	BulkWalkAll(rootOid string) ([]gosnmp.SnmpPDU, error)
+}
+
+// Sample 相同维度的一组指标
+type Sample struct {
+	Metrics    map[string]float64
+	Dimensions map[string]string
+}
+
+type counterValue struct {
+	value float64
+	ts    time.Time
+}
+
+// Poller 按配置采集设备，并记录 counter 的上一次采集值用于计算速率
+type Poller struct {
+	conf *configs.SNMPTaskConfig
+	mibs *MIBStore
+
+	mut      sync.Mutex
+	counters map[string]map[string]counterValue // target -> name|index -> value
+}
+
+func NewPoller(conf *configs.SNMPTaskConfig, mibs *MIBStore) *Poller {
+	return &Poller{
+		conf:     conf,
+		mibs:     mibs,
+		counters: make(map[string]map[string]counterValue),
+	}
+}
+
+// normalizeOID 去掉 oid 开头的点号
+func normalizeOID(oid string) string {
+	return strings.TrimPrefix(oid, ".")
+}
+
+// toFloat 将数值类型的 pdu 转换为浮点数，字符串类型尝试按数字解析
+func toFloat(pdu gosnmp.SnmpPDU) (float64, bool) {
+	switch pdu.Type {
+	case gosnmp.Counter32, gosnmp.Counter64, gosnmp.Gauge32, gosnmp.Integer, gosnmp.TimeTicks, gosnmp.Uinteger32:
+		f, _ := new(big.Float).SetInt(gosnmp.ToBigInt(pdu.Value)).Float64()
+		return f, true
+	case gosnmp.OpaqueFloat:
+		v, ok := pdu.Value.(float32)
+		return float64(v), ok
+	case gosnmp.OpaqueDouble:
+		v, ok := pdu.Value.(float64)
+		return v, ok
+	case gosnmp.OctetString:
+		b, ok := pdu.Value.([]byte)
+		if !ok {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString 将 pdu 转换为维度值
+func toString(pdu gosnmp.SnmpPDU) (string, bool) {
+	switch pdu.Type {
+	case gosnmp.OctetString:
+		b, ok := pdu.Value.([]byte)
+		return string(b), ok
+	case gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+		s, ok := pdu.Value.(string)
+		return normalizeOID(s), ok
+	}
+	if f, ok := toFloat(pdu); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// rate 计算 counter 的每秒增长速率，Counter32 回绕时补偿 2^32，其他情况下的回退视为重置
+func rate(prev counterValue, cur float64, now time.Time, typ gosnmp.Asn1BER) (float64, bool) {
+	seconds := now.Sub(prev.ts).Seconds()
+	if seconds <= 0 {
+		return 0, false
+	}
+	delta := cur - prev.value
+	if delta < 0 {
+		if typ != gosnmp.Counter32 {
+			return 0, false
+		}
+		delta += math.MaxUint32 + 1
+	}
+	return delta / seconds, true
+}
+
+// pollState 单次采集过程中的 counter 状态以及使用的 MIB
+type pollState struct {
+	now  time.Time
+	mib  *MIB
+	prev map[string]counterValue
+	cur  map[string]counterValue
+}
+
+// resolve 将配置中的符号名翻译为 oid，无法翻译时忽略该配置
+func (s *pollState) resolve(name string) (string, bool) {
+	oid, ok := s.mib.Resolve(name)
+	if !ok {
+		logger.Warnf("snmp: unknown oid %s, please check mib_dirs", name)
+	}
+	return oid, ok
+}
+
+// format 维度值优先按照 MIB 中的定义格式化
+func (s *pollState) format(pdu gosnmp.SnmpPDU) (string, bool) {
+	node, _ := s.mib.Lookup(pdu.Name)
+	if value, ok := node.FormatValue(pdu); ok {
+		return value, true
+	}
+	return toString(pdu)
+}
+
+// addMetric 写入指标，counter 类型额外计算 <name>_rate
+func (s *pollState) addMetric(metrics map[string]float64, m configs.SNMPMetricConfig, index string, pdu gosnmp.SnmpPDU) {
+	value, ok := toFloat(pdu)
+	if !ok {
+		logger.Debugf("snmp oid %s of %s is not numeric, type: %v", pdu.Name, m.Name, pdu.Type)
+		return
+	}
+	metrics[m.Name] = value
+	if m.Type != configs.SNMPMetricCounter {
+		return
+	}
+
+	key := m.Name + "|" + index
+	if prev, ok := s.prev[key]; ok {
+		if r, ok := rate(prev, value, s.now, pdu.Type); ok {
+			metrics[m.Name+"_rate"] = r
+		}
+	}
+	s.cur[key] = counterValue{value: value, ts: s.now}
+}
+
+// Poll 采集单个设备的标量以及表格
+func (p *Poller) Poll(cli Client, target string, now time.Time) ([]Sample, error) {
+	p.mut.Lock()
+	state := &pollState{
+		now:  now,
+		mib:  p.mibs.MIB(),
+		prev: p.counters[target],
+		cur:  make(map[string]counterValue),
+	}
+	p.mut.Unlock()
+
+	var samples []Sample
+	if len(p.conf.Metrics) > 0 {
+		sample, err := p.pollScalars(cli, state)
+		if err != nil {
+			return nil, err
+		}
+		if len(sample.Metrics) > 0 {
+			samples = append(samples, sample)
+		}
+	}
+
+	for _, table := range p.conf.Tables {
+		rows, err := p.pollTable(cli, table, state)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, rows...)
+	}
+
+	// 只保留本次采集到的 counter，设备下线的索引随之清理
+	p.mut.Lock()
+	p.counters[target] = state.cur
+	p.mut.Unlock()
+	return samples, nil
+}
+
+// pollScalars 通过 GET 采集标量，超过单次请求的 oid 数量上限时分批请求
+func (p *Poller) pollScalars(cli Client, state *pollState) (Sample, error) {
+	sample := Sample{Metrics: make(map[string]float64), Dimensions: make(map[string]string)}
+
+	oids := make(map[string][]configs.SNMPMetricConfig)
+	names := make([]string, 0, len(p.conf.Metrics))
+	for _, m := range p.conf.Metrics {
+		oid, ok := state.resolve(m.OID)
+		if !ok {
+			continue
+		}
+		if _, ok := oids[oid]; !ok {
+			names = append(names, oid)
+		}
+		oids[oid] = append(oids[oid], m)
+	}
+
+	for start := 0; start < len(names); start += gosnmp.MaxOids {
+		end := start + gosnmp.MaxOids
+		if end > len(names) {
+			end = len(names)
+		}
+		packet, err := cli.Get(names[start:end])
+		if err != nil {
+			return sample, err
+		}
+		for _, pdu := range packet.Variables {
+			for _, m := range oids[normalizeOID(pdu.Name)] {
+				state.addMetric(sample.Metrics, m, "", pdu)
+			}
+		}
+	}
+	return sample, nil
+}
+
+func (p *Poller) walk(cli Client, oid string) ([]gosnmp.SnmpPDU, error) {
+	if p.conf.WalkMode == configs.SNMPWalkModeWalk {
+		return cli.WalkAll(oid)
+	}
+	return cli.BulkWalkAll(oid)
+}
+
+// pollTable 遍历表格中的每一列，相同索引的列组成一行
+func (p *Poller) pollTable(cli Client, table configs.SNMPTableConfig, state *pollState) ([]Sample, error) {
+	rows := make(map[string]*Sample)
+	var indexes []string
+	getRow := func(index string) *Sample {
+		row, ok := rows[index]
+		if !ok {
+			row = &Sample{
+				Metrics:    make(map[string]float64),
+				Dimensions: map[string]string{table.IndexName: index},
+			}
+			rows[index] = row
+			indexes = append(indexes, index)
+		}
+		return row
+	}
+
+	for _, m := range table.Metrics {
+		oid, ok := state.resolve(m.OID)
+		if !ok {
+			continue
+		}
+		pdus, err := p.walk(cli, oid)
+		if err != nil {
+			return nil, err
+		}
+		for _, pdu := range pdus {
+			index := strings.TrimPrefix(normalizeOID(pdu.Name), oid+".")
+			state.addMetric(getRow(index).Metrics, m, index, pdu)
+		}
+	}
+
+	for _, d := range table.Dimensions {
+		oid, ok := state.resolve(d.OID)
+		if !ok {
+			continue
+		}
+		pdus, err := p.walk(cli, oid)
+		if err != nil {
+			return nil, err
+		}
+		for _, pdu := range pdus {
+			index := strings.TrimPrefix(normalizeOID(pdu.Name), oid+".")
+			row, ok := rows[index]
+			if !ok {
+				continue
+			}
+			if value, ok := state.format(pdu); ok {
+				row.Dimensions[d.Name] = value
+			}
+		}
+	}
+
+	samples := make([]Sample, 0, len(indexes))
+	for _, index := range indexes {
+		if row := rows[index]; len(row.Metrics) > 0 {
+			samples = append(samples, *row)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,261 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+// fakeClient 以 oid 为 key 返回预置的数据
+type fakeClient struct {
+	pdus  map[string]gosnmp.SnmpPDU
+	walks int
+	bulks int
+}
+
+func (c *fakeClient) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	packet := &gosnmp.SnmpPacket{}
+	for _, oid := range oids {
+		pdu, ok := c.pdus[oid]
+		if !ok {
+			pdu = gosnmp.SnmpPDU{Name: "." + oid, Type: gosnmp.NoSuchObject}
+		}
+		packet.Variables = append(packet.Variables, pdu)
+	}
+	return packet, nil
+}
+
+func (c *fakeClient) walk(root string) []gosnmp.SnmpPDU {
+	var pdus []gosnmp.SnmpPDU
+	for oid, pdu := range c.pdus {
+		if strings.HasPrefix(oid, root+".") {
+			pdus = append(pdus, pdu)
+		}
+	}
+	return pdus
+}
+
+func (c *fakeClient) WalkAll(root string) ([]gosnmp.SnmpPDU, error) {
+	c.walks++
+	return c.walk(root), nil
+}
+
+func (c *fakeClient) BulkWalkAll(root string) ([]gosnmp.SnmpPDU, error) {
+	c.bulks++
+	return c.walk(root), nil
+}
+
+func (c *fakeClient) set(oid string, typ gosnmp.Asn1BER, value interface{}) {
+	c.pdus[oid] = gosnmp.SnmpPDU{Name: "." + oid, Type: typ, Value: value}
+}
+
+func newTaskConfig(t *testing.T) *configs.SNMPTaskConfig {
+	conf := configs.NewSNMPTaskConfig()
+	conf.Targets = []string{"127.0.0.1"}
+	conf.Metrics = []configs.SNMPMetricConfig{
+		{Name: "sys_uptime", OID: ".1.3.6.1.2.1.1.3.0"},
+		{Name: "cpu_usage", OID: "1.3.6.1.4.1.2021.11.9.0"},
+		{Name: "missing", OID: "1.3.6.1.4.1.9999.0"},
+	}
+	conf.Tables = []configs.SNMPTableConfig{
+		{
+			IndexName: "if_index",
+			Metrics: []configs.SNMPMetricConfig{
+				{Name: "if_in_octets", OID: "1.3.6.1.2.1.2.2.1.10", Type: configs.SNMPMetricCounter},
+				{Name: "if_hc_in_octets", OID: "1.3.6.1.2.1.31.1.1.1.6", Type: "COUNTER"},
+			},
+			Dimensions: []configs.SNMPDimensionConfig{
+				{Name: "if_descr", OID: "1.3.6.1.2.1.2.2.1.2"},
+			},
+		},
+	}
+	assert.NoError(t, conf.Clean())
+	return conf
+}
+
+func newFakeClient() *fakeClient {
+	cli := &fakeClient{pdus: map[string]gosnmp.SnmpPDU{}}
+	cli.set("1.3.6.1.2.1.1.3.0", gosnmp.TimeTicks, uint32(1000))
+	cli.set("1.3.6.1.4.1.2021.11.9.0", gosnmp.OctetString, []byte("12.5"))
+	cli.set("1.3.6.1.2.1.2.2.1.2.1", gosnmp.OctetString, []byte("lo"))
+	cli.set("1.3.6.1.2.1.2.2.1.2.2", gosnmp.OctetString, []byte("eth0"))
+	cli.set("1.3.6.1.2.1.2.2.1.10.1", gosnmp.Counter32, uint(100))
+	cli.set("1.3.6.1.2.1.2.2.1.10.2", gosnmp.Counter32, uint(4294967000))
+	cli.set("1.3.6.1.2.1.31.1.1.1.6.2", gosnmp.Counter64, uint64(1000))
+	return cli
+}
+
+// findRow 按照索引查找表格中的一行
+func findRow(samples []Sample, index string) *Sample {
+	for i := range samples {
+		if samples[i].Dimensions["if_index"] == index {
+			return &samples[i]
+		}
+	}
+	return nil
+}
+
+func TestPoll(t *testing.T) {
+	conf := newTaskConfig(t)
+	cli := newFakeClient()
+	poller := NewPoller(conf, nil)
+
+	now := time.Now()
+	samples, err := poller.Poll(cli, "127.0.0.1", now)
+	assert.NoError(t, err)
+	assert.Len(t, samples, 3)
+	assert.Equal(t, 3, cli.bulks)
+	assert.Equal(t, 0, cli.walks)
+
+	// 标量，不存在的 oid 不上报
+	assert.Equal(t, map[string]float64{"sys_uptime": 1000, "cpu_usage": 12.5}, samples[0].Metrics)
+	assert.Empty(t, samples[0].Dimensions)
+
+	// 表格按照索引组成行，首次采集没有速率
+	row := findRow(samples, "2")
+	assert.NotNil(t, row)
+	assert.Equal(t, map[string]string{"if_index": "2", "if_descr": "eth0"}, row.Dimensions)
+	assert.Equal(t, map[string]float64{"if_in_octets": 4294967000, "if_hc_in_octets": 1000}, row.Metrics)
+
+	// 第二次采集计算速率，Counter32 回绕时补偿 2^32，Counter64 回退视为重置
+	cli.set("1.3.6.1.2.1.2.2.1.10.1", gosnmp.Counter32, uint(300))
+	cli.set("1.3.6.1.2.1.2.2.1.10.2", gosnmp.Counter32, uint(704))
+	cli.set("1.3.6.1.2.1.31.1.1.1.6.2", gosnmp.Counter64, uint64(10))
+	samples, err = poller.Poll(cli, "127.0.0.1", now.Add(10*time.Second))
+	assert.NoError(t, err)
+
+	row = findRow(samples, "1")
+	assert.Equal(t, 20.0, row.Metrics["if_in_octets_rate"])
+	row = findRow(samples, "2")
+	assert.Equal(t, 100.0, row.Metrics["if_in_octets_rate"])
+	_, ok := row.Metrics["if_hc_in_octets_rate"]
+	assert.False(t, ok)
+
+	// 不同设备的 counter 相互独立
+	samples, err = poller.Poll(cli, "127.0.0.2", now.Add(20*time.Second))
+	assert.NoError(t, err)
+	_, ok = findRow(samples, "1").Metrics["if_in_octets_rate"]
+	assert.False(t, ok)
+}
+
+func TestPollV1Walk(t *testing.T) {
+	conf := newTaskConfig(t)
+	conf.Version = "v1"
+	conf.WalkMode = configs.SNMPWalkModeBulkWalk
+	assert.NoError(t, conf.Clean())
+
+	cli := newFakeClient()
+	_, err := NewPoller(conf, nil).Poll(cli, "127.0.0.1", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cli.walks)
+	assert.Equal(t, 0, cli.bulks)
+}
+
+func TestRate(t *testing.T) {
+	now := time.Now()
+	prev := counterValue{value: 100, ts: now}
+
+	testCases := map[string]struct {
+		cur      float64
+		typ      gosnmp.Asn1BER
+		elapsed  time.Duration
+		expected float64
+		ok       bool
+	}{
+		"正常增长": {
+			cur:      200,
+			typ:      gosnmp.Counter64,
+			elapsed:  10 * time.Second,
+			expected: 10,
+			ok:       true,
+		},
+		"Counter32 回绕": {
+			cur:      99,
+			typ:      gosnmp.Counter32,
+			elapsed:  time.Second,
+			expected: 4294967295,
+			ok:       true,
+		},
+		"Counter64 重置": {
+			cur:     10,
+			typ:     gosnmp.Counter64,
+			elapsed: time.Second,
+		},
+		"时间未变化": {
+			cur: 200,
+			typ: gosnmp.Counter64,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			v, ok := rate(prev, c.cur, now.Add(c.elapsed), c.typ)
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	host, port := splitTarget("10.0.0.1", 161)
+	assert.Equal(t, "10.0.0.1", host)
+	assert.Equal(t, uint16(161), port)
+
+	host, port = splitTarget("10.0.0.1:1161", 161)
+	assert.Equal(t, "10.0.0.1", host)
+	assert.Equal(t, uint16(1161), port)
+
+	host, port = splitTarget("[::1]:1161", 161)
+	assert.Equal(t, "::1", host)
+	assert.Equal(t, uint16(1161), port)
+}
+
+func TestPollWithMIB(t *testing.T) {
+	mib, err := ParseMIB(testMIB)
+	assert.NoError(t, err)
+
+	conf := configs.NewSNMPTaskConfig()
+	conf.Metrics = []configs.SNMPMetricConfig{
+		{Name: "temperature", OID: "bkTemperature.0"},
+		{Name: "unknown", OID: "bkUnknown.0"},
+	}
+	conf.Tables = []configs.SNMPTableConfig{
+		{
+			Metrics: []configs.SNMPMetricConfig{
+				{Name: "in_pkts", OID: "BK-TEST-MIB::bkPortInPkts"},
+			},
+			Dimensions: []configs.SNMPDimensionConfig{
+				{Name: "status", OID: "bkPortStatus"},
+				{Name: "address", OID: "bkPortAddress"},
+			},
+		},
+	}
+	assert.NoError(t, conf.Clean())
+
+	cli := &fakeClient{pdus: map[string]gosnmp.SnmpPDU{}}
+	cli.set("1.3.6.1.4.1.99999.1.3.0", gosnmp.Integer, 235)
+	cli.set("1.3.6.1.4.1.99999.1.10.1.4.7", gosnmp.Counter32, uint(10))
+	cli.set("1.3.6.1.4.1.99999.1.10.1.2.7", gosnmp.Integer, 1)
+	cli.set("1.3.6.1.4.1.99999.1.10.1.3.7", gosnmp.IPAddress, "10.0.0.7")
+
+	samples, err := NewPoller(conf, &MIBStore{mib: mib}).Poll(cli, "127.0.0.1", time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, map[string]float64{"temperature": 235}, samples[0].Metrics)
+	assert.Equal(t, map[string]float64{"in_pkts": 10}, samples[1].Metrics)
+	assert.Equal(t, map[string]string{"index": "7", "status": "up", "address": "10.0.0.7"}, samples[1].Dimensions)
+}
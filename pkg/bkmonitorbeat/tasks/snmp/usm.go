@@ -0,0 +1,143 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package snmp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
+)
+
+// UnknownVersion 无法识别的 snmp 版本
+const UnknownVersion gosnmp.SnmpVersion = 4
+
+// ParseVersion 解析配置中的 snmp 版本
+func ParseVersion(version string) gosnmp.SnmpVersion {
+	switch strings.ToLower(version) {
+	case "v1", "1":
+		return gosnmp.Version1
+	case "v2", "v2c", "2", "2c":
+		return gosnmp.Version2c
+	case "v3", "3":
+		return gosnmp.Version3
+	default:
+		return UnknownVersion
+	}
+}
+
+// ParseMsgFlags 解析 v3 消息标识位
+func ParseMsgFlags(flag string) gosnmp.SnmpV3MsgFlags {
+	switch strings.ToLower(flag) {
+	case "authnopriv":
+		return gosnmp.AuthNoPriv
+	case "authpriv":
+		return gosnmp.AuthPriv
+	case "reportable":
+		return gosnmp.Reportable
+	default:
+		return gosnmp.NoAuthNoPriv
+	}
+}
+
+// ParseEngineID 将十六进制字符串格式的 engine id 转换为字节
+func ParseEngineID(id string) ([]byte, error) {
+	reader := bytes.NewReader([]byte(id))
+	resultID := make([]byte, 0)
+	buf := make([]byte, 2)
+	for {
+		num, err := reader.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if num != 2 {
+			return nil, fmt.Errorf("wrong length of enging ID")
+		}
+		i, err := strconv.ParseInt(string(buf), 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		resultID = append(resultID, byte(i))
+	}
+	return resultID, nil
+}
+
+// ParseAuthProtocol 解析 v3 认证协议
+func ParseAuthProtocol(auth string) gosnmp.SnmpV3AuthProtocol {
+	switch strings.ToLower(auth) {
+	case "md5":
+		return gosnmp.MD5
+	case "sha":
+		return gosnmp.SHA
+	case "sha224":
+		return gosnmp.SHA224
+	case "sha256":
+		return gosnmp.SHA256
+	case "sha384":
+		return gosnmp.SHA384
+	case "sha512":
+		return gosnmp.SHA512
+	default:
+		return gosnmp.NoAuth
+	}
+}
+
+// ParsePrivProtocol 解析 v3 加密协议
+func ParsePrivProtocol(privacy string) gosnmp.SnmpV3PrivProtocol {
+	switch strings.ToLower(privacy) {
+	case "des":
+		return gosnmp.DES
+	case "aes":
+		return gosnmp.AES
+	case "aes192":
+		return gosnmp.AES192
+	case "aes192c":
+		return gosnmp.AES192C
+	case "aes256":
+		return gosnmp.AES256
+	case "aes256c":
+		return gosnmp.AES256C
+	default:
+		return gosnmp.NoPriv
+	}
+}
+
+// NewUsmSecurityParameters 根据配置生成 v3 USM 参数
+func NewUsmSecurityParameters(usmConf configs.USMConfig) (*gosnmp.UsmSecurityParameters, error) {
+	engineID, err := ParseEngineID(usmConf.AuthoritativeEngineID)
+	if err != nil {
+		return nil, err
+	}
+	if usmConf.AuthoritativeEngineBoots == 0 {
+		usmConf.AuthoritativeEngineBoots = 1
+	}
+	if usmConf.AuthoritativeEngineTime == 0 {
+		usmConf.AuthoritativeEngineTime = 1
+	}
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:                 usmConf.UserName,
+		AuthenticationProtocol:   ParseAuthProtocol(usmConf.AuthenticationProtocol),
+		AuthenticationPassphrase: usmConf.AuthenticationPassphrase,
+		PrivacyProtocol:          ParsePrivProtocol(usmConf.PrivacyProtocol),
+		PrivacyPassphrase:        usmConf.PrivacyPassphrase,
+		AuthoritativeEngineBoots: usmConf.AuthoritativeEngineBoots,
+		AuthoritativeEngineTime:  usmConf.AuthoritativeEngineTime,
+		AuthoritativeEngineID:    string(engineID),
+	}
+	return sp, nil
+}
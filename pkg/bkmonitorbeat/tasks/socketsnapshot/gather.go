@@ -18,6 +18,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/processbeat/process"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/procsnapshot"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
@@ -64,6 +65,10 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 		return
 	}
 
+	if g.config.TCPInfo.DataID > 0 {
+		g.sendTCPMetrics(now, procs, pids, e)
+	}
+
 	total := len(sockets)
 	if total <= 0 {
 		return
@@ -83,3 +88,18 @@ func (g *Gather) Run(ctx context.Context, e chan<- define.Event) {
 		start = end
 	}
 }
+
+// sendTCPMetrics 采集连接质量指标，采集失败不影响网络快照上报
+func (g *Gather) sendTCPMetrics(now time.Time, procs []procsnapshot.ProcMeta, pids []int32, e chan<- define.Event) {
+	conns, err := process.GetTCPConns(pids)
+	if err != nil {
+		logger.Errorf("failed to get tcp conns: %v", err)
+		return
+	}
+
+	stats := AggregateTCPConns(conns, procNames(procs), g.config.TCPInfo.TopN)
+	if len(stats) == 0 {
+		return
+	}
+	e <- &TCPMetricEvent{dataid: g.config.TCPInfo.DataID, stats: stats, utcTime: now}
+}
@@ -0,0 +1,194 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package socketsnapshot
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/processbeat/process"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/procsnapshot"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse/output/gse"
+)
+
+// TCPStat 同一进程访问同一对端地址的连接质量聚合结果
+type TCPStat struct {
+	Pid      int32
+	Name     string
+	Protocol string
+	Daddr    string
+	Dport    uint32
+
+	Count        int
+	RttSum       uint64
+	RttMax       uint32
+	RttVarSum    uint64
+	Retransmits  uint64
+	TotalRetrans uint64
+	Lost         uint64
+	SndCwndSum   uint64
+	RecvQueue    uint64
+	SendQueue    uint64
+}
+
+func (s *TCPStat) add(info process.TCPInfo) {
+	s.Count++
+	s.RttSum += uint64(info.Rtt)
+	if info.Rtt > s.RttMax {
+		s.RttMax = info.Rtt
+	}
+	s.RttVarSum += uint64(info.RttVar)
+	s.Retransmits += uint64(info.Retransmits)
+	s.TotalRetrans += uint64(info.TotalRetrans)
+	s.Lost += uint64(info.Lost)
+	s.SndCwndSum += uint64(info.SndCwnd)
+	s.RecvQueue += uint64(info.RecvQueue)
+	s.SendQueue += uint64(info.SendQueue)
+}
+
+// Metrics RTT 由 us 转换为 ms，队列长度为所有连接之和
+func (s *TCPStat) Metrics() map[string]float64 {
+	count := float64(s.Count)
+	return map[string]float64{
+		"tcp_conn_count":       count,
+		"tcp_rtt_avg_ms":       float64(s.RttSum) / count / 1000,
+		"tcp_rtt_max_ms":       float64(s.RttMax) / 1000,
+		"tcp_rttvar_avg_ms":    float64(s.RttVarSum) / count / 1000,
+		"tcp_retransmits":      float64(s.Retransmits),
+		"tcp_retrans_total":    float64(s.TotalRetrans),
+		"tcp_lost_total":       float64(s.Lost),
+		"tcp_snd_cwnd_avg":     float64(s.SndCwndSum) / count,
+		"tcp_recv_queue_bytes": float64(s.RecvQueue),
+		"tcp_send_queue_bytes": float64(s.SendQueue),
+	}
+}
+
+func (s *TCPStat) Dimensions() map[string]string {
+	return map[string]string{
+		"pid":          strconv.Itoa(int(s.Pid)),
+		"process_name": s.Name,
+		"protocol":     s.Protocol,
+		"remote_ip":    s.Daddr,
+		"remote_port":  strconv.Itoa(int(s.Dport)),
+	}
+}
+
+type tcpStatKey struct {
+	pid   int32
+	daddr string
+	dport uint32
+}
+
+// AggregateTCPConns 按进程以及对端地址聚合连接，按重传数以及最大 RTT 倒序保留前 topN 个
+func AggregateTCPConns(conns []process.TCPConn, names map[int32]string, topN int) []*TCPStat {
+	stats := make(map[tcpStatKey]*TCPStat)
+	for _, conn := range conns {
+		key := tcpStatKey{pid: conn.Pid, daddr: conn.Daddr, dport: conn.Dport}
+		stat, ok := stats[key]
+		if !ok {
+			stat = &TCPStat{
+				Pid:      conn.Pid,
+				Name:     names[conn.Pid],
+				Protocol: conn.Protocol,
+				Daddr:    conn.Daddr,
+				Dport:    conn.Dport,
+			}
+			stats[key] = stat
+		}
+		stat.add(conn.Info)
+	}
+
+	ret := make([]*TCPStat, 0, len(stats))
+	for _, stat := range stats {
+		ret = append(ret, stat)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].TotalRetrans != ret[j].TotalRetrans {
+			return ret[i].TotalRetrans > ret[j].TotalRetrans
+		}
+		if ret[i].RttMax != ret[j].RttMax {
+			return ret[i].RttMax > ret[j].RttMax
+		}
+		// 保证结果稳定
+		if ret[i].Pid != ret[j].Pid {
+			return ret[i].Pid < ret[j].Pid
+		}
+		if ret[i].Daddr != ret[j].Daddr {
+			return ret[i].Daddr < ret[j].Daddr
+		}
+		return ret[i].Dport < ret[j].Dport
+	})
+
+	if topN > 0 && len(ret) > topN {
+		ret = ret[:topN]
+	}
+	return ret
+}
+
+// TCPMetricEvent 连接质量指标，以自定义指标格式上报
+type TCPMetricEvent struct {
+	dataid  int32
+	stats   []*TCPStat
+	utcTime time.Time
+}
+
+func (e *TCPMetricEvent) AsMapStr() common.MapStr {
+	info, _ := gse.GetAgentInfo()
+	hostDims := map[string]string{
+		"bk_target_ip":       info.IP,
+		"bk_target_cloud_id": strconv.Itoa(int(info.Cloudid)),
+		"bk_agent_id":        info.BKAgentID,
+		"bk_host_id":         strconv.Itoa(int(info.HostID)),
+		"bk_biz_id":          strconv.Itoa(int(info.BKBizID)),
+	}
+
+	ts := e.utcTime.UnixMilli()
+	data := make([]common.MapStr, 0, len(e.stats))
+	for _, stat := range e.stats {
+		dims := stat.Dimensions()
+		for k, v := range hostDims {
+			dims[k] = v
+		}
+		data = append(data, common.MapStr{
+			"metrics":   stat.Metrics(),
+			"target":    info.IP,
+			"timestamp": ts,
+			"dimension": dims,
+		})
+	}
+
+	return common.MapStr{
+		"dataid":    e.dataid,
+		"data":      data,
+		"time":      e.utcTime.Unix(),
+		"timestamp": e.utcTime.Unix(),
+	}
+}
+
+func (e *TCPMetricEvent) IgnoreCMDBLevel() bool {
+	return true
+}
+
+func (e *TCPMetricEvent) GetType() string {
+	return define.ModuleSocketSnapshot
+}
+
+// procNames pid -> 进程名
+func procNames(procs []procsnapshot.ProcMeta) map[int32]string {
+	names := make(map[int32]string, len(procs))
+	for _, proc := range procs {
+		names[proc.Pid] = proc.Name
+	}
+	return names
+}
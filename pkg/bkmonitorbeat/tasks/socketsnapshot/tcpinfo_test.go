@@ -0,0 +1,94 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package socketsnapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/processbeat/process"
+)
+
+func newTCPConn(pid int32, daddr string, dport uint32, info process.TCPInfo) process.TCPConn {
+	return process.TCPConn{
+		FileSocket: process.FileSocket{
+			Pid:      pid,
+			Protocol: process.ProtocolTCP,
+			Daddr:    daddr,
+			Dport:    dport,
+		},
+		Info: info,
+	}
+}
+
+func TestAggregateTCPConns(t *testing.T) {
+	conns := []process.TCPConn{
+		newTCPConn(1, "10.0.0.1", 3306, process.TCPInfo{Rtt: 1000, RttVar: 200, TotalRetrans: 1, SndCwnd: 10, SendQueue: 100}),
+		newTCPConn(1, "10.0.0.1", 3306, process.TCPInfo{Rtt: 3000, RttVar: 400, TotalRetrans: 2, Lost: 1, SndCwnd: 20, RecvQueue: 50}),
+		newTCPConn(1, "10.0.0.2", 6379, process.TCPInfo{Rtt: 9000}),
+		newTCPConn(2, "10.0.0.1", 3306, process.TCPInfo{Rtt: 500, TotalRetrans: 10}),
+	}
+	names := map[int32]string{1: "app", 2: "worker"}
+
+	stats := AggregateTCPConns(conns, names, 0)
+	assert.Len(t, stats, 3)
+
+	// 按重传数倒序
+	assert.Equal(t, int32(2), stats[0].Pid)
+	assert.Equal(t, "worker", stats[0].Name)
+
+	stat := stats[1]
+	assert.Equal(t, map[string]string{
+		"pid":          "1",
+		"process_name": "app",
+		"protocol":     process.ProtocolTCP,
+		"remote_ip":    "10.0.0.1",
+		"remote_port":  "3306",
+	}, stat.Dimensions())
+	assert.Equal(t, map[string]float64{
+		"tcp_conn_count":       2,
+		"tcp_rtt_avg_ms":       2,
+		"tcp_rtt_max_ms":       3,
+		"tcp_rttvar_avg_ms":    0.3,
+		"tcp_retransmits":      0,
+		"tcp_retrans_total":    3,
+		"tcp_lost_total":       1,
+		"tcp_snd_cwnd_avg":     15,
+		"tcp_recv_queue_bytes": 50,
+		"tcp_send_queue_bytes": 100,
+	}, stat.Metrics())
+
+	// 重传数相同时按最大 RTT 倒序
+	assert.Equal(t, "10.0.0.2", stats[2].Daddr)
+
+	stats = AggregateTCPConns(conns, names, 1)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, int32(2), stats[0].Pid)
+}
+
+func TestTCPMetricEvent(t *testing.T) {
+	now := time.Now()
+	stats := AggregateTCPConns([]process.TCPConn{
+		newTCPConn(1, "10.0.0.1", 3306, process.TCPInfo{Rtt: 1000}),
+	}, nil, 10)
+
+	event := &TCPMetricEvent{dataid: 1001, stats: stats, utcTime: now}
+	m := event.AsMapStr()
+	assert.Equal(t, int32(1001), m["dataid"])
+
+	data := m["data"].([]common.MapStr)
+	assert.Len(t, data, 1)
+	assert.Equal(t, now.UnixMilli(), data[0]["timestamp"])
+	assert.Equal(t, 1.0, data[0]["metrics"].(map[string]float64)["tcp_rtt_avg_ms"])
+	assert.Equal(t, "3306", data[0]["dimension"].(map[string]string)["remote_port"])
+}
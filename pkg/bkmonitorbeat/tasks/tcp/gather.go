@@ -11,6 +11,7 @@ package tcp
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net"
 	"strconv"
@@ -37,6 +38,37 @@ var NewConn = func(ctx context.Context, taskConf *configs.TCPTaskConfig, addr st
 	return dialer.DialContext(ctx, "tcp", addr)
 }
 
+// handshake 进行 TLS 握手并记录证书信息，握手时不校验证书，保证证书过期等情况下依然可以获取到证书信息
+func handshake(ctx context.Context, taskConf *configs.TCPTaskConfig, conn net.Conn, event *tasks.SimpleEvent) (*tls.Conn, define.NamedCode) {
+	serverName := taskConf.ServerName
+	if serverName == "" {
+		serverName = event.TargetHost
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, taskConf.Timeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		logger.Debugf("%v: tls handshake with %v fail: %v", taskConf.TaskID, serverName, err)
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return nil, define.CodeConnTimeout
+		}
+		return nil, define.CodeTLSHandshakeFailed
+	}
+
+	state := tlsConn.ConnectionState()
+	event.TLS = tasks.NewTLSInfo(&state, serverName, nil)
+	if !taskConf.InsecureSkipVerify && !event.TLS.ChainValid {
+		logger.Debugf("%v: certificate of %v is invalid: %v", taskConf.TaskID, serverName, event.TLS.ChainError)
+		return tlsConn, define.CodeCertInvalid
+	}
+	return tlsConn, define.CodeOK
+}
+
 // Gather :
 type Gather struct {
 	tasks.BaseTask
@@ -72,6 +104,16 @@ func (g *Gather) checkTargetHost(ctx context.Context, taskConf *configs.TCPTaskC
 	}()
 
 	logger.Debugf("%v: connect %v success", taskConf.TaskID, address)
+	// TLS 握手，之后的请求以及响应都通过 TLS 连接收发
+	if taskConf.TLS {
+		tlsConn, code := handshake(ctx, taskConf, conn, event)
+		if tlsConn != nil {
+			conn = tlsConn
+		}
+		if code != define.CodeOK {
+			return code
+		}
+	}
 	// 无需检查情况直接返回成功
 	if noNeedMatch(taskConf) {
 		logger.Debugf("%v: return without match", taskConf.TaskID)
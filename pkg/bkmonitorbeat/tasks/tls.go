@@ -0,0 +1,209 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tasks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSP 装订状态
+const (
+	OCSPStatusNone    = "none"
+	OCSPStatusGood    = "good"
+	OCSPStatusRevoked = "revoked"
+	OCSPStatusUnknown = "unknown"
+	OCSPStatusInvalid = "invalid"
+)
+
+// TLSInfo TLS 握手以及对端证书信息
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+
+	Subject   string
+	Issuer    string
+	SANs      []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	// ExpireDays 叶子证书剩余有效天数，已过期时为负数
+	ExpireDays int
+	// ChainExpireDays 证书链中最早过期的证书的剩余有效天数
+	ChainExpireDays int
+	// ChainValid 证书链是否可以通过系统根证书校验（包含域名校验）
+	ChainValid bool
+	ChainError string
+
+	OCSPStapled bool
+	OCSPStatus  string
+}
+
+func expireDays(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// NewTLSInfo 根据握手结果生成 TLS 信息，serverName 用于证书域名校验，roots 为空时使用系统根证书
+func NewTLSInfo(state *tls.ConnectionState, serverName string, roots *x509.CertPool) *TLSInfo {
+	if state == nil {
+		return nil
+	}
+
+	now := time.Now()
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		OCSPStatus:  OCSPStatusNone,
+	}
+
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		info.ChainError = "no peer certificate"
+		return info
+	}
+
+	leaf := certs[0]
+	info.Subject = leaf.Subject.String()
+	info.Issuer = leaf.Issuer.String()
+	info.NotBefore = leaf.NotBefore
+	info.NotAfter = leaf.NotAfter
+	info.ExpireDays = expireDays(leaf.NotAfter, now)
+	info.SANs = append(info.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	info.ChainExpireDays = info.ExpireDays
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+		if days := expireDays(cert.NotAfter, now); days < info.ChainExpireDays {
+			info.ChainExpireDays = days
+		}
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		info.ChainError = err.Error()
+	} else {
+		info.ChainValid = true
+	}
+
+	if len(state.OCSPResponse) > 0 {
+		info.OCSPStapled = true
+		info.OCSPStatus = ocspStatus(state.OCSPResponse, certs)
+	}
+	return info
+}
+
+// ocspStatus 解析装订的 OCSP 响应，存在签发者证书时校验签名
+func ocspStatus(raw []byte, certs []*x509.Certificate) string {
+	var issuer *x509.Certificate
+	if len(certs) > 1 {
+		issuer = certs[1]
+	}
+
+	resp, err := ocsp.ParseResponse(raw, issuer)
+	if err != nil {
+		return OCSPStatusInvalid
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return OCSPStatusGood
+	case ocsp.Revoked:
+		return OCSPStatusRevoked
+	default:
+		return OCSPStatusUnknown
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Dimensions 自定义上报的维度，只保留证书有效性相关的取值有限的字段，证书主体以及 SAN 等只在事件内容中上报
+func (i *TLSInfo) Dimensions() map[string]string {
+	return map[string]string{
+		"ocsp_status": i.OCSPStatus,
+	}
+}
+
+// Metrics 自定义上报的指标
+func (i *TLSInfo) Metrics() map[string]interface{} {
+	return map[string]interface{}{
+		"cert_expire_days":       i.ExpireDays,
+		"cert_chain_expire_days": i.ChainExpireDays,
+		"cert_chain_valid":       boolToInt(i.ChainValid),
+		"ocsp_stapled":           boolToInt(i.OCSPStapled),
+	}
+}
+
+// AsMapStr 拨测事件中的字段
+func (i *TLSInfo) AsMapStr() common.MapStr {
+	mapStr := common.MapStr{
+		"tls_version":      i.Version,
+		"tls_cipher":       i.CipherSuite,
+		"cert_subject":     i.Subject,
+		"cert_issuer":      i.Issuer,
+		"cert_sans":        strings.Join(i.SANs, ","),
+		"cert_not_before":  i.NotBefore.Unix(),
+		"cert_not_after":   i.NotAfter.Unix(),
+		"cert_chain_error": i.ChainError,
+	}
+	for k, v := range i.Dimensions() {
+		mapStr[k] = v
+	}
+	for k, v := range i.Metrics() {
+		mapStr[k] = v
+	}
+	return mapStr
+}
+
+// CertInvalidError 握手时证书校验失败，携带对端的证书信息
+type CertInvalidError struct {
+	Info *TLSInfo
+}
+
+func (e *CertInvalidError) Error() string {
+	return "certificate is invalid: " + e.Info.ChainError
+}
+
+// VerifyConnection 用于 tls.Config.VerifyConnection，需要同时设置 InsecureSkipVerify 跳过默认校验
+// 握手时记录证书信息，skipVerify 为 false 并且证书校验失败时中断握手，返回 CertInvalidError
+// serverName 为空时使用握手的 ServerName，roots 为空时使用系统根证书，record 可以为空
+func VerifyConnection(serverName string, roots *x509.CertPool, skipVerify bool, record func(*TLSInfo)) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		name := serverName
+		if name == "" {
+			name = state.ServerName
+		}
+		info := NewTLSInfo(&state, name, roots)
+		if record != nil {
+			record(info)
+		}
+		if !skipVerify && !info.ChainValid {
+			return &CertInvalidError{Info: info}
+		}
+		return nil
+	}
+}
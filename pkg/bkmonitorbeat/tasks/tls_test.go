@@ -0,0 +1,90 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tasks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSInfo(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	assert.Nil(t, err)
+	defer conn.Close()
+	state := conn.ConnectionState()
+
+	cert := server.Certificate()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	testCases := map[string]struct {
+		serverName string
+		roots      *x509.CertPool
+		valid      bool
+	}{
+		"证书链以及域名校验通过": {
+			serverName: "example.com",
+			roots:      roots,
+			valid:      true,
+		},
+		"域名不匹配": {
+			serverName: "bk.tencent.com",
+			roots:      roots,
+		},
+		"系统根证书校验失败": {
+			serverName: "example.com",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			info := NewTLSInfo(&state, c.serverName, c.roots)
+
+			assert.Equal(t, tls.VersionName(state.Version), info.Version)
+			assert.Equal(t, tls.CipherSuiteName(state.CipherSuite), info.CipherSuite)
+			assert.Equal(t, cert.Issuer.String(), info.Issuer)
+			assert.Contains(t, info.SANs, "example.com")
+			assert.Contains(t, info.SANs, "127.0.0.1")
+			assert.Equal(t, cert.NotAfter, info.NotAfter)
+			assert.Equal(t, int(math.Floor(time.Until(cert.NotAfter).Hours()/24)), info.ExpireDays)
+			assert.Equal(t, info.ExpireDays, info.ChainExpireDays)
+			assert.False(t, info.OCSPStapled)
+			assert.Equal(t, OCSPStatusNone, info.OCSPStatus)
+
+			assert.Equal(t, c.valid, info.ChainValid)
+			assert.Equal(t, c.valid, info.ChainError == "")
+
+			mapStr := info.AsMapStr()
+			assert.Equal(t, info.ExpireDays, mapStr["cert_expire_days"])
+			assert.Equal(t, boolToInt(c.valid), mapStr["cert_chain_valid"])
+			assert.Equal(t, info.Subject, mapStr["cert_subject"])
+			// 证书主体以及 SAN 不作为维度
+			assert.Equal(t, map[string]string{"ocsp_status": OCSPStatusNone}, info.Dimensions())
+
+			var recorded *TLSInfo
+			err := VerifyConnection(c.serverName, c.roots, false, func(i *TLSInfo) { recorded = i })(state)
+			assert.Equal(t, c.valid, err == nil)
+			assert.Equal(t, c.valid, recorded.ChainValid)
+			assert.Nil(t, VerifyConnection(c.serverName, c.roots, true, nil)(state))
+		})
+	}
+
+	assert.Nil(t, NewTLSInfo(nil, "", nil))
+}
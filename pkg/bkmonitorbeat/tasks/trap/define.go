@@ -10,7 +10,9 @@
 package trap
 
 import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/snmp"
 )
 
 const (
@@ -53,5 +55,6 @@ func New(globalConfig define.Config, taskConfig define.TaskConfig) define.Task {
 	gather.TaskConfig = taskConfig
 
 	gather.Init()
+	gather.mibs = snmp.GetMIBStore(taskConfig.(*configs.TrapConfig).MibDirs)
 	return gather
 }
@@ -10,11 +10,9 @@
 package trap
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net"
 	"sort"
 	"strconv"
@@ -27,6 +25,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/snmp"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
 )
 
@@ -36,6 +35,7 @@ type Gather struct {
 	udpLostCount int64
 	output       chan<- *Event
 	communityMap map[string]bool
+	mibs         *snmp.MIBStore
 }
 
 type Event struct {
@@ -91,6 +91,21 @@ func (e *Event) toMapStr() common.MapStr {
 }
 
 func matchOid(oid string, oidMap map[string]string) string {
+	translated, _ := matchOidPrefix(oid, oidMap)
+	return translated
+}
+
+// translateOid 用户配置的 oids 与 MIB 按照最长前缀匹配，匹配层级相同时 oids 优先
+func translateOid(oid string, oidMap map[string]string, mib *snmp.MIB) string {
+	translated, depth := matchOidPrefix(oid, oidMap)
+	if name, mibDepth := mib.Translate(oid); mibDepth > depth {
+		return name
+	}
+	return translated
+}
+
+// matchOidPrefix 按照 oid 字典翻译，同时返回匹配的层级数，未匹配时为 0
+func matchOidPrefix(oid string, oidMap map[string]string) (string, int) {
 	var (
 		prefix  string
 		ok      bool
@@ -106,11 +121,11 @@ func matchOid(oid string, oidMap map[string]string) string {
 		}
 		// 存在oid完整匹配的场景，此时不应该在最后加.
 		if i == len(subOids) {
-			return val
+			return val, i
 		}
-		return val + "." + strings.Join(subOids[i:], ".")
+		return val + "." + strings.Join(subOids[i:], "."), i
 	}
-	return oid
+	return oid, 0
 }
 
 func snmpVersionToStr(version gosnmp.SnmpVersion) string {
@@ -185,14 +200,14 @@ func (g *Gather) getInternalDimensions(
 	return internalDimensions
 }
 
-func getTrapOidAndDisplayName(packet *gosnmp.SnmpPacket, oids map[string]string) (string, string) {
+func getTrapOidAndDisplayName(packet *gosnmp.SnmpPacket, oids map[string]string, mib *snmp.MIB) (string, string) {
 	var trapOid string
 	var displayName string
 
 	if packet.Version == gosnmp.Version1 {
 		trapOid, displayName = getV1TrapOID(packet.GenericTrap, packet.SpecificTrap, packet.Enterprise)
 		if displayName == "" {
-			displayName = matchOid(trapOid, oids)
+			displayName = translateOid(trapOid, oids, mib)
 		}
 	}
 	return trapOid, displayName
@@ -206,7 +221,7 @@ func getValueByEncoding(b []byte, encoding string) (string, error) {
 	return string(result), nil
 }
 
-func updateDimension(conf *configs.TrapConfig, v gosnmp.SnmpPDU, value string, dimension map[string]string) {
+func updateDimension(conf *configs.TrapConfig, mib *snmp.MIB, v gosnmp.SnmpPDU, value string, dimension map[string]string) {
 	for _, reportOID := range conf.ReportOIDDimensions {
 		isIndexOID := strings.HasSuffix(reportOID, ".index")
 		realReportOID := reportOID
@@ -229,7 +244,7 @@ func updateDimension(conf *configs.TrapConfig, v gosnmp.SnmpPDU, value string, d
 						var name string
 						// 启用开关，则维度进行翻译,否则使用原始oid上报到维度里
 						if conf.UseDisplayNameOID {
-							name = strings.Replace(matchOid(oidPrefix, conf.OIDS), ".", "_", -1)
+							name = strings.Replace(translateOid(oidPrefix, conf.OIDS, mib), ".", "_", -1)
 						} else {
 							name = strings.Replace(strings.Trim(oidPrefix, "."), ".", "_", -1)
 						}
@@ -245,7 +260,7 @@ func updateDimension(conf *configs.TrapConfig, v gosnmp.SnmpPDU, value string, d
 				var name string
 				// 启用开关，则维度进行翻译,否则使用原始oid上报到维度里
 				if conf.UseDisplayNameOID {
-					name = strings.Replace(matchOid(v.Name, conf.OIDS), ".", "_", -1)
+					name = strings.Replace(translateOid(v.Name, conf.OIDS, mib), ".", "_", -1)
 				} else {
 					name = strings.Replace(strings.Trim(v.Name, "."), ".", "_", -1)
 				}
@@ -263,37 +278,44 @@ func (g *Gather) getEvent(conf *configs.TrapConfig, packet *gosnmp.SnmpPacket, a
 	for _, rawByteOID := range conf.RawByteOIDs {
 		rawByteOIDMap[rawByteOID] = true
 	}
-	trapOid, displayName := getTrapOidAndDisplayName(packet, conf.OIDS)
+	mib := g.mibs.MIB()
+	trapOid, displayName := getTrapOidAndDisplayName(packet, conf.OIDS, mib)
 
 	for _, v := range packet.Variables {
 		var value string
-		switch v.Type {
-		case gosnmp.OctetString:
-			b := v.Value.([]byte)
-			s, err := getValueByEncoding(b, conf.Encode)
-			if err != nil {
-				logger.Errorf("decode value failed,error:%s", err)
+		// 优先按照 MIB 中的枚举以及 DISPLAY-HINT 格式化
+		node, _ := mib.Lookup(v.Name)
+		if formatted, ok := node.FormatValue(v); ok {
+			value = formatted
+		} else {
+			switch v.Type {
+			case gosnmp.OctetString:
+				b := v.Value.([]byte)
+				s, err := getValueByEncoding(b, conf.Encode)
+				if err != nil {
+					logger.Errorf("decode value failed,error:%s", err)
+					continue
+				}
+				value = s
+
+			case gosnmp.ObjectIdentifier:
+				b := v.Value.(string)
+				trapOid = b
+				displayName = translateOid(b, conf.OIDS, mib)
 				continue
+			default:
+				value = fmt.Sprintf("%v", v.Value)
 			}
-			value = s
-
-		case gosnmp.ObjectIdentifier:
-			b := v.Value.(string)
-			trapOid = b
-			displayName = matchOid(b, conf.OIDS)
-			continue
-		default:
-			value = fmt.Sprintf("%v", v.Value)
 		}
 
 		// 如果是不需要翻译的，直接打印内容即可
 		if _, ok := rawByteOIDMap[v.Name]; ok {
 			value = fmt.Sprintf("%v", v.Value)
 		}
-		contentMap[fmt.Sprintf("%s(%s)", matchOid(v.Name, conf.OIDS), v.Name)] = value
+		contentMap[fmt.Sprintf("%s(%s)", translateOid(v.Name, conf.OIDS, mib), v.Name)] = value
 
 		// 如果指定了oid，则将对应oid加入维度里
-		updateDimension(conf, v, value, dimension)
+		updateDimension(conf, mib, v, value, dimension)
 	}
 
 	internalDimensions := g.getInternalDimensions(packet, conf, addr, trapOid, displayName)
@@ -353,122 +375,14 @@ func (g *Gather) TrapHandler(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
 	g.output <- event
 }
 
-func (g *Gather) getMsgFlags(flag string) gosnmp.SnmpV3MsgFlags {
-	switch strings.ToLower(flag) {
-	case "authnopriv":
-		return gosnmp.AuthNoPriv
-	case "authpriv":
-		return gosnmp.AuthPriv
-	case "reportable":
-		return gosnmp.Reportable
-	default:
-		return gosnmp.NoAuthNoPriv
-	}
-}
-
-func (g *Gather) parseEnginID(usmConf configs.USMConfig) ([]byte, error) {
-	id := usmConf.AuthoritativeEngineID
-	reader := bytes.NewReader([]byte(id))
-	resultID := make([]byte, 0)
-	buf := make([]byte, 2)
-	for {
-		num, err := reader.Read(buf)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		if num != 2 {
-			return nil, fmt.Errorf("wrong length of enging ID")
-		}
-		i, err := strconv.ParseInt(string(buf), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		resultID = append(resultID, byte(i))
-	}
-	return resultID, nil
-}
-
-func (g *Gather) getAuthenticationProtocol(usmConf configs.USMConfig) gosnmp.SnmpV3AuthProtocol {
-	auth := usmConf.AuthenticationProtocol
-	switch strings.ToLower(auth) {
-	case "md5":
-		return gosnmp.MD5
-	case "sha":
-		return gosnmp.SHA
-	case "sha224":
-		return gosnmp.SHA224
-	case "sha256":
-		return gosnmp.SHA256
-	case "sha384":
-		return gosnmp.SHA384
-	case "sha512":
-		return gosnmp.SHA512
-	default:
-		return gosnmp.NoAuth
-	}
-}
-
-func (g *Gather) getPrivacyProtocol(usmConf configs.USMConfig) gosnmp.SnmpV3PrivProtocol {
-	privacy := usmConf.PrivacyProtocol
-	switch strings.ToLower(privacy) {
-	case "des":
-		return gosnmp.DES
-	case "aes":
-		return gosnmp.AES
-	case "aes192":
-		return gosnmp.AES192
-	case "aes192c":
-		return gosnmp.AES192C
-	case "aes256":
-		return gosnmp.AES256
-	case "aes256c":
-		return gosnmp.AES256C
-	default:
-		return gosnmp.NoPriv
-	}
-}
-
-func (g *Gather) getUSMConfig(usmConf configs.USMConfig) (*gosnmp.UsmSecurityParameters, error) {
-	engineID, err := g.parseEnginID(usmConf)
-	if err != nil {
-		return nil, err
-	}
-	if usmConf.AuthoritativeEngineBoots == 0 {
-		usmConf.AuthoritativeEngineBoots = 1
-	}
-	if usmConf.AuthoritativeEngineTime == 0 {
-		usmConf.AuthoritativeEngineTime = 1
-	}
-	sp := &gosnmp.UsmSecurityParameters{
-		UserName:                 usmConf.UserName,
-		AuthenticationProtocol:   g.getAuthenticationProtocol(usmConf),
-		AuthenticationPassphrase: usmConf.AuthenticationPassphrase,
-		PrivacyProtocol:          g.getPrivacyProtocol(usmConf),
-		PrivacyPassphrase:        usmConf.PrivacyPassphrase,
-		AuthoritativeEngineBoots: usmConf.AuthoritativeEngineBoots,
-		AuthoritativeEngineTime:  usmConf.AuthoritativeEngineTime,
-		AuthoritativeEngineID:    string(engineID),
-	}
-	return sp, nil
-}
-
 func (g *Gather) getSnmpVersion() gosnmp.SnmpVersion {
 	conf := g.TaskConfig.(*configs.TrapConfig)
-	version := conf.Version
-	switch strings.ToLower(version) {
-	case "v1", "1":
-		return gosnmp.Version1
-	case "v2", "v2c", "2", "2c":
-		return gosnmp.Version2c
-	case "v3", "3":
-		return gosnmp.Version3
-	default:
-		logger.Errorf("error snmp version: %s", version)
+	version := snmp.ParseVersion(conf.Version)
+	if version == snmp.UnknownVersion {
+		logger.Errorf("error snmp version: %s", conf.Version)
 		return unKownTrapVersion
 	}
+	return version
 }
 
 func (g *Gather) initTrapListener() (*gosnmp.TrapListener, error) {
@@ -507,8 +421,8 @@ func (g *Gather) initTrapListener() (*gosnmp.TrapListener, error) {
 		tl.Params.SecurityModel = gosnmp.UserSecurityModel
 		for _, usmInfo := range conf.UsmInfos {
 
-			msgFlags := g.getMsgFlags(usmInfo.MsgFlags)
-			sp, err := g.getUSMConfig(usmInfo.USMConfig)
+			msgFlags := snmp.ParseMsgFlags(usmInfo.MsgFlags)
+			sp, err := snmp.NewUsmSecurityParameters(usmInfo.USMConfig)
 			if err != nil {
 				logger.Errorf("get usm config failed,error:%s", err)
 				return nil, err
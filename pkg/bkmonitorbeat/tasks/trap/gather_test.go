@@ -13,6 +13,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
@@ -25,6 +28,7 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/configs"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/tasks/snmp"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/bkmonitorbeat/test"
 )
 
@@ -416,3 +420,65 @@ func TestMatchOid(t *testing.T) {
 		})
 	}
 }
+
+const trapTestMIB = `
+BK-TRAP-MIB DEFINITIONS ::= BEGIN
+
+bkTrapMIB OBJECT IDENTIFIER ::= { enterprises 99999 }
+
+bkLinkStatus OBJECT-TYPE
+    SYNTAX INTEGER { up(1), down(2) }
+    MAX-ACCESS read-only
+    STATUS current
+    DESCRIPTION "Link status."
+    ::= { bkTrapMIB 1 }
+
+bkLinkDown NOTIFICATION-TYPE
+    OBJECTS { bkLinkStatus }
+    STATUS current
+    DESCRIPTION "Link down."
+    ::= { bkTrapMIB 2 }
+
+END
+`
+
+func TestTranslateOid(t *testing.T) {
+	mib, err := snmp.ParseMIB(trapTestMIB)
+	assert.NoError(t, err)
+
+	oids := map[string]string{
+		"1.3.6.1.4.1":         "enterprises",
+		"1.3.6.1.4.1.99999.2": "customLinkDown",
+	}
+	// MIB 匹配的层级更深时使用 MIB 的翻译
+	assert.Equal(t, "bkLinkStatus.3", translateOid(".1.3.6.1.4.1.99999.1.3", oids, mib))
+	// 层级相同时用户配置优先
+	assert.Equal(t, "customLinkDown", translateOid(".1.3.6.1.4.1.99999.2", oids, mib))
+	// 没有 MIB 时与 oids 翻译一致
+	assert.Equal(t, "enterprises.99999.1.3", translateOid(".1.3.6.1.4.1.99999.1.3", oids, nil))
+	assert.Equal(t, ".1.2.3", translateOid(".1.2.3", oids, mib))
+}
+
+func TestGetEventWithMIB(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "BK-TRAP-MIB"), []byte(trapTestMIB), 0o644))
+
+	taskConf := configs.NewTrapConfig()
+	taskConf.MibDirs = []string{dir}
+	taskConf.UseDisplayNameOID = true
+	taskConf.ReportOIDDimensions = []string{".1.3.6.1.4.1.99999.1"}
+	g := newGather(taskConf)
+
+	packet := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		Variables: []gosnmp.SnmpPDU{
+			{Name: "." + snmptrapOIDKey, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.99999.2"},
+			{Name: ".1.3.6.1.4.1.99999.1.3", Type: gosnmp.Integer, Value: 2},
+		},
+	}
+	event := g.getEvent(taskConf, packet, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1162})
+	assert.Equal(t, "bkLinkDown", event.dimension[EventDisplayNameKey])
+	assert.Equal(t, "down", event.dimension["bkLinkStatus_3"])
+	assert.Equal(t, "down", event.content["bkLinkStatus.3(.1.3.6.1.4.1.99999.1.3)"])
+}
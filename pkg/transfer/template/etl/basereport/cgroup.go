@@ -0,0 +1,141 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package basereport
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	template "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+)
+
+// PressureFields PSI 指标，指标名为 <资源>_<some|full>_<avg10|avg60|avg300|total>
+func PressureFields(path string) []etl.Field {
+	fields := make([]etl.Field, 0, 24)
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		for _, kind := range []string{"some", "full"} {
+			for _, value := range []string{"avg10", "avg60", "avg300", "total"} {
+				fields = append(fields, etl.NewSimpleField(
+					fmt.Sprintf("%s_%s_%s", resource, kind, value),
+					etl.ExtractByJMESPath(fmt.Sprintf("%s.%s.%s.%s", path, resource, kind, value)), etl.TransformNilFloat64,
+				))
+			}
+		}
+	}
+	return fields
+}
+
+// NewCgroupProcessor 容器 cgroup 资源使用，每个 cgroup 一条记录
+func NewCgroupProcessor(ctx context.Context, name string) *template.RecordProcessor {
+	return template.NewRecordProcessorWithDecoderFnWithContext(ctx, name, config.PipelineConfigFromContext(ctx), etl.NewTSSchemaRecord(name).AddDimensions(
+		BaseDimensionBaseReportFieldsValue()...).AddDimensions(
+		etl.NewSimpleField(
+			"cgroup_path",
+			etl.ExtractByJMESPath("item.path"), etl.TransformNilString,
+		),
+		etl.NewSimpleField(
+			"container_id",
+			etl.ExtractByJMESPath("item.container_id"), etl.TransformNilString,
+		),
+		etl.NewSimpleField(
+			"runtime",
+			etl.ExtractByJMESPath("item.runtime"), etl.TransformNilString,
+		),
+		etl.NewSimpleField(
+			"pod_uid",
+			etl.ExtractByJMESPath("item.pod_uid"), etl.TransformNilString,
+		),
+	).AddMetrics(
+		etl.NewSimpleField(
+			"cpu_usage",
+			etl.ExtractByJMESPath("item.cpu.usage"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_limit",
+			etl.ExtractByJMESPath("item.cpu.limit"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_usage_usec",
+			etl.ExtractByJMESPath("item.cpu.usage_usec"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_user_usec",
+			etl.ExtractByJMESPath("item.cpu.user_usec"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_system_usec",
+			etl.ExtractByJMESPath("item.cpu.system_usec"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_nr_periods",
+			etl.ExtractByJMESPath("item.cpu.nr_periods"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_nr_throttled",
+			etl.ExtractByJMESPath("item.cpu.nr_throttled"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"cpu_throttled_usec",
+			etl.ExtractByJMESPath("item.cpu.throttled_usec"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"mem_current",
+			etl.ExtractByJMESPath("item.mem.current"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"mem_limit",
+			etl.ExtractByJMESPath("item.mem.limit"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"mem_oom",
+			etl.ExtractByJMESPath("item.mem.oom"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"mem_oom_kill",
+			etl.ExtractByJMESPath("item.mem.oom_kill"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"io_rbytes",
+			etl.ExtractByJMESPath("item.io.rbytes"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"io_wbytes",
+			etl.ExtractByJMESPath("item.io.wbytes"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"io_rios",
+			etl.ExtractByJMESPath("item.io.rios"), etl.TransformNilFloat64,
+		),
+		etl.NewSimpleField(
+			"io_wios",
+			etl.ExtractByJMESPath("item.io.wios"), etl.TransformNilFloat64,
+		),
+	).AddMetrics(
+		PressureFields("item.pressure")...,
+	).AddTime(etl.NewSimpleField(
+		"time", etl.ExtractByJMESPath("data.utctime"),
+		etl.TransformTimeStampWithUTCLayout("2006-01-02 15:04:05"),
+	)), etl.NewPayloadDecoder().FissionSplitHandler(true, etl.ExtractByJMESPath(`data.cgroup.stats`), "", "item").Decode)
+}
+
+func init() {
+	define.RegisterDataProcessor("system.cgroup", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConf := config.PipelineConfigFromContext(ctx)
+		if pipeConf == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		return NewCgroupProcessor(ctx, pipeConf.FormatName(name)), nil
+	})
+}
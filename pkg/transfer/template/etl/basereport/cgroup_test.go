@@ -0,0 +1,164 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package basereport_test
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl/basereport"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+)
+
+//go:embed fixture/cgroup_test_data.json
+var cgroupData string
+
+func baseReportDimensions() map[string]interface{} {
+	return map[string]interface{}{
+		"ip":                 "127.0.0.1",
+		"bk_target_ip":       "127.0.0.1",
+		"bk_supplier_id":     "0",
+		"bk_cloud_id":        "0",
+		"bk_target_cloud_id": "0",
+		"bk_agent_id":        "010000525400c48bdc1670385834306k",
+		"bk_biz_id":          "2",
+		"bk_host_id":         "30145",
+		"bk_target_host_id":  "30145",
+		"hostname":           "rbtnode1-new",
+		"bk_cmdb_level":      "[{\"a\":1},{\"b\":2}]",
+	}
+}
+
+// pressureMetrics 与测试数据中的 PSI 保持一致
+func pressureMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"cpu_some_avg10": 1.5, "cpu_some_avg60": 1.2, "cpu_some_avg300": 1.0, "cpu_some_total": 1000.0,
+		"cpu_full_avg10": 0.0, "cpu_full_avg60": 0.0, "cpu_full_avg300": 0.0, "cpu_full_total": 0.0,
+		"memory_some_avg10": 0.5, "memory_some_avg60": 0.4, "memory_some_avg300": 0.3, "memory_some_total": 200.0,
+		"memory_full_avg10": 0.2, "memory_full_avg60": 0.1, "memory_full_avg300": 0.1, "memory_full_total": 100.0,
+		"io_some_avg10": 2.5, "io_some_avg60": 2.0, "io_some_avg300": 1.5, "io_some_total": 3000.0,
+		"io_full_avg10": 1.0, "io_full_avg60": 0.8, "io_full_avg300": 0.5, "io_full_total": 1500.0,
+	}
+}
+
+// CgroupTest
+type CgroupTest struct {
+	testsuite.ETLSuite
+}
+
+// TestUsage :
+func (s *CgroupTest) TestUsage() {
+	withPressure := map[string]interface{}{
+		"cpu_usage": 25.5, "cpu_limit": 2.0, "cpu_usage_usec": 5000.0, "cpu_user_usec": 3000.0, "cpu_system_usec": 2000.0,
+		"cpu_nr_periods": 10.0, "cpu_nr_throttled": 2.0, "cpu_throttled_usec": 400.0,
+		"mem_current": 1048576.0, "mem_limit": 2097152.0, "mem_oom": 1.0, "mem_oom_kill": 1.0,
+		"io_rbytes": 4096.0, "io_wbytes": 8192.0, "io_rios": 1.0, "io_wios": 2.0,
+	}
+	for k, v := range pressureMetrics() {
+		withPressure[k] = v
+	}
+
+	expects := map[string]map[string]interface{}{
+		"containerd": {
+			"dimensions": map[string]interface{}{
+				"cgroup_path":  "/kubepods.slice/kubepods-pod1.slice/cri-containerd-aaaa.scope",
+				"container_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"runtime":      "containerd",
+				"pod_uid":      "0b5e5f8c-0f2d-4d3e-9a1c-2f6c3d4e5f6a",
+			},
+			"metrics": withPressure,
+			"time":    1551940933,
+		},
+		"docker": {
+			"dimensions": map[string]interface{}{
+				"cgroup_path":  "/system.slice/docker-bbbb.scope",
+				"container_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+				"runtime":      "docker",
+				"pod_uid":      "",
+			},
+			"metrics": map[string]interface{}{
+				"cpu_usage": 0.0, "cpu_limit": 0.0, "cpu_usage_usec": 100.0, "cpu_user_usec": 60.0, "cpu_system_usec": 40.0,
+				"cpu_nr_periods": 0.0, "cpu_nr_throttled": 0.0, "cpu_throttled_usec": 0.0,
+				"mem_current": 1024.0, "mem_limit": 0.0, "mem_oom": 0.0, "mem_oom_kill": 0.0,
+				"io_rbytes": 0.0, "io_wbytes": 0.0, "io_rios": 0.0, "io_wios": 0.0,
+			},
+			"time": 1551940933,
+		},
+	}
+	// 没有 PSI 信息时 PSI 指标为空
+	for k := range pressureMetrics() {
+		expects["docker"]["metrics"].(map[string]interface{})[k] = nil
+	}
+	for _, expect := range expects {
+		dimensions := expect["dimensions"].(map[string]interface{})
+		for k, v := range baseReportDimensions() {
+			dimensions[k] = v
+		}
+	}
+
+	s.RunN(2,
+		cgroupData,
+		basereport.NewCgroupProcessor(s.CTX, "test"),
+		func(result map[string]interface{}) {
+			runtime := s.GetDimensions(result)["runtime"].(string)
+			s.EqualRecord(result, expects[runtime])
+		},
+	)
+}
+
+// TestDisabled cgroup 采集未开启时没有数据
+func (s *CgroupTest) TestDisabled() {
+	s.RunN(0,
+		loadData,
+		basereport.NewCgroupProcessor(s.CTX, "test"),
+		func(result map[string]interface{}) {},
+	)
+}
+
+// TestCgroupTest :
+func TestCgroupTest(t *testing.T) {
+	suite.Run(t, new(CgroupTest))
+}
+
+// PressureTest
+type PressureTest struct {
+	testsuite.ETLSuite
+}
+
+// TestUsage :
+func (s *PressureTest) TestUsage() {
+	s.Run(
+		cgroupData,
+		basereport.NewPressureProcessor(s.CTX, "test"),
+		func(result map[string]interface{}) {
+			s.EqualRecord(result, map[string]interface{}{
+				"dimensions": baseReportDimensions(),
+				"metrics":    pressureMetrics(),
+				"time":       1551940933,
+			})
+		},
+	)
+}
+
+// TestUnsupported 内核不支持 PSI 时没有数据
+func (s *PressureTest) TestUnsupported() {
+	s.RunN(0,
+		loadData,
+		basereport.NewPressureProcessor(s.CTX, "test"),
+		func(result map[string]interface{}) {},
+	)
+}
+
+// TestPressureTest :
+func TestPressureTest(t *testing.T) {
+	suite.Run(t, new(PressureTest))
+}
@@ -0,0 +1,61 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package basereport
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	template "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+)
+
+// pressureDecoder 内核不支持 PSI 时不上报 pressure，此时直接忽略
+func pressureDecoder() template.Decoder {
+	decode := etl.NewPayloadDecoder().Decode
+	extract := etl.ExtractByJMESPath("data.system.pressure")
+	return func(d define.Payload) ([]etl.Container, error) {
+		containers, err := decode(d)
+		if err != nil {
+			return nil, err
+		}
+		results := containers[:0]
+		for _, container := range containers {
+			if pressure, err := extract(container); err == nil && pressure != nil {
+				results = append(results, container)
+			}
+		}
+		return results, nil
+	}
+}
+
+// NewPressureProcessor 主机级别的 PSI
+func NewPressureProcessor(ctx context.Context, name string) *template.RecordProcessor {
+	return template.NewRecordProcessorWithDecoderFnWithContext(ctx, name, config.PipelineConfigFromContext(ctx), etl.NewTSSchemaRecord(name).AddDimensions(
+		BaseDimensionBaseReportFieldsValue()...).AddMetrics(
+		PressureFields("data.system.pressure")...,
+	).AddTime(etl.NewSimpleField(
+		"time", etl.ExtractByJMESPath("data.utctime"),
+		etl.TransformTimeStampWithUTCLayout("2006-01-02 15:04:05"),
+	)), pressureDecoder())
+}
+
+func init() {
+	define.RegisterDataProcessor("system.pressure", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConf := config.PipelineConfigFromContext(ctx)
+		if pipeConf == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		return NewPressureProcessor(ctx, pipeConf.FormatName(name)), nil
+	})
+}
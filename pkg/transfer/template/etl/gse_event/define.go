@@ -11,11 +11,13 @@ package gse_event
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/cstockton/go-conv"
 	"github.com/mitchellh/mapstructure"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
 )
 
@@ -241,6 +243,79 @@ func (e *OOMEvent) Flat() []EventRecord {
 	}
 }
 
+// kernelHealthEventNames : 主机内核健康事件类型与事件名、事件内容的映射
+var kernelHealthEventNames = map[int][2]string{
+	10: {"InodeFull", "inode_full"},
+	11: {"FdFull", "fd_full"},
+	12: {"ConntrackFull", "conntrack_full"},
+	13: {"ZombieProcess", "zombie_process"},
+	14: {"ClockJump", "clock_jump"},
+	15: {"HungTask", "hung_task"},
+}
+
+// KernelHealthEvent : 主机内核健康事件，维度只保留主机、云区域、事件类型以及进程名，其余字段取值不固定，放到事件内容中
+type KernelHealthEvent struct {
+	Host    string                 `json:"host" mapstructure:"host"`
+	CloudID int                    `json:"cloudid" mapstructure:"cloudid"`
+	Type    int                    `json:"type" mapstructure:"type"`
+	Process string                 `json:"process" mapstructure:"process"`
+	Extra   map[string]interface{} `json:"-" mapstructure:",remain"`
+}
+
+// content 事件内容，格式为 <事件内容> key=value ...，key 按照字典序排列
+func (e *KernelHealthEvent) content(name string) string {
+	keys := make([]string, 0, len(e.Extra))
+	for key := range e.Extra {
+		if key == "bizid" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, key := range keys {
+		value := e.Extra[key]
+		switch value.(type) {
+		case string, float64, bool, int:
+			b.WriteString(fmt.Sprintf(" %s=%s", key, conv.String(value)))
+		default:
+			raw, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			b.WriteString(fmt.Sprintf(" %s=%s", key, raw))
+		}
+	}
+	return b.String()
+}
+
+func (e *KernelHealthEvent) Flat() []EventRecord {
+	names := kernelHealthEventNames[e.Type]
+	dimensions := map[string]interface{}{
+		"bk_target_cloud_id": conv.String(e.CloudID),
+		"bk_target_ip":       e.Host,
+		"ip":                 e.Host,
+		"bk_cloud_id":        conv.String(e.CloudID),
+		"type":               conv.String(e.Type),
+	}
+	if e.Process != "" {
+		dimensions["process"] = e.Process
+	}
+
+	return []EventRecord{
+		{
+			EventName: names[0],
+			Target:    fmt.Sprintf("%d:%s", e.CloudID, e.Host),
+			Event: map[string]interface{}{
+				"content": e.content(names[1]),
+			},
+			EventDimension: dimensions,
+		},
+	}
+}
+
 func parseSystemEvent(data interface{}) []EventRecord {
 	var event EventRecordFlatter
 	var err error
@@ -282,6 +357,11 @@ func parseSystemEvent(data interface{}) []EventRecord {
 		var oomEvent OOMEvent
 		err = mapstructure.Decode(dataMap, &oomEvent)
 		event = &oomEvent
+	case 10, 11, 12, 13, 14, 15:
+		// inode、句柄、conntrack、僵尸进程、时钟跳变以及 hung task
+		var kernelHealthEvent KernelHealthEvent
+		err = mapstructure.Decode(dataMap, &kernelHealthEvent)
+		event = &kernelHealthEvent
 	default:
 		logging.Errorf("system event unknown type: %f", eventType)
 		return nil
@@ -10,6 +10,7 @@
 package gse_event
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 
@@ -492,6 +493,103 @@ func (s *SystemEventSuite) TestUsage() {
 	}
 }
 
+// TestKernelHealth : 内核健康事件只保留固定的维度
+func (s *SystemEventSuite) TestKernelHealth() {
+	input := `{
+		"utctime2":"2019-10-16 00:28:53",
+		"value":[
+			{
+				"event_raw_id":5853,
+				"event_type":"gse_basic_alarm_type",
+				"event_time":"2019-10-16 08:28:53",
+				"extra":%s
+			}
+		]
+	}`
+	dimensions := func(typ string, extra map[string]interface{}) map[string]interface{} {
+		d := map[string]interface{}{
+			"bk_target_cloud_id": "0",
+			"bk_target_ip":       "127.0.0.1",
+			"ip":                 "127.0.0.1",
+			"bk_cloud_id":        "0",
+			"bk_biz_id":          "2",
+			"type":               typ,
+		}
+		for k, v := range extra {
+			d[k] = v
+		}
+		return d
+	}
+
+	cases := []struct {
+		extra      string
+		eventName  string
+		dimensions map[string]interface{}
+	}{
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":10,"disk":"/data","file_system":"/dev/sdb1","fstype":"ext4","inodes_total":100,"inodes_used":98,"inodes_free":2,"used_percent":98}`,
+			"InodeFull", dimensions("10", nil),
+		},
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":11,"used":980,"max":1000,"used_percent":98}`,
+			"FdFull", dimensions("11", nil),
+		},
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":12,"count":65000,"max":65536,"used_percent":99}`,
+			"ConntrackFull", dimensions("12", nil),
+		},
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":13,"total":3,"parents":[{"ppid":1,"name":"init","count":3}]}`,
+			"ZombieProcess", dimensions("13", nil),
+		},
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":14,"offset":30.5,"expected_time":1571185733,"actual_time":1571185763}`,
+			"ClockJump", dimensions("14", nil),
+		},
+		{
+			`{"bizid":0,"cloudid":0,"host":"127.0.0.1","type":15,"kind":"hung_task","process":"java","pid":1234,"cpu":1,"duration":120,"message":"task java:1234 blocked for more than 120 seconds.","total":1}`,
+			"HungTask", dimensions("15", map[string]interface{}{"process": "java"}),
+		},
+	}
+	for _, c := range cases {
+		s.runCase(fmt.Sprintf(input, c.extra), true, c.eventName, c.dimensions, "0:127.0.0.1", 1, 1571185733000)
+	}
+}
+
+// TestKernelHealthContent : 不固定的字段放到事件内容中
+func (s *SystemEventSuite) TestKernelHealthContent() {
+	cases := []struct {
+		event   KernelHealthEvent
+		content string
+	}{
+		{
+			KernelHealthEvent{Host: "127.0.0.1", Type: 14, Extra: map[string]interface{}{
+				"bizid": 0.0, "offset": 30.5, "expected_time": 1571185733.0, "actual_time": 1571185763.0,
+			}},
+			"clock_jump actual_time=1571185763 expected_time=1571185733 offset=30.5",
+		},
+		{
+			KernelHealthEvent{Host: "127.0.0.1", Type: 15, Process: "java", Extra: map[string]interface{}{
+				"pid": 1234.0, "duration": 120.0, "total": 1.0, "message": "blocked",
+			}},
+			"hung_task duration=120 message=blocked pid=1234 total=1",
+		},
+		{
+			KernelHealthEvent{Host: "127.0.0.1", Type: 13, Extra: map[string]interface{}{
+				"total": 3.0, "parents": []interface{}{map[string]interface{}{"ppid": 1.0}},
+			}},
+			`zombie_process parents=[{"ppid":1}] total=3`,
+		},
+	}
+	for _, c := range cases {
+		records := c.event.Flat()
+		s.Len(records, 1)
+		s.Equal(c.content, records[0].Event["content"])
+		s.NotContains(records[0].EventDimension, "pid")
+		s.NotContains(records[0].EventDimension, "offset")
+	}
+}
+
 func TestSystemEventSuite(t *testing.T) {
 	suite.Run(t, new(SystemEventSuite))
 }
@@ -0,0 +1,36 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package uptimecheck
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	template "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl/flat"
+)
+
+// NewDNSProcessor
+func NewDNSProcessor(ctx context.Context, name string) (*template.RecordProcessor, error) {
+	return flat.NewFlatProcessor(ctx, name)
+}
+
+func init() {
+	define.RegisterDataProcessor("uptimecheck.dns", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		return NewDNSProcessor(ctx, pipeConfig.FormatName(name))
+	})
+}
@@ -28,6 +28,8 @@ const (
 	BaseReportPipeIOName        = "system.io"
 	BaseReportPipeSwapName      = "system.swap"
 	BaseReportPipeLoadName      = "system.load"
+	BaseReportPipeCgroupName    = "system.cgroup"
+	BaseReportPipePressureName  = "system.pressure"
 )
 
 // NewBaseReportPipeline :
@@ -48,6 +50,8 @@ func NewBaseReportPipeline(ctx context.Context, name string) (define.Pipeline, e
 		BaseReportPipeIOName,
 		BaseReportPipeSwapName,
 		BaseReportPipeLoadName,
+		BaseReportPipeCgroupName,
+		BaseReportPipePressureName,
 	)
 }
 
@@ -19,4 +19,10 @@ type CMDB interface {
 
 	// QueryResourceMatcherRange 获取目标的关键维度和值（query_range 查询）
 	QueryResourceMatcherRange(ctx context.Context, lookBackDelta, spaceUid string, step string, startTs, endTs int64, target, source Resource, matcher Matcher, pathResource []Resource) (Resource, Matcher, []string, []MatchersWithTimestamp, error)
+
+	// QuerySubgraph 获取资源 hops 跳以内的关联子图（instant 查询）
+	QuerySubgraph(ctx context.Context, lookBackDelta, spaceUid string, ts int64, source Resource, matcher Matcher, hops int, resourceTypes []Resource) (Resource, Matcher, *Subgraph, error)
+
+	// QuerySubgraphRange 获取资源 hops 跳以内的关联子图以及时间范围内的关联变化（query_range 查询）
+	QuerySubgraphRange(ctx context.Context, lookBackDelta, spaceUid string, step string, startTs, endTs int64, source Resource, matcher Matcher, hops int, resourceTypes []Resource) (Resource, Matcher, *Subgraph, error)
 }
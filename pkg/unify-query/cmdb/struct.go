@@ -97,3 +97,83 @@ type RelationMultiResourceRangeResponse struct {
 	TraceID string                                   `json:"trace_id"`
 	Data    []RelationMultiResourceRangeResponseData `json:"data"`
 }
+
+// SubgraphNode 子图中的资源节点
+type SubgraphNode struct {
+	ID           string   `json:"id"`
+	ResourceType Resource `json:"resource_type"`
+	Info         Matcher  `json:"info"`
+	// Hop 距离查询资源的跳数
+	Hop       int   `json:"hop"`
+	FirstSeen int64 `json:"first_seen,omitempty"`
+	LastSeen  int64 `json:"last_seen,omitempty"`
+}
+
+// SubgraphEdge 子图中的关联关系，Type 为关联指标名
+type SubgraphEdge struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Type      string `json:"type"`
+	FirstSeen int64  `json:"first_seen,omitempty"`
+	LastSeen  int64  `json:"last_seen,omitempty"`
+}
+
+// SubgraphChange 时间范围内关联关系的变化
+type SubgraphChange struct {
+	Timestamp int64  `json:"timestamp"`
+	Action    string `json:"action"`
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Type      string `json:"type"`
+}
+
+// Subgraph 从资源出发 N 跳以内可达的子图
+type Subgraph struct {
+	Nodes   []SubgraphNode   `json:"nodes"`
+	Edges   []SubgraphEdge   `json:"edges"`
+	Changes []SubgraphChange `json:"changes,omitempty"`
+	// Truncated 节点数量超过上限时停止扩展
+	Truncated bool `json:"truncated"`
+}
+
+// RelationSubgraphRequest 请求参数
+type RelationSubgraphRequest struct {
+	QueryList []struct {
+		Timestamp     int64      `json:"timestamp"`
+		SourceType    Resource   `json:"source_type"`
+		SourceInfo    Matcher    `json:"source_info"`
+		Hops          int        `json:"hops"`
+		ResourceTypes []Resource `json:"resource_types"`
+		LookBackDelta string     `json:"look_back_delta"`
+	} `json:"query_list"`
+}
+
+// RelationSubgraphRangeRequest 请求参数
+type RelationSubgraphRangeRequest struct {
+	QueryList []struct {
+		StartTs       int64      `json:"start_time"`
+		EndTs         int64      `json:"end_time"`
+		Step          string     `json:"step"` // 必填，需要大于 0，例如 1m
+		SourceType    Resource   `json:"source_type"`
+		SourceInfo    Matcher    `json:"source_info"`
+		Hops          int        `json:"hops"`
+		ResourceTypes []Resource `json:"resource_types"`
+		LookBackDelta string     `json:"look_back_delta"`
+	} `json:"query_list"`
+}
+
+// RelationSubgraphResponseData 响应数据
+type RelationSubgraphResponseData struct {
+	Code       int      `json:"code"`
+	SourceType Resource `json:"source_type"`
+	SourceInfo Matcher  `json:"source_info"`
+	Message    string   `json:"message"`
+
+	Subgraph
+}
+
+// RelationSubgraphResponse 请求返回
+type RelationSubgraphResponse struct {
+	TraceID string                         `json:"trace_id"`
+	Data    []RelationSubgraphResponseData `json:"data"`
+}
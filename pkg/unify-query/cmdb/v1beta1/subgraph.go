@@ -0,0 +1,624 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	pl "github.com/prometheus/prometheus/promql"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/cmdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/function"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+const (
+	SubgraphDefaultHops = 1
+	SubgraphMaxHops     = 5
+	SubgraphMaxNodes    = 1000
+
+	SubgraphActionAdd    = "add"
+	SubgraphActionRemove = "remove"
+)
+
+type QuerySubgraphOptions struct {
+	LookBackDelta string
+	SpaceUid      string
+	Step          time.Duration
+	Start         time.Time
+	End           time.Time
+	Unit          string
+	Source        cmdb.Resource
+	Matcher       cmdb.Matcher
+	Hops          int
+	ResourceTypes []cmdb.Resource
+	Instant       bool
+}
+
+// subgraphFrontier 待扩展的资源节点
+type subgraphFrontier struct {
+	resource cmdb.Resource
+	matcher  cmdb.Matcher
+}
+
+// subgraphBuilder 根据关联查询的结果构建子图，节点和关联关系按照 ID 去重
+type subgraphBuilder struct {
+	maxNodes  int
+	truncated bool
+
+	nodes map[string]*cmdb.SubgraphNode
+	edges map[string]*cmdb.SubgraphEdge
+
+	// edgeTimestamps 记录关联关系出现的时间点，用于计算时间范围内的变化
+	edgeTimestamps map[string]map[int64]struct{}
+	timestamps     map[int64]struct{}
+}
+
+func newSubgraphBuilder(maxNodes int) *subgraphBuilder {
+	return &subgraphBuilder{
+		maxNodes:       maxNodes,
+		nodes:          make(map[string]*cmdb.SubgraphNode),
+		edges:          make(map[string]*cmdb.SubgraphEdge),
+		edgeTimestamps: make(map[string]map[int64]struct{}),
+		timestamps:     make(map[int64]struct{}),
+	}
+}
+
+// subgraphNodeID 节点 ID 由资源类型以及关键维度的值组成，例如：node:bcs_cluster_id=BCS-K8S-00000,node=node-1
+func subgraphNodeID(resource cmdb.Resource, index cmdb.Index, info cmdb.Matcher) string {
+	kvs := make([]string, 0, len(index))
+	for _, i := range index {
+		kvs = append(kvs, fmt.Sprintf("%s=%s", i, info[i]))
+	}
+	return fmt.Sprintf("%s:%s", resource, strings.Join(kvs, ","))
+}
+
+// subgraphEdgeID 关联关系不区分方向，相同的两个节点只保留一条
+func subgraphEdgeID(relation, source, target string) string {
+	if source > target {
+		source, target = target, source
+	}
+	return fmt.Sprintf("%s|%s|%s", relation, source, target)
+}
+
+// indexInfo 从维度中提取资源的关键维度，关键维度缺失时返回 false
+func indexInfo(index cmdb.Index, lbs cmdb.Matcher) (cmdb.Matcher, bool) {
+	info := make(cmdb.Matcher, len(index))
+	for _, i := range index {
+		v, ok := lbs[i]
+		if !ok || v == "" {
+			return nil, false
+		}
+		info[i] = v
+	}
+	return info, true
+}
+
+func seen(firstSeen, lastSeen *int64, timestamps []int64) {
+	for _, ts := range timestamps {
+		if *firstSeen == 0 || ts < *firstSeen {
+			*firstSeen = ts
+		}
+		if ts > *lastSeen {
+			*lastSeen = ts
+		}
+	}
+}
+
+// addNode 增加节点，已经存在的节点只更新出现时间，超过节点上限时返回 nil
+func (b *subgraphBuilder) addNode(resource cmdb.Resource, index cmdb.Index, info cmdb.Matcher, hop int, timestamps []int64) (*cmdb.SubgraphNode, bool) {
+	id := subgraphNodeID(resource, index, info)
+	node, ok := b.nodes[id]
+	if !ok {
+		if b.maxNodes > 0 && len(b.nodes) >= b.maxNodes {
+			b.truncated = true
+			return nil, false
+		}
+
+		node = &cmdb.SubgraphNode{
+			ID:           id,
+			ResourceType: resource,
+			Info:         info,
+			Hop:          hop,
+		}
+		b.nodes[id] = node
+	}
+
+	seen(&node.FirstSeen, &node.LastSeen, timestamps)
+	return node, !ok
+}
+
+// addSeries 将一条关联数据转换为两个节点和一条关联关系，返回新发现的目标节点
+func (b *subgraphBuilder) addSeries(hop int, source, target cmdb.Resource, sourceIndex, targetIndex cmdb.Index, series pl.Series) (*cmdb.SubgraphNode, bool) {
+	lbs := make(cmdb.Matcher, len(series.Metric))
+	for _, m := range series.Metric {
+		lbs[m.Name] = m.Value
+	}
+
+	sourceInfo, ok := indexInfo(sourceIndex, lbs)
+	if !ok {
+		return nil, false
+	}
+	targetInfo, ok := indexInfo(targetIndex, lbs)
+	if !ok {
+		return nil, false
+	}
+
+	timestamps := make([]int64, 0, len(series.Points))
+	for _, p := range series.Points {
+		timestamps = append(timestamps, p.T)
+	}
+
+	sourceNode, _ := b.addNode(source, sourceIndex, sourceInfo, hop, timestamps)
+	if sourceNode == nil {
+		return nil, false
+	}
+	targetNode, isNew := b.addNode(target, targetIndex, targetInfo, hop+1, timestamps)
+	if targetNode == nil {
+		return nil, false
+	}
+
+	relation := getMetric(cmdb.Relation{V: []cmdb.Resource{source, target}})
+	id := subgraphEdgeID(relation, sourceNode.ID, targetNode.ID)
+	edge, ok := b.edges[id]
+	if !ok {
+		edge = &cmdb.SubgraphEdge{
+			Source: sourceNode.ID,
+			Target: targetNode.ID,
+			Type:   relation,
+		}
+		b.edges[id] = edge
+		b.edgeTimestamps[id] = make(map[int64]struct{})
+	}
+	seen(&edge.FirstSeen, &edge.LastSeen, timestamps)
+	for _, ts := range timestamps {
+		b.edgeTimestamps[id][ts] = struct{}{}
+		b.timestamps[ts] = struct{}{}
+	}
+
+	return targetNode, isNew
+}
+
+// changes 按照所有关联数据出现过的时间点，对比相邻时间点之间关联关系的增加和删除
+func (b *subgraphBuilder) changes() []cmdb.SubgraphChange {
+	timestamps := make([]int64, 0, len(b.timestamps))
+	for ts := range b.timestamps {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+
+	edgeIDs := make([]string, 0, len(b.edges))
+	for id := range b.edges {
+		edgeIDs = append(edgeIDs, id)
+	}
+	sort.Strings(edgeIDs)
+
+	changes := make([]cmdb.SubgraphChange, 0)
+	for i := 1; i < len(timestamps); i++ {
+		for _, id := range edgeIDs {
+			_, before := b.edgeTimestamps[id][timestamps[i-1]]
+			_, after := b.edgeTimestamps[id][timestamps[i]]
+			if before == after {
+				continue
+			}
+
+			action := SubgraphActionAdd
+			if before {
+				action = SubgraphActionRemove
+			}
+
+			edge := b.edges[id]
+			changes = append(changes, cmdb.SubgraphChange{
+				Timestamp: timestamps[i],
+				Action:    action,
+				Source:    edge.Source,
+				Target:    edge.Target,
+				Type:      edge.Type,
+			})
+		}
+	}
+	return changes
+}
+
+func (b *subgraphBuilder) build(withChanges bool) *cmdb.Subgraph {
+	sg := &cmdb.Subgraph{
+		Nodes:     make([]cmdb.SubgraphNode, 0, len(b.nodes)),
+		Edges:     make([]cmdb.SubgraphEdge, 0, len(b.edges)),
+		Truncated: b.truncated,
+	}
+
+	for _, n := range b.nodes {
+		sg.Nodes = append(sg.Nodes, *n)
+	}
+	sort.Slice(sg.Nodes, func(i, j int) bool {
+		if sg.Nodes[i].Hop != sg.Nodes[j].Hop {
+			return sg.Nodes[i].Hop < sg.Nodes[j].Hop
+		}
+		return sg.Nodes[i].ID < sg.Nodes[j].ID
+	})
+
+	for _, e := range b.edges {
+		sg.Edges = append(sg.Edges, *e)
+	}
+	sort.Slice(sg.Edges, func(i, j int) bool {
+		if sg.Edges[i].Source != sg.Edges[j].Source {
+			return sg.Edges[i].Source < sg.Edges[j].Source
+		}
+		if sg.Edges[i].Target != sg.Edges[j].Target {
+			return sg.Edges[i].Target < sg.Edges[j].Target
+		}
+		return sg.Edges[i].Type < sg.Edges[j].Type
+	})
+
+	if withChanges {
+		sg.Changes = b.changes()
+	}
+	return sg
+}
+
+// neighbors 获取模型中与资源直接关联的资源，resourceTypes 不为空时只保留指定的资源类型
+func (r *model) neighbors(resource cmdb.Resource, resourceTypes []cmdb.Resource) ([]cmdb.Resource, error) {
+	adjacencyMap, err := r.g.AdjacencyMap()
+	if err != nil {
+		return nil, err
+	}
+
+	allow := make(map[cmdb.Resource]struct{}, len(resourceTypes))
+	for _, rt := range resourceTypes {
+		allow[rt] = struct{}{}
+	}
+
+	neighbors := make([]cmdb.Resource, 0, len(adjacencyMap[string(resource)]))
+	for n := range adjacencyMap[string(resource)] {
+		if _, ok := allow[cmdb.Resource(n)]; len(allow) > 0 && !ok {
+			continue
+		}
+		neighbors = append(neighbors, cmdb.Resource(n))
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i] < neighbors[j]
+	})
+	return neighbors, nil
+}
+
+// convertMatchersToConditions 将同一类资源的多个节点合并为一组查询条件，关键维度使用多值匹配
+// 多值条件之间是笛卡尔积，查询结果需要再通过 inFrontier 过滤出实际的源节点
+func convertMatchersToConditions(matchers []cmdb.Matcher, sourceIndex, targetIndex cmdb.Index) structured.Conditions {
+	if len(matchers) == 1 {
+		return convertMapToConditions(matchers[0], sourceIndex, targetIndex)
+	}
+
+	cond := structured.Conditions{}
+	exists := make(map[string]struct{}, len(sourceIndex)+len(targetIndex))
+	for _, index := range []cmdb.Index{sourceIndex, targetIndex} {
+		for _, i := range index {
+			if _, ok := exists[i]; ok {
+				continue
+			}
+			exists[i] = struct{}{}
+
+			values := make([]string, 0, len(matchers))
+			valueSet := make(map[string]struct{}, len(matchers))
+			for _, m := range matchers {
+				v := m[i]
+				if v == "" {
+					values = nil
+					break
+				}
+				if _, ok := valueSet[v]; ok {
+					continue
+				}
+				valueSet[v] = struct{}{}
+				values = append(values, v)
+			}
+
+			// 所有节点都包含该维度时使用多值匹配，否则只要求不为空
+			if len(values) > 0 {
+				sort.Strings(values)
+				cond.FieldList = append(cond.FieldList, structured.ConditionField{
+					DimensionName: i,
+					Value:         values,
+					Operator:      structured.ConditionEqual,
+				})
+			} else {
+				cond.FieldList = append(cond.FieldList, structured.ConditionField{
+					DimensionName: i,
+					Value:         []string{""},
+					Operator:      structured.ConditionNotEqual,
+				})
+			}
+		}
+	}
+
+	for i := 0; i < len(cond.FieldList)-1; i++ {
+		cond.ConditionList = append(cond.ConditionList, structured.ConditionAnd)
+	}
+	return cond
+}
+
+// inFrontier 判断关联数据的源节点是否在本跳待扩展的节点中，sources 为空时不过滤
+func inFrontier(series pl.Series, source cmdb.Resource, sourceIndex cmdb.Index, sources map[string]struct{}) bool {
+	if len(sources) == 0 {
+		return true
+	}
+
+	lbs := make(cmdb.Matcher, len(series.Metric))
+	for _, m := range series.Metric {
+		lbs[m.Name] = m.Value
+	}
+	info, ok := indexInfo(sourceIndex, lbs)
+	if !ok {
+		return false
+	}
+	_, ok = sources[subgraphNodeID(source, sourceIndex, info)]
+	return ok
+}
+
+// makeRelationQuery 查询两个资源之间的关联数据，同时按照两边的关键维度聚合，用于确定关联关系的两端
+// matchers 为同一类资源的多个节点，合并为一次查询
+func (r *model) makeRelationQuery(ctx context.Context, spaceUid string, source, target cmdb.Resource, matchers []cmdb.Matcher, step time.Duration) (*structured.QueryTs, error) {
+	const ref = "a"
+
+	metric := getMetric(cmdb.Relation{V: []cmdb.Resource{source, target}})
+	if metric == "" {
+		return nil, fmt.Errorf("metric is empty %s => %s", source, target)
+	}
+
+	sourceIndex, err := r.getResourceIndex(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	targetIndex, err := r.getResourceIndex(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	groupBy := make([]string, 0, len(sourceIndex)+len(targetIndex))
+	exists := make(map[string]struct{}, len(sourceIndex)+len(targetIndex))
+	for _, index := range []cmdb.Index{sourceIndex, targetIndex} {
+		for _, i := range index {
+			if _, ok := exists[i]; ok {
+				continue
+			}
+			exists[i] = struct{}{}
+			groupBy = append(groupBy, i)
+		}
+	}
+
+	return &structured.QueryTs{
+		SpaceUid: spaceUid,
+		QueryList: []*structured.Query{
+			{
+				TimeAggregation: relationTimeAggregation(step),
+				FieldName:       metric,
+				ReferenceName:   ref,
+				Conditions:      convertMatchersToConditions(matchers, sourceIndex, targetIndex),
+			},
+		},
+		MetricMerge: fmt.Sprintf(`count(%s) by (%s)`, ref, strings.Join(groupBy, ",")),
+	}, nil
+}
+
+// querySubgraph 从资源出发按照模型逐跳查询关联数据，构建 hops 跳以内可达的子图
+func (r *model) querySubgraph(ctx context.Context, opt QuerySubgraphOptions) (source cmdb.Resource, matcher cmdb.Matcher, sg *cmdb.Subgraph, err error) {
+	var (
+		user          = metadata.GetUser(ctx)
+		lookBackDelta time.Duration
+		allMatch      bool
+	)
+
+	ctx, span := trace.NewSpan(ctx, "get-resource-subgraph")
+	defer span.End(&err)
+
+	span.Set("source", user.Source)
+	span.Set("username", user.Name)
+	span.Set("space-uid", opt.SpaceUid)
+	span.Set("startTs", opt.Start)
+	span.Set("endTs", opt.End)
+	span.Set("step", opt.Step.String())
+	span.Set("resource", opt.Source)
+	span.Set("matcher", opt.Matcher)
+	span.Set("hops", opt.Hops)
+	span.Set("resource-types", opt.ResourceTypes)
+
+	queryMatcher := opt.Matcher.Rename()
+
+	if opt.Source == "" {
+		opt.Source, err = r.getResourceFromMatch(ctx, queryMatcher)
+		if err != nil {
+			err = errors.WithMessage(err, "get resource error")
+			return
+		}
+	}
+
+	source = opt.Source
+	matcher, allMatch, err = r.getIndexMatcher(ctx, opt.Source, queryMatcher)
+	if err != nil {
+		err = errors.WithMessagef(err, "get index matcher error")
+		return
+	}
+
+	if opt.SpaceUid == "" {
+		err = errors.New("space uid is empty")
+		return
+	}
+
+	if opt.Start.Unix() == 0 || opt.End.Unix() == 0 {
+		err = errors.New("timestamp is empty")
+		return
+	}
+
+	if opt.Hops <= 0 {
+		opt.Hops = SubgraphDefaultHops
+	}
+	if opt.Hops > SubgraphMaxHops {
+		err = fmt.Errorf("hops %d is out of range, max is %d", opt.Hops, SubgraphMaxHops)
+		return
+	}
+
+	if opt.LookBackDelta != "" {
+		lookBackDelta, err = time.ParseDuration(opt.LookBackDelta)
+		if err != nil {
+			return
+		}
+	}
+
+	metadata.GetQueryParams(ctx).SetTime(opt.Start, opt.End, opt.Unit).SetIsSkipK8s(true)
+
+	b := newSubgraphBuilder(SubgraphMaxNodes)
+
+	// 查询条件包含全部关键维度时，即使没有关联数据也返回该节点
+	if allMatch {
+		index, _ := r.getResourceIndex(ctx, opt.Source)
+		b.addNode(opt.Source, index, matcher, 0, nil)
+	}
+
+	// 每一跳按照资源类型合并待扩展的节点，每种关联关系只查询一次，避免逐个节点查询
+	frontier := []subgraphFrontier{{resource: opt.Source, matcher: matcher}}
+	for hop := 0; hop < opt.Hops && len(frontier) > 0 && !b.truncated; hop++ {
+		var (
+			next      []subgraphFrontier
+			resources []cmdb.Resource
+			groups    = make(map[cmdb.Resource][]cmdb.Matcher)
+		)
+		for _, f := range frontier {
+			if _, ok := groups[f.resource]; !ok {
+				resources = append(resources, f.resource)
+			}
+			groups[f.resource] = append(groups[f.resource], f.matcher)
+		}
+
+		for _, resource := range resources {
+			matchers := groups[resource]
+
+			neighbors, nErr := r.neighbors(resource, opt.ResourceTypes)
+			if nErr != nil {
+				err = nErr
+				return
+			}
+
+			sourceIndex, iErr := r.getResourceIndex(ctx, resource)
+			if iErr != nil {
+				err = iErr
+				return
+			}
+
+			// 多个节点合并查询时，需要过滤掉多值条件组合出来的非待扩展节点
+			var sources map[string]struct{}
+			if len(matchers) > 1 {
+				sources = make(map[string]struct{}, len(matchers))
+				for _, m := range matchers {
+					sources[subgraphNodeID(resource, sourceIndex, m)] = struct{}{}
+				}
+			}
+
+			for _, target := range neighbors {
+				targetIndex, iErr := r.getResourceIndex(ctx, target)
+				if iErr != nil {
+					err = iErr
+					return
+				}
+
+				queryTs, qErr := r.makeRelationQuery(ctx, opt.SpaceUid, resource, target, matchers, opt.Step)
+				if qErr != nil {
+					err = qErr
+					return
+				}
+
+				matrix, qErr := r.queryMatrix(ctx, queryTs, lookBackDelta, opt.Start, opt.End, opt.Step, opt.Instant)
+				if qErr != nil {
+					err = errors.WithMessagef(qErr, "query %s => %s error", resource, target)
+					return
+				}
+
+				for _, series := range matrix {
+					if !inFrontier(series, resource, sourceIndex, sources) {
+						continue
+					}
+					node, isNew := b.addSeries(hop, resource, target, sourceIndex, targetIndex, series)
+					if isNew {
+						next = append(next, subgraphFrontier{resource: node.ResourceType, matcher: node.Info})
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	sg = b.build(!opt.Instant)
+
+	span.Set("nodes-num", len(sg.Nodes))
+	span.Set("edges-num", len(sg.Edges))
+	span.Set("truncated", sg.Truncated)
+	return
+}
+
+func (r *model) QuerySubgraph(ctx context.Context, lookBackDelta, spaceUid string, timestamp int64, source cmdb.Resource, matcher cmdb.Matcher, hops int, resourceTypes []cmdb.Resource) (cmdb.Resource, cmdb.Matcher, *cmdb.Subgraph, error) {
+	unit, ts, err := function.ParseTimestamp(strconv.FormatInt(timestamp, 10))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	opt := QuerySubgraphOptions{
+		LookBackDelta: lookBackDelta,
+		SpaceUid:      spaceUid,
+		Step:          time.Duration(0),
+		Start:         ts,
+		End:           ts,
+		Unit:          unit,
+		Source:        source,
+		Matcher:       matcher,
+		Hops:          hops,
+		ResourceTypes: resourceTypes,
+		Instant:       true,
+	}
+	return r.querySubgraph(ctx, opt)
+}
+
+func (r *model) QuerySubgraphRange(ctx context.Context, lookBackDelta, spaceUid string, stepString string, startTs, endTs int64, source cmdb.Resource, matcher cmdb.Matcher, hops int, resourceTypes []cmdb.Resource) (cmdb.Resource, cmdb.Matcher, *cmdb.Subgraph, error) {
+	unit, start, end, err := function.QueryTimestamp(strconv.FormatInt(startTs, 10), strconv.FormatInt(endTs, 10))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// 关联变化依赖相邻时间点的对比，step 必须显式指定
+	step, err := time.ParseDuration(stepString)
+	if err != nil {
+		return "", nil, nil, errors.WithMessagef(err, "step %s is invalid", stepString)
+	}
+	if step <= 0 {
+		return "", nil, nil, fmt.Errorf("step %s must be greater than 0", stepString)
+	}
+
+	opt := QuerySubgraphOptions{
+		LookBackDelta: lookBackDelta,
+		SpaceUid:      spaceUid,
+		Step:          step,
+		Start:         start,
+		End:           end,
+		Unit:          unit,
+		Source:        source,
+		Matcher:       matcher,
+		Hops:          hops,
+		ResourceTypes: resourceTypes,
+		Instant:       false,
+	}
+	return r.querySubgraph(ctx, opt)
+}
@@ -0,0 +1,184 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	pl "github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/cmdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+)
+
+func TestModel_Neighbors(t *testing.T) {
+	neighbors, err := testModel.neighbors("node", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, neighbors, cmdb.Resource("pod"))
+	assert.Contains(t, neighbors, cmdb.Resource("system"))
+
+	neighbors, err = testModel.neighbors("node", []cmdb.Resource{"system", "deployment"})
+	assert.Nil(t, err)
+	assert.Equal(t, []cmdb.Resource{"system"}, neighbors)
+}
+
+func TestMakeRelationQuery(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+
+	testCases := map[string]struct {
+		source   cmdb.Resource
+		target   cmdb.Resource
+		matchers []cmdb.Matcher
+		step     time.Duration
+		promQL   string
+	}{
+		"node 关联 pod": {
+			source: "node",
+			target: "pod",
+			matchers: []cmdb.Matcher{{
+				"bcs_cluster_id": "cluster1",
+				"node":           "node1",
+			}},
+			promQL: `count by (bcs_cluster_id, node, namespace, pod) (bkmonitor:node_with_pod_relation{bcs_cluster_id="cluster1",namespace!="",node="node1",pod!=""})`,
+		},
+		"多个 node 合并查询 pod": {
+			source: "node",
+			target: "pod",
+			matchers: []cmdb.Matcher{
+				{"bcs_cluster_id": "cluster1", "node": "node2"},
+				{"bcs_cluster_id": "cluster1", "node": "node1"},
+			},
+			promQL: `count by (bcs_cluster_id, node, namespace, pod) (bkmonitor:node_with_pod_relation{bcs_cluster_id="cluster1",namespace!="",node=~"^(node1|node2)$",pod!=""})`,
+		},
+		"system 关联 node 按照 step 聚合": {
+			source: "system",
+			target: "node",
+			matchers: []cmdb.Matcher{{
+				"bk_target_ip": "127.0.0.1",
+			}},
+			step:   time.Second * 30,
+			promQL: `count by (bk_target_ip, bcs_cluster_id, node) (count_over_time(bkmonitor:node_with_system_relation{bcs_cluster_id!="",bk_target_ip="127.0.0.1",node!=""}[1m]))`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx = metadata.InitHashID(ctx)
+			queryTs, err := testModel.makeRelationQuery(ctx, "", c.source, c.target, c.matchers, c.step)
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+
+			promQL, err := queryTs.ToPromQL(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, c.promQL, promQL)
+		})
+	}
+}
+
+func TestSubgraphBuilder(t *testing.T) {
+	nodeIndex := cmdb.Index{"bcs_cluster_id", "node"}
+	podIndex := cmdb.Index{"bcs_cluster_id", "namespace", "pod"}
+
+	series := func(pod string, ts ...int64) pl.Series {
+		s := pl.Series{
+			Metric: labels.FromStrings("bcs_cluster_id", "c1", "node", "n1", "namespace", "ns", "pod", pod),
+		}
+		for _, t := range ts {
+			s.Points = append(s.Points, pl.Point{T: t, V: 1})
+		}
+		return s
+	}
+
+	t.Run("节点和关联去重", func(t *testing.T) {
+		b := newSubgraphBuilder(0)
+		b.addNode("node", nodeIndex, cmdb.Matcher{"bcs_cluster_id": "c1", "node": "n1"}, 0, nil)
+
+		node, isNew := b.addSeries(0, "node", "pod", nodeIndex, podIndex, series("p1", 1000))
+		assert.True(t, isNew)
+		assert.Equal(t, "pod:bcs_cluster_id=c1,namespace=ns,pod=p1", node.ID)
+
+		// 反方向查询得到的同一条关联
+		_, isNew = b.addSeries(1, "pod", "node", podIndex, nodeIndex, series("p1", 1000))
+		assert.False(t, isNew)
+
+		// 缺少关键维度的数据直接忽略
+		_, isNew = b.addSeries(0, "node", "pod", nodeIndex, podIndex, pl.Series{
+			Metric: labels.FromStrings("bcs_cluster_id", "c1", "node", "n1"),
+		})
+		assert.False(t, isNew)
+
+		sg := b.build(false)
+		assert.Equal(t, []cmdb.SubgraphNode{
+			{ID: "node:bcs_cluster_id=c1,node=n1", ResourceType: "node", Info: cmdb.Matcher{"bcs_cluster_id": "c1", "node": "n1"}, Hop: 0, FirstSeen: 1000, LastSeen: 1000},
+			{ID: "pod:bcs_cluster_id=c1,namespace=ns,pod=p1", ResourceType: "pod", Info: cmdb.Matcher{"bcs_cluster_id": "c1", "namespace": "ns", "pod": "p1"}, Hop: 1, FirstSeen: 1000, LastSeen: 1000},
+		}, sg.Nodes)
+		assert.Equal(t, []cmdb.SubgraphEdge{
+			{Source: "node:bcs_cluster_id=c1,node=n1", Target: "pod:bcs_cluster_id=c1,namespace=ns,pod=p1", Type: "node_with_pod_relation", FirstSeen: 1000, LastSeen: 1000},
+		}, sg.Edges)
+		assert.Nil(t, sg.Changes)
+		assert.False(t, sg.Truncated)
+	})
+
+	t.Run("时间范围内的关联变化", func(t *testing.T) {
+		b := newSubgraphBuilder(0)
+		b.addSeries(0, "node", "pod", nodeIndex, podIndex, series("p1", 1000, 2000))
+		b.addSeries(0, "node", "pod", nodeIndex, podIndex, series("p2", 2000, 3000))
+
+		sg := b.build(true)
+		assert.Len(t, sg.Edges, 2)
+		assert.Equal(t, []cmdb.SubgraphChange{
+			{Timestamp: 2000, Action: SubgraphActionAdd, Source: "node:bcs_cluster_id=c1,node=n1", Target: "pod:bcs_cluster_id=c1,namespace=ns,pod=p2", Type: "node_with_pod_relation"},
+			{Timestamp: 3000, Action: SubgraphActionRemove, Source: "node:bcs_cluster_id=c1,node=n1", Target: "pod:bcs_cluster_id=c1,namespace=ns,pod=p1", Type: "node_with_pod_relation"},
+		}, sg.Changes)
+	})
+
+	t.Run("超过节点上限", func(t *testing.T) {
+		b := newSubgraphBuilder(2)
+		b.addSeries(0, "node", "pod", nodeIndex, podIndex, series("p1", 1000))
+		_, isNew := b.addSeries(0, "node", "pod", nodeIndex, podIndex, series("p2", 1000))
+		assert.False(t, isNew)
+
+		sg := b.build(false)
+		assert.Len(t, sg.Nodes, 2)
+		assert.Len(t, sg.Edges, 1)
+		assert.True(t, sg.Truncated)
+	})
+}
+
+func TestInFrontier(t *testing.T) {
+	nodeIndex := cmdb.Index{"bcs_cluster_id", "node"}
+	sources := map[string]struct{}{
+		subgraphNodeID("node", nodeIndex, cmdb.Matcher{"bcs_cluster_id": "c1", "node": "n1"}): {},
+		subgraphNodeID("node", nodeIndex, cmdb.Matcher{"bcs_cluster_id": "c2", "node": "n2"}): {},
+	}
+
+	// 多值条件组合出来的 c1/n2 不是待扩展节点
+	assert.True(t, inFrontier(pl.Series{Metric: labels.FromStrings("bcs_cluster_id", "c1", "node", "n1", "pod", "p1")}, "node", nodeIndex, sources))
+	assert.False(t, inFrontier(pl.Series{Metric: labels.FromStrings("bcs_cluster_id", "c1", "node", "n2", "pod", "p1")}, "node", nodeIndex, sources))
+	assert.True(t, inFrontier(pl.Series{Metric: labels.FromStrings("bcs_cluster_id", "c1", "node", "n2", "pod", "p1")}, "node", nodeIndex, nil))
+}
+
+func TestQuerySubgraphRangeStep(t *testing.T) {
+	mock.Init()
+	ctx := metadata.InitHashID(context.Background())
+
+	for _, step := range []string{"", "0s", "-1m", "abc"} {
+		_, _, _, err := testModel.QuerySubgraphRange(ctx, "", "bkcc__2", step, 1693973987, 1693974407, "node", cmdb.Matcher{"bcs_cluster_id": "c1", "node": "n1"}, 1, nil)
+		assert.NotNil(t, err, step)
+	}
+}
@@ -412,6 +412,49 @@ func (r *model) doRequest(ctx context.Context, lookBackDeltaStr, spaceUid string
 		return nil, err
 	}
 
+	matrix, err := r.queryMatrix(ctx, queryTs, lookBackDelta, startTs, endTs, step, instant)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matrix) == 0 {
+		return nil, nil
+	}
+
+	merged := make(map[int64]cmdb.Matchers)
+	for _, series := range matrix {
+		for _, p := range series.Points {
+			lbs := make(cmdb.Matcher, len(series.Metric))
+			for _, m := range series.Metric {
+				lbs[m.Name] = m.Value
+			}
+			merged[p.T] = append(merged[p.T], lbs)
+		}
+	}
+
+	// 按时间戳聚合并排序
+	ret := make([]cmdb.MatchersWithTimestamp, 0, len(merged))
+	for k, v := range merged {
+		ret = append(ret, cmdb.MatchersWithTimestamp{
+			Timestamp: k,
+			Matchers:  v,
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Timestamp < ret[j].Timestamp
+	})
+
+	return ret, nil
+}
+
+// queryMatrix 执行关联查询，instant 查询也统一转换为 matrix 返回
+func (r *model) queryMatrix(ctx context.Context, queryTs *structured.QueryTs, lookBackDelta time.Duration, startTs, endTs time.Time, step time.Duration, instant bool) (pl.Matrix, error) {
+	var err error
+
+	ctx, span := trace.NewSpan(ctx, "query-matrix")
+	defer span.End(&err)
+
 	queryReference, err := queryTs.ToQueryReference(ctx)
 
 	if err != nil {
@@ -465,34 +508,9 @@ func (r *model) doRequest(ctx context.Context, lookBackDeltaStr, spaceUid string
 
 	if len(matrix) == 0 {
 		log.Warnf(ctx, "instance data empty, promql: %s", realPromQL)
-		return nil, nil
 	}
 
-	merged := make(map[int64]cmdb.Matchers)
-	for _, series := range matrix {
-		for _, p := range series.Points {
-			lbs := make(cmdb.Matcher, len(series.Metric))
-			for _, m := range series.Metric {
-				lbs[m.Name] = m.Value
-			}
-			merged[p.T] = append(merged[p.T], lbs)
-		}
-	}
-
-	// 按时间戳聚合并排序
-	ret := make([]cmdb.MatchersWithTimestamp, 0, len(merged))
-	for k, v := range merged {
-		ret = append(ret, cmdb.MatchersWithTimestamp{
-			Timestamp: k,
-			Matchers:  v,
-		})
-	}
-
-	sort.Slice(ret, func(i, j int) bool {
-		return ret[i].Timestamp < ret[j].Timestamp
-	})
-
-	return ret, nil
+	return matrix, nil
 }
 
 func (r *model) makeQuery(ctx context.Context, spaceUid string, path []string, matcher map[string]string, step time.Duration) (*structured.QueryTs, error) {
@@ -502,15 +520,7 @@ func (r *model) makeQuery(ctx context.Context, spaceUid string, path []string, m
 		SpaceUid: spaceUid,
 	}
 
-	timeAggregation := structured.TimeAggregation{}
-	if step.Seconds() > 0 {
-		if step < time.Minute {
-			step = time.Minute
-		}
-
-		timeAggregation.Function = structured.CountOT
-		timeAggregation.Window = structured.Window(step.String())
-	}
+	timeAggregation := relationTimeAggregation(step)
 
 	cmdbPath, err := pathParser(path)
 	if err != nil {
@@ -566,6 +576,20 @@ func (r *model) makeQuery(ctx context.Context, spaceUid string, path []string, m
 	return queryTs, nil
 }
 
+// relationTimeAggregation range 查询时按照 step 统计关联数据，最小为 1m
+func relationTimeAggregation(step time.Duration) structured.TimeAggregation {
+	timeAggregation := structured.TimeAggregation{}
+	if step.Seconds() > 0 {
+		if step < time.Minute {
+			step = time.Minute
+		}
+
+		timeAggregation.Function = structured.CountOT
+		timeAggregation.Window = structured.Window(step.String())
+	}
+	return timeAggregation
+}
+
 func vectorToMatrix(vector pl.Vector) pl.Matrix {
 	var matrix pl.Matrix
 	for _, sample := range vector {
@@ -22,6 +22,7 @@ const (
 	QueryReferenceKey     = "query_reference"
 	QueryClusterMetricKey = "query_cluster_metric"
 	JwtPayLoadKey         = "jwt_payload"
+	ExplainKey            = "explain"
 
 	PromDataFormatKey = "prom_data_format"
 
@@ -33,4 +34,6 @@ const (
 	TableIDProxyISNotExists      = "TABLE_ID_PROXY_IS_NOT_EXISTS"
 
 	QueryRawError = "QUERY_RAW_ERROR"
+
+	RollupNotMatched = "ROLLUP_NOT_MATCHED"
 )
@@ -0,0 +1,167 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Explain 查询解释信息，记录查询路由的结果表、各存储实际执行的查询语句以及各阶段耗时
+type Explain struct {
+	lock sync.Mutex
+
+	ResultTables []*ExplainResultTable `json:"result_tables"`
+	Queries      []*ExplainQuery       `json:"queries"`
+	Stages       []*ExplainStage       `json:"stages"`
+}
+
+// ExplainResultTable 路由命中的结果表以及对应的存储
+type ExplainResultTable struct {
+	ReferenceName string   `json:"reference_name"`
+	TableID       string   `json:"table_id"`
+	DataLabel     string   `json:"data_label,omitempty"`
+	MetricName    string   `json:"metric_name,omitempty"`
+	Fields        []string `json:"fields,omitempty"`
+	StorageType   string   `json:"storage_type"`
+	StorageID     string   `json:"storage_id,omitempty"`
+	ClusterName   string   `json:"cluster_name,omitempty"`
+	DB            string   `json:"db,omitempty"`
+	Measurement   string   `json:"measurement,omitempty"`
+	VmRt          string   `json:"vm_rt,omitempty"`
+}
+
+// ExplainQuery 存储实际执行的查询语句以及返回情况
+type ExplainQuery struct {
+	StorageType string `json:"storage_type"`
+	Address     string `json:"address,omitempty"`
+	TableID     string `json:"table_id,omitempty"`
+	Statement   string `json:"statement"`
+	SeriesNum   int    `json:"series_num"`
+	RowNum      int    `json:"row_num"`
+	Cost        string `json:"cost"`
+	Error       string `json:"error,omitempty"`
+
+	start time.Time
+}
+
+// ExplainStage 查询各阶段耗时
+type ExplainStage struct {
+	Name string `json:"name"`
+	Cost string `json:"cost"`
+}
+
+// EnableExplain 开启当前查询的解释模式
+func EnableExplain(ctx context.Context) *Explain {
+	e := &Explain{
+		ResultTables: make([]*ExplainResultTable, 0),
+		Queries:      make([]*ExplainQuery, 0),
+		Stages:       make([]*ExplainStage, 0),
+	}
+	md.set(ctx, ExplainKey, e)
+	return e
+}
+
+// GetExplain 获取解释信息，未开启时返回 nil，所有方法都兼容 nil 调用
+func GetExplain(ctx context.Context) *Explain {
+	r, ok := md.get(ctx, ExplainKey)
+	if ok {
+		if v, ok := r.(*Explain); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Stage 记录从 start 开始到当前的阶段耗时
+func (e *Explain) Stage(name string, start time.Time) {
+	if e == nil {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.Stages = append(e.Stages, &ExplainStage{
+		Name: name,
+		Cost: time.Since(start).String(),
+	})
+}
+
+// AddQueryReference 记录路由命中的结果表
+func (e *Explain) AddQueryReference(queryRef QueryReference) {
+	if e == nil {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	refNames := make([]string, 0, len(queryRef))
+	for refName := range queryRef {
+		refNames = append(refNames, refName)
+	}
+	sort.Strings(refNames)
+
+	for _, refName := range refNames {
+		for _, qm := range queryRef[refName] {
+			for _, qry := range qm.QueryList {
+				e.ResultTables = append(e.ResultTables, &ExplainResultTable{
+					ReferenceName: refName,
+					TableID:       qry.TableID,
+					DataLabel:     qry.DataLabel,
+					MetricName:    qry.MetricName,
+					Fields:        qry.Fields,
+					StorageType:   qry.StorageType,
+					StorageID:     qry.StorageID,
+					ClusterName:   qry.ClusterName,
+					DB:            qry.DB,
+					Measurement:   qry.Measurement,
+					VmRt:          qry.VmRt,
+				})
+			}
+		}
+	}
+}
+
+// StartQuery 开始记录一次存储查询，需要配合 ExplainQuery.Done 使用
+func (e *Explain) StartQuery(storageType, address, tableID, statement string) *ExplainQuery {
+	if e == nil {
+		return nil
+	}
+
+	q := &ExplainQuery{
+		StorageType: storageType,
+		Address:     address,
+		TableID:     tableID,
+		Statement:   statement,
+		start:       time.Now(),
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.Queries = append(e.Queries, q)
+	return q
+}
+
+// Done 记录存储查询的返回数量以及耗时
+func (q *ExplainQuery) Done(seriesNum, rowNum int, err error) {
+	if q == nil {
+		return
+	}
+
+	q.SeriesNum = seriesNum
+	q.RowNum = rowNum
+	q.Cost = time.Since(q.start).String()
+	if err != nil {
+		q.Error = err.Error()
+	}
+}
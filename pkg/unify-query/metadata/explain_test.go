@@ -0,0 +1,68 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	InitMetadata()
+	ctx := InitHashID(context.Background())
+
+	// 未开启解释模式时，所有方法都不生效
+	assert.Nil(t, GetExplain(ctx))
+	GetExplain(ctx).Stage("query-reference", time.Now())
+	GetExplain(ctx).StartQuery("influxdb", "127.0.0.1", "system.cpu_summary", "select 1").Done(1, 1, nil)
+
+	explain := EnableExplain(ctx)
+	assert.Equal(t, explain, GetExplain(ctx))
+
+	explain.AddQueryReference(QueryReference{
+		"b": {
+			{
+				QueryList: QueryList{
+					{TableID: "system.disk", StorageType: "victoria_metrics", VmRt: "2_system_disk"},
+				},
+			},
+		},
+		"a": {
+			{
+				QueryList: QueryList{
+					{TableID: "system.cpu_summary", StorageType: "influxdb", StorageID: "2", ClusterName: "default", DB: "system", Measurement: "cpu_summary", MetricName: "usage"},
+				},
+			},
+		},
+	})
+
+	q := GetExplain(ctx).StartQuery("influxdb", "127.0.0.1", "system.cpu_summary", "select usage from cpu_summary")
+	q.Done(2, 10, nil)
+	GetExplain(ctx).StartQuery("victoria_metrics", "", "", "{}").Done(0, 0, errors.New("timeout"))
+	GetExplain(ctx).Stage("query-reference", time.Now())
+
+	assert.Len(t, explain.ResultTables, 2)
+	assert.Equal(t, "a", explain.ResultTables[0].ReferenceName)
+	assert.Equal(t, "system.cpu_summary", explain.ResultTables[0].TableID)
+	assert.Equal(t, "2_system_disk", explain.ResultTables[1].VmRt)
+
+	assert.Len(t, explain.Queries, 2)
+	assert.Equal(t, 2, explain.Queries[0].SeriesNum)
+	assert.Equal(t, 10, explain.Queries[0].RowNum)
+	assert.NotEmpty(t, explain.Queries[0].Cost)
+	assert.Equal(t, "timeout", explain.Queries[1].Error)
+
+	assert.Len(t, explain.Stages, 1)
+	assert.Equal(t, "query-reference", explain.Stages[0].Name)
+}
@@ -11,6 +11,7 @@ package query
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
@@ -25,6 +26,13 @@ type Record struct {
 
 type StorageClusterRecords []Record
 
+// Rollup 预聚合结果表配置
+type Rollup struct {
+	TableID   string        `json:"table_id"`
+	Interval  time.Duration `json:"interval"`
+	Functions []string      `json:"functions"`
+}
+
 // TsDBV2 适配查询语句的结构体，以 TableID + MetricName 为条件，检索出 RT 基本信息和存储信息
 type TsDBV2 struct {
 	TableID         string   `json:"table_id"`
@@ -56,6 +64,13 @@ type TsDBV2 struct {
 	// SourceType 数据来源
 	SourceType  string `json:"source_type,omitempty"`
 	StorageType string `json:"storage_type,omitempty"`
+
+	// Rollups 预聚合结果表配置
+	Rollups []Rollup `json:"rollups,omitempty"`
+	// RollupFunction 命中预聚合结果表时使用的聚合方法，为空则表示查询原始数据
+	RollupFunction string `json:"rollup_function,omitempty"`
+	// RollupInterval 命中预聚合结果表的聚合周期
+	RollupInterval time.Duration `json:"rollup_interval,omitempty"`
 }
 
 func (z *TsDBV2) IsSplit() bool {
@@ -0,0 +1,144 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pattern
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	DefaultSpikeRatio = 2.0
+)
+
+// Bucket 模板在时间区间内的数量
+type Bucket struct {
+	Timestamp int64 `json:"timestamp"`
+	Count     int   `json:"count"`
+}
+
+// Pattern 日志模板的统计结果
+type Pattern struct {
+	ID           int      `json:"id"`
+	Template     string   `json:"template"`
+	Count        int      `json:"count"`
+	Sample       string   `json:"sample"`
+	Distribution []Bucket `json:"distribution"`
+
+	// 以下字段只有在对比模式下才会返回
+	BaselineCount *int    `json:"baseline_count,omitempty"`
+	Ratio         float64 `json:"ratio,omitempty"`
+	IsNew         bool    `json:"is_new,omitempty"`
+	IsSpike       bool    `json:"is_spike,omitempty"`
+}
+
+type stat struct {
+	count    int
+	baseline int
+	sample   string
+	buckets  map[int64]int
+}
+
+// Analyzer 对日志进行聚类并统计每个模板的数量、样例以及时间分布，支持对比基准时间段
+type Analyzer struct {
+	drain    *Drain
+	interval time.Duration
+	compare  bool
+
+	stats map[int]*stat
+}
+
+// NewAnalyzer interval 为时间分布的聚合周期，compare 为是否开启对比模式
+func NewAnalyzer(opt Options, interval time.Duration, compare bool) *Analyzer {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Analyzer{
+		drain:    NewDrain(opt),
+		interval: interval,
+		compare:  compare,
+		stats:    make(map[int]*stat),
+	}
+}
+
+func (a *Analyzer) stat(c *Cluster) *stat {
+	s, ok := a.stats[c.ID]
+	if !ok {
+		s = &stat{
+			buckets: make(map[int64]int),
+		}
+		a.stats[c.ID] = s
+	}
+	return s
+}
+
+// AddBaseline 增加基准时间段的日志，只参与聚类和基准数量统计
+func (a *Analyzer) AddBaseline(message string) {
+	s := a.stat(a.drain.Add(message))
+	s.baseline++
+}
+
+// Add 增加当前时间段的日志，时间为零值时不统计时间分布
+func (a *Analyzer) Add(message string, t time.Time) {
+	s := a.stat(a.drain.Add(message))
+	s.count++
+	if s.sample == "" {
+		s.sample = message
+	}
+	if !t.IsZero() {
+		s.buckets[t.Truncate(a.interval).UnixMilli()]++
+	}
+}
+
+// Patterns 按照数量倒序返回当前时间段出现过的模板，对比模式下增幅达到 spikeRatio 的模板标记为突增
+func (a *Analyzer) Patterns(spikeRatio float64) []Pattern {
+	if spikeRatio <= 0 {
+		spikeRatio = DefaultSpikeRatio
+	}
+
+	patterns := make([]Pattern, 0, len(a.stats))
+	for _, c := range a.drain.Clusters() {
+		s, ok := a.stats[c.ID]
+		if !ok || s.count == 0 {
+			continue
+		}
+
+		p := Pattern{
+			ID:           c.ID,
+			Template:     c.Template(),
+			Count:        s.count,
+			Sample:       s.sample,
+			Distribution: make([]Bucket, 0, len(s.buckets)),
+		}
+		for ts, cnt := range s.buckets {
+			p.Distribution = append(p.Distribution, Bucket{Timestamp: ts, Count: cnt})
+		}
+		sort.Slice(p.Distribution, func(i, j int) bool {
+			return p.Distribution[i].Timestamp < p.Distribution[j].Timestamp
+		})
+
+		if a.compare {
+			baseline := s.baseline
+			p.BaselineCount = &baseline
+			if baseline == 0 {
+				p.IsNew = true
+			} else {
+				p.Ratio = float64(s.count) / float64(baseline)
+				p.IsSpike = p.Ratio >= spikeRatio
+			}
+		}
+		patterns = append(patterns, p)
+	}
+
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return patterns[i].Count > patterns[j].Count
+	})
+	return patterns
+}
@@ -0,0 +1,295 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pattern
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Wildcard 模板中的变量占位符
+	Wildcard = "<*>"
+
+	DefaultDepth        = 4
+	DefaultSimThreshold = 0.4
+	DefaultMaxChildren  = 100
+)
+
+// Mask 变量屏蔽规则，在分词之前将匹配的内容替换为 <Name>
+type Mask struct {
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// DefaultMasks 默认的变量屏蔽规则，按顺序执行，越具体的规则越靠前
+var DefaultMasks = []Mask{
+	{Name: "UUID", Regex: regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+	{Name: "IP", Regex: regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}(:\d{1,5})?\b`)},
+	{Name: "HEX", Regex: regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b|\b[0-9a-fA-F]{16,}\b`)},
+	{Name: "NUM", Regex: regexp.MustCompile(`\b[-+]?\d+(\.\d+)?([eE][-+]?\d+)?(ms|s|m|h|us|ns|b|kb|mb|gb|KB|MB|GB)?\b`)},
+}
+
+// Options Drain 算法参数
+type Options struct {
+	// Depth 解析树深度（包含根节点、长度节点以及叶子节点），至少为 3
+	Depth int
+	// SimThreshold 日志与模板的相似度阈值，超过阈值才会归为同一个模板
+	SimThreshold float64
+	// MaxChildren 每个节点下最多的子节点数量，超过之后统一归为通配节点
+	MaxChildren int
+	// MaxClusters 最多的模板数量，0 代表不限制，超过之后新日志归入最接近的模板，没有同长度模板时归入通配模板 <*>
+	MaxClusters int
+	// Masks 变量屏蔽规则，为空时使用 DefaultMasks
+	Masks []Mask
+}
+
+// Cluster 日志模板
+type Cluster struct {
+	ID     int
+	Tokens []string
+	Size   int
+}
+
+// Template 模板字符串
+func (c *Cluster) Template() string {
+	return strings.Join(c.Tokens, " ")
+}
+
+type node struct {
+	children map[string]*node
+	clusters []*Cluster
+}
+
+func newNode() *node {
+	return &node{
+		children: make(map[string]*node),
+	}
+}
+
+// Drain 基于固定深度解析树的在线日志聚类算法，参考 Drain: An Online Log Parsing Approach with Fixed Depth Tree
+type Drain struct {
+	opt Options
+
+	root     *node
+	clusters []*Cluster
+
+	// catchAll 模板数量达到上限之后，无法归入任何模板的日志统一使用的通配模板
+	catchAll *Cluster
+}
+
+func NewDrain(opt Options) *Drain {
+	if opt.Depth < 3 {
+		opt.Depth = DefaultDepth
+	}
+	if opt.SimThreshold <= 0 {
+		opt.SimThreshold = DefaultSimThreshold
+	}
+	if opt.MaxChildren <= 0 {
+		opt.MaxChildren = DefaultMaxChildren
+	}
+	if opt.Masks == nil {
+		opt.Masks = DefaultMasks
+	}
+
+	return &Drain{
+		opt:  opt,
+		root: newNode(),
+	}
+}
+
+// Clusters 获取所有模板，按照生成顺序返回
+func (d *Drain) Clusters() []*Cluster {
+	return d.clusters
+}
+
+// Tokenize 屏蔽变量之后按照空白字符分词
+func (d *Drain) Tokenize(message string) []string {
+	message = strings.TrimSpace(message)
+	for _, m := range d.opt.Masks {
+		message = m.Regex.ReplaceAllString(message, "<"+m.Name+">")
+	}
+	return strings.Fields(message)
+}
+
+// Add 将日志加入聚类，返回命中或新建的模板
+func (d *Drain) Add(message string) *Cluster {
+	tokens := d.Tokenize(message)
+
+	leaf := d.leaf(tokens)
+	cluster := d.match(leaf.clusters, tokens)
+	if cluster == nil && d.opt.MaxClusters > 0 && len(d.clusters) >= d.opt.MaxClusters {
+		// 模板数量达到上限之后，不再新建模板，依次归入同一叶子节点、所有同长度模板中最接近的模板
+		cluster = d.closest(leaf.clusters, tokens)
+		if cluster == nil {
+			cluster = d.closest(d.sameLength(len(tokens)), tokens)
+		}
+		// 没有同长度的模板时无法合并 token，统一归入通配模板
+		if cluster == nil {
+			cluster = d.catchAllCluster()
+			cluster.Size++
+			return cluster
+		}
+	}
+
+	if cluster == nil {
+		cluster = &Cluster{
+			ID:     len(d.clusters) + 1,
+			Tokens: tokens,
+		}
+		d.clusters = append(d.clusters, cluster)
+		leaf.clusters = append(leaf.clusters, cluster)
+	} else {
+		cluster.Tokens = mergeTokens(cluster.Tokens, tokens)
+	}
+
+	cluster.Size++
+	return cluster
+}
+
+// sameLength 获取 token 数量相同的模板
+func (d *Drain) sameLength(n int) []*Cluster {
+	var res []*Cluster
+	for _, c := range d.clusters {
+		if c != d.catchAll && len(c.Tokens) == n {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// catchAllCluster 获取通配模板，不存在时创建，该模板不计入 MaxClusters
+func (d *Drain) catchAllCluster() *Cluster {
+	if d.catchAll == nil {
+		d.catchAll = &Cluster{
+			ID:     len(d.clusters) + 1,
+			Tokens: []string{Wildcard},
+		}
+		d.clusters = append(d.clusters, d.catchAll)
+	}
+	return d.catchAll
+}
+
+// leaf 按照日志长度和前缀 token 找到叶子节点，不存在时创建
+func (d *Drain) leaf(tokens []string) *node {
+	lengthKey := lengthToken(len(tokens))
+	cur, ok := d.root.children[lengthKey]
+	if !ok {
+		cur = newNode()
+		d.root.children[lengthKey] = cur
+	}
+
+	// 去掉根节点、长度节点以及叶子节点，剩下的深度用于前缀 token
+	maxDepth := d.opt.Depth - 3
+	for i := 0; i < maxDepth && i < len(tokens); i++ {
+		token := tokens[i]
+		if hasDigit(token) {
+			token = Wildcard
+		}
+
+		next, ok := cur.children[token]
+		if !ok {
+			// 子节点数量达到上限之后，统一归入通配节点
+			if len(cur.children) < d.opt.MaxChildren {
+				next = newNode()
+				cur.children[token] = next
+			} else if next, ok = cur.children[Wildcard]; !ok {
+				next = newNode()
+				cur.children[Wildcard] = next
+			}
+		}
+		cur = next
+	}
+
+	return cur
+}
+
+// match 找到相似度最高并且超过阈值的模板
+func (d *Drain) match(clusters []*Cluster, tokens []string) *Cluster {
+	cluster, sim := d.best(clusters, tokens)
+	if sim >= d.opt.SimThreshold {
+		return cluster
+	}
+	return nil
+}
+
+// closest 找到相似度最高的模板，不判断阈值
+func (d *Drain) closest(clusters []*Cluster, tokens []string) *Cluster {
+	cluster, _ := d.best(clusters, tokens)
+	return cluster
+}
+
+func (d *Drain) best(clusters []*Cluster, tokens []string) (*Cluster, float64) {
+	var (
+		res       *Cluster
+		maxSim    = -1.0
+		maxParams = -1
+	)
+
+	for _, c := range clusters {
+		sim, params := similarity(c.Tokens, tokens)
+		// 相似度相同时，优先选择变量更多的模板
+		if sim > maxSim || (sim == maxSim && params > maxParams) {
+			res = c
+			maxSim = sim
+			maxParams = params
+		}
+	}
+	return res, maxSim
+}
+
+// similarity 计算相同位置 token 相同的比例，通配符不计入相似度
+func similarity(template, tokens []string) (float64, int) {
+	if len(template) != len(tokens) {
+		return 0, 0
+	}
+	if len(tokens) == 0 {
+		return 1, 0
+	}
+
+	var same, params int
+	for i, t := range template {
+		if t == Wildcard {
+			params++
+			continue
+		}
+		if t == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), params
+}
+
+func mergeTokens(template, tokens []string) []string {
+	res := make([]string, len(template))
+	for i, t := range template {
+		if t == tokens[i] {
+			res[i] = t
+		} else {
+			res[i] = Wildcard
+		}
+	}
+	return res
+}
+
+func lengthToken(n int) string {
+	return "<len:" + strconv.Itoa(n) + ">"
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
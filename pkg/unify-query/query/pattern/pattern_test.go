@@ -0,0 +1,259 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pattern
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestDrain(t *testing.T) {
+	testCases := map[string]struct {
+		opt       Options
+		messages  []string
+		templates []string
+		sizes     []int
+	}{
+		"变量屏蔽": {
+			messages: []string{
+				"connect to 127.0.0.1:8080 failed after 3 retries",
+				"connect to 10.0.0.2:9090 failed after 15 retries",
+				"request 3f2b9c1e-8a4d-4e6b-9c1a-2b3c4d5e6f70 cost 12.5ms",
+			},
+			templates: []string{
+				"connect to <IP> failed after <NUM> retries",
+				"request <UUID> cost <NUM>",
+			},
+			sizes: []int{2, 1},
+		},
+		"不同位置的变量合并为通配符": {
+			messages: []string{
+				"user alice login success",
+				"user bob login success",
+				"user carol login failed",
+			},
+			templates: []string{
+				"user <*> login <*>",
+			},
+			sizes: []int{3},
+		},
+		"长度不同的日志不会合并": {
+			messages: []string{
+				"job started",
+				"job started with args",
+			},
+			templates: []string{
+				"job started",
+				"job started with args",
+			},
+			sizes: []int{1, 1},
+		},
+		"相似度阈值": {
+			opt: Options{SimThreshold: 0.8},
+			messages: []string{
+				"user alice login success",
+				"user bob login failed",
+			},
+			templates: []string{
+				"user alice login success",
+				"user bob login failed",
+			},
+			sizes: []int{1, 1},
+		},
+		"模板数量上限": {
+			opt: Options{SimThreshold: 0.9, MaxClusters: 1},
+			messages: []string{
+				"user alice login success",
+				"user bob login failed",
+			},
+			templates: []string{
+				"user <*> login <*>",
+			},
+			sizes: []int{2},
+		},
+		"模板数量上限后不同长度的日志": {
+			opt: Options{MaxClusters: 2},
+			messages: []string{
+				"user alice login",
+				"connection closed",
+				"disk full on device sda",
+				"cache miss",
+				"server started at port <*> now",
+				"user bob login",
+			},
+			templates: []string{
+				"user <*> login",
+				"<*> <*>",
+				"<*>",
+			},
+			sizes: []int{2, 2, 2},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := NewDrain(c.opt)
+			for _, m := range c.messages {
+				d.Add(m)
+			}
+
+			var (
+				templates []string
+				sizes     []int
+			)
+			for _, cl := range d.Clusters() {
+				templates = append(templates, cl.Template())
+				sizes = append(sizes, cl.Size)
+			}
+			assert.Equal(t, c.templates, templates)
+			assert.Equal(t, c.sizes, sizes)
+		})
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	start := time.UnixMilli(1700000000000).Truncate(time.Minute)
+
+	t.Run("数量样例以及时间分布", func(t *testing.T) {
+		a := NewAnalyzer(Options{}, time.Minute, false)
+		a.Add("disk /dev/sda1 usage 91%", start)
+		a.Add("disk /dev/sda2 usage 95%", start.Add(time.Second*30))
+		a.Add("disk /dev/sdb1 usage 97%", start.Add(time.Minute))
+		a.Add("service api restarted", start)
+
+		patterns := a.Patterns(0)
+		assert.Equal(t, []Pattern{
+			{
+				ID:       1,
+				Template: "disk <*> usage <NUM>%",
+				Count:    3,
+				Sample:   "disk /dev/sda1 usage 91%",
+				Distribution: []Bucket{
+					{Timestamp: start.UnixMilli(), Count: 2},
+					{Timestamp: start.Add(time.Minute).UnixMilli(), Count: 1},
+				},
+			},
+			{
+				ID:       2,
+				Template: "service api restarted",
+				Count:    1,
+				Sample:   "service api restarted",
+				Distribution: []Bucket{
+					{Timestamp: start.UnixMilli(), Count: 1},
+				},
+			},
+		}, patterns)
+	})
+
+	t.Run("对比时间段", func(t *testing.T) {
+		a := NewAnalyzer(Options{}, time.Minute, true)
+		a.AddBaseline("query timeout after 30s")
+		a.AddBaseline("cache miss key 1")
+		a.AddBaseline("cache miss key 2")
+
+		for i := 0; i < 4; i++ {
+			a.Add("query timeout after 45s", time.Time{})
+		}
+		a.Add("cache miss key 3", time.Time{})
+		a.Add("panic: nil pointer dereference", time.Time{})
+
+		patterns := a.Patterns(2)
+		assert.Len(t, patterns, 3)
+
+		result := make(map[string]Pattern)
+		for _, p := range patterns {
+			result[p.Template] = p
+		}
+
+		timeout := result["query timeout after <NUM>"]
+		assert.Equal(t, 4, timeout.Count)
+		assert.Equal(t, 1, *timeout.BaselineCount)
+		assert.Equal(t, 4.0, timeout.Ratio)
+		assert.True(t, timeout.IsSpike)
+		assert.False(t, timeout.IsNew)
+
+		cache := result["cache miss key <NUM>"]
+		assert.Equal(t, 2, *cache.BaselineCount)
+		assert.False(t, cache.IsSpike)
+
+		panicPattern := result["panic: nil pointer dereference"]
+		assert.True(t, panicPattern.IsNew)
+		assert.Equal(t, 0, *panicPattern.BaselineCount)
+	})
+}
+
+func TestQueryPattern(t *testing.T) {
+	ts := time.UnixMilli(1700000000000)
+
+	t.Run("日志内容以及时间", func(t *testing.T) {
+		q := &QueryPattern{}
+		item := map[string]any{
+			"log":              "hello world",
+			"dtEventTimeStamp": "1700000000000",
+			"resource": map[string]any{
+				"code": 500.0,
+			},
+		}
+
+		message, ok := q.Message(item)
+		assert.True(t, ok)
+		assert.Equal(t, "hello world", message)
+		assert.Equal(t, ts.UnixMilli(), q.Time(item).UnixMilli())
+
+		q.Field = "resource.code"
+		message, ok = q.Message(item)
+		assert.True(t, ok)
+		assert.Equal(t, "500", message)
+
+		q.Field = "not_exists"
+		_, ok = q.Message(item)
+		assert.False(t, ok)
+	})
+
+	t.Run("时间字段格式", func(t *testing.T) {
+		q := &QueryPattern{TimeField: "time"}
+		for name, v := range map[string]any{
+			"毫秒":      float64(1700000000000),
+			"秒":       int64(1700000000),
+			"RFC3339": ts.UTC().Format(time.RFC3339),
+		} {
+			assert.Equal(t, ts.Unix(), q.Time(map[string]any{"time": v}).Unix(), name)
+		}
+		assert.True(t, q.Time(map[string]any{"time": "abc"}).IsZero())
+	})
+
+	t.Run("基准时间段查询", func(t *testing.T) {
+		q := &QueryPattern{
+			Query: &structured.QueryTs{
+				Start: "1700000000",
+				End:   "1700003600",
+				Limit: 100,
+			},
+			CompareOffset: "1d",
+		}
+
+		baseline, err := q.BaselineQueryTs()
+		assert.Nil(t, err)
+		assert.Equal(t, "1699913600000", baseline.Start)
+		assert.Equal(t, "1699917200000", baseline.End)
+		assert.Equal(t, 100, baseline.Limit)
+
+		// 原始查询不受影响
+		assert.Equal(t, "1700000000", q.Query.Start)
+
+		q.CompareOffset = "abc"
+		_, err = q.BaselineQueryTs()
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,185 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package pattern
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/function"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+const (
+	DefaultField     = "log"
+	DefaultTimeField = "dtEventTimeStamp"
+	DefaultInterval  = time.Minute
+)
+
+var (
+	ErrEmptyQuery = errors.New("pattern query is empty")
+)
+
+// QueryPattern 日志聚类查询参数
+type QueryPattern struct {
+	// Query 原始日志查询，与 /query/ts/raw 的参数一致
+	Query *structured.QueryTs `json:"query"`
+	// Field 用于聚类的日志字段，支持使用 . 访问嵌套字段
+	Field string `json:"field,omitempty" example:"log"`
+	// TimeField 用于统计时间分布的时间字段
+	TimeField string `json:"time_field,omitempty" example:"dtEventTimeStamp"`
+	// Interval 时间分布的聚合周期
+	Interval string `json:"interval,omitempty" example:"1m"`
+	// CompareOffset 对比时间偏移，不为空时会同时查询偏移之后的时间段作为基准
+	CompareOffset string `json:"compare_offset,omitempty" example:"1d"`
+	// SpikeRatio 当前数量与基准数量的比例达到该值时认为是突增
+	SpikeRatio float64 `json:"spike_ratio,omitempty" example:"2"`
+	// SimThreshold 聚类相似度阈值
+	SimThreshold float64 `json:"sim_threshold,omitempty" example:"0.4"`
+	// MaxClusters 最多的模板数量
+	MaxClusters int `json:"max_clusters,omitempty" example:"0"`
+}
+
+// Options 聚类参数
+func (q *QueryPattern) Options() Options {
+	return Options{
+		SimThreshold: q.SimThreshold,
+		MaxClusters:  q.MaxClusters,
+	}
+}
+
+// IntervalDuration 时间分布的聚合周期
+func (q *QueryPattern) IntervalDuration() (time.Duration, error) {
+	if q.Interval == "" {
+		return DefaultInterval, nil
+	}
+	d, err := model.ParseDuration(q.Interval)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(d), nil
+}
+
+// IsCompare 是否开启对比模式
+func (q *QueryPattern) IsCompare() bool {
+	return q.CompareOffset != ""
+}
+
+// BaselineQueryTs 生成基准时间段的查询，需要在执行原始查询之前调用，避免使用被修改过的查询参数
+func (q *QueryPattern) BaselineQueryTs() (*structured.QueryTs, error) {
+	if q.Query == nil {
+		return nil, ErrEmptyQuery
+	}
+
+	offset, err := model.ParseDuration(q.CompareOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	_, start, end, err := function.QueryTimestamp(q.Query.Start, q.Query.End)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := json.Marshal(q.Query)
+	if err != nil {
+		return nil, err
+	}
+	baseline := &structured.QueryTs{}
+	if err = json.Unmarshal(s, baseline); err != nil {
+		return nil, err
+	}
+
+	baseline.Start = strconv.FormatInt(start.Add(-time.Duration(offset)).UnixMilli(), 10)
+	baseline.End = strconv.FormatInt(end.Add(-time.Duration(offset)).UnixMilli(), 10)
+	return baseline, nil
+}
+
+// Message 获取日志中用于聚类的内容
+func (q *QueryPattern) Message(item map[string]any) (string, bool) {
+	field := q.Field
+	if field == "" {
+		field = DefaultField
+	}
+
+	v, ok := lookup(item, field)
+	if !ok || v == nil {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(v), true
+}
+
+// Time 获取日志的时间，无法解析时返回零值
+func (q *QueryPattern) Time(item map[string]any) time.Time {
+	field := q.TimeField
+	if field == "" {
+		field = DefaultTimeField
+	}
+
+	v, ok := lookup(item, field)
+	if !ok {
+		return time.Time{}
+	}
+
+	switch t := v.(type) {
+	case float64:
+		return numberToTime(int64(t))
+	case int64:
+		return numberToTime(t)
+	case int:
+		return numberToTime(int64(t))
+	case string:
+		if _, ts, err := function.ParseTimestamp(t); err == nil {
+			return ts
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// numberToTime 数值类型的时间只区分秒和毫秒
+func numberToTime(n int64) time.Time {
+	if n < 1e11 {
+		return time.Unix(n, 0)
+	}
+	return time.UnixMilli(n)
+}
+
+// lookup 优先使用完整的字段名，不存在时按照 . 逐层查找嵌套字段
+func lookup(item map[string]any, field string) (any, bool) {
+	if v, ok := item[field]; ok {
+		return v, true
+	}
+
+	var cur any = item
+	for _, key := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
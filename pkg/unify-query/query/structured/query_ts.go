@@ -392,6 +392,9 @@ type Query struct {
 
 	// HighLight 是否打开高亮，只对原始数据接口生效
 	HighLight *metadata.HighLight `json:"highlight,omitempty"`
+
+	// rollupSourceFunction 命中预聚合结果表之前的时间聚合函数
+	rollupSourceFunction string
 }
 
 func (q *Query) ToRouter() (*Route, error) {
@@ -599,11 +602,22 @@ func (q *Query) ToQueryMetric(ctx context.Context, spaceUid string) (*metadata.Q
 		IsSkipSpace:   metadata.GetUser(ctx).IsSkipSpace(),
 		IsSkipK8s:     metadata.GetQueryParams(ctx).IsSkipK8s,
 		IsSkipField:   isSkipField,
+		Rollup:        q.rollupOption(),
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// 命中预聚合结果表后时间聚合函数会发生变化，需要重新计算降采样聚合
+	if q.applyRollup(tsDBs) {
+		aggregates, err = q.Aggregates()
+		if err != nil {
+			return nil, err
+		}
+		span.Set("query-rollup-function", tsDBs[0].RollupFunction)
+		span.Set("query-time-aggregation-function", q.TimeAggregation.Function)
+	}
+
 	queryMetric.QueryList = make([]*metadata.Query, 0, len(tsDBs))
 
 	queryLabelsMatcher, _, _ := q.Conditions.ToProm()
@@ -684,6 +698,10 @@ func (q *Query) BuildMetadataQuery(
 	defer span.End(&err)
 
 	metricName := q.FieldName
+	// 预聚合结果表中的字段名为 {指标名}_{聚合方法}
+	if tsDB.RollupFunction != "" {
+		metricName = RollupFieldName(metricName, tsDB.RollupFunction)
+	}
 	expandMetricNames := tsDB.ExpandMetricNames
 	measurement = tsDB.Measurement
 	if measurement != "" {
@@ -706,6 +724,8 @@ func (q *Query) BuildMetadataQuery(
 	span.Set("tsdb-time-field", tsDB.TimeField)
 	span.Set("tsdb-need-add-time", tsDB.NeedAddTime)
 	span.Set("tsdb-source-type", tsDB.SourceType)
+	span.Set("tsdb-rollup-function", tsDB.RollupFunction)
+	span.Set("tsdb-rollup-interval", tsDB.RollupInterval.String())
 
 	if q.Offset != "" {
 		dTmp, err := model.ParseDuration(q.Offset)
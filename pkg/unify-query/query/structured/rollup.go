@@ -0,0 +1,185 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package structured
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query"
+)
+
+type rollupFunction struct {
+	// rollup 预聚合表中使用的聚合方法
+	rollup string
+	// function 查询预聚合表时替换的时间聚合函数
+	function string
+}
+
+// rollupFunctions 支持使用预聚合结果表的时间聚合函数，例如 count_over_time 需要对预聚合的 count 进行 sum_over_time
+var rollupFunctions = map[string]rollupFunction{
+	SumOT:   {rollup: "sum", function: SumOT},
+	CountOT: {rollup: "count", function: SumOT},
+	MinOT:   {rollup: "min", function: MinOT},
+	MaxOT:   {rollup: "max", function: MaxOT},
+}
+
+// RollupOption 预聚合结果表路由条件
+type RollupOption struct {
+	Step     time.Duration
+	Window   time.Duration
+	Function string
+}
+
+// RollupFieldName 预聚合结果表中的字段命名规则：{指标名}_{聚合方法}
+func RollupFieldName(field, function string) string {
+	return fmt.Sprintf("%s_%s", field, function)
+}
+
+// rollupOption 判断查询是否满足预聚合路由的前提条件，不满足时返回 nil
+func (q *Query) rollupOption() *RollupOption {
+	function := q.TimeAggregation.Function
+	// 已经命中过预聚合的查询，需要使用原始的时间聚合函数进行判断
+	if q.rollupSourceFunction != "" {
+		function = q.rollupSourceFunction
+	}
+
+	if _, ok := rollupFunctions[function]; !ok {
+		return nil
+	}
+
+	// 正则指标以及子查询无法确定预聚合字段，直接查询原始数据
+	if q.IsRegexp || q.TimeAggregation.IsSubQuery || q.FieldName == "" {
+		return nil
+	}
+
+	window, err := q.TimeAggregation.Window.Duration()
+	if err != nil || window <= 0 {
+		return nil
+	}
+
+	return &RollupOption{
+		Step:     StepParse(q.Step),
+		Window:   window,
+		Function: function,
+	}
+}
+
+// applyRollup 根据路由结果调整时间聚合函数，多次转换时保证使用的是原始的时间聚合函数
+func (q *Query) applyRollup(tsDBs []*query.TsDBV2) bool {
+	if q.rollupSourceFunction != "" {
+		q.TimeAggregation.Function = q.rollupSourceFunction
+		q.rollupSourceFunction = ""
+	}
+
+	if len(tsDBs) == 0 || tsDBs[0].RollupFunction == "" {
+		return false
+	}
+
+	q.rollupSourceFunction = q.TimeAggregation.Function
+	q.TimeAggregation.Function = rollupFunctions[q.rollupSourceFunction].function
+	return true
+}
+
+// Select 选择满足条件的最粗粒度预聚合结果表，预聚合周期需要能整除 step 和 window，才能保证聚合结果与原始数据一致
+func (o *RollupOption) Select(rollups []query.Rollup) *query.Rollup {
+	rf, ok := rollupFunctions[o.Function]
+	if !ok {
+		return nil
+	}
+
+	var res *query.Rollup
+	for i, r := range rollups {
+		if r.Interval <= 0 || r.Interval > o.Window {
+			continue
+		}
+		if o.Step%r.Interval != 0 || o.Window%r.Interval != 0 {
+			continue
+		}
+
+		support := false
+		for _, f := range r.Functions {
+			if f == rf.rollup {
+				support = true
+				break
+			}
+		}
+		if !support {
+			continue
+		}
+
+		if res == nil || r.Interval > res.Interval {
+			res = &rollups[i]
+		}
+	}
+	return res
+}
+
+// RollupTsDBs 将 tsDB 路由到预聚合结果表，只有全部 tsDB 都能命中时才进行替换，否则使用原始数据查询并记录原因
+func (s *SpaceFilter) RollupTsDBs(tsDBs []*query.TsDBV2, opt *RollupOption) []*query.TsDBV2 {
+	var (
+		hasRollup bool
+		message   string
+		rf        = rollupFunctions[opt.Function]
+		newTsDBs  = make([]*query.TsDBV2, 0, len(tsDBs))
+	)
+
+	for _, tsDB := range tsDBs {
+		if len(tsDB.Rollups) > 0 {
+			hasRollup = true
+		}
+		if message != "" {
+			continue
+		}
+
+		rollup := opt.Select(tsDB.Rollups)
+		if rollup == nil {
+			message = fmt.Sprintf("%s has no rollup matched with function: %s, step: %s, window: %s", tsDB.TableID, opt.Function, opt.Step, opt.Window)
+			continue
+		}
+
+		rtDetail := s.router.GetResultTable(s.ctx, rollup.TableID, false)
+		if rtDetail == nil {
+			message = fmt.Sprintf("rollup result table %s of %s is not exists", rollup.TableID, tsDB.TableID)
+			continue
+		}
+
+		// 保留原始结果表的空间过滤条件和指标信息，存储相关信息使用预聚合结果表
+		origin := *tsDB
+		origin.StorageClusterRecords = nil
+		newTsDB := s.getTsDBWithResultTableDetail(origin, rtDetail)
+		newTsDB.DataLabel = tsDB.DataLabel
+		newTsDB.Rollups = tsDB.Rollups
+		newTsDB.RollupFunction = rf.rollup
+		newTsDB.RollupInterval = rollup.Interval
+		newTsDB.ExpandMetricNames = make([]string, 0, len(tsDB.ExpandMetricNames))
+		for _, m := range tsDB.ExpandMetricNames {
+			newTsDB.ExpandMetricNames = append(newTsDB.ExpandMetricNames, RollupFieldName(m, rf.rollup))
+		}
+		newTsDBs = append(newTsDBs, &newTsDB)
+	}
+
+	// 未配置任何预聚合结果表时，无需提示
+	if !hasRollup {
+		return tsDBs
+	}
+
+	if message != "" {
+		log.Debugf(s.ctx, "rollup fallback to raw data: %s", message)
+		if metadata.GetStatus(s.ctx) == nil {
+			metadata.SetStatus(s.ctx, metadata.RollupNotMatched, message)
+		}
+		return tsDBs
+	}
+
+	return newTsDBs
+}
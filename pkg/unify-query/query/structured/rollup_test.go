@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package structured
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query"
+)
+
+func TestRollupOption_Select(t *testing.T) {
+	rollups := []query.Rollup{
+		{TableID: "system.cpu_1m", Interval: time.Minute, Functions: []string{"sum", "count", "min", "max"}},
+		{TableID: "system.cpu_5m", Interval: time.Minute * 5, Functions: []string{"sum", "count"}},
+		{TableID: "system.cpu_1h", Interval: time.Hour, Functions: []string{"sum", "count", "min", "max"}},
+	}
+
+	testCases := map[string]struct {
+		opt      RollupOption
+		expected string
+	}{
+		"选择最粗粒度": {
+			opt:      RollupOption{Step: time.Hour * 2, Window: time.Hour * 2, Function: SumOT},
+			expected: "system.cpu_1h",
+		},
+		"周期大于 window": {
+			opt:      RollupOption{Step: time.Minute * 10, Window: time.Minute * 10, Function: CountOT},
+			expected: "system.cpu_5m",
+		},
+		"step 无法整除": {
+			opt:      RollupOption{Step: time.Minute * 7, Window: time.Hour, Function: SumOT},
+			expected: "system.cpu_1m",
+		},
+		"聚合方法不支持": {
+			opt:      RollupOption{Step: time.Minute * 10, Window: time.Minute * 10, Function: MaxOT},
+			expected: "system.cpu_1m",
+		},
+		"不支持的时间聚合函数": {
+			opt: RollupOption{Step: time.Hour, Window: time.Hour, Function: "rate"},
+		},
+		"没有满足条件的预聚合表": {
+			opt: RollupOption{Step: time.Second * 30, Window: time.Second * 30, Function: SumOT},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := c.opt.Select(rollups)
+			if c.expected == "" {
+				assert.Nil(t, actual)
+				return
+			}
+			if assert.NotNil(t, actual) {
+				assert.Equal(t, c.expected, actual.TableID)
+			}
+		})
+	}
+}
+
+func TestQuery_ApplyRollup(t *testing.T) {
+	q := &Query{
+		FieldName: "usage",
+		Step:      "1h",
+		TimeAggregation: TimeAggregation{
+			Function: CountOT,
+			Window:   "1h",
+		},
+	}
+
+	opt := q.rollupOption()
+	if assert.NotNil(t, opt) {
+		assert.Equal(t, RollupOption{Step: time.Hour, Window: time.Hour, Function: CountOT}, *opt)
+	}
+
+	// 命中预聚合表后 count_over_time 需要转换为 sum_over_time
+	assert.True(t, q.applyRollup([]*query.TsDBV2{{RollupFunction: "count"}}))
+	assert.Equal(t, SumOT, q.TimeAggregation.Function)
+
+	// 再次转换时需要使用原始的时间聚合函数判断
+	opt = q.rollupOption()
+	if assert.NotNil(t, opt) {
+		assert.Equal(t, CountOT, opt.Function)
+	}
+
+	// 未命中时还原时间聚合函数
+	assert.False(t, q.applyRollup([]*query.TsDBV2{{}}))
+	assert.Equal(t, CountOT, q.TimeAggregation.Function)
+
+	// 正则指标不使用预聚合
+	q.IsRegexp = true
+	assert.Nil(t, q.rollupOption())
+}
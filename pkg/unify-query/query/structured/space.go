@@ -16,6 +16,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/set"
@@ -87,6 +90,20 @@ func (s *SpaceFilter) getTsDBWithResultTableDetail(t query.TsDBV2, d *routerInfl
 	t.NeedAddTime = d.Options.NeedAddTime
 	t.SourceType = d.SourceType
 
+	t.Rollups = make([]query.Rollup, 0, len(d.Rollups))
+	for _, r := range d.Rollups {
+		interval, err := model.ParseDuration(r.Interval)
+		if err != nil || interval <= 0 {
+			log.Warnf(s.ctx, "rollup interval %s of %s is invalid", r.Interval, r.TableId)
+			continue
+		}
+		t.Rollups = append(t.Rollups, query.Rollup{
+			TableID:   r.TableId,
+			Interval:  time.Duration(interval),
+			Functions: r.Functions,
+		})
+	}
+
 	sort.SliceStable(d.StorageClusterRecords, func(i, j int) bool {
 		return d.StorageClusterRecords[i].EnableTime > d.StorageClusterRecords[j].EnableTime
 	})
@@ -332,6 +349,8 @@ type TsDBOption struct {
 	// IsRegexp 指标是否使用正则查询
 	IsRegexp      bool
 	AllConditions AllConditions
+	// Rollup 预聚合结果表路由条件，为空则不进行预聚合路由
+	Rollup *RollupOption
 }
 
 type TsDBs []*query.TsDBV2
@@ -355,5 +374,9 @@ func GetTsDBList(ctx context.Context, option *TsDBOption) (TsDBs, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if option.Rollup != nil {
+		tsDBs = spaceFilter.RollupTsDBs(tsDBs, option.Rollup)
+	}
 	return tsDBs, nil
 }
@@ -0,0 +1,188 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"strconv"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+// span 结果表中的字段名，与 apm 预计算写入的格式保持一致，时间单位为微秒
+const (
+	FieldTraceID      = "trace_id"
+	FieldSpanID       = "span_id"
+	FieldParentSpanID = "parent_span_id"
+	FieldSpanName     = "span_name"
+	FieldStartTime    = "start_time"
+	FieldEndTime      = "end_time"
+	FieldElapsedTime  = "elapsed_time"
+	FieldStatusCode   = "status.code"
+	FieldKind         = "kind"
+	FieldServiceName  = "resource.service.name"
+
+	attributesPrefix = "attributes."
+	resourcePrefix   = "resource."
+)
+
+// MaxConditionGroups 展开为析取范式后允许的最大条件组数量，避免生成过大的存储查询
+const MaxConditionGroups = 64
+
+// Fields 获取属性对应的存储字段，未指定作用域的属性同时匹配 attributes 和 resource
+func (a Attribute) Fields() []string {
+	switch a.Scope {
+	case ScopeSpan:
+		return []string{attributesPrefix + a.Name}
+	case ScopeResource:
+		return []string{resourcePrefix + a.Name}
+	case ScopeIntrinsic:
+		switch a.Name {
+		case IntrinsicName:
+			return []string{FieldSpanName}
+		case IntrinsicStatus:
+			return []string{FieldStatusCode}
+		case IntrinsicDuration:
+			return []string{FieldElapsedTime}
+		case IntrinsicKind:
+			return []string{FieldKind}
+		}
+		return nil
+	default:
+		return []string{attributesPrefix + a.Name, resourcePrefix + a.Name}
+	}
+}
+
+// StorageValue 常量在存储中的表示
+func (s Static) StorageValue() string {
+	switch s.Type {
+	case TypeNumber:
+		return strconv.FormatFloat(s.N, 'f', -1, 64)
+	case TypeDuration:
+		return strconv.FormatInt(s.Duration.Microseconds(), 10)
+	case TypeBool:
+		return strconv.FormatBool(s.B)
+	case TypeStatus:
+		return strconv.Itoa(statusValues[s.S])
+	case TypeKind:
+		return strconv.Itoa(kindValues[s.S])
+	default:
+		return s.S
+	}
+}
+
+// negative 是否为否定操作符
+func (op Operator) negative() bool {
+	return op == OpNotEqual || op == OpNotRegex
+}
+
+func (op Operator) condition() string {
+	switch op {
+	case OpNotEqual:
+		return structured.ConditionNotEqual
+	case OpRegex:
+		return structured.ConditionRegEqual
+	case OpNotRegex:
+		return structured.ConditionNotRegEqual
+	case OpGt:
+		return structured.ConditionGt
+	case OpGte:
+		return structured.ConditionGte
+	case OpLt:
+		return structured.ConditionLt
+	case OpLte:
+		return structured.ConditionLte
+	default:
+		return structured.ConditionEqual
+	}
+}
+
+// Conditions 将 spanset 过滤条件转换为 structured 的查询条件，由 es / doris 等存储各自翻译为查询语句
+// 因为 structured.Conditions 只支持 or 连接的 and 条件组，所以这里会先将表达式展开为析取范式
+func (e *SpansetFilter) Conditions() (structured.Conditions, error) {
+	var conditions structured.Conditions
+	if e.Filter == nil {
+		return conditions, nil
+	}
+
+	groups, err := toDNF(e.Filter)
+	if err != nil {
+		return conditions, err
+	}
+
+	for i, group := range groups {
+		for j, field := range group {
+			if len(conditions.FieldList) > 0 {
+				if i > 0 && j == 0 {
+					conditions.ConditionList = append(conditions.ConditionList, structured.ConditionOr)
+				} else {
+					conditions.ConditionList = append(conditions.ConditionList, structured.ConditionAnd)
+				}
+			}
+			conditions.FieldList = append(conditions.FieldList, field)
+		}
+	}
+	return conditions, nil
+}
+
+func toDNF(expr FieldExpr) ([][]structured.ConditionField, error) {
+	switch e := expr.(type) {
+	case *Comparison:
+		fields := e.Attribute.Fields()
+		conditions := make([]structured.ConditionField, 0, len(fields))
+		for _, f := range fields {
+			conditions = append(conditions, structured.ConditionField{
+				DimensionName: f,
+				Value:         []string{e.Value.StorageValue()},
+				Operator:      e.Op.condition(),
+			})
+		}
+
+		// 否定条件需要所有字段都不满足，放在同一个 and 条件组中；肯定条件任意字段满足即可，每个字段一组
+		if e.Op.negative() {
+			return [][]structured.ConditionField{conditions}, nil
+		}
+		groups := make([][]structured.ConditionField, 0, len(conditions))
+		for _, c := range conditions {
+			groups = append(groups, []structured.ConditionField{c})
+		}
+		return groups, nil
+	case *BinaryFieldExpr:
+		lhs, err := toDNF(e.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := toDNF(e.RHS)
+		if err != nil {
+			return nil, err
+		}
+
+		var groups [][]structured.ConditionField
+		if e.Op == LogicOr {
+			groups = append(lhs, rhs...)
+		} else {
+			groups = make([][]structured.ConditionField, 0, len(lhs)*len(rhs))
+			for _, l := range lhs {
+				for _, r := range rhs {
+					group := make([]structured.ConditionField, 0, len(l)+len(r))
+					group = append(group, l...)
+					group = append(group, r...)
+					groups = append(groups, group)
+				}
+			}
+		}
+
+		if len(groups) > MaxConditionGroups {
+			return nil, ErrTooManyConditions
+		}
+		return groups, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,23 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrEmptyQuery        = errors.New("traceql is empty")
+	ErrSyntax            = errors.New("traceql syntax error")
+	ErrUnknownAttribute  = errors.New("unknown traceql attribute")
+	ErrInvalidComparison = errors.New("invalid traceql comparison")
+	ErrTooManyConditions = errors.New("too many conditions after expanding traceql filter")
+	ErrEmptyTableID      = errors.New("table id is empty")
+)
@@ -0,0 +1,266 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Operator 字段比较操作符
+type Operator string
+
+const (
+	OpEqual    Operator = "="
+	OpNotEqual Operator = "!="
+	OpRegex    Operator = "=~"
+	OpNotRegex Operator = "!~"
+	OpGt       Operator = ">"
+	OpGte      Operator = ">="
+	OpLt       Operator = "<"
+	OpLte      Operator = "<="
+)
+
+// LogicOp spanset 内部字段条件的组合方式
+type LogicOp string
+
+const (
+	LogicAnd LogicOp = "&&"
+	LogicOr  LogicOp = "||"
+)
+
+// SpansetOp spanset 之间的组合方式，包含逻辑组合以及结构化组合
+type SpansetOp string
+
+const (
+	SpansetAnd        SpansetOp = "&&"
+	SpansetOr         SpansetOp = "||"
+	SpansetChild      SpansetOp = ">"
+	SpansetDescendant SpansetOp = ">>"
+	SpansetSibling    SpansetOp = "~"
+)
+
+// Scope 属性作用域
+type Scope string
+
+const (
+	ScopeNone      Scope = ""
+	ScopeSpan      Scope = "span"
+	ScopeResource  Scope = "resource"
+	ScopeIntrinsic Scope = "intrinsic"
+)
+
+// 内置字段
+const (
+	IntrinsicName     = "name"
+	IntrinsicStatus   = "status"
+	IntrinsicDuration = "duration"
+	IntrinsicKind     = "kind"
+)
+
+// StaticType 常量类型
+type StaticType int
+
+const (
+	TypeString StaticType = iota
+	TypeNumber
+	TypeDuration
+	TypeBool
+	TypeStatus
+	TypeKind
+)
+
+// Expr spanset 表达式，执行后返回 trace 中命中的 span 列表
+type Expr interface {
+	fmt.Stringer
+	Eval(t *Trace) []*Span
+}
+
+// FieldExpr spanset 内部的字段过滤表达式
+type FieldExpr interface {
+	fmt.Stringer
+	Match(s *Span) bool
+}
+
+// SpansetFilter 花括号包裹的 span 过滤条件，Filter 为空时匹配所有 span
+type SpansetFilter struct {
+	Filter FieldExpr
+}
+
+// SpansetOperation 两个 spanset 之间的组合
+type SpansetOperation struct {
+	Op  SpansetOp
+	LHS Expr
+	RHS Expr
+}
+
+// BinaryFieldExpr 字段条件的逻辑组合
+type BinaryFieldExpr struct {
+	Op  LogicOp
+	LHS FieldExpr
+	RHS FieldExpr
+}
+
+// Attribute 属性引用，例如 span.http.method、resource.service.name、.foo、duration
+type Attribute struct {
+	Scope Scope
+	Name  string
+}
+
+// Static 常量值
+type Static struct {
+	Type     StaticType
+	S        string
+	N        float64
+	B        bool
+	Duration time.Duration
+}
+
+// Comparison 单个字段比较条件
+type Comparison struct {
+	Attribute Attribute
+	Op        Operator
+	Value     Static
+
+	re *regexp.Regexp
+}
+
+func (e *SpansetFilter) String() string {
+	if e.Filter == nil {
+		return "{ }"
+	}
+	return fmt.Sprintf("{ %s }", e.Filter.String())
+}
+
+func (e *SpansetOperation) String() string {
+	return fmt.Sprintf("(%s) %s (%s)", e.LHS.String(), e.Op, e.RHS.String())
+}
+
+func (e *BinaryFieldExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.LHS.String(), e.Op, e.RHS.String())
+}
+
+func (e *Comparison) String() string {
+	return fmt.Sprintf("%s %s %s", e.Attribute.String(), e.Op, e.Value.String())
+}
+
+func (a Attribute) String() string {
+	switch a.Scope {
+	case ScopeIntrinsic:
+		return a.Name
+	case ScopeNone:
+		return "." + a.Name
+	default:
+		return string(a.Scope) + "." + a.Name
+	}
+}
+
+func (s Static) String() string {
+	switch s.Type {
+	case TypeNumber:
+		return strconv.FormatFloat(s.N, 'f', -1, 64)
+	case TypeDuration:
+		return s.Duration.String()
+	case TypeBool:
+		return strconv.FormatBool(s.B)
+	case TypeStatus, TypeKind:
+		return s.S
+	default:
+		return strconv.Quote(s.S)
+	}
+}
+
+// CandidateTraceIDs 按照 spanset 组合关系合并每个过滤条件查询出的 TraceID：|| 取并集，&& 以及结构化操作符取交集
+// 空过滤条件 {} 匹配所有 span，不参与交集，只有整个表达式都不包含过滤条件时才会查询
+func CandidateTraceIDs(expr Expr, fetch func(filter *SpansetFilter) ([]string, error)) ([]string, error) {
+	c, err := candidateTraceIDs(expr, fetch)
+	if err != nil {
+		return nil, err
+	}
+	if c.all {
+		return fetch(&SpansetFilter{})
+	}
+	return c.ids, nil
+}
+
+// candidates 候选 TraceID，all 表示不限制
+type candidates struct {
+	all bool
+	ids []string
+}
+
+func candidateTraceIDs(expr Expr, fetch func(filter *SpansetFilter) ([]string, error)) (candidates, error) {
+	switch e := expr.(type) {
+	case *SpansetFilter:
+		if e.Filter == nil {
+			return candidates{all: true}, nil
+		}
+		ids, err := fetch(e)
+		return candidates{ids: ids}, err
+	case *SpansetOperation:
+		lhs, err := candidateTraceIDs(e.LHS, fetch)
+		if err != nil {
+			return lhs, err
+		}
+		rhs, err := candidateTraceIDs(e.RHS, fetch)
+		if err != nil {
+			return rhs, err
+		}
+
+		if e.Op == SpansetOr {
+			if lhs.all || rhs.all {
+				return candidates{all: true}, nil
+			}
+			return candidates{ids: unionTraceIDs(lhs.ids, rhs.ids)}, nil
+		}
+
+		switch {
+		case lhs.all:
+			return rhs, nil
+		case rhs.all:
+			return lhs, nil
+		}
+		return candidates{ids: intersectTraceIDs(lhs.ids, rhs.ids)}, nil
+	default:
+		return candidates{}, nil
+	}
+}
+
+func unionTraceIDs(a, b []string) []string {
+	set := make(map[string]struct{}, len(a)+len(b))
+	res := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, id := range list {
+			if _, ok := set[id]; ok {
+				continue
+			}
+			set[id] = struct{}{}
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+func intersectTraceIDs(a, b []string) []string {
+	set := make(map[string]struct{}, len(b))
+	for _, id := range b {
+		set[id] = struct{}{}
+	}
+	res := make([]string, 0)
+	for _, id := range a {
+		if _, ok := set[id]; ok {
+			res = append(res, id)
+			delete(set, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,165 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenLBrace
+	tokenRBrace
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenOperator
+	tokenStructural
+	tokenIdentifier
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// lex 将 traceql 语句切分为 token 列表
+func lex(input string) ([]token, error) {
+	var (
+		tokens []token
+		rs     = []rune(input)
+	)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '{':
+			tokens = append(tokens, token{typ: tokenLBrace, val: "{", pos: i})
+			i++
+		case r == '}':
+			tokens = append(tokens, token{typ: tokenRBrace, val: "}", pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{typ: tokenLParen, val: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{typ: tokenRParen, val: ")", pos: i})
+			i++
+		case r == '&':
+			if i+1 >= len(rs) || rs[i+1] != '&' {
+				return nil, fmt.Errorf("%w: unexpected '&' at %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{typ: tokenAnd, val: "&&", pos: i})
+			i += 2
+		case r == '|':
+			if i+1 >= len(rs) || rs[i+1] != '|' {
+				return nil, fmt.Errorf("%w: unexpected '|' at %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{typ: tokenOr, val: "||", pos: i})
+			i += 2
+		case r == '~':
+			tokens = append(tokens, token{typ: tokenStructural, val: "~", pos: i})
+			i++
+		case r == '=' || r == '!' || r == '>' || r == '<':
+			val := string(r)
+			if i+1 < len(rs) {
+				next := rs[i+1]
+				switch {
+				case r == '=' && next == '~', r == '!' && (next == '~' || next == '='),
+					r == '>' && (next == '=' || next == '>'), r == '<' && next == '=':
+					val += string(next)
+				}
+			}
+			if val == "!" {
+				return nil, fmt.Errorf("%w: unexpected '!' at %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{typ: tokenOperator, val: val, pos: i})
+			i += len([]rune(val))
+		case r == '"' || r == '`':
+			s, n, err := lexString(rs[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at %d", err, i)
+			}
+			tokens = append(tokens, token{typ: tokenString, val: s, pos: i})
+			i += n
+		case r == '-' || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{typ: tokenNumber, val: string(rs[i:j]), pos: i})
+			i = j
+		case r == '.' || r == '_' || unicode.IsLetter(r):
+			j := i + 1
+			for j < len(rs) && isIdentifierRune(rs[j]) {
+				j++
+			}
+			tokens = append(tokens, token{typ: tokenIdentifier, val: string(rs[i:j]), pos: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, r, i)
+		}
+	}
+
+	tokens = append(tokens, token{typ: tokenEOF, pos: len(rs)})
+	return tokens, nil
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '.' || r == '_' || r == '-' || r == '/' || r == ':' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lexString 解析字符串常量，返回解析后的值以及消耗的字符数
+func lexString(rs []rune) (string, int, error) {
+	var (
+		quote = rs[0]
+		sb    strings.Builder
+	)
+
+	for i := 1; i < len(rs); i++ {
+		r := rs[i]
+		// 反引号字符串不做转义处理
+		if quote == '"' && r == '\\' && i+1 < len(rs) {
+			i++
+			switch rs[i] {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(rs[i])
+			}
+			continue
+		}
+		if r == quote {
+			return sb.String(), i + 1, nil
+		}
+		sb.WriteRune(r)
+	}
+
+	return "", 0, fmt.Errorf("%w: unterminated string", ErrSyntax)
+}
@@ -0,0 +1,351 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	statusValues = map[string]int{
+		"unset": 0,
+		"ok":    1,
+		"error": 2,
+	}
+
+	kindValues = map[string]int{
+		"unspecified": 0,
+		"internal":    1,
+		"server":      2,
+		"client":      3,
+		"producer":    4,
+		"consumer":    5,
+	}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse 解析 traceql 语句，支持的语法如下：
+//
+//	{ span.http.method = "GET" && duration > 100ms }
+//	{ resource.service.name = "api" } >> { status = error }
+//	({ .foo = "bar" } || { name =~ "db.*" }) && { kind = server }
+//
+// spanset 组合操作符优先级：结构化操作符(>、>>、~) 高于 && 高于 ||
+func Parse(q string) (Expr, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	tokens, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseSpansetOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(typ tokenType, val string) error {
+	t := p.next()
+	if t.typ != typ {
+		return fmt.Errorf("%w: expected %q, got %s at %d", ErrSyntax, val, t, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return fmt.Errorf("%w: unexpected %s at %d", ErrSyntax, t, t.pos)
+}
+
+func (p *parser) parseSpansetOr() (Expr, error) {
+	lhs, err := p.parseSpansetAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenOr {
+		p.next()
+		rhs, err := p.parseSpansetAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: SpansetOr, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseSpansetAnd() (Expr, error) {
+	lhs, err := p.parseStructural()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenAnd {
+		p.next()
+		rhs, err := p.parseStructural()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: SpansetAnd, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseStructural() (Expr, error) {
+	lhs, err := p.parseSpansetPrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		var op SpansetOp
+		switch {
+		case t.typ == tokenStructural && t.val == "~":
+			op = SpansetSibling
+		case t.typ == tokenOperator && t.val == ">":
+			op = SpansetChild
+		case t.typ == tokenOperator && t.val == ">>":
+			op = SpansetDescendant
+		default:
+			return lhs, nil
+		}
+		p.next()
+
+		rhs, err := p.parseSpansetPrimary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: op, LHS: lhs, RHS: rhs}
+	}
+}
+
+func (p *parser) parseSpansetPrimary() (Expr, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenLParen:
+		expr, err := p.parseSpansetOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case tokenLBrace:
+		filter := &SpansetFilter{}
+		if p.peek().typ == tokenRBrace {
+			p.next()
+			return filter, nil
+		}
+		expr, err := p.parseFieldOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRBrace, "}"); err != nil {
+			return nil, err
+		}
+		filter.Filter = expr
+		return filter, nil
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *parser) parseFieldOr() (FieldExpr, error) {
+	lhs, err := p.parseFieldAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenOr {
+		p.next()
+		rhs, err := p.parseFieldAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryFieldExpr{Op: LogicOr, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseFieldAnd() (FieldExpr, error) {
+	lhs, err := p.parseFieldPrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenAnd {
+		p.next()
+		rhs, err := p.parseFieldPrimary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryFieldExpr{Op: LogicAnd, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseFieldPrimary() (FieldExpr, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenLParen:
+		expr, err := p.parseFieldOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case tokenIdentifier:
+		return p.parseComparison(t)
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *parser) parseComparison(t token) (FieldExpr, error) {
+	attr, err := parseAttribute(t.val)
+	if err != nil {
+		return nil, fmt.Errorf("%w at %d", err, t.pos)
+	}
+
+	opToken := p.next()
+	if opToken.typ != tokenOperator {
+		return nil, p.unexpected(opToken)
+	}
+	op := Operator(opToken.val)
+	switch op {
+	case OpEqual, OpNotEqual, OpRegex, OpNotRegex, OpGt, OpGte, OpLt, OpLte:
+	default:
+		return nil, fmt.Errorf("%w: unknown operator %s at %d", ErrSyntax, opToken, opToken.pos)
+	}
+
+	valueToken := p.next()
+	value, err := parseStatic(attr, valueToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w at %d", err, valueToken.pos)
+	}
+
+	c := &Comparison{
+		Attribute: attr,
+		Op:        op,
+		Value:     value,
+	}
+	if err = c.validate(); err != nil {
+		return nil, fmt.Errorf("%w at %d", err, t.pos)
+	}
+	return c, nil
+}
+
+func parseAttribute(s string) (Attribute, error) {
+	switch {
+	case strings.HasPrefix(s, "span."):
+		return Attribute{Scope: ScopeSpan, Name: strings.TrimPrefix(s, "span.")}, nil
+	case strings.HasPrefix(s, "resource."):
+		return Attribute{Scope: ScopeResource, Name: strings.TrimPrefix(s, "resource.")}, nil
+	case strings.HasPrefix(s, ".") && len(s) > 1:
+		return Attribute{Scope: ScopeNone, Name: s[1:]}, nil
+	}
+
+	switch s {
+	case IntrinsicName, IntrinsicStatus, IntrinsicDuration, IntrinsicKind:
+		return Attribute{Scope: ScopeIntrinsic, Name: s}, nil
+	}
+	return Attribute{}, fmt.Errorf("%w: %s", ErrUnknownAttribute, s)
+}
+
+func parseStatic(attr Attribute, t token) (Static, error) {
+	switch t.typ {
+	case tokenString:
+		return Static{Type: TypeString, S: t.val}, nil
+	case tokenNumber:
+		if n, err := strconv.ParseFloat(t.val, 64); err == nil {
+			// duration 与不带单位的数字比较时（如 duration > 0），按纳秒处理
+			if attr.Scope == ScopeIntrinsic && attr.Name == IntrinsicDuration {
+				return Static{Type: TypeDuration, Duration: time.Duration(n)}, nil
+			}
+			return Static{Type: TypeNumber, N: n}, nil
+		}
+		if d, err := time.ParseDuration(t.val); err == nil {
+			return Static{Type: TypeDuration, Duration: d}, nil
+		}
+		return Static{}, fmt.Errorf("%w: invalid number %s", ErrSyntax, t)
+	case tokenIdentifier:
+		switch {
+		case t.val == "true" || t.val == "false":
+			return Static{Type: TypeBool, B: t.val == "true"}, nil
+		case attr.Scope == ScopeIntrinsic && attr.Name == IntrinsicStatus:
+			if _, ok := statusValues[t.val]; ok {
+				return Static{Type: TypeStatus, S: t.val}, nil
+			}
+		case attr.Scope == ScopeIntrinsic && attr.Name == IntrinsicKind:
+			if _, ok := kindValues[t.val]; ok {
+				return Static{Type: TypeKind, S: t.val}, nil
+			}
+		}
+	}
+	return Static{}, fmt.Errorf("%w: invalid value %s", ErrSyntax, t)
+}
+
+// validate 校验操作符与值的类型是否匹配，并预编译正则
+func (c *Comparison) validate() error {
+	if c.Attribute.Scope == ScopeIntrinsic {
+		switch c.Attribute.Name {
+		case IntrinsicDuration:
+			if c.Value.Type != TypeDuration {
+				return fmt.Errorf("%w: duration must compare with a duration value", ErrInvalidComparison)
+			}
+		case IntrinsicStatus, IntrinsicKind:
+			if c.Value.Type != TypeStatus && c.Value.Type != TypeKind {
+				return fmt.Errorf("%w: %s must compare with a %s literal", ErrInvalidComparison, c.Attribute.Name, c.Attribute.Name)
+			}
+			if c.Op != OpEqual && c.Op != OpNotEqual {
+				return fmt.Errorf("%w: %s only support = and !=", ErrInvalidComparison, c.Attribute.Name)
+			}
+		}
+	}
+
+	if c.Op == OpRegex || c.Op == OpNotRegex {
+		if c.Value.Type != TypeString {
+			return fmt.Errorf("%w: regex must compare with a string value", ErrInvalidComparison)
+		}
+		re, err := regexp.Compile("^(?:" + c.Value.S + ")$")
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidComparison, err.Error())
+		}
+		c.re = re
+	}
+	return nil
+}
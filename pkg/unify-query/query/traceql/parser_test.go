@@ -0,0 +1,177 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		q   string
+		s   string
+		err error
+	}{
+		"空过滤条件": {
+			q: `{}`,
+			s: `{ }`,
+		},
+		"单个条件": {
+			q: `{ span.http.method = "GET" }`,
+			s: `{ span.http.method = "GET" }`,
+		},
+		"内置字段": {
+			q: `{ duration > 100ms && status = error && kind != client }`,
+			s: `{ ((duration > 100ms && status = error) && kind != client) }`,
+		},
+		"字段逻辑优先级": {
+			q: `{ .a = 1 || .b = "x" && name =~ "db.*" }`,
+			s: `{ (.a = 1 || (.b = "x" && name =~ "db.*")) }`,
+		},
+		"结构化操作符": {
+			q: `{ resource.service.name = "api" } >> { status = error } && { } > { .c = true }`,
+			s: `(({ resource.service.name = "api" }) >> ({ status = error })) && (({ }) > ({ .c = true }))`,
+		},
+		"括号": {
+			q: `({ name = "a" } || { name = "b" }) ~ { name = "c" }`,
+			s: `(({ name = "a" }) || ({ name = "b" })) ~ ({ name = "c" })`,
+		},
+		"空语句": {
+			q:   ` `,
+			err: ErrEmptyQuery,
+		},
+		"未知字段": {
+			q:   `{ foo = "bar" }`,
+			err: ErrUnknownAttribute,
+		},
+		"duration 与数字比较": {
+			q: `{ duration > 0 }`,
+			s: `{ duration > 0s }`,
+		},
+		"duration 类型错误": {
+			q:   `{ duration > "1s" }`,
+			err: ErrInvalidComparison,
+		},
+		"status 操作符错误": {
+			q:   `{ status > error }`,
+			err: ErrInvalidComparison,
+		},
+		"括号不匹配": {
+			q:   `{ name = "a" `,
+			err: ErrSyntax,
+		},
+		"字符串未结束": {
+			q:   `{ name = "a }`,
+			err: ErrSyntax,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, err := Parse(c.q)
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			if err == nil {
+				assert.Equal(t, c.s, expr.String())
+			}
+		})
+	}
+}
+
+func TestSpansetFilterConditions(t *testing.T) {
+	testCases := map[string]struct {
+		q          string
+		conditions structured.Conditions
+		err        error
+	}{
+		"空过滤条件": {
+			q: `{}`,
+		},
+		"内置字段转换": {
+			q: `{ duration >= 1.5ms && status = error && kind = server }`,
+			conditions: structured.Conditions{
+				FieldList: []structured.ConditionField{
+					{DimensionName: FieldElapsedTime, Value: []string{"1500"}, Operator: structured.ConditionGte},
+					{DimensionName: FieldStatusCode, Value: []string{"2"}, Operator: structured.ConditionEqual},
+					{DimensionName: FieldKind, Value: []string{"2"}, Operator: structured.ConditionEqual},
+				},
+				ConditionList: []string{structured.ConditionAnd, structured.ConditionAnd},
+			},
+		},
+		"未指定作用域展开": {
+			q: `{ .http.method = "GET" && name !~ "health.*" }`,
+			conditions: structured.Conditions{
+				FieldList: []structured.ConditionField{
+					{DimensionName: "attributes.http.method", Value: []string{"GET"}, Operator: structured.ConditionEqual},
+					{DimensionName: FieldSpanName, Value: []string{"health.*"}, Operator: structured.ConditionNotRegEqual},
+					{DimensionName: "resource.http.method", Value: []string{"GET"}, Operator: structured.ConditionEqual},
+					{DimensionName: FieldSpanName, Value: []string{"health.*"}, Operator: structured.ConditionNotRegEqual},
+				},
+				ConditionList: []string{structured.ConditionAnd, structured.ConditionOr, structured.ConditionAnd},
+			},
+		},
+		"未指定作用域否定条件": {
+			q: `{ .http.method != "GET" }`,
+			conditions: structured.Conditions{
+				FieldList: []structured.ConditionField{
+					{DimensionName: "attributes.http.method", Value: []string{"GET"}, Operator: structured.ConditionNotEqual},
+					{DimensionName: "resource.http.method", Value: []string{"GET"}, Operator: structured.ConditionNotEqual},
+				},
+				ConditionList: []string{structured.ConditionAnd},
+			},
+		},
+		"分配律展开": {
+			q: `{ (span.a = "1" || span.b = "2") && resource.c < 3 }`,
+			conditions: structured.Conditions{
+				FieldList: []structured.ConditionField{
+					{DimensionName: "attributes.a", Value: []string{"1"}, Operator: structured.ConditionEqual},
+					{DimensionName: "resource.c", Value: []string{"3"}, Operator: structured.ConditionLt},
+					{DimensionName: "attributes.b", Value: []string{"2"}, Operator: structured.ConditionEqual},
+					{DimensionName: "resource.c", Value: []string{"3"}, Operator: structured.ConditionLt},
+				},
+				ConditionList: []string{structured.ConditionAnd, structured.ConditionOr, structured.ConditionAnd},
+			},
+		},
+		"条件过多": {
+			q:   `{ (.a = 1 || .b = 1) && (.c = 1 || .d = 1) && (.e = 1 || .f = 1) && (.g = 1 || .h = 1) }`,
+			err: ErrTooManyConditions,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, err := Parse(c.q)
+			assert.Nil(t, err)
+
+			filter, ok := expr.(*SpansetFilter)
+			assert.True(t, ok)
+
+			conditions, err := filter.Conditions()
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.conditions, conditions)
+
+			// 生成的条件需要能被 structured 正常解析
+			_, err = conditions.AnalysisConditions()
+			assert.Nil(t, err)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+const (
+	DefaultTraceLimit = 20
+	DefaultSpanLimit  = 1000
+
+	// candidateFactor 候选 trace 数量相对于返回 trace 数量的倍数，结构化条件需要在完整 trace 上二次过滤
+	candidateFactor = 5
+)
+
+// QueryTraceQL trace 检索请求
+type QueryTraceQL struct {
+	// SpaceUid 空间ID
+	SpaceUid string `json:"space_uid,omitempty"`
+	// TableID span 结果表
+	TableID structured.TableID `json:"table_id" example:"2_bkapm_trace_demo.__default__"`
+	// TraceQL 检索语句
+	TraceQL string `json:"traceql" example:"{ resource.service.name = \"api\" } >> { status = error }"`
+	// Start 开始时间：单位为任意长度的时间戳
+	Start string `json:"start_time,omitempty" example:"1657848000"`
+	// End 结束时间：单位为任意长度的时间戳
+	End string `json:"end_time,omitempty" example:"1657851600"`
+	// Timezone 时区
+	Timezone string `json:"timezone,omitempty" example:"Asia/Shanghai"`
+	// Limit 返回 trace 数量
+	Limit int `json:"limit,omitempty" example:"20"`
+	// SpanLimit 每个过滤条件查询的最大 span 数量，同时也是单个 trace 的最大 span 数量
+	SpanLimit int `json:"span_limit,omitempty" example:"1000"`
+}
+
+// CandidateLimit 候选 trace 数量
+func (q *QueryTraceQL) CandidateLimit() int {
+	return q.Limit * candidateFactor
+}
+
+// SpanQueryTs 生成查询满足过滤条件 span 的请求，按开始时间倒序优先返回最新的 span
+func (q *QueryTraceQL) SpanQueryTs(filter *SpansetFilter) (*structured.QueryTs, error) {
+	conditions, err := filter.Conditions()
+	if err != nil {
+		return nil, err
+	}
+
+	return q.queryTs(conditions, []string{FieldTraceID}), nil
+}
+
+// TraceQueryTs 生成查询单个完整 trace 的请求，SpanLimit 作为单个 trace 的 span 数量上限，按开始时间正序保证根节点优先返回
+func (q *QueryTraceQL) TraceQueryTs(traceID string) *structured.QueryTs {
+	conditions := structured.Conditions{
+		FieldList: []structured.ConditionField{
+			{
+				DimensionName: FieldTraceID,
+				Value:         []string{traceID},
+				Operator:      structured.ConditionEqual,
+			},
+		},
+	}
+
+	queryTs := q.queryTs(conditions, nil)
+	queryTs.OrderBy = structured.OrderBy{FieldStartTime}
+	return queryTs
+}
+
+func (q *QueryTraceQL) queryTs(conditions structured.Conditions, keepColumns []string) *structured.QueryTs {
+	return &structured.QueryTs{
+		SpaceUid: q.SpaceUid,
+		QueryList: []*structured.Query{
+			{
+				TableID:       q.TableID,
+				Conditions:    conditions,
+				KeepColumns:   keepColumns,
+				ReferenceName: "a",
+			},
+		},
+		OrderBy:  structured.OrderBy{"-" + FieldStartTime},
+		Start:    q.Start,
+		End:      q.End,
+		Timezone: q.Timezone,
+		Limit:    q.SpanLimit,
+	}
+}
@@ -0,0 +1,385 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Span 从原始数据中解析出的 span，原始数据为 es 返回的扁平化结构
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	ServiceName  string
+	StartTime    int64
+	EndTime      int64
+	Duration     int64
+	StatusCode   int
+	Kind         int
+
+	Data map[string]any
+}
+
+// Trace 同一个 TraceID 下的所有 span
+type Trace struct {
+	TraceID string
+	Spans   []*Span
+
+	spans map[string]*Span
+}
+
+// TraceSummary trace 概要信息
+type TraceSummary struct {
+	TraceID         string           `json:"trace_id"`
+	RootServiceName string           `json:"root_service_name"`
+	RootSpanName    string           `json:"root_span_name"`
+	StartTime       int64            `json:"start_time"`
+	EndTime         int64            `json:"end_time"`
+	Duration        int64            `json:"duration"`
+	SpanCount       int              `json:"span_count"`
+	ErrorCount      int              `json:"error_count"`
+	ServiceNames    []string         `json:"service_names"`
+	MatchedSpans    []map[string]any `json:"matched_spans"`
+}
+
+// NewSpan 将原始数据转换为 span
+func NewSpan(data map[string]any) *Span {
+	s := &Span{
+		TraceID:      toString(data[FieldTraceID]),
+		SpanID:       toString(data[FieldSpanID]),
+		ParentSpanID: toString(data[FieldParentSpanID]),
+		Name:         toString(data[FieldSpanName]),
+		ServiceName:  toString(data[FieldServiceName]),
+		Data:         data,
+	}
+
+	if v, ok := toFloat(data[FieldStartTime]); ok {
+		s.StartTime = int64(v)
+	}
+	if v, ok := toFloat(data[FieldEndTime]); ok {
+		s.EndTime = int64(v)
+	}
+	if v, ok := toFloat(data[FieldElapsedTime]); ok {
+		s.Duration = int64(v)
+	} else {
+		s.Duration = s.EndTime - s.StartTime
+	}
+	if v, ok := toFloat(data[FieldStatusCode]); ok {
+		s.StatusCode = int(v)
+	}
+	if v, ok := toFloat(data[FieldKind]); ok {
+		s.Kind = int(v)
+	}
+	return s
+}
+
+// NewTraces 将 span 列表按照 TraceID 组装成 trace，按首次出现的顺序返回
+func NewTraces(list []map[string]any) []*Trace {
+	var (
+		traces []*Trace
+		index  = make(map[string]*Trace)
+	)
+
+	for _, data := range list {
+		s := NewSpan(data)
+		if s.TraceID == "" {
+			continue
+		}
+
+		t, ok := index[s.TraceID]
+		if !ok {
+			t = &Trace{
+				TraceID: s.TraceID,
+				spans:   make(map[string]*Span),
+			}
+			index[s.TraceID] = t
+			traces = append(traces, t)
+		}
+
+		// 相同 span 可能在多个索引中重复出现，只保留一份
+		if s.SpanID != "" {
+			if _, exists := t.spans[s.SpanID]; exists {
+				continue
+			}
+			t.spans[s.SpanID] = s
+		}
+		t.Spans = append(t.Spans, s)
+	}
+	return traces
+}
+
+// Parent 获取父 span，不存在时返回 nil
+func (t *Trace) Parent(s *Span) *Span {
+	if s.ParentSpanID == "" {
+		return nil
+	}
+	return t.spans[s.ParentSpanID]
+}
+
+// Root 获取根 span，没有父 span 的节点中开始时间最早的一个
+func (t *Trace) Root() *Span {
+	var root *Span
+	for _, s := range t.Spans {
+		if t.Parent(s) != nil {
+			continue
+		}
+		if root == nil || s.StartTime < root.StartTime {
+			root = s
+		}
+	}
+	return root
+}
+
+// Summary 生成 trace 概要信息
+func (t *Trace) Summary(matched []*Span) *TraceSummary {
+	summary := &TraceSummary{
+		TraceID:      t.TraceID,
+		SpanCount:    len(t.Spans),
+		MatchedSpans: make([]map[string]any, 0, len(matched)),
+	}
+
+	services := make(map[string]struct{})
+	for i, s := range t.Spans {
+		if i == 0 || s.StartTime < summary.StartTime {
+			summary.StartTime = s.StartTime
+		}
+		if s.EndTime > summary.EndTime {
+			summary.EndTime = s.EndTime
+		}
+		if s.StatusCode == statusValues["error"] {
+			summary.ErrorCount++
+		}
+		if s.ServiceName != "" {
+			services[s.ServiceName] = struct{}{}
+		}
+	}
+	summary.Duration = summary.EndTime - summary.StartTime
+
+	summary.ServiceNames = make([]string, 0, len(services))
+	for name := range services {
+		summary.ServiceNames = append(summary.ServiceNames, name)
+	}
+	sort.Strings(summary.ServiceNames)
+
+	if root := t.Root(); root != nil {
+		summary.RootServiceName = root.ServiceName
+		summary.RootSpanName = root.Name
+	}
+
+	for _, s := range matched {
+		summary.MatchedSpans = append(summary.MatchedSpans, s.Data)
+	}
+	return summary
+}
+
+// Eval 返回 trace 中满足过滤条件的 span
+func (e *SpansetFilter) Eval(t *Trace) []*Span {
+	var res []*Span
+	for _, s := range t.Spans {
+		if e.Filter == nil || e.Filter.Match(s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Eval 结构化操作符返回右侧 spanset 中满足关系的 span，逻辑操作符返回两侧 spanset 的并集
+func (e *SpansetOperation) Eval(t *Trace) []*Span {
+	lhs := e.LHS.Eval(t)
+	if len(lhs) == 0 && e.Op != SpansetOr {
+		return nil
+	}
+	rhs := e.RHS.Eval(t)
+
+	switch e.Op {
+	case SpansetAnd:
+		if len(rhs) == 0 {
+			return nil
+		}
+		return union(lhs, rhs)
+	case SpansetOr:
+		return union(lhs, rhs)
+	}
+
+	lhsSet := make(map[*Span]struct{}, len(lhs))
+	for _, s := range lhs {
+		lhsSet[s] = struct{}{}
+	}
+
+	var res []*Span
+	for _, s := range rhs {
+		var ok bool
+		switch e.Op {
+		case SpansetChild:
+			if p := t.Parent(s); p != nil {
+				_, ok = lhsSet[p]
+			}
+		case SpansetDescendant:
+			// 防止脏数据中出现环，最多回溯 span 数量的层级
+			p := t.Parent(s)
+			for depth := 0; p != nil && depth < len(t.Spans); depth++ {
+				if _, ok = lhsSet[p]; ok {
+					break
+				}
+				p = t.Parent(p)
+			}
+		case SpansetSibling:
+			for l := range lhsSet {
+				if l != s && l.ParentSpanID != "" && l.ParentSpanID == s.ParentSpanID {
+					ok = true
+					break
+				}
+			}
+		}
+		if ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func union(a, b []*Span) []*Span {
+	seen := make(map[*Span]struct{}, len(a)+len(b))
+	res := make([]*Span, 0, len(a)+len(b))
+	for _, list := range [][]*Span{a, b} {
+		for _, s := range list {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+			seen[s] = struct{}{}
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Match 判断 span 是否满足逻辑组合条件
+func (e *BinaryFieldExpr) Match(s *Span) bool {
+	if e.Op == LogicAnd {
+		return e.LHS.Match(s) && e.RHS.Match(s)
+	}
+	return e.LHS.Match(s) || e.RHS.Match(s)
+}
+
+// Match 判断 span 是否满足比较条件，字段不存在时不匹配
+// 未指定作用域的属性：肯定条件任意一个字段满足即可，否定条件（!=、!~）需要所有存在的字段都满足
+func (e *Comparison) Match(s *Span) bool {
+	var found bool
+	for _, f := range e.Attribute.Fields() {
+		v, ok := s.Data[f]
+		if !ok || v == nil {
+			continue
+		}
+		found = true
+		matched := e.compare(v)
+		if e.Op.negative() {
+			if !matched {
+				return false
+			}
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return found && e.Op.negative()
+}
+
+func (e *Comparison) compare(v any) bool {
+	switch e.Op {
+	case OpRegex:
+		return e.re.MatchString(toString(v))
+	case OpNotRegex:
+		return !e.re.MatchString(toString(v))
+	}
+
+	var cmp int
+	switch e.Value.Type {
+	case TypeString:
+		cmp = compareString(toString(v), e.Value.S)
+	case TypeBool:
+		cmp = compareString(toString(v), strconv.FormatBool(e.Value.B))
+	default:
+		n, ok := toFloat(v)
+		if !ok {
+			return false
+		}
+		expected, _ := strconv.ParseFloat(e.Value.StorageValue(), 64)
+		switch {
+		case n < expected:
+			cmp = -1
+		case n > expected:
+			cmp = 1
+		}
+	}
+
+	switch e.Op {
+	case OpEqual:
+		return cmp == 0
+	case OpNotEqual:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+func compareString(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", s)
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,194 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package traceql
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSpan(traceID, spanID, parentSpanID, service, name string, start, end float64, status float64) map[string]any {
+	return map[string]any{
+		FieldTraceID:          traceID,
+		FieldSpanID:           spanID,
+		FieldParentSpanID:     parentSpanID,
+		FieldServiceName:      service,
+		FieldSpanName:         name,
+		FieldStartTime:        start,
+		FieldEndTime:          end,
+		FieldElapsedTime:      end - start,
+		FieldStatusCode:       status,
+		FieldKind:             float64(2),
+		"attributes.http.url": "/api/" + name,
+	}
+}
+
+func TestTraceEval(t *testing.T) {
+	// gateway(root) -> api -> db
+	//                      -> cache(error)
+	// 另外一条 trace 只有单个 span
+	list := []map[string]any{
+		mockSpan("t1", "s1", "", "gateway", "entry", 1000, 9000, 1),
+		mockSpan("t1", "s2", "s1", "api", "handle", 2000, 8000, 0),
+		mockSpan("t1", "s3", "s2", "mysql", "query", 3000, 5000, 0),
+		mockSpan("t1", "s4", "s2", "redis", "get", 5000, 7000, 2),
+		mockSpan("t1", "s4", "s2", "redis", "get", 5000, 7000, 2),
+		mockSpan("t2", "a1", "", "gateway", "entry", 100, 200, 2),
+	}
+
+	traces := NewTraces(list)
+	assert.Len(t, traces, 2)
+	assert.Len(t, traces[0].Spans, 4)
+
+	testCases := map[string]struct {
+		q       string
+		matched map[string][]string
+	}{
+		"单条件": {
+			q: `{ status = error }`,
+			matched: map[string][]string{
+				"t1": {"s4"},
+				"t2": {"a1"},
+			},
+		},
+		"耗时": {
+			q: `{ duration >= 5ms && resource.service.name != "gateway" }`,
+			matched: map[string][]string{
+				"t1": {"s2"},
+			},
+		},
+		"未指定作用域否定条件": {
+			q: `{ .http.url != "/api/query" && duration > 0 }`,
+			matched: map[string][]string{
+				"t1": {"s1", "s2", "s4"},
+				"t2": {"a1"},
+			},
+		},
+		"子节点": {
+			q: `{ resource.service.name = "gateway" } > { }`,
+			matched: map[string][]string{
+				"t1": {"s2"},
+			},
+		},
+		"后代节点": {
+			q: `{ name = "entry" } >> { status = error }`,
+			matched: map[string][]string{
+				"t1": {"s4"},
+			},
+		},
+		"兄弟节点": {
+			q: `{ resource.service.name = "mysql" } ~ { }`,
+			matched: map[string][]string{
+				"t1": {"s4"},
+			},
+		},
+		"逻辑与": {
+			q: `{ .service.name = "mysql" } && { span.http.url =~ "/api/e.*" }`,
+			matched: map[string][]string{
+				"t1": {"s3", "s1"},
+			},
+		},
+		"逻辑或": {
+			q: `{ name = "query" } || { status = error && duration < 1ms }`,
+			matched: map[string][]string{
+				"t1": {"s3"},
+				"t2": {"a1"},
+			},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, err := Parse(c.q)
+			assert.Nil(t, err)
+
+			matched := make(map[string][]string)
+			for _, tr := range traces {
+				for _, s := range expr.Eval(tr) {
+					matched[tr.TraceID] = append(matched[tr.TraceID], s.SpanID)
+				}
+			}
+			assert.Equal(t, c.matched, matched)
+		})
+	}
+}
+
+func TestTraceSummary(t *testing.T) {
+	traces := NewTraces([]map[string]any{
+		mockSpan("t1", "s2", "s1", "api", "handle", 2000, 8000, 0),
+		mockSpan("t1", "s1", "", "gateway", "entry", 1000, 9000, 1),
+		mockSpan("t1", "s3", "s2", "mysql", "query", 3000, 5000, 2),
+	})
+	assert.Len(t, traces, 1)
+
+	summary := traces[0].Summary(traces[0].Spans[2:])
+	sort.Strings(summary.ServiceNames)
+
+	assert.Equal(t, "t1", summary.TraceID)
+	assert.Equal(t, "gateway", summary.RootServiceName)
+	assert.Equal(t, "entry", summary.RootSpanName)
+	assert.Equal(t, int64(1000), summary.StartTime)
+	assert.Equal(t, int64(9000), summary.EndTime)
+	assert.Equal(t, int64(8000), summary.Duration)
+	assert.Equal(t, 3, summary.SpanCount)
+	assert.Equal(t, 1, summary.ErrorCount)
+	assert.Equal(t, []string{"api", "gateway", "mysql"}, summary.ServiceNames)
+	assert.Len(t, summary.MatchedSpans, 1)
+}
+
+func TestCandidateTraceIDs(t *testing.T) {
+	result := map[string][]string{
+		`{ .a = 1 }`: {"t1", "t2", "t3"},
+		`{ .b = 1 }`: {"t3", "t2"},
+		`{ .c = 1 }`: {"t4"},
+		`{ }`:        {"t5"},
+	}
+
+	testCases := map[string]struct {
+		q        string
+		traceIDs []string
+	}{
+		"逻辑与取交集": {
+			q:        `{ .a = 1 } && { .b = 1 }`,
+			traceIDs: []string{"t2", "t3"},
+		},
+		"逻辑或取并集": {
+			q:        `{ .b = 1 } || { .c = 1 } || { .a = 1 }`,
+			traceIDs: []string{"t3", "t2", "t4", "t1"},
+		},
+		"结构化操作符取交集": {
+			q:        `({ .a = 1 } || { .c = 1 }) >> { .b = 1 }`,
+			traceIDs: []string{"t2", "t3"},
+		},
+		"空过滤条件不参与交集": {
+			q:        `{ .c = 1 } > { }`,
+			traceIDs: []string{"t4"},
+		},
+		"只有空过滤条件": {
+			q:        `{ } ~ { }`,
+			traceIDs: []string{"t5"},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, err := Parse(c.q)
+			assert.Nil(t, err)
+
+			traceIDs, err := CandidateTraceIDs(expr, func(filter *SpansetFilter) ([]string, error) {
+				return result[filter.String()], nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, c.traceIDs, traceIDs)
+		})
+	}
+}
@@ -191,3 +191,191 @@ func HandlerAPIRelationMultiResourceRange(c *gin.Context) {
 
 	resp.success(ctx, data)
 }
+
+// HandlerAPIRelationSubgraph
+// @Summary  query relation subgraph
+// @ID       relation_subgraph_query
+// @Produce  json
+// @Param    traceparent            header    string                          false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    X-Bk-Scope-Space-Uid   header    string                          false  "空间UID" default(bkcc__2)
+// @Param    data                  	body      cmdb.RelationSubgraphRequest			  true   "json data"
+// @Success  200                   	{object}  cmdb.RelationSubgraphResponse
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /api/v1/relation/subgraph [post]
+func HandlerAPIRelationSubgraph(c *gin.Context) {
+	var (
+		ctx = c.Request.Context()
+
+		user = metadata.GetUser(ctx)
+		err  error
+
+		resp = &response{
+			c: c,
+		}
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-api-relation-subgraph")
+	defer span.End(&err)
+
+	request := new(cmdb.RelationSubgraphRequest)
+	err = json.NewDecoder(c.Request.Body).Decode(request)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	paramsBody, _ := json.Marshal(request)
+	span.Set("handler-headers", c.Request.Header)
+	span.Set("handler-body", string(paramsBody))
+
+	model, err := v1beta1.GetModel(ctx)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	data := new(cmdb.RelationSubgraphResponse)
+	data.TraceID = span.TraceID()
+	data.Data = make([]cmdb.RelationSubgraphResponseData, len(request.QueryList))
+
+	var (
+		sendWg sync.WaitGroup
+		lock   sync.Mutex
+	)
+	p, _ := ants.NewPool(RelationMaxRouting)
+	defer p.Release()
+
+	for idx, qry := range request.QueryList {
+		idx := idx
+		qry := qry
+		sendWg.Add(1)
+		_ = p.Submit(func() {
+			defer sendWg.Done()
+			d := cmdb.RelationSubgraphResponseData{
+				Code: http.StatusOK,
+			}
+
+			var (
+				sg     *cmdb.Subgraph
+				qryErr error
+			)
+			d.SourceType, d.SourceInfo, sg, qryErr = model.QuerySubgraph(ctx, qry.LookBackDelta, user.SpaceUid, qry.Timestamp, qry.SourceType, qry.SourceInfo, qry.Hops, qry.ResourceTypes)
+			if qryErr != nil {
+				log.Errorf(ctx, qryErr.Error())
+
+				d.Message = qryErr.Error()
+				d.Code = http.StatusBadRequest
+			}
+
+			d.Subgraph = subgraphOrEmpty(sg)
+
+			lock.Lock()
+			data.Data[idx] = d
+			lock.Unlock()
+		})
+	}
+	sendWg.Wait()
+
+	resp.success(ctx, data)
+}
+
+// HandlerAPIRelationSubgraphRange
+// @Summary  query relation subgraph range
+// @ID       relation_subgraph_query_range
+// @Produce  json
+// @Param    traceparent            header    string                          false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    X-Bk-Scope-Space-Uid   header    string                          false  "空间UID" default(bkcc__2)
+// @Param    data                  	body      cmdb.RelationSubgraphRangeRequest			  true   "json data"
+// @Success  200                   	{object}  cmdb.RelationSubgraphResponse
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /api/v1/relation/subgraph_range [post]
+func HandlerAPIRelationSubgraphRange(c *gin.Context) {
+	var (
+		ctx = c.Request.Context()
+
+		user = metadata.GetUser(ctx)
+		err  error
+
+		resp = &response{
+			c: c,
+		}
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-api-relation-subgraph-range")
+	defer span.End(&err)
+
+	request := new(cmdb.RelationSubgraphRangeRequest)
+	err = json.NewDecoder(c.Request.Body).Decode(request)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	paramsBody, _ := json.Marshal(request)
+	span.Set("handler-headers", c.Request.Header)
+	span.Set("handler-body", string(paramsBody))
+
+	model, err := v1beta1.GetModel(ctx)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	data := new(cmdb.RelationSubgraphResponse)
+	data.TraceID = span.TraceID()
+	data.Data = make([]cmdb.RelationSubgraphResponseData, len(request.QueryList))
+
+	var (
+		sendWg sync.WaitGroup
+		lock   sync.Mutex
+	)
+	p, _ := ants.NewPool(RelationMaxRouting)
+	defer p.Release()
+
+	for idx, qry := range request.QueryList {
+		idx := idx
+		qry := qry
+		sendWg.Add(1)
+		_ = p.Submit(func() {
+			defer sendWg.Done()
+			d := cmdb.RelationSubgraphResponseData{
+				Code: http.StatusOK,
+			}
+
+			var (
+				sg     *cmdb.Subgraph
+				qryErr error
+			)
+			d.SourceType, d.SourceInfo, sg, qryErr = model.QuerySubgraphRange(ctx, qry.LookBackDelta, user.SpaceUid, qry.Step, qry.StartTs, qry.EndTs, qry.SourceType, qry.SourceInfo, qry.Hops, qry.ResourceTypes)
+			if qryErr != nil {
+				log.Errorf(ctx, qryErr.Error())
+
+				d.Message = qryErr.Error()
+				d.Code = http.StatusBadRequest
+			}
+
+			d.Subgraph = subgraphOrEmpty(sg)
+			if d.Subgraph.Changes == nil {
+				d.Subgraph.Changes = make([]cmdb.SubgraphChange, 0)
+			}
+
+			lock.Lock()
+			data.Data[idx] = d
+			lock.Unlock()
+		})
+	}
+	sendWg.Wait()
+
+	resp.success(ctx, data)
+}
+
+// subgraphOrEmpty 查询异常时也返回空的节点和关联列表
+func subgraphOrEmpty(sg *cmdb.Subgraph) cmdb.Subgraph {
+	if sg == nil {
+		return cmdb.Subgraph{
+			Nodes: make([]cmdb.SubgraphNode, 0),
+			Edges: make([]cmdb.SubgraphEdge, 0),
+		}
+	}
+	return *sg
+}
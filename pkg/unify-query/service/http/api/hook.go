@@ -20,12 +20,16 @@ import (
 func setDefaultConfig() {
 	viper.SetDefault(RelationMultiResourceConfigPath, "/api/v1/relation/multi_resource")
 	viper.SetDefault(RelationMultiResourceRangeConfigPath, "/api/v1/relation/multi_resource_range")
+	viper.SetDefault(RelationSubgraphConfigPath, "/api/v1/relation/subgraph")
+	viper.SetDefault(RelationSubgraphRangeConfigPath, "/api/v1/relation/subgraph_range")
 	viper.SetDefault(RelationMaxRoutingConfigPath, 5)
 }
 
 func loadConfig() {
 	RelationMultiResource = viper.GetString(RelationMultiResourceConfigPath)
 	RelationMultiResourceRange = viper.GetString(RelationMultiResourceRangeConfigPath)
+	RelationSubgraph = viper.GetString(RelationSubgraphConfigPath)
+	RelationSubgraphRange = viper.GetString(RelationSubgraphRangeConfigPath)
 	RelationMaxRouting = viper.GetInt(RelationMaxRoutingConfigPath)
 }
 
@@ -21,7 +21,11 @@ func RegisterRelation(ctx context.Context, g *gin.RouterGroup) {
 
 	g.POST(RelationMultiResource, HandlerAPIRelationMultiResource)
 	g.POST(RelationMultiResourceRange, HandlerAPIRelationMultiResourceRange)
+	g.POST(RelationSubgraph, HandlerAPIRelationSubgraph)
+	g.POST(RelationSubgraphRange, HandlerAPIRelationSubgraphRange)
 
 	log.Infof(ctx, "RegisterRelation => [POST] %s", RelationMultiResource)
 	log.Infof(ctx, "RegisterRelation => [POST] %s", RelationMultiResourceRange)
+	log.Infof(ctx, "RegisterRelation => [POST] %s", RelationSubgraph)
+	log.Infof(ctx, "RegisterRelation => [POST] %s", RelationSubgraphRange)
 }
@@ -12,11 +12,15 @@ package api
 const (
 	RelationMultiResourceConfigPath      = "api.relation.multi_resource"
 	RelationMultiResourceRangeConfigPath = "api.relation.mutil_resource_range"
+	RelationSubgraphConfigPath           = "api.relation.subgraph"
+	RelationSubgraphRangeConfigPath      = "api.relation.subgraph_range"
 	RelationMaxRoutingConfigPath         = "api.relation.max_routing"
 )
 
 var (
 	RelationMultiResource      string
 	RelationMultiResourceRange string
+	RelationSubgraph           string
+	RelationSubgraphRange      string
 	RelationMaxRouting         int
 )
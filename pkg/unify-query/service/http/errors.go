@@ -19,4 +19,12 @@ var (
 	ErrPromParse        = errors.New("parse promql error")
 	ErrInvalidLabelName = errors.New("invalid label name")
 	ErrLabelMatcher     = errors.New("wrong label match")
+
+	ErrEmptySpaceUid      = errors.New("space uid is empty")
+	ErrRemoteReadMatchers = errors.New("remote read matchers must select a single metric")
+	ErrResponseNotFlusher = errors.New("response writer does not implement http.Flusher")
+	ErrStreamSortRequired = errors.New("stream es query requires sort in body")
+
+	// errStreamMaxRows 流式返回达到最大行数，剩余数据直接丢弃
+	errStreamMaxRows = errors.New("stream reached max rows")
 )
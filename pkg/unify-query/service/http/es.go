@@ -48,7 +48,7 @@ type Query struct {
 	FuzzyMatching bool   `json:"fuzzy_matching"`
 }
 
-// 处理请求
+// 处理请求，通过 format 参数或者 Accept 头开启流式返回，此时 body 中必须指定 sort
 func HandleESQueryRequest(c *gin.Context) {
 	// 这里开始context就使用trace生成的了
 	var (
@@ -85,6 +85,21 @@ func HandleESQueryRequest(c *gin.Context) {
 		Body:          req.Query.Body,
 		FuzzyMatching: req.Query.FuzzyMatching,
 	}
+
+	// 通过 format 参数或者 Accept 头开启流式返回
+	if format := streamFormat(c); format != "" {
+		span.Set("stream-format", format)
+		err = handlerESStream(ctx, c, params, format, span.TraceID())
+		if err != nil {
+			log.Errorf(ctx, "stream query es failed for->[%s]", err)
+			metric.APIRequestInc(ctx, servicePath, metric.StatusFailed, user.SpaceUid, user.Source)
+			c.JSON(400, ErrResponse{TraceID: span.TraceID(), Err: err.Error()})
+			return
+		}
+		metric.APIRequestInc(ctx, servicePath, metric.StatusSuccess, user.SpaceUid, user.Source)
+		return
+	}
+
 	result, err := es.Query(params)
 	if err != nil {
 		log.Errorf(context.TODO(), "query es failed for->[%s]", err)
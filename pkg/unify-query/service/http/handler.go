@@ -11,6 +11,7 @@ package http
 
 import (
 	"fmt"
+	"time"
 	"unsafe"
 
 	"github.com/gin-gonic/gin"
@@ -192,6 +193,7 @@ func HandlerQueryExemplar(c *gin.Context) {
 // @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
 // @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
 // @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    format                 query     string                        false  "流式返回格式 ndjson / arrow，也可以通过 Accept 头指定"
 // @Param    data                  	body      structured.QueryTs  			true   "json data"
 // @Success  200                   	{object}  PromData
 // @Failure  400                   	{object}  ErrResponse
@@ -239,6 +241,13 @@ func HandlerQueryRaw(c *gin.Context) {
 
 	listData.TraceID = span.TraceID()
 
+	// 通过 format 参数或者 Accept 头开启流式返回
+	if format := streamFormat(c); format != "" {
+		span.Set("stream-format", format)
+		err = handlerQueryRawStream(ctx, c, queryTs, format, listData.TraceID)
+		return
+	}
+
 	listData.Total, listData.List, listData.ResultTableOptions, err = queryRawWithInstance(ctx, queryTs)
 	if err != nil {
 		listData.Status = &metadata.Status{
@@ -315,6 +324,73 @@ func HandlerQueryTs(c *gin.Context) {
 	resp.success(ctx, res)
 }
 
+// HandlerQueryTsExplain
+// @Summary  explain query monitor by struct
+// @ID       query_ts_explain
+// @Produce  json
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      structured.QueryTs  			true   "json data"
+// @Success  200                   	{object}  PromData
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/explain [post]
+func HandlerQueryTsExplain(c *gin.Context) {
+	var (
+		ctx = c.Request.Context()
+
+		resp = &response{
+			c: c,
+		}
+		user = metadata.GetUser(ctx)
+
+		err error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-query-ts-explain")
+	defer span.End(&err)
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+
+	span.Set("query-source", user.Key)
+	span.Set("query-space-uid", user.SpaceUid)
+
+	// 解析请求 body
+	query := &structured.QueryTs{}
+	err = json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		resp.failed(ctx, err)
+		return
+	}
+
+	// metadata 中的 spaceUid 是从 header 头信息中获取，header 如果有的话，覆盖参数里的
+	if user.SpaceUid != "" {
+		query.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(query)
+	span.Set("query-body", string(queryStr))
+
+	log.Infof(ctx, fmt.Sprintf("header: %+v, body: %s", c.Request.Header, queryStr))
+
+	// 开启解释模式，查询过程中会记录路由结果、各存储的查询语句以及耗时
+	explain := metadata.EnableExplain(ctx)
+	start := time.Now()
+
+	res, err := queryTsWithPromEngine(ctx, query)
+	explain.Stage("total", start)
+	if err != nil {
+		// 查询失败的情况下也需要返回已经收集到的解释信息，便于排查
+		resp.failedWithExplain(ctx, err, explain)
+		return
+	}
+
+	resp.success(ctx, res)
+}
+
 // HandlerQueryPromQL
 // @Summary  query monitor by promql
 // @ID       query_promql
@@ -46,12 +46,19 @@ func setDefaultConfig() {
 	viper.SetDefault(TSQueryReferenceQueryHandlePathConfigPath, "/query/ts/reference")
 	viper.SetDefault(TSQueryRawQueryHandlePathConfigPath, "/query/ts/raw")
 	viper.SetDefault(TSQueryRawMAXLimitConfigPath, 1e2)
+	viper.SetDefault(TSQueryRawPatternHandlePathConfigPath, "/query/ts/raw/pattern")
+	viper.SetDefault(TSQueryPatternMaxLimitConfigPath, 1e4)
+	viper.SetDefault(TSQueryRawStreamPageSizeConfigPath, 1e3)
+	viper.SetDefault(TSQueryRawStreamMaxRowsConfigPath, 1e6)
 	viper.SetDefault(TSQueryInfoHandlePathConfigPath, "/query/ts/info")
 	viper.SetDefault(TSQueryStructToPromQLHandlePathConfigPath, "/query/ts/struct_to_promql")
 	viper.SetDefault(TSQueryPromQLToStructHandlePathConfigPath, "/query/ts/promql_to_struct")
 
 	viper.SetDefault(TSQueryLabelValuesPathConfigPath, "/query/ts/label/:label_name/values")
 	viper.SetDefault(TSQueryClusterMetricsPathConfigPath, "/query/ts/cluster_metrics")
+	viper.SetDefault(TSQueryTraceQLHandlePathConfigPath, "/query/ts/traceql")
+	viper.SetDefault(TSQueryExplainHandlePathConfigPath, "/query/ts/explain")
+	viper.SetDefault(RemoteReadHandlePathConfigPath, "/api/v1/read")
 
 	viper.SetDefault(PrintHandlePathConfigPath, "/print")
 	viper.SetDefault(FeatureFlagHandlePathConfigPath, "/ff")
@@ -73,6 +80,9 @@ func setDefaultConfig() {
 
 	viper.SetDefault(QueryMaxRoutingConfigPath, 2)
 
+	viper.SetDefault(RemoteReadSampleLimitConfigPath, 5e7)
+	viper.SetDefault(RemoteReadMaxBytesInFrameConfigPath, 1024*1024)
+
 	viper.SetDefault(ClusterMetricQueryPrefixConfigPath, "bkmonitor")
 	viper.SetDefault(ClusterMetricQueryTimeoutConfigPath, "30s")
 
@@ -96,6 +106,14 @@ func LoadConfig() {
 
 	QueryMaxRouting = viper.GetInt(QueryMaxRoutingConfigPath)
 
+	QueryPatternMaxLimit = viper.GetInt(TSQueryPatternMaxLimitConfigPath)
+
+	QueryRawStreamPageSize = viper.GetInt(TSQueryRawStreamPageSizeConfigPath)
+	QueryRawStreamMaxRows = viper.GetInt(TSQueryRawStreamMaxRowsConfigPath)
+
+	RemoteReadSampleLimit = viper.GetInt(RemoteReadSampleLimitConfigPath)
+	RemoteReadMaxBytesInFrame = viper.GetInt(RemoteReadMaxBytesInFrameConfigPath)
+
 	ClusterMetricQueryPrefix = viper.GetString(ClusterMetricQueryPrefixConfigPath)
 	ClusterMetricQueryTimeout = viper.GetDuration(ClusterMetricQueryTimeoutConfigPath)
 
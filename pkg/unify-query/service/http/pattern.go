@@ -0,0 +1,150 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/pattern"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// HandlerQueryRawPattern
+// @Summary  query raw log pattern
+// @ID       query_raw_pattern
+// @Produce  json
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      pattern.QueryPattern  		true   "json data"
+// @Success  200                   	{object}  PatternListData
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/raw/pattern [post]
+func HandlerQueryRawPattern(c *gin.Context) {
+	var (
+		ctx         = c.Request.Context()
+		resp        = &response{c: c}
+		user        = metadata.GetUser(ctx)
+		err         error
+		span        *trace.Span
+		patternData PatternListData
+	)
+
+	ctx, span = trace.NewSpan(ctx, "handler-query-raw-pattern")
+	defer func() {
+		if err != nil {
+			log.Errorf(ctx, err.Error())
+			resp.failed(ctx, err)
+		}
+
+		span.End(&err)
+	}()
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+	span.Set("query-source", user.Key)
+	span.Set("query-space-uid", user.SpaceUid)
+
+	// 解析请求 body
+	query := &pattern.QueryPattern{}
+	err = json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		return
+	}
+	if query.Query == nil {
+		err = pattern.ErrEmptyQuery
+		return
+	}
+
+	// metadata 中的 spaceUid 是从 header 头信息中获取
+	if user.SpaceUid != "" {
+		query.Query.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(query)
+	span.Set("query-body", string(queryStr))
+
+	log.Infof(ctx, fmt.Sprintf("header: %+v, body: %s", c.Request.Header, queryStr))
+
+	patternData.TraceID = span.TraceID()
+	patternData.Total, patternData.List, err = queryRawPattern(ctx, query)
+	if err != nil {
+		return
+	}
+
+	resp.success(ctx, patternData)
+}
+
+// queryRawPattern 查询原始日志并进行聚类，对比模式下先聚类基准时间段的日志，保证两个时间段使用相同的模板
+func queryRawPattern(ctx context.Context, query *pattern.QueryPattern) (total int, list []pattern.Pattern, err error) {
+	ctx, span := trace.NewSpan(ctx, "query-raw-pattern")
+	defer span.End(&err)
+
+	interval, err := query.IntervalDuration()
+	if err != nil {
+		return
+	}
+
+	// 聚类需要足够多的样本，未指定或者超过上限时使用上限
+	if query.Query.Limit <= 0 || query.Query.Limit > QueryPatternMaxLimit {
+		query.Query.Limit = QueryPatternMaxLimit
+	}
+
+	span.Set("query-field", query.Field)
+	span.Set("query-interval", interval.String())
+	span.Set("query-limit", query.Query.Limit)
+	span.Set("query-compare-offset", query.CompareOffset)
+
+	analyzer := pattern.NewAnalyzer(query.Options(), interval, query.IsCompare())
+
+	if query.IsCompare() {
+		baseline, bErr := query.BaselineQueryTs()
+		if bErr != nil {
+			err = bErr
+			return
+		}
+
+		_, baselineList, _, bErr := queryRawWithInstance(ctx, baseline)
+		if bErr != nil {
+			err = bErr
+			return
+		}
+		for _, item := range baselineList {
+			if message, ok := query.Message(item); ok {
+				analyzer.AddBaseline(message)
+			}
+		}
+		span.Set("baseline-num", len(baselineList))
+	}
+
+	_, data, _, err := queryRawWithInstance(ctx, query.Query)
+	if err != nil {
+		return
+	}
+	for _, item := range data {
+		if message, ok := query.Message(item); ok {
+			analyzer.Add(message, query.Time(item))
+			total++
+		}
+	}
+
+	list = analyzer.Patterns(query.SpikeRatio)
+
+	span.Set("data-num", len(data))
+	span.Set("pattern-num", len(list))
+	return
+}
@@ -20,9 +20,10 @@ import (
 // 返回结构化数据
 type PromData struct {
 	dimensions map[string]bool
-	Tables     []*TablesItem    `json:"series"`
-	Status     *metadata.Status `json:"status,omitempty"`
-	TraceID    string           `json:"trace_id,omitempty"`
+	Tables     []*TablesItem     `json:"series"`
+	Status     *metadata.Status  `json:"status,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	Explain    *metadata.Explain `json:"explain,omitempty"`
 }
 
 // NewPromData
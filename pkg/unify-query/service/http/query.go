@@ -157,31 +157,10 @@ func queryExemplar(ctx context.Context, query *structured.QueryTs) (interface{},
 	return resp, err
 }
 
-func queryRawWithInstance(ctx context.Context, queryTs *structured.QueryTs) (total int64, list []map[string]any, resultTableOptions metadata.ResultTableOptions, err error) {
-	ignoreDimensions := []string{elasticsearch.KeyAddress}
-
-	ctx, span := trace.NewSpan(ctx, "query-raw-with-instance")
-	defer span.End(&err)
+// queryRawList 构建原始数据查询的路由列表，公共的时间、排序、翻页等配置会复用到每个查询中
+func queryRawList(ctx context.Context, queryTs *structured.QueryTs) ([]*metadata.Query, error) {
+	var queryList []*metadata.Query
 
-	unit, start, end, timeErr := function.QueryTimestamp(queryTs.Start, queryTs.End)
-	if timeErr != nil {
-		err = timeErr
-		return
-	}
-	metadata.GetQueryParams(ctx).SetTime(start, end, unit)
-
-	var (
-		receiveWg sync.WaitGroup
-		dataCh    = make(chan map[string]any)
-
-		message   strings.Builder
-		queryList []*metadata.Query
-		lock      sync.Mutex
-	)
-
-	list = make([]map[string]any, 0)
-
-	// 构建查询路由列表
 	if queryTs.SpaceUid == "" {
 		queryTs.SpaceUid = metadata.GetUser(ctx).SpaceUid
 	}
@@ -233,10 +212,9 @@ func queryRawWithInstance(ctx context.Context, queryTs *structured.QueryTs) (tot
 			ql.KeepColumns = queryTs.ResultColumns
 		}
 
-		qm, qmErr := ql.ToQueryMetric(ctx, queryTs.SpaceUid)
-		if qmErr != nil {
-			err = qmErr
-			return
+		qm, err := ql.ToQueryMetric(ctx, queryTs.SpaceUid)
+		if err != nil {
+			return nil, err
 		}
 
 		for _, qry := range qm.QueryList {
@@ -246,6 +224,38 @@ func queryRawWithInstance(ctx context.Context, queryTs *structured.QueryTs) (tot
 		}
 	}
 
+	return queryList, nil
+}
+
+func queryRawWithInstance(ctx context.Context, queryTs *structured.QueryTs) (total int64, list []map[string]any, resultTableOptions metadata.ResultTableOptions, err error) {
+	ignoreDimensions := []string{elasticsearch.KeyAddress}
+
+	ctx, span := trace.NewSpan(ctx, "query-raw-with-instance")
+	defer span.End(&err)
+
+	unit, start, end, timeErr := function.QueryTimestamp(queryTs.Start, queryTs.End)
+	if timeErr != nil {
+		err = timeErr
+		return
+	}
+	metadata.GetQueryParams(ctx).SetTime(start, end, unit)
+
+	var (
+		receiveWg sync.WaitGroup
+		dataCh    = make(chan map[string]any)
+
+		message   strings.Builder
+		queryList []*metadata.Query
+		lock      sync.Mutex
+	)
+
+	list = make([]map[string]any, 0)
+
+	queryList, err = queryRawList(ctx, queryTs)
+	if err != nil {
+		return
+	}
+
 	receiveWg.Add(1)
 
 	// 启动合并数据
@@ -570,11 +580,16 @@ func queryTsToInstanceAndStmt(ctx context.Context, queryTs *structured.QueryTs)
 	}
 
 	// 转换成 queryRef
+	routeStart := time.Now()
 	queryRef, err := queryTs.ToQueryReference(ctx)
 	if err != nil {
 		return
 	}
 
+	explain := metadata.GetExplain(ctx)
+	explain.Stage("query-reference", routeStart)
+	explain.AddQueryReference(queryRef)
+
 	if metadata.GetQueryParams(ctx).IsDirectQuery() {
 		// 判断是否是直查
 		vmExpand := queryRef.ToVmExpand(ctx)
@@ -631,6 +646,7 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 	defer func() {
 		resp.TraceID = span.TraceID()
 		resp.Status = metadata.GetStatus(ctx)
+		resp.Explain = metadata.GetExplain(ctx)
 		span.End(&err)
 	}()
 
@@ -655,11 +671,13 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 
 	span.Set("storage-type", instance.InstanceType())
 
+	queryStart := time.Now()
 	if query.Instant {
 		res, err = instance.DirectQuery(ctx, stmt, end)
 	} else {
 		res, err = instance.DirectQueryRange(ctx, stmt, start, end, step)
 	}
+	metadata.GetExplain(ctx).Stage("query-storage", queryStart)
 	if err != nil {
 		return nil, err
 	}
@@ -699,7 +717,9 @@ func queryTsWithPromEngine(ctx context.Context, query *structured.QueryTs) (any,
 	span.Set("resp-series-num", seriesNum)
 	span.Set("resp-points-num", pointsNum)
 
+	fillStart := time.Now()
 	err = resp.Fill(tables)
+	metadata.GetExplain(ctx).Stage("format", fillStart)
 	if err != nil {
 		return nil, err
 	}
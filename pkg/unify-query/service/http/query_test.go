@@ -2923,3 +2923,50 @@ func TestQueryTsToInstanceAndStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryTsExplain(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	mock.Init()
+	promql.MockEngine()
+	influxdb.MockSpaceRouter(ctx)
+
+	query := `{"query_list":[{"table_id":"result_table.vm","field_name":"container_cpu_usage_seconds_total","function":[{"method":"sum","dimensions":["namespace"]}],"time_aggregation":{"function":"sum_over_time","window":"1m0s"},"reference_name":"a"}],"metric_merge":"a","start_time":"1697458200","end_time":"1697461800","step":"60s"}`
+
+	metadata.SetUser(ctx, "username:explain", influxdb.SpaceUid, "")
+	explain := metadata.EnableExplain(ctx)
+
+	var queryTs *structured.QueryTs
+	err := json.Unmarshal([]byte(query), &queryTs)
+	assert.Nil(t, err)
+	queryTs.SpaceUid = influxdb.SpaceUid
+
+	// 无论查询是否成功，都需要记录路由结果和实际执行的查询语句
+	res, err := queryTsWithPromEngine(ctx, queryTs)
+	if err == nil {
+		data, ok := res.(*PromData)
+		assert.True(t, ok)
+		assert.Equal(t, explain, data.Explain)
+	}
+
+	if assert.Len(t, explain.ResultTables, 1) {
+		assert.Equal(t, "a", explain.ResultTables[0].ReferenceName)
+		assert.Equal(t, "result_table.vm", explain.ResultTables[0].TableID)
+		assert.Equal(t, "2_bcs_prom_computation_result_table", explain.ResultTables[0].VmRt)
+		assert.Equal(t, consul.VictoriaMetricsStorageType, explain.ResultTables[0].StorageType)
+	}
+
+	if assert.Len(t, explain.Queries, 1) {
+		assert.Equal(t, consul.VictoriaMetricsStorageType, explain.Queries[0].StorageType)
+		assert.Contains(t, explain.Queries[0].Statement, `"result_table_list":["2_bcs_prom_computation_result_table"]`)
+		assert.NotEmpty(t, explain.Queries[0].Cost)
+		if err != nil {
+			assert.Equal(t, err.Error(), explain.Queries[0].Error)
+		}
+	}
+
+	if assert.True(t, len(explain.Stages) >= 2) {
+		assert.Equal(t, "query-reference", explain.Stages[0].Name)
+		assert.Equal(t, "query-storage", explain.Stages[1].Name)
+	}
+}
@@ -60,6 +60,10 @@ func registerDefaultHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TSQueryHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTs)
 
+	// query/ts/explain
+	handlerPath = viper.GetString(TSQueryExplainHandlePathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTsExplain)
+
 	// query/ts/promql
 	handlerPath = viper.GetString(TSQueryPromQLHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryPromQL)
@@ -72,6 +76,10 @@ func registerDefaultHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TSQueryRawQueryHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryRaw)
 
+	// query/ts/raw/pattern
+	handlerPath = viper.GetString(TSQueryRawPatternHandlePathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryRawPattern)
+
 	// query/ts/exemplar
 	handlerPath = viper.GetString(TSQueryExemplarHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryExemplar)
@@ -107,6 +115,14 @@ func registerDefaultHandlers(ctx context.Context, g *gin.RouterGroup) {
 	handlerPath = viper.GetString(TSQueryClusterMetricsPathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTsClusterMetrics)
 
+	// query/ts/traceql
+	handlerPath = viper.GetString(TSQueryTraceQLHandlePathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerQueryTraceQL)
+
+	// api/v1/read
+	handlerPath = viper.GetString(RemoteReadHandlePathConfigPath)
+	registerHandler.register(http.MethodPost, handlerPath, HandlerRemoteRead)
+
 	// query/es/
 	handlerPath = viper.GetString(ESHandlePathConfigPath)
 	registerHandler.register(http.MethodPost, handlerPath, HandleESQueryRequest)
@@ -0,0 +1,237 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/function"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+// remoteReadMarshalPool 复用 chunk 序列化的 buffer
+var remoteReadMarshalPool = &sync.Pool{}
+
+// HandlerRemoteRead
+// @Summary  prometheus remote read
+// @ID       remote_read
+// @Accept   application/x-protobuf
+// @Produce  application/x-protobuf
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        true   "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      prompb.ReadRequest  			true   "snappy 压缩的 protobuf 数据"
+// @Success  200                   	{object}  prompb.ReadResponse
+// @Failure  400                   	{string}  string
+// @Router   /api/v1/read [post]
+func HandlerRemoteRead(c *gin.Context) {
+	var (
+		ctx    = c.Request.Context()
+		user   = metadata.GetUser(ctx)
+		status = http.StatusBadRequest
+
+		err error
+	)
+
+	ctx, span := trace.NewSpan(ctx, "handler-remote-read")
+	defer func() {
+		if err != nil {
+			log.Errorf(ctx, err.Error())
+			if httpErr, ok := err.(remote.HTTPError); ok {
+				status = httpErr.Status()
+			}
+			http.Error(c.Writer, err.Error(), status)
+		}
+		span.End(&err)
+	}()
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+	span.Set("query-source", user.Key)
+	span.Set("query-space-uid", user.SpaceUid)
+
+	// 远程读取只能读取空间下的数据，必须通过 header 指定空间
+	if user.SpaceUid == "" && !user.IsSkipSpace() {
+		err = ErrEmptySpaceUid
+		return
+	}
+
+	req, err := remote.DecodeReadRequest(c.Request)
+	if err != nil {
+		return
+	}
+
+	responseType, err := remote.NegotiateResponseType(req.AcceptedResponseTypes)
+	if err != nil {
+		return
+	}
+
+	span.Set("remote-read-response-type", responseType.String())
+	span.Set("remote-read-query-num", len(req.Queries))
+
+	log.Infof(ctx, fmt.Sprintf("header: %+v, remote read: %s", c.Request.Header, req.String()))
+
+	// 解析完请求之后的错误都属于查询异常
+	status = http.StatusInternalServerError
+	switch responseType {
+	case prompb.ReadRequest_STREAMED_XOR_CHUNKS:
+		err = remoteReadStreamedChunks(ctx, c.Writer, req, user.SpaceUid)
+	default:
+		err = remoteReadSamples(ctx, c.Writer, req, user.SpaceUid)
+	}
+}
+
+// remoteReadSamples 返回完整的 sample 数据，所有查询结果一次性返回
+func remoteReadSamples(ctx context.Context, w http.ResponseWriter, req *prompb.ReadRequest, spaceUid string) error {
+	resp := &prompb.ReadResponse{
+		Results: make([]*prompb.QueryResult, len(req.Queries)),
+	}
+
+	for i, q := range req.Queries {
+		ss, err := remoteReadSeriesSet(ctx, q, spaceUid, false)
+		if err != nil {
+			return err
+		}
+
+		var ws storage.Warnings
+		resp.Results[i], ws, err = remote.ToQueryResult(ss, RemoteReadSampleLimit)
+		if err != nil {
+			return err
+		}
+		for _, warn := range ws {
+			log.Warnf(ctx, "remote read query %d warning: %s", i, warn.Error())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	return remote.EncodeReadResponse(resp, w)
+}
+
+// remoteReadStreamedChunks 按照 XOR chunk 的格式流式返回，每个查询的 series 需要有序
+func remoteReadStreamedChunks(ctx context.Context, w http.ResponseWriter, req *prompb.ReadRequest, spaceUid string) error {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return ErrResponseNotFlusher
+	}
+
+	w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+
+	for i, q := range req.Queries {
+		ss, err := remoteReadSeriesSet(ctx, q, spaceUid, true)
+		if err != nil {
+			return err
+		}
+
+		ws, err := remote.StreamChunkedReadResponses(
+			remote.NewChunkedWriter(w, f),
+			int64(i),
+			storage.NewSeriesSetToChunkSet(ss),
+			nil,
+			RemoteReadMaxBytesInFrame,
+			remoteReadMarshalPool,
+		)
+		if err != nil {
+			return err
+		}
+		for _, warn := range ws {
+			log.Warnf(ctx, "remote read query %d warning: %s", i, warn.Error())
+		}
+	}
+
+	return nil
+}
+
+// remoteReadSeriesSet 将 remote read 查询转换为 queryReference，通过 QuerySeriesSet 获取各个存储的原始数据
+func remoteReadSeriesSet(ctx context.Context, q *prompb.Query, spaceUid string, sortSeries bool) (storage.SeriesSet, error) {
+	var err error
+
+	ctx, span := trace.NewSpan(ctx, "remote-read-series-set")
+	defer span.End(&err)
+
+	queryTs, err := remoteReadQueryTs(ctx, q, spaceUid)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.UnixMilli(q.StartTimestampMs)
+	end := time.UnixMilli(q.EndTimestampMs)
+
+	span.Set("start", start)
+	span.Set("end", end)
+
+	metadata.GetQueryParams(ctx).SetTime(start, end, function.Millisecond)
+
+	queryRef, err := queryTs.ToQueryReference(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata.SetQueryReference(ctx, queryRef)
+
+	hints := &storage.SelectHints{
+		Start: q.StartTimestampMs,
+		End:   q.EndTimestampMs,
+	}
+	if q.Hints != nil {
+		hints.Step = q.Hints.StepMs
+		hints.Func = q.Hints.Func
+		hints.Grouping = q.Hints.Grouping
+		hints.Range = q.Hints.RangeMs
+		hints.By = q.Hints.By
+	}
+
+	referenceName := queryTs.QueryList[0].ReferenceName
+	span.Set("reference-name", referenceName)
+
+	querier := prometheus.NewQuerier(ctx, start, end, QueryMaxRouting, SingleflightTimeout)
+	return querier.Select(sortSeries, hints, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, referenceName)), nil
+}
+
+// remoteReadQueryTs 复用 promql 的解析逻辑，将 label matchers 转换为结构化查询
+func remoteReadQueryTs(ctx context.Context, q *prompb.Query, spaceUid string) (*structured.QueryTs, error) {
+	matchers, err := remote.FromLabelMatchers(q.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := &parser.VectorSelector{
+		LabelMatchers: matchers,
+	}
+
+	queryTs, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(queryTs.QueryList) != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrRemoteReadMatchers, selector.String())
+	}
+
+	queryTs.SpaceUid = spaceUid
+	return queryTs, nil
+}
@@ -0,0 +1,303 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb/decoder"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+func TestRemoteReadQueryTs(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+
+	testCases := map[string]struct {
+		matchers   []*prompb.LabelMatcher
+		dataSource string
+		tableID    structured.TableID
+		fieldName  string
+		isRegexp   bool
+		conditions structured.Conditions
+	}{
+		"指标名和维度": {
+			matchers: []*prompb.LabelMatcher{
+				{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "bkmonitor:system:cpu_summary:usage"},
+				{Type: prompb.LabelMatcher_RE, Name: "bk_target_ip", Value: "127.0.0.1|127.0.0.2"},
+				{Type: prompb.LabelMatcher_NEQ, Name: "bk_cloud_id", Value: "1"},
+			},
+			dataSource: "bkmonitor",
+			tableID:    "system.cpu_summary",
+			fieldName:  "usage",
+			conditions: structured.Conditions{
+				FieldList: []structured.ConditionField{
+					{DimensionName: "bk_cloud_id", Value: []string{"1"}, Operator: structured.ConditionNotEqual},
+					{DimensionName: "bk_target_ip", Value: []string{"127.0.0.1|127.0.0.2"}, Operator: structured.ConditionRegEqual},
+				},
+				ConditionList: []string{structured.ConditionAnd},
+			},
+		},
+		"正则指标名": {
+			matchers: []*prompb.LabelMatcher{
+				{Type: prompb.LabelMatcher_RE, Name: "__name__", Value: "container_.+"},
+			},
+			dataSource: "bkmonitor",
+			fieldName:  "container_.+",
+			isRegexp:   true,
+			conditions: structured.Conditions{
+				FieldList:     []structured.ConditionField{},
+				ConditionList: []string{},
+			},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			queryTs, err := remoteReadQueryTs(ctx, &prompb.Query{Matchers: c.matchers}, "bkcc__2")
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, "bkcc__2", queryTs.SpaceUid)
+			assert.Len(t, queryTs.QueryList, 1)
+
+			qry := queryTs.QueryList[0]
+			assert.Equal(t, c.dataSource, qry.DataSource)
+			assert.Equal(t, c.tableID, qry.TableID)
+			assert.Equal(t, c.fieldName, qry.FieldName)
+			assert.Equal(t, c.isRegexp, qry.IsRegexp)
+			assert.Equal(t, c.conditions, qry.Conditions)
+		})
+	}
+}
+
+func TestHandlerRemoteRead(t *testing.T) {
+	metadata.InitMetadata()
+	gin.SetMode(gin.ReleaseMode)
+
+	readRequest := func(req *prompb.ReadRequest) []byte {
+		data, _ := proto.Marshal(req)
+		return snappy.Encode(nil, data)
+	}
+
+	testCases := map[string]struct {
+		spaceUid string
+		body     []byte
+		code     int
+		message  string
+	}{
+		"未指定空间": {
+			body:    readRequest(&prompb.ReadRequest{}),
+			code:    http.StatusBadRequest,
+			message: ErrEmptySpaceUid.Error(),
+		},
+		"请求未压缩": {
+			spaceUid: "bkcc__2",
+			body:     []byte("not snappy"),
+			code:     http.StatusBadRequest,
+		},
+		"不支持的返回类型": {
+			spaceUid: "bkcc__2",
+			body: readRequest(&prompb.ReadRequest{
+				AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{prompb.ReadRequest_ResponseType(10)},
+			}),
+			code: http.StatusBadRequest,
+		},
+		"空查询": {
+			spaceUid: "bkcc__2",
+			body:     readRequest(&prompb.ReadRequest{}),
+			code:     http.StatusOK,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.InitHashID(context.Background())
+			metadata.SetUser(ctx, "username:test", c.spaceUid, "")
+
+			w := httptest.NewRecorder()
+			gc, _ := gin.CreateTestContext(w)
+			gc.Request = httptest.NewRequest(http.MethodPost, "/api/v1/read", bytes.NewReader(c.body)).WithContext(ctx)
+
+			HandlerRemoteRead(gc)
+
+			assert.Equal(t, c.code, w.Code)
+			if c.message != "" {
+				assert.Contains(t, w.Body.String(), c.message)
+			}
+			if c.code == http.StatusOK {
+				data, err := snappy.Decode(nil, w.Body.Bytes())
+				assert.Nil(t, err)
+
+				resp := &prompb.ReadResponse{}
+				assert.Nil(t, proto.Unmarshal(data, resp))
+				assert.Len(t, resp.Results, 0)
+			}
+		})
+	}
+}
+
+func TestHandlerRemoteReadWithMockTsdb(t *testing.T) {
+	ctx := metadata.InitHashID(context.Background())
+	mock.Init()
+	influxdb.MockSpaceRouter(ctx)
+	promql.MockEngine()
+	gin.SetMode(gin.ReleaseMode)
+
+	mock.InfluxDB.Set(map[string]any{
+		`SELECT "usage" AS _value, *::tag, "time" AS _time FROM cpu_summary WHERE time > 1677081600000000000 and time < 1677081720000000000 AND (status='failed' and bk_biz_id='2') LIMIT 100000005 SLIMIT 100005 TZ('UTC')`: &decoder.Response{
+			Results: []decoder.Result{
+				{
+					Series: []*decoder.Row{
+						{
+							Name: "",
+							Tags: map[string]string{},
+							Columns: []string{
+								influxdb.ResultColumnName,
+								"notice_way",
+								"status",
+								influxdb.TimeColumnName,
+							},
+							Values: [][]any{
+								{30, "weixin", "failed", 1677081600000000000},
+								{21, "weixin", "failed", 1677081660000000000},
+								{7, "mail", "failed", 1677081600000000000},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	query := &prompb.Query{
+		StartTimestampMs: 1677081600000,
+		EndTimestampMs:   1677081720000,
+		Matchers: []*prompb.LabelMatcher{
+			{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "bkmonitor:system:cpu_summary:usage"},
+			{Type: prompb.LabelMatcher_EQ, Name: "status", Value: "failed"},
+		},
+	}
+
+	// 返回的 series 使用原始指标名，而不是查询内部的引用名
+	expected := []*prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "bkmonitor:system:cpu_summary:usage"},
+				{Name: "notice_way", Value: "mail"},
+				{Name: "status", Value: "failed"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: 1677081600000, Value: 7},
+			},
+		},
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "bkmonitor:system:cpu_summary:usage"},
+				{Name: "notice_way", Value: "weixin"},
+				{Name: "status", Value: "failed"},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: 1677081600000, Value: 30},
+				{Timestamp: 1677081660000, Value: 21},
+			},
+		},
+	}
+
+	remoteRead := func(t *testing.T, responseType prompb.ReadRequest_ResponseType) *httptest.ResponseRecorder {
+		data, err := proto.Marshal(&prompb.ReadRequest{
+			Queries:               []*prompb.Query{query},
+			AcceptedResponseTypes: []prompb.ReadRequest_ResponseType{responseType},
+		})
+		assert.Nil(t, err)
+
+		reqCtx := metadata.InitHashID(context.Background())
+		metadata.SetUser(reqCtx, "username:test", influxdb.SpaceUid, "")
+
+		w := httptest.NewRecorder()
+		gc, _ := gin.CreateTestContext(w)
+		gc.Request = httptest.NewRequest(
+			http.MethodPost, "/api/v1/read", bytes.NewReader(snappy.Encode(nil, data)),
+		).WithContext(reqCtx)
+
+		HandlerRemoteRead(gc)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	t.Run("samples", func(t *testing.T) {
+		w := remoteRead(t, prompb.ReadRequest_SAMPLES)
+
+		data, err := snappy.Decode(nil, w.Body.Bytes())
+		assert.Nil(t, err)
+		resp := &prompb.ReadResponse{}
+		assert.Nil(t, proto.Unmarshal(data, resp))
+
+		assert.Len(t, resp.Results, 1)
+		if len(resp.Results) == 1 {
+			assert.Equal(t, expected, resp.Results[0].Timeseries)
+		}
+	})
+
+	t.Run("streamed xor chunks", func(t *testing.T) {
+		w := remoteRead(t, prompb.ReadRequest_STREAMED_XOR_CHUNKS)
+
+		var actual []*prompb.TimeSeries
+		reader := remote.NewChunkedReader(w.Body, remote.DefaultChunkedReadLimit, nil)
+		for {
+			resp := &prompb.ChunkedReadResponse{}
+			err := reader.NextProto(resp)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			assert.Nil(t, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, int64(0), resp.QueryIndex)
+
+			for _, series := range resp.ChunkedSeries {
+				ts := &prompb.TimeSeries{Labels: series.Labels}
+				for _, chk := range series.Chunks {
+					assert.Equal(t, prompb.Chunk_XOR, chk.Type)
+					c, err := chunkenc.FromData(chunkenc.EncXOR, chk.Data)
+					assert.Nil(t, err)
+					it := c.Iterator(nil)
+					for it.Next() == chunkenc.ValFloat {
+						tt, v := it.At()
+						ts.Samples = append(ts.Samples, prompb.Sample{Timestamp: tt, Value: v})
+					}
+					assert.Nil(t, it.Err())
+				}
+				actual = append(actual, ts)
+			}
+		}
+		assert.Equal(t, expected, actual)
+	})
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/pattern"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/traceql"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
 )
 
@@ -39,6 +41,22 @@ func (r *response) failed(ctx context.Context, err error) {
 	})
 }
 
+// failedWithExplain 解释模式下查询失败时，同时返回已经收集到的解释信息
+func (r *response) failedWithExplain(ctx context.Context, err error, explain *metadata.Explain) {
+	log.Errorf(ctx, err.Error())
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid, user.Source)
+
+	_, span := trace.NewSpan(ctx, "response-failed")
+	r.c.JSON(http.StatusBadRequest, ExplainErrResponse{
+		ErrResponse: ErrResponse{
+			TraceID: span.TraceID(),
+			Err:     err.Error(),
+		},
+		Explain: explain,
+	})
+}
+
 func (r *response) success(ctx context.Context, data interface{}) {
 	log.Debugf(ctx, "query data size is %s", fmt.Sprint(unsafe.Sizeof(data)))
 	user := metadata.GetUser(ctx)
@@ -54,3 +72,23 @@ type ListData struct {
 	Status             *metadata.Status            `json:"status,omitempty" json:"status,omitempty"`
 	ResultTableOptions metadata.ResultTableOptions `json:"result_table_options,omitempty"`
 }
+
+// TraceListData trace 检索返回格式
+type TraceListData struct {
+	Total   int                     `json:"total"`
+	List    []*traceql.TraceSummary `json:"list"`
+	TraceID string                  `json:"trace_id,omitempty"`
+}
+
+// PatternListData 日志聚类返回格式
+type PatternListData struct {
+	Total   int               `json:"total"`
+	List    []pattern.Pattern `json:"list"`
+	TraceID string            `json:"trace_id,omitempty"`
+}
+
+// ExplainErrResponse 解释模式下的错误返回格式
+type ExplainErrResponse struct {
+	ErrResponse
+	Explain *metadata.Explain `json:"explain,omitempty"`
+}
@@ -28,6 +28,10 @@ const (
 	QueryContentTypeConfigPath     = "http.query.content_type"
 	QueryContentEncodingConfigPath = "http.query.content_encoding"
 
+	// remote read 配置
+	RemoteReadSampleLimitConfigPath     = "http.remote_read.sample_limit"
+	RemoteReadMaxBytesInFrameConfigPath = "http.remote_read.max_bytes_in_frame"
+
 	// 服务配置
 	EnablePrometheusConfigPath = "http.prometheus.enable"
 	PrometheusPathConfigPath   = "http.prometheus.path"
@@ -42,10 +46,14 @@ const (
 	TSQueryPromQLHandlePathConfigPath         = "http.path.ts_promql"
 	TSQueryReferenceQueryHandlePathConfigPath = "http.path.ts_reference"
 	TSQueryRawQueryHandlePathConfigPath       = "http.path.ts_raw"
+	TSQueryRawPatternHandlePathConfigPath     = "http.path.ts_raw_pattern"
 	TSQueryStructToPromQLHandlePathConfigPath = "http.path.ts_struct_to_promql"
 	TSQueryPromQLToStructHandlePathConfigPath = "http.path.ts_promql_to_struct"
 	TSQueryLabelValuesPathConfigPath          = "http.path.ts_label_values"
 	TSQueryClusterMetricsPathConfigPath       = "http.path.ts_cluster_metrics"
+	TSQueryTraceQLHandlePathConfigPath        = "http.path.ts_traceql"
+	TSQueryExplainHandlePathConfigPath        = "http.path.ts_explain"
+	RemoteReadHandlePathConfigPath            = "http.path.remote_read"
 	FluxHandlePromqlPathConfigPath            = "http.path.promql"
 	PrintHandlePathConfigPath                 = "http.path.print"
 	InfluxDBPrintHandlePathConfigPath         = "http.path.influxdb_print"
@@ -55,6 +63,9 @@ const (
 	FeatureFlagHandlePathConfigPath           = "http.path.feature_flag_path"
 	ESHandlePathConfigPath                    = "http.path.es"
 	TSQueryRawMAXLimitConfigPath              = "http.query.raw.max_limit"
+	TSQueryPatternMaxLimitConfigPath          = "http.query.pattern.max_limit"
+	TSQueryRawStreamPageSizeConfigPath        = "http.query.raw.stream.page_size"
+	TSQueryRawStreamMaxRowsConfigPath         = "http.query.raw.stream.max_rows"
 
 	CheckQueryTsConfigPath     = "http.path.check_query_ts"
 	CheckQueryPromQLConfigPath = "http.path.check_query_promql"
@@ -91,6 +102,14 @@ var (
 
 	QueryMaxRouting int
 
+	QueryPatternMaxLimit int
+
+	QueryRawStreamPageSize int
+	QueryRawStreamMaxRows  int
+
+	RemoteReadSampleLimit     int
+	RemoteReadMaxBytesInFrame int
+
 	ClusterMetricQueryPrefix  string
 	ClusterMetricQueryTimeout time.Duration
 
@@ -0,0 +1,758 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/gin-gonic/gin"
+	ants "github.com/panjf2000/ants/v2"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/function"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/es"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+const (
+	StreamFormatNDJSON = "ndjson"
+	StreamFormatArrow  = "arrow"
+
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeArrow  = "application/vnd.apache.arrow.stream"
+
+	// StreamSummaryKey ndjson 最后一行的汇总信息
+	StreamSummaryKey = "__summary"
+	// StreamSummaryTrailer 通过 http trailer 返回汇总信息，arrow 格式只能通过该方式获取游标
+	StreamSummaryTrailer = "X-Bk-Stream-Summary"
+
+	arrowBatchSize = 1024
+)
+
+// StreamSummary 流式返回结束之后的汇总信息，ResultTableOptions 为空时说明数据已经全部返回
+type StreamSummary struct {
+	Total              int64                       `json:"total"`
+	Rows               int64                       `json:"rows"`
+	ResultTableOptions metadata.ResultTableOptions `json:"result_table_options,omitempty"`
+	TraceID            string                      `json:"trace_id,omitempty"`
+	Error              string                      `json:"error,omitempty"`
+}
+
+// streamFormat 通过 format 参数或者 Accept 头判断是否使用流式返回，为空时使用普通的 json 返回
+func streamFormat(c *gin.Context) string {
+	switch c.Query("format") {
+	case StreamFormatNDJSON:
+		return StreamFormatNDJSON
+	case StreamFormatArrow:
+		return StreamFormatArrow
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, ContentTypeNDJSON):
+		return StreamFormatNDJSON
+	case strings.Contains(accept, ContentTypeArrow):
+		return StreamFormatArrow
+	}
+	return ""
+}
+
+// rowWriter 流式写入每一行数据
+type rowWriter interface {
+	Write(row map[string]any) error
+	Flush() error
+	Close(summary *StreamSummary) error
+}
+
+func newRowWriter(format string, w http.ResponseWriter, columns []string) (rowWriter, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrResponseNotFlusher
+	}
+
+	// 汇总信息在数据全部返回之后才能确定，需要提前声明 trailer
+	w.Header().Set("Trailer", StreamSummaryTrailer)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	switch format {
+	case StreamFormatArrow:
+		w.Header().Set("Content-Type", ContentTypeArrow)
+		return &arrowWriter{w: w, f: f, columns: columns}, nil
+	default:
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+		return &ndjsonWriter{w: w, f: f}, nil
+	}
+}
+
+func writeSummaryTrailer(w http.ResponseWriter, summary *StreamSummary) {
+	s, _ := json.Marshal(summary)
+	w.Header().Set(StreamSummaryTrailer, string(s))
+}
+
+// ndjsonWriter 每行一个 json 对象，最后一行为汇总信息
+type ndjsonWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func (n *ndjsonWriter) Write(row map[string]any) error {
+	s, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = n.w.Write(append(s, '\n'))
+	return err
+}
+
+func (n *ndjsonWriter) Flush() error {
+	n.f.Flush()
+	return nil
+}
+
+func (n *ndjsonWriter) Close(summary *StreamSummary) error {
+	err := n.Write(map[string]any{
+		StreamSummaryKey: summary,
+	})
+	writeSummaryTrailer(n.w, summary)
+	n.f.Flush()
+	return err
+}
+
+// arrowWriter 按批写入 arrow ipc stream，schema 由指定的字段或者第一批数据确定，之后出现的字段会被忽略
+type arrowWriter struct {
+	w       http.ResponseWriter
+	f       http.Flusher
+	columns []string
+
+	rows   []map[string]any
+	mem    memory.Allocator
+	schema *arrow.Schema
+	writer *ipc.Writer
+}
+
+func (a *arrowWriter) Write(row map[string]any) error {
+	a.rows = append(a.rows, row)
+	if len(a.rows) >= arrowBatchSize {
+		return a.writeBatch()
+	}
+	return nil
+}
+
+func (a *arrowWriter) Flush() error {
+	if err := a.writeBatch(); err != nil {
+		return err
+	}
+	a.f.Flush()
+	return nil
+}
+
+func (a *arrowWriter) Close(summary *StreamSummary) error {
+	err := a.writeBatch()
+	if err == nil && a.writer == nil {
+		// 没有数据的情况下也需要返回 schema
+		err = a.start()
+	}
+	if a.writer != nil {
+		if closeErr := a.writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	writeSummaryTrailer(a.w, summary)
+	a.f.Flush()
+	return err
+}
+
+func (a *arrowWriter) start() error {
+	a.mem = memory.NewGoAllocator()
+	a.schema = arrowSchema(a.columns, a.rows)
+	a.writer = ipc.NewWriter(a.w, ipc.WithSchema(a.schema), ipc.WithAllocator(a.mem))
+	return nil
+}
+
+func (a *arrowWriter) writeBatch() error {
+	if len(a.rows) == 0 {
+		return nil
+	}
+	if a.writer == nil {
+		if err := a.start(); err != nil {
+			return err
+		}
+	}
+
+	b := array.NewRecordBuilder(a.mem, a.schema)
+	defer b.Release()
+
+	for i, field := range a.schema.Fields() {
+		for _, row := range a.rows {
+			appendArrowValue(b.Field(i), field.Type, row[field.Name])
+		}
+	}
+
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	a.rows = a.rows[:0]
+	return a.writer.Write(rec)
+}
+
+// arrowSchema 字段类型按照第一批数据推断，同一个字段出现多种类型时使用字符串
+func arrowSchema(columns []string, rows []map[string]any) *arrow.Schema {
+	if len(columns) == 0 {
+		keys := make(map[string]struct{})
+		for _, row := range rows {
+			for k := range row {
+				keys[k] = struct{}{}
+			}
+		}
+		for k := range keys {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+	}
+
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, col := range columns {
+		var dt arrow.DataType
+		for _, row := range rows {
+			v, ok := row[col]
+			if !ok || v == nil {
+				continue
+			}
+
+			var t arrow.DataType
+			switch v.(type) {
+			case float64, float32, int, int64, int32:
+				t = arrow.PrimitiveTypes.Float64
+			case bool:
+				t = arrow.FixedWidthTypes.Boolean
+			default:
+				t = arrow.BinaryTypes.String
+			}
+
+			if dt == nil {
+				dt = t
+			} else if dt.ID() != t.ID() {
+				dt = arrow.BinaryTypes.String
+				break
+			}
+		}
+		if dt == nil {
+			dt = arrow.BinaryTypes.String
+		}
+
+		fields = append(fields, arrow.Field{Name: col, Type: dt, Nullable: true})
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func appendArrowValue(b array.Builder, dt arrow.DataType, v any) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+
+	switch dt.ID() {
+	case arrow.FLOAT64:
+		switch n := v.(type) {
+		case float64:
+			b.(*array.Float64Builder).Append(n)
+		case float32:
+			b.(*array.Float64Builder).Append(float64(n))
+		case int:
+			b.(*array.Float64Builder).Append(float64(n))
+		case int64:
+			b.(*array.Float64Builder).Append(float64(n))
+		case int32:
+			b.(*array.Float64Builder).Append(float64(n))
+		default:
+			b.AppendNull()
+		}
+	case arrow.BOOL:
+		if n, ok := v.(bool); ok {
+			b.(*array.BooleanBuilder).Append(n)
+		} else {
+			b.AppendNull()
+		}
+	default:
+		switch s := v.(type) {
+		case string:
+			b.(*array.StringBuilder).Append(s)
+		case float64, float32, int, int64, int32, bool:
+			b.(*array.StringBuilder).Append(fmt.Sprint(s))
+		default:
+			// 嵌套结构使用 json 字符串
+			js, err := json.Marshal(s)
+			if err != nil {
+				b.AppendNull()
+				return
+			}
+			b.(*array.StringBuilder).Append(string(js))
+		}
+	}
+}
+
+type rawStreamKey struct {
+	tableID string
+	address string
+}
+
+// rawStreamPage 单个查询的翻页状态，优先使用存储返回的 scroll / search_after 游标，没有游标的存储使用 from 翻页
+type rawStreamPage struct {
+	qry  *metadata.Query
+	from int
+
+	// rows 本页已经返回的行数，written 累计已经返回的行数
+	rows    map[rawStreamKey]int
+	written map[rawStreamKey]int
+	// truncated 达到最大行数之后本页有数据被丢弃，存储返回的游标已经越过丢弃的数据，只能使用 from 翻页
+	truncated bool
+	options   metadata.ResultTableOptions
+	total     int64
+	err       error
+}
+
+func newRawStreamPage(qry *metadata.Query) *rawStreamPage {
+	return &rawStreamPage{
+		qry:     qry,
+		from:    qry.From,
+		written: make(map[rawStreamKey]int),
+	}
+}
+
+func (p *rawStreamPage) reset() {
+	p.rows = make(map[rawStreamKey]int)
+	p.truncated = false
+	p.options = nil
+	p.total = 0
+	p.err = nil
+}
+
+// next 计算下一页的翻页配置，本页返回的数据都不满一页时说明已经查询完毕
+func (p *rawStreamPage) next() (metadata.ResultTableOptions, bool) {
+	full := p.truncated
+	for _, n := range p.rows {
+		if p.qry.Size > 0 && n >= p.qry.Size {
+			full = true
+			break
+		}
+	}
+	if !full {
+		return nil, false
+	}
+
+	options := make(metadata.ResultTableOptions)
+	for key, n := range p.rows {
+		if p.truncated {
+			options.SetOption(key.tableID, key.address, &metadata.ResultTableOption{
+				From: function.IntPoint(p.from + p.written[key]),
+			})
+			continue
+		}
+
+		option := p.options.GetOption(key.tableID, key.address)
+		if option != nil && (option.ScrollID != "" || len(option.SearchAfter) > 0) {
+			options.SetOption(key.tableID, key.address, option)
+			continue
+		}
+
+		from := p.from
+		if prev := p.qry.ResultTableOptions.GetOption(key.tableID, key.address); prev != nil && prev.From != nil {
+			from = *prev.From
+		}
+		options.SetOption(key.tableID, key.address, &metadata.ResultTableOption{
+			From: function.IntPoint(from + n),
+		})
+	}
+	return options, true
+}
+
+// rawStreamRow 查询返回的一行数据以及所属的查询
+type rawStreamRow struct {
+	page *rawStreamPage
+	data map[string]any
+}
+
+// queryRawStreamPage 并发查询当前页的数据，通过 write 逐行返回
+// write 返回 errStreamMaxRows 时丢弃剩余数据并标记对应的查询被截断，其它异常则取消查询
+func queryRawStreamPage(ctx context.Context, pages []*rawStreamPage, start, end time.Time, write func(map[string]any) error) error {
+	var (
+		writeErr error
+		sendWg   sync.WaitGroup
+		dataCh   = make(chan rawStreamRow)
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p, _ := ants.NewPool(QueryMaxRouting)
+	defer p.Release()
+
+	go func() {
+		defer func() {
+			sendWg.Wait()
+			close(dataCh)
+		}()
+
+		for _, page := range pages {
+			page := page
+			page.reset()
+			sendWg.Add(1)
+
+			submitErr := p.Submit(func() {
+				defer sendWg.Done()
+
+				instance := prometheus.GetTsDbInstance(ctx, page.qry)
+				if instance == nil {
+					log.Warnf(ctx, "not instance in %s", page.qry.StorageID)
+					return
+				}
+
+				ch := make(chan map[string]any)
+				go func() {
+					defer close(ch)
+					page.total, page.options, page.err = instance.QueryRawData(ctx, page.qry, start, end, ch)
+				}()
+
+				for d := range ch {
+					dataCh <- rawStreamRow{page: page, data: d}
+				}
+			})
+			if submitErr != nil {
+				page.err = submitErr
+				sendWg.Done()
+			}
+		}
+	}()
+
+	for row := range dataCh {
+		key := rawStreamKey{}
+		key.tableID, _ = row.data[elasticsearch.KeyTableID].(string)
+		key.address, _ = row.data[elasticsearch.KeyAddress].(string)
+
+		if writeErr == nil {
+			delete(row.data, elasticsearch.KeyAddress)
+			writeErr = write(row.data)
+			if writeErr == nil {
+				row.page.rows[key]++
+				row.page.written[key]++
+				continue
+			}
+			if !errors.Is(writeErr, errStreamMaxRows) {
+				cancel()
+			}
+		}
+
+		if errors.Is(writeErr, errStreamMaxRows) {
+			row.page.truncated = true
+			if _, ok := row.page.rows[key]; !ok {
+				row.page.rows[key] = 0
+			}
+		}
+	}
+
+	if errors.Is(writeErr, errStreamMaxRows) {
+		return nil
+	}
+	return writeErr
+}
+
+// queryRawStream 按页查询原始数据并流式返回，多个结果表之间不进行排序，Limit 为最多返回的行数，按页截断
+func queryRawStream(ctx context.Context, queryTs *structured.QueryTs, w rowWriter) (summary *StreamSummary, err error) {
+	ctx, span := trace.NewSpan(ctx, "query-raw-stream")
+	defer span.End(&err)
+
+	summary = &StreamSummary{}
+
+	unit, start, end, err := function.QueryTimestamp(queryTs.Start, queryTs.End)
+	if err != nil {
+		return
+	}
+	metadata.GetQueryParams(ctx).SetTime(start, end, unit)
+
+	maxRows := int64(QueryRawStreamMaxRows)
+	if queryTs.Limit > 0 && int64(queryTs.Limit) < maxRows {
+		maxRows = int64(queryTs.Limit)
+	}
+	pageSize := QueryRawStreamPageSize
+	if int64(pageSize) > maxRows {
+		pageSize = int(maxRows)
+	}
+	// 流式查询中 Limit 用于控制每页的数量
+	queryTs.Limit = pageSize
+	queryTs.IsMultiFrom = false
+
+	span.Set("stream-max-rows", maxRows)
+	span.Set("stream-page-size", pageSize)
+
+	queryList, err := queryRawList(ctx, queryTs)
+	if err != nil {
+		return
+	}
+
+	pages := make([]*rawStreamPage, 0, len(queryList))
+	for _, qry := range queryList {
+		pages = append(pages, newRawStreamPage(qry))
+	}
+
+	// 超过最大行数的数据直接丢弃，保证最后一页也不会超过 maxRows
+	write := func(d map[string]any) error {
+		if summary.Rows >= maxRows {
+			return errStreamMaxRows
+		}
+		summary.Rows++
+		return w.Write(d)
+	}
+
+	var pageNum int
+	for len(pages) > 0 && summary.Rows < maxRows {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// 最后一页只查询剩余的行数
+		for _, page := range pages {
+			if remain := maxRows - summary.Rows; int64(page.qry.Size) > remain {
+				page.qry.Size = int(remain)
+			}
+		}
+
+		err = queryRawStreamPage(ctx, pages, start, end, write)
+		if err != nil {
+			return
+		}
+		if err = w.Flush(); err != nil {
+			return
+		}
+		pageNum++
+
+		var (
+			active  []*rawStreamPage
+			message strings.Builder
+		)
+		summary.ResultTableOptions = nil
+		for _, page := range pages {
+			if page.err != nil {
+				message.WriteString(fmt.Sprintf("query %s:%s is error: %s ", page.qry.TableID, page.qry.Fields, page.err.Error()))
+				continue
+			}
+
+			// 总数只取第一页的结果
+			if pageNum == 1 {
+				summary.Total += page.total
+			}
+
+			options, ok := page.next()
+			if !ok {
+				continue
+			}
+
+			page.qry.ResultTableOptions = options
+			if summary.ResultTableOptions == nil {
+				summary.ResultTableOptions = make(metadata.ResultTableOptions)
+			}
+			summary.ResultTableOptions.MergeOptions(options)
+			active = append(active, page)
+		}
+		if message.Len() > 0 {
+			err = fmt.Errorf("%s", message.String())
+			return
+		}
+		pages = active
+	}
+
+	span.Set("stream-page-num", pageNum)
+	span.Set("stream-rows", summary.Rows)
+	return
+}
+
+// handlerQueryRawStream 流式返回原始数据，开始写入数据之后的异常只能通过汇总信息返回
+func handlerQueryRawStream(ctx context.Context, c *gin.Context, queryTs *structured.QueryTs, format string, traceID string) error {
+	return handlerStream(ctx, c, queryTs.ResultColumns, format, traceID, func(w rowWriter) (*StreamSummary, error) {
+		return queryRawStream(ctx, queryTs, w)
+	})
+}
+
+// handlerESStream 流式返回 es 查询结果
+func handlerESStream(ctx context.Context, c *gin.Context, params *es.Params, format string, traceID string) error {
+	return handlerStream(ctx, c, nil, format, traceID, func(w rowWriter) (*StreamSummary, error) {
+		return queryESStream(ctx, params, w, es.Query)
+	})
+}
+
+func handlerStream(ctx context.Context, c *gin.Context, columns []string, format string, traceID string, query func(w rowWriter) (*StreamSummary, error)) error {
+	w, err := newRowWriter(format, c.Writer, columns)
+	if err != nil {
+		return err
+	}
+
+	summary, err := query(w)
+	if err != nil && summary.Rows == 0 && !c.Writer.Written() {
+		// 还没有返回数据时，按照普通的错误返回
+		c.Writer.Header().Del("Trailer")
+		return err
+	}
+
+	summary.TraceID = traceID
+	if err != nil {
+		log.Errorf(ctx, "query stream error: %s", err.Error())
+		summary.Error = err.Error()
+	}
+
+	c.Status(http.StatusOK)
+	if closeErr := w.Close(summary); closeErr != nil {
+		log.Warnf(ctx, "query stream close error: %s", closeErr.Error())
+	}
+	return nil
+}
+
+// esSearchResult es _search 返回中流式查询需要的部分
+type esSearchResult struct {
+	Error any `json:"error,omitempty"`
+	Hits  struct {
+		// Total es 7 以上为对象，es 6 为数字
+		Total any `json:"total"`
+		Hits  []struct {
+			Index  string         `json:"_index"`
+			ID     string         `json:"_id"`
+			Source map[string]any `json:"_source"`
+			Sort   []any          `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (r *esSearchResult) total() int64 {
+	switch t := r.Hits.Total.(type) {
+	case map[string]any:
+		v, _ := t["value"].(float64)
+		return int64(v)
+	case float64:
+		return int64(t)
+	}
+	return 0
+}
+
+// queryESStream 使用 search_after 逐页查询 es 并流式返回，body 中必须指定 sort，size 为最多返回的行数
+// 每行数据为 _source，并通过 __index、__doc_id 返回所在的索引和文档 ID
+func queryESStream(ctx context.Context, params *es.Params, w rowWriter, search func(*es.Params) (string, error)) (summary *StreamSummary, err error) {
+	ctx, span := trace.NewSpan(ctx, "query-es-stream")
+	defer span.End(&err)
+
+	summary = &StreamSummary{}
+
+	body := make(map[string]any)
+	if err = json.Unmarshal([]byte(params.Body), &body); err != nil {
+		return
+	}
+	if _, ok := body["sort"]; !ok {
+		err = ErrStreamSortRequired
+		return
+	}
+
+	maxRows := int64(QueryRawStreamMaxRows)
+	if size, ok := body["size"].(float64); ok && size > 0 && int64(size) < maxRows {
+		maxRows = int64(size)
+	}
+	pageSize := int64(QueryRawStreamPageSize)
+	if pageSize > maxRows {
+		pageSize = maxRows
+	}
+
+	span.Set("stream-max-rows", maxRows)
+	span.Set("stream-page-size", pageSize)
+
+	var pageNum int
+	for summary.Rows < maxRows {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// 最后一页只查询剩余的行数
+		size := pageSize
+		if remain := maxRows - summary.Rows; size > remain {
+			size = remain
+		}
+		body["size"] = size
+
+		qry := *params
+		b, _ := json.Marshal(body)
+		qry.Body = string(b)
+
+		var res string
+		res, err = search(&qry)
+		if err != nil {
+			return
+		}
+
+		result := &esSearchResult{}
+		if err = json.Unmarshal([]byte(res), result); err != nil {
+			return
+		}
+		if result.Error != nil {
+			e, _ := json.Marshal(result.Error)
+			err = fmt.Errorf("%s", e)
+			return
+		}
+
+		if pageNum == 0 {
+			summary.Total = result.total()
+		}
+		pageNum++
+
+		var searchAfter []any
+		for _, hit := range result.Hits.Hits {
+			row := hit.Source
+			if row == nil {
+				row = make(map[string]any)
+			}
+			row[elasticsearch.KeyIndex] = hit.Index
+			row[elasticsearch.KeyDocID] = hit.ID
+
+			summary.Rows++
+			if err = w.Write(row); err != nil {
+				return
+			}
+			searchAfter = hit.Sort
+		}
+		if err = w.Flush(); err != nil {
+			return
+		}
+
+		// 不满一页说明已经查询完毕
+		summary.ResultTableOptions = nil
+		if int64(len(result.Hits.Hits)) < size || len(searchAfter) == 0 {
+			break
+		}
+		summary.ResultTableOptions = make(metadata.ResultTableOptions)
+		summary.ResultTableOptions.SetOption(params.TableID, "", &metadata.ResultTableOption{
+			SearchAfter: searchAfter,
+		})
+		body["search_after"] = searchAfter
+	}
+
+	span.Set("stream-page-num", pageNum)
+	span.Set("stream-rows", summary.Rows)
+	return
+}
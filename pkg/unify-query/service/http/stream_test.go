@@ -0,0 +1,371 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/function"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/es"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
+)
+
+func TestStreamFormat(t *testing.T) {
+	testCases := map[string]struct {
+		url    string
+		accept string
+		format string
+	}{
+		"默认不使用流式返回": {
+			url: "/query/ts/raw",
+		},
+		"通过参数指定 ndjson": {
+			url:    "/query/ts/raw?format=ndjson",
+			format: StreamFormatNDJSON,
+		},
+		"通过 Accept 指定 arrow": {
+			url:    "/query/ts/raw",
+			accept: ContentTypeArrow,
+			format: StreamFormatArrow,
+		},
+		"参数优先于 Accept": {
+			url:    "/query/ts/raw?format=arrow",
+			accept: ContentTypeNDJSON,
+			format: StreamFormatArrow,
+		},
+		"未知的格式": {
+			url:    "/query/ts/raw?format=csv",
+			accept: "application/json",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gc, _ := gin.CreateTestContext(httptest.NewRecorder())
+			gc.Request = httptest.NewRequest(http.MethodPost, c.url, nil)
+			if c.accept != "" {
+				gc.Request.Header.Set("Accept", c.accept)
+			}
+			assert.Equal(t, c.format, streamFormat(gc))
+		})
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw, err := newRowWriter(StreamFormatNDJSON, w, nil)
+	assert.Nil(t, err)
+
+	rows := []map[string]any{
+		{"__result_table": "result_table.es", "log": "a", "value": 1.0},
+		{"__result_table": "result_table.es", "log": "b", "value": 2.0},
+	}
+	for _, row := range rows {
+		assert.Nil(t, rw.Write(row))
+	}
+	assert.Nil(t, rw.Flush())
+
+	options := make(metadata.ResultTableOptions)
+	options.SetOption("result_table.es", "http://127.0.0.1:9200", &metadata.ResultTableOption{
+		SearchAfter: []any{1700000000000.0, "id"},
+	})
+	summary := &StreamSummary{Total: 10, Rows: 2, ResultTableOptions: options, TraceID: "trace"}
+	assert.Nil(t, rw.Close(summary))
+
+	res := w.Result()
+	assert.Equal(t, ContentTypeNDJSON, res.Header.Get("Content-Type"))
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := make(map[string]any)
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	assert.Len(t, lines, 3)
+	assert.Equal(t, rows, lines[:2])
+
+	actual, _ := json.Marshal(lines[2][StreamSummaryKey])
+	expected, _ := json.Marshal(summary)
+	assert.JSONEq(t, string(expected), string(actual))
+	assert.JSONEq(t, string(expected), res.Trailer.Get(StreamSummaryTrailer))
+}
+
+func TestArrowWriter(t *testing.T) {
+	t.Run("字段类型推断", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rw, err := newRowWriter(StreamFormatArrow, w, nil)
+		assert.Nil(t, err)
+
+		rows := []map[string]any{
+			{"log": "a", "value": 1.0, "ok": true, "mixed": 1.0, "tags": map[string]any{"k": "v"}},
+			{"log": "b", "value": 2, "mixed": "x"},
+			{"log": "c", "value": 3.5, "ok": false, "unknown": "ignored"},
+		}
+		for _, row := range rows {
+			assert.Nil(t, rw.Write(row))
+		}
+		assert.Nil(t, rw.Close(&StreamSummary{Rows: 3}))
+		assert.Equal(t, ContentTypeArrow, w.Header().Get("Content-Type"))
+
+		r, err := ipc.NewReader(w.Body)
+		assert.Nil(t, err)
+		defer r.Release()
+
+		schema := r.Schema()
+		var names []string
+		types := make(map[string]arrow.Type)
+		for _, f := range schema.Fields() {
+			names = append(names, f.Name)
+			types[f.Name] = f.Type.ID()
+		}
+		assert.Equal(t, []string{"log", "mixed", "ok", "tags", "unknown", "value"}, names)
+		assert.Equal(t, map[string]arrow.Type{
+			"log":     arrow.STRING,
+			"mixed":   arrow.STRING,
+			"ok":      arrow.BOOL,
+			"tags":    arrow.STRING,
+			"unknown": arrow.STRING,
+			"value":   arrow.FLOAT64,
+		}, types)
+
+		assert.True(t, r.Next())
+		rec := r.Record()
+		assert.Equal(t, int64(3), rec.NumRows())
+
+		value := rec.Column(5).(*array.Float64)
+		assert.Equal(t, []float64{1, 2, 3.5}, value.Float64Values())
+
+		ok := rec.Column(2).(*array.Boolean)
+		assert.True(t, ok.Value(0))
+		assert.True(t, ok.IsNull(1))
+		assert.False(t, ok.Value(2))
+
+		mixed := rec.Column(1).(*array.String)
+		assert.Equal(t, "1", mixed.Value(0))
+		assert.Equal(t, "x", mixed.Value(1))
+
+		tags := rec.Column(3).(*array.String)
+		assert.Equal(t, `{"k":"v"}`, tags.Value(0))
+
+		assert.False(t, r.Next())
+	})
+
+	t.Run("指定字段以及空数据", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rw, err := newRowWriter(StreamFormatArrow, w, []string{"log"})
+		assert.Nil(t, err)
+		assert.Nil(t, rw.Close(&StreamSummary{}))
+
+		r, err := ipc.NewReader(w.Body)
+		assert.Nil(t, err)
+		defer r.Release()
+
+		assert.Equal(t, 1, len(r.Schema().Fields()))
+		assert.Equal(t, "log", r.Schema().Field(0).Name)
+		assert.False(t, r.Next())
+	})
+}
+
+func TestRawStreamPageNext(t *testing.T) {
+	const (
+		tableID = "result_table.es"
+		address = "http://127.0.0.1:9200"
+	)
+
+	testCases := map[string]struct {
+		qry       *metadata.Query
+		rows      int
+		written   int
+		truncated bool
+		options   metadata.ResultTableOptions
+		ok        bool
+		next      *metadata.ResultTableOption
+	}{
+		"不满一页则结束": {
+			qry:  &metadata.Query{Size: 10},
+			rows: 5,
+		},
+		"使用 search_after 游标": {
+			qry:  &metadata.Query{Size: 10},
+			rows: 10,
+			options: metadata.ResultTableOptions{
+				tableID + "|" + address: {SearchAfter: []any{1.0, "a"}},
+			},
+			ok:   true,
+			next: &metadata.ResultTableOption{SearchAfter: []any{1.0, "a"}},
+		},
+		"没有游标时使用 from 翻页": {
+			qry:  &metadata.Query{Size: 10, From: 5},
+			rows: 10,
+			ok:   true,
+			next: &metadata.ResultTableOption{From: function.IntPoint(15)},
+		},
+		"在上一页的 from 基础上翻页": {
+			qry: &metadata.Query{
+				Size: 10,
+				ResultTableOptions: metadata.ResultTableOptions{
+					tableID + "|" + address: {From: function.IntPoint(20)},
+				},
+			},
+			rows: 10,
+			ok:   true,
+			next: &metadata.ResultTableOption{From: function.IntPoint(30)},
+		},
+		"截断之后按照累计返回的行数使用 from 翻页": {
+			qry:       &metadata.Query{Size: 10, From: 5},
+			rows:      3,
+			written:   13,
+			truncated: true,
+			options: metadata.ResultTableOptions{
+				tableID + "|" + address: {SearchAfter: []any{1.0, "a"}},
+			},
+			ok:   true,
+			next: &metadata.ResultTableOption{From: function.IntPoint(18)},
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			page := newRawStreamPage(c.qry)
+			page.reset()
+			page.rows[rawStreamKey{tableID: tableID, address: address}] = c.rows
+			page.written[rawStreamKey{tableID: tableID, address: address}] = c.written
+			page.truncated = c.truncated
+			page.options = c.options
+
+			options, ok := page.next()
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.next, options.GetOption(tableID, address))
+		})
+	}
+}
+
+// sliceRowWriter 将数据保存在内存中的 rowWriter
+type sliceRowWriter struct {
+	rows []map[string]any
+}
+
+func (s *sliceRowWriter) Write(row map[string]any) error {
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+func (s *sliceRowWriter) Flush() error { return nil }
+
+func (s *sliceRowWriter) Close(_ *StreamSummary) error { return nil }
+
+func TestQueryESStream(t *testing.T) {
+	pageSize, maxRows := QueryRawStreamPageSize, QueryRawStreamMaxRows
+	defer func() {
+		QueryRawStreamPageSize, QueryRawStreamMaxRows = pageSize, maxRows
+	}()
+	QueryRawStreamPageSize, QueryRawStreamMaxRows = 5, 100
+
+	// 模拟 es 按照 time 排序，通过 search_after 翻页
+	const docs = 13
+	search := func(sizes *[]int) func(*es.Params) (string, error) {
+		return func(p *es.Params) (string, error) {
+			body := make(map[string]any)
+			if err := json.Unmarshal([]byte(p.Body), &body); err != nil {
+				return "", err
+			}
+			size := int(body["size"].(float64))
+			*sizes = append(*sizes, size)
+
+			start := 0
+			if after, ok := body["search_after"].([]any); ok {
+				start = int(after[0].(float64)) + 1
+			}
+
+			hits := make([]map[string]any, 0)
+			for i := start; i < docs && len(hits) < size; i++ {
+				hits = append(hits, map[string]any{
+					"_index":  "index_1",
+					"_id":     fmt.Sprintf("id_%d", i),
+					"_source": map[string]any{"time": float64(i)},
+					"sort":    []any{float64(i)},
+				})
+			}
+			res, _ := json.Marshal(map[string]any{
+				"hits": map[string]any{
+					"total": map[string]any{"value": docs},
+					"hits":  hits,
+				},
+			})
+			return string(res), nil
+		}
+	}
+
+	testCases := map[string]struct {
+		body   string
+		sizes  []int
+		rows   int64
+		option *metadata.ResultTableOption
+		err    error
+	}{
+		"全部返回": {
+			body:  `{"sort": [{"time": "asc"}]}`,
+			sizes: []int{5, 5, 5},
+			rows:  docs,
+		},
+		"size 限制最多返回的行数并截断最后一页": {
+			body:   `{"sort": [{"time": "asc"}], "size": 7}`,
+			sizes:  []int{5, 2},
+			rows:   7,
+			option: &metadata.ResultTableOption{SearchAfter: []any{6.0}},
+		},
+		"从 search_after 继续查询": {
+			body:  `{"sort": [{"time": "asc"}], "search_after": [9]}`,
+			sizes: []int{5},
+			rows:  3,
+		},
+		"没有指定 sort": {
+			body: `{"size": 7}`,
+			err:  ErrStreamSortRequired,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var (
+				sizes []int
+				w     = &sliceRowWriter{}
+			)
+			summary, err := queryESStream(context.Background(), &es.Params{TableID: "table_1", Body: c.body}, w, search(&sizes))
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.sizes, sizes)
+			assert.Equal(t, c.rows, summary.Rows)
+			assert.Len(t, w.rows, int(c.rows))
+			assert.Equal(t, int64(docs), summary.Total)
+			assert.Equal(t, c.option, summary.ResultTableOptions.GetOption("table_1", ""))
+			if len(w.rows) > 0 {
+				assert.Equal(t, "index_1", w.rows[0][elasticsearch.KeyIndex])
+			}
+		})
+	}
+}
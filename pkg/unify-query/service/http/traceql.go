@@ -0,0 +1,218 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/panjf2000/ants/v2"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/internal/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/traceql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// HandlerQueryTraceQL
+// @Summary  query trace by traceql
+// @ID       query_ts_traceql
+// @Produce  json
+// @Param    traceparent            header    string                        false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                        false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                        false  "空间UID" default(bkcc__2)
+// @Param	 X-Bk-Scope-Skip-Space  header	  string						false  "是否跳过空间验证" default()
+// @Param    data                  	body      traceql.QueryTraceQL  		true   "json data"
+// @Success  200                   	{object}  TraceListData
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/traceql [post]
+func HandlerQueryTraceQL(c *gin.Context) {
+	var (
+		ctx       = c.Request.Context()
+		resp      = &response{c: c}
+		user      = metadata.GetUser(ctx)
+		err       error
+		span      *trace.Span
+		traceData TraceListData
+	)
+
+	ctx, span = trace.NewSpan(ctx, "handler-query-traceql")
+	defer func() {
+		if err != nil {
+			log.Errorf(ctx, err.Error())
+			resp.failed(ctx, err)
+		}
+
+		span.End(&err)
+	}()
+
+	span.Set("request-url", c.Request.URL.String())
+	span.Set("request-header", c.Request.Header)
+	span.Set("query-source", user.Key)
+	span.Set("query-space-uid", user.SpaceUid)
+
+	// 解析请求 body
+	query := &traceql.QueryTraceQL{}
+	err = json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		return
+	}
+
+	// metadata 中的 spaceUid 是从 header 头信息中获取
+	if user.SpaceUid != "" {
+		query.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(query)
+	span.Set("query-body", string(queryStr))
+	span.Set("query-traceql", query.TraceQL)
+
+	log.Infof(ctx, fmt.Sprintf("header: %+v, body: %s", c.Request.Header, queryStr))
+
+	traceData.TraceID = span.TraceID()
+	traceData.List, err = queryTraceQL(ctx, query)
+	if err != nil {
+		return
+	}
+	traceData.Total = len(traceData.List)
+
+	resp.success(ctx, traceData)
+}
+
+// queryTraceQL 先按照每个 spanset 过滤条件查询出候选 TraceID 并按组合关系合并，再拉取完整 trace 在内存中执行结构化匹配
+func queryTraceQL(ctx context.Context, query *traceql.QueryTraceQL) (list []*traceql.TraceSummary, err error) {
+	ctx, span := trace.NewSpan(ctx, "query-traceql")
+	defer span.End(&err)
+
+	if query.TableID == "" {
+		err = traceql.ErrEmptyTableID
+		return
+	}
+	if query.Limit <= 0 {
+		query.Limit = traceql.DefaultTraceLimit
+	}
+	if query.SpanLimit <= 0 {
+		query.SpanLimit = traceql.DefaultSpanLimit
+	}
+
+	expr, err := traceql.Parse(query.TraceQL)
+	if err != nil {
+		return
+	}
+	span.Set("traceql-expr", expr.String())
+
+	traceIDs, err := traceql.CandidateTraceIDs(expr, func(filter *traceql.SpansetFilter) ([]string, error) {
+		queryTs, qErr := query.SpanQueryTs(filter)
+		if qErr != nil {
+			return nil, qErr
+		}
+
+		_, spans, _, qErr := queryRawWithInstance(ctx, queryTs)
+		if qErr != nil {
+			return nil, qErr
+		}
+
+		ids := make([]string, 0, len(spans))
+		idSet := make(map[string]struct{}, len(spans))
+		for _, s := range spans {
+			traceID, ok := s[traceql.FieldTraceID].(string)
+			if !ok || traceID == "" {
+				continue
+			}
+			if _, ok = idSet[traceID]; ok {
+				continue
+			}
+			idSet[traceID] = struct{}{}
+			ids = append(ids, traceID)
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return
+	}
+
+	// 所有过滤条件合并之后再截断，保证 && 条件能够收窄候选集
+	if candidateLimit := query.CandidateLimit(); len(traceIDs) > candidateLimit {
+		traceIDs = traceIDs[:candidateLimit]
+	}
+	span.Set("candidate-trace-num", len(traceIDs))
+
+	list = make([]*traceql.TraceSummary, 0)
+	if len(traceIDs) == 0 {
+		return
+	}
+
+	traces, err := queryTraces(ctx, query, traceIDs)
+	if err != nil {
+		return
+	}
+
+	for _, t := range traces {
+		matched := expr.Eval(t)
+		if len(matched) == 0 {
+			continue
+		}
+		list = append(list, t.Summary(matched))
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].StartTime > list[j].StartTime
+	})
+	if len(list) > query.Limit {
+		list = list[:query.Limit]
+	}
+
+	span.Set("trace-num", len(list))
+	return
+}
+
+// queryTraces 按 TraceID 并发拉取完整 trace，每个 trace 单独查询以保证 SpanLimit 作用于单个 trace
+func queryTraces(ctx context.Context, query *traceql.QueryTraceQL, traceIDs []string) ([]*traceql.Trace, error) {
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		spans   = make([]map[string]any, 0)
+		errList = make([]error, 0)
+	)
+
+	p, _ := ants.NewPool(QueryMaxRouting)
+	defer p.Release()
+
+	for _, traceID := range traceIDs {
+		traceID := traceID
+		wg.Add(1)
+		err := p.Submit(func() {
+			defer wg.Done()
+
+			_, list, _, qErr := queryRawWithInstance(ctx, query.TraceQueryTs(traceID))
+			lock.Lock()
+			defer lock.Unlock()
+			if qErr != nil {
+				errList = append(errList, qErr)
+				return
+			}
+			spans = append(spans, list...)
+		})
+		if err != nil {
+			wg.Done()
+			return nil, err
+		}
+	}
+	wg.Wait()
+
+	if len(errList) > 0 {
+		return nil, errList[0]
+	}
+	return traceql.NewTraces(spans), nil
+}
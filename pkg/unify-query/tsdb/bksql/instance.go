@@ -134,6 +134,15 @@ func (i *Instance) sqlQuery(ctx context.Context, sql string) (*QuerySyncResultDa
 	ctx, cancel := context.WithTimeout(ctx, i.timeout)
 	defer cancel()
 
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), "", "", sql)
+	defer func() {
+		rowNum := 0
+		if data != nil {
+			rowNum = len(data.List)
+		}
+		explainQuery.Done(0, rowNum, err)
+	}()
+
 	// 发起异步查询
 	res := i.client.QuerySync(ctx, sql, span)
 	if err = i.checkResult(res); err != nil {
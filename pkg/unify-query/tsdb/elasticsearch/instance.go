@@ -301,13 +301,22 @@ func (i *Instance) esQuery(ctx context.Context, qo *queryOption, fact *FormatFac
 	log.Infof(ctx, "elasticsearch-query indexes: %s", qo.indexes)
 	log.Infof(ctx, "elasticsearch-query body: %s", bodyString)
 
+	var res *elastic.SearchResult
+
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), qo.conn.Address, qb.TableID, bodyString)
+	defer func() {
+		rowNum := 0
+		if res != nil && res.Hits != nil {
+			rowNum = len(res.Hits.Hits)
+		}
+		explainQuery.Done(0, rowNum, err)
+	}()
+
 	startAnalyze := time.Now()
 	client, err := i.getClient(ctx, qo.conn)
 	if err != nil {
 		return nil, err
 	}
-
-	var res *elastic.SearchResult
 	func() {
 		if qb.ResultTableOptions != nil {
 			opt := qb.ResultTableOptions.GetOption(qb.TableID, qo.conn.Address)
@@ -442,6 +442,11 @@ func (i *Instance) query(
 		return nil, err
 	}
 
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), i.host, query.TableID, sql)
+	defer func() {
+		explainQuery.Done(seriesNum, pointNum, err)
+	}()
+
 	values := &url.Values{}
 	values.Set("db", query.DB)
 	values.Set("q", sql)
@@ -622,7 +627,10 @@ func (i *Instance) grpcStream(
 	filterRequest, _ := json.Marshal(req)
 	span.Set("grpc-query-filter-request", string(filterRequest))
 
+	// 流式查询无法提前统计返回数量，只记录查询语句以及建立连接的耗时
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), urlPath, fmt.Sprintf("%s.%s", db, measurement), string(filterRequest))
 	stream, err := client.Raw(ctx, req)
+	explainQuery.Done(0, 0, err)
 	if err != nil {
 		log.Errorf(ctx, err.Error())
 		return storage.EmptySeriesSet()
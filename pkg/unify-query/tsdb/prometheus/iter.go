@@ -10,6 +10,9 @@
 package prometheus
 
 import (
+	"sort"
+
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/storage"
 )
 
@@ -54,3 +57,51 @@ func (c *lazySeriesSet) Warnings() storage.Warnings {
 	}
 	return nil
 }
+
+// sortedSeriesSet 按照标签排序的 SeriesSet，用于满足 Select(sortSeries=true) 的语义
+type sortedSeriesSet struct {
+	series   []storage.Series
+	idx      int
+	warnings storage.Warnings
+}
+
+// newSortedSeriesSet 读取全部 series 后按标签排序，原 set 出错时直接返回错误
+func newSortedSeriesSet(set storage.SeriesSet) storage.SeriesSet {
+	series := make([]storage.Series, 0)
+	for set.Next() {
+		series = append(series, set.At())
+	}
+	if set.Err() != nil {
+		return storage.ErrSeriesSet(set.Err())
+	}
+
+	sort.SliceStable(series, func(i, j int) bool {
+		return labels.Compare(series[i].Labels(), series[j].Labels()) < 0
+	})
+	return &sortedSeriesSet{
+		series:   series,
+		idx:      -1,
+		warnings: set.Warnings(),
+	}
+}
+
+// Next
+func (s *sortedSeriesSet) Next() bool {
+	s.idx++
+	return s.idx < len(s.series)
+}
+
+// At
+func (s *sortedSeriesSet) At() storage.Series {
+	return s.series[s.idx]
+}
+
+// Err
+func (s *sortedSeriesSet) Err() error {
+	return nil
+}
+
+// Warnings
+func (s *sortedSeriesSet) Warnings() storage.Warnings {
+	return s.warnings
+}
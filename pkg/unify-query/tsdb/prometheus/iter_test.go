@@ -0,0 +1,65 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package prometheus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// listSeriesSet 按照传入顺序返回 series 的 SeriesSet
+type listSeriesSet struct {
+	series []storage.Series
+	idx    int
+	err    error
+}
+
+func (s *listSeriesSet) Next() bool {
+	s.idx++
+	return s.idx < len(s.series)
+}
+
+func (s *listSeriesSet) At() storage.Series         { return s.series[s.idx] }
+func (s *listSeriesSet) Err() error                 { return s.err }
+func (s *listSeriesSet) Warnings() storage.Warnings { return nil }
+
+func TestSortedSeriesSet(t *testing.T) {
+	unsorted := &listSeriesSet{
+		idx: -1,
+		series: []storage.Series{
+			storage.NewListSeries(labels.FromStrings("__name__", "m", "pod", "c"), []tsdbutil.Sample{}),
+			storage.NewListSeries(labels.FromStrings("__name__", "m", "pod", "a"), []tsdbutil.Sample{}),
+			storage.NewListSeries(labels.FromStrings("__name__", "m", "ns", "x", "pod", "b"), []tsdbutil.Sample{}),
+			storage.NewListSeries(labels.FromStrings("__name__", "m", "pod", "b"), []tsdbutil.Sample{}),
+		},
+	}
+
+	set := newSortedSeriesSet(unsorted)
+	var actual []string
+	for set.Next() {
+		actual = append(actual, set.At().Labels().String())
+	}
+	assert.Nil(t, set.Err())
+	assert.Equal(t, []string{
+		`{__name__="m", ns="x", pod="b"}`,
+		`{__name__="m", pod="a"}`,
+		`{__name__="m", pod="b"}`,
+		`{__name__="m", pod="c"}`,
+	}, actual)
+
+	errSet := newSortedSeriesSet(&listSeriesSet{idx: -1, err: errors.New("query error")})
+	assert.False(t, errSet.Next())
+	assert.EqualError(t, errSet.Err(), "query error")
+}
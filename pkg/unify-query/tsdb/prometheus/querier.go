@@ -190,7 +190,8 @@ func (q *Querier) selectFn(hints *storage.SelectHints, matchers ...*labels.Match
 	return set
 }
 
-func (q *Querier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+// Select 查询原始数据，sortSeries 为 true 时返回按照标签排序的 series
+func (q *Querier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
 	promise := make(chan storage.SeriesSet, 1)
 	go func() {
 		defer close(promise)
@@ -199,7 +200,11 @@ func (q *Querier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels
 			return
 		}
 
-		promise <- q.selectFn(hints, matchers...)
+		set := q.selectFn(hints, matchers...)
+		if sortSeries {
+			set = newSortedSeriesSet(set)
+		}
+		promise <- set
 	}()
 
 	return &lazySeriesSet{
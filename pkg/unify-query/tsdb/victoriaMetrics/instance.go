@@ -471,12 +471,22 @@ func (i *Instance) DirectQueryRange(
 		return nil, err
 	}
 
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), i.url, "", string(sql))
 	err = i.vmQuery(ctx, string(sql), vmResp, span)
 	if err != nil {
+		explainQuery.Done(0, 0, err)
 		return nil, err
 	}
 
-	return i.matrixFormat(ctx, vmResp, span)
+	matrix, err := i.matrixFormat(ctx, vmResp, span)
+
+	pointNum := 0
+	for _, series := range matrix {
+		pointNum += len(series.Points)
+	}
+	explainQuery.Done(len(matrix), pointNum, err)
+
+	return matrix, err
 }
 
 // Query instant 查询
@@ -532,12 +542,17 @@ func (i *Instance) DirectQuery(
 		return nil, err
 	}
 
+	explainQuery := metadata.GetExplain(ctx).StartQuery(i.InstanceType(), i.url, "", string(sql))
 	err = i.vmQuery(ctx, string(sql), vmResp, span)
 	if err != nil {
+		explainQuery.Done(0, 0, err)
 		return nil, err
 	}
 
-	return i.vectorFormat(ctx, vmResp, span)
+	vector, err := i.vectorFormat(ctx, vmResp, span)
+	explainQuery.Done(len(vector), len(vector), err)
+
+	return vector, err
 }
 
 func (i *Instance) QuerySeries(ctx context.Context, query *metadata.Query, start, end time.Time) (series []map[string]string, err error) {
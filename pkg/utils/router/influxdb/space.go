@@ -72,6 +72,13 @@ type Record struct {
 	EnableTime int64 `json:"enable_time,omitempty"`
 }
 
+// Rollup 预聚合结果表，按照 Interval 周期对原始数据进行 Functions 聚合后写入 TableId
+type Rollup struct {
+	TableId   string   `json:"table_id"`
+	Interval  string   `json:"interval"`
+	Functions []string `json:"functions"`
+}
+
 //go:generate msgp -tests=false
 type ResultTableDetail struct {
 	StorageId             int64    `json:"storage_id"`
@@ -90,6 +97,7 @@ type ResultTableDetail struct {
 	TagsKey               []string `json:"tags_key"`
 	DataId                int64    `json:"bk_data_id"`
 	SourceType            string   `json:"source_type"`
+	Rollups               []Rollup `json:"rollups,omitempty"`
 	Options               struct {
 		// 自定义时间聚合字段
 		TimeField TimeField `json:"time_field"`
@@ -814,15 +814,34 @@ func (z *ResultTableDetail) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "SourceType")
 				return
 			}
-		case "Options":
+		case "Rollups":
 			var zb0006 uint32
-			zb0006, err = dc.ReadMapHeader()
+			zb0006, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Rollups")
+				return
+			}
+			if cap(z.Rollups) >= int(zb0006) {
+				z.Rollups = (z.Rollups)[:zb0006]
+			} else {
+				z.Rollups = make([]Rollup, zb0006)
+			}
+			for za0004 := range z.Rollups {
+				err = z.Rollups[za0004].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Rollups", za0004)
+					return
+				}
+			}
+		case "Options":
+			var zb0007 uint32
+			zb0007, err = dc.ReadMapHeader()
 			if err != nil {
 				err = msgp.WrapError(err, "Options")
 				return
 			}
-			for zb0006 > 0 {
-				zb0006--
+			for zb0007 > 0 {
+				zb0007--
 				field, err = dc.ReadMapKeyPtr()
 				if err != nil {
 					err = msgp.WrapError(err, "Options")
@@ -830,14 +849,14 @@ func (z *ResultTableDetail) DecodeMsg(dc *msgp.Reader) (err error) {
 				}
 				switch msgp.UnsafeString(field) {
 				case "TimeField":
-					var zb0007 uint32
-					zb0007, err = dc.ReadMapHeader()
+					var zb0008 uint32
+					zb0008, err = dc.ReadMapHeader()
 					if err != nil {
 						err = msgp.WrapError(err, "Options", "TimeField")
 						return
 					}
-					for zb0007 > 0 {
-						zb0007--
+					for zb0008 > 0 {
+						zb0008--
 						field, err = dc.ReadMapKeyPtr()
 						if err != nil {
 							err = msgp.WrapError(err, "Options", "TimeField")
@@ -897,9 +916,9 @@ func (z *ResultTableDetail) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *ResultTableDetail) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 17
+	// map header, size 18
 	// write "StorageId"
-	err = en.Append(0xde, 0x0, 0x11, 0xa9, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x49, 0x64)
+	err = en.Append(0xde, 0x0, 0x12, 0xa9, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x49, 0x64)
 	if err != nil {
 		return
 	}
@@ -1095,6 +1114,23 @@ func (z *ResultTableDetail) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "SourceType")
 		return
 	}
+	// write "Rollups"
+	err = en.Append(0xa7, 0x52, 0x6f, 0x6c, 0x6c, 0x75, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Rollups)))
+	if err != nil {
+		err = msgp.WrapError(err, "Rollups")
+		return
+	}
+	for za0004 := range z.Rollups {
+		err = z.Rollups[za0004].EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Rollups", za0004)
+			return
+		}
+	}
 	// write "Options"
 	err = en.Append(0xa7, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73)
 	if err != nil {
@@ -1153,9 +1189,9 @@ func (z *ResultTableDetail) EncodeMsg(en *msgp.Writer) (err error) {
 // MarshalMsg implements msgp.Marshaler
 func (z *ResultTableDetail) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 17
+	// map header, size 18
 	// string "StorageId"
-	o = append(o, 0xde, 0x0, 0x11, 0xa9, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x49, 0x64)
+	o = append(o, 0xde, 0x0, 0x12, 0xa9, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x49, 0x64)
 	o = msgp.AppendInt64(o, z.StorageId)
 	// string "StorageName"
 	o = append(o, 0xab, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x4e, 0x61, 0x6d, 0x65)
@@ -1217,6 +1253,16 @@ func (z *ResultTableDetail) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "SourceType"
 	o = append(o, 0xaa, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x54, 0x79, 0x70, 0x65)
 	o = msgp.AppendString(o, z.SourceType)
+	// string "Rollups"
+	o = append(o, 0xa7, 0x52, 0x6f, 0x6c, 0x6c, 0x75, 0x70, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Rollups)))
+	for za0004 := range z.Rollups {
+		o, err = z.Rollups[za0004].MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Rollups", za0004)
+			return
+		}
+	}
 	// string "Options"
 	o = append(o, 0xa7, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73)
 	// map header, size 2
@@ -1420,15 +1466,34 @@ func (z *ResultTableDetail) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "SourceType")
 				return
 			}
-		case "Options":
+		case "Rollups":
 			var zb0006 uint32
-			zb0006, bts, err = msgp.ReadMapHeaderBytes(bts)
+			zb0006, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Rollups")
+				return
+			}
+			if cap(z.Rollups) >= int(zb0006) {
+				z.Rollups = (z.Rollups)[:zb0006]
+			} else {
+				z.Rollups = make([]Rollup, zb0006)
+			}
+			for za0004 := range z.Rollups {
+				bts, err = z.Rollups[za0004].UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Rollups", za0004)
+					return
+				}
+			}
+		case "Options":
+			var zb0007 uint32
+			zb0007, bts, err = msgp.ReadMapHeaderBytes(bts)
 			if err != nil {
 				err = msgp.WrapError(err, "Options")
 				return
 			}
-			for zb0006 > 0 {
-				zb0006--
+			for zb0007 > 0 {
+				zb0007--
 				field, bts, err = msgp.ReadMapKeyZC(bts)
 				if err != nil {
 					err = msgp.WrapError(err, "Options")
@@ -1436,14 +1501,14 @@ func (z *ResultTableDetail) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				}
 				switch msgp.UnsafeString(field) {
 				case "TimeField":
-					var zb0007 uint32
-					zb0007, bts, err = msgp.ReadMapHeaderBytes(bts)
+					var zb0008 uint32
+					zb0008, bts, err = msgp.ReadMapHeaderBytes(bts)
 					if err != nil {
 						err = msgp.WrapError(err, "Options", "TimeField")
 						return
 					}
-					for zb0007 > 0 {
-						zb0007--
+					for zb0008 > 0 {
+						zb0008--
 						field, bts, err = msgp.ReadMapKeyZC(bts)
 						if err != nil {
 							err = msgp.WrapError(err, "Options", "TimeField")
@@ -1512,7 +1577,11 @@ func (z *ResultTableDetail) Msgsize() (s int) {
 	for za0003 := range z.TagsKey {
 		s += msgp.StringPrefixSize + len(z.TagsKey[za0003])
 	}
-	s += 7 + msgp.Int64Size + 11 + msgp.StringPrefixSize + len(z.SourceType) + 8 + 1 + 10 + 1 + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Name) + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Type) + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Unit) + 12 + msgp.BoolSize
+	s += 7 + msgp.Int64Size + 11 + msgp.StringPrefixSize + len(z.SourceType) + 8 + msgp.ArrayHeaderSize
+	for za0004 := range z.Rollups {
+		s += z.Rollups[za0004].Msgsize()
+	}
+	s += 8 + 1 + 10 + 1 + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Name) + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Type) + 5 + msgp.StringPrefixSize + len(z.Options.TimeField.Unit) + 12 + msgp.BoolSize
 	return
 }
 
@@ -1756,6 +1825,198 @@ func (z ResultTableList) Msgsize() (s int) {
 	return
 }
 
+// DecodeMsg implements msgp.Decodable
+func (z *Rollup) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "TableId":
+			z.TableId, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "TableId")
+				return
+			}
+		case "Interval":
+			z.Interval, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Interval")
+				return
+			}
+		case "Functions":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Functions")
+				return
+			}
+			if cap(z.Functions) >= int(zb0002) {
+				z.Functions = (z.Functions)[:zb0002]
+			} else {
+				z.Functions = make([]string, zb0002)
+			}
+			for za0001 := range z.Functions {
+				z.Functions[za0001], err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Functions", za0001)
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *Rollup) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "TableId"
+	err = en.Append(0x83, 0xa7, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.TableId)
+	if err != nil {
+		err = msgp.WrapError(err, "TableId")
+		return
+	}
+	// write "Interval"
+	err = en.Append(0xa8, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Interval)
+	if err != nil {
+		err = msgp.WrapError(err, "Interval")
+		return
+	}
+	// write "Functions"
+	err = en.Append(0xa9, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Functions)))
+	if err != nil {
+		err = msgp.WrapError(err, "Functions")
+		return
+	}
+	for za0001 := range z.Functions {
+		err = en.WriteString(z.Functions[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "Functions", za0001)
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *Rollup) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "TableId"
+	o = append(o, 0x83, 0xa7, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x64)
+	o = msgp.AppendString(o, z.TableId)
+	// string "Interval"
+	o = append(o, 0xa8, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c)
+	o = msgp.AppendString(o, z.Interval)
+	// string "Functions"
+	o = append(o, 0xa9, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Functions)))
+	for za0001 := range z.Functions {
+		o = msgp.AppendString(o, z.Functions[za0001])
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Rollup) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "TableId":
+			z.TableId, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "TableId")
+				return
+			}
+		case "Interval":
+			z.Interval, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Interval")
+				return
+			}
+		case "Functions":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Functions")
+				return
+			}
+			if cap(z.Functions) >= int(zb0002) {
+				z.Functions = (z.Functions)[:zb0002]
+			} else {
+				z.Functions = make([]string, zb0002)
+			}
+			for za0001 := range z.Functions {
+				z.Functions[za0001], bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Functions", za0001)
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *Rollup) Msgsize() (s int) {
+	s = 1 + 8 + msgp.StringPrefixSize + len(z.TableId) + 9 + msgp.StringPrefixSize + len(z.Interval) + 10 + msgp.ArrayHeaderSize
+	for za0001 := range z.Functions {
+		s += msgp.StringPrefixSize + len(z.Functions[za0001])
+	}
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *Space) DecodeMsg(dc *msgp.Reader) (err error) {
 	var zb0003 uint32